@@ -24,6 +24,8 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/yeeco/gyee/log"
 	"github.com/yeeco/gyee/p2p"
@@ -40,6 +42,9 @@ func main() {
 		chainPort   = flag.Int("cport", p2pCfg.DftUdpPort, "chain port")
 		dhtIp       = flag.String("dip", "0.0.0.0", "dht ip(b1.b2.b3.b4)")
 		dhtPort     = flag.Int("dport", p2pCfg.DftDhtPort, "dht port")
+		seedOnly    = flag.Bool("seedonly", false, "accept peers and shed them after a grace period")
+		seedGrace   = flag.Duration("seedgrace", p2pCfg.DftSeedGraceTime, "grace period before a peer is shed")
+		stopTimeout = flag.Duration("stoptimeout", 30*time.Second, "max duration of a soft shutdown before it's forced")
 		nodeKey     *ecdsa.PrivateKey
 		err         error
 	)
@@ -79,7 +84,7 @@ func main() {
 			log.Crit("failed to parse nodeID")
 			os.Exit(-1)
 		}
-		fmt.Printf("\n\t%x\n", *nodeID)
+		fmt.Printf("\n\t%s\n", p2pCfg.P2pNodeId2String(*nodeID))
 		os.Exit(0)
 	}
 
@@ -99,6 +104,8 @@ func main() {
 		nodeCfg.Name = *nodeName
 	}
 	nodeCfg.BootstrapNode = true
+	nodeCfg.SeedOnly = *seedOnly
+	nodeCfg.SeedGraceTime = *seedGrace
 	nodeCfg.Validator = false
 	nodeCfg.SubNetMaskBits = 0
 	nodeCfg.NatType = p2pCfg.NATT_NONE
@@ -113,10 +120,29 @@ func main() {
 		os.Exit(-3)
 	}
 
+	// stop softly on SIGINT, SIGTERM or SIGQUIT: the peer db and dht store are
+	// closed by the tasks powered off. it's forced if the stop takes longer
+	// than stopTimeout or another signal comes, and exits with non-zero then.
 	sig := make(chan os.Signal, 1)
-	signal.Notify(sig, os.Interrupt)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 	defer signal.Stop(sig)
-	<-sig
-	bootNode.Stop()
-	os.Exit(0)
+	log.Info("bootnode: got signal, stopping...", <-sig)
+
+	done := make(chan struct{})
+	go func() {
+		bootNode.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		log.Info("bootnode: stopped")
+		os.Exit(0)
+	case <-time.After(*stopTimeout):
+		log.Error("bootnode: stop timeout, forced", "timeout", *stopTimeout)
+		os.Exit(-4)
+	case s := <-sig:
+		log.Error("bootnode: stop forced by signal", "signal", s)
+		os.Exit(-5)
+	}
 }
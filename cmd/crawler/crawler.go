@@ -0,0 +1,315 @@
+// Copyright (C) 2018 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/p2p"
+	p2pCfg "github.com/yeeco/gyee/p2p/config"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+const (
+	roleBootstrap = "bootstrap" // listed as a bootstrap node
+	roleChain     = "chain"     // found by the chain discovery
+	roleDht       = "dht"       // found by the dht
+	regionUnknown = "unknown"   // not covered by the geo table
+	dhtFindNodeTo = 16 * time.Second
+)
+
+// crawledNode is a node seen while crawling, it's keyed by the node identity.
+type crawledNode struct {
+	ID        string    `json:"id"`
+	IP        string    `json:"ip"`
+	UDP       uint16    `json:"udp,omitempty"`
+	TCP       uint16    `json:"tcp,omitempty"`
+	DhtTCP    uint16    `json:"dhtTcp,omitempty"`
+	Subnet    string    `json:"subnet"`
+	Roles     []string  `json:"roles"`
+	Region    string    `json:"region"`
+	Reachable bool      `json:"reachable"`
+	RttMs     float64   `json:"rttMs,omitempty"`
+	Seen      int       `json:"seen"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// crawlSummary aggregates the crawled nodes for network health reports.
+type crawlSummary struct {
+	Total     int            `json:"total"`
+	Reachable int            `json:"reachable"`
+	RttP50Ms  float64        `json:"rttP50Ms"`
+	RttP90Ms  float64        `json:"rttP90Ms"`
+	BySubnet  map[string]int `json:"bySubnet"`
+	ByRole    map[string]int `json:"byRole"`
+	ByRegion  map[string]int `json:"byRegion"`
+}
+
+// crawlReport is what written to the output file.
+type crawlReport struct {
+	Started  time.Time      `json:"started"`
+	Finished time.Time      `json:"finished"`
+	Lookups  int            `json:"lookups"`
+	Summary  crawlSummary   `json:"summary"`
+	Nodes    []*crawledNode `json:"nodes"`
+}
+
+// geoEntry maps a network to a region name, loaded from a "cidr,region" file.
+type geoEntry struct {
+	network *net.IPNet
+	region  string
+}
+
+type crawler struct {
+	svc      *p2p.OsnService
+	maskBits int
+	useDht   bool
+	dialTo   time.Duration
+	geo      []geoEntry
+	nodes    map[p2pCfg.NodeID]*crawledNode
+	lookups  int
+}
+
+func newCrawler(svc *p2p.OsnService, maskBits int, useDht bool, dialTo time.Duration) *crawler {
+	return &crawler{
+		svc:      svc,
+		maskBits: maskBits,
+		useDht:   useDht,
+		dialTo:   dialTo,
+		nodes:    make(map[p2pCfg.NodeID]*crawledNode, 0),
+	}
+}
+
+func (c *crawler) loadGeo(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.SplitN(text, ",", 2)
+		if len(fields) != 2 {
+			return fmt.Errorf("loadGeo: invalid line %d: %s", line, text)
+		}
+		_, network, err := net.ParseCIDR(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return fmt.Errorf("loadGeo: invalid cidr at line %d: %s", line, err.Error())
+		}
+		c.geo = append(c.geo, geoEntry{network: network, region: strings.TrimSpace(fields[1])})
+	}
+	return scanner.Err()
+}
+
+func (c *crawler) region(ip net.IP) string {
+	for _, ge := range c.geo {
+		if ge.network.Contains(ip) {
+			return ge.region
+		}
+	}
+	return regionUnknown
+}
+
+func (c *crawler) addBootstrapNodes(urls []string) {
+	for _, node := range p2pCfg.P2pSetupBootstrapNodes(urls) {
+		c.record(node, roleBootstrap)
+	}
+}
+
+func (c *crawler) record(node *p2pCfg.Node, role string) {
+	if node == nil || node.IP == nil {
+		return
+	}
+	cn, ok := c.nodes[node.ID]
+	if !ok {
+		snid, _ := p2p.GetSubnetIdentity(node.ID, c.maskBits)
+		cn = &crawledNode{
+			ID:        p2pCfg.P2pNodeId2String(node.ID),
+			IP:        node.IP.String(),
+			Subnet:    p2pCfg.P2pSubNetId2HexString(snid),
+			Region:    c.region(node.IP),
+			FirstSeen: time.Now(),
+		}
+		c.nodes[node.ID] = cn
+	}
+	if role == roleDht {
+		cn.DhtTCP = node.TCP
+	} else {
+		cn.UDP = node.UDP
+		cn.TCP = node.TCP
+	}
+	for _, r := range cn.Roles {
+		if r == role {
+			role = ""
+			break
+		}
+	}
+	if role != "" {
+		cn.Roles = append(cn.Roles, role)
+	}
+	cn.Seen++
+	cn.LastSeen = time.Now()
+}
+
+// crawl issues lookups to random targets until rounds done or the deadline
+// reached, a round is one chain lookup plus one dht find node if enabled.
+func (c *crawler) crawl(rounds int, deadline time.Time, stop chan os.Signal) {
+	for round := 0; rounds <= 0 || round < rounds; round++ {
+		if time.Now().After(deadline) {
+			return
+		}
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		var target p2pCfg.NodeID
+		rand.Read(target[:])
+		c.lookups++
+
+		nodes, err := c.svc.Lookup(p2pCfg.AnySubNet, target)
+		if err != nil {
+			log.Warn("lookup failed", "round", round, "err", err)
+		}
+		for _, n := range nodes {
+			c.record(n, roleChain)
+		}
+
+		if c.useDht {
+			c.dhtFindNode(&target)
+		}
+		log.Info("crawl round done", "round", round, "found", len(nodes), "total", len(c.nodes))
+	}
+}
+
+func (c *crawler) dhtFindNode(target *p2pCfg.NodeID) {
+	done := make(chan interface{}, 1)
+	if err := c.svc.DhtFindNode(target, done); err != nil {
+		log.Warn("dht find node failed", "err", err)
+		return
+	}
+	select {
+	case rsp := <-done:
+		if ind, ok := rsp.(*sch.MsgDhtQryMgrQueryResultInd); ok {
+			for _, n := range ind.Peers {
+				c.record(n, roleDht)
+			}
+		}
+	case <-time.After(dhtFindNodeTo):
+		log.Warn("dht find node timeout")
+	}
+}
+
+// probe measures the latency to each node by the time taken to connect to
+// its tcp port, nodes not accepting are marked as unreachable.
+func (c *crawler) probe(concurrency int) {
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for _, cn := range c.nodes {
+		port := cn.TCP
+		if port == 0 {
+			port = cn.DhtTCP
+		}
+		if port == 0 {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(cn *crawledNode, addr string) {
+			defer func() { <-sem; wg.Done() }()
+			start := time.Now()
+			conn, err := net.DialTimeout("tcp", addr, c.dialTo)
+			if err != nil {
+				return
+			}
+			cn.RttMs = float64(time.Since(start)) / float64(time.Millisecond)
+			cn.Reachable = true
+			conn.Close()
+		}(cn, net.JoinHostPort(cn.IP, fmt.Sprintf("%d", port)))
+	}
+	wg.Wait()
+}
+
+func (c *crawler) report(started time.Time) *crawlReport {
+	rpt := &crawlReport{
+		Started:  started,
+		Finished: time.Now(),
+		Lookups:  c.lookups,
+		Summary: crawlSummary{
+			BySubnet: make(map[string]int, 0),
+			ByRole:   make(map[string]int, 0),
+			ByRegion: make(map[string]int, 0),
+		},
+		Nodes: make([]*crawledNode, 0, len(c.nodes)),
+	}
+	rtts := make([]float64, 0)
+	for _, cn := range c.nodes {
+		rpt.Nodes = append(rpt.Nodes, cn)
+		rpt.Summary.BySubnet[cn.Subnet]++
+		rpt.Summary.ByRegion[cn.Region]++
+		for _, r := range cn.Roles {
+			rpt.Summary.ByRole[r]++
+		}
+		if cn.Reachable {
+			rpt.Summary.Reachable++
+			rtts = append(rtts, cn.RttMs)
+		}
+	}
+	sort.Slice(rpt.Nodes, func(i, j int) bool { return rpt.Nodes[i].ID < rpt.Nodes[j].ID })
+	sort.Float64s(rtts)
+	rpt.Summary.Total = len(rpt.Nodes)
+	rpt.Summary.RttP50Ms = percentile(rtts, 50)
+	rpt.Summary.RttP90Ms = percentile(rtts, 90)
+	return rpt
+}
+
+func percentile(sorted []float64, p int) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)-1)*p/100]
+}
+
+func writeReport(path string, rpt *crawlReport) error {
+	data, err := json.MarshalIndent(rpt, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == "" || path == "-" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
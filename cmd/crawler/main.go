@@ -0,0 +1,116 @@
+// Copyright (C) 2018 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+// crawler walks the gyee network by the discovery and the dht, and writes
+// the nodes found, with their subnets, roles, latencies and regions, to a
+// json report for network health checking.
+package main
+
+import (
+	"flag"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/p2p"
+	p2pCfg "github.com/yeeco/gyee/p2p/config"
+)
+
+func main() {
+	var (
+		bootNodes    = flag.String("bootnodes", "", "comma separated chain bootstrap nodes(id@ip:udp:tcp)")
+		dhtBootNodes = flag.String("dhtbootnodes", "", "comma separated dht bootstrap nodes(id@ip:port:port)")
+		chainIp      = flag.String("cip", "0.0.0.0", "chain ip(b1.b2.b3.b4)")
+		chainPort    = flag.Int("cport", p2pCfg.DftUdpPort, "chain port")
+		dhtIp        = flag.String("dip", "0.0.0.0", "dht ip(b1.b2.b3.b4)")
+		dhtPort      = flag.Int("dport", p2pCfg.DftDhtPort, "dht port")
+		useDht       = flag.Bool("dht", true, "crawl the dht too")
+		maskBits     = flag.Int("maskbits", p2pCfg.DftSnmBits, "subnet mask bits of the network crawled")
+		rounds       = flag.Int("rounds", 0, "number of lookup rounds, 0 for no limit")
+		duration     = flag.Duration("duration", 10*time.Minute, "max time to crawl")
+		warmup       = flag.Duration("warmup", 10*time.Second, "time to wait for bootstrapping before crawling")
+		dialTimeout  = flag.Duration("dialtimeout", 2*time.Second, "timeout to probe a node")
+		probes       = flag.Int("probes", 16, "max concurrency probes")
+		geoFile      = flag.String("geo", "", "file of \"cidr,region\" lines to map nodes to regions")
+		output       = flag.String("out", "-", "json report file, \"-\" for stdout")
+	)
+	flag.Parse()
+
+	if *bootNodes == "" {
+		log.Crit("bootnodes must not be empty")
+		os.Exit(-1)
+	}
+
+	nodeCfg := p2p.DefaultYeShellConfig
+	nodeCfg.AppType = p2pCfg.P2P_TYPE_CHAIN
+	nodeCfg.LocalNodeIp = *chainIp
+	nodeCfg.LocalTcpPort = (uint16)(*chainPort & 0xffff)
+	nodeCfg.LocalUdpPort = (uint16)(*chainPort & 0xffff)
+	nodeCfg.BootstrapNodes = strings.Split(*bootNodes, ",")
+	nodeCfg.DhtBootstrapNodes = make([]string, 0)
+	if *useDht {
+		nodeCfg.AppType = p2pCfg.P2P_TYPE_ALL
+		nodeCfg.LocalDhtIp = *dhtIp
+		nodeCfg.LocalDhtPort = (uint16)(*dhtPort & 0xffff)
+		if *dhtBootNodes != "" {
+			nodeCfg.DhtBootstrapNodes = strings.Split(*dhtBootNodes, ",")
+		}
+	}
+	nodeCfg.Name = "crawler"
+	nodeCfg.Validator = false
+	nodeCfg.SubNetMaskBits = 0
+	nodeCfg.NatType = p2pCfg.NATT_NONE
+
+	svc, err := p2p.NewOsnService(&nodeCfg)
+	if err != nil {
+		log.Crit("failed to create crawler node", "err", err)
+		os.Exit(-2)
+	} else if err := svc.Start(); err != nil {
+		log.Crit("failed to start crawler node", "err", err)
+		os.Exit(-3)
+	}
+	defer svc.Stop()
+
+	c := newCrawler(svc, *maskBits, *useDht, *dialTimeout)
+	if *geoFile != "" {
+		if err := c.loadGeo(*geoFile); err != nil {
+			log.Crit("failed to load geo file", "err", err)
+			os.Exit(-1)
+		}
+	}
+	c.addBootstrapNodes(nodeCfg.BootstrapNodes)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+
+	started := time.Now()
+	select {
+	case <-sig:
+		return
+	case <-time.After(*warmup):
+	}
+	c.crawl(*rounds, started.Add(*duration), sig)
+	c.probe(*probes)
+
+	if err := writeReport(*output, c.report(started)); err != nil {
+		log.Crit("failed to write report", "err", err)
+		os.Exit(-4)
+	}
+}
@@ -19,3 +19,194 @@
  */
 
 package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofrs/flock"
+	"github.com/urfave/cli"
+	"github.com/yeeco/gyee/config"
+	"github.com/yeeco/gyee/core"
+	"github.com/yeeco/gyee/persistent"
+	"github.com/yeeco/gyee/utils/datadir"
+)
+
+var (
+	checkpointCommand = cli.Command{
+		Name:     "checkpoint",
+		Usage:    "Manage chain checkpoints",
+		Category: "CHAIN COMMANDS",
+		Description: `
+Export, verify or import checkpoint files. A checkpoint is a block header signed by
+the validators, new nodes can be trust-initialized from it. The node must not be running.`,
+
+		Subcommands: []cli.Command{
+			{
+				Name:      "export",
+				Usage:     "Export checkpoint of a local block to file",
+				ArgsUsage: "<number> <file>",
+				Action:    config.MergeFlags(checkpointExport),
+			},
+			{
+				Name:      "verify",
+				Usage:     "Verify checkpoint file with the trusted validators of local chain",
+				ArgsUsage: "<file>",
+				Action:    config.MergeFlags(checkpointVerify),
+			},
+			{
+				Name:      "import",
+				Usage:     "Verify checkpoint file and import it as trusted start point",
+				ArgsUsage: "<file>",
+				Action:    config.MergeFlags(checkpointImport),
+			},
+		},
+	}
+
+	stateCommand = cli.Command{
+		Name:     "state",
+		Usage:    "Inspect chain state",
+		Category: "CHAIN COMMANDS",
+		Description: `
+Inspect state of local blocks. The node must not be running.`,
+
+		Subcommands: []cli.Command{
+			{
+				Name:      "digest",
+				Usage:     "Print digest of accounts in state of a local block",
+				ArgsUsage: "<number> [prefix]",
+				Action:    config.MergeFlags(stateDigest),
+				Description: `
+Print digest of accounts under the address prefix in hex, all accounts if not given,
+and sub-digests by the next hex digit of address. Compare the digests of two nodes,
+then those under the prefix differs, to narrow down to the accounts diverged.`,
+			},
+		},
+	}
+)
+
+func checkpointExport(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return errors.New("block number and file expected")
+	}
+	number, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+	if err != nil {
+		return err
+	}
+	return withChain(ctx, func(bc *core.BlockChain) error {
+		cp, err := bc.Checkpoint(number)
+		if err != nil {
+			return err
+		}
+		if err := core.WriteCheckpointFile(ctx.Args().Get(1), cp); err != nil {
+			return err
+		}
+		printCheckpoint(cp)
+		return nil
+	})
+}
+
+func checkpointVerify(ctx *cli.Context) error {
+	cp, err := readCheckpointArg(ctx)
+	if err != nil {
+		return err
+	}
+	return withChain(ctx, func(bc *core.BlockChain) error {
+		if err := bc.VerifyCheckpoint(cp); err != nil {
+			return err
+		}
+		printCheckpoint(cp)
+		fmt.Println("Checkpoint verified")
+		return nil
+	})
+}
+
+func checkpointImport(ctx *cli.Context) error {
+	cp, err := readCheckpointArg(ctx)
+	if err != nil {
+		return err
+	}
+	return withChain(ctx, func(bc *core.BlockChain) error {
+		if err := bc.ImportCheckpoint(cp); err != nil {
+			return err
+		}
+		printCheckpoint(cp)
+		fmt.Println("Checkpoint imported")
+		return nil
+	})
+}
+
+func stateDigest(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return errors.New("block number and optional address prefix expected")
+	}
+	number, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+	if err != nil {
+		return err
+	}
+	return withChain(ctx, func(bc *core.BlockChain) error {
+		sd, err := bc.StateDigest(number, ctx.Args().Get(1))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Number: %d\n", sd.Number)
+		fmt.Printf("Hash: %s\n", sd.Hash.Hex())
+		fmt.Printf("State root: %s\n", sd.StateRoot.Hex())
+		fmt.Printf("Prefix: %s\n", sd.Prefix)
+		fmt.Printf("Accounts: %d\n", sd.Accounts)
+		fmt.Printf("Digest: %s\n", sd.Digest.Hex())
+		for _, sub := range sd.Subs {
+			fmt.Printf("  %-40s %8d %s\n", sub.Prefix, sub.Accounts, sub.Digest.Hex())
+		}
+		return nil
+	})
+}
+
+func readCheckpointArg(ctx *cli.Context) (*core.Checkpoint, error) {
+	if ctx.NArg() != 1 {
+		return nil, errors.New("checkpoint file expected")
+	}
+	return core.ReadCheckpointFile(ctx.Args().Get(0))
+}
+
+func printCheckpoint(cp *core.Checkpoint) {
+	fmt.Printf("Chain: %d\n", cp.ChainID)
+	fmt.Printf("Number: %d\n", cp.Number)
+	fmt.Printf("Hash: %s\n", cp.Hash.Hex())
+	fmt.Printf("State root: %s\n", cp.StateRoot.Hex())
+	fmt.Printf("Validators: %d, signatures: %d\n", len(cp.Validators), len(cp.Signatures))
+}
+
+// open the local chain of node dir locked, and call fn with it
+func withChain(ctx *cli.Context, fn func(bc *core.BlockChain) error) error {
+	conf := config.GetConfig(ctx)
+	layout, err := datadir.Open(conf.NodeDir)
+	if err != nil {
+		return err
+	}
+	if err := layout.Check(); err != nil {
+		return err
+	}
+
+	filelock := flock.New(layout.LockFile())
+	locked, err := filelock.TryLock()
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return errors.New("node dir is in use, stop the node first")
+	}
+	defer filelock.Unlock()
+
+	storage, err := persistent.NewLevelStorage(layout.ChainDataDir())
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+	bc, err := core.NewBlockChain(core.ChainID(conf.Chain.ChainID), storage, nil)
+	if err != nil {
+		return err
+	}
+	return fn(bc)
+}
@@ -354,6 +354,186 @@ func (b *jsBridge) sendTransaction(call otto.FunctionCall) otto.Value {
 	return v
 }
 
+// addStatic adds a static node by url "id@ip:udp:tcp" and connects to it
+func (b *jsBridge) addStatic(call otto.FunctionCall) otto.Value {
+	response, err := b.svcAdmin.AddStatic(b.ctx,
+		&rpcpb.AddStaticRequest{
+			Url: call.Argument(0).String(),
+		})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
+func (b *jsBridge) removePeer(call otto.FunctionCall) otto.Value {
+	response, err := b.svcAdmin.RemovePeer(b.ctx,
+		&rpcpb.RemovePeerRequest{
+			Id: call.Argument(0).String(),
+		})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
+// banPeer bans a peer for seconds, or unbans it if seconds is 0
+func (b *jsBridge) banPeer(call otto.FunctionCall) otto.Value {
+	dObj := call.Argument(1)
+	if !dObj.IsNumber() {
+		return jsError(call.Otto, errors.New("not duration number"))
+	}
+	d, _ := dObj.ToInteger()
+	if d < 0 {
+		return jsError(call.Otto, errors.New("negative duration"))
+	}
+	response, err := b.svcAdmin.BanPeer(b.ctx,
+		&rpcpb.BanPeerRequest{
+			Id:       call.Argument(0).String(),
+			Duration: uint64(d),
+		})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
+// pingPeer probes the link to a peer with count echo requests padded to size bytes
+func (b *jsBridge) pingPeer(call otto.FunctionCall) otto.Value {
+	size, err := call.Argument(1).ToInteger()
+	if err != nil || size < 0 {
+		return jsError(call.Otto, errors.New("invalid size number"))
+	}
+	count, err := call.Argument(2).ToInteger()
+	if err != nil || count < 0 {
+		return jsError(call.Otto, errors.New("invalid count number"))
+	}
+	response, err := b.svcAdmin.PingPeer(b.ctx,
+		&rpcpb.PingPeerRequest{
+			Id:    call.Argument(0).String(),
+			Size:  uint32(size),
+			Count: uint32(count),
+		})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
+func (b *jsBridge) setLogLevel(call otto.FunctionCall) otto.Value {
+	response, err := b.svcAdmin.SetLogLevel(b.ctx,
+		&rpcpb.SetLogLevelRequest{
+			Level: call.Argument(0).String(),
+		})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
+func (b *jsBridge) p2pNodeInfo(call otto.FunctionCall) otto.Value {
+	response, err := b.svcAdmin.NodeInfo(b.ctx, &rpcpb.NonParamsRequest{})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
+func (b *jsBridge) startStop(call otto.FunctionCall) otto.Value {
+	start, err := call.Argument(1).ToBoolean()
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	response, err := b.svcAdmin.StartStop(b.ctx,
+		&rpcpb.StartStopRequest{
+			Subsystem: call.Argument(0).String(),
+			Start:     start,
+		})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
+// getEvidences gets evidences of malicious blocks and txs from start, limit of them
+func (b *jsBridge) getEvidences(call otto.FunctionCall) otto.Value {
+	start, err := call.Argument(0).ToInteger()
+	if err != nil || start < 0 {
+		return jsError(call.Otto, errors.New("invalid start number"))
+	}
+	limit, err := call.Argument(1).ToInteger()
+	if err != nil || limit < 0 {
+		return jsError(call.Otto, errors.New("invalid limit number"))
+	}
+	response, err := b.svcAdmin.GetEvidences(b.ctx,
+		&rpcpb.GetEvidencesRequest{
+			Start: uint64(start),
+			Limit: uint64(limit),
+		})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
+// getStateDigest gets digest of state at block number, of accounts under the
+// address prefix if given
+func (b *jsBridge) getStateDigest(call otto.FunctionCall) otto.Value {
+	number, err := call.Argument(0).ToInteger()
+	if err != nil || number < 0 {
+		return jsError(call.Otto, errors.New("invalid block number"))
+	}
+	prefix := ""
+	if call.Argument(1).IsString() {
+		prefix = call.Argument(1).String()
+	}
+	response, err := b.svcAdmin.GetStateDigest(b.ctx,
+		&rpcpb.GetStateDigestRequest{
+			Number: uint64(number),
+			Prefix: prefix,
+		})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
+// compactStorage starts compaction of chain db keyspace, the whole db if not given
+func (b *jsBridge) compactStorage(call otto.FunctionCall) otto.Value {
+	keyspace := ""
+	if call.Argument(0).IsString() {
+		keyspace = call.Argument(0).String()
+	}
+	response, err := b.svcAdmin.CompactStorage(b.ctx,
+		&rpcpb.CompactStorageRequest{
+			Keyspace: keyspace,
+		})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
+// diskUsage gets estimated disk usage of chain db by keyspace
+func (b *jsBridge) diskUsage(call otto.FunctionCall) otto.Value {
+	response, err := b.svcAdmin.DiskUsage(b.ctx, &rpcpb.NonParamsRequest{})
+	if err != nil {
+		return jsError(call.Otto, err)
+	}
+	value, _ := otto.ToValue(response.String())
+	return value
+}
+
 // sendTransactionWithPassphrase handle the transaction send with passphrase input
 func (b *jsBridge) sendTransactionWithPassphrase(call otto.FunctionCall) otto.Value {
 	if !call.Argument(0).IsString() || !call.Argument(1).IsString() {
@@ -116,6 +116,18 @@ func (c *Console) setupBridge() error {
 
 	_ = obj.Set("sendTransaction", c.bridge.sendTransaction)
 
+	_ = obj.Set("addStatic", c.bridge.addStatic)
+	_ = obj.Set("removePeer", c.bridge.removePeer)
+	_ = obj.Set("banPeer", c.bridge.banPeer)
+	_ = obj.Set("pingPeer", c.bridge.pingPeer)
+	_ = obj.Set("setLogLevel", c.bridge.setLogLevel)
+	_ = obj.Set("p2pNodeInfo", c.bridge.p2pNodeInfo)
+	_ = obj.Set("startStop", c.bridge.startStop)
+	_ = obj.Set("getEvidences", c.bridge.getEvidences)
+	_ = obj.Set("getStateDigest", c.bridge.getStateDigest)
+	_ = obj.Set("compactStorage", c.bridge.compactStorage)
+	_ = obj.Set("diskUsage", c.bridge.diskUsage)
+
 	// temporary bridge api, should switch to js binding later
 	if true {
 		_ = obj.Set("getBlockByHash", c.bridge.getBlockByHash)
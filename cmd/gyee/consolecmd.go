@@ -22,11 +22,16 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"time"
 
 	"github.com/urfave/cli"
 	"github.com/yeeco/gyee/cmd/gyee/console"
 	"github.com/yeeco/gyee/config"
+	"github.com/yeeco/gyee/node"
+	"github.com/yeeco/gyee/rpc"
+	"github.com/yeeco/gyee/utils/datadir"
 	"google.golang.org/grpc"
 )
 
@@ -40,14 +45,27 @@ var (
 	}
 
 	attachCommand = cli.Command{
-		Name:        "attach",
-		Usage:       "Start an interactive JavaScript console to running node",
-		Category:    "CONSOLE COMMANDS",
-		Description: "",
-		Action:      config.MergeFlags(consoleAttach),
+		Name:      "attach",
+		Usage:     "Start an interactive JavaScript console to running node",
+		ArgsUsage: "[endpoint]",
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "exec",
+				Usage: "Execute JavaScript statement and exit",
+			},
+		},
+		Category: "CONSOLE COMMANDS",
+		Description: `
+The console is attached to the ipc endpoint of a running node, a unix socket or
+a named pipe on windows, given as argument or configured by ipc_path. no network
+port is opened by the node for it.`,
+		Action: config.MergeFlags(consoleAttach),
 	}
 )
 
+// max time to connect to the node attached
+const attachTimeout = 5 * time.Second
+
 func consoleStart(ctx *cli.Context) error {
 	//node := makeNode(ctx)
 	console := console.NewConsole(nil)
@@ -61,22 +79,46 @@ func consoleStart(ctx *cli.Context) error {
 func consoleAttach(ctx *cli.Context) error {
 	conf := config.GetConfig(ctx)
 	target := conf.IPCEndpoint()
+	if ctx.NArg() > 0 {
+		target = ctx.Args().First()
+	}
+	if target == "" {
+		return fmt.Errorf("attach: no ipc endpoint, ipc_path not configured")
+	}
 
-	// grpc connection
-	conn, err := grpc.Dial(target, grpc.WithInsecure(),
-		grpc.WithContextDialer(func(ctx context.Context, addr string) (conn net.Conn, e error) {
-			d := net.Dialer{}
-			return d.DialContext(ctx, "unix", addr)
+	opts := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			return node.DialIPC(ctx, addr)
 		}),
-	)
+	}
+	if conf.Rpc.AdminAuth {
+		token, err := rpc.ReadAdminToken(datadir.New(conf.NodeDir).AdminTokenFile())
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{},
+			cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(rpc.WithAdminToken(ctx, token), method, req, reply, cc, opts...)
+		}))
+	}
+
+	// grpc connection
+	dialCtx, cancel := context.WithTimeout(context.Background(), attachTimeout)
+	defer cancel()
+	conn, err := grpc.DialContext(dialCtx, target, opts...)
 	if err != nil {
-		return err
+		return fmt.Errorf("attach: node not reachable at %s: %v", target, err)
 	}
 	defer conn.Close()
 
 	c := console.NewConsole(conn)
-	c.Setup()
 	defer c.Stop()
+	if code := ctx.String("exec"); code != "" {
+		return c.Evaluate(code)
+	}
+	c.Setup()
 
 	c.Interactive()
 
@@ -0,0 +1,112 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofrs/flock"
+	"github.com/urfave/cli"
+	"github.com/yeeco/gyee/config"
+	"github.com/yeeco/gyee/utils/datadir"
+)
+
+var (
+	dataDirDryRunFlag = cli.BoolFlag{
+		Name:  "dryrun",
+		Usage: "print planned moves without changing anything",
+	}
+
+	dataDirCommand = cli.Command{
+		Name:     "datadir",
+		Usage:    "Manage node data directory",
+		Category: "DATADIR COMMANDS",
+		Description: `
+Show or migrate the layout of the node data directory.`,
+
+		Subcommands: []cli.Command{
+			{
+				Name:   "info",
+				Usage:  "Show layout version and paths",
+				Action: config.MergeFlags(dataDirInfo),
+			},
+			{
+				Name:   "migrate",
+				Usage:  "Migrate node data directory to current layout",
+				Flags:  []cli.Flag{dataDirDryRunFlag},
+				Action: config.MergeFlags(dataDirMigrate),
+				Description: `
+Move files of an old layout to their current places and record the layout version.
+The node must not be running.`,
+			},
+		},
+	}
+)
+
+func dataDirInfo(ctx *cli.Context) error {
+	conf := config.GetConfig(ctx)
+	layout, err := datadir.Inspect(conf.NodeDir)
+	if layout == nil {
+		return err
+	}
+	fmt.Printf("Node dir: %s\n", layout.Root())
+	fmt.Printf("Layout version: %d (current: %d)\n", layout.Version(), datadir.CurrentLayout)
+	fmt.Printf("Chain data: %s\n", layout.ChainDataDir())
+	fmt.Printf("Keystore: %s\n", layout.KeystoreDir())
+	fmt.Printf("P2p data: %s\n", layout.P2pDir())
+	fmt.Printf("Logs: %s\n", layout.LogDir())
+	for _, m := range layout.Pending() {
+		fmt.Printf("Pending migration: %d -> %d, %s\n", m.From, m.From+1, m.Desc)
+	}
+	return err
+}
+
+func dataDirMigrate(ctx *cli.Context) error {
+	conf := config.GetConfig(ctx)
+	layout, err := datadir.Open(conf.NodeDir)
+	if err != nil {
+		return err
+	}
+	if len(layout.Pending()) == 0 {
+		fmt.Printf("Layout version %d is up to date\n", layout.Version())
+		return nil
+	}
+
+	filelock := flock.New(layout.LockFile())
+	locked, err := filelock.TryLock()
+	if err != nil {
+		return err
+	}
+	if !locked {
+		return errors.New("node dir is in use, stop the node first")
+	}
+	defer filelock.Unlock()
+
+	dryRun := ctx.Bool(dataDirDryRunFlag.Name)
+	if err := layout.Migrate(dryRun, func(msg string) { fmt.Println(msg) }); err != nil {
+		return err
+	}
+	if !dryRun {
+		fmt.Printf("Migrated to layout version %d\n", layout.Version())
+	}
+	return nil
+}
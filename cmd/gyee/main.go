@@ -60,6 +60,9 @@ func init() {
 		consoleCommand,
 		attachCommand,
 		configCommand,
+		dataDirCommand,
+		checkpointCommand,
+		stateCommand,
 		accountCommand,
 		licenseCommand,
 		versionCommand,
@@ -98,6 +101,5 @@ func gyee(ctx *cli.Context) error {
 		return err
 	}
 
-	n.WaitForShutdown()
-	return nil
+	return n.WaitForShutdown()
 }
@@ -0,0 +1,73 @@
+// Copyright (C) 2018 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+// netsim runs a scripted network scenario, written in yaml, on nodes in
+// process, and writes the outcomes of deliveries, discoveries and syncs to a
+// json report, for tests and demos. see example.yaml for what a scenario is.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+
+	"github.com/yeeco/gyee/log"
+)
+
+func main() {
+	var (
+		scenarioFile = flag.String("scenario", "", "yaml scenario file")
+		seed         = flag.Int64("seed", 0, "seed of the random source, overriding the one of the scenario")
+		output       = flag.String("out", "-", "json report file, \"-\" for stdout")
+	)
+	flag.Parse()
+
+	if *scenarioFile == "" {
+		log.Crit("scenario must not be empty")
+		os.Exit(-1)
+	}
+	sc, err := loadScenario(*scenarioFile)
+	if err != nil {
+		log.Crit("failed to load scenario", "err", err)
+		os.Exit(-1)
+	}
+	if *seed != 0 {
+		sc.Seed = *seed
+	}
+
+	rpt := newSim(sc).run()
+	if err := writeReport(*output, rpt); err != nil {
+		log.Crit("failed to write report", "err", err)
+		os.Exit(-2)
+	}
+	if rpt.Summary.Failures > 0 {
+		os.Exit(1)
+	}
+}
+
+func writeReport(path string, rpt *simReport) error {
+	data, err := json.MarshalIndent(rpt, "", "  ")
+	if err != nil {
+		return err
+	}
+	if path == "" || path == "-" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,158 @@
+// Copyright (C) 2018 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseYaml(t *testing.T) {
+	doc := `
+name: "a # b"   # comment
+list:
+- x
+-   y: 1
+    z: [1, [2, 'it''s'], "3"]
+- - nested
+map:
+  k: v
+  empty:
+`
+	got, err := parseYaml([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name": "a # b",
+		"list": []interface{}{
+			"x",
+			map[string]interface{}{"y": "1", "z": []interface{}{"1", []interface{}{"2", "it's"}, "3"}},
+			[]interface{}{"nested"},
+		},
+		"map": map[string]interface{}{"k": "v", "empty": ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v", got)
+	}
+
+	for _, bad := range []string{"a: 1\n  b: 2", "a: [1, 2", "a: 1\na: 2", "a: {b: 1}", "- a\nb: 1"} {
+		if _, err := parseYaml([]byte(bad)); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+}
+
+func TestParseScenario(t *testing.T) {
+	for _, bad := range []string{
+		"nodes: 2\nactions:\n  - do: kill\n    node: 2",
+		"nodes: 2\nactions:\n  - do: jump",
+		"nodes: 2\nunknown: 1",
+		"nodes: 2\ntopology:\n  - block: [0, 1, 1]",
+		"nodes: 2\nactions:\n  - do: broadcast\n    expect: 2",
+	} {
+		if _, err := parseScenario([]byte(bad)); err == nil {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+
+	sc, err := parseScenario([]byte(`
+nodes: 4
+topology:
+  - partition: [[0, 1], [2, 3]]
+    to: 1s
+  - block: [0, 1]
+    from: 2s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		a, b int
+		t    time.Duration
+		want bool
+	}{
+		{0, 2, 0, false}, {0, 2, time.Second, true}, {0, 1, 0, true}, {1, 0, time.Second * 2, false}, {2, 3, 0, true},
+	} {
+		if got := sc.reachable(c.a, c.b, c.t); got != c.want {
+			t.Errorf("reachable(%d, %d, %s): %t", c.a, c.b, c.t, got)
+		}
+	}
+}
+
+func TestRunScenario(t *testing.T) {
+	sc, err := parseScenario([]byte(strings.Replace(`
+name: short
+nodes: 3
+grace: 300ms
+topology:
+  - block: [0, 2]
+actions:
+  - at: 10ms
+    node: 0
+    do: broadcast
+    count: 4
+    expect: 1
+  - at: 10ms
+    node: 2
+    do: provide
+    kind: block
+    key: "1"
+    value: b1
+  - at: 20ms
+    node: 1
+    do: kill
+  - at: 30ms
+    node: 0
+    do: sync
+    kind: block
+    key: "1"
+    expect: missing
+  - at: 30ms
+    node: 1
+    do: sync
+    kind: block
+    key: "1"
+  - at: 40ms
+    node: 1
+    do: restart
+  - at: 50ms
+    node: 1
+    do: sync
+    kind: block
+    key: "1"
+    expect: found
+`, "\t", "  ", -1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rpt := newSim(sc).run()
+	sum := rpt.Summary
+	if sum.Broadcasts != 4 || sum.Expected != 4 || sum.Delivered != 4 || sum.Blocked != 4 {
+		t.Errorf("summary got %+v", sum)
+	}
+	// the sync asked by node 1 while killed failed
+	if sum.Syncs != 2 || sum.SyncsFound != 1 || sum.Failures != 1 {
+		t.Errorf("summary got %+v", sum)
+	}
+	if len(rpt.Nodes) != 3 || !rpt.Nodes[1].Alive || rpt.Nodes[1].Kills != 1 || rpt.Nodes[1].Received != 4 {
+		t.Errorf("nodes got %+v", rpt.Nodes[1])
+	}
+}
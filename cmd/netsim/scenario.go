@@ -0,0 +1,419 @@
+// Copyright (C) 2018 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	actBroadcast = "broadcast" // broadcast messages of a type from the node
+	actPublish   = "publish"   // put a key and value to the dht from the node
+	actLookup    = "lookup"    // get a key from the dht at the node
+	actProvide   = "provide"   // the node provides chain data of a kind and key
+	actSync      = "sync"      // the node asks other nodes for chain data
+	actKill      = "kill"      // the node is stopped
+	actRestart   = "restart"   // the node killed is started again
+
+	expectFound   = "found"   // lookup or sync expected to be found
+	expectMissing = "missing" // lookup or sync expected to be missing
+
+	dftGrace   = time.Second // default time waited for deliveries after the last action
+	dftMsgType = "tx"        // default type of messages broadcast
+)
+
+// link is what the messages sent by a node go through
+type link struct {
+	Delay time.Duration // mean delay of messages
+	Loss  int           // percent of messages lost
+}
+
+// role names nodes, and the link of them if it's not the default one
+type role struct {
+	Name  string
+	Nodes []int
+	Link  *link
+}
+
+// constraint is a topology constraint, active in [From, To), To 0 for the end
+type constraint struct {
+	Partition [][]int // groups of nodes not reaching each other
+	Block     []int   // pair of nodes not reaching each other
+	From      time.Duration
+	To        time.Duration
+}
+
+type action struct {
+	At     time.Duration // time since the scenario started
+	Node   int           // node acting
+	Do     string        // actXXX
+	Type   string        // message type for actBroadcast
+	Count  int           // messages for actBroadcast
+	Key    string        // key for actPublish, actLookup, actProvide and actSync
+	Value  string        // value for actPublish and actProvide, or expected for actLookup
+	Kind   string        // chain data kind for actProvide and actSync
+	Expect string        // min delivery ratio for actBroadcast, expectXXX for actLookup and actSync
+}
+
+type scenario struct {
+	Name     string
+	Nodes    int
+	Duration time.Duration // min time the scenario run
+	Grace    time.Duration // time waited for deliveries after the last action
+	Seed     int64         // seed of the random source, 0 for seeding by time
+	Link     link          // default link of nodes
+	Roles    []role
+	Topology []constraint
+	Actions  []action // sorted by time
+}
+
+func loadScenario(path string) (*scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseScenario(data)
+}
+
+func parseScenario(data []byte) (*scenario, error) {
+	tree, err := parseYaml(data)
+	if err != nil {
+		return nil, err
+	}
+	top := newYamlFields(tree, "scenario")
+	sc := &scenario{
+		Name:     top.str("name", "netsim"),
+		Nodes:    top.int("nodes", 0),
+		Duration: top.dur("duration", 0),
+		Grace:    top.dur("grace", dftGrace),
+		Seed:     int64(top.int("seed", 0)),
+	}
+	if lf := top.fields("link"); lf != nil {
+		sc.Link = lf.link()
+		top.check(lf.done())
+	}
+	for i, v := range top.list("roles") {
+		rf := newYamlFields(v, fmt.Sprintf("roles[%d]", i))
+		r := role{Name: rf.str("name", ""), Nodes: rf.ints("nodes")}
+		if rf.has("delay") || rf.has("loss") {
+			l := rf.link()
+			r.Link = &l
+		}
+		top.check(rf.done())
+		sc.Roles = append(sc.Roles, r)
+	}
+	for i, v := range top.list("topology") {
+		cf := newYamlFields(v, fmt.Sprintf("topology[%d]", i))
+		c := constraint{From: cf.dur("from", 0), To: cf.dur("to", 0)}
+		for j, g := range cf.list("partition") {
+			c.Partition = append(c.Partition, cf.intsOf(g, fmt.Sprintf("partition[%d]", j)))
+		}
+		c.Block = cf.ints("block")
+		top.check(cf.done())
+		sc.Topology = append(sc.Topology, c)
+	}
+	for i, v := range top.list("actions") {
+		af := newYamlFields(v, fmt.Sprintf("actions[%d]", i))
+		a := action{
+			At:     af.dur("at", 0),
+			Node:   af.int("node", 0),
+			Do:     af.str("do", ""),
+			Type:   af.str("type", dftMsgType),
+			Count:  af.int("count", 1),
+			Key:    af.str("key", ""),
+			Value:  af.str("value", ""),
+			Kind:   af.str("kind", ""),
+			Expect: af.str("expect", ""),
+		}
+		top.check(af.done())
+		sc.Actions = append(sc.Actions, a)
+	}
+	if err := top.done(); err != nil {
+		return nil, err
+	}
+	sort.SliceStable(sc.Actions, func(i, j int) bool {
+		return sc.Actions[i].At < sc.Actions[j].At
+	})
+	return sc, sc.validate()
+}
+
+func (sc *scenario) validate() error {
+	if sc.Nodes <= 0 {
+		return fmt.Errorf("scenario: nodes must be positive")
+	}
+	node := func(what string, n int) error {
+		if n < 0 || n >= sc.Nodes {
+			return fmt.Errorf("%s: node %d out of range [0, %d)", what, n, sc.Nodes)
+		}
+		return nil
+	}
+	roled := make(map[int]string, 0)
+	for i, r := range sc.Roles {
+		if r.Name == "" {
+			return fmt.Errorf("roles[%d]: name missing", i)
+		}
+		for _, n := range r.Nodes {
+			if err := node(r.Name, n); err != nil {
+				return err
+			}
+			if other, dup := roled[n]; dup {
+				return fmt.Errorf("roles[%d]: node %d is of role %s already", i, n, other)
+			}
+			roled[n] = r.Name
+		}
+	}
+	for i, c := range sc.Topology {
+		what := fmt.Sprintf("topology[%d]", i)
+		if (len(c.Partition) == 0) == (len(c.Block) == 0) {
+			return fmt.Errorf("%s: one of partition and block expected", what)
+		}
+		if len(c.Block) != 0 && len(c.Block) != 2 {
+			return fmt.Errorf("%s: block is a pair of nodes", what)
+		}
+		if c.To != 0 && c.To <= c.From {
+			return fmt.Errorf("%s: to must be after from", what)
+		}
+		for _, g := range append([][]int{c.Block}, c.Partition...) {
+			for _, n := range g {
+				if err := node(what, n); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	for i, a := range sc.Actions {
+		what := fmt.Sprintf("actions[%d]", i)
+		if err := node(what, a.Node); err != nil {
+			return err
+		}
+		switch a.Do {
+		case actBroadcast:
+			if a.Count <= 0 {
+				return fmt.Errorf("%s: count must be positive", what)
+			}
+			if a.Expect != "" {
+				if r, err := strconv.ParseFloat(a.Expect, 64); err != nil || r < 0 || r > 1 {
+					return fmt.Errorf("%s: expect must be a ratio in [0, 1]", what)
+				}
+			}
+		case actPublish, actLookup, actProvide, actSync:
+			if a.Key == "" {
+				return fmt.Errorf("%s: key missing", what)
+			}
+			if (a.Do == actProvide || a.Do == actSync) && a.Kind == "" {
+				return fmt.Errorf("%s: kind missing", what)
+			}
+			if (a.Do == actLookup || a.Do == actSync) && a.Expect != "" && a.Expect != expectFound && a.Expect != expectMissing {
+				return fmt.Errorf("%s: expect must be %s or %s", what, expectFound, expectMissing)
+			}
+		case actKill, actRestart:
+		default:
+			return fmt.Errorf("%s: unknown action %q", what, a.Do)
+		}
+	}
+	return nil
+}
+
+// role of node, nil if it's of no role
+func (sc *scenario) role(n int) *role {
+	for i := range sc.Roles {
+		for _, m := range sc.Roles[i].Nodes {
+			if m == n {
+				return &sc.Roles[i]
+			}
+		}
+	}
+	return nil
+}
+
+// check if messages from node a reach node b at time t
+func (sc *scenario) reachable(a, b int, t time.Duration) bool {
+	for _, c := range sc.Topology {
+		if t < c.From || (c.To != 0 && t >= c.To) {
+			continue
+		}
+		if len(c.Block) == 2 && (c.Block[0] == a && c.Block[1] == b || c.Block[0] == b && c.Block[1] == a) {
+			return false
+		}
+		ga, gb := -1, -1
+		for i, g := range c.Partition {
+			for _, n := range g {
+				if n == a {
+					ga = i
+				}
+				if n == b {
+					gb = i
+				}
+			}
+		}
+		if ga >= 0 && gb >= 0 && ga != gb {
+			return false
+		}
+	}
+	return true
+}
+
+// yamlFields decodes a yaml mapping into fields, the first error kept, and
+// keys not decoded are reported by done.
+type yamlFields struct {
+	m    map[string]interface{}
+	path string
+	used map[string]bool
+	err  error
+}
+
+func newYamlFields(v interface{}, path string) *yamlFields {
+	f := &yamlFields{path: path, used: make(map[string]bool, 0)}
+	if m, ok := v.(map[string]interface{}); ok {
+		f.m = m
+	} else {
+		f.err = fmt.Errorf("%s: mapping expected", path)
+	}
+	return f
+}
+
+func (f *yamlFields) check(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *yamlFields) has(key string) bool {
+	_, ok := f.m[key]
+	return ok
+}
+
+func (f *yamlFields) get(key string) (interface{}, bool) {
+	v, ok := f.m[key]
+	f.used[key] = true
+	return v, ok
+}
+
+func (f *yamlFields) scalar(key string) (string, bool) {
+	v, ok := f.get(key)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	if !ok {
+		f.check(fmt.Errorf("%s.%s: scalar expected", f.path, key))
+	}
+	return s, ok
+}
+
+func (f *yamlFields) str(key string, def string) string {
+	if s, ok := f.scalar(key); ok {
+		return s
+	}
+	return def
+}
+
+func (f *yamlFields) int(key string, def int) int {
+	s, ok := f.scalar(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		f.check(fmt.Errorf("%s.%s: integer expected", f.path, key))
+	}
+	return n
+}
+
+func (f *yamlFields) dur(key string, def time.Duration) time.Duration {
+	s, ok := f.scalar(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		f.check(fmt.Errorf("%s.%s: duration expected", f.path, key))
+	}
+	return d
+}
+
+func (f *yamlFields) list(key string) []interface{} {
+	v, ok := f.get(key)
+	if !ok || v == "" {
+		return nil
+	}
+	l, ok := v.([]interface{})
+	if !ok {
+		f.check(fmt.Errorf("%s.%s: sequence expected", f.path, key))
+	}
+	return l
+}
+
+func (f *yamlFields) intsOf(v interface{}, path string) []int {
+	l, ok := v.([]interface{})
+	if !ok {
+		f.check(fmt.Errorf("%s.%s: sequence expected", f.path, path))
+		return nil
+	}
+	ns := make([]int, 0, len(l))
+	for _, e := range l {
+		s, _ := e.(string)
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			f.check(fmt.Errorf("%s.%s: integers expected", f.path, path))
+			return nil
+		}
+		ns = append(ns, n)
+	}
+	return ns
+}
+
+func (f *yamlFields) ints(key string) []int {
+	v, ok := f.get(key)
+	if !ok {
+		return nil
+	}
+	return f.intsOf(v, key)
+}
+
+func (f *yamlFields) fields(key string) *yamlFields {
+	v, ok := f.get(key)
+	if !ok {
+		return nil
+	}
+	return newYamlFields(v, f.path+"."+key)
+}
+
+func (f *yamlFields) link() link {
+	l := link{Delay: f.dur("delay", 0), Loss: f.int("loss", 0)}
+	if l.Loss < 0 || l.Loss > 100 {
+		f.check(fmt.Errorf("%s.loss: percent expected", f.path))
+	}
+	return l
+}
+
+func (f *yamlFields) done() error {
+	if f.err != nil {
+		return f.err
+	}
+	for key := range f.m {
+		if !f.used[key] {
+			return fmt.Errorf("%s: unknown key %q", f.path, key)
+		}
+	}
+	return nil
+}
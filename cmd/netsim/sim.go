@@ -0,0 +1,452 @@
+// Copyright (C) 2018 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/yeeco/gyee/p2p"
+)
+
+// The simulator runs the nodes of a scenario in process, each an InmemService
+// on the shared InmemHub. the link of a node, delay and loss, is applied by the
+// hub to messages the node sends; the topology is applied when messages are
+// received, those from nodes not reachable are counted as blocked but not
+// delivered, and to chain data asked, nodes not reachable do not answer. the
+// dht of the hub is one store shared by all nodes, so lookups are not subject
+// to the topology, and values published by a node are kept after it's killed.
+
+type simNode struct {
+	idx      int
+	role     string
+	link     link
+	svc      *p2p.InmemService // nil if killed
+	done     chan struct{}     // closed when the node killed, to stop receiving
+	chain    map[string][]byte // chain data provided by kind and key
+	received int               // messages received
+	kills    int               // times killed
+}
+
+// simChain provides the chain data of a node
+type simChain struct {
+	s *sim
+	n *simNode
+}
+
+// message broadcast, tracked for deliveries
+type simMsg struct {
+	rec      *deliveryRecord
+	sent     time.Time
+	expected map[int]bool // nodes the message expected to be delivered to
+	got      map[int]bool // nodes the message delivered to
+}
+
+type sim struct {
+	sc      *scenario
+	types   []string // message types broadcast
+	lock    sync.Mutex
+	started time.Time
+	nodes   []*simNode
+	asking  int             // node asking for chain data, see simChain
+	msgs    map[int]*simMsg // messages broadcast by sequence
+	report  *simReport
+}
+
+type nodeReport struct {
+	Node     int    `json:"node"`
+	Role     string `json:"role,omitempty"`
+	Alive    bool   `json:"alive"`
+	Kills    int    `json:"kills"`
+	Received int    `json:"received"`
+}
+
+type eventRecord struct {
+	At     string `json:"at"`
+	Node   int    `json:"node"`
+	Action string `json:"action"`
+	Key    string `json:"key,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type deliveryRecord struct {
+	At           string  `json:"at"`
+	Node         int     `json:"node"`
+	Type         string  `json:"type"`
+	Messages     int     `json:"messages"`
+	Expected     int     `json:"expected"`
+	Delivered    int     `json:"delivered"`
+	Blocked      int     `json:"blocked"`
+	Ratio        float64 `json:"ratio"`
+	MaxLatencyMs float64 `json:"maxLatencyMs"`
+	Expect       string  `json:"expect,omitempty"`
+	Failed       bool    `json:"failed,omitempty"`
+}
+
+type lookupRecord struct {
+	At     string `json:"at"`
+	Node   int    `json:"node"`
+	Key    string `json:"key"`
+	Found  bool   `json:"found"`
+	Value  string `json:"value,omitempty"`
+	Want   string `json:"want,omitempty"`
+	Expect string `json:"expect,omitempty"`
+	Failed bool   `json:"failed,omitempty"`
+}
+
+type syncRecord struct {
+	At     string `json:"at"`
+	Node   int    `json:"node"`
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+	Found  bool   `json:"found"`
+	Value  string `json:"value,omitempty"`
+	Expect string `json:"expect,omitempty"`
+	Failed bool   `json:"failed,omitempty"`
+}
+
+type simSummary struct {
+	Broadcasts    int     `json:"broadcasts"`
+	Expected      int     `json:"expected"`
+	Delivered     int     `json:"delivered"`
+	Blocked       int     `json:"blocked"`
+	DeliveryRatio float64 `json:"deliveryRatio"`
+	Lookups       int     `json:"lookups"`
+	LookupsFound  int     `json:"lookupsFound"`
+	Syncs         int     `json:"syncs"`
+	SyncsFound    int     `json:"syncsFound"`
+	Failures      int     `json:"failures"`
+}
+
+// simReport is what written to the output file.
+type simReport struct {
+	Scenario  string            `json:"scenario"`
+	Seed      int64             `json:"seed"`
+	Started   time.Time         `json:"started"`
+	Finished  time.Time         `json:"finished"`
+	Summary   simSummary        `json:"summary"`
+	Nodes     []*nodeReport     `json:"nodes"`
+	Events    []*eventRecord    `json:"events"`
+	Delivery  []*deliveryRecord `json:"delivery"`
+	Discovery []*lookupRecord   `json:"discovery"`
+	Sync      []*syncRecord     `json:"sync"`
+}
+
+func newSim(sc *scenario) *sim {
+	s := &sim{
+		sc:   sc,
+		msgs: make(map[int]*simMsg, 0),
+		report: &simReport{
+			Scenario:  sc.Name,
+			Seed:      sc.Seed,
+			Nodes:     make([]*nodeReport, 0, sc.Nodes),
+			Events:    make([]*eventRecord, 0),
+			Delivery:  make([]*deliveryRecord, 0),
+			Discovery: make([]*lookupRecord, 0),
+			Sync:      make([]*syncRecord, 0),
+		},
+	}
+	types := make(map[string]bool, 0)
+	for _, a := range sc.Actions {
+		if a.Do == actBroadcast && !types[a.Type] {
+			types[a.Type] = true
+			s.types = append(s.types, a.Type)
+		}
+	}
+	for i := 0; i < sc.Nodes; i++ {
+		n := &simNode{idx: i, link: sc.Link, chain: make(map[string][]byte, 0)}
+		if r := sc.role(i); r != nil {
+			n.role = r.Name
+			if r.Link != nil {
+				n.link = *r.Link
+			}
+		}
+		s.nodes = append(s.nodes, n)
+	}
+	return s
+}
+
+// run the scenario, the report returned
+func (s *sim) run() *simReport {
+	if s.sc.Seed != 0 {
+		rand.Seed(s.sc.Seed)
+	} else {
+		rand.Seed(time.Now().UnixNano())
+	}
+	s.started = time.Now()
+	s.report.Started = s.started
+	for _, n := range s.nodes {
+		s.start(n)
+	}
+	for _, a := range s.sc.Actions {
+		s.wait(a.At)
+		s.act(a)
+	}
+	s.wait(s.sc.Duration)
+	time.Sleep(s.sc.Grace)
+	s.finish()
+	for _, n := range s.nodes {
+		if n.svc != nil {
+			s.stop(n)
+		}
+	}
+	return s.report
+}
+
+func (s *sim) wait(at time.Duration) {
+	if d := time.Until(s.started.Add(at)); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+func (s *sim) start(n *simNode) {
+	svc, _ := p2p.NewInmemService()
+	svc.SetOutLink(int(n.link.Delay/time.Millisecond), n.link.Loss)
+	svc.RegChainProvider(&simChain{s: s, n: n})
+	ch := make(chan p2p.Message, 64)
+	for _, t := range s.types {
+		svc.Register(p2p.NewSubscriber(n, ch, t))
+	}
+	svc.Start()
+	s.lock.Lock()
+	n.svc = svc
+	n.done = make(chan struct{})
+	s.lock.Unlock()
+	go s.receive(n, ch, n.done)
+}
+
+func (s *sim) stop(n *simNode) {
+	// the receiver is kept till the service stopped, since the service might be
+	// blocked in delivering to it
+	n.svc.Stop()
+	s.lock.Lock()
+	n.svc = nil
+	close(n.done)
+	s.lock.Unlock()
+}
+
+func (s *sim) receive(n *simNode, ch chan p2p.Message, done chan struct{}) {
+	for {
+		select {
+		case m := <-ch:
+			s.delivered(n, m)
+		case <-done:
+			return
+		}
+	}
+}
+
+func (s *sim) delivered(n *simNode, m p2p.Message) {
+	from, err := strconv.Atoi(m.From)
+	if err != nil {
+		return
+	}
+	seq, err := strconv.Atoi(string(m.Key))
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n.received++
+	sm, ok := s.msgs[seq]
+	if !ok {
+		return
+	}
+	if !s.sc.reachable(from, n.idx, now.Sub(s.started)) {
+		sm.rec.Blocked++
+		return
+	}
+	if !sm.expected[n.idx] || sm.got[n.idx] {
+		return
+	}
+	sm.got[n.idx] = true
+	sm.rec.Delivered++
+	if ms := float64(now.Sub(sm.sent)) / float64(time.Millisecond); ms > sm.rec.MaxLatencyMs {
+		sm.rec.MaxLatencyMs = ms
+	}
+}
+
+func (c *simChain) GetChainData(kind string, key []byte) []byte {
+	c.s.lock.Lock()
+	defer c.s.lock.Unlock()
+	if !c.s.sc.reachable(c.s.asking, c.n.idx, time.Since(c.s.started)) {
+		return nil
+	}
+	return c.n.chain[kind+"/"+string(key)]
+}
+
+func (s *sim) event(a action, err error) {
+	ev := &eventRecord{At: a.At.String(), Node: a.Node, Action: a.Do, Key: a.Key}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	s.lock.Lock()
+	s.report.Events = append(s.report.Events, ev)
+	s.lock.Unlock()
+}
+
+func (s *sim) act(a action) {
+	n := s.nodes[a.Node]
+	if (n.svc == nil) != (a.Do == actRestart) {
+		if n.svc == nil {
+			s.event(a, fmt.Errorf("node killed"))
+		} else {
+			s.event(a, fmt.Errorf("node alive"))
+		}
+		return
+	}
+	switch a.Do {
+	case actBroadcast:
+		s.broadcast(n, a)
+	case actPublish:
+		s.event(a, n.svc.DhtSetValue([]byte(a.Key), []byte(a.Value)))
+	case actLookup:
+		s.lookup(n, a)
+	case actProvide:
+		s.lock.Lock()
+		n.chain[a.Kind+"/"+a.Key] = []byte(a.Value)
+		s.lock.Unlock()
+		s.event(a, nil)
+	case actSync:
+		s.sync(n, a)
+	case actKill:
+		s.stop(n)
+		n.kills++
+		s.event(a, nil)
+	case actRestart:
+		s.start(n)
+		s.event(a, nil)
+	}
+}
+
+func (s *sim) broadcast(n *simNode, a action) {
+	rec := &deliveryRecord{At: a.At.String(), Node: n.idx, Type: a.Type, Messages: a.Count, Expect: a.Expect}
+	s.lock.Lock()
+	s.report.Delivery = append(s.report.Delivery, rec)
+	s.lock.Unlock()
+	for i := 0; i < a.Count; i++ {
+		s.lock.Lock()
+		now := time.Now()
+		sm := &simMsg{rec: rec, sent: now, expected: make(map[int]bool, 0), got: make(map[int]bool, 0)}
+		for _, to := range s.nodes {
+			if to != n && to.svc != nil && s.sc.reachable(n.idx, to.idx, now.Sub(s.started)) {
+				sm.expected[to.idx] = true
+			}
+		}
+		rec.Expected += len(sm.expected)
+		seq := len(s.msgs)
+		s.msgs[seq] = sm
+		s.lock.Unlock()
+		n.svc.BroadcastMessage(p2p.Message{
+			MsgType: a.Type,
+			From:    strconv.Itoa(n.idx),
+			Key:     []byte(strconv.Itoa(seq)),
+		})
+	}
+}
+
+func (s *sim) lookup(n *simNode, a action) {
+	rec := &lookupRecord{At: a.At.String(), Node: n.idx, Key: a.Key, Want: a.Value, Expect: a.Expect}
+	if v, err := n.svc.DhtGetValue([]byte(a.Key)); err == nil {
+		rec.Found, rec.Value = true, string(v)
+	}
+	rec.Failed = a.Expect == expectFound && !rec.Found || a.Expect == expectMissing && rec.Found ||
+		rec.Found && a.Value != "" && rec.Value != a.Value
+	s.lock.Lock()
+	s.report.Discovery = append(s.report.Discovery, rec)
+	s.lock.Unlock()
+}
+
+func (s *sim) sync(n *simNode, a action) {
+	rec := &syncRecord{At: a.At.String(), Node: n.idx, Kind: a.Kind, Key: a.Key, Expect: a.Expect}
+	s.lock.Lock()
+	s.asking = n.idx
+	s.lock.Unlock()
+	if v, err := n.svc.GetChainInfo(a.Kind, []byte(a.Key)); err == nil {
+		rec.Found, rec.Value = true, string(v)
+	}
+	rec.Failed = a.Expect == expectFound && !rec.Found || a.Expect == expectMissing && rec.Found
+	s.lock.Lock()
+	s.report.Sync = append(s.report.Sync, rec)
+	s.lock.Unlock()
+}
+
+func (s *sim) finish() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	rpt := s.report
+	sum := &rpt.Summary
+	for _, n := range s.nodes {
+		rpt.Nodes = append(rpt.Nodes, &nodeReport{
+			Node:     n.idx,
+			Role:     n.role,
+			Alive:    n.svc != nil,
+			Kills:    n.kills,
+			Received: n.received,
+		})
+	}
+	for _, ev := range rpt.Events {
+		if ev.Error != "" {
+			sum.Failures++
+		}
+	}
+	for _, rec := range rpt.Delivery {
+		rec.Ratio = 1
+		if rec.Expected > 0 {
+			rec.Ratio = float64(rec.Delivered) / float64(rec.Expected)
+		}
+		if rec.Expect != "" {
+			min, _ := strconv.ParseFloat(rec.Expect, 64)
+			rec.Failed = rec.Ratio < min
+		}
+		sum.Broadcasts += rec.Messages
+		sum.Expected += rec.Expected
+		sum.Delivered += rec.Delivered
+		sum.Blocked += rec.Blocked
+		if rec.Failed {
+			sum.Failures++
+		}
+	}
+	sum.DeliveryRatio = 1
+	if sum.Expected > 0 {
+		sum.DeliveryRatio = float64(sum.Delivered) / float64(sum.Expected)
+	}
+	for _, rec := range rpt.Discovery {
+		sum.Lookups++
+		if rec.Found {
+			sum.LookupsFound++
+		}
+		if rec.Failed {
+			sum.Failures++
+		}
+	}
+	for _, rec := range rpt.Sync {
+		sum.Syncs++
+		if rec.Found {
+			sum.SyncsFound++
+		}
+		if rec.Failed {
+			sum.Failures++
+		}
+	}
+	rpt.Finished = time.Now()
+}
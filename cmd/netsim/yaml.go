@@ -0,0 +1,279 @@
+// Copyright (C) 2018 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The subset of yaml scenarios are written in: block mappings and sequences
+// by indentation, flow sequences like [1, [2, 3]], plain and quoted scalars,
+// and comments. values parsed are map[string]interface{}, []interface{} or
+// string, scalars are converted by the decoder as the field expects.
+
+type yamlLine struct {
+	num    int    // line number, from 1
+	indent int    // spaces the line indented
+	text   string // content, comment stripped
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func parseYaml(data []byte) (interface{}, error) {
+	p := &yamlParser{}
+	for n, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("line %d: tab in indentation", n+1)
+		}
+		text = strings.TrimRight(yamlStripComment(text), " \t")
+		if len(text) == 0 {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{num: n + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text})
+	}
+	if len(p.lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	v, err := p.block(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return v, nil
+}
+
+// comment starts at a '#' at the beginning or after a space, out of quotes
+func yamlStripComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+	return text
+}
+
+func yamlIsItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// split "key: value", ok is false if it's not a mapping entry
+func yamlSplitKey(text string) (key string, value string, ok bool) {
+	if len(text) == 0 || strings.IndexByte("[{\"'", text[0]) >= 0 {
+		return "", "", false
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), i > 0
+		}
+	}
+	return "", "", false
+}
+
+func (p *yamlParser) block(indent int) (interface{}, error) {
+	l := p.lines[p.pos]
+	if yamlIsItem(l.text) {
+		return p.sequence(indent)
+	}
+	if _, _, ok := yamlSplitKey(l.text); ok {
+		return p.mapping(indent)
+	}
+	p.pos++
+	return yamlScalar(l.text, l.num)
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	seq := make([]interface{}, 0)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && yamlIsItem(p.lines[p.pos].text) {
+		l := p.lines[p.pos]
+		rest := strings.TrimLeft(l.text[1:], " ")
+		if len(rest) == 0 {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				item, err := p.block(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				seq = append(seq, item)
+			} else {
+				seq = append(seq, "")
+			}
+			continue
+		}
+		// the content after "- " is taken as a block indented to where it starts,
+		// so the other keys of a mapping item are aligned with the first one.
+		p.lines[p.pos] = yamlLine{num: l.num, indent: indent + len(l.text) - len(rest), text: rest}
+		item, err := p.block(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		seq = append(seq, item)
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return seq, nil
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := make(map[string]interface{}, 0)
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && !yamlIsItem(p.lines[p.pos].text) {
+		l := p.lines[p.pos]
+		key, rest, ok := yamlSplitKey(l.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: \"key: value\" expected", l.num)
+		}
+		if _, dup := m[key]; dup {
+			return nil, fmt.Errorf("line %d: key %q duplicated", l.num, key)
+		}
+		p.pos++
+		var v interface{} = ""
+		var err error
+		switch {
+		case len(rest) > 0:
+			v, err = yamlScalar(rest, l.num)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			v, err = p.block(p.lines[p.pos].indent)
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && yamlIsItem(p.lines[p.pos].text):
+			v, err = p.sequence(indent)
+		}
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].num)
+	}
+	return m, nil
+}
+
+func yamlScalar(text string, num int) (interface{}, error) {
+	switch text[0] {
+	case '[':
+		v, n, err := yamlFlow(text, 0)
+		if err == nil && strings.TrimSpace(text[n:]) != "" {
+			err = fmt.Errorf("trailing %q", text[n:])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", num, err.Error())
+		}
+		return v, nil
+	case '{', '&', '*', '|', '>', '!':
+		return nil, fmt.Errorf("line %d: %q not supported", num, text[0])
+	case '"', '\'':
+		s, n, err := yamlQuoted(text, 0)
+		if err == nil && n != len(text) {
+			err = fmt.Errorf("trailing %q", text[n:])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", num, err.Error())
+		}
+		return s, nil
+	}
+	return text, nil
+}
+
+// quoted string started at text[i], the index after it returned
+func yamlQuoted(text string, i int) (string, int, error) {
+	quote := text[i]
+	for j := i + 1; j < len(text); j++ {
+		if quote == '"' && text[j] == '\\' {
+			j++
+			continue
+		}
+		if text[j] != quote {
+			continue
+		}
+		if quote == '\'' && j+1 < len(text) && text[j+1] == '\'' {
+			j++
+			continue
+		}
+		if quote == '"' {
+			s, err := strconv.Unquote(text[i : j+1])
+			return s, j + 1, err
+		}
+		return strings.Replace(text[i+1:j], "''", "'", -1), j + 1, nil
+	}
+	return "", 0, fmt.Errorf("unterminated %c", quote)
+}
+
+// flow sequence started at text[i], the index after it returned
+func yamlFlow(text string, i int) (interface{}, int, error) {
+	seq := make([]interface{}, 0)
+	i++
+	for {
+		for i < len(text) && text[i] == ' ' {
+			i++
+		}
+		if i >= len(text) {
+			return nil, 0, fmt.Errorf("unterminated [")
+		}
+		if text[i] == ']' && len(seq) == 0 {
+			return seq, i + 1, nil
+		}
+		var item interface{}
+		var err error
+		switch text[i] {
+		case '[':
+			item, i, err = yamlFlow(text, i)
+		case '"', '\'':
+			item, i, err = yamlQuoted(text, i)
+		default:
+			j := i
+			for j < len(text) && text[j] != ',' && text[j] != ']' {
+				j++
+			}
+			item, i = strings.TrimSpace(text[i:j]), j
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		seq = append(seq, item)
+		for i < len(text) && text[i] == ' ' {
+			i++
+		}
+		if i >= len(text) {
+			return nil, 0, fmt.Errorf("unterminated [")
+		}
+		if text[i] == ']' {
+			return seq, i + 1, nil
+		}
+		if text[i] != ',' {
+			return nil, 0, fmt.Errorf("',' or ']' expected at %q", text[i:])
+		}
+		i++
+	}
+}
@@ -0,0 +1,168 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package hexutil encodes bytes and quantities as 0x-prefixed hex text, the
+// text form of fixed-size types in JSON, RPC and config.
+//
+// Decoding takes the 0x prefix as optional, since hashes and addresses are
+// printed without it elsewhere, but rejects anything else not a valid hex.
+package hexutil
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrEmptyString = errors.New("hexutil: empty string")
+	ErrSyntax      = errors.New("hexutil: invalid hex string")
+	ErrOddLength   = errors.New("hexutil: hex string of odd length")
+	ErrLength      = errors.New("hexutil: hex string of wrong length")
+	ErrNumber      = errors.New("hexutil: invalid number")
+	ErrNegative    = errors.New("hexutil: negative number")
+	ErrBig256Range = errors.New("hexutil: number exceeds 256 bits")
+)
+
+// Encode b as 0x-prefixed hex
+func Encode(b []byte) string {
+	enc := make([]byte, len(b)*2+2)
+	copy(enc, "0x")
+	hex.Encode(enc[2:], b)
+	return string(enc)
+}
+
+// Decode hex string, with or without 0x prefix
+func Decode(s string) ([]byte, error) {
+	s = trimPrefix(s)
+	if len(s)%2 == 1 {
+		return nil, ErrOddLength
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, ErrSyntax
+	}
+	return b, nil
+}
+
+// DecodeFixed decodes hex string of exactly len(out) bytes into out
+func DecodeFixed(s string, out []byte) error {
+	b, err := Decode(s)
+	if err != nil {
+		return err
+	}
+	if len(b) != len(out) {
+		return ErrLength
+	}
+	copy(out, b)
+	return nil
+}
+
+// EncodeBig encodes a quantity as 0x-prefixed hex without leading zeros,
+// "0x0" for zero
+func EncodeBig(v *big.Int) string {
+	if v.Sign() < 0 {
+		return "-0x" + v.Text(16)[1:]
+	}
+	return "0x" + v.Text(16)
+}
+
+// ParseBig parses a non-negative quantity of at most 256 bits, in decimal or
+// 0x-prefixed hex, as amounts typed by users or written in config
+func ParseBig(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, ErrEmptyString
+	}
+	if strings.HasPrefix(s, "-") {
+		return nil, ErrNegative
+	}
+	base := 10
+	if hasPrefix(s) {
+		s, base = s[2:], 16
+	}
+	if s == "" || strings.HasPrefix(s, "+") {
+		return nil, ErrNumber
+	}
+	v, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return nil, ErrNumber
+	}
+	if v.BitLen() > 256 {
+		return nil, ErrBig256Range
+	}
+	return v, nil
+}
+
+// Bytes marshals as 0x-prefixed hex text
+type Bytes []byte
+
+func (b Bytes) MarshalText() ([]byte, error) {
+	return []byte(Encode(b)), nil
+}
+
+func (b *Bytes) UnmarshalText(input []byte) error {
+	dec, err := Decode(string(input))
+	if err != nil {
+		return err
+	}
+	*b = dec
+	return nil
+}
+
+func (b Bytes) String() string {
+	return Encode(b)
+}
+
+// Big marshals as 0x-prefixed hex quantity, and unmarshals from decimal too
+type Big big.Int
+
+func (b Big) MarshalText() ([]byte, error) {
+	return []byte(EncodeBig((*big.Int)(&b))), nil
+}
+
+func (b *Big) UnmarshalText(input []byte) error {
+	v, err := ParseBig(string(input))
+	if err != nil {
+		return err
+	}
+	(*big.Int)(b).Set(v)
+	return nil
+}
+
+// ToInt converts b to a big.Int
+func (b *Big) ToInt() *big.Int {
+	return (*big.Int)(b)
+}
+
+func (b *Big) String() string {
+	return EncodeBig(b.ToInt())
+}
+
+func hasPrefix(s string) bool {
+	return len(s) >= 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X')
+}
+
+func trimPrefix(s string) string {
+	if hasPrefix(s) {
+		return s[2:]
+	}
+	return s
+}
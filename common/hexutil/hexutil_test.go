@@ -0,0 +1,114 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hexutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []byte
+		err   error
+	}{
+		{"", []byte{}, nil},
+		{"0x", []byte{}, nil},
+		{"0x01ff", []byte{0x01, 0xff}, nil},
+		{"01FF", []byte{0x01, 0xff}, nil},
+		{"0x1", nil, ErrOddLength},
+		{"0xzz", nil, ErrSyntax},
+		{"0x0x01", nil, ErrSyntax},
+	}
+	for _, test := range tests {
+		got, err := Decode(test.input)
+		if err != test.err || !bytes.Equal(got, test.want) {
+			t.Errorf("Decode(%q) got %x %v, want %x %v", test.input, got, err, test.want, test.err)
+		}
+	}
+	if Encode([]byte{0x01, 0xff}) != "0x01ff" {
+		t.Errorf("Encode() got %s", Encode([]byte{0x01, 0xff}))
+	}
+
+	var out [2]byte
+	if err := DecodeFixed("0x0102", out[:]); err != nil || out != [2]byte{1, 2} {
+		t.Errorf("DecodeFixed() got %x %v", out, err)
+	}
+	if err := DecodeFixed("010203", out[:]); err != ErrLength || out != [2]byte{1, 2} {
+		t.Errorf("DecodeFixed() of long input got %x %v", out, err)
+	}
+}
+
+func TestParseBig(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"0", "0", nil},
+		{"1000000000000000000", "1000000000000000000", nil},
+		{"0x10", "16", nil},
+		{"0XfF", "255", nil},
+		{"", "", ErrEmptyString},
+		{"0x", "", ErrNumber},
+		{"-1", "", ErrNegative},
+		{"+1", "", ErrNumber},
+		{"1e3", "", ErrNumber},
+		{"0o17", "", ErrNumber},
+		{"0x1" + string(bytes.Repeat([]byte{'0'}, 64)), "", ErrBig256Range},
+	}
+	for _, test := range tests {
+		got, err := ParseBig(test.input)
+		if err != test.err || (err == nil && got.String() != test.want) {
+			t.Errorf("ParseBig(%q) got %v %v, want %s %v", test.input, got, err, test.want, test.err)
+		}
+	}
+	if s := EncodeBig(big.NewInt(0)); s != "0x0" {
+		t.Errorf("EncodeBig(0) got %s", s)
+	}
+	if s := EncodeBig(big.NewInt(255)); s != "0xff" {
+		t.Errorf("EncodeBig(255) got %s", s)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	type obj struct {
+		Data  Bytes `json:"data"`
+		Value *Big  `json:"value"`
+	}
+	enc, err := json.Marshal(obj{Data: Bytes{0xab}, Value: (*Big)(big.NewInt(4096))})
+	if err != nil || string(enc) != `{"data":"0xab","value":"0x1000"}` {
+		t.Fatalf("json.Marshal() got %s %v", enc, err)
+	}
+	var dec obj
+	if err := json.Unmarshal([]byte(`{"data":"ab","value":"100"}`), &dec); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dec.Data, []byte{0xab}) || dec.Value.ToInt().Int64() != 100 {
+		t.Errorf("json.Unmarshal() got %x %v", dec.Data, dec.Value)
+	}
+	if err := json.Unmarshal([]byte(`{"data":"0xa"}`), &dec); err == nil {
+		t.Errorf("json.Unmarshal() of odd hex no error")
+	}
+}
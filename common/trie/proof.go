@@ -0,0 +1,225 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/log"
+)
+
+// ProofSet is a set of trie nodes keyed by their hashes, proving one or more
+// keys of a trie. Nodes shared by the paths of several keys are kept once, so
+// proving keys into the same set builds a multiproof.
+type ProofSet struct {
+	nodes map[common.Hash][]byte
+	order []common.Hash
+}
+
+// NewProofSet creates an empty proof set.
+func NewProofSet() *ProofSet {
+	return &ProofSet{nodes: make(map[common.Hash][]byte)}
+}
+
+// NewProofSetFromList creates a proof set of encoded nodes, e.g. received from
+// a peer, keyed by their hashes computed locally.
+func NewProofSetFromList(nodes [][]byte) *ProofSet {
+	h := newHasher(0, 0, nil)
+	defer returnHasherToPool(h)
+
+	ps := NewProofSet()
+	for _, enc := range nodes {
+		ps.put(common.BytesToHash(h.makeHashNode(enc)), enc)
+	}
+	return ps
+}
+
+func (ps *ProofSet) put(hash common.Hash, enc []byte) {
+	if _, ok := ps.nodes[hash]; ok {
+		return
+	}
+	ps.nodes[hash] = common.CopyBytes(enc)
+	ps.order = append(ps.order, hash)
+}
+
+// Get retrieves the encoded node of a hash.
+func (ps *ProofSet) Get(key []byte) ([]byte, error) {
+	if enc, ok := ps.nodes[common.BytesToHash(key)]; ok {
+		return enc, nil
+	}
+	return nil, fmt.Errorf("proof node %x missing", key)
+}
+
+// Has retrieves whether the node of a hash is in the set.
+func (ps *ProofSet) Has(key []byte) (bool, error) {
+	_, ok := ps.nodes[common.BytesToHash(key)]
+	return ok, nil
+}
+
+// Len returns the number of nodes in the set.
+func (ps *ProofSet) Len() int {
+	return len(ps.nodes)
+}
+
+// List returns the encoded nodes in the order they were added, to be sent
+// to a peer and restored by NewProofSetFromList.
+func (ps *ProofSet) List() [][]byte {
+	list := make([][]byte, 0, len(ps.order))
+	for _, hash := range ps.order {
+		list = append(list, ps.nodes[hash])
+	}
+	return list
+}
+
+// Prove adds the nodes on the path of key to proof. The path is walked as
+// far as the trie goes, so a key not in the trie gets a proof of absence.
+// Nodes embedded in their parents are not added separately, the root node
+// always is.
+func (t *Trie) Prove(key []byte, proof *ProofSet) error {
+	// Collect all nodes on the path to key.
+	key = keybytesToHex(key)
+	var nodes []node
+	tn := t.root
+	for len(key) > 0 && tn != nil {
+		switch n := tn.(type) {
+		case *shortNode:
+			if len(key) < len(n.Key) || !bytes.Equal(n.Key, key[:len(n.Key)]) {
+				// The trie doesn't contain the key.
+				tn = nil
+			} else {
+				tn = n.Val
+				key = key[len(n.Key):]
+			}
+			nodes = append(nodes, n)
+		case *fullNode:
+			tn = n.Children[key[0]]
+			key = key[1:]
+			nodes = append(nodes, n)
+		case hashNode:
+			var err error
+			tn, err = t.resolveHash(n, nil)
+			if err != nil {
+				log.Error(fmt.Sprintf("Unhandled trie error: %v", err))
+				return err
+			}
+		case valueNode:
+			tn = nil
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", tn, tn))
+		}
+	}
+	hasher := newHasher(0, 0, nil)
+	defer returnHasherToPool(hasher)
+
+	for i, n := range nodes {
+		// Don't bother checking for errors here since hasher panics
+		// if encoding doesn't work and we're not writing to any database.
+		n, _, _ = hasher.hashChildren(n, nil)
+		hn, _ := hasher.store(n, nil, false)
+		if hash, ok := hn.(hashNode); ok || i == 0 {
+			// If the node's database encoding is a hash (or is the
+			// root node), it becomes a proof element.
+			enc, _ := rlp.EncodeToBytes(n)
+			if !ok {
+				hash = hasher.makeHashNode(enc)
+			}
+			proof.put(common.BytesToHash(hash), enc)
+		}
+	}
+	return nil
+}
+
+// VerifyProof checks a proof of key against the root hash of a trie, and
+// returns the value of key. A nil value with nil error means the proof shows
+// key is not in the trie. An error is returned if the proof lacks a node on
+// the path of key or a node is malformed.
+func VerifyProof(rootHash common.Hash, key []byte, proof DatabaseReader) ([]byte, error) {
+	if rootHash == emptyRoot {
+		return nil, nil
+	}
+	key = keybytesToHex(key)
+	wantHash := rootHash
+	for i := 0; ; i++ {
+		buf, _ := proof.Get(wantHash[:])
+		if buf == nil {
+			return nil, fmt.Errorf("proof node %d (hash %064x) missing", i, wantHash)
+		}
+		n, err := decodeNode(wantHash[:], buf, 0)
+		if err != nil {
+			return nil, fmt.Errorf("bad proof node %d: %v", i, err)
+		}
+		keyrest, cld := proofGet(n, key)
+		switch cld := cld.(type) {
+		case nil:
+			// The trie doesn't contain the key.
+			return nil, nil
+		case hashNode:
+			key = keyrest
+			copy(wantHash[:], cld)
+		case valueNode:
+			return cld, nil
+		}
+	}
+}
+
+// VerifyMultiProof checks a proof of keys against the root hash of a trie,
+// and returns values of keys in order, nil for those not in the trie.
+func VerifyMultiProof(rootHash common.Hash, keys [][]byte, proof DatabaseReader) ([][]byte, error) {
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		value, err := VerifyProof(rootHash, key, proof)
+		if err != nil {
+			return nil, fmt.Errorf("key %x: %v", key, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// walk down a decoded proof node along key, stopping at a child by hash
+func proofGet(tn node, key []byte) ([]byte, node) {
+	for {
+		switch n := tn.(type) {
+		case *shortNode:
+			if len(key) < len(n.Key) || !bytes.Equal(n.Key, key[:len(n.Key)]) {
+				return nil, nil
+			}
+			tn = n.Val
+			key = key[len(n.Key):]
+		case *fullNode:
+			if len(key) == 0 {
+				return nil, nil
+			}
+			tn = n.Children[key[0]]
+			key = key[1:]
+		case hashNode:
+			return key, n
+		case nil:
+			return key, nil
+		case valueNode:
+			if len(key) > 0 {
+				return nil, nil
+			}
+			return nil, n
+		default:
+			panic(fmt.Sprintf("%T: invalid node: %v", tn, tn))
+		}
+	}
+}
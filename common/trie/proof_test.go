@@ -0,0 +1,155 @@
+// Copyright 2015 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	mrand "math/rand"
+	"testing"
+
+	"github.com/yeeco/gyee/common"
+)
+
+// random trie of n entries, keys and values of random lengths so that
+// short nodes embedded in parents and keys prefixing others are covered
+func randomTrie(rnd *mrand.Rand, n int) (*Trie, map[string][]byte) {
+	trie := newEmpty()
+	vals := make(map[string][]byte)
+	for i := 0; i < n; i++ {
+		key := randBytes(rnd, 1+rnd.Intn(8))
+		value := randBytes(rnd, 1+rnd.Intn(40))
+		trie.Update(key, value)
+		vals[string(key)] = value
+	}
+	return trie, vals
+}
+
+func randBytes(rnd *mrand.Rand, n int) []byte {
+	b := make([]byte, n)
+	rnd.Read(b)
+	// few distinct bytes for long shared prefixes
+	for i := range b {
+		b[i] %= 4
+	}
+	return b
+}
+
+func TestProofEmptyTrie(t *testing.T) {
+	trie := newEmpty()
+	proof := NewProofSet()
+	if err := trie.Prove([]byte("key"), proof); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := VerifyProof(trie.Hash(), []byte("key"), proof); value != nil || err != nil {
+		t.Errorf("VerifyProof() of empty trie got %x %v", value, err)
+	}
+}
+
+func TestProofRandom(t *testing.T) {
+	rnd := mrand.New(mrand.NewSource(1))
+	for round := 0; round < 300; round++ {
+		trie, vals := randomTrie(rnd, 1+rnd.Intn(200))
+		root := trie.Hash()
+		// proofs from a trie resolved from the database too
+		if round%2 == 1 {
+			if _, err := trie.Commit(nil); err != nil {
+				t.Fatal(err)
+			}
+			var err error
+			if trie, err = New(root, trie.db); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for key, value := range vals {
+			proof := NewProofSet()
+			if err := trie.Prove([]byte(key), proof); err != nil {
+				t.Fatalf("round %d Prove(%x) %v", round, key, err)
+			}
+			got, err := VerifyProof(root, []byte(key), NewProofSetFromList(proof.List()))
+			if err != nil || !bytes.Equal(got, value) {
+				t.Fatalf("round %d VerifyProof(%x) got %x %v want %x", round, key, got, err, value)
+			}
+			// every node of a single key proof is on the path
+			for _, enc := range proof.List() {
+				list := proof.List()
+				for i := range list {
+					if bytes.Equal(list[i], enc) {
+						list = append(list[:i], list[i+1:]...)
+						break
+					}
+				}
+				if _, err := VerifyProof(root, []byte(key), NewProofSetFromList(list)); err == nil {
+					t.Fatalf("round %d VerifyProof(%x) missing node no error", round, key)
+				}
+			}
+			// corrupted node
+			list := proof.List()
+			i := rnd.Intn(len(list))
+			bad := common.CopyBytes(list[i])
+			bad[rnd.Intn(len(bad))] ^= 0x01
+			list[i] = bad
+			if got, err := VerifyProof(root, []byte(key), NewProofSetFromList(list)); err == nil && bytes.Equal(got, value) {
+				t.Fatalf("round %d VerifyProof(%x) corrupted node accepted", round, key)
+			}
+		}
+
+		// absent keys
+		for i := 0; i < 20; i++ {
+			key := randBytes(rnd, 1+rnd.Intn(9))
+			if _, ok := vals[string(key)]; ok {
+				continue
+			}
+			proof := NewProofSet()
+			if err := trie.Prove(key, proof); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := VerifyProof(root, key, proof); got != nil || err != nil {
+				t.Fatalf("round %d VerifyProof(%x) absent got %x %v", round, key, got, err)
+			}
+		}
+
+		// multiproof of a subset of keys
+		var keys [][]byte
+		for key := range vals {
+			if rnd.Intn(3) == 0 {
+				keys = append(keys, []byte(key))
+			}
+		}
+		keys = append(keys, randBytes(rnd, 9))
+		proof := NewProofSet()
+		size := 0
+		for _, key := range keys {
+			single := NewProofSet()
+			trie.Prove(key, single)
+			size += single.Len()
+			trie.Prove(key, proof)
+		}
+		if proof.Len() > size {
+			t.Fatalf("round %d multiproof of %d nodes larger than %d", round, proof.Len(), size)
+		}
+		values, err := VerifyMultiProof(root, keys, proof)
+		if err != nil {
+			t.Fatalf("round %d VerifyMultiProof() %v", round, err)
+		}
+		for i, key := range keys {
+			if !bytes.Equal(values[i], vals[string(key)]) {
+				t.Fatalf("round %d VerifyMultiProof() %x got %x want %x", round, key, values[i], vals[string(key)])
+			}
+		}
+	}
+}
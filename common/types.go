@@ -24,6 +24,7 @@ import (
 	"encoding/hex"
 
 	"github.com/mr-tron/base58/base58"
+	"github.com/yeeco/gyee/common/hexutil"
 )
 
 const (
@@ -62,6 +63,24 @@ func (h Hash) Base58() string { return base58.Encode(h[:]) }
 
 func (h Hash) String() string { return h.Hex() }
 
+// MarshalText encodes hash as 0x-prefixed hex, for JSON and config.
+func (h Hash) MarshalText() ([]byte, error) {
+	return []byte(hexutil.Encode(h[:])), nil
+}
+
+// UnmarshalText decodes hex of exactly HashLength bytes, 0x prefix optional.
+func (h *Hash) UnmarshalText(input []byte) error {
+	return hexutil.DecodeFixed(string(input), h[:])
+}
+
+// ParseHash parses hex of a hash, unlike HexToHash, invalid or short input
+// is an error instead of a cropped or zero hash.
+func ParseHash(s string) (Hash, error) {
+	var h Hash
+	err := h.UnmarshalText([]byte(s))
+	return h, err
+}
+
 func (h Hash) Equals(b Hash) bool {
 	return h == b
 	//return bytes.Compare(h[:], b[:]) == 0
@@ -100,6 +119,23 @@ func (a Address) Hex() string { return hex.EncodeToString(a[:]) }
 
 func (a Address) String() string { return a.Hex() }
 
+// MarshalText encodes address as 0x-prefixed hex, for JSON and config.
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(hexutil.Encode(a[:])), nil
+}
+
+// UnmarshalText decodes hex of exactly AddressLength bytes, 0x prefix optional.
+func (a *Address) UnmarshalText(input []byte) error {
+	return hexutil.DecodeFixed(string(input), a[:])
+}
+
+// ParseAddress parses hex of an address, invalid or short input is an error.
+func ParseAddress(s string) (Address, error) {
+	var a Address
+	err := a.UnmarshalText([]byte(s))
+	return a, err
+}
+
 func (a *Address) SetBytes(b []byte) {
 	if len(b) > len(a) {
 		b = b[len(b)-AddressLength:]
@@ -0,0 +1,58 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package common
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHashAddressJSON(t *testing.T) {
+	type obj struct {
+		Hash    Hash    `json:"hash"`
+		Address Address `json:"address"`
+	}
+	v := obj{
+		Hash:    HexToHash("0x2a"),
+		Address: HexToAddress("0011223344556677889900112233445566778899"),
+	}
+	enc, err := json.Marshal(v)
+	want := `{"hash":"0x000000000000000000000000000000000000000000000000000000000000002a",` +
+		`"address":"0x0011223344556677889900112233445566778899"}`
+	if err != nil || string(enc) != want {
+		t.Fatalf("json.Marshal() got %s %v", enc, err)
+	}
+	var dec obj
+	if err := json.Unmarshal(enc, &dec); err != nil || dec != v {
+		t.Errorf("json.Unmarshal() got %v %v", dec, err)
+	}
+
+	// strict parsing, unlike HexToHash and HexToAddress
+	if _, err := ParseHash("0x2a"); err == nil {
+		t.Errorf("ParseHash() of short hash no error")
+	}
+	if _, err := ParseAddress("0011223344556677889900112233445566778899"); err != nil {
+		t.Errorf("ParseAddress() without prefix got %v", err)
+	}
+	if _, err := ParseAddress("zz11223344556677889900112233445566778899"); err == nil {
+		t.Errorf("ParseAddress() of invalid hex no error")
+	}
+}
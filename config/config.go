@@ -21,6 +21,8 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -42,6 +44,7 @@ type Config struct {
 	P2p     *P2pConfig     `toml:"network"`
 	Rpc     *RpcConfig     `toml:"rpc"`
 	Chain   *ChainConfig   `toml:"chain"`
+	Chains  []*ChainConfig `toml:"chains"` // extra chains hosted besides Chain
 	Metrics *MetricsConfig `toml:"metrics"`
 	Misc    *MiscConfig    `toml:"misc"`
 }
@@ -51,6 +54,8 @@ type AppConfig struct {
 	LogFile           string   `toml:"log_file"`
 	EnableCrashReport bool     `toml:"enable_crash_report"`
 	CrashReportUrl    []string `toml:"crash_report_url"`
+	ShutdownTimeout   int      `toml:"shutdown_timeout"` // max seconds of a soft shutdown
+	CryptoProvider    string   `toml:"crypto_provider"`  // "generic" or "fast", generic if empty
 }
 
 //P2P Config, bootnode, MaxConn, MaxIncoming, MaxOutgoing, Listen Port,..
@@ -62,21 +67,82 @@ type P2pConfig struct {
 	Name              string   `toml:"name"`
 	Validator         bool     `toml:"validator"`
 	BootstrapNode     bool     `toml:"bootstrap_node"`
+	SeedOnly          bool     `toml:"seed_only"`
+	SeedGraceTime     int      `toml:"seed_grace_time"`
 	BootstrapNodes    []string `toml:"bootstrap_nodes"`
 	DhtBootstrapNodes []string `toml:"dht_bootstrap_nodes"`
 	LocalNodeIp       string   `toml:"local_node_ip"`
+	LocalNodeAltIp    string   `toml:"local_node_alt_ip"` // ip of the other family for dual stack, empty if single stack
 	LocalUdpPort      uint16   `toml:"local_udp_port"`
 	LocalTcpPort      uint16   `toml:"local_tcp_port"`
 	LocalDhtIp        string   `toml:"local_dht_ip"`
 	LocalDhtPort      uint16   `toml:"local_dht_port"`
+	AdvertiseIp       string   `toml:"advertise_ip"`
+	AdvertiseUdpPort  uint16   `toml:"advertise_udp_port"`
+	AdvertiseTcpPort  uint16   `toml:"advertise_tcp_port"`
+	AdvertiseDhtPort  uint16   `toml:"advertise_dht_port"`
 	NodeDataDir       string   `toml:"node_data_path"`
 	NodeDatabase      string   `toml:"node_database"`
+	EncryptDb         bool     `toml:"encrypt_db"` // encrypt values of node database and dht store by a key derived from coinbase key
+	DataKey           []byte   `toml:"-"`          // key derived for encrypt_db, set by node
 	SubNetMaskBits    int      `toml:"subnet_mask_bits"`
+	AdaptiveSlots     bool     `toml:"adaptive_slots"`
+	SlotOutboundMin   int      `toml:"slot_outbound_min"`
+	SlotOutboundMax   int      `toml:"slot_outbound_max"`
+	RxQueuePolicy     string   `toml:"rx_queue_policy"`
+	RxBlockTimeout    int      `toml:"rx_block_timeout"`
+	RxGrowMax         int      `toml:"rx_grow_max"`
+	StreamMaxSize     int      `toml:"stream_max_size"`
+	StreamTimeout     int      `toml:"stream_timeout"`
+	PeerTxRate        int      `toml:"peer_tx_rate"`  // max tx bytes per second of a peer, 0 for unlimited
+	PeerRxRate        int      `toml:"peer_rx_rate"`  // max rx bytes per second of a peer, 0 for unlimited
+	TotalTxRate       int      `toml:"total_tx_rate"` // max tx bytes per second of all peers, 0 for unlimited
+	HsAddrCheck       string   `toml:"hs_addr_check"`
+	HsAuthRequired    bool     `toml:"hs_auth_required"`    // handshakes not signed with a nonce refused, whatever version agreed
+	HsNonceWindow     int      `toml:"hs_nonce_window"`     // max seconds of clock skew of the time in a handshake nonce
+	IpPreference      string   `toml:"ip_preference"`       // address family dialed first, "any", "v4" or "v6"
+	WsPort            uint16   `toml:"ws_port"`             // tcp port for websocket peer connections, 0 to disable
+	Encryption        string   `toml:"encryption"`          // encryption of tcp peer connections, "none", "opportunistic" or "required"
+	SubNetEncryption  []string `toml:"subnet_encryption"`   // encryption by sub network, "snid(hex):policy"
+	ProxyAddr         string   `toml:"proxy_addr"`          // socks5 proxy to dial peers through, "host:port", empty if none
+	ProxyUser         string   `toml:"proxy_user"`          // user name of the proxy, empty if no authentication
+	ProxyPassword     string   `toml:"proxy_password"`      // password of the proxy user
+	SubNetProxy       []string `toml:"subnet_proxy"`        // proxy enabled by sub network, "snid(hex):true|false"
+	CompressDisabled  bool     `toml:"compress_disabled"`   // large user packages not compressed
+	AcceptResume      int      `toml:"accept_resume"`       // inbounds in percent of the limit the accepter resumed at
+	AcceptMinPause    int      `toml:"accept_min_pause"`    // min seconds the accepter paused for inbounds full
+	SelfProbeInterval int      `toml:"self_probe_interval"` // seconds between reachability self probes, negative to disable
+	IpMaxInbounds     int      `toml:"ip_max_inbounds"`     // max inbound connections from an ip, negative for unlimited
+	IpAcceptRate      int      `toml:"ip_accept_rate"`      // max connections accepted from an ip per minute, negative for unlimited
 	EvKeepTime        int      `toml:"ev_keep_time"`
 	DedupTime         int      `toml:"dedup_time"`
 	BootstrapTime     int      `toml:"bootstrap_time"`
 	NatType           string   `toml:"nat_type"`
 	GatewayIp         string   `toml:"gateway_ip"`
+	DisableChain      bool     `toml:"disable_chain"`
+	DisableDht        bool     `toml:"disable_dht"`
+	DhtChainBootstrap bool     `toml:"dht_chain_bootstrap"`
+	DhtVivaldi        bool     `toml:"dht_vivaldi"`
+	DhtReplication    []string `toml:"dht_replication"`
+	DhtAcl            []string `toml:"dht_acl"`
+	Discv4Enabled     bool     `toml:"discv4_enabled"`
+	Discv4Port        uint16   `toml:"discv4_port"`
+	Discv4Nodes       []string `toml:"discv4_nodes"`
+	Discv4SeedTime    int      `toml:"discv4_seed_time"`
+	RandSeed          int64    `toml:"rand_seed"`
+	LogSampling       []string `toml:"log_sampling"`
+
+	DhtTuning DhtTuningConfig `toml:"dht_tuning"`
+}
+
+// Tunables of dht queries and route table, 0 for defaults
+type DhtTuningConfig struct {
+	QryMaxWidth     int `toml:"qry_max_width"`     // max peers queried for a query
+	QryMaxDepth     int `toml:"qry_max_depth"`     // max depth of a query
+	BucketSize      int `toml:"bucket_size"`       // max peers of a bucket of route table
+	MaxLatency      int `toml:"max_latency"`       // max seconds of latency accepted by route table
+	BootstrapQryNum int `toml:"bootstrap_qry_num"` // random lookups of a bootstrap round
+	BootstrapPeriod int `toml:"bootstrap_period"`  // seconds between bootstrap rounds
 }
 
 //Listen addr, modules, access right
@@ -84,6 +150,17 @@ type RpcConfig struct {
 	IpcPath    string   `toml:"ipc_path"`
 	RpcListen  []string `toml:"rpc_listen"`
 	HttpListen []string `toml:"http_listen"`
+	WsListen   []string `toml:"ws_listen"`  // websocket subscriptions
+	AdminAuth  bool     `toml:"admin_auth"` // admin methods require token written to node dir
+
+	IpcMethods    []string `toml:"ipc_methods"`    // methods allowed on ipc, all if empty
+	PublicMethods []string `toml:"public_methods"` // methods allowed on rpc_listen and ws_listen, ApiService and ws if empty
+	CorsOrigins   []string `toml:"cors_origins"`   // origins of browsers allowed by ws_listen, all if empty
+	AuthToken     string   `toml:"auth_token"`     // api token accepted by public transports
+	JwtSecret     string   `toml:"jwt_secret"`     // secret of HS256 jwt accepted by public transports
+	RateLimit     float64  `toml:"rate_limit"`     // requests per second of a client ip, 0 unlimited
+	RateBurst     int      `toml:"rate_burst"`     // burst requests of a client ip
+	MethodLimits  []string `toml:"method_limits"`  // limits of methods for all clients, "method:rate:burst"
 }
 
 //Genesis, ChainID, Keydir, Coinbase, gas...
@@ -96,6 +173,27 @@ type ChainConfig struct {
 	Coinbase string `toml:"coinbase"`
 	PwdFile  string `toml:"pwdfile"`
 	Key      []byte // raw private key used in unit test
+
+	DbMigrateDryRun bool   `toml:"db_migrate_dryrun"` // only report pending chain db migrations
+	ReadOnly        bool   `toml:"read_only"`         // open chain db read-only beside the node writing it, serving queries only
+	ReadOnlyRefresh uint64 `toml:"read_only_refresh"` // seconds to refresh chain head in read-only mode, 0 for default
+	CheckpointEpoch uint64 `toml:"checkpoint_epoch"`  // blocks of an epoch to write checkpoint, 0 disabled
+	BlockMaxTxs     int    `toml:"block_max_txs"`     // max txs of block built from pool, 0 unlimited
+	BlockMaxSize    int    `toml:"block_max_size"`    // max bytes of txs of block built from pool, 0 unlimited
+	BlockGasLimit   uint64 `toml:"block_gas_limit"`   // gas limit of blocks, 0 for default
+	FeeRecipient    string `toml:"fee_recipient"`     // address tx fees go to, burned if empty
+	AccountIndex    bool   `toml:"account_index"`     // index txs by accounts for history queries
+	TxPoolTTL       uint64 `toml:"txpool_ttl"`        // seconds txs kept in pool, 0 for default
+	CompactInterval uint64 `toml:"compact_interval"`  // seconds between scheduled chain db compactions, 0 disabled
+	FreezeDistance  uint64 `toml:"freeze_distance"`   // blocks behind head moved from chain db to the cold store, 0 disabled
+
+	HistoryOff      bool    `toml:"history_off"`       // not serving historical blocks to peers
+	HistoryRate     float64 `toml:"history_rate"`      // headers and bodies served per second to a peer, 0 for default
+	HistoryBurst    float64 `toml:"history_burst"`     // headers and bodies served to a peer at most in a burst, 0 for default
+	HistoryMaxItems int     `toml:"history_max_items"` // max headers or bodies of a response, 0 for default
+	HistoryMaxBytes int     `toml:"history_max_bytes"` // max bytes of a response, 0 for default
+
+	P2p *P2pConfig `toml:"p2p"` // own p2p network and subnets of an extra chain, the node's shared if nil
 }
 
 //cpu, mem, disk profile,
@@ -202,6 +300,50 @@ func SaveConfigToFile(file string, config *Config) error {
 	return nil
 }
 
+// Check chains hosted, the identities of them must be unique
+func (c *Config) CheckChains() error {
+	if c.Chain == nil {
+		return errors.New("config: primary chain not configured")
+	}
+	ids := map[uint32]bool{c.Chain.ChainID: true}
+	for _, cc := range c.Chains {
+		if cc == nil {
+			return errors.New("config: empty chain configured")
+		}
+		if ids[cc.ChainID] {
+			return fmt.Errorf("config: duplicated chain id: %d", cc.ChainID)
+		}
+		ids[cc.ChainID] = true
+	}
+
+	// chains of their own p2p networks can't share the endpoints
+	ports := make(map[uint16]uint32, 0)
+	addPorts := func(chainID uint32, p2p *P2pConfig) error {
+		if p2p == nil {
+			return nil
+		}
+		for _, port := range []uint16{p2p.LocalUdpPort, p2p.LocalTcpPort, p2p.LocalDhtPort} {
+			if port == 0 {
+				continue
+			}
+			if other, dup := ports[port]; dup && other != chainID {
+				return fmt.Errorf("config: port %d of chain %d used by chain %d", port, chainID, other)
+			}
+			ports[port] = chainID
+		}
+		return nil
+	}
+	if err := addPorts(c.Chain.ChainID, c.P2p); err != nil {
+		return err
+	}
+	for _, cc := range c.Chains {
+		if err := addPorts(cc.ChainID, cc.P2p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c *Config) IPCEndpoint() string {
 	// Short circuit if IPC has not been enabled
 	if c.Rpc.IpcPath == "" {
@@ -31,3 +31,26 @@ func TestGetDefaultConfig(t *testing.T) {
 
 	SaveConfigToFile("/tmp/config.toml", config)
 }
+
+func TestCheckChains(t *testing.T) {
+	conf := &Config{
+		Chain: &ChainConfig{ChainID: 1},
+		P2p:   &P2pConfig{LocalUdpPort: 30303, LocalTcpPort: 30303},
+		Chains: []*ChainConfig{
+			{ChainID: 2},
+			{ChainID: 3, P2p: &P2pConfig{LocalUdpPort: 30313, LocalTcpPort: 30313}},
+		},
+	}
+	if err := conf.CheckChains(); err != nil {
+		t.Fatalf("CheckChains failed: %v", err)
+	}
+	conf.Chains[1].P2p.LocalDhtPort = 30303
+	if err := conf.CheckChains(); err == nil {
+		t.Errorf("port shared with the primary chain accepted")
+	}
+	conf.Chains[1].P2p.LocalDhtPort = 0
+	conf.Chains[0].ChainID = 3
+	if err := conf.CheckChains(); err == nil {
+		t.Errorf("duplicated chain id accepted")
+	}
+}
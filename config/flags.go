@@ -53,6 +53,7 @@ var (
 		AppLogFileFlag,
 		AppEnableCrashReportFlag,
 		AppCrashReportUrlFlag,
+		AppShutdownTimeoutFlag,
 	}
 
 	AppLogLevelFlag = cli.StringFlag{
@@ -75,6 +76,11 @@ var (
 		Usage: "crash report url",
 	}
 
+	AppShutdownTimeoutFlag = cli.IntFlag{
+		Name:  "shutdown_timeout",
+		Usage: "max seconds of a soft shutdown before it's forced",
+	}
+
 	//NetworkConfig Flags
 	NetworkFlags = []cli.Flag{
 		NetworkBootNodeFlag,
@@ -96,6 +102,8 @@ var (
 		RpcIpcPathFlag,
 		RpcListenFlag,
 		RpcHttpListenFlag,
+		RpcWsListenFlag,
+		RpcAdminAuthFlag,
 	}
 
 	RpcIpcPathFlag = cli.StringFlag{
@@ -113,6 +121,16 @@ var (
 		Usage: "http listen",
 	}
 
+	RpcWsListenFlag = cli.StringSliceFlag{
+		Name:  "ws_listen",
+		Usage: "websocket listen",
+	}
+
+	RpcAdminAuthFlag = cli.BoolFlag{
+		Name:  "admin_auth",
+		Usage: "admin methods require token written to node dir",
+	}
+
 	//ChainConfig Flags
 	ChainFlags = []cli.Flag{
 		ChainIDFlag,
@@ -122,6 +140,8 @@ var (
 		ChainMineFlag,
 		ChainCoinbaseFlag,
 		ChainPwdFileFlag,
+		ChainDbMigrateDryRunFlag,
+		ChainReadOnlyFlag,
 	}
 
 	ChainIDFlag = cli.IntFlag{
@@ -159,6 +179,16 @@ var (
 		Usage: "pwdfile for coinbase keystore",
 	}
 
+	ChainDbMigrateDryRunFlag = cli.BoolFlag{
+		Name:  "db_migrate_dryrun",
+		Usage: "report pending chain db migrations and exit",
+	}
+
+	ChainReadOnlyFlag = cli.BoolFlag{
+		Name:  "readonly",
+		Usage: "open chain db read-only beside the node writing it, serving queries only",
+	}
+
 	//MetricsConfig Flags
 	MetricsFlags = []cli.Flag{
 		MetricsEnableFlag,
@@ -205,6 +235,10 @@ func getAppConfig(ctx *cli.Context, cfg *Config) {
 	if ctx.GlobalIsSet(FlagName(AppCrashReportUrlFlag.Name)) {
 		cfg.App.CrashReportUrl = ctx.GlobalStringSlice(FlagName(AppCrashReportUrlFlag.Name))
 	}
+
+	if ctx.GlobalIsSet(FlagName(AppShutdownTimeoutFlag.Name)) {
+		cfg.App.ShutdownTimeout = ctx.GlobalInt(FlagName(AppShutdownTimeoutFlag.Name))
+	}
 }
 
 func getNetworkConfig(ctx *cli.Context, cfg *Config) {
@@ -237,6 +271,14 @@ func getRpcConfig(ctx *cli.Context, cfg *Config) {
 	if ctx.GlobalIsSet(FlagName(RpcHttpListenFlag.Name)) {
 		cfg.Rpc.HttpListen = ctx.GlobalStringSlice(FlagName(RpcHttpListenFlag.Name))
 	}
+
+	if ctx.GlobalIsSet(FlagName(RpcWsListenFlag.Name)) {
+		cfg.Rpc.WsListen = ctx.GlobalStringSlice(FlagName(RpcWsListenFlag.Name))
+	}
+
+	if ctx.GlobalIsSet(FlagName(RpcAdminAuthFlag.Name)) {
+		cfg.Rpc.AdminAuth = ctx.GlobalBool(FlagName(RpcAdminAuthFlag.Name))
+	}
 }
 
 func getChainConfig(ctx *cli.Context, cfg *Config) {
@@ -271,6 +313,14 @@ func getChainConfig(ctx *cli.Context, cfg *Config) {
 	if ctx.GlobalIsSet(FlagName(ChainPwdFileFlag.Name)) {
 		cfg.Chain.PwdFile = ctx.GlobalString(FlagName(ChainPwdFileFlag.Name))
 	}
+
+	if ctx.GlobalIsSet(FlagName(ChainDbMigrateDryRunFlag.Name)) {
+		cfg.Chain.DbMigrateDryRun = ctx.GlobalBool(FlagName(ChainDbMigrateDryRunFlag.Name))
+	}
+
+	if ctx.GlobalIsSet(FlagName(ChainReadOnlyFlag.Name)) {
+		cfg.Chain.ReadOnly = ctx.GlobalBool(FlagName(ChainReadOnlyFlag.Name))
+	}
 }
 
 func getMetricsConfig(ctx *cli.Context, cfg *Config) {
@@ -0,0 +1,159 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   账户索引：导入区块时记录每个账户（发送者和接收者）相关的交易在哪个区块的第几个，
+   按顺序编号存放，另存账户的交易数。浏览器和钱包可以按账户分页查询交易历史，不用
+   扫描整条链。索引占用存储较多，由配置account_index开启，开启之前导入的区块没有
+   索引。
+*/
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/core/pb"
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/persistent"
+)
+
+var (
+	ErrAccountIndexDisabled = errors.New("core.index: account index disabled")
+	ErrAccountIndexCorrupt  = errors.New("core.index: account index entry corrupt")
+)
+
+// Tx of an account, located by block number and index in block
+type AccountTx struct {
+	Number uint64
+	Index  uint32
+	Tx     *Transaction
+}
+
+// Get number of txs indexed for account
+func (bc *BlockChain) AccountTxCount(addr common.Address) (uint64, error) {
+	if !bc.accountIndex {
+		return 0, ErrAccountIndexDisabled
+	}
+	return getAccountTxCount(bc.storage, addr), nil
+}
+
+// Get txs of account from the start-th one indexed, limit of them at most
+func (bc *BlockChain) GetTransactionsByAccount(addr common.Address, start, limit uint64) ([]*AccountTx, error) {
+	if !bc.accountIndex {
+		return nil, ErrAccountIndexDisabled
+	}
+	count := getAccountTxCount(bc.storage, addr)
+	if start >= count {
+		return nil, nil
+	}
+	if limit > count-start {
+		limit = count - start
+	}
+	var (
+		txs  = make([]*AccountTx, 0, limit)
+		num  uint64
+		body *corepb.BlockBody
+	)
+	for seq := start; seq < start+limit; seq++ {
+		enc, err := bc.storage.Get(keyAccountTx(addr, seq))
+		if err != nil {
+			return nil, err
+		}
+		if len(enc) != 12 {
+			return nil, ErrAccountIndexCorrupt
+		}
+		atx := &AccountTx{
+			Number: binary.BigEndian.Uint64(enc[:8]),
+			Index:  binary.BigEndian.Uint32(enc[8:]),
+		}
+		if body == nil || num != atx.Number {
+			hash := getBlockNum2Hash(bc.storage, atx.Number)
+			if body = bc.readBody(hash); body == nil {
+				return nil, ErrAccountIndexCorrupt
+			}
+			num = atx.Number
+		}
+		if int(atx.Index) >= len(body.RawTransactions) {
+			return nil, ErrAccountIndexCorrupt
+		}
+		atx.Tx = new(Transaction)
+		if err := atx.Tx.Decode(body.RawTransactions[atx.Index]); err != nil {
+			return nil, err
+		}
+		txs = append(txs, atx)
+	}
+	return txs, nil
+}
+
+// index txs of block by sender and recipient, written with the block
+func (bc *BlockChain) indexAccountTxs(putter persistent.Putter, b *Block) {
+	counts := make(map[common.Address]uint64)
+	for i, tx := range b.transactions {
+		from := tx.from
+		if from == nil && tx.signature != nil {
+			from = tx.From()
+		}
+		addrs := []*common.Address{from}
+		if tx.to != nil && (from == nil || *from != *tx.to) {
+			// txs sent to self indexed once
+			addrs = append(addrs, tx.to)
+		}
+		for _, addr := range addrs {
+			if addr == nil {
+				continue
+			}
+			seq, ok := counts[*addr]
+			if !ok {
+				seq = getAccountTxCount(bc.storage, *addr)
+			}
+			enc := make([]byte, 12)
+			binary.BigEndian.PutUint64(enc[:8], b.Number())
+			binary.BigEndian.PutUint32(enc[8:], uint32(i))
+			if err := putter.Put(keyAccountTx(*addr, seq), enc); err != nil {
+				log.Crit("indexAccountTxs()", "err", err)
+			}
+			counts[*addr] = seq + 1
+		}
+	}
+	for addr, count := range counts {
+		enc := make([]byte, 8)
+		binary.BigEndian.PutUint64(enc, count)
+		if err := putter.Put(keyAccountTxCount(addr), enc); err != nil {
+			log.Crit("indexAccountTxs()", "err", err)
+		}
+	}
+}
+
+func getAccountTxCount(getter persistent.Getter, addr common.Address) uint64 {
+	enc, err := getter.Get(keyAccountTxCount(addr))
+	if err != nil {
+		if err != persistent.ErrKeyNotFound {
+			log.Error("getAccountTxCount()", "addr", addr, "err", err)
+		}
+		return 0
+	}
+	if len(enc) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(enc)
+}
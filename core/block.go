@@ -32,8 +32,7 @@ import (
 	"github.com/yeeco/gyee/core/pb"
 	"github.com/yeeco/gyee/core/state"
 	"github.com/yeeco/gyee/crypto"
-	sha3 "github.com/yeeco/gyee/crypto/hash"
-	"github.com/yeeco/gyee/crypto/secp256k1"
+	"github.com/yeeco/gyee/crypto/provider"
 	"github.com/yeeco/gyee/log"
 	"github.com/yeeco/gyee/persistent"
 )
@@ -65,6 +64,10 @@ type BlockHeader struct {
 
 	// extra binary data
 	Extra []byte `json:"extraData"`
+
+	// gas limit and gas used, empty in blocks before gas metering, so the
+	// hashes of them are not changed
+	Gas []uint64 `json:"gas,omitempty" rlp:"tail"`
 }
 
 func CopyHeader(header *BlockHeader) *BlockHeader {
@@ -72,12 +75,17 @@ func CopyHeader(header *BlockHeader) *BlockHeader {
 	return &cpy
 }
 
+// check if gas is recorded in header
+func (bh *BlockHeader) Metered() bool {
+	return len(bh.Gas) == 2
+}
+
 func (bh *BlockHeader) Hash() ([]byte, error) {
 	enc, err := bh.ToBytes()
 	if err != nil {
 		return nil, err
 	}
-	return sha3.Sha3256(enc), nil
+	return provider.Current().Hash256(enc), nil
 }
 
 func (bh *BlockHeader) ToBytes() ([]byte, error) {
@@ -124,6 +132,7 @@ type Block struct {
 	// cache for block pool, accessed from single goroutine
 	checkAgainstParent bool                                // if signature were checked against parent block
 	signatureMap       map[common.Address]crypto.Signature // signature cache
+	origin             string                              // peer block received from, empty if synced
 }
 
 func CopyBlock(b *Block) *Block {
@@ -134,13 +143,12 @@ func CopyBlock(b *Block) *Block {
 	return cpy
 }
 
+// Create block with header taken as is, roots of it are not checked against
+// txs. blocks to be sealed should be built with BlockBuilder instead.
 func NewBlock(header *BlockHeader, txs []*Transaction) *Block {
 	b := &Block{header: CopyHeader(header)}
 
-	if len(txs) == 0 {
-		// TODO: header TxsRoot for empty txs
-	} else {
-		// TODO: header TxsRoot for txs
+	if len(txs) > 0 {
 		b.transactions = make([]*Transaction, len(txs))
 		copy(b.transactions, txs)
 	}
@@ -160,6 +168,20 @@ func (b *Block) ReceiptsRoot() common.Hash  { return b.header.ReceiptsRoot }
 func (b *Block) Time() uint64  { return b.header.Time }
 func (b *Block) Extra() []byte { return b.header.Extra }
 
+func (b *Block) GasLimit() uint64 {
+	if !b.header.Metered() {
+		return 0
+	}
+	return b.header.Gas[0]
+}
+
+func (b *Block) GasUsed() uint64 {
+	if !b.header.Metered() {
+		return 0
+	}
+	return b.header.Gas[1]
+}
+
 func (b *Block) Hash() common.Hash {
 	if hash := b.hash.Load(); hash != nil {
 		return hash.(common.Hash)
@@ -262,22 +284,28 @@ func (b *Block) Sign(signer crypto.Signer) error {
 }
 
 func (b *Block) Signers() (map[common.Address]crypto.Signature, error) {
-	result := make(map[common.Address]crypto.Signature)
-	signer := secp256k1.NewSecp256k1Signer()
-	for _, sig := range b.pbHeader.Signatures {
-		sig := crypto.Signature{
-			Algorithm: crypto.Algorithm(sig.SigAlgorithm),
-			Signature: sig.Signature,
+	hash := b.Hash()
+	items := make([]*provider.RecoverItem, len(b.pbHeader.Signatures))
+	for i, sig := range b.pbHeader.Signatures {
+		items[i] = &provider.RecoverItem{
+			Data: hash[:],
+			Signature: &crypto.Signature{
+				Algorithm: crypto.Algorithm(sig.SigAlgorithm),
+				Signature: sig.Signature,
+			},
 		}
-		pubkey, err := signer.RecoverPublicKey(b.Hash().Bytes(), &sig)
-		if err != nil {
-			return nil, err
+	}
+	provider.Current().RecoverBatch(items)
+	result := make(map[common.Address]crypto.Signature)
+	for _, item := range items {
+		if item.Err != nil {
+			return nil, item.Err
 		}
-		addr, err := address.NewAddressFromPublicKey(pubkey)
+		addr, err := address.NewAddressFromPublicKey(item.PublicKey)
 		if err != nil {
 			return nil, err
 		}
-		result[*addr.CommonAddress()] = sig
+		result[*addr.CommonAddress()] = *item.Signature
 	}
 	return result, nil
 }
@@ -315,12 +343,7 @@ func (b *Block) VerifyBody() error {
 	if txHash != b.header.TxsRoot {
 		return ErrBlockBodyTxsMismatch
 	}
-	for _, tx := range b.transactions {
-		if err := tx.VerifySig(); err != nil {
-			return err
-		}
-	}
-	return nil
+	return verifyTxSigs(b.transactions)
 }
 
 func (b *Block) GetAccount(address common.Address) state.Account {
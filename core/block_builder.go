@@ -17,14 +17,161 @@
 
 package core
 
+/*
+   block builder：共识引擎出块的流水线
+   1. NewBlockBuilder在父块之上开始一个新块，state从父块的state root打开，不影响父块
+   2. AddTxs按nonce整理交易，逐个执行并计gas，合约交易交给VM执行并生成收据，超过块的
+      交易数、大小或gas限制时停止
+   3. Build计算state/consensus/txs/receipts root，记录gas，生成块头
+   4. Seal调用共识提供的seal hook（签名）后加入链
+*/
+
 import (
+	"errors"
 	"fmt"
 
 	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/core/pb"
 	"github.com/yeeco/gyee/core/state"
 	"github.com/yeeco/gyee/log"
 )
 
+var (
+	ErrBlockBuilderDone = errors.New("core.builder: block already built")
+)
+
+// Limits of a block built, 0 for unlimited
+type BlockLimits struct {
+	MaxTxs  int // max number of txs
+	MaxSize int // max size of encoded txs in bytes
+}
+
+// Seal hook of consensus, called with the block built before it's added to
+// chain, to sign it
+type SealFunc func(b *Block) error
+
+type BlockBuilder struct {
+	chain  *BlockChain
+	parent *Block
+	limits BlockLimits
+	block  *Block   // block in building
+	gp     *GasPool // gas of block
+	size   int      // size of encoded txs added
+	built  bool
+}
+
+// Start building next block of parent at time t, in milli seconds
+func (bc *BlockChain) NewBlockBuilder(parent *Block, t uint64, limits BlockLimits) (*BlockBuilder, error) {
+	next := &Block{
+		header:       CopyHeader(parent.header),
+		body:         new(corepb.BlockBody),
+		transactions: make(Transactions, 0),
+	}
+	next.header.Number++
+	next.header.ParentHash = parent.Hash()
+	next.header.Time = t
+
+	// trie opened at root of parent, parent itself not changed
+	if err := next.prepareTrie(bc.stateDB); err != nil {
+		return nil, err
+	}
+	return &BlockBuilder{
+		chain:  bc,
+		parent: parent,
+		limits: limits,
+		block:  next,
+		gp:     NewGasPool(bc.gasLimit, bc.feeRecipient),
+	}, nil
+}
+
+// Add txs to block, they're ordered by nonce and executed one by one, txs
+// failed are dropped. Txs not added for the limits reached are returned.
+func (bb *BlockBuilder) AddTxs(txs Transactions) (Transactions, error) {
+	if bb.built {
+		return nil, ErrBlockBuilderDone
+	}
+	if err := txs.encode(); err != nil {
+		return nil, err
+	}
+	txs = organizeTxs(bb.block.stateTrie, txs)
+	for idx, tx := range txs {
+		if bb.full(tx) {
+			return txs[idx:], nil
+		}
+		if err := bb.addTx(tx); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// execute tx and add it to block, txs failed are dropped
+func (bb *BlockBuilder) addTx(tx *Transaction) error {
+	receipt, ok, err := bb.chain.execTx(bb.block.stateTrie, bb.block.header, tx, bb.gp)
+	if err != nil || !ok {
+		return err
+	}
+	bb.block.transactions = append(bb.block.transactions, tx)
+	if receipt != nil {
+		bb.block.receipts = append(bb.block.receipts, receipt)
+	}
+	if tx.raw == nil {
+		if err := Transactions([]*Transaction{tx}).encode(); err != nil {
+			return err
+		}
+	}
+	bb.size += len(tx.raw)
+	return nil
+}
+
+// Add pending txs of pool to block
+func (bb *BlockBuilder) AddPending(tp *TransactionPool) (Transactions, error) {
+	return bb.AddTxs(tp.Pending())
+}
+
+func (bb *BlockBuilder) full(tx *Transaction) bool {
+	if bb.limits.MaxTxs > 0 && len(bb.block.transactions) >= bb.limits.MaxTxs {
+		return true
+	}
+	if bb.limits.MaxSize > 0 && bb.size+len(tx.raw) > bb.limits.MaxSize {
+		return true
+	}
+	return !bb.gp.fits(IntrinsicGas(tx))
+}
+
+// Finish building, the body and header with roots filled
+func (bb *BlockBuilder) Build() (*Block, error) {
+	if bb.built {
+		return bb.block, nil
+	}
+	bb.block.header.Gas = []uint64{bb.gp.Limit(), bb.gp.Used()}
+	if err := bb.block.updateBody(); err != nil {
+		return nil, err
+	}
+	if err := bb.block.updateHeader(); err != nil {
+		return nil, err
+	}
+	bb.built = true
+	return bb.block, nil
+}
+
+// Build block, seal it with hook of consensus and add it to chain
+func (bb *BlockBuilder) Seal(seal SealFunc) (*Block, error) {
+	b, err := bb.Build()
+	if err != nil {
+		return nil, err
+	}
+	if seal != nil {
+		if err := seal(b); err != nil {
+			return nil, err
+		}
+	}
+	if err := bb.chain.AddBlock(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
 func organizeTxs(state state.AccountTrie, txs Transactions) Transactions {
 	txsRoot := DeriveHash(txs)
 	log.Info("organizeTxs", "cnt", len(txs), "txsRoot", txsRoot)
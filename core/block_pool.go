@@ -45,6 +45,9 @@ import (
 
 const TooFarBlocks = 120
 
+// blocks asked for at a time when syncing
+const syncBatchSize = 64
+
 var (
 	ErrBlockChainID        = errors.New("block chainID mismatch")
 	ErrBlockTooFarForChain = errors.New("block too far for chain head")
@@ -127,6 +130,7 @@ func (bp *BlockPool) Stop() {
 func (bp *BlockPool) AddSealRequest(h, t uint64, txs Transactions) {
 	req := &sealRequest{
 		h:   h,
+		t:   t,
 		txs: txs,
 	}
 	bp.sealChan <- req
@@ -170,7 +174,7 @@ func (bp *BlockPool) processMsgHeader(msg p2p.Message) {
 
 	var h = new(corepb.SignedBlockHeader)
 	if err := proto.Unmarshal(msg.Data, h); err != nil {
-		bp.markBadPeer(msg)
+		bp.markBadPeer(msg, err)
 		return
 	}
 	// TODO:
@@ -183,18 +187,22 @@ func (bp *BlockPool) processMsgBlock(msg p2p.Message) {
 	var b = new(Block)
 	if err := b.setBytes(msg.Data); err != nil {
 		log.Warn("block decode failure", "msg", msg)
-		bp.markBadPeer(msg)
+		bp.markBadPeer(msg, err)
 		return
 	}
+	b.origin = msg.From
 	bp.processBlock(b)
 }
 
 func (bp *BlockPool) processBlock(blk *Block) {
 	if err := bp.chain.verifyBlock(blk, false); err != nil {
 		log.Warn("processBlock() verify fails", "err", err)
-		// TODO: mark bad peer?
+		if ev := blockEvidence(blk, err); ev != nil {
+			bp.core.evidence.report(ev)
+		}
 		return
 	}
+	bp.core.checkEquivocation(blk)
 	bp.blockChan <- blk
 }
 
@@ -252,6 +260,9 @@ func (bp *BlockPool) processVerifiedBlock(blk *Block) {
 			"sCnt", sigCount, "vCnt", validatorCount)
 		if err := bp.core.blockChain.AddBlock(blk); err != nil {
 			log.Warn("processBlock() add fail", "err", err)
+			if err == ErrBlockStateTrieMismatch || err == ErrBlockReceiptsMismatch {
+				bp.core.evidence.report(blockEvidence(blk, err))
+			}
 			return
 		}
 		bp.cacheNum2Hash.Add(blk.Number(), blk.Hash())
@@ -267,6 +278,27 @@ func (bp *BlockPool) processVerifiedBlock(blk *Block) {
 	}
 }
 
+var errForkBlock = errors.New("fork block")
+
+// seal hook of block built for engine output
+func (bp *BlockPool) sealBlock(b *Block) error {
+	if err := bp.core.signBlock(b); err != nil {
+		log.Crit("failed to sign block", "err", err)
+	}
+	// merge with received signatures
+	if knownBlock, ok := bp.blockMap[b.Number()]; ok {
+		if knownBlock.Hash() != b.Hash() {
+			// TODO:
+			log.Crit("fork block!!!")
+			return errForkBlock
+		}
+		if _, err := b.mergeSignature(knownBlock); err != nil {
+			log.Warn("failed to merge signature", "blk", knownBlock, "err", err)
+		}
+	}
+	return nil
+}
+
 func (bp *BlockPool) handleSealRequest(req *sealRequest) {
 	currHeight := bp.chain.CurrentBlockHeight()
 	switch {
@@ -286,39 +318,26 @@ func (bp *BlockPool) handleSealRequest(req *sealRequest) {
 			log.Crit("wrong request height", "req", req, "chain", bp.chain)
 		}
 		currBlock := bp.chain.GetBlockByNumber(currHeight)
-		currState, err := bp.chain.StateAt(currBlock.StateRoot())
+		// txs of engine output are agreed by validators, no limits applied
+		builder, err := bp.chain.NewBlockBuilder(currBlock, req.t, BlockLimits{})
 		if err != nil {
 			log.Crit("failed to get state of current block", "err", err)
 			break
 		}
-		// engine output not ordered by nonce
-		txs := organizeTxs(currState, req.txs)
-		// build next block
-		nextBlock, err := bp.chain.BuildNextBlock(currBlock, req.t, txs)
-		if err != nil {
+		// engine output not ordered by nonce, organized by builder
+		if _, err := builder.AddTxs(req.txs); err != nil {
 			log.Crit("failed to build next block", "parent", currBlock,
 				"err", err)
 		}
-		if err := bp.core.signBlock(nextBlock); err != nil {
-			log.Crit("failed to sign block", "err", err)
-		}
-		log.Info("block sealed", "H", nextBlock.header.Number, "txs", len(nextBlock.transactions), "hash", nextBlock.Hash())
-		// merge with received signatures
-		if knownBlock, ok := bp.blockMap[nextBlock.Number()]; ok {
-			if knownBlock.Hash() != nextBlock.Hash() {
-				// TODO:
-				log.Crit("fork block!!!")
-				return
-			}
-			if _, err := nextBlock.mergeSignature(knownBlock); err != nil {
-				log.Warn("failed to merge signature", "blk", knownBlock, "err", err)
-			}
-		}
-		// insert chain
-		if err := bp.chain.AddBlock(nextBlock); err != nil {
+		// sign and merge with received signatures, then insert chain
+		nextBlock, err := builder.Seal(bp.sealBlock)
+		if err == errForkBlock {
+			return
+		} else if err != nil {
 			log.Warn("failed to seal block", "err", err)
 			break
 		}
+		log.Info("block sealed", "H", nextBlock.header.Number, "txs", len(nextBlock.transactions), "hash", nextBlock.Hash())
 		bp.cacheNum2Hash.Add(nextBlock.Number(), nextBlock.Hash())
 		bp.cacheHash2Blk.Add(nextBlock.Hash(), nextBlock)
 		delete(bp.sealMap, currHeight)
@@ -371,8 +390,14 @@ func (bp *BlockPool) handleNewSignature(blk *Block) {
 	}
 }
 
-func (bp *BlockPool) markBadPeer(msg p2p.Message) {
-	// TODO: inform bad peed msg.From to p2p module
+// record message can't be decoded as evidence of the peer sent it
+func (bp *BlockPool) markBadPeer(msg p2p.Message, err error) {
+	bp.core.evidence.report(&Evidence{
+		Kind:   EvidenceBadEncoding,
+		Peer:   msg.From,
+		Reason: err.Error(),
+		Data:   msg.Data,
+	})
 }
 
 func (bp *BlockPool) startFullSync() {
@@ -401,13 +426,25 @@ func (bp *BlockPool) syncLoop() {
 	}
 	h := bp.chain.CurrentBlockHeight() + 1
 	for h <= remoteHeight {
-		b, err := bp.core.GetRemoteBlockByNumber(h)
+		count := remoteHeight - h + 1
+		if count > syncBatchSize {
+			count = syncBatchSize
+		}
+		blocks, err := bp.core.GetRemoteBlocks(&HistoryReq{Number: h, Count: count})
 		if err != nil {
-			log.Warn("failed to get remote block", "err", err)
-			return
+			// peers not serving history, ask for blocks one by one
+			log.Debug("failed to get remote blocks", "H", h, "err", err)
+			b, err := bp.core.GetRemoteBlockByNumber(h)
+			if err != nil {
+				log.Warn("failed to get remote block", "err", err)
+				return
+			}
+			blocks = []*Block{b}
+		}
+		for _, b := range blocks {
+			bp.processBlock(b)
 		}
-		bp.processBlock(b)
-		h++
+		h += uint64(len(blocks))
 	}
 	log.Info("block pool sync finished")
 }
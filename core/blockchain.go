@@ -23,25 +23,31 @@ package core
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"sync"
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/common/address"
 	"github.com/yeeco/gyee/consensus"
-	"github.com/yeeco/gyee/core/pb"
 	"github.com/yeeco/gyee/core/state"
+	"github.com/yeeco/gyee/core/yvm"
 	"github.com/yeeco/gyee/crypto"
 	"github.com/yeeco/gyee/log"
 	"github.com/yeeco/gyee/persistent"
+	"github.com/yeeco/gyee/utils/datadir"
 )
 
 // chainData types used to query from peers
 const (
-	ChainDataTypeLatestH = "latestH" // latest block hash
-	ChainDataTypeLatestN = "latestN" // latest block number
-	ChainDataTypeBlockH  = "blkH"    // block for given hash
-	ChainDataTypeBlockN  = "blkN"    // block for given number
+	ChainDataTypeLatestH   = "latestH" // latest block hash
+	ChainDataTypeLatestN   = "latestN" // latest block number
+	ChainDataTypeBlockH    = "blkH"    // block for given hash
+	ChainDataTypeBlockN    = "blkN"    // block for given number
+	ChainDataTypeHeaders   = "hdrs"    // signed headers of a range of blocks
+	ChainDataTypeBodies    = "bodies"  // bodies of a range of blocks
+	ChainDataTypeTrieNodes = "nodes"   // state trie nodes of given hashes
 )
 
 var (
@@ -51,6 +57,8 @@ var (
 	ErrBlockParentMissing     = errors.New("core.chain: block parent missing")
 	ErrBlockParentMismatch    = errors.New("core.chain: block parent mismatch")
 	ErrBlockSignatureMismatch = errors.New("core.chain: block signature mismatch")
+	ErrBlockReceiptsMismatch  = errors.New("core.chain: receipts root hash mismatch")
+	ErrBlockChainHeadMissing  = errors.New("core.chain: last block missing in storage")
 )
 
 // BlockChain is a Data Manager that
@@ -71,15 +79,52 @@ type BlockChain struct {
 
 	chainmu sync.RWMutex
 
+	checkpointEpoch uint64 // blocks of an epoch, no checkpoint written if 0
+	checkpointDir   string // where checkpoint files written
+
+	freezer        *persistent.Freezer // cold store of old blocks, nil if not enabled
+	freezeDistance uint64              // blocks behind head moved to freezer, not moving if 0
+
+	gasLimit     uint64          // gas limit of blocks
+	feeRecipient *common.Address // where tx fees go, burned if nil
+	vm           yvm.YVM         // executing contract-bearing txs
+	accountIndex bool            // index txs by accounts
+	events       *eventFeed      // events of chain and tx pool
+
 	stopped int32          // state
 	wg      sync.WaitGroup // sub routine wait group
 }
 
 func NewBlockChainWithCore(core *Core) (*BlockChain, error) {
-	return NewBlockChain(ChainID(core.config.Chain.ChainID), core.storage, core.engine)
+	bc, err := newBlockChain(ChainID(core.config.Chain.ChainID), core.storage, core.engine, core.freezer)
+	if err != nil {
+		return nil, err
+	}
+	bc.freezeDistance = core.config.Chain.FreezeDistance
+	if core.yvm != nil {
+		bc.vm = core.yvm
+	}
+	bc.accountIndex = core.config.Chain.AccountIndex
+	bc.checkpointEpoch = core.config.Chain.CheckpointEpoch
+	bc.checkpointDir = datadir.New(core.config.NodeDir).CheckpointDir()
+	if core.config.Chain.BlockGasLimit > 0 {
+		bc.gasLimit = core.config.Chain.BlockGasLimit
+	}
+	if recipient := core.config.Chain.FeeRecipient; recipient != "" {
+		addr, err := address.AddressParse(recipient)
+		if err != nil {
+			return nil, err
+		}
+		bc.feeRecipient = addr.CommonAddress()
+	}
+	return bc, nil
 }
 
 func NewBlockChain(chainID ChainID, storage persistent.Storage, engine consensus.Engine) (*BlockChain, error) {
+	return newBlockChain(chainID, storage, engine, nil)
+}
+
+func newBlockChain(chainID ChainID, storage persistent.Storage, engine consensus.Engine, freezer *persistent.Freezer) (*BlockChain, error) {
 	log.Info("Create New Blockchain")
 
 	// check storage
@@ -90,12 +135,20 @@ func NewBlockChain(chainID ChainID, storage persistent.Storage, engine consensus
 	if err := prepareStorage(storage, chainID); err != nil {
 		return nil, err
 	}
+	if frozen := getFrozen(storage); frozen > 0 && (freezer == nil || freezer.Items() < frozen) {
+		return nil, ErrChainFreezerMissing
+	}
 
 	bc := &BlockChain{
 		chainID: chainID,
 		storage: storage,
 		stateDB: GetStateDB(storage),
 		engine:  engine,
+		freezer: freezer,
+
+		gasLimit: DefaultBlockGasLimit,
+		vm:       yvm.NewNoopVM(),
+		events:   newEventFeed(),
 	}
 
 	bc.genesis = bc.GetBlockByNumber(0)
@@ -151,6 +204,31 @@ func (bc *BlockChain) loadLastBlock() error {
 	return nil
 }
 
+// Reload last block from storage written by another process, for chain
+// opened read-only. Blocks added since are posted as new heads.
+func (bc *BlockChain) RefreshLastBlock() error {
+	last := bc.LastBlock()
+	lastHash := getLastBlock(bc.storage)
+	if lastHash == common.EmptyHash || lastHash == last.Hash() {
+		return nil
+	}
+	b := bc.GetBlockByHash(lastHash)
+	if b == nil {
+		return ErrBlockChainHeadMissing
+	}
+	if err := b.prepareTrie(bc.stateDB); err != nil {
+		return err
+	}
+	for number := last.Number() + 1; number < b.Number(); number++ {
+		if added := bc.GetBlockByNumber(number); added != nil {
+			bc.events.postBlock(added)
+		}
+	}
+	bc.lastBlock.Store(b)
+	bc.events.postBlock(b)
+	return nil
+}
+
 // repair broken lastBlock trie, by rewinding through chain
 func (bc *BlockChain) repair(head **Block) error {
 	for {
@@ -221,11 +299,18 @@ func (bc *BlockChain) storeBlock(b *Block) error {
 			if err != nil {
 				return err
 			}
-			// replay txs from prev block
-			_, err = bc.replayTxs(stateTrie, b.transactions)
+			// replay txs from prev block, charged if block metered
+			var gp *GasPool
+			if b.header.Metered() {
+				gp = NewGasPool(b.GasLimit(), bc.feeRecipient)
+			}
+			_, receipts, err := bc.replayTxs(stateTrie, b.header, b.transactions, gp)
 			if err != nil {
 				return err
 			}
+			if DeriveHash(receipts) != b.header.ReceiptsRoot {
+				return ErrBlockReceiptsMismatch
+			}
 			// check state root hash
 			h, err := stateTrie.Commit()
 			if err != nil {
@@ -236,11 +321,20 @@ func (bc *BlockChain) storeBlock(b *Block) error {
 			}
 			// all set
 			b.stateTrie = stateTrie
+			b.receipts = receipts
 		}
 	}
 
 	batch := bc.storage.NewBatch()
 
+	if bc.accountIndex {
+		// blocks stored before already indexed
+		if stored, err := bc.storage.Has(keyBlockHash2Num(b.Hash())); err != nil {
+			return err
+		} else if !stored {
+			bc.indexAccountTxs(batch, b)
+		}
+	}
 	if err := b.Write(batch); err != nil {
 		return err
 	}
@@ -265,6 +359,9 @@ func (bc *BlockChain) AddBlock(b *Block) error {
 			return ErrBlockParentMismatch
 		}
 	}
+	if err := bc.verifyCheckpoint(b); err != nil {
+		return err
+	}
 	// add to storage
 	if err := bc.storeBlock(b); err != nil {
 		return err
@@ -273,7 +370,9 @@ func (bc *BlockChain) AddBlock(b *Block) error {
 		return err
 	}
 
+	putLastBlock(bc.storage, b.Hash())
 	bc.lastBlock.Store(b)
+	bc.events.postBlock(b)
 
 	if engine := bc.engine; engine != nil {
 		txs := make([]common.Hash, 0, len(b.transactions))
@@ -282,6 +381,7 @@ func (bc *BlockChain) AddBlock(b *Block) error {
 		}
 		engine.OnTxSealed(b.Number(), txs)
 	}
+	bc.writeCheckpoint(b)
 
 	return nil
 }
@@ -295,11 +395,11 @@ func (bc *BlockChain) GetBlockByNumber(number uint64) *Block {
 }
 
 func (bc *BlockChain) GetBlockByHash(hash common.Hash) *Block {
-	signedHeader := getHeader(bc.storage, hash)
+	signedHeader := bc.readHeader(hash)
 	if signedHeader == nil {
 		return nil
 	}
-	body := getBlockBody(bc.storage, hash)
+	body := bc.readBody(hash)
 	if body == nil {
 		return nil
 	}
@@ -340,66 +440,84 @@ func (bc *BlockChain) GetTxByHash(hash common.Hash) *Transaction {
 
 // Build Next block from parent block, with transactions
 func (bc *BlockChain) BuildNextBlock(parent *Block, t uint64, txs Transactions) (*Block, error) {
-	var err error
-	next := &Block{
-		header:       CopyHeader(parent.header),
-		body:         new(corepb.BlockBody),
-		transactions: make(Transactions, 0, len(txs)),
-	}
-	// next block number
-	next.header.Number++
-	next.header.ParentHash = parent.Hash()
-	next.header.Time = t
-
-	// block trie
-	if err = next.prepareTrie(bc.stateDB); err != nil {
-		return nil, err
-	}
-
-	// iterate txs for state changes
-	next.transactions, err = bc.replayTxs(next.stateTrie, txs)
+	bb, err := bc.NewBlockBuilder(parent, t, BlockLimits{})
 	if err != nil {
-		log.Crit("replayTxs", "err", err)
-	}
-
-	if err := next.updateBody(); err != nil {
 		return nil, err
 	}
-
-	if err := next.updateHeader(); err != nil {
-		return nil, err
+	for _, tx := range txs {
+		if err := bb.addTx(tx); err != nil {
+			return nil, err
+		}
 	}
-	return next, nil
+	return bb.Build()
 }
 
-func (bc *BlockChain) replayTxs(stateTrie state.AccountTrie, txs Transactions) (Transactions, error) {
+func (bc *BlockChain) replayTxs(stateTrie state.AccountTrie, header *BlockHeader, txs Transactions, gp *GasPool) (Transactions, Receipts, error) {
 	inBlockTxs := make(Transactions, 0, len(txs))
+	var receipts Receipts
 	for _, tx := range txs {
-		if tx.from == nil {
-			continue
+		receipt, ok, err := bc.execTx(stateTrie, header, tx, gp)
+		if err != nil {
+			return nil, nil, err
 		}
-		accountFrom := stateTrie.GetAccount(*tx.from, false)
-		if accountFrom == nil {
-			// TODO: mark tx failure
+		if !ok {
 			continue
 		}
-		if accountFrom.Nonce() != tx.nonce {
-			// TODO: mark tx failure
-			continue
+		inBlockTxs = append(inBlockTxs, tx)
+		if receipt != nil {
+			receipts = append(receipts, receipt)
 		}
-		if accountFrom.Balance().Cmp(tx.amount) < 0 {
+	}
+	return inBlockTxs, receipts, nil
+}
+
+// execute tx against state, false if it failed and state not changed. gas is
+// metered with gp, the fee charged goes to recipient of it; nothing charged
+// if gp is nil, for blocks before gas metering.
+func applyTx(stateTrie state.AccountTrie, tx *Transaction, gp *GasPool) bool {
+	if tx.from == nil {
+		return false
+	}
+	var (
+		gas  uint64
+		cost = tx.amount
+		fee  *big.Int
+	)
+	if gp != nil {
+		gas = IntrinsicGas(tx)
+		if tx.GasLimit() < gas || !gp.fits(gas) {
 			// TODO: mark tx failure
-			continue
+			return false
+		}
+		fee = TxFee(tx, gas)
+		cost = new(big.Int).Add(tx.amount, fee)
+	}
+	accountFrom := stateTrie.GetAccount(*tx.from, false)
+	if accountFrom == nil {
+		// TODO: mark tx failure
+		return false
+	}
+	if accountFrom.Nonce() != tx.nonce {
+		// TODO: mark tx failure
+		return false
+	}
+	if accountFrom.Balance().Cmp(cost) < 0 {
+		// TODO: mark tx failure
+		return false
+	}
+	// checked, update balance nonce
+	accountFrom.AddNonce(1)
+	accountFrom.SubBalance(cost)
+	if to := tx.transferTo(); to != nil {
+		stateTrie.GetAccount(*to, true).AddBalance(tx.amount)
+	}
+	if gp != nil {
+		gp.used += gas
+		if fee.Sign() > 0 && gp.recipient != nil {
+			stateTrie.GetAccount(*gp.recipient, true).AddBalance(fee)
 		}
-		accountTo := stateTrie.GetAccount(*tx.to, true)
-		// checked, update balance nonce
-		accountFrom.AddNonce(1)
-		accountFrom.SubBalance(tx.amount)
-		accountTo.AddBalance(tx.amount)
-
-		inBlockTxs = append(inBlockTxs, tx)
 	}
-	return inBlockTxs, nil
+	return true
 }
 
 func (bc *BlockChain) LastBlock() *Block {
@@ -506,16 +624,35 @@ func (bc *BlockChain) verifyBlock(b *Block, next bool) error {
 	if err := b.VerifyBody(); err != nil {
 		return err
 	}
+	// verify gas recorded in header
+	if err := verifyBlockGas(b, bc.gasLimit); err != nil {
+		return err
+	}
+	// verify txs within validity window at block time
+	if err := verifyTxWindows(b); err != nil {
+		return err
+	}
 	// verify block signature
 	return bc.verifySignature(b, next)
 }
 
+// check if txs of block are valid at block time, in milli seconds
+func verifyTxWindows(b *Block) error {
+	now := b.Time() / 1000
+	for _, tx := range b.transactions {
+		if err := tx.ValidAt(now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // check if tx is valid and belongs to chain
 func (bc *BlockChain) verifyTx(tx *Transaction) error {
 	if ChainID(tx.chainID) != bc.chainID {
 		return ErrTxChainID
 	}
-	return nil
+	return tx.Validate()
 }
 
 func GetStateDB(storage persistent.Storage) state.Database {
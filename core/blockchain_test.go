@@ -26,6 +26,8 @@ import (
 
 	"github.com/yeeco/gyee/common"
 	"github.com/yeeco/gyee/common/address"
+	"github.com/yeeco/gyee/core/state"
+	"github.com/yeeco/gyee/core/yvm"
 	"github.com/yeeco/gyee/persistent"
 )
 
@@ -114,6 +116,353 @@ func TestBlockChainGrow(t *testing.T) {
 	chain.Stop()
 }
 
+func TestBlockChainReadOnly(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "yee-chain-test")
+	if err != nil {
+		t.Fatalf("TempDir() %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	storage, err := persistent.NewLevelStorage(tmpDir)
+	if err != nil {
+		t.Fatalf("newDB() %v", err)
+	}
+	defer storage.Close()
+	chain, err := NewBlockChain(TestNetID, storage, nil)
+	if err != nil {
+		t.Fatalf("newChain() %v", err)
+	}
+
+	roStorage, err := persistent.NewReadOnlyLevelStorage(tmpDir)
+	if err != nil {
+		t.Fatalf("NewReadOnlyLevelStorage() %v", err)
+	}
+	defer roStorage.Close()
+	roChain, err := NewBlockChain(TestNetID, roStorage, nil)
+	if err != nil {
+		t.Fatalf("NewBlockChain() read-only %v", err)
+	}
+	sub := roChain.SubscribeEvents(4, EventNewHead)
+	defer sub.Unsubscribe()
+
+	lastBlock := chain.LastBlock()
+	for i := 0; i < 2; i++ {
+		if lastBlock, err = chain.BuildNextBlock(lastBlock, 0, nil); err != nil {
+			t.Fatalf("BuildNextBlock() %v", err)
+		}
+		if err := chain.AddBlock(lastBlock); err != nil {
+			t.Fatalf("AddBlock() %v", err)
+		}
+	}
+	if err := roChain.RefreshLastBlock(); err != nil || roChain.CurrentBlockHeight() != 0 {
+		t.Fatalf("RefreshLastBlock() before db refresh got %d %v", roChain.CurrentBlockHeight(), err)
+	}
+	if err := roStorage.Refresh(); err != nil {
+		t.Fatalf("Refresh() %v", err)
+	}
+	if err := roChain.RefreshLastBlock(); err != nil {
+		t.Fatalf("RefreshLastBlock() %v", err)
+	}
+	if roChain.LastBlock().Hash() != lastBlock.Hash() {
+		t.Errorf("RefreshLastBlock() got %d, want %d", roChain.CurrentBlockHeight(), lastBlock.Number())
+	}
+	for i := uint64(1); i <= 2; i++ {
+		select {
+		case ev := <-sub.C:
+			if ev.Block.Number() != i {
+				t.Errorf("new head event got %d, want %d", ev.Block.Number(), i)
+			}
+		default:
+			t.Fatalf("new head event of %d not posted", i)
+		}
+	}
+}
+
+func TestBlockChainFreezer(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "yee-chain-test")
+	if err != nil {
+		t.Fatalf("TempDir() %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	freezer, err := openChainFreezer(tmpDir, 2, false)
+	if err != nil {
+		t.Fatalf("openChainFreezer() %v", err)
+	}
+	defer freezer.Close()
+	storage := persistent.NewMemoryStorage()
+	chain, err := newBlockChain(TestNetID, storage, nil, freezer)
+	if err != nil {
+		t.Fatalf("newChain() %v", err)
+	}
+	chain.freezeDistance = 2
+
+	blocks := []*Block{chain.LastBlock()}
+	for i := 0; i < 5; i++ {
+		b, err := chain.BuildNextBlock(blocks[len(blocks)-1], 0, nil)
+		if err != nil {
+			t.Fatalf("BuildNextBlock() %v", err)
+		}
+		if err := chain.AddBlock(b); err != nil {
+			t.Fatalf("AddBlock() %v", err)
+		}
+		blocks = append(blocks, b)
+	}
+	if moved, err := chain.freeze(); err != nil || moved != 4 {
+		t.Fatalf("freeze() got %d %v", moved, err)
+	}
+	if moved, err := chain.freeze(); err != nil || moved != 0 {
+		t.Errorf("freeze() again got %d %v", moved, err)
+	}
+	if freezer.Items() != 4 || getFrozen(storage) != 4 {
+		t.Errorf("frozen got %d %d", freezer.Items(), getFrozen(storage))
+	}
+	for _, b := range blocks {
+		frozen := b.Number() < 4
+		if inDB := getHeader(storage, b.Hash()) != nil; inDB == frozen {
+			t.Errorf("block %d in chain db %v", b.Number(), inDB)
+		}
+		got := chain.GetBlockByNumber(b.Number())
+		if got == nil || got.Hash() != b.Hash() {
+			t.Errorf("GetBlockByNumber(%d) got %v", b.Number(), got)
+		}
+	}
+
+	if _, err := NewBlockChain(TestNetID, storage, nil); err != ErrChainFreezerMissing {
+		t.Errorf("NewBlockChain() without cold store got %v", err)
+	}
+}
+
+func TestBlockBuilderLimits(t *testing.T) {
+	chain, err := NewBlockChain(TestNetID, persistent.NewMemoryStorage(), nil)
+	if err != nil {
+		t.Fatalf("newChain() %v", err)
+	}
+	genesis, err := LoadGenesis(TestNetID)
+	if err != nil || len(genesis.InitYeeDist) == 0 {
+		t.Fatalf("LoadGenesis() %v", err)
+	}
+	account0, err := address.AddressParse(genesis.InitYeeDist[0].Address)
+	if err != nil {
+		t.Fatalf("AddressParse %v", err)
+	}
+	var txs Transactions
+	for i := 9; i >= 0; i-- {
+		tx := new(Transaction)
+		tx.from = account0.CommonAddress()
+		tx.to = new(common.Address)
+		tx.to[0] = byte(i)
+		tx.amount = big.NewInt(1)
+		tx.nonce = uint64(i)
+		txs = append(txs, tx)
+	}
+
+	parent := chain.LastBlock()
+	bb, err := chain.NewBlockBuilder(parent, 1, BlockLimits{MaxTxs: 4})
+	if err != nil {
+		t.Fatalf("NewBlockBuilder() %v", err)
+	}
+	left, err := bb.AddTxs(txs)
+	if err != nil {
+		t.Fatalf("AddTxs() %v", err)
+	}
+	if len(left) != 6 || left[0].nonce != 4 {
+		t.Fatalf("AddTxs() left %d txs", len(left))
+	}
+	b, err := bb.Seal(nil)
+	if err != nil {
+		t.Fatalf("Seal() %v", err)
+	}
+	if len(b.transactions) != 4 || b.TxsRoot() != DeriveHash(b.transactions) {
+		t.Errorf("block built with %d txs, txsRoot %v", len(b.transactions), b.TxsRoot())
+	}
+	if chain.LastBlock().Hash() != b.Hash() || b.ParentHash() != parent.Hash() {
+		t.Errorf("block sealed not added to chain")
+	}
+	if b.StateRoot() == parent.StateRoot() {
+		t.Errorf("state root not updated")
+	}
+}
+
+func TestBlockGasFee(t *testing.T) {
+	chain, err := NewBlockChain(TestNetID, persistent.NewMemoryStorage(), nil)
+	if err != nil {
+		t.Fatalf("newChain() %v", err)
+	}
+	genesis, err := LoadGenesis(TestNetID)
+	if err != nil || len(genesis.InitYeeDist) == 0 {
+		t.Fatalf("LoadGenesis() %v", err)
+	}
+	account0, err := address.AddressParse(genesis.InitYeeDist[0].Address)
+	if err != nil {
+		t.Fatalf("AddressParse %v", err)
+	}
+	chain.feeRecipient = &common.Address{0xfe}
+	chain.gasLimit = TxGasIntrinsic * 10
+
+	var txs Transactions
+	for i := 0; i < 3; i++ {
+		tx := NewTransaction(uint32(TestNetID), uint64(i), &common.Address{byte(i)}, big.NewInt(1))
+		tx.SetGas(0, big.NewInt(2))
+		tx.from = account0.CommonAddress()
+		txs = append(txs, tx)
+	}
+	// tx with gas limit too low is dropped
+	low := NewTransaction(uint32(TestNetID), 3, &common.Address{3}, big.NewInt(1))
+	low.SetGas(TxGasIntrinsic, nil)
+	low.from = account0.CommonAddress()
+	txs = append(txs, low)
+
+	bb, err := chain.NewBlockBuilder(chain.LastBlock(), 1, BlockLimits{})
+	if err != nil {
+		t.Fatalf("NewBlockBuilder() %v", err)
+	}
+	if _, err := bb.AddTxs(txs); err != nil {
+		t.Fatalf("AddTxs() %v", err)
+	}
+	b, err := bb.Build()
+	if err != nil {
+		t.Fatalf("Build() %v", err)
+	}
+	if len(b.transactions) != 3 {
+		t.Fatalf("block built with %d txs", len(b.transactions))
+	}
+	var used uint64
+	for _, tx := range b.transactions {
+		used += IntrinsicGas(tx)
+	}
+	if b.GasUsed() != used || b.GasLimit() != chain.gasLimit {
+		t.Errorf("block gas %d/%d, want %d/%d", b.GasUsed(), b.GasLimit(), used, chain.gasLimit)
+	}
+	fee := b.stateTrie.GetAccount(*chain.feeRecipient, false)
+	if fee == nil || fee.Balance().Cmp(new(big.Int).SetUint64(used*2)) != 0 {
+		t.Errorf("fee not collected")
+	}
+	if err := verifyBlockGas(b, chain.gasLimit); err != nil {
+		t.Errorf("verifyBlockGas() %v", err)
+	}
+	b.header.Gas[1]--
+	if err := verifyBlockGas(b, chain.gasLimit); err != ErrBlockGasMismatch {
+		t.Errorf("verifyBlockGas() with gas used modified got %v", err)
+	}
+	b.header.Gas = nil
+	if err := verifyBlockGas(b, chain.gasLimit); err != ErrBlockGasMissing {
+		t.Errorf("verifyBlockGas() without gas got %v", err)
+	}
+}
+
+func TestAccountIndex(t *testing.T) {
+	chain, err := NewBlockChain(TestNetID, persistent.NewMemoryStorage(), nil)
+	if err != nil {
+		t.Fatalf("newChain() %v", err)
+	}
+	genesis, err := LoadGenesis(TestNetID)
+	if err != nil || len(genesis.InitYeeDist) == 0 {
+		t.Fatalf("LoadGenesis() %v", err)
+	}
+	account0, err := address.AddressParse(genesis.InitYeeDist[0].Address)
+	if err != nil {
+		t.Fatalf("AddressParse %v", err)
+	}
+	addrFrom := account0.CommonAddress()
+	addrTo := &common.Address{1}
+	if _, err := chain.GetTransactionsByAccount(*addrFrom, 0, 10); err != ErrAccountIndexDisabled {
+		t.Errorf("GetTransactionsByAccount() without index got %v", err)
+	}
+	chain.accountIndex = true
+
+	lastBlock := chain.LastBlock()
+	nonce := uint64(0)
+	for i := 0; i < 3; i++ {
+		var txs Transactions
+		for j := 0; j < 2; j++ {
+			tx := NewTransaction(uint32(TestNetID), nonce, addrTo, big.NewInt(1))
+			tx.from = addrFrom
+			txs = append(txs, tx)
+			nonce++
+		}
+		lastBlock, err = chain.BuildNextBlock(lastBlock, 0, txs)
+		if err != nil {
+			t.Fatalf("BuildNextBlock() %v", err)
+		}
+		if err := chain.AddBlock(lastBlock); err != nil {
+			t.Fatalf("AddBlock() %v", err)
+		}
+	}
+	for _, addr := range []*common.Address{addrFrom, addrTo} {
+		if count, err := chain.AccountTxCount(*addr); err != nil || count != 6 {
+			t.Errorf("AccountTxCount() %d %v", count, err)
+		}
+	}
+	txs, err := chain.GetTransactionsByAccount(*addrTo, 3, 10)
+	if err != nil || len(txs) != 3 {
+		t.Fatalf("GetTransactionsByAccount() %d %v", len(txs), err)
+	}
+	if txs[0].Number != 2 || txs[0].Index != 1 || txs[0].Tx.Nonce() != 3 {
+		t.Errorf("account tx mismatch %v", txs[0])
+	}
+	if txs, _ := chain.GetTransactionsByAccount(common.Address{2}, 0, 10); len(txs) != 0 {
+		t.Errorf("txs of account not indexed got %d", len(txs))
+	}
+}
+
+// VM consuming fixed gas and logging the payload
+type testVM struct {
+	gas uint64
+}
+
+func (vm *testVM) Execute(ctx *yvm.Context, stateTrie state.AccountTrie, msg *yvm.Message) (*yvm.Result, error) {
+	return &yvm.Result{
+		GasUsed: vm.gas,
+		Logs:    []*yvm.Log{{Address: msg.To, Data: msg.Payload}},
+	}, nil
+}
+
+func TestBlockContractTx(t *testing.T) {
+	chain, err := NewBlockChain(TestNetID, persistent.NewMemoryStorage(), nil)
+	if err != nil {
+		t.Fatalf("newChain() %v", err)
+	}
+	genesis, err := LoadGenesis(TestNetID)
+	if err != nil || len(genesis.InitYeeDist) == 0 {
+		t.Fatalf("LoadGenesis() %v", err)
+	}
+	account0, err := address.AddressParse(genesis.InitYeeDist[0].Address)
+	if err != nil {
+		t.Fatalf("AddressParse %v", err)
+	}
+	chain.vm = &testVM{gas: 1000}
+
+	plain := NewTransaction(uint32(TestNetID), 0, &common.Address{1}, big.NewInt(1))
+	plain.from = account0.CommonAddress()
+	contract := NewTransaction(uint32(TestNetID), 1, &common.Address{2}, big.NewInt(0))
+	contract.SetType(TxTypeContract)
+	contract.SetPayload([]byte("call"))
+	contract.from = account0.CommonAddress()
+
+	b, err := chain.BuildNextBlock(chain.LastBlock(), 1, Transactions{plain, contract})
+	if err != nil {
+		t.Fatalf("BuildNextBlock() %v", err)
+	}
+	if len(b.transactions) != 2 || len(b.receipts) != 1 {
+		t.Fatalf("block built with %d txs %d receipts", len(b.transactions), len(b.receipts))
+	}
+	r := b.receipts[0]
+	if r.TxHash != *contract.Hash() || len(r.Logs) != 1 || string(r.Logs[0].Data) != "call" {
+		t.Errorf("receipt mismatch %v", r)
+	}
+	if r.GasUsed != IntrinsicGas(contract)+1000 {
+		t.Errorf("receipt gas used %d", r.GasUsed)
+	}
+	if b.GasUsed() != IntrinsicGas(plain)+r.GasUsed {
+		t.Errorf("block gas used %d", b.GasUsed())
+	}
+	if b.header.ReceiptsRoot != DeriveHash(b.receipts) {
+		t.Errorf("receipts root not updated")
+	}
+	if err := verifyBlockGas(b, chain.gasLimit); err != nil {
+		t.Errorf("verifyBlockGas() %v", err)
+	}
+}
+
 func benchAddBlock(b *testing.B, storage persistent.Storage, cnt int) {
 	if err := prepareStorage(storage, TestNetID); err != nil {
 		b.Fatalf("prepareStorage() failed %v", err)
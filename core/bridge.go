@@ -0,0 +1,272 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   共识消息桥：连接共识引擎和p2p。引擎发出的event用矿工私钥签名，封装后通过验证者
+   子网广播，同时以event的hash为key存到dht，供其他节点请求缺失的父event。收到的消息
+   先按event hash去重，再验证签名者是当前验证者集合中的成员，通过后交给引擎。从dht
+   取回的event同样要验证。
+*/
+
+import (
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/hashicorp/golang-lru"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/common/address"
+	"github.com/yeeco/gyee/consensus"
+	"github.com/yeeco/gyee/crypto"
+	sha3 "github.com/yeeco/gyee/crypto/hash"
+	"github.com/yeeco/gyee/crypto/secp256k1"
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/p2p"
+)
+
+const (
+	bridgeMsgVersion  = 1                // version of signed event envelope
+	bridgeSeenSize    = 4096             // number of event hashes remembered for dedup
+	bridgeReqRetry    = 60               // times to ask dht for an event requested
+	bridgeReqInterval = time.Second      // interval between dht requests
+	bridgeRecvChSize  = 64               // size of channel to subscribe events
+	bridgeEventTTL    = 10 * time.Second // events not sent to a peer in it are dropped, peers missing them ask dht
+)
+
+var (
+	ErrBridgeMsgFormat  = errors.New("core.bridge: invalid consensus message")
+	ErrBridgeMsgVersion = errors.New("core.bridge: unsupported consensus message version")
+	ErrBridgeMsgChain   = errors.New("core.bridge: consensus message of other chain")
+	ErrBridgeMsgSigner  = errors.New("core.bridge: consensus message not signed by validator")
+)
+
+// envelope of engine events sent over p2p
+type bridgeMsg struct {
+	Version   uint
+	ChainID   uint32
+	Event     []byte
+	Signature crypto.Signature
+}
+
+// hash signed of an event, bound to the chain
+func (m *bridgeMsg) sigHash() []byte {
+	enc, _ := rlp.EncodeToBytes([]interface{}{m.Version, m.ChainID, m.Event})
+	return sha3.Sha3256(enc)
+}
+
+// ConsensusBridge carries events of the consensus engine over the validator
+// subnet: events sent by the engine are signed and broadcast, events received
+// are verified, deduplicated and delivered to the engine.
+type ConsensusBridge struct {
+	core    *Core
+	engine  consensus.Engine
+	signer  crypto.Signer
+	chainID uint32
+
+	validators func() []common.Address // current validator set
+
+	subscriber *p2p.Subscriber
+	seen       *lru.Cache // hashes of events sent or delivered
+
+	quitCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func NewConsensusBridge(core *Core, engine consensus.Engine, signer crypto.Signer) *ConsensusBridge {
+	seen, _ := lru.New(bridgeSeenSize)
+	return &ConsensusBridge{
+		core:    core,
+		engine:  engine,
+		signer:  signer,
+		chainID: uint32(core.blockChain.ChainID()),
+		validators: func() []common.Address {
+			return core.blockChain.LastBlock().ValidatorAddr()
+		},
+		seen:   seen,
+		quitCh: make(chan struct{}),
+	}
+}
+
+func (cb *ConsensusBridge) Start() {
+	cb.subscriber = p2p.NewSubscriber(cb, make(chan p2p.Message, bridgeRecvChSize), p2p.MessageTypeEvent)
+	cb.core.node.P2pService().Register(cb.subscriber)
+	cb.wg.Add(1)
+	go cb.loop()
+}
+
+func (cb *ConsensusBridge) Stop() {
+	cb.core.node.P2pService().UnRegister(cb.subscriber)
+	close(cb.quitCh)
+	cb.wg.Wait()
+}
+
+func (cb *ConsensusBridge) loop() {
+	defer cb.wg.Done()
+	for {
+		select {
+		case <-cb.quitCh:
+			return
+		case event := <-cb.engine.ChanEventSend():
+			log.Trace("engine send event")
+			cb.wg.Add(1)
+			go cb.send(event)
+		case req := <-cb.engine.ChanEventReq():
+			log.Trace("engine req event", "hash", req)
+			cb.wg.Add(1)
+			go cb.request(req)
+		case msg := <-cb.subscriber.MsgChan:
+			cb.core.metrics.p2pMsgRecv.Mark(1)
+			cb.core.metrics.p2pMsgRecvEv.Mark(1)
+			cb.receive(&msg)
+		}
+	}
+}
+
+// Sign an event of this node and encode it to be sent
+func (cb *ConsensusBridge) Seal(event []byte) ([]byte, error) {
+	msg := &bridgeMsg{
+		Version: bridgeMsgVersion,
+		ChainID: cb.chainID,
+		Event:   event,
+	}
+	sig, err := cb.signer.Sign(msg.sigHash())
+	if err != nil {
+		return nil, err
+	}
+	msg.Signature = *sig
+	return rlp.EncodeToBytes(msg)
+}
+
+// Decode an event received and verify it's signed by a validator of chain
+func (cb *ConsensusBridge) Open(data []byte) ([]byte, *common.Address, error) {
+	msg := new(bridgeMsg)
+	if err := rlp.DecodeBytes(data, msg); err != nil {
+		return nil, nil, ErrBridgeMsgFormat
+	}
+	if msg.Version != bridgeMsgVersion {
+		return nil, nil, ErrBridgeMsgVersion
+	}
+	if msg.ChainID != cb.chainID {
+		return nil, nil, ErrBridgeMsgChain
+	}
+	pubkey, err := secp256k1.NewSecp256k1Signer().RecoverPublicKey(msg.sigHash(), &msg.Signature)
+	if err != nil {
+		return nil, nil, ErrBridgeMsgSigner
+	}
+	addr, err := address.NewAddressFromPublicKey(pubkey)
+	if err != nil {
+		return nil, nil, ErrBridgeMsgSigner
+	}
+	for _, v := range cb.validators() {
+		if v == *addr.CommonAddress() {
+			return msg.Event, addr.CommonAddress(), nil
+		}
+	}
+	return nil, nil, ErrBridgeMsgSigner
+}
+
+// remember hash of event, false if seen before
+func (cb *ConsensusBridge) markSeen(hash [sha256.Size]byte) bool {
+	seen, _ := cb.seen.ContainsOrAdd(hash, struct{}{})
+	return !seen
+}
+
+func (cb *ConsensusBridge) send(event []byte) {
+	defer cb.wg.Done()
+
+	h := sha256.Sum256(event)
+	cb.markSeen(h)
+	data, err := cb.Seal(event)
+	if err != nil {
+		log.Warn("bridge seal event failed", "err", err)
+		return
+	}
+	p2pSvc := cb.core.node.P2pService()
+	cb.core.metrics.p2pDhtSetMeter.Mark(1)
+	if err := p2pSvc.DhtSetValue(h[:], data); err != nil {
+		log.Warn("engine send event to dht failed", "err", err)
+	}
+	cb.core.metrics.p2pMsgSent.Mark(1)
+	err = p2pSvc.BroadcastMessageOsn(p2p.Message{
+		MsgType: p2p.MessageTypeEvent,
+		From:    cb.core.node.NodeID(),
+		Key:     h[:],
+		Data:    data,
+		TTL:     bridgeEventTTL,
+	})
+	if err != nil {
+		log.Warn("engine send event failed", "err", err)
+		cb.core.metrics.p2pMsgSendFail.Mark(1)
+	}
+}
+
+func (cb *ConsensusBridge) receive(msg *p2p.Message) {
+	event, signer, err := cb.Open(msg.Data)
+	if err != nil {
+		log.Warn("bridge drop event", "from", msg.From, "err", err)
+		cb.core.metrics.p2pMsgRecvEvBad.Mark(1)
+		return
+	}
+	if !cb.markSeen(sha256.Sum256(event)) {
+		cb.core.metrics.p2pMsgRecvEvDup.Mark(1)
+		return
+	}
+	log.Trace("bridge deliver event", "from", msg.From, "signer", signer)
+	go cb.engine.SendEvent(event)
+}
+
+func (cb *ConsensusBridge) request(hash common.Hash) {
+	defer cb.wg.Done()
+
+	p2pSvc := cb.core.node.P2pService()
+	for retry := bridgeReqRetry; retry > 0; retry-- {
+		cb.core.metrics.p2pDhtGetMeter.Mark(1)
+		data, err := p2pSvc.DhtGetValue(hash[:])
+		if err == nil {
+			event, _, err := cb.Open(data)
+			if err == nil && sha256.Sum256(event) != hash {
+				err = ErrBridgeMsgFormat
+			}
+			if err == nil {
+				cb.core.metrics.p2pDhtHitMeter.Mark(1)
+				cb.markSeen(hash)
+				cb.engine.SendParentEvent(event)
+				return
+			}
+			log.Warn("bridge drop event from dht", "hash", hash, "err", err)
+			cb.core.metrics.p2pMsgRecvEvBad.Mark(1)
+			return
+		}
+		cb.core.metrics.p2pDhtMissMeter.Mark(1)
+		if retry == 1 {
+			log.Warn("engine req event failed", "hash", hash, "err", err)
+			return
+		}
+		select {
+		case <-cb.quitCh:
+			return
+		case <-time.After(bridgeReqInterval):
+		}
+	}
+}
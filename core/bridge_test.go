@@ -0,0 +1,111 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/hashicorp/golang-lru"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/crypto"
+)
+
+func newTestBridge(signer crypto.Signer, validators []common.Address) *ConsensusBridge {
+	seen, _ := lru.New(bridgeSeenSize)
+	return &ConsensusBridge{
+		signer:     signer,
+		chainID:    uint32(TestNetID),
+		validators: func() []common.Address { return validators },
+		seen:       seen,
+	}
+}
+
+func TestBridgeSealOpen(t *testing.T) {
+	signers, validators := newCheckpointSigners(t, 2)
+	cb := newTestBridge(signers[0], validators)
+	event := []byte("tetris event")
+
+	data, err := cb.Seal(event)
+	if err != nil {
+		t.Fatalf("Seal() %v", err)
+	}
+	got, signer, err := cb.Open(data)
+	if err != nil {
+		t.Fatalf("Open() %v", err)
+	}
+	if !bytes.Equal(got, event) || *signer != validators[0] {
+		t.Errorf("Open() got %x by %v", got, signer)
+	}
+
+	// signed by node not a validator
+	if _, _, err := newTestBridge(signers[1], validators[:1]).Open(mustSeal(t, signers[1], event)); err != ErrBridgeMsgSigner {
+		t.Errorf("Open() by non validator got %v", err)
+	}
+
+	// event modified after signed
+	msg := new(bridgeMsg)
+	if err := rlp.DecodeBytes(data, msg); err != nil {
+		t.Fatalf("DecodeBytes() %v", err)
+	}
+	msg.Event = []byte("forged event")
+	forged, _ := rlp.EncodeToBytes(msg)
+	if _, _, err := cb.Open(forged); err != ErrBridgeMsgSigner {
+		t.Errorf("Open() forged got %v", err)
+	}
+
+	// message of other chain
+	other := newTestBridge(signers[0], validators)
+	other.chainID++
+	if _, _, err := cb.Open(mustSealBy(t, other, event)); err != ErrBridgeMsgChain {
+		t.Errorf("Open() of other chain got %v", err)
+	}
+
+	if _, _, err := cb.Open([]byte("garbage")); err != ErrBridgeMsgFormat {
+		t.Errorf("Open() garbage got %v", err)
+	}
+}
+
+func TestBridgeDedup(t *testing.T) {
+	signers, validators := newCheckpointSigners(t, 1)
+	cb := newTestBridge(signers[0], validators)
+	h := sha256.Sum256([]byte("tetris event"))
+	if !cb.markSeen(h) {
+		t.Errorf("markSeen() first time got false")
+	}
+	if cb.markSeen(h) {
+		t.Errorf("markSeen() second time got true")
+	}
+}
+
+func mustSeal(t *testing.T, signer crypto.Signer, event []byte) []byte {
+	return mustSealBy(t, newTestBridge(signer, nil), event)
+}
+
+func mustSealBy(t *testing.T, cb *ConsensusBridge, event []byte) []byte {
+	data, err := cb.Seal(event)
+	if err != nil {
+		t.Fatalf("Seal() %v", err)
+	}
+	return data
+}
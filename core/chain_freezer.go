@@ -0,0 +1,210 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   区块冷存储：区块头、区块体写入后基本不再变化，和频繁更新的索引放在同一个leveldb里，
+   压缩时被反复重写。配置freeze_distance后，落后链头超过这个距离的区块（认为已经确定，
+   签名也不会再合并）由后台按区块号顺序追加到链数据库目录下的ancient冷存储，再从leveldb
+   中删除。区块号和哈希的索引仍在leveldb中，读区块时先查leveldb，没有的再按区块号从
+   冷存储中取。
+   先同步冷存储再删除leveldb中的副本，KeyFrozen记录已经删除到的区块号，中途崩溃后
+   下一轮补删。链数据库中没有存交易回执，所以冷存储只有区块头和区块体两个表。
+*/
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/core/pb"
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/persistent"
+)
+
+const (
+	freezerDir     = "ancient" // cold store under chain db path
+	freezerHeaders = "headers"
+	freezerBodies  = "bodies"
+
+	freezeInterval = time.Minute
+	freezeBatch    = 2048 // blocks moved at most by a round
+)
+
+var freezerTables = []string{freezerHeaders, freezerBodies}
+
+var ErrChainFreezerMissing = errors.New("core.chain: blocks moved to cold store missing in it")
+
+// open cold store of chain db, nil if not enabled and not created before
+func openChainFreezer(dbPath string, distance uint64, readOnly bool) (*persistent.Freezer, error) {
+	path := filepath.Join(dbPath, freezerDir)
+	if distance == 0 || readOnly {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return nil, nil
+		}
+	}
+	return persistent.OpenFreezer(path, freezerTables, readOnly)
+}
+
+// signed header of block, from chain db or the cold store if frozen
+func (bc *BlockChain) readHeader(hash common.Hash) *corepb.SignedBlockHeader {
+	if header := getHeader(bc.storage, hash); header != nil || bc.freezer == nil {
+		return header
+	}
+	header := new(corepb.SignedBlockHeader)
+	if !bc.readFrozen(freezerHeaders, hash, header) {
+		return nil
+	}
+	return header
+}
+
+// body of block, from chain db or the cold store if frozen
+func (bc *BlockChain) readBody(hash common.Hash) *corepb.BlockBody {
+	if body := getBlockBody(bc.storage, hash); body != nil || bc.freezer == nil {
+		return body
+	}
+	body := new(corepb.BlockBody)
+	if !bc.readFrozen(freezerBodies, hash, body) {
+		return nil
+	}
+	return body
+}
+
+func (bc *BlockChain) readFrozen(table string, hash common.Hash, msg proto.Message) bool {
+	number := getBlockHash2Num(bc.storage, hash)
+	if number == nil || *number >= bc.freezer.Items() ||
+		getBlockNum2Hash(bc.storage, *number) != hash {
+		return false
+	}
+	enc, err := bc.freezer.Retrieve(table, *number)
+	if err != nil {
+		log.Error("failed to read cold store", "table", table, "number", *number, "err", err)
+		return false
+	}
+	if err := proto.Unmarshal(enc, msg); err != nil {
+		log.Error("failed to decode cold store", "table", table, "number", *number, "err", err)
+		return false
+	}
+	return true
+}
+
+// move a batch of blocks behind head by freezeDistance to the cold store,
+// returns the number of blocks moved
+func (bc *BlockChain) freeze() (uint64, error) {
+	bc.wg.Add(1)
+	defer bc.wg.Done()
+	if atomic.LoadInt32(&bc.stopped) == 1 {
+		return 0, nil
+	}
+
+	frozen := getFrozen(bc.storage)
+	if items := bc.freezer.Items(); frozen < items {
+		// appended before a crash, not deleted from chain db yet
+		if err := bc.deleteFrozen(frozen, items); err != nil {
+			return 0, err
+		}
+		frozen = items
+	}
+	head := bc.CurrentBlockHeight()
+	if head < bc.freezeDistance {
+		return 0, nil
+	}
+	limit := head - bc.freezeDistance + 1
+	if limit > frozen+freezeBatch {
+		limit = frozen + freezeBatch
+	}
+	if limit <= frozen {
+		return 0, nil
+	}
+	for number := frozen; number < limit; number++ {
+		hash := getBlockNum2Hash(bc.storage, number)
+		header, err := bc.storage.Get(keyHeader(hash))
+		if err != nil {
+			return 0, err
+		}
+		body, err := bc.storage.Get(keyBlockBody(hash))
+		if err != nil {
+			return 0, err
+		}
+		if err := bc.freezer.Append(number, [][]byte{header, body}); err != nil {
+			return 0, err
+		}
+	}
+	if err := bc.freezer.Sync(); err != nil {
+		return 0, err
+	}
+	if err := bc.deleteFrozen(frozen, limit); err != nil {
+		return 0, err
+	}
+	return limit - frozen, nil
+}
+
+// delete headers and bodies of blocks in [from, to) from chain db
+func (bc *BlockChain) deleteFrozen(from, to uint64) error {
+	batch := bc.storage.NewBatch()
+	for number := from; number < to; number++ {
+		hash := getBlockNum2Hash(bc.storage, number)
+		batch.Del(keyHeader(hash))
+		batch.Del(keyBlockBody(hash))
+	}
+	if err := putFrozen(batch, to); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// move blocks out of chain db in background
+func (c *Core) freezeLoop() {
+	c.wg.Add(1)
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(freezeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.quitCh:
+			return
+		case <-ticker.C:
+		}
+		for {
+			moved, err := c.blockChain.freeze()
+			if err != nil {
+				log.Warn("failed to move blocks to cold store", "err", err)
+				break
+			}
+			if moved > 0 {
+				log.Debug("blocks moved to cold store", "count", moved, "frozen", c.freezer.Items())
+			}
+			if moved < freezeBatch {
+				break
+			}
+			select {
+			case <-c.quitCh:
+				return
+			default:
+			}
+		}
+	}
+}
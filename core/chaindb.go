@@ -24,7 +24,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/yeeco/gyee/common"
 	"github.com/yeeco/gyee/core/pb"
-	sha3 "github.com/yeeco/gyee/crypto/hash"
+	"github.com/yeeco/gyee/crypto/provider"
 	"github.com/yeeco/gyee/log"
 	"github.com/yeeco/gyee/persistent"
 )
@@ -33,7 +33,13 @@ import (
 const (
 	KeyChainID = "ChainID"
 
+	KeySchemaVersion = "SchemaVersion"
+
 	KeyLastBlock = "LastBlock"
+	// key for trusted checkpoint imported
+	KeyCheckpoint = "Checkpoint"
+	// key for number of blocks moved to the cold store
+	KeyFrozen = "Frozen"
 
 	KeyPrefixStateTrie = "sTrie-" // stateTrie Hash => trie node
 
@@ -43,6 +49,10 @@ const (
 
 	KeyPrefixBlockNum2Hash = "bn2h-" // blockNum => blockHash
 	KeyPrefixBlockHash2Num = "bh2n-" // blockHash => blockNum
+
+	KeyPrefixAccountTx = "acTx-" // address => number of txs, address+seq => blockNum+txIndex
+
+	KeyPrefixEvidence = "evid-" // => number of evidences, seq => encoded evidence
 )
 
 func prepareStorage(storage persistent.Storage, id ChainID) error {
@@ -65,11 +75,34 @@ func prepareStorage(storage persistent.Storage, id ChainID) error {
 			if err := storage.Put(key, encChainID); err != nil {
 				return err
 			}
+			// fresh storage, no migration needed
+			if err := putSchemaVersion(storage, CurrentSchemaVersion); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+func getSchemaVersion(getter persistent.Getter) (uint32, error) {
+	enc, err := getter.Get(keySchemaVersion())
+	if err == persistent.ErrKeyNotFound {
+		return SchemaVersionLegacy, nil
+	} else if err != nil {
+		return 0, err
+	}
+	if len(enc) != 4 {
+		return 0, ErrChainDBSchemaInvalid
+	}
+	return binary.BigEndian.Uint32(enc), nil
+}
+
+func putSchemaVersion(putter persistent.Putter, version uint32) error {
+	enc := make([]byte, 4)
+	binary.BigEndian.PutUint32(enc, version)
+	return putter.Put(keySchemaVersion(), enc)
+}
+
 func getLastBlock(getter persistent.Getter) common.Hash {
 	enc, err := getter.Get(keyLastBlock())
 	if err != nil {
@@ -88,6 +121,20 @@ func putLastBlock(putter persistent.Putter, hash common.Hash) {
 	}
 }
 
+func getFrozen(getter persistent.Getter) uint64 {
+	enc, _ := getter.Get(keyFrozen())
+	if len(enc) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(enc)
+}
+
+func putFrozen(putter persistent.Putter, frozen uint64) error {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, frozen)
+	return putter.Put(keyFrozen(), buf)
+}
+
 func getHeader(getter persistent.Getter, hash common.Hash) *corepb.SignedBlockHeader {
 	msg := new(corepb.SignedBlockHeader)
 	if err := getProtoMsg(getter, keyHeader(hash), msg); err != nil {
@@ -100,7 +147,7 @@ func getHeader(getter persistent.Getter, hash common.Hash) *corepb.SignedBlockHe
 }
 
 func putHeader(putter persistent.Putter, header *corepb.SignedBlockHeader) common.Hash {
-	hash := common.BytesToHash(sha3.Sha3256(header.Header))
+	hash := common.BytesToHash(provider.Current().Hash256(header.Header))
 	putProtoMsg(putter, keyHeader(hash), header)
 	return hash
 }
@@ -202,10 +249,22 @@ func keyChainID() []byte {
 	return []byte(KeyChainID)
 }
 
+func keySchemaVersion() []byte {
+	return []byte(KeySchemaVersion)
+}
+
 func keyLastBlock() []byte {
 	return []byte(KeyLastBlock)
 }
 
+func keyCheckpoint() []byte {
+	return []byte(KeyCheckpoint)
+}
+
+func keyFrozen() []byte {
+	return []byte(KeyFrozen)
+}
+
 func keyHeader(hash common.Hash) []byte {
 	return append([]byte(KeyPrefixHeader), hash[:]...)
 }
@@ -227,3 +286,23 @@ func keyBlockNum2Hash(num uint64) []byte {
 func keyTx(hash common.Hash) []byte {
 	return append([]byte(KeyPrefixTx), hash[:]...)
 }
+
+func keyAccountTxCount(addr common.Address) []byte {
+	return append([]byte(KeyPrefixAccountTx), addr[:]...)
+}
+
+func keyAccountTx(addr common.Address, seq uint64) []byte {
+	buf := append(keyAccountTxCount(addr), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(buf[len(buf)-8:], seq)
+	return buf
+}
+
+func keyEvidenceCount() []byte {
+	return []byte(KeyPrefixEvidence)
+}
+
+func keyEvidence(seq uint64) []byte {
+	buf := append(keyEvidenceCount(), make([]byte, 8)...)
+	binary.BigEndian.PutUint64(buf[len(buf)-8:], seq)
+	return buf
+}
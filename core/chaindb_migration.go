@@ -0,0 +1,141 @@
+// Copyright (C) 2019 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/persistent"
+)
+
+// Schema versions of chain db, stored under KeySchemaVersion
+const (
+	SchemaVersionLegacy   uint32 = 0 // no version key
+	SchemaVersionHash2Num uint32 = 1 // blockHash => blockNum index for all blocks
+
+	CurrentSchemaVersion = SchemaVersionHash2Num
+)
+
+// report progress every migrationLogInterval items
+const migrationLogInterval = 10000
+
+var (
+	ErrChainDBSchemaInvalid  = errors.New("core.chaindb: invalid schema version")
+	ErrChainDBSchemaTooNew   = errors.New("core.chaindb: schema version newer than supported, upgrade binary")
+	ErrChainDBMigrateDryRun  = errors.New("core.chaindb: migration dry run done, storage not opened")
+	ErrChainDBMigratePending = errors.New("core.chaindb: migration pending, can't be done read-only")
+)
+
+// dbMigration upgrades chain db from version-1 to version
+type dbMigration struct {
+	version uint32
+	desc    string
+	run     func(storage persistent.Storage, dryRun bool) error
+}
+
+// dbMigrations must be kept sorted by version
+var dbMigrations = []dbMigration{
+	{
+		version: SchemaVersionHash2Num,
+		desc:    "build blockHash to blockNum index",
+		run:     migrateHash2Num,
+	},
+}
+
+// migrateStorage brings an existing chain db to CurrentSchemaVersion.
+// Fresh storages are stamped by prepareStorage. In dry run mode pending
+// migrations are only logged, and ErrChainDBMigrateDryRun returned if any.
+func migrateStorage(storage persistent.Storage, dryRun bool) error {
+	if has, err := storage.Has(keyChainID()); err != nil {
+		return err
+	} else if !has {
+		return nil
+	}
+	version, err := getSchemaVersion(storage)
+	if err != nil {
+		return err
+	}
+	if version > CurrentSchemaVersion {
+		log.Error("chain db schema too new",
+			"version", version, "supported", CurrentSchemaVersion)
+		return ErrChainDBSchemaTooNew
+	}
+	if version == CurrentSchemaVersion {
+		return nil
+	}
+
+	for _, m := range dbMigrations {
+		if m.version <= version {
+			continue
+		}
+		log.Info("chain db migration", "from", m.version-1, "to", m.version,
+			"desc", m.desc, "dryRun", dryRun)
+		if err := m.run(storage, dryRun); err != nil {
+			log.Error("chain db migration failed", "to", m.version, "err", err)
+			return err
+		}
+		if dryRun {
+			continue
+		}
+		if err := putSchemaVersion(storage, m.version); err != nil {
+			return err
+		}
+		log.Info("chain db migration done", "version", m.version)
+	}
+	if dryRun {
+		return ErrChainDBMigrateDryRun
+	}
+	return nil
+}
+
+// walk blockNum => blockHash mapping from genesis, fill missing reverse index
+func migrateHash2Num(storage persistent.Storage, dryRun bool) error {
+	batch := storage.NewBatch()
+	missing := uint64(0)
+	num := uint64(0)
+	for ; ; num++ {
+		hash := getBlockNum2Hash(storage, num)
+		if hash == common.EmptyHash {
+			break
+		}
+		if getBlockHash2Num(storage, hash) == nil {
+			missing++
+			if !dryRun {
+				putBlockHash2Num(batch, hash, num)
+			}
+		}
+		if batch.ValueSize() >= persistent.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if num > 0 && num%migrationLogInterval == 0 {
+			log.Info("chain db migration progress", "blocks", num, "missing", missing)
+		}
+	}
+	if batch.ValueSize() > 0 {
+		if err := batch.Write(); err != nil {
+			return err
+		}
+	}
+	log.Info("chain db migration scanned", "blocks", num, "missing", missing)
+	return nil
+}
@@ -66,3 +66,50 @@ func TestChainDB(t *testing.T) {
 		t.Errorf("getBlockBody() exist got %v", b)
 	}
 }
+
+func TestChainDBMigration(t *testing.T) {
+	mem := persistent.NewMemoryStorage()
+	if err := prepareStorage(mem, MainNetID); err != nil {
+		t.Fatalf("prepareStorage %v", err)
+	}
+	if v, err := getSchemaVersion(mem); err != nil || v != CurrentSchemaVersion {
+		t.Fatalf("fresh storage version %v %v", v, err)
+	}
+
+	// simulate legacy db: num => hash mapping only, no version key
+	if err := mem.Del(keySchemaVersion()); err != nil {
+		t.Fatal(err)
+	}
+	hashes := make([]common.Hash, 3)
+	for i := range hashes {
+		hashes[i] = common.BytesToHash([]byte{byte(i + 1)})
+		putBlockNum2Hash(mem, uint64(i), hashes[i])
+	}
+
+	if err := migrateStorage(mem, true); err != ErrChainDBMigrateDryRun {
+		t.Fatalf("dry run got %v", err)
+	}
+	if getBlockHash2Num(mem, hashes[0]) != nil {
+		t.Fatalf("dry run changed storage")
+	}
+
+	if err := migrateStorage(mem, false); err != nil {
+		t.Fatalf("migrateStorage %v", err)
+	}
+	for i, hash := range hashes {
+		if num := getBlockHash2Num(mem, hash); num == nil || *num != uint64(i) {
+			t.Errorf("hash2num for block %d got %v", i, num)
+		}
+	}
+	if v, _ := getSchemaVersion(mem); v != CurrentSchemaVersion {
+		t.Fatalf("migrated version %v", v)
+	}
+
+	// storage from a newer binary must be refused
+	if err := putSchemaVersion(mem, CurrentSchemaVersion+1); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrateStorage(mem, false); err != ErrChainDBSchemaTooNew {
+		t.Fatalf("newer schema got %v", err)
+	}
+}
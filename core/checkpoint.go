@@ -0,0 +1,279 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   checkpoint：每个epoch（ChainConfig.CheckpointEpoch个区块）的最后一个区块生成一个
+   checkpoint，包括区块头hash，state root，验证者集合和验证者对区块头的签名，写到
+   紧凑的checkpoint文件中。新节点可以导入checkpoint文件作为可信起点，导入前用本地
+   可信的验证者集合验证签名，签名数达到2/3以上才接受。
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/common/address"
+	"github.com/yeeco/gyee/crypto"
+	sha3 "github.com/yeeco/gyee/crypto/hash"
+	"github.com/yeeco/gyee/crypto/secp256k1"
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/persistent"
+)
+
+const (
+	checkpointMagic   = "GYEECP" // leading magic of checkpoint files
+	checkpointVersion = 1        // format version following the magic
+	checkpointFileFmt = "%d-%d.cp"
+)
+
+var (
+	ErrCheckpointFormat     = errors.New("core.checkpoint: invalid checkpoint file")
+	ErrCheckpointVersion    = errors.New("core.checkpoint: unsupported checkpoint version")
+	ErrCheckpointHeader     = errors.New("core.checkpoint: header mismatch")
+	ErrCheckpointSignature  = errors.New("core.checkpoint: signature of unknown validator")
+	ErrCheckpointQuorum     = errors.New("core.checkpoint: not enough validator signatures")
+	ErrCheckpointNoTrust    = errors.New("core.checkpoint: no trusted validator set")
+	ErrCheckpointMismatch   = errors.New("core.checkpoint: block mismatch with trusted checkpoint")
+	ErrCheckpointBlockUnset = errors.New("core.checkpoint: block not found")
+)
+
+// Checkpoint of a chain, a block header signed by the validators
+type Checkpoint struct {
+	ChainID    uint32
+	Number     uint64
+	Hash       common.Hash
+	StateRoot  common.Hash
+	Header     []byte           // rlp encoded header, hashed to Hash
+	Validators []common.Address // validator set of the block, for next epochs
+	Signatures []crypto.Signature
+}
+
+// Create checkpoint from a stored block
+func NewCheckpoint(b *Block) (*Checkpoint, error) {
+	if b == nil || b.pbHeader == nil {
+		return nil, ErrCheckpointBlockUnset
+	}
+	cp := &Checkpoint{
+		ChainID:    b.ChainID(),
+		Number:     b.Number(),
+		Hash:       b.Hash(),
+		StateRoot:  b.StateRoot(),
+		Header:     b.pbHeader.Header,
+		Validators: b.ValidatorAddr(),
+	}
+	for _, sig := range b.pbHeader.Signatures {
+		cp.Signatures = append(cp.Signatures, crypto.Signature{
+			Algorithm: crypto.Algorithm(sig.SigAlgorithm),
+			Signature: sig.Signature,
+		})
+	}
+	return cp, nil
+}
+
+// Verify checkpoint against a trusted validator set, more than 2/3 of them
+// must have signed the header, and no one else.
+func (cp *Checkpoint) Verify(trusted []common.Address) error {
+	if len(trusted) == 0 {
+		return ErrCheckpointNoTrust
+	}
+	header := new(BlockHeader)
+	if err := rlp.DecodeBytes(cp.Header, header); err != nil {
+		return ErrCheckpointFormat
+	}
+	if common.BytesToHash(sha3.Sha3256(cp.Header)) != cp.Hash ||
+		header.ChainID != cp.ChainID ||
+		header.Number != cp.Number ||
+		header.StateRoot != cp.StateRoot {
+		return ErrCheckpointHeader
+	}
+	validators := make(map[common.Address]bool, len(trusted))
+	for _, addr := range trusted {
+		validators[addr] = true
+	}
+	signer := secp256k1.NewSecp256k1Signer()
+	signers := make(map[common.Address]bool, len(cp.Signatures))
+	for idx := range cp.Signatures {
+		pubkey, err := signer.RecoverPublicKey(cp.Hash[:], &cp.Signatures[idx])
+		if err != nil {
+			return err
+		}
+		addr, err := address.NewAddressFromPublicKey(pubkey)
+		if err != nil {
+			return err
+		}
+		if !validators[*addr.CommonAddress()] {
+			return ErrCheckpointSignature
+		}
+		signers[*addr.CommonAddress()] = true
+	}
+	if len(signers)*3 < len(validators)*2 {
+		return ErrCheckpointQuorum
+	}
+	return nil
+}
+
+func (cp *Checkpoint) ToBytes() ([]byte, error) {
+	enc, err := rlp.EncodeToBytes(cp)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, len(checkpointMagic)+1+len(enc))
+	buf = append(buf, checkpointMagic...)
+	buf = append(buf, checkpointVersion)
+	return append(buf, enc...), nil
+}
+
+func ParseCheckpoint(enc []byte) (*Checkpoint, error) {
+	if len(enc) <= len(checkpointMagic) || !bytes.HasPrefix(enc, []byte(checkpointMagic)) {
+		return nil, ErrCheckpointFormat
+	}
+	if enc[len(checkpointMagic)] != checkpointVersion {
+		return nil, ErrCheckpointVersion
+	}
+	cp := new(Checkpoint)
+	if err := rlp.DecodeBytes(enc[len(checkpointMagic)+1:], cp); err != nil {
+		return nil, ErrCheckpointFormat
+	}
+	return cp, nil
+}
+
+// Write checkpoint to file, it's replaced as a whole
+func WriteCheckpointFile(path string, cp *Checkpoint) error {
+	enc, err := cp.ToBytes()
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, enc, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func ReadCheckpointFile(path string) (*Checkpoint, error) {
+	enc, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseCheckpoint(enc)
+}
+
+// name of checkpoint file for block of chain
+func CheckpointFileName(chainID uint32, number uint64) string {
+	return fmt.Sprintf(checkpointFileFmt, chainID, number)
+}
+
+// Get checkpoint of block stored in chain
+func (bc *BlockChain) Checkpoint(number uint64) (*Checkpoint, error) {
+	return NewCheckpoint(bc.GetBlockByNumber(number))
+}
+
+// Get trusted checkpoint imported, nil if none
+func (bc *BlockChain) TrustedCheckpoint() *Checkpoint {
+	enc, err := bc.storage.Get(keyCheckpoint())
+	if err != nil {
+		if err != persistent.ErrKeyNotFound {
+			log.Error("TrustedCheckpoint()", "err", err)
+		}
+		return nil
+	}
+	cp, err := ParseCheckpoint(enc)
+	if err != nil {
+		log.Error("TrustedCheckpoint()", "err", err)
+		return nil
+	}
+	return cp
+}
+
+// Verify checkpoint with the validator set of the trusted checkpoint imported
+// before if it's newer than local blocks, or that of the last local block, the
+// genesis at least.
+func (bc *BlockChain) VerifyCheckpoint(cp *Checkpoint) error {
+	if ChainID(cp.ChainID) != bc.chainID {
+		return ErrBlockChainIDMismatch
+	}
+	trusted := bc.LastBlock().ValidatorAddr()
+	if prev := bc.TrustedCheckpoint(); prev != nil && prev.Number > bc.CurrentBlockHeight() {
+		trusted = prev.Validators
+	}
+	if err := cp.Verify(trusted); err != nil {
+		return err
+	}
+	if local := bc.GetBlockNum2Hash(cp.Number); local != nil && *local != cp.Hash {
+		return ErrCheckpointMismatch
+	}
+	return nil
+}
+
+// Import checkpoint as trusted start point of chain after it's verified
+func (bc *BlockChain) ImportCheckpoint(cp *Checkpoint) error {
+	if err := bc.VerifyCheckpoint(cp); err != nil {
+		return err
+	}
+	enc, err := cp.ToBytes()
+	if err != nil {
+		return err
+	}
+	return bc.storage.Put(keyCheckpoint(), enc)
+}
+
+// check block against trusted checkpoint, blocks of other forks are rejected
+func (bc *BlockChain) verifyCheckpoint(b *Block) error {
+	if cp := bc.TrustedCheckpoint(); cp != nil && cp.Number == b.Number() && cp.Hash != b.Hash() {
+		return ErrCheckpointMismatch
+	}
+	return nil
+}
+
+// write checkpoint file for block added if it ends an epoch
+func (bc *BlockChain) writeCheckpoint(b *Block) {
+	if bc.checkpointEpoch == 0 || b.Number() == 0 || b.Number()%bc.checkpointEpoch != 0 {
+		return
+	}
+	cp, err := NewCheckpoint(b)
+	if err != nil {
+		log.Warn("writeCheckpoint()", "H", b.Number(), "err", err)
+		return
+	}
+	if parent := bc.GetBlockByNumber(b.Number() - 1); parent != nil {
+		if err := cp.Verify(parent.ValidatorAddr()); err != nil {
+			log.Warn("writeCheckpoint() not verifiable, skipped", "H", b.Number(), "err", err)
+			return
+		}
+	}
+	if err := os.MkdirAll(bc.checkpointDir, 0755); err != nil {
+		log.Warn("writeCheckpoint()", "dir", bc.checkpointDir, "err", err)
+		return
+	}
+	path := filepath.Join(bc.checkpointDir, CheckpointFileName(cp.ChainID, cp.Number))
+	if err := WriteCheckpointFile(path, cp); err != nil {
+		log.Warn("writeCheckpoint()", "path", path, "err", err)
+		return
+	}
+	log.Info("checkpoint written", "H", cp.Number, "hash", cp.Hash, "path", path)
+}
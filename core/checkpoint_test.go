@@ -0,0 +1,119 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/common/address"
+	"github.com/yeeco/gyee/crypto"
+	"github.com/yeeco/gyee/crypto/secp256k1"
+	"github.com/yeeco/gyee/persistent"
+)
+
+func newCheckpointSigners(t *testing.T, cnt int) ([]crypto.Signer, []common.Address) {
+	signers := make([]crypto.Signer, 0, cnt)
+	addrs := make([]common.Address, 0, cnt)
+	for i := 0; i < cnt; i++ {
+		key := secp256k1.GenerateKey()
+		signer := secp256k1.NewSecp256k1Signer()
+		if err := signer.InitSigner(key.PrivateKey()); err != nil {
+			t.Fatalf("InitSigner() %v", err)
+		}
+		addr, err := address.NewAddressFromPublicKey(key.PublicKey())
+		if err != nil {
+			t.Fatalf("NewAddressFromPublicKey() %v", err)
+		}
+		signers = append(signers, signer)
+		addrs = append(addrs, *addr.CommonAddress())
+	}
+	return signers, addrs
+}
+
+func newCheckpointBlock(t *testing.T, signers []crypto.Signer) (*BlockChain, *Block) {
+	chain, err := NewBlockChain(TestNetID, persistent.NewMemoryStorage(), nil)
+	if err != nil {
+		t.Fatalf("NewBlockChain() %v", err)
+	}
+	b, err := chain.BuildNextBlock(chain.LastBlock(), 0, nil)
+	if err != nil {
+		t.Fatalf("BuildNextBlock() %v", err)
+	}
+	for _, signer := range signers {
+		if err := b.Sign(signer); err != nil {
+			t.Fatalf("Sign() %v", err)
+		}
+	}
+	if err := chain.AddBlock(b); err != nil {
+		t.Fatalf("AddBlock() %v", err)
+	}
+	return chain, b
+}
+
+func TestCheckpointVerify(t *testing.T) {
+	signers, trusted := newCheckpointSigners(t, 3)
+	_, b := newCheckpointBlock(t, signers[:2])
+	cp, err := NewCheckpoint(b)
+	if err != nil {
+		t.Fatalf("NewCheckpoint() %v", err)
+	}
+	if err := cp.Verify(trusted); err != nil {
+		t.Errorf("Verify() with quorum %v", err)
+	}
+	if err := cp.Verify(append(trusted, common.Address{1})); err != ErrCheckpointQuorum {
+		t.Errorf("Verify() without quorum got %v", err)
+	}
+	if err := cp.Verify(trusted[1:]); err != ErrCheckpointSignature {
+		t.Errorf("Verify() with unknown signer got %v", err)
+	}
+	cp.StateRoot[0] ^= 0xff
+	if err := cp.Verify(trusted); err != ErrCheckpointHeader {
+		t.Errorf("Verify() with header modified got %v", err)
+	}
+}
+
+func TestCheckpointFile(t *testing.T) {
+	signers, trusted := newCheckpointSigners(t, 1)
+	chain, b := newCheckpointBlock(t, signers)
+	cp, err := chain.Checkpoint(b.Number())
+	if err != nil {
+		t.Fatalf("Checkpoint() %v", err)
+	}
+	path := filepath.Join(t.TempDir(), CheckpointFileName(cp.ChainID, cp.Number))
+	if err := WriteCheckpointFile(path, cp); err != nil {
+		t.Fatalf("WriteCheckpointFile() %v", err)
+	}
+	got, err := ReadCheckpointFile(path)
+	if err != nil {
+		t.Fatalf("ReadCheckpointFile() %v", err)
+	}
+	if got.Hash != b.Hash() || got.Number != b.Number() || len(got.Signatures) != 1 {
+		t.Errorf("checkpoint mismatch, got %v", got)
+	}
+	if err := got.Verify(trusted); err != nil {
+		t.Errorf("Verify() %v", err)
+	}
+	if _, err := ParseCheckpoint([]byte("not a checkpoint")); err != ErrCheckpointFormat {
+		t.Errorf("ParseCheckpoint() got %v", err)
+	}
+}
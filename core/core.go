@@ -40,12 +40,10 @@ package core
 
 */
 import (
-	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -58,16 +56,28 @@ import (
 	"github.com/yeeco/gyee/core/yvm"
 	"github.com/yeeco/gyee/crypto"
 	"github.com/yeeco/gyee/crypto/keystore"
+	"github.com/yeeco/gyee/crypto/provider"
 	"github.com/yeeco/gyee/crypto/secp256k1"
 	"github.com/yeeco/gyee/log"
 	"github.com/yeeco/gyee/p2p"
 	"github.com/yeeco/gyee/persistent"
+	"github.com/yeeco/gyee/utils/datadir"
+	"github.com/yeeco/gyee/version"
 )
 
 var (
 	ErrNoCoinbase          = errors.New("coinbase not provided")
 	ErrNoCoinbasePwdFile   = errors.New("coinbase keystore password file not provided")
 	ErrCoinbaseKeyNotFound = errors.New("coinbase not found in keystore")
+	ErrNoPeerVersions      = errors.New("p2p service does not report peer versions")
+	ErrNoAcceptStats       = errors.New("p2p service does not report accept stats")
+	ErrNoKillStats         = errors.New("p2p service does not report kill stats")
+	ErrCoreReadOnly        = errors.New("core opened read-only")
+)
+
+const (
+	peerVersionsInterval   = time.Minute     // interval to update metrics of peer versions and accepter
+	defaultReadOnlyRefresh = 2 * time.Second // interval to refresh chain head in read-only mode
 )
 
 type Core struct {
@@ -76,20 +86,28 @@ type Core struct {
 	engine  consensus.Engine
 	storage persistent.Storage
 
+	// chain db written by another process, nil if not opened read-only
+	readOnly *persistent.ReadOnlyLevelStorage
+	// cold store of old blocks, nil if not enabled
+	freezer *persistent.Freezer
+
 	blockChain *BlockChain
 	blockPool  *BlockPool
 	txPool     *TransactionPool
 
-	yvm        yvm.YVM
-	subscriber *p2p.Subscriber
-	subsChan   chan p2p.Message
+	yvm          yvm.YVM
+	bridge       *ConsensusBridge
+	history      *historyServer
+	evidence     *evidenceRecorder
+	equivocation *equivocationMonitor
 
 	// miner
 	keystore  *keystore.Keystore
 	minerKey  []byte
 	minerAddr *address.Address
 
-	metrics *coreMetrics
+	metrics    *coreMetrics
+	compacting int32 // chain db compaction running
 
 	lock    sync.RWMutex
 	running bool
@@ -102,14 +120,48 @@ func NewCore(node INode, conf *config.Config) (*Core, error) {
 }
 
 func NewCoreWithGenesis(node INode, conf *config.Config, genesis *Genesis) (*Core, error) {
-	log.Info("Create new core")
+	return newCore(node, conf, datadir.New(conf.NodeDir).ChainDataDir(), genesis)
+}
+
+// Create core of an extra chain hosted by node besides the primary one, the
+// chain db of it is kept apart from that of the primary chain
+func NewCoreOfChain(node INode, conf *config.Config, chain *config.ChainConfig) (*Core, error) {
+	if conf.Chain.ReadOnly && !chain.ReadOnly {
+		readOnly := *chain
+		readOnly.ReadOnly = true
+		chain = &readOnly
+	}
+	chainConf := *conf
+	chainConf.Chain = chain
+	chainConf.Chains = nil
+	if chain.P2p != nil {
+		chainConf.P2p = chain.P2p
+	}
+	dbPath := datadir.New(conf.NodeDir).ChainDataDirOf(chain.ChainID)
+	return newCore(node, &chainConf, dbPath, nil)
+}
+
+func newCore(node INode, conf *config.Config, dbPath string, genesis *Genesis) (*Core, error) {
+	log.Info("Create new core", "chainID", conf.Chain.ChainID)
+
+	if conf.Chain.ReadOnly {
+		return newReadOnlyCore(node, conf, dbPath)
+	}
 
 	// prepare chain db
-	dbPath := filepath.Join(conf.NodeDir, "chaindata")
 	storage, err := persistent.NewLevelStorage(dbPath)
 	if err != nil {
 		return nil, err
 	}
+	if err := migrateStorage(storage, conf.Chain.DbMigrateDryRun); err != nil {
+		storage.Close()
+		return nil, err
+	}
+	freezer, err := openChainFreezer(dbPath, conf.Chain.FreezeDistance, false)
+	if err != nil {
+		storage.Close()
+		return nil, err
+	}
 
 	// prepare storage with genesis
 	// for unit tests only
@@ -124,6 +176,8 @@ func NewCoreWithGenesis(node INode, conf *config.Config, genesis *Genesis) (*Cor
 		node:    node,
 		config:  conf,
 		storage: storage,
+		freezer: freezer,
+		yvm:     yvm.NewNoopVM(),
 		metrics: newCoreMetrics(),
 		quitCh:  make(chan struct{}),
 	}
@@ -131,6 +185,9 @@ func NewCoreWithGenesis(node INode, conf *config.Config, genesis *Genesis) (*Cor
 	if err != nil {
 		return nil, err
 	}
+	core.history = newHistoryServer(core.blockChain, conf.Chain)
+	core.evidence = newEvidenceRecorder(core.blockChain, core.banPeer)
+	core.equivocation = newEquivocationMonitor()
 	core.blockPool, err = NewBlockPool(core)
 	if err != nil {
 		return nil, err
@@ -143,6 +200,52 @@ func NewCoreWithGenesis(node INode, conf *config.Config, genesis *Genesis) (*Cor
 	return core, nil
 }
 
+// core of chain db opened read-only beside the node writing it, it only serves
+// queries on blocks and state, with chain head refreshed periodically
+func newReadOnlyCore(node INode, conf *config.Config, dbPath string) (*Core, error) {
+	storage, err := persistent.NewReadOnlyLevelStorage(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateStorage(storage, true); err != nil {
+		storage.Close()
+		if err == ErrChainDBMigrateDryRun {
+			err = ErrChainDBMigratePending
+		}
+		return nil, err
+	}
+	freezer, err := openChainFreezer(dbPath, conf.Chain.FreezeDistance, true)
+	if err != nil {
+		storage.Close()
+		return nil, err
+	}
+
+	core := &Core{
+		node:     node,
+		config:   conf,
+		storage:  storage,
+		readOnly: storage,
+		freezer:  freezer,
+		yvm:      yvm.NewNoopVM(),
+		metrics:  newCoreMetrics(),
+		quitCh:   make(chan struct{}),
+	}
+	core.blockChain, err = NewBlockChainWithCore(core)
+	if err != nil {
+		storage.Close()
+		if freezer != nil {
+			freezer.Close()
+		}
+		return nil, err
+	}
+	return core, nil
+}
+
+// Whether chain db opened read-only
+func (c *Core) ReadOnly() bool {
+	return c.readOnly != nil
+}
+
 func (c *Core) Start() error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -152,6 +255,12 @@ func (c *Core) Start() error {
 	}
 	log.Info("Core Start...")
 
+	if c.readOnly != nil {
+		go c.refreshLoop()
+		c.running = true
+		return nil
+	}
+
 	c.blockPool.Start()
 	c.txPool.Start()
 	c.node.P2pService().RegChainProvider(c)
@@ -168,18 +277,23 @@ func (c *Core) Start() error {
 		if err != nil {
 			return err
 		}
+		signer, err := c.GetMinerSigner()
+		if err != nil {
+			return err
+		}
 		c.engine = tetris
 		if err := c.engine.Start(); err != nil {
 			return err
 		}
 
-		c.subscriber = p2p.NewSubscriber(c, make(chan p2p.Message), p2p.MessageTypeEvent)
-		p2p := c.node.P2pService()
-		p2p.Register(c.subscriber)
-		c.subsChan = c.subscriber.MsgChan
+		c.bridge = NewConsensusBridge(c, c.engine, signer)
+		c.bridge.Start()
 	}
 
 	go c.loop()
+	if c.freezer != nil && c.config.Chain.FreezeDistance > 0 {
+		go c.freezeLoop()
+	}
 
 	c.running = true
 	return nil
@@ -197,20 +311,29 @@ func (c *Core) Stop() error {
 	// output metrics
 	c.metrics.printMetrics()
 
-	// unsubscribe from p2p net
-	c.node.P2pService().UnRegister(c.subscriber)
+	// stop consensus messages from and to p2p net
+	if c.bridge != nil {
+		c.bridge.Stop()
+	}
 
-	// stop tx pool and wait
-	c.txPool.Stop()
+	if c.readOnly == nil {
+		// stop tx pool and wait
+		c.txPool.Stop()
 
-	// stop block pool and wait
-	c.blockPool.Stop()
+		// stop block pool and wait
+		c.blockPool.Stop()
+	}
 
 	// stop chain also wait for cache flush
 	c.blockChain.Stop()
 	if err := c.storage.Close(); err != nil {
 		log.Error("core: storage.Close():", err)
 	}
+	if c.freezer != nil {
+		if err := c.freezer.Close(); err != nil {
+			log.Error("core: freezer.Close():", err)
+		}
+	}
 
 	// stop tetris
 	if c.engine != nil {
@@ -231,15 +354,19 @@ func (c *Core) loop() {
 	defer func() { c.running = false }()
 
 	log.Trace("Core loop...")
+	verTicker := time.NewTicker(peerVersionsInterval)
+	defer verTicker.Stop()
+	usageTicker := time.NewTicker(storageUsageInterval)
+	defer usageTicker.Stop()
+	var compactCh <-chan time.Time
+	if interval := c.config.Chain.CompactInterval; interval > 0 {
+		compactTicker := time.NewTicker(time.Duration(interval) * time.Second)
+		defer compactTicker.Stop()
+		compactCh = compactTicker.C
+	}
 	for {
-		var (
-			chanEventSend <-chan []byte
-			chanEventReq  <-chan common.Hash
-			outputChan    <-chan *consensus.Output
-		)
+		var outputChan <-chan *consensus.Output
 		if c.engine != nil {
-			chanEventSend = c.engine.ChanEventSend()
-			chanEventReq = c.engine.ChanEventReq()
 			outputChan = c.engine.Output()
 		}
 
@@ -247,77 +374,61 @@ func (c *Core) loop() {
 		case <-c.quitCh:
 			log.Info("Core loop end.")
 			return
-		case event := <-chanEventSend:
-			log.Trace("engine send event")
-			go c.handleEngineEventSend(event)
-		case req := <-chanEventReq:
-			log.Trace("engine req event", "hash", req)
-			go c.handleEngineEventReq(req)
+		case <-verTicker.C:
+			if vers, err := c.PeerVersions(); err == nil {
+				c.metrics.updatePeerVersions(vers)
+			}
+			if stats, err := c.AcceptStats(); err == nil {
+				c.metrics.updateAcceptStats(stats)
+			}
+			if stats, err := c.KillStats(); err == nil {
+				c.metrics.updateKillStats(stats)
+			}
+		case <-usageTicker.C:
+			c.updateDiskUsage()
+		case <-compactCh:
+			if err := c.CompactStorage(KeyspaceAll); err != nil {
+				log.Warn("scheduled chain db compaction skipped", "err", err)
+			}
 		case output := <-outputChan:
 			log.Info("core receive engine output", "output", output)
 			c.handleEngineOutput(output)
-		case msg := <-c.subsChan:
-			log.Trace("core receive ", msg.MsgType, " ", msg.From)
-			c.metrics.p2pMsgRecv.Mark(1)
-			switch msg.MsgType {
-			case p2p.MessageTypeEvent:
-				c.metrics.p2pMsgRecvEv.Mark(1)
-				go c.engine.SendEvent(msg.Data)
-			default:
-				log.Crit("wrong msg", "msg", msg)
-			}
 		}
 	}
 }
 
-func (c *Core) handleEngineEventSend(event []byte) {
+// refresh chain head from the chain db written by another process
+func (c *Core) refreshLoop() {
 	c.wg.Add(1)
 	defer c.wg.Done()
+	defer func() { c.running = false }()
 
-	if !c.running {
-		return
-	}
-	h := sha256.Sum256(event)
-	c.metrics.p2pDhtSetMeter.Mark(1)
-	err := c.node.P2pService().DhtSetValue(h[:], event)
-	if err != nil {
-		log.Warn("engine send event to dht failed", "err", err)
-	}
-	c.metrics.p2pMsgSent.Mark(1)
-	err = c.node.P2pService().BroadcastMessage(p2p.Message{
-		MsgType: p2p.MessageTypeEvent,
-		From:    c.node.NodeID(),
-		Data:    event,
-	})
-	if err != nil {
-		log.Warn("engine send event failed", "err", err)
-		c.metrics.p2pMsgSendFail.Mark(1)
+	interval := defaultReadOnlyRefresh
+	if c.config.Chain.ReadOnlyRefresh > 0 {
+		interval = time.Duration(c.config.Chain.ReadOnlyRefresh) * time.Second
 	}
-}
-
-func (c *Core) handleEngineEventReq(hash common.Hash) {
-	c.wg.Add(1)
-	defer c.wg.Done()
-	retry := 60
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 	for {
-		if !c.running {
-			return
-		}
-
-		c.metrics.p2pDhtGetMeter.Mark(1)
-		data, err := c.node.P2pService().DhtGetValue(hash[:])
-		if err == nil {
-			c.metrics.p2pDhtHitMeter.Mark(1)
-			c.engine.SendParentEvent(data)
+		select {
+		case <-c.quitCh:
 			return
+		case <-ticker.C:
+			if err := c.readOnly.Refresh(); err != nil {
+				log.Warn("failed to refresh chain db", "err", err)
+				continue
+			}
+			if c.freezer != nil {
+				// after chain db, to cover blocks deleted from it
+				if err := c.freezer.Refresh(); err != nil {
+					log.Warn("failed to refresh cold store", "err", err)
+					continue
+				}
+			}
+			if err := c.blockChain.RefreshLastBlock(); err != nil {
+				log.Warn("failed to refresh chain head", "err", err)
+			}
 		}
-		c.metrics.p2pDhtMissMeter.Mark(1)
-		retry--
-		if retry <= 0 {
-			log.Warn("engine req event failed", "hash", hash, "err", err)
-			break
-		}
-		time.Sleep(time.Second)
 	}
 }
 
@@ -392,36 +503,44 @@ func (c *Core) handleEngineOutput(o *consensus.Output) {
 }
 
 func (c *Core) loadCoinbaseKey() error {
-	conf := c.config
+	ks, key, err := LoadCoinbaseKey(c.config)
+	if err != nil {
+		return err
+	}
+	c.keystore = ks
+	c.minerKey = key
+	return nil
+}
 
+// LoadCoinbaseKey loads private key of coinbase from keystore by password in
+// pwdfile, keystore is nil if the key provided in config
+func LoadCoinbaseKey(conf *config.Config) (*keystore.Keystore, []byte, error) {
 	if key := conf.Chain.Key; len(key) > 0 {
 		// private key provided in config
-		c.minerKey = key
-		return nil
+		return nil, key, nil
 	}
 
 	coinbase := conf.Chain.Coinbase
 	if len(coinbase) == 0 {
-		return ErrNoCoinbase
+		return nil, nil, ErrNoCoinbase
 	}
 	if len(conf.Chain.PwdFile) == 0 {
-		return ErrNoCoinbasePwdFile
+		return nil, nil, ErrNoCoinbasePwdFile
 	}
-	c.keystore = keystore.NewKeystoreWithConfig(conf)
-	if contains, _ := c.keystore.Contains(coinbase); !contains {
-		return ErrCoinbaseKeyNotFound
+	ks := keystore.NewKeystoreWithConfig(conf)
+	if contains, _ := ks.Contains(coinbase); !contains {
+		return nil, nil, ErrCoinbaseKeyNotFound
 	}
 	pwdContent, err := ioutil.ReadFile(conf.Chain.PwdFile)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	pwd := []byte(strings.Split(string(pwdContent), "\n")[0])
-	key, err := c.keystore.GetKey(coinbase, pwd)
+	key, err := ks.GetKey(coinbase, pwd)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	c.minerKey = key
-	return nil
+	return ks, key, nil
 }
 
 func (c *Core) prepareCoinbase() error {
@@ -443,11 +562,51 @@ func (c *Core) Chain() *BlockChain {
 	return c.blockChain
 }
 
+func (c *Core) TxPool() *TransactionPool {
+	return c.txPool
+}
+
+// version and build info of this node
+func (c *Core) VersionInfo() version.Info {
+	return version.GetInfo()
+}
+
+// number of active peers by the client versions they announced
+func (c *Core) PeerVersions() (map[string]int, error) {
+	pvr, ok := c.node.P2pService().(p2p.PeerVersionReporter)
+	if !ok {
+		return nil, ErrNoPeerVersions
+	}
+	return pvr.GetPeerVersions()
+}
+
+// statistics of the accepter of inbound peers paused for them full
+func (c *Core) AcceptStats() (*p2p.AcceptStats, error) {
+	asr, ok := c.node.P2pService().(p2p.AcceptStatsReporter)
+	if !ok {
+		return nil, ErrNoAcceptStats
+	}
+	return asr.GetAcceptStats()
+}
+
+// number of peer instances killed by sub network and cause, to tell the churn
+// of network, duplicated or resource limited, from handshake failures
+func (c *Core) KillStats() ([]p2p.KillStat, error) {
+	ksr, ok := c.node.P2pService().(p2p.KillStatsReporter)
+	if !ok {
+		return nil, ErrNoKillStats
+	}
+	return ksr.GetKillStats()
+}
+
 func (c *Core) MinerAddr() *address.Address {
 	return c.minerAddr.Copy()
 }
 
 func (c *Core) TxBroadcast(tx *Transaction) error {
+	if c.readOnly != nil {
+		return ErrCoreReadOnly
+	}
 	return c.txPool.TxBroadcast(tx)
 }
 
@@ -456,6 +615,16 @@ func (c *Core) FakeP2pRecv(msg *p2p.Message) {
 	c.txPool.subscriber.MsgChan <- *msg
 }
 
+// Start building next block of the last one in chain at time t, in milli
+// seconds, with limits configured for txs gathered from pool
+func (c *Core) NewBlockBuilder(t uint64) (*BlockBuilder, error) {
+	limits := BlockLimits{
+		MaxTxs:  c.config.Chain.BlockMaxTxs,
+		MaxSize: c.config.Chain.BlockMaxSize,
+	}
+	return c.blockChain.NewBlockBuilder(c.blockChain.LastBlock(), t, limits)
+}
+
 func (c *Core) signBlock(b *Block) error {
 	if c.engine == nil {
 		log.Crit("not in miner mode")
@@ -471,8 +640,7 @@ func (c *Core) signBlock(b *Block) error {
 
 //ICORE
 func (c *Core) GetSigner() crypto.Signer {
-	signer := secp256k1.NewSecp256k1Signer()
-	return signer
+	return provider.Current().Signer(crypto.ALG_SECP256K1)
 }
 
 func (c *Core) GetMinerSigner() (crypto.Signer, error) {
@@ -507,13 +675,11 @@ func (c *Core) AddressFromPublicKey(publicKey []byte) ([]byte, error) {
 }
 
 func getSigner(algorithm crypto.Algorithm) crypto.Signer {
-	switch algorithm {
-	case crypto.ALG_SECP256K1:
-		return secp256k1.NewSecp256k1Signer()
-	default:
+	signer := provider.Current().Signer(algorithm)
+	if signer == nil {
 		log.Warn("wrong crypto algorithm", "algorithm", algorithm)
-		return nil
 	}
+	return signer
 }
 
 func (c *Core) GetChainData(kind string, key []byte) []byte {
@@ -542,10 +708,36 @@ func (c *Core) GetChainData(kind string, key []byte) []byte {
 			}
 			return enc
 		}
+	case ChainDataTypeHeaders, ChainDataTypeBodies:
+		return c.history.serve("", kind, key)
+	case ChainDataTypeTrieNodes:
+		return c.history.serveNodes("", key)
 	}
 	return nil
 }
 
+// ban peer by p2p service for evidences of it
+func (c *Core) banPeer(peer string, duration time.Duration) error {
+	pa, ok := c.node.P2pService().(p2p.PeerAdmin)
+	if !ok {
+		return errors.New("peers can't be banned by p2p service")
+	}
+	return pa.BanPeer(peer, duration)
+}
+
+// Get chain data for a peer, historical blocks are served within quota of it
+func (c *Core) GetChainDataFor(peer string, kind string, key []byte) []byte {
+	switch kind {
+	case ChainDataTypeHeaders, ChainDataTypeBodies:
+		c.metrics.p2pChainInfoAnswer.Mark(1)
+		return c.history.serve(peer, kind, key)
+	case ChainDataTypeTrieNodes:
+		c.metrics.p2pChainInfoAnswer.Mark(1)
+		return c.history.serveNodes(peer, key)
+	}
+	return c.GetChainData(kind, key)
+}
+
 func (c *Core) GetRemoteLatestHash() (*common.Hash, error) {
 	c.metrics.p2pChainInfoGet.Mark(1)
 	encoded, err := c.node.P2pService().GetChainInfo(ChainDataTypeLatestH, []byte(""))
@@ -18,8 +18,6 @@
 package core
 
 import (
-	"bytes"
-
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/yeeco/gyee/common"
 	"github.com/yeeco/gyee/common/trie"
@@ -34,12 +32,37 @@ type DerivableList interface {
 // Add list elements to a in-mem trie, with index as key,
 // trie root hash is returned.
 func DeriveHash(list DerivableList) common.Hash {
-	keybuf := new(bytes.Buffer)
+	return deriveTrie(list).Hash()
+}
+
+// Proof of list elements at indexes against the root hash of DeriveHash
+func DeriveProof(list DerivableList, indexes ...int) (*trie.ProofSet, error) {
+	t := deriveTrie(list)
+	proof := trie.NewProofSet()
+	for _, index := range indexes {
+		if err := t.Prove(deriveKey(index), proof); err != nil {
+			return nil, err
+		}
+	}
+	return proof, nil
+}
+
+// Verify proof of list element at index against root hash of the list,
+// returns encoded element, nil if index out of the list.
+func VerifyDerivedProof(root common.Hash, index int, proof trie.DatabaseReader) ([]byte, error) {
+	return trie.VerifyProof(root, deriveKey(index), proof)
+}
+
+func deriveTrie(list DerivableList) *trie.Trie {
 	t := new(trie.Trie)
 	for i := 0; i < list.Len(); i++ {
-		keybuf.Reset()
-		_ = rlp.Encode(keybuf, uint(i))
-		t.Update(keybuf.Bytes(), list.GetEncoded(i))
+		t.Update(deriveKey(i), list.GetEncoded(i))
 	}
-	return t.Hash()
+	return t
+}
+
+// trie key of list element, rlp encoded index
+func deriveKey(index int) []byte {
+	key, _ := rlp.EncodeToBytes(uint(index))
+	return key
 }
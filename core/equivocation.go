@@ -0,0 +1,144 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   双签检测：收到的区块（包括后来补充的签名）验证过验证者签名之后，按（验证者，高度）
+   记下签过的区块hash和签名。同一个验证者在同一高度签了不同的区块就是双签，发布
+   EventEquivocation事件，带上两份各自只含该验证者签名的区块头，共识层或者治理可以据此
+   处理，同时记为作恶证据。区块头里没有轮次，按高度区分。只保留链头附近若干高度的记录。
+*/
+
+import (
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/core/pb"
+	"github.com/yeeco/gyee/crypto"
+	"github.com/yeeco/gyee/log"
+)
+
+// heights below chain head signatures kept for
+const equivocationWindow = TooFarBlocks
+
+// Equivocation of a validator, signed two blocks of a height. headers carry
+// the signature of the validator only.
+type Equivocation struct {
+	Validator common.Address
+	Number    uint64
+	First     *corepb.SignedBlockHeader
+	Second    *corepb.SignedBlockHeader
+}
+
+// block signed by a validator at a height
+type signedVote struct {
+	hash     common.Hash
+	header   *corepb.SignedBlockHeader
+	reported bool
+}
+
+// tracks blocks signed by validators by height, to find equivocations
+type equivocationMonitor struct {
+	lock  sync.Mutex
+	votes map[uint64]map[common.Address]*signedVote
+}
+
+func newEquivocationMonitor() *equivocationMonitor {
+	return &equivocationMonitor{
+		votes: make(map[uint64]map[common.Address]*signedVote),
+	}
+}
+
+// Observe signatures of validators on block, equivocations found returned.
+// head is the chain height, signatures of heights too far below are dropped.
+func (em *equivocationMonitor) observe(b *Block, head uint64) []*Equivocation {
+	em.lock.Lock()
+	defer em.lock.Unlock()
+
+	if head > equivocationWindow {
+		for number := range em.votes {
+			if number < head-equivocationWindow {
+				delete(em.votes, number)
+			}
+		}
+		if b.Number() < head-equivocationWindow {
+			return nil
+		}
+	}
+	votes, ok := em.votes[b.Number()]
+	if !ok {
+		votes = make(map[common.Address]*signedVote)
+		em.votes[b.Number()] = votes
+	}
+	var found []*Equivocation
+	hash := b.Hash()
+	for addr, sig := range b.signatureMap {
+		vote, ok := votes[addr]
+		if !ok {
+			votes[addr] = &signedVote{hash: hash, header: signedHeaderOf(b, sig)}
+			continue
+		}
+		if vote.hash == hash || vote.reported {
+			continue
+		}
+		vote.reported = true
+		found = append(found, &Equivocation{
+			Validator: addr,
+			Number:    b.Number(),
+			First:     vote.header,
+			Second:    signedHeaderOf(b, sig),
+		})
+	}
+	return found
+}
+
+// header of block with signature of one validator
+func signedHeaderOf(b *Block, sig crypto.Signature) *corepb.SignedBlockHeader {
+	return &corepb.SignedBlockHeader{
+		Header: b.pbHeader.Header,
+		Bloom:  b.pbHeader.Bloom,
+		Signatures: []*corepb.Signature{{
+			SigAlgorithm: uint32(sig.Algorithm),
+			Signature:    sig.Signature,
+		}},
+	}
+}
+
+// check signatures of validators on block received, equivocations found are
+// posted as events and recorded as evidences
+func (c *Core) checkEquivocation(b *Block) {
+	for _, eq := range c.equivocation.observe(b, c.blockChain.CurrentBlockHeight()) {
+		log.Warn("validator equivocation", "validator", eq.Validator, "H", eq.Number)
+		c.blockChain.events.post(&Event{Kind: EventEquivocation, Equivocation: eq})
+		ev := &Evidence{
+			Kind:    EvidenceDoubleSign,
+			Number:  eq.Number,
+			Hash:    b.Hash(),
+			Peer:    b.origin,
+			Reason:  "validator signed two blocks of height",
+			Signers: []common.Address{eq.Validator},
+		}
+		ev.Data, _ = proto.Marshal(eq.Second)
+		ev.Other, _ = proto.Marshal(eq.First)
+		c.evidence.report(ev)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/yeeco/gyee/crypto"
+)
+
+func newEquivocationBlock(t *testing.T, chain *BlockChain, ts uint64, signers []crypto.Signer) *Block {
+	b, err := chain.BuildNextBlock(chain.GetBlockByNumber(0), ts, nil)
+	if err != nil {
+		t.Fatalf("BuildNextBlock() %v", err)
+	}
+	for _, signer := range signers {
+		if err := b.Sign(signer); err != nil {
+			t.Fatalf("Sign() %v", err)
+		}
+	}
+	if b.signatureMap, err = b.Signers(); err != nil {
+		t.Fatalf("Signers() %v", err)
+	}
+	return b
+}
+
+func TestEquivocationMonitor(t *testing.T) {
+	signers, validators := newCheckpointSigners(t, 3)
+	chain, _ := newCheckpointBlock(t, nil)
+	em := newEquivocationMonitor()
+
+	first := newEquivocationBlock(t, chain, 1, signers[:2])
+	if found := em.observe(first, 0); len(found) != 0 {
+		t.Fatalf("observe() first got %d", len(found))
+	}
+	// same block with more signatures
+	if found := em.observe(newEquivocationBlock(t, chain, 1, signers), 0); len(found) != 0 {
+		t.Fatalf("observe() same block got %d", len(found))
+	}
+
+	second := newEquivocationBlock(t, chain, 2, signers[1:2])
+	found := em.observe(second, 0)
+	if len(found) != 1 {
+		t.Fatalf("observe() conflicting got %d, want 1", len(found))
+	}
+	eq := found[0]
+	if eq.Validator != validators[1] || eq.Number != 1 ||
+		string(eq.First.Header) != string(first.pbHeader.Header) ||
+		string(eq.Second.Header) != string(second.pbHeader.Header) ||
+		len(eq.First.Signatures) != 1 || len(eq.Second.Signatures) != 1 {
+		t.Errorf("observe() conflicting got %v", eq)
+	}
+	// reported once only
+	if found := em.observe(second, 0); len(found) != 0 {
+		t.Errorf("observe() reported got %d", len(found))
+	}
+
+	// heights far below head dropped
+	if found := em.observe(newEquivocationBlock(t, chain, 3, signers), equivocationWindow+2); len(found) != 0 {
+		t.Errorf("observe() too old got %d", len(found))
+	}
+	if len(em.votes) != 0 {
+		t.Errorf("votes of %d heights kept", len(em.votes))
+	}
+}
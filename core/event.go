@@ -0,0 +1,128 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   事件订阅：区块加入链时发布新区块头和合约日志事件，交易池接受交易时发布待处理交易
+   事件，发现验证者双签时发布双签事件。订阅者按事件类型订阅，事件通过channel送出，
+   订阅者处理不过来时丢弃事件并计数，不阻塞链和交易池；丢失的区块头可以按高度从链上
+   补回。
+*/
+
+import (
+	"sync"
+
+	"github.com/yeeco/gyee/core/yvm"
+)
+
+type EventKind int
+
+const (
+	EventNewHead      EventKind = iota // block added as last block
+	EventPendingTx                     // tx accepted by tx pool
+	EventLogs                          // logs of contract txs in block added
+	EventEquivocation                  // validator signed two blocks of a height
+)
+
+type Event struct {
+	Kind  EventKind
+	Block *Block       // for EventNewHead and EventLogs
+	Tx    *Transaction // for EventPendingTx
+	Logs  []*yvm.Log   // for EventLogs
+
+	Equivocation *Equivocation // for EventEquivocation
+}
+
+// EventSub is a subscription of events, delivered by channel C
+type EventSub struct {
+	C       chan *Event
+	kinds   map[EventKind]bool
+	feed    *eventFeed
+	dropped uint64 // events dropped for C full
+}
+
+type eventFeed struct {
+	lock sync.Mutex
+	subs map[*EventSub]struct{}
+}
+
+func newEventFeed() *eventFeed {
+	return &eventFeed{
+		subs: make(map[*EventSub]struct{}),
+	}
+}
+
+// Subscribe events of kinds, with channel of size buffered
+func (bc *BlockChain) SubscribeEvents(size int, kinds ...EventKind) *EventSub {
+	sub := &EventSub{
+		C:     make(chan *Event, size),
+		kinds: make(map[EventKind]bool, len(kinds)),
+		feed:  bc.events,
+	}
+	for _, kind := range kinds {
+		sub.kinds[kind] = true
+	}
+	bc.events.lock.Lock()
+	bc.events.subs[sub] = struct{}{}
+	bc.events.lock.Unlock()
+	return sub
+}
+
+// Unsubscribe events, C is not closed for events may be sent concurrently
+func (sub *EventSub) Unsubscribe() {
+	sub.feed.lock.Lock()
+	delete(sub.feed.subs, sub)
+	sub.feed.lock.Unlock()
+}
+
+// Get number of events dropped for subscriber too slow
+func (sub *EventSub) Dropped() uint64 {
+	sub.feed.lock.Lock()
+	defer sub.feed.lock.Unlock()
+	return sub.dropped
+}
+
+func (feed *eventFeed) post(ev *Event) {
+	feed.lock.Lock()
+	defer feed.lock.Unlock()
+	for sub := range feed.subs {
+		if !sub.kinds[ev.Kind] {
+			continue
+		}
+		select {
+		case sub.C <- ev:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+// post events of block added
+func (feed *eventFeed) postBlock(b *Block) {
+	feed.post(&Event{Kind: EventNewHead, Block: b})
+	var logs []*yvm.Log
+	for _, r := range b.receipts {
+		logs = append(logs, r.Logs...)
+	}
+	if len(logs) > 0 {
+		feed.post(&Event{Kind: EventLogs, Block: b, Logs: logs})
+	}
+}
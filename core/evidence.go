@@ -0,0 +1,228 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   作恶证据：收到的区块或交易验证失败（无法解码、签名错误、状态根不符、同一高度签了
+   两个不同的区块），把原始数据、来源peer、高度和原因作为证据按顺序编号存下来，可以
+   通过RPC查询，为以后的惩罚和分叉调试做准备。消息是转发过来的，转发的peer不一定是
+   作恶者，所以每条证据给来源peer记一定的罚分，累计到阈值才禁止它一段时间。
+   同样的证据短时间内只记一次。
+*/
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/hashicorp/golang-lru"
+	"github.com/yeeco/gyee/common"
+	sha3 "github.com/yeeco/gyee/crypto/hash"
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/persistent"
+)
+
+// Kinds of evidence
+const (
+	EvidenceBadEncoding  = "encoding"   // message can't be decoded
+	EvidenceBadSignature = "signature"  // block not signed by validators
+	EvidenceBadBlock     = "block"      // block header, body or gas invalid
+	EvidenceBadState     = "state"      // state root or receipts mismatch after txs executed
+	EvidenceDoubleSign   = "doublesign" // validators signed two blocks of a height
+	EvidenceBadTx        = "tx"         // tx invalid or signature mismatch
+)
+
+const (
+	evidenceBanScore = 100       // penalty score of a peer to be banned
+	evidenceBanTime  = time.Hour // duration peers banned for
+	evidenceSeenSize = 1024      // number of evidences kept for dedup
+	evidencePeers    = 1024      // number of peers scores kept for
+)
+
+// penalty scores of peer by kind of evidence
+var evidencePenalty = map[string]int{
+	EvidenceBadEncoding:  20,
+	EvidenceBadSignature: 50,
+	EvidenceBadBlock:     50,
+	EvidenceBadState:     50,
+	EvidenceDoubleSign:   0, // signers to blame, not the peer relayed it
+	EvidenceBadTx:        10,
+}
+
+var ErrEvidenceCorrupt = errors.New("core.evidence: evidence entry corrupt")
+
+// Evidence of a malicious block or tx received
+type Evidence struct {
+	Kind    string
+	Number  uint64           // block height, 0 if unknown
+	Hash    common.Hash      // block or tx hash, empty if can't be decoded
+	Peer    string           // peer it's received from, empty if unknown
+	Reason  string           // error of verification
+	Time    uint64           // unix time recorded
+	Data    []byte           // offending bytes
+	Other   []byte           // conflicting header of the same height, for double-sign
+	Signers []common.Address // validators signed offending block
+}
+
+// records evidences to chain storage and penalizes peers of them
+type evidenceRecorder struct {
+	chain *BlockChain
+	ban   func(peer string, duration time.Duration) error
+
+	lock   sync.Mutex
+	seen   *lru.Cache // evidences recorded recently
+	scores *lru.Cache // penalty scores by peer
+}
+
+func newEvidenceRecorder(chain *BlockChain, ban func(string, time.Duration) error) *evidenceRecorder {
+	er := &evidenceRecorder{
+		chain: chain,
+		ban:   ban,
+	}
+	er.seen, _ = lru.New(evidenceSeenSize)
+	er.scores, _ = lru.New(evidencePeers)
+	return er
+}
+
+// Record evidence and penalize the peer of it, the peer is banned if its
+// score reached
+func (er *evidenceRecorder) report(ev *Evidence) {
+	er.lock.Lock()
+	defer er.lock.Unlock()
+
+	id := common.BytesToHash(sha3.Sha3256([]byte(ev.Kind+ev.Peer), ev.Data))
+	if ok, _ := er.seen.ContainsOrAdd(id, struct{}{}); ok {
+		return
+	}
+	if ev.Time == 0 {
+		ev.Time = uint64(time.Now().Unix())
+	}
+	log.Warn("malicious evidence", "kind", ev.Kind, "H", ev.Number, "hash", ev.Hash,
+		"peer", ev.Peer, "reason", ev.Reason)
+	if err := er.chain.putEvidence(ev); err != nil {
+		log.Error("failed to record evidence", "err", err)
+	}
+	if len(ev.Peer) == 0 {
+		return
+	}
+	score := evidencePenalty[ev.Kind]
+	if v, ok := er.scores.Get(ev.Peer); ok {
+		score += v.(int)
+	}
+	if score < evidenceBanScore {
+		er.scores.Add(ev.Peer, score)
+		return
+	}
+	er.scores.Remove(ev.Peer)
+	if err := er.ban(ev.Peer, evidenceBanTime); err != nil {
+		log.Warn("failed to ban peer", "peer", ev.Peer, "err", err)
+	}
+}
+
+// Get number of evidences recorded
+func (bc *BlockChain) EvidenceCount() uint64 {
+	return getEvidenceCount(bc.storage)
+}
+
+// Get evidences from the start-th one recorded, limit of them at most
+func (bc *BlockChain) GetEvidences(start, limit uint64) ([]*Evidence, error) {
+	count := getEvidenceCount(bc.storage)
+	if start >= count {
+		return nil, nil
+	}
+	if limit > count-start {
+		limit = count - start
+	}
+	evs := make([]*Evidence, 0, limit)
+	for seq := start; seq < start+limit; seq++ {
+		enc, err := bc.storage.Get(keyEvidence(seq))
+		if err != nil {
+			return nil, err
+		}
+		ev := new(Evidence)
+		if err := rlp.DecodeBytes(enc, ev); err != nil {
+			return nil, ErrEvidenceCorrupt
+		}
+		evs = append(evs, ev)
+	}
+	return evs, nil
+}
+
+// append evidence with next sequence number, callers serialize it
+func (bc *BlockChain) putEvidence(ev *Evidence) error {
+	enc, err := rlp.EncodeToBytes(ev)
+	if err != nil {
+		return err
+	}
+	seq := getEvidenceCount(bc.storage)
+	batch := bc.storage.NewBatch()
+	if err := batch.Put(keyEvidence(seq), enc); err != nil {
+		return err
+	}
+	encCount := make([]byte, 8)
+	binary.BigEndian.PutUint64(encCount, seq+1)
+	if err := batch.Put(keyEvidenceCount(), encCount); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+func getEvidenceCount(getter persistent.Getter) uint64 {
+	enc, err := getter.Get(keyEvidenceCount())
+	if err != nil {
+		if err != persistent.ErrKeyNotFound {
+			log.Error("getEvidenceCount()", "err", err)
+		}
+		return 0
+	}
+	if len(enc) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(enc)
+}
+
+// evidence of a block failed verification, nil if the failure doesn't
+// prove the block malicious
+func blockEvidence(b *Block, err error) *Evidence {
+	kind := EvidenceBadBlock
+	switch err {
+	case ErrBlockTooFarForChain, ErrBlockParentMissing:
+		return nil
+	case ErrBlockSignatureMismatch:
+		kind = EvidenceBadSignature
+	case ErrBlockStateTrieMismatch, ErrBlockReceiptsMismatch:
+		kind = EvidenceBadState
+	}
+	ev := &Evidence{
+		Kind:   kind,
+		Number: b.Number(),
+		Hash:   b.Hash(),
+		Peer:   b.origin,
+		Reason: err.Error(),
+	}
+	ev.Data, _ = b.ToBytes()
+	for addr := range b.signatureMap {
+		ev.Signers = append(ev.Signers, addr)
+	}
+	return ev
+}
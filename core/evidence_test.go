@@ -0,0 +1,77 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEvidenceRecord(t *testing.T) {
+	signers, _ := newCheckpointSigners(t, 1)
+	chain, _ := newCheckpointBlock(t, signers)
+	banned := make(map[string]time.Duration)
+	er := newEvidenceRecorder(chain, func(peer string, d time.Duration) error {
+		banned[peer] = d
+		return nil
+	})
+
+	for i := 0; i < 5; i++ {
+		er.report(&Evidence{Kind: EvidenceBadEncoding, Peer: "peer", Data: []byte{byte(i)}})
+	}
+	// recorded once only
+	er.report(&Evidence{Kind: EvidenceBadEncoding, Peer: "peer", Data: []byte{0}})
+
+	if n := chain.EvidenceCount(); n != 5 {
+		t.Fatalf("EvidenceCount() got %d, want 5", n)
+	}
+	evs, err := chain.GetEvidences(3, 10)
+	if err != nil {
+		t.Fatalf("GetEvidences() %v", err)
+	}
+	if len(evs) != 2 || !bytes.Equal(evs[0].Data, []byte{3}) || evs[1].Peer != "peer" || evs[1].Time == 0 {
+		t.Errorf("GetEvidences() got %v", evs)
+	}
+	if evs, _ := chain.GetEvidences(5, 10); len(evs) != 0 {
+		t.Errorf("GetEvidences() beyond got %d", len(evs))
+	}
+	if d, ok := banned["peer"]; !ok || d != evidenceBanTime {
+		t.Errorf("peer not banned after %d evidences", chain.EvidenceCount())
+	}
+
+	er.report(&Evidence{Kind: EvidenceBadTx, Peer: "other", Data: []byte{0}})
+	if _, ok := banned["other"]; ok {
+		t.Errorf("peer banned for an invalid tx")
+	}
+}
+
+func TestBlockEvidence(t *testing.T) {
+	signers, _ := newCheckpointSigners(t, 1)
+	_, b := newCheckpointBlock(t, signers)
+
+	if ev := blockEvidence(b, ErrBlockTooFarForChain); ev != nil {
+		t.Errorf("blockEvidence() of too far block got %v", ev)
+	}
+	if ev := blockEvidence(b, ErrBlockStateTrieMismatch); ev == nil || ev.Kind != EvidenceBadState || ev.Hash != b.Hash() {
+		t.Errorf("blockEvidence() of state mismatch got %v", ev)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   合约执行：合约类型的交易，先按普通交易转账、扣固定gas，再交给VM执行，
+   VM通过AccountTrie读写状态，返回消耗的gas和日志，生成收据放入区块。VM可以替换
+   （WASM或兼容EVM的实现），默认是什么都不做的NoopVM。普通交易不生成收据，原有
+   区块的ReceiptsRoot不变。
+*/
+
+import (
+	"math/big"
+
+	"github.com/yeeco/gyee/core/state"
+	"github.com/yeeco/gyee/core/yvm"
+	"github.com/yeeco/gyee/log"
+)
+
+// Set VM to execute contract-bearing txs, it must be done before core started
+func (c *Core) SetVM(vm yvm.YVM) {
+	c.yvm = vm
+	c.blockChain.vm = vm
+}
+
+// execute tx of block with header against state, dispatched by tx type. plain
+// txs are applied by applyTx only, contract-bearing ones are handed to VM after
+// that, and the receipt of them returned. invalid txs are dropped, an error is
+// returned only if VM can't work at all.
+func (bc *BlockChain) execTx(stateTrie state.AccountTrie, header *BlockHeader, tx *Transaction, gp *GasPool) (*Receipt, bool, error) {
+	if err := tx.Validate(); err != nil {
+		return nil, false, nil
+	}
+	if err := tx.ValidAt(header.Time / 1000); err != nil {
+		return nil, false, nil
+	}
+	return txKinds[tx.txType].execute(bc, stateTrie, header, tx, gp)
+}
+
+func execContractTx(bc *BlockChain, stateTrie state.AccountTrie, header *BlockHeader, tx *Transaction, gp *GasPool) (*Receipt, bool, error) {
+	if gp != nil && tx.from != nil {
+		// gas limit of tx paid for up front
+		cost := new(big.Int).Add(tx.amount, TxFee(tx, tx.GasLimit()))
+		if from := stateTrie.GetAccount(*tx.from, false); from == nil || from.Balance().Cmp(cost) < 0 {
+			return nil, false, nil
+		}
+	}
+	if !applyTx(stateTrie, tx, gp) {
+		return nil, false, nil
+	}
+
+	intrinsic := uint64(0)
+	gasLeft := tx.GasLimit()
+	if gp != nil {
+		intrinsic = IntrinsicGas(tx)
+		gasLeft = tx.GasLimit() - intrinsic
+		if left := gp.limit - gp.used; left < gasLeft {
+			gasLeft = left
+		}
+	}
+	ctx := &yvm.Context{
+		ChainID: header.ChainID,
+		Number:  header.Number,
+		Time:    header.Time,
+	}
+	msg := &yvm.Message{
+		TxHash:   *tx.Hash(),
+		From:     *tx.from,
+		To:       *tx.to,
+		Nonce:    tx.nonce,
+		Amount:   new(big.Int).Set(tx.amount),
+		Payload:  tx.payload,
+		GasLimit: gasLeft,
+	}
+	result, err := bc.vm.Execute(ctx, stateTrie, msg)
+	if err != nil {
+		log.Error("execTx()", "tx", tx, "err", err)
+		return nil, false, err
+	}
+	if result.GasUsed > gasLeft {
+		result.GasUsed = gasLeft
+		result.Failed = true
+	}
+	if gp != nil && result.GasUsed > 0 {
+		gp.used += result.GasUsed
+		fee := TxFee(tx, result.GasUsed)
+		from := stateTrie.GetAccount(*tx.from, false)
+		if from.Balance().Cmp(fee) < 0 {
+			fee = new(big.Int).Set(from.Balance())
+		}
+		from.SubBalance(fee)
+		if fee.Sign() > 0 && gp.recipient != nil {
+			stateTrie.GetAccount(*gp.recipient, true).AddBalance(fee)
+		}
+	}
+	receipt, err := newReceipt(tx, intrinsic+result.GasUsed, result)
+	if err != nil {
+		return nil, false, err
+	}
+	return receipt, true, nil
+}
@@ -0,0 +1,123 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   gas计费：交易执行消耗的gas = 固定开销 + 每字节开销（交易编码后的长度），合约交易
+   另加VM执行消耗的gas。发送者支付 gas used * gas price 作为手续费，转给配置的手续费
+   接收者，没有配置则销毁。区块头记录gas limit和gas used，验证区块时检查块内交易的
+   gas之和与块头一致（有合约交易时不少于固定开销之和）且不超过gas limit。
+   gas计费之前的区块头没有gas字段，hash不变。
+*/
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/yeeco/gyee/common"
+)
+
+const (
+	TxGasIntrinsic       uint64 = 21000    // base gas of a tx
+	TxGasPerByte         uint64 = 16       // gas per byte of encoded tx
+	DefaultTxGasLimit    uint64 = 50000    // gas limit of tx not set
+	DefaultBlockGasLimit uint64 = 10000000 // gas limit of block if not configured
+)
+
+var (
+	ErrBlockGasMissing  = errors.New("core.gas: block gas not recorded in header")
+	ErrBlockGasMismatch = errors.New("core.gas: block gas used mismatch with txs")
+	ErrBlockGasExceeded = errors.New("core.gas: block gas limit exceeded")
+	ErrTxGasTooLow      = errors.New("core.gas: tx gas limit lower than intrinsic gas")
+)
+
+// Gas consumed by tx execution before VM, the only gas of plain txs
+func IntrinsicGas(tx *Transaction) uint64 {
+	if err := Transactions([]*Transaction{tx}).encode(); err != nil {
+		return TxGasIntrinsic
+	}
+	return TxGasIntrinsic + TxGasPerByte*uint64(len(tx.raw))
+}
+
+// Fee paid by sender for gas used
+func TxFee(tx *Transaction, gasUsed uint64) *big.Int {
+	if tx.gasPrice == nil || tx.gasPrice.Sign() == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Mul(tx.gasPrice, new(big.Int).SetUint64(gasUsed))
+}
+
+// GasPool tracks gas of a block while txs executed
+type GasPool struct {
+	limit     uint64          // gas limit of block
+	used      uint64          // gas used by txs executed
+	recipient *common.Address // fee recipient, fee burned if nil
+}
+
+func NewGasPool(limit uint64, recipient *common.Address) *GasPool {
+	return &GasPool{
+		limit:     limit,
+		recipient: recipient,
+	}
+}
+
+func (gp *GasPool) Limit() uint64 {
+	return gp.limit
+}
+
+func (gp *GasPool) Used() uint64 {
+	return gp.used
+}
+
+// check if gas left is enough for tx
+func (gp *GasPool) fits(gas uint64) bool {
+	return gp.used+gas <= gp.limit
+}
+
+// check gas recorded in block header against its txs
+func verifyBlockGas(b *Block, maxLimit uint64) error {
+	if !b.header.Metered() {
+		return ErrBlockGasMissing
+	}
+	if b.GasLimit() > maxLimit {
+		return ErrBlockGasExceeded
+	}
+	var (
+		used     uint64
+		contract bool
+	)
+	for _, tx := range b.transactions {
+		gas := IntrinsicGas(tx)
+		if tx.GasLimit() < gas {
+			return ErrTxGasTooLow
+		}
+		used += gas
+		contract = contract || tx.IsContract()
+	}
+	// gas used by VM is checked by state root when txs replayed
+	if used != b.GasUsed() && !(contract && used < b.GasUsed()) {
+		return ErrBlockGasMismatch
+	}
+	if b.GasUsed() > b.GasLimit() {
+		return ErrBlockGasExceeded
+	}
+	return nil
+}
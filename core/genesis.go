@@ -27,6 +27,7 @@ import (
 	"github.com/BurntSushi/toml"
 	"github.com/yeeco/gyee/common"
 	"github.com/yeeco/gyee/common/address"
+	"github.com/yeeco/gyee/common/hexutil"
 	"github.com/yeeco/gyee/core/state"
 	"github.com/yeeco/gyee/persistent"
 	"github.com/yeeco/gyee/res"
@@ -102,9 +103,9 @@ func (g *Genesis) genBlock(stateDB state.Database) (*Block, error) {
 		if err != nil {
 			return nil, err
 		}
-		value, ok := new(big.Int).SetString(dist.Value, 0)
-		if !ok {
-			return nil, fmt.Errorf("failed to parse value %v", dist.Value)
+		value, err := hexutil.ParseBig(dist.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse value %v: %v", dist.Value, err)
 		}
 		account := accountTrie.GetAccount(*addr.CommonAddress(), true)
 		account.SetBalance(value)
@@ -0,0 +1,280 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   状态修复：快照同步或者裁剪之后，本地的状态trie可能缺少部分节点。从状态根开始由
+   trie.Sync调度找出缺失的节点，按hash成批向peer请求，请求轮流分给各个peer，同时在途
+   的请求数有上限。返回的每个节点都校验hash，有错的peer不再使用；没有取到的节点换个
+   peer重新请求，连续失败多次的peer也不再使用。服务端和历史区块一样按peer限额。
+*/
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/common/trie"
+	sha3 "github.com/yeeco/gyee/crypto/hash"
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/p2p"
+	"github.com/yeeco/gyee/persistent"
+)
+
+const (
+	healBatchSize = 32 // trie nodes asked for in a request
+	healMaxFails  = 3  // consecutive failures of a peer before it's dropped
+	healParallel  = 4  // requests in flight at most
+)
+
+var (
+	ErrHealNoPeers = errors.New("core.heal: no peer to heal state from")
+	ErrHealAborted = errors.New("core.heal: healing aborted")
+)
+
+// Serve trie nodes asked for by hashes, missing ones are left empty
+func (hs *historyServer) serveNodes(peer string, key []byte) []byte {
+	if hs.off {
+		return nil
+	}
+	var hashes []common.Hash
+	if err := rlp.DecodeBytes(key, &hashes); err != nil || len(hashes) == 0 || len(hashes) > healBatchSize {
+		log.Debug("trie nodes request invalid", "peer", peer, "err", err)
+		return nil
+	}
+	n := len(hashes)
+	if len(peer) > 0 {
+		if n = hs.take(peer, n); n == 0 {
+			log.Debug("history quota exceeded", "peer", peer, "kind", ChainDataTypeTrieNodes)
+			return nil
+		}
+	}
+	nodes := make([][]byte, 0, n)
+	size, found := 0, 0
+	for _, hash := range hashes[:n] {
+		blob, err := hs.chain.stateDB.TrieDB().Node(hash)
+		if err != nil {
+			blob = nil
+		}
+		if size += len(blob); size > hs.maxBytes && found > 0 {
+			break
+		}
+		if len(blob) > 0 {
+			found++
+		}
+		nodes = append(nodes, blob)
+	}
+	if len(peer) > 0 {
+		hs.refund(peer, n-found)
+	}
+	if found == 0 {
+		return nil
+	}
+	enc, err := rlp.EncodeToBytes(nodes)
+	if err != nil {
+		log.Warn("trie nodes response encode failed", "err", err)
+		return nil
+	}
+	return enc
+}
+
+// peer trie nodes asked from, empty id for any peer
+type healPeer struct {
+	id    string
+	fails int // consecutive failures
+}
+
+type healResult struct {
+	peer   *healPeer
+	hashes []common.Hash
+	nodes  [][]byte
+	err    error
+}
+
+// StateHealer retrieves trie nodes missing under a state root from peers
+type StateHealer struct {
+	storage persistent.Storage
+	sched   *trie.Sync
+	peers   []*healPeer
+	next    int           // index of peer to ask next
+	retry   []common.Hash // nodes to be asked for again
+
+	// ask a peer for trie nodes
+	getNodes func(peer string, hashes []common.Hash) ([][]byte, error)
+}
+
+func newStateHealer(root common.Hash, storage persistent.Storage, peers []string,
+	getNodes func(peer string, hashes []common.Hash) ([][]byte, error)) *StateHealer {
+	sh := &StateHealer{
+		storage:  storage,
+		sched:    trie.NewSync(root, storage, nil),
+		getNodes: getNodes,
+	}
+	for _, id := range peers {
+		sh.peers = append(sh.peers, &healPeer{id: id})
+	}
+	return sh
+}
+
+// Create healer of state trie rooted at root, with peers of p2p service, or
+// any peer if the service can't ask a given one.
+func (c *Core) NewStateHealer(root common.Hash) *StateHealer {
+	svc := c.node.P2pService()
+	peers := []string{""}
+	if pcg, ok := svc.(p2p.PeerChainInfoGetter); ok {
+		if ids, err := pcg.GetActivePeers(); err == nil && len(ids) > 0 {
+			peers = ids
+		}
+	}
+	return newStateHealer(root, c.storage, peers, func(peer string, hashes []common.Hash) ([][]byte, error) {
+		key, err := rlp.EncodeToBytes(hashes)
+		if err != nil {
+			return nil, err
+		}
+		var enc []byte
+		if pcg, ok := svc.(p2p.PeerChainInfoGetter); ok && len(peer) > 0 {
+			enc, err = pcg.GetChainInfoFrom(peer, ChainDataTypeTrieNodes, key)
+		} else {
+			enc, err = svc.GetChainInfo(ChainDataTypeTrieNodes, key)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return decodeHistoryRsp(enc)
+	})
+}
+
+// Heal state trie rooted at root, it returns when all nodes are retrieved
+func (c *Core) HealState(root common.Hash) error {
+	return c.NewStateHealer(root).Run(c.quitCh)
+}
+
+// Run healing until all nodes missing are retrieved, or no peer left to ask
+func (sh *StateHealer) Run(quit <-chan struct{}) error {
+	results := make(chan *healResult, healParallel)
+	inflight := 0
+	for {
+		for inflight < healParallel {
+			hashes := sh.nextBatch()
+			if len(hashes) == 0 {
+				break
+			}
+			peer := sh.nextPeer()
+			if peer == nil {
+				sh.retry = append(sh.retry, hashes...)
+				break
+			}
+			inflight++
+			go func() {
+				nodes, err := sh.getNodes(peer.id, hashes)
+				results <- &healResult{peer: peer, hashes: hashes, nodes: nodes, err: err}
+			}()
+		}
+		if inflight == 0 {
+			if sh.sched.Pending() == 0 {
+				return nil
+			}
+			return ErrHealNoPeers
+		}
+		select {
+		case <-quit:
+			return ErrHealAborted
+		case r := <-results:
+			inflight--
+			if err := sh.deliver(r); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Number of trie nodes not retrieved yet
+func (sh *StateHealer) Pending() int {
+	return sh.sched.Pending()
+}
+
+// nodes to be asked for in next request, those failed before go first
+func (sh *StateHealer) nextBatch() []common.Hash {
+	n := len(sh.retry)
+	if n > healBatchSize {
+		n = healBatchSize
+	}
+	hashes := append([]common.Hash{}, sh.retry[:n]...)
+	sh.retry = sh.retry[n:]
+	if len(hashes) < healBatchSize {
+		hashes = append(hashes, sh.sched.Missing(healBatchSize-len(hashes))...)
+	}
+	return hashes
+}
+
+// peers are asked in turn, nil if all of them dropped
+func (sh *StateHealer) nextPeer() *healPeer {
+	for range sh.peers {
+		peer := sh.peers[sh.next%len(sh.peers)]
+		sh.next++
+		if peer.fails < healMaxFails {
+			return peer
+		}
+	}
+	return nil
+}
+
+func (sh *StateHealer) deliver(r *healResult) error {
+	if r.err != nil {
+		log.Debug("trie nodes request failed", "peer", r.peer.id, "err", r.err)
+		r.peer.fails++
+		sh.retry = append(sh.retry, r.hashes...)
+		return nil
+	}
+	results := make([]trie.SyncResult, 0, len(r.nodes))
+	for i, hash := range r.hashes {
+		if i >= len(r.nodes) || len(r.nodes[i]) == 0 {
+			sh.retry = append(sh.retry, hash)
+			continue
+		}
+		if !bytes.Equal(sha3.Sha3256(r.nodes[i]), hash[:]) {
+			log.Warn("trie node hash mismatch, peer dropped", "peer", r.peer.id, "hash", hash)
+			r.peer.fails = healMaxFails
+			sh.retry = append(sh.retry, r.hashes[i:]...)
+			break
+		}
+		results = append(results, trie.SyncResult{Hash: hash, Data: r.nodes[i]})
+	}
+	if len(results) == 0 {
+		r.peer.fails++
+		return nil
+	}
+	if r.peer.fails < healMaxFails {
+		r.peer.fails = 0
+	}
+	for _, result := range results {
+		if _, _, err := sh.sched.Process([]trie.SyncResult{result}); err != nil &&
+			err != trie.ErrNotRequested && err != trie.ErrAlreadyProcessed {
+			return err
+		}
+	}
+	batch := sh.storage.NewBatch()
+	if _, err := sh.sched.Commit(batch); err != nil {
+		return err
+	}
+	return batch.Write()
+}
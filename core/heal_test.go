@@ -0,0 +1,144 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/common/trie"
+	"github.com/yeeco/gyee/config"
+	"github.com/yeeco/gyee/persistent"
+)
+
+func newHealTestTrie(t *testing.T, storage persistent.Storage, entries int) common.Hash {
+	db := trie.NewDatabase(storage)
+	tr, err := trie.New(common.Hash{}, db)
+	if err != nil {
+		t.Fatalf("trie.New() %v", err)
+	}
+	for i := 0; i < entries; i++ {
+		key := common.BytesToHash([]byte(fmt.Sprintf("key%d", i)))
+		if err := tr.TryUpdate(key[:], []byte(fmt.Sprintf("value%d", i))); err != nil {
+			t.Fatalf("TryUpdate() %v", err)
+		}
+	}
+	root, err := tr.Commit(nil)
+	if err != nil {
+		t.Fatalf("Commit() %v", err)
+	}
+	if err := db.Commit(root, false); err != nil {
+		t.Fatalf("db.Commit() %v", err)
+	}
+	return root
+}
+
+func serveHealTestNodes(src persistent.Storage, hashes []common.Hash) [][]byte {
+	nodes := make([][]byte, 0, len(hashes))
+	for _, hash := range hashes {
+		blob, _ := src.Get(hash[:])
+		nodes = append(nodes, blob)
+	}
+	return nodes
+}
+
+func TestStateHealer(t *testing.T) {
+	src := persistent.NewMemoryStorage()
+	root := newHealTestTrie(t, src, 500)
+	dst := persistent.NewMemoryStorage()
+
+	var lock sync.Mutex
+	asked := make(map[string]int)
+	sh := newStateHealer(root, dst, []string{"good", "bad", "down"}, func(peer string, hashes []common.Hash) ([][]byte, error) {
+		lock.Lock()
+		asked[peer]++
+		lock.Unlock()
+		switch peer {
+		case "bad":
+			nodes := serveHealTestNodes(src, hashes)
+			nodes[0] = append([]byte{0}, nodes[0]...)
+			return nodes, nil
+		case "down":
+			return nil, errors.New("timeout")
+		}
+		return serveHealTestNodes(src, hashes), nil
+	})
+
+	if err := sh.Run(make(chan struct{})); err != nil {
+		t.Fatalf("Run() %v", err)
+	}
+	if sh.Pending() != 0 {
+		t.Errorf("Pending() %d after healed", sh.Pending())
+	}
+	if asked["bad"] != 1 {
+		t.Errorf("bad peer asked %d times, want 1", asked["bad"])
+	}
+	if asked["down"] != healMaxFails {
+		t.Errorf("down peer asked %d times, want %d", asked["down"], healMaxFails)
+	}
+
+	tr, err := trie.New(root, trie.NewDatabase(dst))
+	if err != nil {
+		t.Fatalf("trie.New() healed %v", err)
+	}
+	for i := 0; i < 500; i++ {
+		key := common.BytesToHash([]byte(fmt.Sprintf("key%d", i)))
+		if value, err := tr.TryGet(key[:]); err != nil || string(value) != fmt.Sprintf("value%d", i) {
+			t.Fatalf("TryGet() healed %d got %s %v", i, value, err)
+		}
+	}
+}
+
+func TestStateHealerNoPeers(t *testing.T) {
+	src := persistent.NewMemoryStorage()
+	root := newHealTestTrie(t, src, 10)
+	sh := newStateHealer(root, persistent.NewMemoryStorage(), []string{"down"}, func(string, []common.Hash) ([][]byte, error) {
+		return nil, errors.New("timeout")
+	})
+	if err := sh.Run(make(chan struct{})); err != ErrHealNoPeers {
+		t.Errorf("Run() got %v, want %v", err, ErrHealNoPeers)
+	}
+}
+
+func TestServeTrieNodes(t *testing.T) {
+	signers, _ := newCheckpointSigners(t, 1)
+	chain, b := newCheckpointBlock(t, signers)
+	hs := newHistoryServer(chain, &config.ChainConfig{})
+
+	hashes := []common.Hash{b.StateRoot(), {1}}
+	key, _ := rlp.EncodeToBytes(hashes)
+	enc := hs.serveNodes("peer", key)
+	nodes, err := decodeHistoryRsp(enc)
+	if err != nil {
+		t.Fatalf("decodeHistoryRsp() %v", err)
+	}
+	if len(nodes) != 2 || len(nodes[0]) == 0 || len(nodes[1]) != 0 {
+		t.Errorf("serveNodes() got %d nodes", len(nodes))
+	}
+	key, _ = rlp.EncodeToBytes(hashes[1:])
+	if enc := hs.serveNodes("peer", key); enc != nil {
+		t.Errorf("serveNodes() of missing got %x", enc)
+	}
+}
@@ -0,0 +1,339 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   历史区块服务：通过p2p的chain data消息（PID_EXT上的GCD/PCD），节点可以向peer请求
+   一段区块的签名区块头或区块体，起点由区块hash或高度指定。同步和轻节点都用这个接口。
+   服务端按peer限额（令牌桶，每个区块头或区块体消耗一个令牌），单个应答的条数和字节
+   数也有上限，超过部分不返回，请求方接着请求剩下的。资源受限的节点可以关闭服务。
+   不知道请求方的调用（本地或者内存网络）不限额。
+*/
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/protobuf/proto"
+	"github.com/hashicorp/golang-lru"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/config"
+	"github.com/yeeco/gyee/core/pb"
+	"github.com/yeeco/gyee/log"
+)
+
+const (
+	DefaultHistoryRate     = 256             // headers and bodies served per second to a peer
+	DefaultHistoryBurst    = 1024            // headers and bodies served to a peer in a burst
+	DefaultHistoryMaxItems = 128             // headers or bodies of a response
+	DefaultHistoryMaxBytes = 2 * 1024 * 1024 // bytes of a response
+	historyQuotaPeers      = 1024            // number of peers quotas kept for
+)
+
+var (
+	ErrHistoryReq      = errors.New("core.history: invalid history request")
+	ErrHistoryResponse = errors.New("core.history: invalid history response")
+)
+
+// Request for a range of historical blocks, starting from the block of Hash
+// if it's set, or the block of Number otherwise.
+type HistoryReq struct {
+	Number uint64
+	Hash   common.Hash
+	Count  uint64
+}
+
+type historyQuota struct {
+	tokens float64   // tokens left
+	last   time.Time // last time tokens refilled
+}
+
+// serves historical blocks of chain to peers
+type historyServer struct {
+	chain    *BlockChain
+	off      bool
+	rate     float64
+	burst    float64
+	maxItems int
+	maxBytes int
+
+	lock   sync.Mutex
+	quotas *lru.Cache // quotas by peer
+}
+
+func newHistoryServer(chain *BlockChain, conf *config.ChainConfig) *historyServer {
+	hs := &historyServer{
+		chain:    chain,
+		off:      conf.HistoryOff,
+		rate:     conf.HistoryRate,
+		burst:    conf.HistoryBurst,
+		maxItems: conf.HistoryMaxItems,
+		maxBytes: conf.HistoryMaxBytes,
+	}
+	if hs.rate <= 0 {
+		hs.rate = DefaultHistoryRate
+	}
+	if hs.burst < 1 {
+		hs.burst = DefaultHistoryBurst
+	}
+	if hs.maxItems <= 0 {
+		hs.maxItems = DefaultHistoryMaxItems
+	}
+	if hs.maxBytes <= 0 {
+		hs.maxBytes = DefaultHistoryMaxBytes
+	}
+	hs.quotas, _ = lru.New(historyQuotaPeers)
+	return hs
+}
+
+// take up to n tokens of peer, the number taken returned
+func (hs *historyServer) take(peer string, n int) int {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+
+	now := time.Now()
+	q := &historyQuota{tokens: hs.burst, last: now}
+	if v, ok := hs.quotas.Get(peer); ok {
+		q = v.(*historyQuota)
+	} else {
+		hs.quotas.Add(peer, q)
+	}
+	q.tokens += now.Sub(q.last).Seconds() * hs.rate
+	if q.tokens > hs.burst {
+		q.tokens = hs.burst
+	}
+	q.last = now
+	if float64(n) > q.tokens {
+		n = int(q.tokens)
+	}
+	q.tokens -= float64(n)
+	return n
+}
+
+// give back tokens taken but not used
+func (hs *historyServer) refund(peer string, n int) {
+	hs.lock.Lock()
+	defer hs.lock.Unlock()
+	if v, ok := hs.quotas.Get(peer); ok {
+		v.(*historyQuota).tokens += float64(n)
+	}
+}
+
+// Serve a request of kind from peer, requests of empty peer are not limited by quota
+func (hs *historyServer) serve(peer string, kind string, key []byte) []byte {
+	if hs.off {
+		return nil
+	}
+	req := new(HistoryReq)
+	if err := rlp.DecodeBytes(key, req); err != nil || req.Count == 0 {
+		log.Debug("history request invalid", "peer", peer, "kind", kind, "err", err)
+		return nil
+	}
+	n := hs.maxItems
+	if req.Count < uint64(n) {
+		n = int(req.Count)
+	}
+	if len(peer) > 0 {
+		if n = hs.take(peer, n); n == 0 {
+			log.Debug("history quota exceeded", "peer", peer, "kind", kind)
+			return nil
+		}
+	}
+	items, err := hs.collect(kind, req, n)
+	if err != nil {
+		log.Debug("history request failed", "peer", peer, "kind", kind, "err", err)
+	}
+	if len(peer) > 0 {
+		hs.refund(peer, n-len(items))
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	enc, err := rlp.EncodeToBytes(items)
+	if err != nil {
+		log.Warn("history response encode failed", "err", err)
+		return nil
+	}
+	return enc
+}
+
+// collect encoded headers or bodies of at most n blocks asked for
+func (hs *historyServer) collect(kind string, req *HistoryReq, n int) ([][]byte, error) {
+	number := req.Number
+	if req.Hash != common.EmptyHash {
+		num := getBlockHash2Num(hs.chain.storage, req.Hash)
+		if num == nil {
+			return nil, ErrHistoryReq
+		}
+		number = *num
+	}
+	items := make([][]byte, 0, n)
+	size := 0
+	for i := 0; i < n; i++ {
+		hash := getBlockNum2Hash(hs.chain.storage, number+uint64(i))
+		if hash == common.EmptyHash {
+			break
+		}
+		var msg proto.Message
+		switch kind {
+		case ChainDataTypeHeaders:
+			if header := hs.chain.readHeader(hash); header != nil {
+				msg = header
+			}
+		case ChainDataTypeBodies:
+			if body := hs.chain.readBody(hash); body != nil {
+				msg = body
+			}
+		default:
+			return nil, ErrHistoryReq
+		}
+		if msg == nil {
+			break
+		}
+		enc, err := proto.Marshal(msg)
+		if err != nil {
+			return items, err
+		}
+		// one item at least, so the requester moves on even if it's too large
+		if size += len(enc); size > hs.maxBytes && len(items) > 0 {
+			break
+		}
+		items = append(items, enc)
+	}
+	return items, nil
+}
+
+func encodeHistoryReq(req *HistoryReq) []byte {
+	enc, _ := rlp.EncodeToBytes(req)
+	return enc
+}
+
+func decodeHistoryRsp(enc []byte) ([][]byte, error) {
+	var items [][]byte
+	if err := rlp.DecodeBytes(enc, &items); err != nil || len(items) == 0 {
+		return nil, ErrHistoryResponse
+	}
+	return items, nil
+}
+
+// Get signed headers of a range of blocks from peers, as blocks without body.
+// fewer headers may be returned than asked for, due to limits of peer serving.
+func (c *Core) GetRemoteHeaders(req *HistoryReq) ([]*Block, error) {
+	if req.Count == 0 {
+		return nil, ErrHistoryReq
+	}
+	c.metrics.p2pChainInfoGet.Mark(1)
+	encoded, err := c.node.P2pService().GetChainInfo(ChainDataTypeHeaders, encodeHistoryReq(req))
+	if err != nil {
+		return nil, err
+	}
+	items, err := decodeHistoryRsp(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(items)) > req.Count {
+		return nil, ErrHistoryResponse
+	}
+	blocks := make([]*Block, 0, len(items))
+	for i, item := range items {
+		signed := new(corepb.SignedBlockHeader)
+		if err := proto.Unmarshal(item, signed); err != nil {
+			return nil, ErrHistoryResponse
+		}
+		header := new(BlockHeader)
+		if err := rlp.DecodeBytes(signed.Header, header); err != nil {
+			return nil, ErrHistoryResponse
+		}
+		b := &Block{header: header, pbHeader: signed}
+		// headers must be the ones asked for, and chained one by one
+		if i == 0 {
+			if req.Hash != common.EmptyHash && b.Hash() != req.Hash ||
+				req.Hash == common.EmptyHash && b.Number() != req.Number {
+				return nil, ErrHistoryResponse
+			}
+		} else if prev := blocks[i-1]; b.Number() != prev.Number()+1 || b.ParentHash() != prev.Hash() {
+			return nil, ErrHistoryResponse
+		}
+		blocks = append(blocks, b)
+	}
+	c.metrics.p2pChainInfoHit.Mark(1)
+	return blocks, nil
+}
+
+// Get blocks of a range from peers, headers first and then bodies of them.
+// fewer blocks may be returned than asked for, due to limits of peer serving.
+func (c *Core) GetRemoteBlocks(req *HistoryReq) ([]*Block, error) {
+	blocks, err := c.GetRemoteHeaders(req)
+	if err != nil {
+		return nil, err
+	}
+	filled := 0
+	for filled < len(blocks) {
+		n, err := c.getRemoteBodies(blocks[filled:])
+		if err != nil {
+			if filled == 0 {
+				return nil, err
+			}
+			break
+		}
+		filled += n
+	}
+	return blocks[:filled], nil
+}
+
+// fill bodies of blocks from peers, the number of blocks filled returned
+func (c *Core) getRemoteBodies(blocks []*Block) (int, error) {
+	req := &HistoryReq{Hash: blocks[0].Hash(), Count: uint64(len(blocks))}
+	c.metrics.p2pChainInfoGet.Mark(1)
+	encoded, err := c.node.P2pService().GetChainInfo(ChainDataTypeBodies, encodeHistoryReq(req))
+	if err != nil {
+		return 0, err
+	}
+	items, err := decodeHistoryRsp(encoded)
+	if err != nil {
+		return 0, err
+	}
+	if len(items) > len(blocks) {
+		return 0, ErrHistoryResponse
+	}
+	for i, item := range items {
+		body := new(corepb.BlockBody)
+		if err := proto.Unmarshal(item, body); err != nil {
+			return 0, ErrHistoryResponse
+		}
+		enc, err := proto.Marshal(&corepb.Block{Header: blocks[i].pbHeader, Body: body})
+		if err != nil {
+			return 0, err
+		}
+		b, err := ParseBlock(enc)
+		if err != nil {
+			return 0, ErrHistoryResponse
+		}
+		if err := b.VerifyBody(); err != nil {
+			return 0, err
+		}
+		blocks[i] = b
+	}
+	c.metrics.p2pChainInfoHit.Mark(1)
+	return len(items), nil
+}
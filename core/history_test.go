@@ -0,0 +1,105 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/yeeco/gyee/config"
+	"github.com/yeeco/gyee/core/pb"
+)
+
+func serveHistory(t *testing.T, hs *historyServer, peer, kind string, req *HistoryReq) [][]byte {
+	enc := hs.serve(peer, kind, encodeHistoryReq(req))
+	if len(enc) == 0 {
+		return nil
+	}
+	items, err := decodeHistoryRsp(enc)
+	if err != nil {
+		t.Fatalf("decodeHistoryRsp() %v", err)
+	}
+	return items
+}
+
+func TestHistoryServe(t *testing.T) {
+	signers, _ := newCheckpointSigners(t, 1)
+	chain, b := newCheckpointBlock(t, signers)
+	hs := newHistoryServer(chain, &config.ChainConfig{})
+
+	items := serveHistory(t, hs, "peer", ChainDataTypeHeaders, &HistoryReq{Number: 0, Count: 10})
+	if len(items) != 2 {
+		t.Fatalf("headers got %d, want 2", len(items))
+	}
+	header := new(corepb.SignedBlockHeader)
+	if err := proto.Unmarshal(items[1], header); err != nil {
+		t.Fatalf("Unmarshal() %v", err)
+	}
+	if string(header.Header) != string(b.pbHeader.Header) {
+		t.Errorf("header mismatch")
+	}
+
+	items = serveHistory(t, hs, "peer", ChainDataTypeBodies, &HistoryReq{Hash: b.Hash(), Count: 10})
+	if len(items) != 1 {
+		t.Errorf("bodies by hash got %d, want 1", len(items))
+	}
+	if items := serveHistory(t, hs, "peer", ChainDataTypeHeaders, &HistoryReq{Number: 5, Count: 1}); items != nil {
+		t.Errorf("headers beyond chain got %d", len(items))
+	}
+	if items := serveHistory(t, hs, "peer", "unknown", &HistoryReq{Count: 1}); items != nil {
+		t.Errorf("unknown kind got %d", len(items))
+	}
+}
+
+func TestHistoryLimits(t *testing.T) {
+	signers, _ := newCheckpointSigners(t, 1)
+	chain, _ := newCheckpointBlock(t, signers)
+	req := &HistoryReq{Number: 0, Count: 2}
+
+	// one byte cap still serves one item
+	hs := newHistoryServer(chain, &config.ChainConfig{HistoryMaxBytes: 1})
+	if items := serveHistory(t, hs, "peer", ChainDataTypeHeaders, req); len(items) != 1 {
+		t.Errorf("bytes capped got %d, want 1", len(items))
+	}
+
+	// quota of peer, only requests of peers are limited
+	hs = newHistoryServer(chain, &config.ChainConfig{HistoryRate: 0.001, HistoryBurst: 3})
+	if items := serveHistory(t, hs, "peer", ChainDataTypeHeaders, req); len(items) != 2 {
+		t.Errorf("within quota got %d, want 2", len(items))
+	}
+	if items := serveHistory(t, hs, "peer", ChainDataTypeHeaders, req); len(items) != 1 {
+		t.Errorf("quota left got %d, want 1", len(items))
+	}
+	if items := serveHistory(t, hs, "peer", ChainDataTypeHeaders, req); items != nil {
+		t.Errorf("quota exceeded got %d", len(items))
+	}
+	if items := serveHistory(t, hs, "other", ChainDataTypeHeaders, req); len(items) != 2 {
+		t.Errorf("other peer got %d, want 2", len(items))
+	}
+	if items := serveHistory(t, hs, "", ChainDataTypeHeaders, req); len(items) != 2 {
+		t.Errorf("no peer got %d, want 2", len(items))
+	}
+
+	hs = newHistoryServer(chain, &config.ChainConfig{HistoryOff: true})
+	if items := serveHistory(t, hs, "peer", ChainDataTypeHeaders, req); items != nil {
+		t.Errorf("serving off got %d", len(items))
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/p2p"
 )
 
 type coreMetrics struct {
@@ -38,9 +39,25 @@ type coreMetrics struct {
 	p2pMsgRecvEv   metrics.Meter
 	p2pMsgRecvTx   metrics.Meter
 
+	p2pMsgRecvEvBad metrics.Meter
+	p2pMsgRecvEvDup metrics.Meter
+
 	p2pChainInfoGet    metrics.Meter
 	p2pChainInfoHit    metrics.Meter
 	p2pChainInfoAnswer metrics.Meter
+
+	p2pPeerVersions map[string]metrics.Gauge
+
+	p2pAcceptPaused    metrics.Gauge
+	p2pAcceptPauses    metrics.Gauge
+	p2pAcceptResumes   metrics.Gauge
+	p2pAcceptDeferred  metrics.Gauge
+	p2pAcceptIpFull    metrics.Gauge
+	p2pAcceptIpLimited metrics.Gauge
+
+	p2pKillStats map[string]metrics.Gauge
+
+	storageUsage map[string]metrics.Gauge
 }
 
 func newCoreMetrics() *coreMetrics {
@@ -59,9 +76,82 @@ func newCoreMetrics() *coreMetrics {
 		p2pMsgRecvEv:   metrics.NewRegisteredMeter("core/p2p/msg/recvEv", nil),
 		p2pMsgRecvTx:   metrics.NewRegisteredMeter("core/p2p/msg/recvTx", nil),
 
+		p2pMsgRecvEvBad: metrics.NewRegisteredMeter("core/p2p/msg/recvEvBad", nil),
+		p2pMsgRecvEvDup: metrics.NewRegisteredMeter("core/p2p/msg/recvEvDup", nil),
+
 		p2pChainInfoGet:    metrics.NewRegisteredMeter("core/p2p/cInfo/get", nil),
 		p2pChainInfoHit:    metrics.NewRegisteredMeter("core/p2p/cInfo/hit", nil),
 		p2pChainInfoAnswer: metrics.NewRegisteredMeter("core/p2p/cInfo/answer", nil),
+
+		p2pPeerVersions: make(map[string]metrics.Gauge),
+
+		p2pAcceptPaused:    metrics.NewRegisteredGauge("core/p2p/accept/paused", nil),
+		p2pAcceptPauses:    metrics.NewRegisteredGauge("core/p2p/accept/pauses", nil),
+		p2pAcceptResumes:   metrics.NewRegisteredGauge("core/p2p/accept/resumes", nil),
+		p2pAcceptDeferred:  metrics.NewRegisteredGauge("core/p2p/accept/deferred", nil),
+		p2pAcceptIpFull:    metrics.NewRegisteredGauge("core/p2p/accept/ipFull", nil),
+		p2pAcceptIpLimited: metrics.NewRegisteredGauge("core/p2p/accept/ipLimited", nil),
+
+		p2pKillStats: make(map[string]metrics.Gauge),
+
+		storageUsage: make(map[string]metrics.Gauge),
+	}
+}
+
+// update number of active peers by client version, gauges of versions gone are zeroed
+func (cm *coreMetrics) updatePeerVersions(vers map[string]int) {
+	for v, g := range cm.p2pPeerVersions {
+		if _, ok := vers[v]; !ok {
+			g.Update(0)
+		}
+	}
+	for v, n := range vers {
+		g, ok := cm.p2pPeerVersions[v]
+		if !ok {
+			g = metrics.GetOrRegisterGauge("core/p2p/peer/version/"+v, nil)
+			cm.p2pPeerVersions[v] = g
+		}
+		g.Update(int64(n))
+	}
+}
+
+// update pausing of the accepter of inbound peers, pauses and resumes are the
+// times it flapped since started, and connections closed for the quota per ip
+func (cm *coreMetrics) updateAcceptStats(stats *p2p.AcceptStats) {
+	paused := int64(0)
+	if stats.Paused {
+		paused = 1
+	}
+	cm.p2pAcceptPaused.Update(paused)
+	cm.p2pAcceptPauses.Update(stats.Pauses)
+	cm.p2pAcceptResumes.Update(stats.Resumes)
+	cm.p2pAcceptDeferred.Update(stats.Deferred)
+	cm.p2pAcceptIpFull.Update(stats.IpFull)
+	cm.p2pAcceptIpLimited.Update(stats.IpLimited)
+}
+
+// update number of peer instances killed by sub network and cause
+func (cm *coreMetrics) updateKillStats(stats []p2p.KillStat) {
+	for _, ks := range stats {
+		name := "core/p2p/kill/" + ks.Snid + "/" + ks.Cause
+		g, ok := cm.p2pKillStats[name]
+		if !ok {
+			g = metrics.GetOrRegisterGauge(name, nil)
+			cm.p2pKillStats[name] = g
+		}
+		g.Update(ks.Count)
+	}
+}
+
+// update estimated disk usage in bytes by keyspace
+func (cm *coreMetrics) updateStorageUsage(usages []*KeyspaceUsage) {
+	for _, usage := range usages {
+		g, ok := cm.storageUsage[usage.Name]
+		if !ok {
+			g = metrics.GetOrRegisterGauge("core/storage/"+usage.Name, nil)
+			cm.storageUsage[usage.Name] = g
+		}
+		g.Update(int64(usage.Bytes))
 	}
 }
 
@@ -75,6 +165,7 @@ func (cm *coreMetrics) printMetrics() {
 	m["msgRecv"] = fmt.Sprintf("%d", cm.p2pMsgRecv.Count())
 	m["msgRecvType"] = fmt.Sprintf("blk:%d H:%d tx:%d ev:%d",
 		cm.p2pMsgRecvBlk.Count(), cm.p2pMsgRecvH.Count(), cm.p2pMsgRecvTx.Count(), cm.p2pMsgRecvEv.Count())
+	m["msgRecvEv"] = fmt.Sprintf("bad:%d dup:%d", cm.p2pMsgRecvEvBad.Count(), cm.p2pMsgRecvEvDup.Count())
 
 	m["cInfoGet"] = fmt.Sprintf("%d / %d", cm.p2pChainInfoHit.Count(), cm.p2pChainInfoGet.Count())
 	m["cInfoAns"] = fmt.Sprintf("%d", cm.p2pChainInfoAnswer.Count())
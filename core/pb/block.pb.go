@@ -32,7 +32,7 @@ func (m *Account) Reset()         { *m = Account{} }
 func (m *Account) String() string { return proto.CompactTextString(m) }
 func (*Account) ProtoMessage()    {}
 func (*Account) Descriptor() ([]byte, []int) {
-	return fileDescriptor_block_dc06e4ac52b7100d, []int{0}
+	return fileDescriptor_block_a8ff2d9425249be8, []int{0}
 }
 func (m *Account) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Account.Unmarshal(m, b)
@@ -85,7 +85,7 @@ func (m *Signature) Reset()         { *m = Signature{} }
 func (m *Signature) String() string { return proto.CompactTextString(m) }
 func (*Signature) ProtoMessage()    {}
 func (*Signature) Descriptor() ([]byte, []int) {
-	return fileDescriptor_block_dc06e4ac52b7100d, []int{1}
+	return fileDescriptor_block_a8ff2d9425249be8, []int{1}
 }
 func (m *Signature) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Signature.Unmarshal(m, b)
@@ -135,6 +135,20 @@ type Transaction struct {
 	Recipient []byte `protobuf:"bytes,3,opt,name=recipient,proto3" json:"recipient,omitempty"`
 	// transaction amount
 	Amount []byte `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	// max gas the sender pays for
+	GasLimit uint64 `protobuf:"varint,5,opt,name=gas_limit,json=gasLimit,proto3" json:"gas_limit,omitempty"`
+	// price of gas
+	GasPrice []byte `protobuf:"bytes,6,opt,name=gas_price,json=gasPrice,proto3" json:"gas_price,omitempty"`
+	// payload for contract or data anchored
+	Payload []byte `protobuf:"bytes,7,opt,name=payload,proto3" json:"payload,omitempty"`
+	// envelope version, 0 for the first one
+	Version uint32 `protobuf:"varint,8,opt,name=version,proto3" json:"version,omitempty"`
+	// transaction type, 0 for transfer
+	Type uint32 `protobuf:"varint,9,opt,name=type,proto3" json:"type,omitempty"`
+	// unix time in seconds tx valid from, 0 for no limit
+	NotBefore uint64 `protobuf:"varint,10,opt,name=not_before,json=notBefore,proto3" json:"not_before,omitempty"`
+	// unix time in seconds tx expires at, 0 for never
+	ExpiresAt uint64 `protobuf:"varint,11,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
 	// signature with LAST MESSAGE TAG of one byte
 	Signature            *Signature `protobuf:"bytes,15,opt,name=signature,proto3" json:"signature,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
@@ -146,7 +160,7 @@ func (m *Transaction) Reset()         { *m = Transaction{} }
 func (m *Transaction) String() string { return proto.CompactTextString(m) }
 func (*Transaction) ProtoMessage()    {}
 func (*Transaction) Descriptor() ([]byte, []int) {
-	return fileDescriptor_block_dc06e4ac52b7100d, []int{2}
+	return fileDescriptor_block_a8ff2d9425249be8, []int{2}
 }
 func (m *Transaction) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Transaction.Unmarshal(m, b)
@@ -194,6 +208,55 @@ func (m *Transaction) GetAmount() []byte {
 	return nil
 }
 
+func (m *Transaction) GetGasLimit() uint64 {
+	if m != nil {
+		return m.GasLimit
+	}
+	return 0
+}
+
+func (m *Transaction) GetGasPrice() []byte {
+	if m != nil {
+		return m.GasPrice
+	}
+	return nil
+}
+
+func (m *Transaction) GetPayload() []byte {
+	if m != nil {
+		return m.Payload
+	}
+	return nil
+}
+
+func (m *Transaction) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *Transaction) GetType() uint32 {
+	if m != nil {
+		return m.Type
+	}
+	return 0
+}
+
+func (m *Transaction) GetNotBefore() uint64 {
+	if m != nil {
+		return m.NotBefore
+	}
+	return 0
+}
+
+func (m *Transaction) GetExpiresAt() uint64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
 func (m *Transaction) GetSignature() *Signature {
 	if m != nil {
 		return m.Signature
@@ -202,9 +265,10 @@ func (m *Transaction) GetSignature() *Signature {
 }
 
 // message for
-//   block header
-//   bloom filter for related addresses
-//   multiple signatures from validator
+//
+//	block header
+//	bloom filter for related addresses
+//	multiple signatures from validator
 type SignedBlockHeader struct {
 	// embedded encoded block header
 	Header []byte `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
@@ -221,7 +285,7 @@ func (m *SignedBlockHeader) Reset()         { *m = SignedBlockHeader{} }
 func (m *SignedBlockHeader) String() string { return proto.CompactTextString(m) }
 func (*SignedBlockHeader) ProtoMessage()    {}
 func (*SignedBlockHeader) Descriptor() ([]byte, []int) {
-	return fileDescriptor_block_dc06e4ac52b7100d, []int{3}
+	return fileDescriptor_block_a8ff2d9425249be8, []int{3}
 }
 func (m *SignedBlockHeader) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SignedBlockHeader.Unmarshal(m, b)
@@ -263,7 +327,8 @@ func (m *SignedBlockHeader) GetSignatures() []*Signature {
 }
 
 // message for
-//   block body = block - header
+//
+//	block body = block - header
 type BlockBody struct {
 	// encoded transaction bytes
 	RawTransactions      [][]byte `protobuf:"bytes,1,rep,name=raw_transactions,json=rawTransactions,proto3" json:"raw_transactions,omitempty"`
@@ -276,7 +341,7 @@ func (m *BlockBody) Reset()         { *m = BlockBody{} }
 func (m *BlockBody) String() string { return proto.CompactTextString(m) }
 func (*BlockBody) ProtoMessage()    {}
 func (*BlockBody) Descriptor() ([]byte, []int) {
-	return fileDescriptor_block_dc06e4ac52b7100d, []int{4}
+	return fileDescriptor_block_a8ff2d9425249be8, []int{4}
 }
 func (m *BlockBody) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BlockBody.Unmarshal(m, b)
@@ -315,7 +380,7 @@ func (m *Block) Reset()         { *m = Block{} }
 func (m *Block) String() string { return proto.CompactTextString(m) }
 func (*Block) ProtoMessage()    {}
 func (*Block) Descriptor() ([]byte, []int) {
-	return fileDescriptor_block_dc06e4ac52b7100d, []int{5}
+	return fileDescriptor_block_a8ff2d9425249be8, []int{5}
 }
 func (m *Block) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Block.Unmarshal(m, b)
@@ -358,30 +423,36 @@ func init() {
 	proto.RegisterType((*Block)(nil), "corepb.Block")
 }
 
-func init() { proto.RegisterFile("block.proto", fileDescriptor_block_dc06e4ac52b7100d) }
-
-var fileDescriptor_block_dc06e4ac52b7100d = []byte{
-	// 346 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x92, 0x4f, 0x6b, 0xe3, 0x30,
-	0x10, 0xc5, 0x71, 0x9c, 0x3f, 0x78, 0xec, 0x90, 0x8d, 0x58, 0x16, 0x2f, 0xe4, 0x60, 0x0c, 0x05,
-	0xf7, 0x92, 0x92, 0x14, 0x0a, 0x3d, 0x26, 0xf4, 0xd0, 0x5e, 0xd5, 0xde, 0x8b, 0x2c, 0x0b, 0x47,
-	0xd4, 0x91, 0x82, 0xa4, 0x10, 0xf2, 0x7d, 0xfa, 0x41, 0x8b, 0xe4, 0xd8, 0x51, 0xa0, 0x37, 0xbf,
-	0xb1, 0xe7, 0xcd, 0xef, 0x3d, 0x0c, 0x71, 0xd9, 0x48, 0xfa, 0xb5, 0x3c, 0x28, 0x69, 0x24, 0x1a,
-	0x53, 0xa9, 0xd8, 0xa1, 0xcc, 0x9f, 0x61, 0xb2, 0xa1, 0x54, 0x1e, 0x85, 0x41, 0x7f, 0x61, 0x24,
-	0xa4, 0xa0, 0x2c, 0x0d, 0xb2, 0xa0, 0x18, 0xe2, 0x56, 0xa0, 0x14, 0x26, 0x25, 0x69, 0x88, 0x9d,
-	0x0f, 0xb2, 0xa0, 0x48, 0x70, 0x27, 0x73, 0x06, 0xd1, 0x3b, 0xaf, 0x05, 0x31, 0x47, 0xc5, 0xd0,
-	0x3f, 0x18, 0x6b, 0x5e, 0x0b, 0xa6, 0xdc, 0x76, 0x82, 0x2f, 0x0a, 0xe5, 0x90, 0x68, 0x5e, 0x6f,
-	0x9a, 0x5a, 0x2a, 0x6e, 0x76, 0x7b, 0xe7, 0x31, 0xc5, 0x37, 0x33, 0xb4, 0x80, 0x48, 0x77, 0x46,
-	0x69, 0xe8, 0xd6, 0xaf, 0x83, 0xfc, 0x3b, 0x80, 0xf8, 0x43, 0x11, 0xa1, 0x09, 0x35, 0x5c, 0x0a,
-	0x0b, 0x44, 0x77, 0x84, 0x8b, 0xb7, 0x17, 0x77, 0x6a, 0x8a, 0x3b, 0x79, 0x0d, 0x30, 0xf0, 0x03,
-	0x2c, 0x20, 0x52, 0x8c, 0xf2, 0x03, 0x67, 0xc2, 0x74, 0xee, 0xfd, 0xc0, 0x72, 0x93, 0xbd, 0x8d,
-	0x9f, 0x0e, 0x5b, 0xee, 0x56, 0xa1, 0x07, 0x9f, 0x69, 0x96, 0x05, 0x45, 0xbc, 0x9e, 0x2f, 0xdb,
-	0xce, 0x96, 0x7d, 0x6a, 0x1f, 0xd3, 0xc0, 0xdc, 0xce, 0x59, 0xb5, 0xb5, 0x2d, 0xbf, 0x32, 0x52,
-	0x31, 0x65, 0xdd, 0x77, 0xee, 0xa9, 0x6b, 0xa5, 0x55, 0x96, 0xb4, 0x6c, 0xa4, 0xdc, 0x5f, 0x2a,
-	0x6d, 0x05, 0x5a, 0x01, 0xf4, 0x7e, 0x3a, 0x0d, 0xb3, 0xf0, 0xf7, 0xa3, 0xde, 0x47, 0xf9, 0x13,
-	0x44, 0xee, 0xde, 0x56, 0x56, 0x67, 0x74, 0x0f, 0x7f, 0x14, 0x39, 0x7d, 0x9a, 0x6b, 0x59, 0x3a,
-	0x0d, 0xb2, 0xb0, 0x48, 0xf0, 0x4c, 0x91, 0x93, 0xd7, 0xa1, 0xce, 0x09, 0x8c, 0xdc, 0x1e, 0x5a,
-	0xdd, 0x10, 0xc6, 0xeb, 0xff, 0xfe, 0xbd, 0x9b, 0x30, 0x3d, 0xfc, 0x1d, 0x0c, 0x4b, 0x59, 0x9d,
-	0x1d, 0xbb, 0x07, 0xd8, 0x73, 0x60, 0xf7, 0xba, 0x1c, 0xbb, 0x1f, 0xed, 0xf1, 0x27, 0x00, 0x00,
-	0xff, 0xff, 0xb0, 0xfd, 0x05, 0xd1, 0x77, 0x02, 0x00, 0x00,
+func init() { proto.RegisterFile("block.proto", fileDescriptor_block_a8ff2d9425249be8) }
+
+var fileDescriptor_block_a8ff2d9425249be8 = []byte{
+	// 444 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x53, 0x4d, 0x8b, 0xd4, 0x40,
+	0x10, 0x25, 0xf3, 0xb9, 0xa9, 0xcc, 0xb2, 0x6e, 0x23, 0xd2, 0xe2, 0x0a, 0x21, 0x20, 0x8c, 0x97,
+	0x91, 0x5d, 0x41, 0xf0, 0x38, 0x83, 0x07, 0x05, 0x0f, 0x12, 0xbd, 0x0f, 0x9d, 0xa4, 0xcc, 0x34,
+	0x66, 0xba, 0x42, 0x77, 0xaf, 0xeb, 0xfc, 0x5f, 0x7f, 0x88, 0x74, 0x77, 0x92, 0xc9, 0x80, 0xb7,
+	0x7e, 0xef, 0xa5, 0xaa, 0x5e, 0x7d, 0x04, 0x92, 0xa2, 0xa1, 0xf2, 0xd7, 0xa6, 0xd5, 0x64, 0x89,
+	0x2d, 0x4a, 0xd2, 0xd8, 0x16, 0xd9, 0x47, 0x58, 0x6e, 0xcb, 0x92, 0x1e, 0x95, 0x65, 0xcf, 0x61,
+	0xae, 0x48, 0x95, 0xc8, 0xa3, 0x34, 0x5a, 0xcf, 0xf2, 0x00, 0x18, 0x87, 0x65, 0x21, 0x1a, 0xe1,
+	0xf8, 0x49, 0x1a, 0xad, 0x57, 0x79, 0x0f, 0x33, 0x84, 0xf8, 0xbb, 0xac, 0x95, 0xb0, 0x8f, 0x1a,
+	0xd9, 0x0b, 0x58, 0x18, 0x59, 0x2b, 0xd4, 0x3e, 0x7a, 0x95, 0x77, 0x88, 0x65, 0xb0, 0x32, 0xb2,
+	0xde, 0x36, 0x35, 0x69, 0x69, 0x0f, 0x47, 0x9f, 0xe3, 0x3a, 0xbf, 0xe0, 0xd8, 0x1d, 0xc4, 0xa6,
+	0x4f, 0xc4, 0xa7, 0x3e, 0xfc, 0x4c, 0x64, 0x7f, 0x27, 0x90, 0xfc, 0xd0, 0x42, 0x19, 0x51, 0x5a,
+	0x49, 0xca, 0x19, 0x2a, 0x0f, 0x42, 0xaa, 0x2f, 0x9f, 0x7c, 0xa9, 0xeb, 0xbc, 0x87, 0xe7, 0x06,
+	0x26, 0xe3, 0x06, 0xee, 0x20, 0xd6, 0x58, 0xca, 0x56, 0xa2, 0xb2, 0x7d, 0xf6, 0x81, 0x70, 0xbe,
+	0xc5, 0xd1, 0xb5, 0xcf, 0x67, 0xc1, 0x77, 0x40, 0xec, 0x15, 0xc4, 0xb5, 0x30, 0xfb, 0x46, 0x1e,
+	0xa5, 0xe5, 0x73, 0x9f, 0xef, 0xaa, 0x16, 0xe6, 0xab, 0xc3, 0xbd, 0xd8, 0x6a, 0x59, 0x22, 0x5f,
+	0xf8, 0x38, 0x27, 0x7e, 0x73, 0xd8, 0xf9, 0x6b, 0xc5, 0xa9, 0x21, 0x51, 0xf1, 0x65, 0x18, 0x58,
+	0x07, 0x9d, 0xf2, 0x1b, 0xb5, 0x91, 0xa4, 0xf8, 0x55, 0x70, 0xde, 0x41, 0xc6, 0x60, 0x66, 0x4f,
+	0x2d, 0xf2, 0xd8, 0xd3, 0xfe, 0xcd, 0x5e, 0x03, 0x28, 0xb2, 0xfb, 0x02, 0x7f, 0x92, 0x46, 0x0e,
+	0xde, 0x42, 0xac, 0xc8, 0xee, 0x3c, 0xe1, 0x64, 0xfc, 0xd3, 0x4a, 0x8d, 0x66, 0x2f, 0x2c, 0x4f,
+	0x82, 0xdc, 0x31, 0x5b, 0xcb, 0xde, 0x8d, 0x67, 0x7a, 0x93, 0x46, 0xeb, 0xe4, 0xe1, 0x76, 0x13,
+	0x76, 0xbe, 0x19, 0xb6, 0x36, 0x1e, 0xb3, 0x85, 0x5b, 0xc7, 0x63, 0xb5, 0x73, 0x57, 0xf2, 0x19,
+	0x45, 0x85, 0xda, 0x4d, 0xe7, 0xe0, 0x5f, 0xfd, 0x56, 0x03, 0x72, 0x93, 0x2e, 0x1a, 0xa2, 0x63,
+	0x77, 0x12, 0x01, 0xb0, 0x7b, 0x80, 0x21, 0x9f, 0xe1, 0xd3, 0x74, 0xfa, 0xff, 0xa2, 0xa3, 0x8f,
+	0xb2, 0x0f, 0x10, 0xfb, 0x7a, 0x3b, 0xaa, 0x4e, 0xec, 0x2d, 0x3c, 0xd3, 0xe2, 0x69, 0x6f, 0xcf,
+	0xcb, 0x36, 0x3c, 0x4a, 0xa7, 0xeb, 0x55, 0x7e, 0xa3, 0xc5, 0xd3, 0xe8, 0x06, 0x4c, 0x26, 0x60,
+	0xee, 0xe3, 0xd8, 0xfd, 0x85, 0xc3, 0xe4, 0xe1, 0xe5, 0xb8, 0xde, 0x45, 0x33, 0x83, 0xf9, 0x37,
+	0x30, 0x2b, 0xa8, 0x3a, 0x79, 0xef, 0x23, 0x83, 0x83, 0x8f, 0xdc, 0xcb, 0xc5, 0xc2, 0xff, 0x28,
+	0xef, 0xff, 0x0d, 0x00, 0xee, 0x24, 0x89, 0xbf, 0x37, 0x03, 0x00, 0x00,
 }
@@ -18,18 +18,36 @@
 package core
 
 import (
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/core/yvm"
 	"github.com/yeeco/gyee/log"
 )
 
+// Receipt of contract-bearing tx executed by VM
 type Receipt struct {
+	TxHash  common.Hash
+	Failed  bool
+	GasUsed uint64 // intrinsic gas and gas used by VM
+	Logs    []*yvm.Log
+
 	// caches
 	raw []byte
 }
 
-func newReceipt() *Receipt {
+func newReceipt(tx *Transaction, gasUsed uint64, result *yvm.Result) (*Receipt, error) {
 	r := &Receipt{
+		TxHash:  *tx.Hash(),
+		Failed:  result.Failed,
+		GasUsed: gasUsed,
+		Logs:    result.Logs,
+	}
+	enc, err := rlp.EncodeToBytes(r)
+	if err != nil {
+		return nil, err
 	}
-	return r
+	r.raw = enc
+	return r, nil
 }
 
 type Receipts []*Receipt
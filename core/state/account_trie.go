@@ -21,6 +21,7 @@ import (
 	"fmt"
 
 	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/common/trie"
 	"github.com/yeeco/gyee/log"
 )
 
@@ -106,6 +107,25 @@ func (at *accountTrie) GetAccount(address common.Address, createIfMissing bool)
 	return account
 }
 
+func (at *accountTrie) ProveAccount(address common.Address, proof *trie.ProofSet) error {
+	return at.trie.Prove(address[:], proof)
+}
+
+// Verify proof of account against state root, returns account detached from
+// any trie, nil if the proof shows account not in state
+func VerifyAccountProof(root common.Hash, address common.Address, proof trie.DatabaseReader) (Account, error) {
+	enc, err := trie.VerifyProof(root, address[:], proof)
+	if err != nil || len(enc) == 0 {
+		return nil, err
+	}
+	account := newAccount(nil, address)
+	if err := account.setBytes(enc); err != nil {
+		return nil, err
+	}
+	account.dirty = false
+	return account, nil
+}
+
 //
 // trie ops
 //
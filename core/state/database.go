@@ -48,6 +48,7 @@ type Trie interface {
 	Commit(onleaf trie.LeafCallback) (common.Hash, error)
 	Hash() common.Hash
 	NodeIterator(startKey []byte) trie.NodeIterator
+	Prove(key []byte, proof *trie.ProofSet) error
 }
 
 func NewDatabase(storage persistent.Storage) Database {
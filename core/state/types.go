@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/common/trie"
 )
 
 // interface for single account, NO CONCURRENCY
@@ -66,6 +67,10 @@ type AccountTrie interface {
 
 	// Get account from trie, create if requested
 	GetAccount(address common.Address, createIfMissing bool) Account
+
+	// Add proof of account against Root() to proof set, changes of
+	// accounts not committed yet are not covered
+	ProveAccount(address common.Address, proof *trie.ProofSet) error
 }
 
 type ConsensusTrie interface {
@@ -0,0 +1,156 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   状态摘要：怀疑共识出错、两个节点状态不一致时，用来快速定位差异。按key顺序遍历某个
+   区块的账户trie，把每个账户的（地址，数据）依次计入摘要，和trie的编码方式无关。
+   同时按地址的下一位十六进制数分成16组各算一个子摘要。两边比较子摘要，找到不同的
+   那一组，再以它为前缀继续计算，逐步缩小到具体的账户。
+*/
+
+import (
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/common/trie"
+	sha3 "github.com/yeeco/gyee/crypto/hash"
+)
+
+const hexDigits = "0123456789abcdef"
+
+var (
+	ErrStateDigestPrefix = errors.New("core.digest: invalid address prefix")
+	ErrStateDigestBlock  = errors.New("core.digest: block not found")
+)
+
+// StateDigest of accounts under an address prefix in state of a block
+type StateDigest struct {
+	Number    uint64
+	Hash      common.Hash // block hash
+	StateRoot common.Hash
+	Prefix    string          // address prefix in hex, empty for all accounts
+	Accounts  uint64          // number of accounts under prefix
+	Digest    common.Hash     // digest of accounts under prefix
+	Subs      []*PrefixDigest // digests by the next hex digit of address
+}
+
+// PrefixDigest of accounts under an address prefix
+type PrefixDigest struct {
+	Prefix   string
+	Accounts uint64
+	Digest   common.Hash
+}
+
+type digester struct {
+	hasher   hash.Hash
+	accounts uint64
+}
+
+func (d *digester) add(entry []byte) {
+	d.hasher.Write(entry)
+	d.accounts++
+}
+
+func (d *digester) sum() common.Hash {
+	return common.BytesToHash(d.hasher.Sum(nil))
+}
+
+// Get digest of accounts under address prefix in state of block number, with
+// sub-digests by the next hex digit of address.
+func (bc *BlockChain) StateDigest(number uint64, prefix string) (*StateDigest, error) {
+	prefix = strings.TrimPrefix(strings.ToLower(prefix), "0x")
+	if len(prefix) > 2*common.AddressLength {
+		return nil, ErrStateDigestPrefix
+	}
+	b := bc.GetBlockByNumber(number)
+	if b == nil {
+		return nil, ErrStateDigestBlock
+	}
+	tr, err := trie.New(b.StateRoot(), bc.stateDB.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	sd, err := digestTrie(tr, prefix)
+	if err != nil {
+		return nil, err
+	}
+	sd.Number = b.Number()
+	sd.Hash = b.Hash()
+	sd.StateRoot = b.StateRoot()
+	return sd, nil
+}
+
+// digest entries of trie with keys under hex prefix, in order of keys
+func digestTrie(tr *trie.Trie, prefix string) (*StateDigest, error) {
+	start := prefix
+	if len(start)%2 == 1 {
+		start += "0"
+	}
+	startKey, err := hex.DecodeString(start)
+	if err != nil {
+		return nil, ErrStateDigestPrefix
+	}
+
+	total := &digester{hasher: sha3.NewHash256()}
+	subs := make([]*digester, len(hexDigits))
+	for i := range subs {
+		subs[i] = &digester{hasher: sha3.NewHash256()}
+	}
+	it := trie.NewIterator(tr.NodeIterator(startKey))
+	for it.Next() {
+		key := hex.EncodeToString(it.Key)
+		if !strings.HasPrefix(key, prefix) {
+			break
+		}
+		entry, err := rlp.EncodeToBytes([][]byte{it.Key, it.Value})
+		if err != nil {
+			return nil, err
+		}
+		total.add(entry)
+		if len(key) > len(prefix) {
+			subs[strings.IndexByte(hexDigits, key[len(prefix)])].add(entry)
+		}
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+
+	sd := &StateDigest{
+		Prefix:   prefix,
+		Accounts: total.accounts,
+		Digest:   total.sum(),
+	}
+	if len(prefix) < 2*common.AddressLength {
+		for i, d := range subs {
+			sd.Subs = append(sd.Subs, &PrefixDigest{
+				Prefix:   prefix + hexDigits[i:i+1],
+				Accounts: d.accounts,
+				Digest:   d.sum(),
+			})
+		}
+	}
+	return sd, nil
+}
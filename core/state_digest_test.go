@@ -0,0 +1,89 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/common/trie"
+	"github.com/yeeco/gyee/persistent"
+)
+
+func digestTestTrie(t *testing.T, root common.Hash, storage persistent.Storage, prefix string) *StateDigest {
+	tr, err := trie.New(root, trie.NewDatabase(storage))
+	if err != nil {
+		t.Fatalf("trie.New() %v", err)
+	}
+	sd, err := digestTrie(tr, prefix)
+	if err != nil {
+		t.Fatalf("digestTrie(%q) %v", prefix, err)
+	}
+	return sd
+}
+
+func TestStateDigest(t *testing.T) {
+	storage := persistent.NewMemoryStorage()
+	root := newHealTestTrie(t, storage, 100)
+	sd := digestTestTrie(t, root, storage, "")
+	if sd.Accounts != 100 || len(sd.Subs) != 16 {
+		t.Fatalf("digestTrie() got %d accounts, %d subs", sd.Accounts, len(sd.Subs))
+	}
+	if again := digestTestTrie(t, root, storage, ""); again.Digest != sd.Digest {
+		t.Errorf("digestTrie() not deterministic")
+	}
+
+	// sub-digest equals digest of its prefix
+	for _, sub := range sd.Subs {
+		if sub.Accounts == 0 {
+			continue
+		}
+		sd2 := digestTestTrie(t, root, storage, sub.Prefix)
+		if sd2.Digest != sub.Digest || sd2.Accounts != sub.Accounts {
+			t.Errorf("digest of prefix %s got %d %x, want %d %x", sub.Prefix,
+				sd2.Accounts, sd2.Digest, sub.Accounts, sub.Digest)
+		}
+		break
+	}
+
+	// a different account changes the sub-digest of its prefix only
+	tr, _ := trie.New(root, trie.NewDatabase(storage))
+	key := common.BytesToHash([]byte(fmt.Sprintf("key%d", 7)))
+	tr.TryUpdate(key[:], []byte("diverged"))
+	sd2, err := digestTrie(tr, "")
+	if err != nil {
+		t.Fatalf("digestTrie() %v", err)
+	}
+	if sd2.Digest == sd.Digest {
+		t.Errorf("digest unchanged after account changed")
+	}
+	for i, sub := range sd2.Subs {
+		changed := sub.Digest != sd.Subs[i].Digest
+		if changed != (sub.Prefix[0] == fmt.Sprintf("%x", key[:1])[0]) {
+			t.Errorf("sub-digest of %s changed %v", sub.Prefix, changed)
+		}
+	}
+
+	if _, err := digestTrie(tr, "0g"); err != ErrStateDigestPrefix {
+		t.Errorf("digestTrie() of invalid prefix got %v", err)
+	}
+}
@@ -0,0 +1,179 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   存储压缩和磁盘占用：链数据库按key前缀分成几个keyspace（区块头、区块体、交易、状态、
+   索引、证据），用leveldb的SizeOf估算各自占用的磁盘空间，dht数据在p2p目录下单独的
+   数据库里，按目录大小统计。占用定期更新到metrics，也可以通过RPC查询，方便规划磁盘。
+   leveldb后台压缩不可控，可能在繁忙时造成延迟，可以配置定期压缩，或者通过RPC手动压缩
+   某个keyspace，同一时间只有一个压缩在进行。
+*/
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/persistent"
+)
+
+// interval to update metrics of disk usage
+const storageUsageInterval = 5 * time.Minute
+
+const (
+	KeyspaceAll     = "all"     // whole chain db
+	KeyspaceDht     = "dht"     // dht datastore and node db of p2p
+	KeyspaceAncient = "ancient" // cold store of old blocks
+)
+
+var (
+	ErrStorageNoCompact      = errors.New("core.storage: storage can't be compacted")
+	ErrStorageKeyspace       = errors.New("core.storage: unknown keyspace")
+	ErrStorageCompactRunning = errors.New("core.storage: compaction already running")
+)
+
+// keyspaces of chain db by key prefixes
+var storageKeyspaces = []struct {
+	name     string
+	prefixes []string
+}{
+	{"headers", []string{KeyPrefixHeader}},
+	{"bodies", []string{KeyPrefixBody}},
+	{"tx", []string{KeyPrefixTx}},
+	{"state", []string{KeyPrefixStateTrie}},
+	{"index", []string{KeyPrefixBlockNum2Hash, KeyPrefixBlockHash2Num, KeyPrefixAccountTx}},
+	{"evidence", []string{KeyPrefixEvidence}},
+}
+
+// KeyspaceUsage is estimated disk usage of a keyspace
+type KeyspaceUsage struct {
+	Name  string
+	Bytes uint64
+}
+
+// Estimate disk usage of keyspaces of chain db, the whole of it, the cold store
+// and dht
+func (c *Core) DiskUsage() ([]*KeyspaceUsage, error) {
+	compacter, ok := c.storage.(persistent.Compacter)
+	if !ok {
+		return nil, ErrStorageNoCompact
+	}
+	usages := make([]*KeyspaceUsage, 0, len(storageKeyspaces)+2)
+	for _, ks := range storageKeyspaces {
+		usage := &KeyspaceUsage{Name: ks.name}
+		for _, prefix := range ks.prefixes {
+			n, err := compacter.DiskUsage([]byte(prefix))
+			if err != nil {
+				return nil, err
+			}
+			usage.Bytes += n
+		}
+		usages = append(usages, usage)
+	}
+	n, err := compacter.DiskUsage(nil)
+	if err != nil {
+		return nil, err
+	}
+	usages = append(usages, &KeyspaceUsage{Name: KeyspaceAll, Bytes: n})
+	if c.freezer != nil {
+		n, err := dirSize(c.freezer.Path())
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, &KeyspaceUsage{Name: KeyspaceAncient, Bytes: n})
+	}
+	if c.config.P2p != nil && c.config.P2p.NodeDataDir != "" {
+		n, err := dirSize(c.config.P2p.NodeDataDir)
+		if err != nil {
+			return nil, err
+		}
+		usages = append(usages, &KeyspaceUsage{Name: KeyspaceDht, Bytes: n})
+	}
+	return usages, nil
+}
+
+// Compact keyspace of chain db in background, the whole of it if keyspace is
+// empty or KeyspaceAll
+func (c *Core) CompactStorage(keyspace string) error {
+	compacter, ok := c.storage.(persistent.Compacter)
+	if !ok || c.readOnly != nil {
+		return ErrStorageNoCompact
+	}
+	prefixes := []string{""}
+	if keyspace != "" && keyspace != KeyspaceAll {
+		prefixes = nil
+		for _, ks := range storageKeyspaces {
+			if ks.name == keyspace {
+				prefixes = ks.prefixes
+			}
+		}
+		if prefixes == nil {
+			return ErrStorageKeyspace
+		}
+	}
+	if !atomic.CompareAndSwapInt32(&c.compacting, 0, 1) {
+		return ErrStorageCompactRunning
+	}
+	go func() {
+		defer atomic.StoreInt32(&c.compacting, 0)
+		start := time.Now()
+		log.Info("chain db compaction started", "keyspace", keyspace)
+		for _, prefix := range prefixes {
+			if err := compacter.Compact([]byte(prefix)); err != nil {
+				log.Error("chain db compaction failed", "keyspace", keyspace, "err", err)
+				return
+			}
+		}
+		log.Info("chain db compaction done", "keyspace", keyspace, "elapsed", time.Since(start))
+	}()
+	return nil
+}
+
+func (c *Core) updateDiskUsage() {
+	usages, err := c.DiskUsage()
+	if err != nil {
+		log.Debug("failed to estimate disk usage", "err", err)
+		return
+	}
+	c.metrics.updateStorageUsage(usages)
+}
+
+// total size of files under dir, 0 if it doesn't exist
+func dirSize(dir string) (uint64, error) {
+	var size uint64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.IsDir() {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size, err
+}
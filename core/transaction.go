@@ -31,7 +31,7 @@ import (
 	"github.com/yeeco/gyee/common/address"
 	"github.com/yeeco/gyee/core/pb"
 	"github.com/yeeco/gyee/crypto"
-	sha3 "github.com/yeeco/gyee/crypto/hash"
+	"github.com/yeeco/gyee/crypto/provider"
 	"github.com/yeeco/gyee/log"
 	"github.com/yeeco/gyee/persistent"
 )
@@ -44,10 +44,17 @@ var (
 )
 
 type Transaction struct {
+	version   uint32
+	txType    TxType
 	chainID   uint32
 	nonce     uint64
 	to        *common.Address
 	amount    *big.Int
+	gasLimit  uint64
+	gasPrice  *big.Int
+	payload   []byte
+	notBefore uint64 // unix time in seconds, 0 for no limit
+	expiresAt uint64 // unix time in seconds, 0 for never
 	signature *crypto.Signature
 
 	// caches
@@ -60,10 +67,11 @@ type Transaction struct {
 
 func NewTransaction(chainID uint32, nonce uint64, recipient *common.Address, amount *big.Int) *Transaction {
 	tx := &Transaction{
-		chainID: chainID,
-		nonce:   nonce,
-		to:      recipient,
-		amount:  new(big.Int),
+		chainID:  chainID,
+		nonce:    nonce,
+		to:       recipient,
+		amount:   new(big.Int),
+		gasPrice: new(big.Int),
 	}
 	if amount != nil {
 		tx.amount.Set(amount)
@@ -71,6 +79,15 @@ func NewTransaction(chainID uint32, nonce uint64, recipient *common.Address, amo
 	return tx
 }
 
+// Set gas limit and price of tx, it must be done before signed
+func (t *Transaction) SetGas(limit uint64, price *big.Int) {
+	t.gasLimit = limit
+	t.gasPrice = new(big.Int)
+	if price != nil {
+		t.gasPrice.Set(price)
+	}
+}
+
 func NewTransactionFromProto(msg proto.Message) (*Transaction, error) {
 	tx := &Transaction{}
 	err := tx.FromProto(msg)
@@ -100,12 +117,116 @@ func (t *Transaction) Amount() *big.Int {
 	return t.amount
 }
 
+// Get gas limit of tx, DefaultTxGasLimit if not set
+func (t *Transaction) GasLimit() uint64 {
+	if t.gasLimit == 0 {
+		return DefaultTxGasLimit
+	}
+	return t.gasLimit
+}
+
+func (t *Transaction) GasPrice() *big.Int {
+	if t.gasPrice == nil {
+		return new(big.Int)
+	}
+	return t.gasPrice
+}
+
+// Set type of tx, it must be done before signed
+func (t *Transaction) SetType(txType TxType) {
+	t.txType = txType
+}
+
+func (t *Transaction) Type() TxType {
+	return t.txType
+}
+
+func (t *Transaction) Version() uint32 {
+	return t.version
+}
+
+// Set payload for contract or data, it must be done before signed
+func (t *Transaction) SetPayload(payload []byte) {
+	t.payload = common.CopyBytes(payload)
+}
+
+func (t *Transaction) Payload() []byte {
+	return t.payload
+}
+
+// Set validity window of tx in unix seconds, 0 for no limit. it must be done
+// before signed, the envelope version is raised for the window.
+func (t *Transaction) SetWindow(notBefore, expiresAt uint64) {
+	t.notBefore = notBefore
+	t.expiresAt = expiresAt
+	if (notBefore > 0 || expiresAt > 0) && t.version < TxVersionWindow {
+		t.version = TxVersionWindow
+	}
+}
+
+func (t *Transaction) NotBefore() uint64 {
+	return t.notBefore
+}
+
+func (t *Transaction) ExpiresAt() uint64 {
+	return t.expiresAt
+}
+
+// check if tx is within its validity window at unix time now in seconds
+func (t *Transaction) ValidAt(now uint64) error {
+	if now < t.notBefore {
+		return ErrTxNotValid
+	}
+	if t.expiresAt > 0 && now >= t.expiresAt {
+		return ErrTxExpired
+	}
+	return nil
+}
+
+// check if tx is executed by VM
+func (t *Transaction) IsContract() bool {
+	return t.txType == TxTypeContract
+}
+
+// Validate tx by its envelope version and type
+func (t *Transaction) Validate() error {
+	if t.version > TxVersion {
+		return ErrTxVersion
+	}
+	if t.notBefore > 0 || t.expiresAt > 0 {
+		if t.version < TxVersionWindow {
+			return ErrTxWindow
+		}
+		if t.expiresAt > 0 && t.notBefore >= t.expiresAt {
+			return ErrTxWindow
+		}
+	}
+	kind, ok := txKinds[t.txType]
+	if !ok {
+		return ErrTxType
+	}
+	return kind.validate(t)
+}
+
+// where amount of tx goes, nil if nowhere
+func (t *Transaction) transferTo() *common.Address {
+	switch t.txType {
+	case TxTypeStaking:
+		stake := StakeAddress(*t.from, *t.to)
+		return &stake
+	case TxTypeData:
+		return nil
+	default:
+		return t.to
+	}
+}
+
 func (t *Transaction) contentHash() (*common.Hash, error) {
 	encoded, err := t.encode(true)
 	if err != nil {
 		return nil, err
 	}
-	h := new(common.Hash).SetBytes(sha3.Sha3256(encoded))
+	h := new(common.Hash).SetBytes(provider.Current().Hash256(encoded))
 	return h, nil
 }
 
@@ -128,7 +249,7 @@ func (t *Transaction) Hash() *common.Hash {
 		if err != nil {
 			log.Crit("wrong tx hash")
 		}
-		t.hash = new(common.Hash).SetBytes(sha3.Sha3256(enc))
+		t.hash = new(common.Hash).SetBytes(provider.Current().Hash256(enc))
 	}
 	return t.hash
 }
@@ -177,8 +298,13 @@ func (t *Transaction) To() *common.Address {
 
 func (t *Transaction) ToProto() (*corepb.Transaction, error) {
 	pbTx := &corepb.Transaction{
-		ChainID: t.chainID,
-		Nonce:   t.nonce,
+		Version:   t.version,
+		Type:      uint32(t.txType),
+		ChainID:   t.chainID,
+		Nonce:     t.nonce,
+		GasLimit:  t.gasLimit,
+		NotBefore: t.notBefore,
+		ExpiresAt: t.expiresAt,
 	}
 	if t.to != nil {
 		pbTx.Recipient = common.CopyBytes(t.to[:])
@@ -186,6 +312,12 @@ func (t *Transaction) ToProto() (*corepb.Transaction, error) {
 	if t.amount != nil {
 		pbTx.Amount = t.amount.Bytes()
 	}
+	if t.gasPrice != nil && t.gasPrice.Sign() > 0 {
+		pbTx.GasPrice = t.gasPrice.Bytes()
+	}
+	if len(t.payload) > 0 {
+		pbTx.Payload = common.CopyBytes(t.payload)
+	}
 	if t.signature != nil {
 		pbTx.Signature = &corepb.Signature{
 			SigAlgorithm: uint32(t.signature.Algorithm),
@@ -203,7 +335,9 @@ func (t *Transaction) FromProto(msg proto.Message) error {
 	if pbt == nil {
 		return ErrInvalidProtoToTransaction
 	}
-	// copy value
+	// copy value, version and type checked by Validate()
+	t.version = pbt.Version
+	t.txType = TxType(pbt.Type)
 	t.chainID = pbt.ChainID
 	t.nonce = pbt.Nonce
 	if pbt.Recipient != nil {
@@ -214,6 +348,11 @@ func (t *Transaction) FromProto(msg proto.Message) error {
 	if pbt.Amount != nil {
 		t.amount.SetBytes(pbt.Amount)
 	}
+	t.gasLimit = pbt.GasLimit
+	t.gasPrice = new(big.Int).SetBytes(pbt.GasPrice)
+	t.payload = common.CopyBytes(pbt.Payload)
+	t.notBefore = pbt.NotBefore
+	t.expiresAt = pbt.ExpiresAt
 	if pbt.Signature != nil {
 		t.signature = &crypto.Signature{
 			Algorithm: crypto.Algorithm(pbt.Signature.SigAlgorithm),
@@ -240,17 +379,41 @@ func (t *Transaction) encode(withoutSig bool) ([]byte, error) {
 }
 
 func (t *Transaction) VerifySig() error {
-	if t.signature == nil {
-		return ErrNoSignature
-	}
-	sigFrom, err := t.sigFrom(true)
-	if err != nil {
-		return err
-	}
-	if t.from == nil {
-		t.from = sigFrom
-	} else {
-		if *t.from != *sigFrom {
+	return verifyTxSigs(Transactions{t})
+}
+
+// verify signatures of txs in a batch by the crypto provider, and set senders
+// of them recovered
+func verifyTxSigs(txs Transactions) error {
+	items := make([]*provider.RecoverItem, len(txs))
+	for i, t := range txs {
+		if t.signature == nil {
+			return ErrNoSignature
+		}
+		h, err := t.contentHash()
+		if err != nil {
+			return err
+		}
+		items[i] = &provider.RecoverItem{Data: h[:], Signature: t.signature}
+	}
+	provider.Current().RecoverBatch(items)
+	for i, t := range txs {
+		switch items[i].Err {
+		case nil:
+		case provider.ErrNoSigner:
+			return ErrNoSigner
+		case provider.ErrVerify:
+			return ErrSignatureMismatch
+		default:
+			return items[i].Err
+		}
+		addr, err := address.NewAddressFromPublicKey(items[i].PublicKey)
+		if err != nil {
+			return err
+		}
+		if t.from == nil {
+			t.from = addr.CommonAddress()
+		} else if *t.from != *addr.CommonAddress() {
 			return ErrTxFromMismatch
 		}
 	}
@@ -31,6 +31,7 @@ package core
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/yeeco/gyee/common"
 	"github.com/yeeco/gyee/log"
@@ -39,6 +40,12 @@ import (
 
 const TooFarTx = 8192
 
+const (
+	DefaultTxPoolTTL = 3 * time.Hour // duration txs kept in pool
+	txEvictInterval  = time.Minute   // interval txs evicted from pool
+	txClockDrift     = 15            // seconds txs admitted before their window starts
+)
+
 var (
 	ErrTxChainID = errors.New("transaction chainID mismatch")
 )
@@ -52,6 +59,9 @@ type TransactionPool struct {
 
 	// pending tx pool
 	pendingPool map[common.Hash]*Transaction
+	pendingTime map[common.Hash]time.Time // time txs added to pending pool
+	pendingLock sync.RWMutex              // pending txs read by block builder
+	ttl         time.Duration
 
 	lock   sync.RWMutex
 	quitCh chan struct{}
@@ -64,8 +74,13 @@ func NewTransactionPool(core *Core) (*TransactionPool, error) {
 		core:        core,
 		reqPool:     make(map[common.Hash]struct{}),
 		pendingPool: make(map[common.Hash]*Transaction),
+		pendingTime: make(map[common.Hash]time.Time),
+		ttl:         DefaultTxPoolTTL,
 		quitCh:      make(chan struct{}),
 	}
+	if ttl := core.config.Chain.TxPoolTTL; ttl > 0 {
+		bp.ttl = time.Duration(ttl) * time.Second
+	}
 	return bp, nil
 }
 
@@ -96,6 +111,9 @@ func (tp *TransactionPool) loop() {
 	tp.wg.Add(1)
 	defer tp.wg.Done()
 
+	evictTicker := time.NewTicker(txEvictInterval)
+	defer evictTicker.Stop()
+
 	for {
 		select {
 		case <-tp.quitCh:
@@ -104,6 +122,8 @@ func (tp *TransactionPool) loop() {
 		case msg := <-tp.subscriber.MsgChan:
 			//log.Info("tx pool receive ", msg.MsgType, " ", msg.From)
 			tp.processMsg(msg)
+		case now := <-evictTicker.C:
+			tp.evict(now)
 		}
 	}
 }
@@ -115,32 +135,41 @@ func (tp *TransactionPool) processMsg(msg p2p.Message) {
 		tp.core.metrics.p2pMsgRecvTx.Mark(1)
 		var tx = new(Transaction)
 		if err := tx.Decode(msg.Data); err != nil {
-			tp.markBadPeer(msg)
+			tp.markBadPeer(msg, err)
 			break
 		}
-		tp.processTx(tx)
+		tp.processTx(tx, msg.From)
 	default:
 		log.Crit("unhandled msg sent to txPool", "msg", msg)
 	}
 }
 
-func (tp *TransactionPool) processTx(tx *Transaction) {
+func (tp *TransactionPool) processTx(tx *Transaction, from string) {
 	// validate tx integrity
 	if err := tp.core.blockChain.verifyTx(tx); err != nil {
 		log.Warn("processTx() verify fails", "err", err, "tx", tx)
-		// TODO: mark bad peer?
+		tp.reportTx(tx, from, err)
 		return
 	}
 	if err := tx.VerifySig(); err != nil {
 		log.Warn("tx sig verify failed", "err", err)
-		// TODO: mark bad peer?
+		tp.reportTx(tx, from, err)
+		return
+	}
+	// txs out of validity window may be delayed only, not evidence
+	now := uint64(time.Now().Unix())
+	if tx.notBefore > now+txClockDrift || tx.ValidAt(now) == ErrTxExpired {
+		log.Debug("tx out of validity window", "tx", tx, "now", now)
 		return
 	}
 
 	// search in-mem request, if we are requesting for this tx
 	if _, ok := tp.reqPool[*tx.Hash()]; ok {
 		delete(tp.reqPool, *tx.Hash())
+		tp.pendingLock.Lock()
 		tp.pendingPool[*tx.Hash()] = tx
+		tp.pendingTime[*tx.Hash()] = time.Now()
+		tp.pendingLock.Unlock()
 
 		// TODO: check if block can be sealed
 		return
@@ -169,6 +198,8 @@ func (tp *TransactionPool) processTx(tx *Transaction) {
 		return
 	}
 
+	tp.core.blockChain.events.post(&Event{Kind: EventPendingTx, Tx: tx})
+
 	// put tx to DHT
 	// TODO:
 
@@ -178,6 +209,31 @@ func (tp *TransactionPool) processTx(tx *Transaction) {
 	}
 }
 
+// Get pending txs, for block builder
+func (tp *TransactionPool) Pending() Transactions {
+	tp.pendingLock.RLock()
+	defer tp.pendingLock.RUnlock()
+	txs := make(Transactions, 0, len(tp.pendingPool))
+	for _, tx := range tp.pendingPool {
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+// evict txs kept in pool longer than ttl, expired or sealed already
+func (tp *TransactionPool) evict(now time.Time) {
+	tp.pendingLock.Lock()
+	defer tp.pendingLock.Unlock()
+	for hash, tx := range tp.pendingPool {
+		if now.Sub(tp.pendingTime[hash]) < tp.ttl && tx.ValidAt(uint64(now.Unix())) != ErrTxExpired &&
+			!hasTransaction(tp.core.storage, hash) {
+			continue
+		}
+		delete(tp.pendingPool, hash)
+		delete(tp.pendingTime, hash)
+	}
+}
+
 func (tp *TransactionPool) TxBroadcast(tx *Transaction) error {
 	data, err := tx.Encode()
 	if err != nil {
@@ -199,6 +255,23 @@ func (tp *TransactionPool) TxBroadcast(tx *Transaction) error {
 	return nil
 }
 
-func (tp *TransactionPool) markBadPeer(msg p2p.Message) {
-	// TODO: inform bad peed msg.From to p2p module
+// record message can't be decoded as evidence of the peer sent it
+func (tp *TransactionPool) markBadPeer(msg p2p.Message, err error) {
+	tp.core.evidence.report(&Evidence{
+		Kind:   EvidenceBadEncoding,
+		Peer:   msg.From,
+		Reason: err.Error(),
+		Data:   msg.Data,
+	})
+}
+
+// record invalid tx as evidence of the peer sent it
+func (tp *TransactionPool) reportTx(tx *Transaction, from string, err error) {
+	tp.core.evidence.report(&Evidence{
+		Kind:   EvidenceBadTx,
+		Hash:   *tx.Hash(),
+		Peer:   from,
+		Reason: err.Error(),
+		Data:   tx.raw,
+	})
 }
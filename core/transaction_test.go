@@ -18,6 +18,7 @@
 package core
 
 import (
+	"bytes"
 	"encoding/hex"
 	"math/big"
 	"testing"
@@ -83,3 +84,113 @@ func TestTxEncode(t *testing.T) {
 		t.Errorf("tx encoded hex mismatch, got %v", hexStr)
 	}
 }
+
+func TestTxTypeEnvelope(t *testing.T) {
+	address := common.HexToAddress(txTestAddress)
+	tx := NewTransaction(255, 128, &address, big.NewInt(0))
+	tx.SetType(TxTypeData)
+	tx.SetPayload([]byte("anchor"))
+	if err := tx.Validate(); err != nil {
+		t.Errorf("data tx validate failed %v", err)
+	}
+	enc, err := tx.Encode()
+	if err != nil {
+		t.Fatalf("tx encode failed %v", err)
+	}
+	dec := new(Transaction)
+	if err := dec.Decode(enc); err != nil {
+		t.Fatalf("tx decode failed %v", err)
+	}
+	if dec.Type() != TxTypeData || string(dec.Payload()) != "anchor" {
+		t.Errorf("decoded tx mismatch, got type %v", dec.Type())
+	}
+
+	// txs of unknown type or newer version decoded, but not valid
+	pbTx, _ := tx.ToProto()
+	pbTx.Type = 100
+	pbTx.Version = TxVersion + 1
+	enc, _ = proto.Marshal(pbTx)
+	if err := dec.Decode(enc); err != nil {
+		t.Fatalf("tx of newer version decode failed %v", err)
+	}
+	if err := dec.Validate(); err != ErrTxVersion {
+		t.Errorf("tx of newer version validate got %v", err)
+	}
+	dec.version = TxVersion
+	if err := dec.Validate(); err != ErrTxType {
+		t.Errorf("tx of unknown type validate got %v", err)
+	}
+
+	tx.SetType(TxTypeTransfer)
+	if err := tx.Validate(); err != ErrTxPayload {
+		t.Errorf("transfer tx with payload validate got %v", err)
+	}
+}
+
+func TestTxWindow(t *testing.T) {
+	address := common.HexToAddress(txTestAddress)
+	tx := NewTransaction(255, 128, &address, big.NewInt(10000))
+	tx.SetWindow(100, 200)
+	if tx.Version() != TxVersionWindow {
+		t.Errorf("tx with window version %d", tx.Version())
+	}
+	if err := tx.Validate(); err != nil {
+		t.Errorf("tx with window validate failed %v", err)
+	}
+	enc, err := tx.Encode()
+	if err != nil {
+		t.Fatalf("tx encode failed %v", err)
+	}
+	dec := new(Transaction)
+	if err := dec.Decode(enc); err != nil {
+		t.Fatalf("tx decode failed %v", err)
+	}
+	if dec.NotBefore() != 100 || dec.ExpiresAt() != 200 {
+		t.Errorf("decoded tx window %d %d", dec.NotBefore(), dec.ExpiresAt())
+	}
+	for now, want := range map[uint64]error{99: ErrTxNotValid, 100: nil, 199: nil, 200: ErrTxExpired} {
+		if err := dec.ValidAt(now); err != want {
+			t.Errorf("ValidAt(%d) got %v, want %v", now, err, want)
+		}
+	}
+
+	// window of envelope before versioned, or empty
+	dec.version = 0
+	if err := dec.Validate(); err != ErrTxWindow {
+		t.Errorf("tx of version 0 with window validate got %v", err)
+	}
+	tx.SetWindow(200, 200)
+	if err := tx.Validate(); err != ErrTxWindow {
+		t.Errorf("tx of empty window validate got %v", err)
+	}
+	tx.SetWindow(0, 0)
+	if err := tx.ValidAt(1 << 40); err != nil {
+		t.Errorf("tx without window ValidAt() got %v", err)
+	}
+}
+
+func TestTxProof(t *testing.T) {
+	address := common.HexToAddress(txTestAddress)
+	txs := make(Transactions, 20)
+	for i := range txs {
+		txs[i] = NewTransaction(255, uint64(i), &address, big.NewInt(10000))
+		txs[i].raw, _ = txs[i].Encode()
+	}
+	root := DeriveHash(txs)
+	proof, err := DeriveProof(txs, 3, 17, 25)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []int{3, 17} {
+		enc, err := VerifyDerivedProof(root, index, proof)
+		if err != nil || !bytes.Equal(enc, txs.GetEncoded(index)) {
+			t.Errorf("VerifyDerivedProof(%d) got %x %v", index, enc, err)
+		}
+	}
+	if enc, err := VerifyDerivedProof(root, 25, proof); enc != nil || err != nil {
+		t.Errorf("VerifyDerivedProof() out of list got %x %v", enc, err)
+	}
+	if _, err := VerifyDerivedProof(root, 8, proof); err == nil {
+		t.Errorf("VerifyDerivedProof() not proved got no error")
+	}
+}
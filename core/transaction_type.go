@@ -0,0 +1,143 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package core
+
+/*
+   交易类型：交易信封带版本号和类型，类型决定交易的校验和执行方式：
+   1. 转账：原有交易都是这种类型，类型和版本都是0，编码不变
+   2. 质押：金额转入质押者对验证者（接收者）的质押账户
+   3. 数据：payload作为数据锚定在链上，不转账
+   4. 合约：交给VM执行
+   解码不检查版本和类型，老节点能解码新类型的交易，只是校验时拒绝，不会因为新增
+   交易类型解码失败。新增类型在txKinds中注册校验和执行函数即可。
+   版本1的交易可以带有效期（生效时间和过期时间，unix秒），不在有效期内的交易交易池不
+   接受，区块里也不允许有，老节点按版本拒绝这种交易。
+*/
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/core/state"
+	sha3 "github.com/yeeco/gyee/crypto/hash"
+)
+
+type TxType uint32
+
+const (
+	TxTypeTransfer TxType = iota // transfer, txs before typed
+	TxTypeStaking                // amount staked for validator recipient
+	TxTypeData                   // data anchored in payload
+	TxTypeContract               // contract call executed by VM
+)
+
+const (
+	TxVersion       uint32 = 1    // latest envelope version supported
+	TxVersionWindow uint32 = 1    // first version with validity window
+	MaxTxDataSize          = 4096 // max payload size of data txs
+	stakeAddrTag           = "stake"
+)
+
+var (
+	ErrTxVersion   = errors.New("core.tx: unsupported tx version")
+	ErrTxType      = errors.New("core.tx: unknown tx type")
+	ErrTxRecipient = errors.New("core.tx: invalid recipient for tx type")
+	ErrTxAmount    = errors.New("core.tx: invalid amount for tx type")
+	ErrTxPayload   = errors.New("core.tx: invalid payload for tx type")
+	ErrTxWindow    = errors.New("core.tx: invalid validity window")
+	ErrTxNotValid  = errors.New("core.tx: tx not valid yet")
+	ErrTxExpired   = errors.New("core.tx: tx expired")
+)
+
+// validation and execution of a tx type
+type txKind struct {
+	name     string
+	validate func(tx *Transaction) error
+	execute  func(bc *BlockChain, stateTrie state.AccountTrie, header *BlockHeader, tx *Transaction, gp *GasPool) (*Receipt, bool, error)
+}
+
+var txKinds = map[TxType]*txKind{
+	TxTypeTransfer: {"transfer", validateTransferTx, execPlainTx},
+	TxTypeStaking:  {"staking", validateStakingTx, execPlainTx},
+	TxTypeData:     {"data", validateDataTx, execPlainTx},
+	TxTypeContract: {"contract", validateContractTx, execContractTx},
+}
+
+func (t TxType) String() string {
+	if kind, ok := txKinds[t]; ok {
+		return kind.name
+	}
+	return fmt.Sprintf("unknown(%d)", uint32(t))
+}
+
+// Get address holding amount staked by staker for validator
+func StakeAddress(staker, validator common.Address) common.Address {
+	return common.BytesToAddress(sha3.Sha3256([]byte(stakeAddrTag), staker[:], validator[:]))
+}
+
+func validateTransferTx(tx *Transaction) error {
+	if tx.to == nil {
+		return ErrTxRecipient
+	}
+	if len(tx.payload) > 0 {
+		return ErrTxPayload
+	}
+	return nil
+}
+
+func validateStakingTx(tx *Transaction) error {
+	if tx.to == nil {
+		return ErrTxRecipient
+	}
+	if tx.amount.Sign() <= 0 {
+		return ErrTxAmount
+	}
+	if len(tx.payload) > 0 {
+		return ErrTxPayload
+	}
+	return nil
+}
+
+func validateDataTx(tx *Transaction) error {
+	if tx.amount.Sign() != 0 {
+		return ErrTxAmount
+	}
+	if len(tx.payload) == 0 || len(tx.payload) > MaxTxDataSize {
+		return ErrTxPayload
+	}
+	return nil
+}
+
+func validateContractTx(tx *Transaction) error {
+	if tx.to == nil {
+		return ErrTxRecipient
+	}
+	if len(tx.payload) == 0 {
+		return ErrTxPayload
+	}
+	return nil
+}
+
+// txs without VM involved are done by transfer
+func execPlainTx(bc *BlockChain, stateTrie state.AccountTrie, header *BlockHeader, tx *Transaction, gp *GasPool) (*Receipt, bool, error) {
+	return nil, applyTx(stateTrie, tx, gp), nil
+}
@@ -0,0 +1,36 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package yvm
+
+import (
+	"github.com/yeeco/gyee/core/state"
+)
+
+// NoopVM executes nothing, payload of txs is ignored and no gas used
+type NoopVM struct{}
+
+func NewNoopVM() YVM {
+	return &NoopVM{}
+}
+
+func (vm *NoopVM) Execute(ctx *Context, stateTrie state.AccountTrie, msg *Message) (*Result, error) {
+	return &Result{}, nil
+}
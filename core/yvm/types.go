@@ -20,5 +20,55 @@
 
 package yvm
 
+/*
+   执行层接口：合约交易（带payload的交易）由core交给YVM执行，WASM或EVM兼容的VM
+   实现这个接口即可接入。VM通过AccountTrie访问状态，返回消耗的gas和日志，core据此
+   生成收据。执行失败时VM不能修改状态。
+*/
+
+import (
+	"math/big"
+
+	"github.com/yeeco/gyee/common"
+	"github.com/yeeco/gyee/core/state"
+)
+
+// Context of block the tx executed in
+type Context struct {
+	ChainID uint32
+	Number  uint64
+	Time    uint64
+}
+
+// Message is the contract-bearing tx handed to VM, amount is transferred and
+// nonce increased by core before it's executed
+type Message struct {
+	TxHash   common.Hash
+	From     common.Address
+	To       common.Address
+	Nonce    uint64
+	Amount   *big.Int
+	Payload  []byte
+	GasLimit uint64 // gas left for VM after intrinsic gas of tx
+}
+
+// Log emitted by contract
+type Log struct {
+	Address common.Address
+	Topics  []common.Hash
+	Data    []byte
+}
+
+// Result of execution
+type Result struct {
+	GasUsed uint64 // gas used by VM, intrinsic gas not included
+	Return  []byte
+	Logs    []*Log
+	Failed  bool // state not changed if failed
+}
+
 type YVM interface {
+	// Execute message against state, an error returned only if the block can't
+	// be processed at all, a failed contract call is reported in result.
+	Execute(ctx *Context, stateTrie state.AccountTrie, msg *Message) (*Result, error)
 }
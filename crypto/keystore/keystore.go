@@ -36,6 +36,7 @@ import (
 	"github.com/yeeco/gyee/config"
 	"github.com/yeeco/gyee/crypto/keystore/cipher"
 	"github.com/yeeco/gyee/crypto/util"
+	"github.com/yeeco/gyee/utils/datadir"
 	"github.com/yeeco/gyee/utils/logging"
 )
 
@@ -68,7 +69,7 @@ type Keystore struct {
 
 func NewKeystoreWithConfig(config *config.Config) *Keystore {
 	//TODO: 用config里的keydir来拼
-	return NewKeystore(filepath.Join(config.NodeDir, "keystore"))
+	return NewKeystore(datadir.New(config.NodeDir).KeystoreDir())
 }
 
 func NewKeystore(dirPath string) *Keystore {
@@ -0,0 +1,89 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package provider
+
+import (
+	"hash"
+	"runtime"
+	"sync"
+
+	"github.com/yeeco/gyee/crypto"
+	sha3 "github.com/yeeco/gyee/crypto/hash"
+)
+
+// batches smaller than this recovered in the calling goroutine
+const fastBatchMin = 8
+
+// fast provider, reusing hasher states and recovering batches in parallel
+type fast struct {
+	hashers sync.Pool
+}
+
+func newFast() *fast {
+	return &fast{
+		hashers: sync.Pool{
+			New: func() interface{} { return sha3.NewHash256() },
+		},
+	}
+}
+
+func (f *fast) Name() string {
+	return NameFast
+}
+
+func (f *fast) Hash256(data ...[]byte) []byte {
+	hasher := f.hashers.Get().(hash.Hash)
+	hasher.Reset()
+	for _, b := range data {
+		hasher.Write(b)
+	}
+	sum := hasher.Sum(nil)
+	f.hashers.Put(hasher)
+	return sum
+}
+
+func (f *fast) Signer(algorithm crypto.Algorithm) crypto.Signer {
+	return newSigner(algorithm)
+}
+
+func (f *fast) RecoverBatch(items []*RecoverItem) {
+	workers := runtime.GOMAXPROCS(0)
+	if len(items) < fastBatchMin || workers < 2 {
+		for _, item := range items {
+			recoverItem(f, item)
+		}
+		return
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(items); i += workers {
+				recoverItem(f, items[i])
+			}
+		}(w)
+	}
+	wg.Wait()
+}
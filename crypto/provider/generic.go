@@ -0,0 +1,61 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package provider
+
+import (
+	"github.com/yeeco/gyee/crypto"
+	"github.com/yeeco/gyee/crypto/hash"
+	"github.com/yeeco/gyee/crypto/secp256k1"
+)
+
+// generic provider, hashing and recovering one by one
+type generic struct{}
+
+func newGeneric() *generic {
+	return &generic{}
+}
+
+func (g *generic) Name() string {
+	return NameGeneric
+}
+
+func (g *generic) Hash256(data ...[]byte) []byte {
+	return hash.Sha3256(data...)
+}
+
+func (g *generic) Signer(algorithm crypto.Algorithm) crypto.Signer {
+	return newSigner(algorithm)
+}
+
+func (g *generic) RecoverBatch(items []*RecoverItem) {
+	for _, item := range items {
+		recoverItem(g, item)
+	}
+}
+
+func newSigner(algorithm crypto.Algorithm) crypto.Signer {
+	switch algorithm {
+	case crypto.ALG_SECP256K1:
+		return secp256k1.NewSecp256k1Signer()
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,136 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package provider abstracts hashing and signing used on hot paths of block
+// and tx verification, so they can be served by accelerated implementations.
+package provider
+
+/*
+   密码算法提供者：区块、交易验证的热点路径上要反复计算哈希、恢复签名的公钥。这里把哈希
+   和签名抽象成Provider，进程内选用一个：generic是原来的逐个计算；fast复用sha3的状态
+   （x/crypto的keccak置换在amd64上是汇编实现），并把一批签名分给多个goroutine并行恢复
+   和验证（libsecp256k1的context验证时只读，可以并发使用）。以后有硬件加速的实现，注册
+   一个新的Provider即可。
+*/
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yeeco/gyee/crypto"
+)
+
+const (
+	NameGeneric = "generic" // one by one, the default
+	NameFast    = "fast"    // pooled hashers, signatures recovered in parallel
+)
+
+var (
+	ErrProviderUnknown = errors.New("crypto.provider: unknown provider")
+	ErrNoSigner        = errors.New("crypto.provider: signature algorithm not supported")
+	ErrVerify          = errors.New("crypto.provider: signature verification failed")
+)
+
+// Provider of hashing and signature algorithms
+type Provider interface {
+	Name() string
+
+	// SHA3-256 digest of data concatenated
+	Hash256(data ...[]byte) []byte
+
+	// Signer of algorithm, nil if not supported
+	Signer(algorithm crypto.Algorithm) crypto.Signer
+
+	// Recover public keys of signatures and verify them, results set in items
+	RecoverBatch(items []*RecoverItem)
+}
+
+// RecoverItem is a signature to recover public key of, in a batch
+type RecoverItem struct {
+	Data      []byte
+	Signature *crypto.Signature
+
+	PublicKey []byte // recovered
+	Err       error
+}
+
+var (
+	lock      sync.Mutex
+	providers = make(map[string]Provider)
+	current   atomic.Value // of selected
+)
+
+// selected provider, of the same type stored in atomic.Value
+type selected struct {
+	Provider
+}
+
+func init() {
+	Register(newGeneric())
+	Register(newFast())
+	current.Store(selected{providers[NameGeneric]})
+}
+
+// Register a provider to be selected by name
+func Register(p Provider) {
+	lock.Lock()
+	defer lock.Unlock()
+	providers[p.Name()] = p
+}
+
+// Select provider used by process, the default one if name is empty
+func Set(name string) error {
+	if name == "" {
+		name = NameGeneric
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	p, ok := providers[name]
+	if !ok {
+		return ErrProviderUnknown
+	}
+	current.Store(selected{p})
+	return nil
+}
+
+// Current provider used by process
+func Current() Provider {
+	return current.Load().(selected).Provider
+}
+
+// recover public key of item and verify signature by signer of provider
+func recoverItem(p Provider, item *RecoverItem) {
+	signer := p.Signer(item.Signature.Algorithm)
+	if signer == nil {
+		item.Err = ErrNoSigner
+		return
+	}
+	pubkey, err := signer.RecoverPublicKey(item.Data, item.Signature)
+	if err != nil {
+		item.Err = err
+		return
+	}
+	if !signer.Verify(pubkey, item.Data, item.Signature) {
+		item.Err = ErrVerify
+		return
+	}
+	item.PublicKey = pubkey
+}
@@ -0,0 +1,119 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package provider
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/yeeco/gyee/crypto"
+	"github.com/yeeco/gyee/crypto/hash"
+	"github.com/yeeco/gyee/crypto/secp256k1"
+)
+
+// items signed by different keys, the odd-th ones tampered if tamper
+func testRecoverItems(t testing.TB, n int, tamper bool) ([]*RecoverItem, [][]byte) {
+	items := make([]*RecoverItem, n)
+	pubkeys := make([][]byte, n)
+	for i := range items {
+		key := secp256k1.NewPrivateKey()
+		pubkeys[i], _ = secp256k1.GetPublicKey(key)
+		signer := secp256k1.NewSecp256k1Signer()
+		signer.InitSigner(key)
+		data := hash.Sha3256([]byte(fmt.Sprintf("tx %d", i)))
+		sig, err := signer.Sign(data)
+		if err != nil {
+			t.Fatalf("Sign() %v", err)
+		}
+		if tamper && i%2 == 1 {
+			data = hash.Sha3256(data)
+		}
+		items[i] = &RecoverItem{Data: data, Signature: sig}
+	}
+	return items, pubkeys
+}
+
+func TestProviders(t *testing.T) {
+	data := [][]byte{[]byte("header"), []byte("body")}
+	for _, name := range []string{NameGeneric, NameFast} {
+		if err := Set(name); err != nil {
+			t.Fatalf("Set(%s) %v", name, err)
+		}
+		p := Current()
+		if got := p.Hash256(data...); !bytes.Equal(got, hash.Sha3256(data...)) {
+			t.Errorf("%s Hash256() got %x", name, got)
+		}
+		if p.Signer(crypto.ALG_UNKNOWN) != nil {
+			t.Errorf("%s Signer() of unknown algorithm not nil", name)
+		}
+
+		items, pubkeys := testRecoverItems(t, 2*fastBatchMin, true)
+		items[0].Signature = &crypto.Signature{Algorithm: crypto.ALG_UNKNOWN}
+		p.RecoverBatch(items)
+		if items[0].Err != ErrNoSigner {
+			t.Errorf("%s RecoverBatch() unknown algorithm got %v", name, items[0].Err)
+		}
+		for i, item := range items[1:] {
+			i++
+			if i%2 == 1 {
+				// recovered to another key
+				if item.Err == nil && bytes.Equal(item.PublicKey, pubkeys[i]) {
+					t.Errorf("%s RecoverBatch() tampered %d recovered signer", name, i)
+				}
+			} else if item.Err != nil || !bytes.Equal(item.PublicKey, pubkeys[i]) {
+				t.Errorf("%s RecoverBatch() %d got %x %v", name, i, item.PublicKey, item.Err)
+			}
+		}
+	}
+	if err := Set("hsm"); err != ErrProviderUnknown {
+		t.Errorf("Set() unknown got %v", err)
+	}
+	Set("")
+	if Current().Name() != NameGeneric {
+		t.Errorf("default provider got %s", Current().Name())
+	}
+}
+
+func benchmarkHash256(b *testing.B, name string) {
+	p := providers[name]
+	header := make([]byte, 512)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Hash256(header)
+	}
+}
+
+func BenchmarkHash256Generic(b *testing.B) { benchmarkHash256(b, NameGeneric) }
+func BenchmarkHash256Fast(b *testing.B)    { benchmarkHash256(b, NameFast) }
+
+func benchmarkRecoverBatch(b *testing.B, name string) {
+	p := providers[name]
+	items, _ := testRecoverItems(b, 256, false)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.RecoverBatch(items)
+	}
+}
+
+func BenchmarkRecoverBatchGeneric(b *testing.B) { benchmarkRecoverBatch(b, NameGeneric) }
+func BenchmarkRecoverBatchFast(b *testing.B)    { benchmarkRecoverBatch(b, NameFast) }
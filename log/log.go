@@ -18,6 +18,7 @@
 package log
 
 import (
+	"github.com/sirupsen/logrus"
 	"github.com/yeeco/gyee/utils/logging"
 )
 
@@ -50,3 +51,13 @@ func Error(msg string, ctx ...interface{}) {
 func Crit(msg string, ctx ...interface{}) {
 	logging.Logger.Fatal(msg, ctx)
 }
+
+// SetLevel sets level of the root logger, shared by p2p, at runtime
+func SetLevel(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	logging.Logger.SetLevel(lvl)
+	return nil
+}
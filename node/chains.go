@@ -0,0 +1,105 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package node
+
+/*
+   一个节点进程可以承载多条链（测试链和主链，或者多个分片），配置在Config.Chains中。
+   每条链有自己的core和链数据目录，共用节点的账户。没有配置[chains.p2p]的链共用节点的
+   p2p服务和子网，p2p服务通过ChainMux为每条链提供一个视图，消息和dht数据按链隔离；
+   配置了[chains.p2p]的链有自己的p2p服务，即自己的端口、bootstrap节点和子网配置，
+   数据目录为节点目录下的p2p-<chain_id>。
+*/
+
+import (
+	"fmt"
+
+	"github.com/yeeco/gyee/config"
+	"github.com/yeeco/gyee/core"
+	"github.com/yeeco/gyee/p2p"
+)
+
+// chainNode is the node seen by the core of an extra chain
+type chainNode struct {
+	*Node
+	chainID uint32
+	core    *core.Core
+	p2p     p2p.Service
+	ownP2p  bool // p2p is the chain's own service, not a view of the node's
+}
+
+func newChainNode(node *Node, mux *p2p.ChainMux, chain *config.ChainConfig) (*chainNode, error) {
+	cn := &chainNode{
+		Node:    node,
+		chainID: chain.ChainID,
+	}
+	if chain.P2p == nil {
+		cn.p2p = mux.Chain(chain.ChainID)
+	} else {
+		svc, err := newChainP2p(node, chain)
+		if err != nil {
+			return nil, err
+		}
+		cn.p2p, cn.ownP2p = svc, true
+	}
+	c, err := core.NewCoreOfChain(cn, node.config, chain)
+	if err != nil {
+		return nil, err
+	}
+	cn.core = c
+	return cn, nil
+}
+
+func (cn *chainNode) Core() *core.Core {
+	return cn.core
+}
+
+func (cn *chainNode) P2pService() p2p.Service {
+	return cn.p2p
+}
+
+// p2p service of a chain on its own network, with the subnets configured
+// for the chain
+func newChainP2p(node *Node, chain *config.ChainConfig) (p2p.Service, error) {
+	p2pConf := *chain.P2p
+	if p2pConf.Name == "" {
+		p2pConf.Name = fmt.Sprintf("chain-%d", chain.ChainID)
+	}
+	if node.config.NodeDir != "" {
+		p2pConf.NodeDataDir = node.layout.P2pDirOf(chain.ChainID)
+	}
+	if p2pConf.EncryptDb {
+		p2pConf.DataKey = node.config.P2p.DataKey
+		if p2pConf.DataKey == nil {
+			key, err := deriveDataKey(node.config)
+			if err != nil {
+				return nil, err
+			}
+			p2pConf.DataKey = key
+		}
+	}
+	chain.P2p = &p2pConf
+
+	conf := *node.config
+	conf.P2p = &p2pConf
+	conf.Chain = chain
+	conf.Chains = nil
+	return p2p.NewOsnServiceWithCfg(&conf)
+}
@@ -15,14 +15,19 @@
 // You should have received a copy of the GNU General Public License
 // along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
 
+//go:build !windows
+// +build !windows
+
 package node
 
 import (
+	"context"
 	"net"
 	"os"
 	"path/filepath"
 )
 
+// ipc endpoints are unix sockets, readable and writable by the owner only
 func ipcListen(endpoint string) (net.Listener, error) {
 	if err := os.MkdirAll(filepath.Dir(endpoint), 0751); err != nil {
 		return nil, err
@@ -35,3 +40,9 @@ func ipcListen(endpoint string) (net.Listener, error) {
 	_ = os.Chmod(endpoint, 0600)
 	return l, nil
 }
+
+// DialIPC connects to ipc endpoint of a node, see config.IPCEndpoint
+func DialIPC(ctx context.Context, endpoint string) (net.Conn, error) {
+	d := net.Dialer{}
+	return d.DialContext(ctx, "unix", endpoint)
+}
@@ -0,0 +1,170 @@
+// Copyright (C) 2019 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// ipc endpoints are named pipes on windows, "\\.\pipe\<name>". the pipes are
+// opened for synchronous io, deadlines are not supported, and connections are
+// closed by disconnecting the pipes first, so blocked reads return.
+
+const (
+	pipeAccessDuplex   = 0x3
+	pipeTypeByte       = 0x0
+	pipeUnlimitedInsts = 255
+	pipeBufferSize     = 4096
+	pipeBusyRetry      = 50 * time.Millisecond // wait before dialing a busy pipe again
+
+	errPipeBusy      = syscall.Errno(231)
+	errPipeConnected = syscall.Errno(535)
+)
+
+var (
+	modKernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procCreateNamedPipe     = modKernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe    = modKernel32.NewProc("ConnectNamedPipe")
+	procDisconnectNamedPipe = modKernel32.NewProc("DisconnectNamedPipe")
+
+	errPipeClosed = errors.New("node: ipc pipe closed")
+)
+
+type pipeAddr string
+
+func (a pipeAddr) Network() string { return "pipe" }
+func (a pipeAddr) String() string  { return string(a) }
+
+// a pipe instance is created ahead, so clients always find one to connect
+type pipeListener struct {
+	path   string
+	lock   sync.Mutex
+	next   syscall.Handle // instance for next client, owned by Accept
+	closed bool
+}
+
+type pipeConn struct {
+	*os.File
+	addr   pipeAddr
+	server bool // server end, disconnected when closed
+}
+
+func ipcListen(endpoint string) (net.Listener, error) {
+	h, err := createNamedPipe(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &pipeListener{path: endpoint, next: h}, nil
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.lock.Lock()
+	h, closed := l.next, l.closed
+	l.lock.Unlock()
+	if closed {
+		syscall.CloseHandle(h)
+		return nil, errPipeClosed
+	}
+	if r, _, err := procConnectNamedPipe.Call(uintptr(h), 0); r == 0 && err != errPipeConnected {
+		return nil, err
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.closed {
+		// connected by Close to wake up
+		syscall.CloseHandle(h)
+		return nil, errPipeClosed
+	}
+	next, err := createNamedPipe(l.path)
+	if err != nil {
+		syscall.CloseHandle(h)
+		return nil, err
+	}
+	l.next = next
+	return &pipeConn{File: os.NewFile(uintptr(h), l.path), addr: pipeAddr(l.path), server: true}, nil
+}
+
+func (l *pipeListener) Close() error {
+	l.lock.Lock()
+	if l.closed {
+		l.lock.Unlock()
+		return nil
+	}
+	l.closed = true
+	l.lock.Unlock()
+	// wake up Accept blocked in connecting
+	if f, err := os.OpenFile(l.path, os.O_RDWR, 0); err == nil {
+		f.Close()
+	}
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr(l.path)
+}
+
+func (pc *pipeConn) Close() error {
+	if pc.server {
+		procDisconnectNamedPipe.Call(pc.Fd())
+	}
+	return pc.File.Close()
+}
+
+func (pc *pipeConn) LocalAddr() net.Addr                { return pc.addr }
+func (pc *pipeConn) RemoteAddr() net.Addr               { return pc.addr }
+func (pc *pipeConn) SetDeadline(t time.Time) error      { return nil }
+func (pc *pipeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (pc *pipeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+func createNamedPipe(path string) (syscall.Handle, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return syscall.InvalidHandle, err
+	}
+	r, _, err := procCreateNamedPipe.Call(uintptr(unsafe.Pointer(name)), pipeAccessDuplex, pipeTypeByte,
+		pipeUnlimitedInsts, pipeBufferSize, pipeBufferSize, 0, 0)
+	if syscall.Handle(r) == syscall.InvalidHandle {
+		return syscall.InvalidHandle, err
+	}
+	return syscall.Handle(r), nil
+}
+
+// DialIPC connects to ipc endpoint of a node, see config.IPCEndpoint
+func DialIPC(ctx context.Context, endpoint string) (net.Conn, error) {
+	for {
+		f, err := os.OpenFile(endpoint, os.O_RDWR, 0)
+		if err == nil {
+			return &pipeConn{File: f, addr: pipeAddr(endpoint)}, nil
+		}
+		if pe, ok := err.(*os.PathError); !ok || pe.Err != errPipeBusy {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pipeBusyRetry):
+		}
+	}
+}
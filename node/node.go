@@ -39,33 +39,59 @@ package node
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/gofrs/flock"
 	"github.com/yeeco/gyee/accounts"
 	"github.com/yeeco/gyee/config"
 	"github.com/yeeco/gyee/core"
+	"github.com/yeeco/gyee/crypto/provider"
 	"github.com/yeeco/gyee/log"
 	"github.com/yeeco/gyee/p2p"
+	p2pCfg "github.com/yeeco/gyee/p2p/config"
+	"github.com/yeeco/gyee/persistent"
 	"github.com/yeeco/gyee/rpc"
+	"github.com/yeeco/gyee/utils/datadir"
+	"github.com/yeeco/gyee/utils/logging"
+)
+
+// default max duration of a soft shutdown, see AppConfig.ShutdownTimeout
+const defaultShutdownTimeout = 30 * time.Second
+
+// log files kept under the logs dir of the node, rotated daily
+const logRotationCount = 7
+
+// the logger is shared by nodes in a process, files go to the first node dir
+var logFileOnce sync.Once
+
+var (
+	ErrShutdownTimeout = errors.New("node: shutdown timeout, forced")
+	ErrShutdownForced  = errors.New("node: shutdown forced by signal")
 )
 
 type Node struct {
 	name           string //for test purpose
 	config         *config.Config
+	layout         *datadir.Layout
 	core           *core.Core
 	accountManager *accounts.AccountManager
-	p2p            p2p.Service
+	p2p            p2p.Service // shared by all chains hosted but those of their own networks
+	chainP2p       p2p.Service // view of p2p for primary chain, nil if it's the only one
+	chains         []*chainNode
 	rpc            rpc.RPCServer
 
 	lock        sync.RWMutex
 	filelock    *flock.Flock
 	stop        chan struct{}
 	ipcEndpoint string
+	wsStarted   bool // websocket listeners started
 }
 
 func NewNode(conf *config.Config) (*Node, error) {
@@ -74,21 +100,42 @@ func NewNode(conf *config.Config) (*Node, error) {
 
 func NewNodeWithGenesis(conf *config.Config, genesis *core.Genesis, p2pSvc p2p.Service) (*Node, error) {
 	log.Info("Create new node")
+	cryptoProvider := provider.NameGeneric
+	if conf.App != nil {
+		cryptoProvider = conf.App.CryptoProvider
+	}
+	if err := provider.Set(cryptoProvider); err != nil {
+		return nil, err
+	}
 	if conf.NodeDir != "" {
 		absdatadir, err := filepath.Abs(conf.NodeDir)
 		if err != nil {
 			log.Crit("node: config path: ", err)
 		}
 		conf.NodeDir = absdatadir
-		conf.P2p.NodeDataDir = filepath.Join(absdatadir, "p2p")
 	}
 	err := os.MkdirAll(conf.NodeDir, 0755)
 	if err != nil {
 		return nil, err
 	}
 
+	layout, err := datadir.Open(conf.NodeDir)
+	if err != nil {
+		return nil, err
+	}
+	if err = layout.Check(); err != nil {
+		return nil, err
+	}
+	if conf.NodeDir != "" {
+		conf.P2p.NodeDataDir = layout.P2pDir()
+		logFileOnce.Do(func() {
+			logging.SetFileRotationHooker(layout.LogDir(), logRotationCount)
+		})
+	}
+
 	node := &Node{
 		config: conf,
+		layout: layout,
 	}
 
 	node.accountManager, err = accounts.NewAccountManager(conf)
@@ -96,18 +143,38 @@ func NewNodeWithGenesis(conf *config.Config, genesis *core.Genesis, p2pSvc p2p.S
 		log.Crit("node: accountMgr: ", err)
 	}
 
-	node.core, err = core.NewCoreWithGenesis(node, conf, genesis)
-	if err != nil {
-		log.Crit("node: core: ", err)
-	}
-
 	if p2pSvc == nil {
+		if conf.P2p.EncryptDb {
+			if conf.P2p.DataKey, err = deriveDataKey(conf); err != nil {
+				return nil, err
+			}
+		}
 		if p2pSvc, err = p2p.NewOsnServiceWithCfg(conf); err != nil {
 			log.Crit("node: p2p: ", err)
 		}
 	}
 	node.p2p = p2pSvc
 
+	if len(conf.Chains) > 0 {
+		if err = conf.CheckChains(); err != nil {
+			return nil, err
+		}
+		mux := p2p.NewChainMux(p2pSvc, conf.Chain.ChainID)
+		node.chainP2p = mux.Chain(conf.Chain.ChainID)
+		for _, cc := range conf.Chains {
+			cn, err := newChainNode(node, mux, cc)
+			if err != nil {
+				log.Crit("node: chain: ", cc.ChainID, err)
+			}
+			node.chains = append(node.chains, cn)
+		}
+	}
+
+	node.core, err = core.NewCoreWithGenesis(node, conf, genesis)
+	if err != nil {
+		log.Crit("node: core: ", err)
+	}
+
 	node.stop = make(chan struct{})
 	return node, nil
 }
@@ -117,9 +184,14 @@ func (n *Node) Start() (err error) {
 	defer n.lock.Unlock()
 	log.Info("Node Start...")
 
-	if err = n.lockDataDir(); err != nil {
-		log.Error("node: lockDataDir(): ", err)
-		return err
+	// a read-only node serves queries beside the node owning the dir, it
+	// has no peers nor ipc endpoint of its own
+	readOnly := n.config.Chain.ReadOnly
+	if !readOnly {
+		if err = n.lockDataDir(); err != nil {
+			log.Error("node: lockDataDir(): ", err)
+			return err
+		}
 	}
 
 	//依次启动p2p，rpc, ipc, blockchain, sync service, consensus
@@ -128,15 +200,39 @@ func (n *Node) Start() (err error) {
 	}
 	log.Info("Node Started")
 
-	if err = n.p2p.Start(); err != nil {
+	if !readOnly {
+		if err = n.p2p.Start(); err != nil {
+			return err
+		}
+		log.Info("p2p Started")
+	}
+
+	for _, cn := range n.chains {
+		if cn.ownP2p && !readOnly {
+			if err = cn.p2p.Start(); err != nil {
+				return err
+			}
+		}
+		if err = cn.core.Start(); err != nil {
+			return err
+		}
+		log.Info("Chain Started", cn.chainID)
+	}
+
+	if !readOnly {
+		if err = n.startIPC(); err != nil {
+			return err
+		}
+		log.Info("IPC Started")
+	}
+
+	if err = n.startRPC(); err != nil {
 		return err
 	}
-	log.Info("p2p Started")
 
-	if err = n.startIPC(); err != nil {
+	if err = n.startWS(); err != nil {
 		return err
 	}
-	log.Info("IPC Started")
 
 	return nil
 }
@@ -146,10 +242,27 @@ func (n *Node) Stop() error {
 	defer n.lock.Unlock()
 	log.Info("Node Stop...")
 
-	n.p2p.Stop()
+	// stop accepting requests first, then p2p and core, the core closes
+	// the chain db after the pools and chain flushed.
+	if n.rpc != nil {
+		n.rpc.Stop()
+		n.rpc = nil
+	}
+	if !n.config.Chain.ReadOnly {
+		n.p2p.Stop()
+		log.Info("p2p Stopped")
+	}
 	if err := n.core.Stop(); err != nil {
 		return err
 	}
+	for _, cn := range n.chains {
+		if cn.ownP2p && !n.config.Chain.ReadOnly {
+			cn.p2p.Stop()
+		}
+		if err := cn.core.Stop(); err != nil {
+			log.Error("node: chain: Stop(): ", cn.chainID, err)
+		}
+	}
 
 	if err := n.unlockDataDir(); err != nil {
 		log.Error("node: unlockDataDir():", err)
@@ -159,26 +272,63 @@ func (n *Node) Stop() error {
 	return nil
 }
 
-func (n *Node) WaitForShutdown() {
+// WaitForShutdown waits for SIGINT, SIGTERM or SIGQUIT and stops the node. the
+// stop is given AppConfig.ShutdownTimeout at most, and a second signal forces
+// it, in both cases an error is returned so the caller can exit with non-zero.
+func (n *Node) WaitForShutdown() error {
 	log.Info("Node Wait for shutdown...")
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer signal.Stop(sigc)
+
+	select {
+	case <-n.stop:
+		return nil
+	case sig := <-sigc:
+		log.Info("Got signal, shutting down...", sig)
+	}
+
+	done := make(chan error, 1)
 	go func() {
-		sigc := make(chan os.Signal, 1)
-		signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
-		defer signal.Stop(sigc)
-		<-sigc
+		done <- n.Stop()
+	}()
 
-		log.Info("Got interrupt, shutting down...")
-		if err := n.Stop(); err != nil {
+	tm := time.NewTimer(n.shutdownTimeout())
+	defer tm.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
 			log.Error("node: Stop(): ", err)
 		}
-	}()
+		return err
+	case <-tm.C:
+		log.Error("node: Stop(): ", ErrShutdownTimeout)
+		return ErrShutdownTimeout
+	case sig := <-sigc:
+		log.Error("node: Stop(): ", ErrShutdownForced, sig)
+		return ErrShutdownForced
+	}
+}
+
+func (n *Node) shutdownTimeout() time.Duration {
+	if n.config.App != nil && n.config.App.ShutdownTimeout > 0 {
+		return time.Duration(n.config.App.ShutdownTimeout) * time.Second
+	}
+	return defaultShutdownTimeout
+}
 
-	<-n.stop
-	return
+// key to encrypt values of node database and dht store at rest, derived from
+// the coinbase key in keystore
+func deriveDataKey(conf *config.Config) ([]byte, error) {
+	_, secret, err := core.LoadCoinbaseKey(conf)
+	if err != nil {
+		return nil, fmt.Errorf("node: encrypt_db: %v", err)
+	}
+	return persistent.DeriveStorageKey(secret)
 }
 
 func (n *Node) lockDataDir() error {
-	filelock := flock.New(filepath.Join(n.config.NodeDir, "LOCK"))
+	filelock := flock.New(n.layout.LockFile())
 	locked, err := filelock.TryLock()
 	if err != nil {
 		return err
@@ -210,7 +360,9 @@ func (n *Node) startIPC() error {
 		return err
 	}
 
-	n.rpc = rpc.NewServer(n.config, n)
+	if n.rpc, err = rpc.NewServer(n.config, n); err != nil {
+		return err
+	}
 
 	go func() {
 		if err := n.rpc.Serve(listener); err != nil {
@@ -221,9 +373,87 @@ func (n *Node) startIPC() error {
 	return nil
 }
 
-//get the node id of self
+func (n *Node) startWS() error {
+	if len(n.config.Rpc.WsListen) == 0 || n.wsStarted {
+		return nil
+	}
+	if err := n.newRPC(); err != nil {
+		return err
+	}
+	for _, addr := range n.config.Rpc.WsListen {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := n.rpc.ServeWS(listener); err != nil {
+				log.Error("WebSocket exited", "err", err)
+			}
+		}()
+		log.Info("WebSocket Started", "addr", addr)
+	}
+	n.wsStarted = true
+	return nil
+}
+
+// serve grpc on public listeners, limited by policy of public transports
+func (n *Node) startRPC() error {
+	if len(n.config.Rpc.RpcListen) == 0 {
+		return nil
+	}
+	if err := n.newRPC(); err != nil {
+		return err
+	}
+	for _, addr := range n.config.Rpc.RpcListen {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := n.rpc.ServePublic(listener); err != nil {
+				log.Error("RPC exited", "err", err)
+			}
+		}()
+		log.Info("RPC Started", "addr", addr)
+	}
+	return nil
+}
+
+// create rpc server if not created by startIPC
+func (n *Node) newRPC() (err error) {
+	if n.rpc == nil {
+		n.rpc, err = rpc.NewServer(n.config, n)
+	}
+	return err
+}
+
+// Start or stop a subsystem at runtime by admin rpc, only "ws" for websocket
+// listeners now
+func (n *Node) StartStop(subsystem string, start bool) error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	switch subsystem {
+	case "ws":
+		if start {
+			return n.startWS()
+		}
+		if n.rpc != nil {
+			n.rpc.StopWS()
+		}
+		n.wsStarted = false
+		return nil
+	}
+	return fmt.Errorf("node: unknown subsystem: %s", subsystem)
+}
+
+//get the node id of self, in canonical textual format
 func (n *Node) NodeID() string {
-	return "aaaa"
+	if ln, ok := n.p2p.(interface{ GetLocalNode() *p2pCfg.Node }); ok {
+		if local := ln.GetLocalNode(); local != nil {
+			return p2pCfg.P2pNodeId2String(local.ID)
+		}
+	}
+	return ""
 }
 
 func (n *Node) NodeName() string {
@@ -238,10 +468,30 @@ func (n *Node) AccountManager() *accounts.AccountManager {
 	return n.accountManager
 }
 
+func (n *Node) Layout() *datadir.Layout {
+	return n.layout
+}
+
 func (n *Node) Core() *core.Core {
 	return n.core
 }
 
 func (n *Node) P2pService() p2p.Service {
+	if n.chainP2p != nil {
+		return n.chainP2p
+	}
 	return n.p2p
 }
+
+// get core of a chain hosted by the node, nil if not found
+func (n *Node) ChainCore(chainID uint32) *core.Core {
+	if n.config.Chain != nil && n.config.Chain.ChainID == chainID {
+		return n.core
+	}
+	for _, cn := range n.chains {
+		if cn.chainID == chainID {
+			return cn.core
+		}
+	}
+	return nil
+}
@@ -0,0 +1,360 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package p2p
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Chain multiplexer: one p2p service (and so its schedulers) is shared by the
+// chains hosted in one process. each chain gets a view of the service by its
+// chain identity: messages broadcast by a view are tagged with the chain, and
+// only those with the same tag are delivered to subscribers of the view; dht
+// keys are hashed with the chain, and the kinds of chain info asked for are
+// prefixed with it. the primary chain is not tagged at all, so it keeps talking
+// with nodes hosting one chain only.
+//
+const (
+	chainTagMagic  = "\xc7chn"                   // magic of chain tag in message data
+	chainTagSize   = len(chainTagMagic) + 4      // magic and chain identity
+	chainKindPrefx = "chain/"                    // prefix of chain info kinds: "chain/<id>/<kind>"
+	chainSubChSize = 64                          // size of channel to subscribe from the shared service
+	chainSubIdTag  = "ChainMux: subscriber for " // identity of subscribers to the shared service
+)
+
+type ChainMux struct {
+	svc       Service                  // the shared service
+	primary   uint32                   // identity of the primary chain
+	lock      sync.Mutex               // lock to protect providers
+	providers map[uint32]ChainProvider // chain data providers by chain identity
+	regOnce   sync.Once                // to register the multiplexer as provider once
+}
+
+type chainView struct {
+	mux     *ChainMux                        // the multiplexer
+	chainId uint32                           // chain identity
+	tag     []byte                           // tag of message data, nil for primary
+	lock    sync.Mutex                       // lock to protect proxies
+	proxies map[*Subscriber]*chainSubscriber // proxies subscribed to shared service
+}
+
+type chainSubscriber struct {
+	sub   *Subscriber   // the subscriber to shared service
+	done  chan struct{} // closed when unregistered
+	owner *Subscriber   // subscriber of the view
+}
+
+func NewChainMux(svc Service, primary uint32) *ChainMux {
+	return &ChainMux{
+		svc:       svc,
+		primary:   primary,
+		providers: make(map[uint32]ChainProvider, 0),
+	}
+}
+
+//
+// Get view of the shared service for a chain, the view is not started or
+// stopped itself, the shared service is.
+//
+func (cm *ChainMux) Chain(chainId uint32) Service {
+	cv := &chainView{
+		mux:     cm,
+		chainId: chainId,
+		proxies: make(map[*Subscriber]*chainSubscriber, 0),
+	}
+	if chainId != cm.primary {
+		cv.tag = make([]byte, chainTagSize)
+		copy(cv.tag, chainTagMagic)
+		binary.BigEndian.PutUint32(cv.tag[len(chainTagMagic):], chainId)
+	}
+	return cv
+}
+
+//
+// Dispatch chain data requests from peers to provider of the chain asked for
+//
+func (cm *ChainMux) GetChainData(kind string, key []byte) []byte {
+	cp, kind := cm.provider(kind)
+	if cp == nil {
+		return nil
+	}
+	return cp.GetChainData(kind, key)
+}
+
+func (cm *ChainMux) GetChainDataFor(peer string, kind string, key []byte) []byte {
+	cp, kind := cm.provider(kind)
+	if cp == nil {
+		return nil
+	}
+	if cpp, ok := cp.(ChainPeerProvider); ok {
+		return cpp.GetChainDataFor(peer, kind, key)
+	}
+	return cp.GetChainData(kind, key)
+}
+
+//
+// Get provider of the chain a kind asked for, and the kind without prefix
+//
+func (cm *ChainMux) provider(kind string) (ChainProvider, string) {
+	chainId := cm.primary
+	if strings.HasPrefix(kind, chainKindPrefx) {
+		fields := strings.SplitN(kind[len(chainKindPrefx):], "/", 2)
+		if len(fields) != 2 {
+			return nil, kind
+		}
+		id, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			return nil, kind
+		}
+		chainId, kind = uint32(id), fields[1]
+	}
+	cm.lock.Lock()
+	defer cm.lock.Unlock()
+	return cm.providers[chainId], kind
+}
+
+func (cv *chainView) Start() error {
+	return nil
+}
+
+func (cv *chainView) Stop() {
+}
+
+func (cv *chainView) Reconfig(reCfg *RecfgCommand) error {
+	return cv.mux.svc.Reconfig(reCfg)
+}
+
+func (cv *chainView) BroadcastMessage(message Message) error {
+	return cv.mux.svc.BroadcastMessage(cv.tagged(message))
+}
+
+func (cv *chainView) BroadcastMessageOsn(message Message) error {
+	return cv.mux.svc.BroadcastMessageOsn(cv.tagged(message))
+}
+
+func (cv *chainView) Register(subscriber *Subscriber) {
+	cs := &chainSubscriber{
+		sub:   NewSubscriber(chainSubIdTag+fmt.Sprint(cv.chainId), make(chan Message, chainSubChSize), subscriber.MsgType),
+		done:  make(chan struct{}),
+		owner: subscriber,
+	}
+	cv.lock.Lock()
+	if _, dup := cv.proxies[subscriber]; dup {
+		cv.lock.Unlock()
+		return
+	}
+	cv.proxies[subscriber] = cs
+	cv.lock.Unlock()
+	cv.mux.svc.Register(cs.sub)
+	go cv.forward(cs)
+}
+
+func (cv *chainView) UnRegister(subscriber *Subscriber) {
+	cv.lock.Lock()
+	cs, ok := cv.proxies[subscriber]
+	delete(cv.proxies, subscriber)
+	cv.lock.Unlock()
+	if ok {
+		cv.mux.svc.UnRegister(cs.sub)
+		close(cs.done)
+	}
+}
+
+func (cv *chainView) DhtGetValue(key []byte) ([]byte, error) {
+	return cv.mux.svc.DhtGetValue(cv.dhtKey(key))
+}
+
+func (cv *chainView) DhtSetValue(key []byte, value []byte) error {
+	return cv.mux.svc.DhtSetValue(cv.dhtKey(key), value)
+}
+
+func (cv *chainView) RegChainProvider(cp ChainProvider) {
+	cv.mux.lock.Lock()
+	cv.mux.providers[cv.chainId] = cp
+	cv.mux.lock.Unlock()
+	cv.mux.regOnce.Do(func() {
+		cv.mux.svc.RegChainProvider(cv.mux)
+	})
+}
+
+func (cv *chainView) GetChainInfo(kind string, key []byte) ([]byte, error) {
+	if cv.tag != nil {
+		kind = fmt.Sprintf("%s%d/%s", chainKindPrefx, cv.chainId, kind)
+	}
+	return cv.mux.svc.GetChainInfo(kind, key)
+}
+
+func (cv *chainView) GetActivePeers() ([]string, error) {
+	if pcg, ok := cv.mux.svc.(PeerChainInfoGetter); ok {
+		return pcg.GetActivePeers()
+	}
+	return nil, fmt.Errorf("GetActivePeers: not supported by service")
+}
+
+func (cv *chainView) GetChainInfoFrom(peer string, kind string, key []byte) ([]byte, error) {
+	pcg, ok := cv.mux.svc.(PeerChainInfoGetter)
+	if !ok {
+		return nil, fmt.Errorf("GetChainInfoFrom: not supported by service")
+	}
+	if cv.tag != nil {
+		kind = fmt.Sprintf("%s%d/%s", chainKindPrefx, cv.chainId, kind)
+	}
+	return pcg.GetChainInfoFrom(peer, kind, key)
+}
+
+func (cv *chainView) GetPeerVersions() (map[string]int, error) {
+	if pvr, ok := cv.mux.svc.(PeerVersionReporter); ok {
+		return pvr.GetPeerVersions()
+	}
+	return nil, fmt.Errorf("GetPeerVersions: not supported by service")
+}
+
+func (cv *chainView) GetAcceptStats() (*AcceptStats, error) {
+	if asr, ok := cv.mux.svc.(AcceptStatsReporter); ok {
+		return asr.GetAcceptStats()
+	}
+	return nil, fmt.Errorf("GetAcceptStats: not supported by service")
+}
+
+func (cv *chainView) GetKillStats() ([]KillStat, error) {
+	if ksr, ok := cv.mux.svc.(KillStatsReporter); ok {
+		return ksr.GetKillStats()
+	}
+	return nil, fmt.Errorf("GetKillStats: not supported by service")
+}
+
+func (cv *chainView) Peers() ([]PeerSummary, error) {
+	if pl, ok := cv.mux.svc.(PeerLister); ok {
+		return pl.Peers()
+	}
+	return nil, fmt.Errorf("Peers: not supported by service")
+}
+
+// peers are shared by the chains, so is the management of them
+func (cv *chainView) peerAdmin() (PeerAdmin, error) {
+	if pa, ok := cv.mux.svc.(PeerAdmin); ok {
+		return pa, nil
+	}
+	return nil, fmt.Errorf("PeerAdmin: not supported by service")
+}
+
+func (cv *chainView) AddStatic(url string) error {
+	pa, err := cv.peerAdmin()
+	if err != nil {
+		return err
+	}
+	return pa.AddStatic(url)
+}
+
+func (cv *chainView) RemovePeer(id string) error {
+	pa, err := cv.peerAdmin()
+	if err != nil {
+		return err
+	}
+	return pa.RemovePeer(id)
+}
+
+func (cv *chainView) BanPeer(id string, duration time.Duration) error {
+	pa, err := cv.peerAdmin()
+	if err != nil {
+		return err
+	}
+	return pa.BanPeer(id, duration)
+}
+
+func (cv *chainView) UnbanPeer(id string) error {
+	pa, err := cv.peerAdmin()
+	if err != nil {
+		return err
+	}
+	return pa.UnbanPeer(id)
+}
+
+func (cv *chainView) GetNodeInfo() (*NodeInfo, error) {
+	pa, err := cv.peerAdmin()
+	if err != nil {
+		return nil, err
+	}
+	return pa.GetNodeInfo()
+}
+
+func (cv *chainView) Ping(id string, size int, count int) (*PingStats, error) {
+	pa, err := cv.peerAdmin()
+	if err != nil {
+		return nil, err
+	}
+	return pa.Ping(id, size, count)
+}
+
+func (cv *chainView) tagged(message Message) Message {
+	if cv.tag != nil {
+		data := make([]byte, 0, len(cv.tag)+len(message.Data))
+		data = append(data, cv.tag...)
+		message.Data = append(data, message.Data...)
+	}
+	return message
+}
+
+//
+// Get chain identity from message data, and the data without tag
+//
+func (cm *ChainMux) untagged(data []byte) (uint32, []byte) {
+	if len(data) < chainTagSize || !bytes.HasPrefix(data, []byte(chainTagMagic)) {
+		return cm.primary, data
+	}
+	return binary.BigEndian.Uint32(data[len(chainTagMagic):]), data[chainTagSize:]
+}
+
+func (cv *chainView) dhtKey(key []byte) []byte {
+	if cv.tag == nil {
+		return key
+	}
+	h := sha256.Sum256(append(append([]byte{}, cv.tag...), key...))
+	return h[:]
+}
+
+func (cv *chainView) forward(cs *chainSubscriber) {
+	for {
+		select {
+		case <-cs.done:
+			return
+		case msg := <-cs.sub.MsgChan:
+			chainId, data := cv.mux.untagged(msg.Data)
+			if chainId != cv.chainId {
+				continue
+			}
+			msg.Data = data
+			select {
+			case cs.owner.MsgChan <- msg:
+			case <-cs.done:
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,243 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package p2p
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// services connected to each other directly, messages are delivered at once
+type muxTestNet struct {
+	lock  sync.Mutex
+	nodes []*muxTestService
+	dht   map[string][]byte
+}
+
+type muxTestService struct {
+	net  *muxTestNet
+	lock sync.Mutex
+	subs map[*Subscriber]bool
+	cp   ChainProvider
+}
+
+func (mn *muxTestNet) service() *muxTestService {
+	ms := &muxTestService{net: mn, subs: make(map[*Subscriber]bool, 0)}
+	mn.lock.Lock()
+	mn.nodes = append(mn.nodes, ms)
+	mn.lock.Unlock()
+	return ms
+}
+
+func (ms *muxTestService) Start() error                       { return nil }
+func (ms *muxTestService) Stop()                              {}
+func (ms *muxTestService) Reconfig(reCfg *RecfgCommand) error { return nil }
+
+func (ms *muxTestService) BroadcastMessage(message Message) error {
+	ms.net.lock.Lock()
+	nodes := ms.net.nodes
+	ms.net.lock.Unlock()
+	for _, n := range nodes {
+		if n == ms {
+			continue
+		}
+		n.lock.Lock()
+		for sub := range n.subs {
+			if sub.MsgType == message.MsgType {
+				sub.MsgChan <- message
+			}
+		}
+		n.lock.Unlock()
+	}
+	return nil
+}
+
+func (ms *muxTestService) BroadcastMessageOsn(message Message) error {
+	return ms.BroadcastMessage(message)
+}
+
+func (ms *muxTestService) Register(subscriber *Subscriber) {
+	ms.lock.Lock()
+	ms.subs[subscriber] = true
+	ms.lock.Unlock()
+}
+
+func (ms *muxTestService) UnRegister(subscriber *Subscriber) {
+	ms.lock.Lock()
+	delete(ms.subs, subscriber)
+	ms.lock.Unlock()
+}
+
+func (ms *muxTestService) DhtGetValue(key []byte) ([]byte, error) {
+	ms.net.lock.Lock()
+	defer ms.net.lock.Unlock()
+	return ms.net.dht[string(key)], nil
+}
+
+func (ms *muxTestService) DhtSetValue(key []byte, value []byte) error {
+	ms.net.lock.Lock()
+	defer ms.net.lock.Unlock()
+	ms.net.dht[string(key)] = value
+	return nil
+}
+
+func (ms *muxTestService) RegChainProvider(cp ChainProvider) {
+	ms.cp = cp
+}
+
+func (ms *muxTestService) GetChainInfo(kind string, key []byte) ([]byte, error) {
+	ms.net.lock.Lock()
+	nodes := ms.net.nodes
+	ms.net.lock.Unlock()
+	for _, n := range nodes {
+		if n != ms && n.cp != nil {
+			if data := n.cp.GetChainData(kind, key); data != nil {
+				return data, nil
+			}
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+type muxTestProvider string
+
+func (mp muxTestProvider) GetChainData(kind string, key []byte) []byte {
+	return []byte(string(mp) + ":" + kind + ":" + string(key))
+}
+
+func muxTestSubscribe(svc Service) chan Message {
+	ch := make(chan Message, 8)
+	svc.Register(NewSubscriber(nil, ch, MessageTypeTx))
+	return ch
+}
+
+func muxTestExpect(t *testing.T, what string, ch chan Message, data []byte) {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		if !bytes.Equal(msg.Data, data) {
+			t.Errorf("%s: got %q, want %q", what, msg.Data, data)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("%s: nothing received", what)
+	}
+}
+
+func muxTestExpectNone(t *testing.T, what string, ch chan Message) {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		t.Errorf("%s: unexpected %q", what, msg.Data)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestChainMuxIsolation(t *testing.T) {
+	net := &muxTestNet{dht: make(map[string][]byte, 0)}
+	muxA, muxB := NewChainMux(net.service(), 1), NewChainMux(net.service(), 1)
+	a1, a2 := muxA.Chain(1), muxA.Chain(2)
+	b1, b2, b3 := muxB.Chain(1), muxB.Chain(2), muxB.Chain(3)
+	rx1, rx2, rx3 := muxTestSubscribe(b1), muxTestSubscribe(b2), muxTestSubscribe(b3)
+
+	// messages are delivered to the views of the same chain only, untagged
+	a2.BroadcastMessage(Message{MsgType: MessageTypeTx, Data: []byte("tx2")})
+	muxTestExpect(t, "chain 2", rx2, []byte("tx2"))
+	muxTestExpectNone(t, "chain 2 to chain 1", rx1)
+	muxTestExpectNone(t, "chain 2 to chain 3", rx3)
+
+	a1.BroadcastMessageOsn(Message{MsgType: MessageTypeTx, Data: []byte("tx1")})
+	muxTestExpect(t, "chain 1", rx1, []byte("tx1"))
+	muxTestExpectNone(t, "chain 1 to chain 2", rx2)
+
+	// subscribers unregistered get nothing more
+	sub := NewSubscriber(nil, make(chan Message, 1), MessageTypeTx)
+	b2.Register(sub)
+	b2.UnRegister(sub)
+	a2.BroadcastMessage(Message{MsgType: MessageTypeTx, Data: []byte("tx2")})
+	muxTestExpect(t, "chain 2 again", rx2, []byte("tx2"))
+	muxTestExpectNone(t, "unregistered", sub.MsgChan)
+
+	// dht keys of chains are apart, the primary's kept as they are
+	a1.DhtSetValue([]byte("k"), []byte("v1"))
+	a2.DhtSetValue([]byte("k"), []byte("v2"))
+	if v, _ := b1.DhtGetValue([]byte("k")); string(v) != "v1" {
+		t.Errorf("chain 1 dht got %q", v)
+	}
+	if v, _ := b2.DhtGetValue([]byte("k")); string(v) != "v2" {
+		t.Errorf("chain 2 dht got %q", v)
+	}
+	if v, _ := b3.DhtGetValue([]byte("k")); v != nil {
+		t.Errorf("chain 3 dht got %q", v)
+	}
+	if string(net.dht["k"]) != "v1" {
+		t.Errorf("primary dht key changed")
+	}
+
+	// chain info asked for is answered by the provider of the same chain
+	b1.RegChainProvider(muxTestProvider("b1"))
+	b2.RegChainProvider(muxTestProvider("b2"))
+	if v, err := a2.GetChainInfo("block", []byte("h")); err != nil || string(v) != "b2:block:h" {
+		t.Errorf("chain 2 info got %q, %v", v, err)
+	}
+	if v, err := a1.GetChainInfo("block", []byte("h")); err != nil || string(v) != "b1:block:h" {
+		t.Errorf("chain 1 info got %q, %v", v, err)
+	}
+	if _, err := muxA.Chain(3).GetChainInfo("block", []byte("h")); err == nil {
+		t.Errorf("chain 3 info answered without provider")
+	}
+}
+
+func TestChainMuxPrimaryInterop(t *testing.T) {
+	// a node hosting the primary chain only talks with the primary views
+	net := &muxTestNet{dht: make(map[string][]byte, 0)}
+	plain := net.service()
+	mux := NewChainMux(net.service(), 1)
+	v1, v2 := mux.Chain(1), mux.Chain(2)
+	rxPlain, rx1, rx2 := muxTestSubscribe(plain), muxTestSubscribe(v1), muxTestSubscribe(v2)
+
+	plain.BroadcastMessage(Message{MsgType: MessageTypeTx, Data: []byte("tx")})
+	muxTestExpect(t, "plain to primary", rx1, []byte("tx"))
+	muxTestExpectNone(t, "plain to chain 2", rx2)
+
+	v1.BroadcastMessage(Message{MsgType: MessageTypeTx, Data: []byte("tx1")})
+	muxTestExpect(t, "primary to plain", rxPlain, []byte("tx1"))
+
+	// the plain node sees messages of other chains tagged, and so never takes
+	// them as those of the primary chain
+	v2.BroadcastMessage(Message{MsgType: MessageTypeTx, Data: []byte("tx2")})
+	select {
+	case msg := <-rxPlain:
+		if !bytes.HasPrefix(msg.Data, []byte(chainTagMagic)) || !bytes.HasSuffix(msg.Data, []byte("tx2")) {
+			t.Errorf("chain 2 message got %q", msg.Data)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("chain 2 message not seen")
+	}
+
+	v1.RegChainProvider(muxTestProvider("primary"))
+	v2.RegChainProvider(muxTestProvider("chain2"))
+	if v, err := plain.GetChainInfo("block", []byte("h")); err != nil || string(v) != "primary:block:h" {
+		t.Errorf("plain info got %q, %v", v, err)
+	}
+}
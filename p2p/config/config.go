@@ -25,6 +25,7 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -41,6 +42,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/mr-tron/base58/base58"
+	"github.com/yeeco/gyee/common/hexutil"
 	p2plog "github.com/yeeco/gyee/p2p/logger"
 )
 
@@ -98,8 +101,10 @@ func (eno P2pCfgErrno) Error() string {
 
 // Some specific paths
 const (
-	KeyFileName     = "nodekey" // Path within the datadir to the node's private key
-	dirNodeDatabase = "nodes"   // Path within the datadir to store the nodes
+	KeyFileName        = "nodekey"         // Path within the datadir to the node's private key
+	dirNodeDatabase    = "nodes"           // Path within the datadir to store the nodes
+	BanListFileName    = "banlist.json"    // Path within the datadir to the bans of peers
+	KnownPeersFileName = "knownpeers.json" // Path within the datadir to the peers handshaked
 )
 
 // Bootstrap nodes, in a format like: node-identity-hex-string@ip:udp-port:tcp-port
@@ -147,6 +152,7 @@ var (
 // Node
 type Node struct {
 	IP       net.IP // ip address
+	AltIP    net.IP // ip address of the other family, nil if single stack
 	UDP, TCP uint16 // port numbers
 	ID       NodeID // the node's public key
 }
@@ -156,6 +162,25 @@ type Protocol struct {
 	Ver [4]byte // protocol version: M.m0.m1.m2
 }
 
+// Versions of the p2p internal protocol(Pid 0) advertised in handshakes. A
+// peer agreed on P2pProtoVerExt or higher understands messages added since,
+// see PeerInstance.p2pExtended; one agreed on P2pProtoVerAuth or higher must
+// sign its handshake with a nonce, see hsauth.go in package peer.
+var (
+	P2pProtoVerBase = [4]byte{0, 1, 0, 0} // handshake, pingpong, data packages
+	P2pProtoVerExt  = [4]byte{0, 2, 0, 0} // plus rx queue drop notices, MID_FRAME streams
+	P2pProtoVerAuth = [4]byte{0, 3, 0, 0} // plus handshakes signed with a nonce
+)
+
+// Default local protocol table
+func P2pDefaultProtocols() []Protocol {
+	return []Protocol{
+		{Pid: 0, Ver: P2pProtoVerBase},
+		{Pid: 0, Ver: P2pProtoVerExt},
+		{Pid: 0, Ver: P2pProtoVerAuth},
+	}
+}
+
 // Node static Configuration parameters
 const (
 	P2pNetworkTypeDynamic = 0 // neighbor discovering needed
@@ -194,10 +219,48 @@ type Config struct {
 	NodeDataDir        string                            // node data directory
 	NodeDatabase       string                            // node database
 	NoNdbHistory       bool                              // do not use history of nodes
+	DataKey            []byte                            // key to encrypt values of node database and dht store at rest, plain if empty
 	NoDial             bool                              // do not dial out flag
 	NoAccept           bool                              // do not accept incoming dial flag
 	BootstrapNode      bool                              // bootstrap node flag
+	SeedOnly           bool                              // bootstrap node accepts inbound but sheds peers later
+	SeedGraceTime      time.Duration                     // duration an inbound peer kept by a seed-only node
+	AdaptiveSlots      bool                              // shift inbound/outbound slots of dynamic sub networks
+	SlotOutboundMin    int                               // min outbound slots, in percent of inbound+outbound
+	SlotOutboundMax    int                               // max outbound slots, in percent of inbound+outbound
+	RxQueuePolicy      int                               // what to do when rx queue of a peer is full
+	RxBlockTimeout     time.Duration                     // max time blocked for RxqPolicyBlock
+	RxGrowMax          int                               // max packages pending for RxqPolicyGrow
+	IndQueuePolicy     int                               // what to do when the peer indication queue is full
+	IndBlockTimeout    time.Duration                     // max time blocked for IndqPolicyBlock
+	StreamMaxSize      int                               // max bytes of a large message streamed in frames
+	StreamTimeout      time.Duration                     // max time to receive all frames of a message
+	AcceptResume       int                               // accepter paused for inbounds full resumed at them in percent
+	AcceptMinPause     time.Duration                     // min duration the accepter paused for inbounds full
+	SelfProbeInterval  time.Duration                     // interval of the reachability self probe, 0 to disable
+	IpMaxInbounds      int                               // max inbound connections from a remote ip, 0 for unlimited
+	IpAcceptRate       int                               // max connections accepted from a remote ip per minute, 0 for unlimited
+	PeerTxRate         int                               // max tx bytes per second of a peer, 0 for unlimited
+	PeerRxRate         int                               // max rx bytes per second of a peer, 0 for unlimited
+	TotalTxRate        int                               // max tx bytes per second of all peers, 0 for unlimited
+	HsAddrCheck        int                               // check level of ip claimed in inbound handshake
+	HsAuthRequired     bool                              // handshakes not signed with a nonce refused, whatever version agreed
+	HsNonceWindow      time.Duration                     // max clock skew of the time in a handshake nonce
+	IpPreference       int                               // address family dialed first, see IpPrefer*
+	ClientVersion      string                            // client version announced in handshake
+	DhtDisabled        bool                              // dht not run, its port not announced in handshake
+	PeerTransport      PeerTransport                     // transport for peer connections, tcp if nil
+	WsPort             uint16                            // tcp port for websocket peer connections, 0 to disable
+	Encryption         int                               // encryption of tcp peer connections, see PeerEnc*
+	SubNetEncryption   map[SubNetworkID]int              // encryption by sub network, overriding Encryption
+	CompressDisabled   bool                              // user packages not compressed, even for peers supporting it
+	GossipFanout       int                               // peers a message is broadcast to in a dynamic sub network, 0 for all
+	SubNetGossipFanout map[SubNetworkID]int              // fanout by sub network, overriding GossipFanout, 0 for all
+	PeerProxy          *PeerProxy                        // socks5 proxy to dial peers through, nil if none
+	SubNetProxy        map[SubNetworkID]bool             // proxy enabled by sub network, all enabled if not listed
+	RandSeed           int64                             // seed for random sources of schedulers, 0 for seeding by time
 	Local              Node                              // local node struct
+	Advertise          Node                              // address announced to others, zero fields fallback to Local
 	CheckAddress       bool                              // check the neighbor reported address with the source ip
 	ProtoNum           uint32                            // local protocol number
 	Protocols          []Protocol                        // local protocol table
@@ -213,11 +276,14 @@ type Config struct {
 	// DHT application part
 	//
 
-	DhtLocal  Node                 // dht local node config
-	DhtRutCfg Cfg4DhtRouteManager  // for dht route manager
-	DhtQryCfg Cfg4DhtQryManager    // for dht query manager
-	DhtConCfg Cfg4DhtConManager    // for dht connection manager
-	DhtFdsCfg Cfg4DhtFileDatastore // for dht file data store
+	DhtLocal     Node                 // dht local node config
+	DhtAdvertise Node                 // dht address announced to others, zero fields fallback to DhtLocal
+	DhtRutCfg    Cfg4DhtRouteManager  // for dht route manager
+	DhtQryCfg    Cfg4DhtQryManager    // for dht query manager
+	DhtConCfg    Cfg4DhtConManager    // for dht connection manager
+	DhtFdsCfg    Cfg4DhtFileDatastore // for dht file data store
+	DhtAcls      []DhtAcl             // access policies of dht namespaces
+	DhtTransport PeerTransport        // transport for dht connections, tcp if nil
 
 	//
 	// NAT part
@@ -246,12 +312,34 @@ type Cfg4UdpNgbListener struct {
 	CheckAddr bool   // check reported address against the source ip
 }
 
+// Transport for peer connections: addresses are in "ip:port" form, and addresses
+// of connections and listeners returned should be *net.TCPAddr. it's for tests to
+// run peers without sockets mostly, see peer.NewMemNetwork. other transports, a
+// QUIC one for example, can be plugged here as long as a connection is a reliable
+// ordered stream; none but tcp is built in, nor is the transport negotiated in
+// handshake, so both ends must be configured with the same one.
+type PeerTransport interface {
+	Dial(addr string, timeout time.Duration) (net.Conn, error) // dial to address
+	Listen(addr string) (net.Listener, error)                  // listen on address
+}
+
+// SOCKS5 proxy to dial peers through, such as the one of tor. outbound peer
+// connections are made by it, inbound ones are not affected.
+type PeerProxy struct {
+	Addr     string // address of the proxy, "host:port"
+	User     string // user name, empty if no authentication
+	Password string // password of the user
+}
+
 // Configuration about peer listener on TCP
 type Cfg4PeerListener struct {
-	IP          net.IP // ip address
-	Port        uint16 // port numbers
-	ID          NodeID // the node's public key
-	MaxInBounds int    // max concurrency inbounds
+	IP          net.IP        // ip address
+	AltIP       net.IP        // ip address of the other family to listen on besides, nil if not
+	Port        uint16        // port numbers
+	ID          NodeID        // the node's public key
+	MaxInBounds int           // max concurrency inbounds
+	Transport   PeerTransport // transport to listen on, tcp if nil
+	WsPort      uint16        // tcp port for websocket, 0 to disable
 }
 
 // Configuration about peer manager
@@ -259,6 +347,7 @@ type Cfg4PeerManager struct {
 	CfgName            string                            // p2p configuration name
 	NetworkType        int                               // p2p network type
 	IP                 net.IP                            // ip address
+	AltIP              net.IP                            // ip address of the other family, nil if single stack
 	Port               uint16                            // tcp port number
 	UDP                uint16                            // udp port number, used with handshake procedure
 	ID                 NodeID                            // the node's public key
@@ -270,15 +359,51 @@ type Cfg4PeerManager struct {
 	SubNetMaxPeers     map[SubNetworkID]int              // max peers would be
 	SubNetMaxOutbounds map[SubNetworkID]int              // max concurrency outbounds
 	SubNetMaxInBounds  map[SubNetworkID]int              // max concurrency inbounds
+	SubNetEncryption   map[SubNetworkID]int              // encryption by sub network, overriding Encryption
+	CompressDisabled   bool                              // large user packages not compressed
+	Proxy              *PeerProxy                        // socks5 proxy to dial peers through, nil if none
+	SubNetProxy        map[SubNetworkID]bool             // proxy enabled by sub network, all enabled if not listed
+	AcceptResume       int                               // accepter paused for inbounds full resumed at them in percent
+	AcceptMinPause     time.Duration                     // min duration the accepter paused for inbounds full
+	SelfProbeInterval  time.Duration                     // interval of the reachability self probe, 0 to disable
+	IpMaxInbounds      int                               // max inbound connections from a remote ip, 0 for unlimited
+	IpAcceptRate       int                               // max connections accepted from a remote ip per minute, 0 for unlimited
 	SubNetKeyList      map[SubNetworkID]ecdsa.PrivateKey // keys for sub-node
 	SubNetNodeList     map[SubNetworkID]Node             // sub-node
 	SubNetIdList       []SubNetworkID                    // sub network identity list. do not put the identity
 	// of the local node in this list.
-	NoDial        bool       // do not dial outbound
-	NoAccept      bool       // do not accept inbound
-	BootstrapNode bool       // local is a bootstrap node
-	ProtoNum      uint32     // local protocol number
-	Protocols     []Protocol // local protocol table
+	NoDial         bool          // do not dial outbound
+	NoAccept       bool          // do not accept inbound
+	BootstrapNode  bool          // local is a bootstrap node
+	SeedOnly       bool          // shed peers after SeedGraceTime, bootstrap node only
+	SeedGraceTime  time.Duration // duration an activated peer is kept in seed-only mode
+	AdaptiveSlots  bool          // shift inbound/outbound slots of dynamic sub networks
+	SlotOutMin     int           // min outbound slots, in percent of inbound+outbound
+	SlotOutMax     int           // max outbound slots, in percent of inbound+outbound
+	RxqPolicy      int           // what to do when rx queue of a peer is full
+	RxBlockTime    time.Duration // max time blocked for RxqPolicyBlock
+	RxGrowMax      int           // max packages pending for RxqPolicyGrow
+	IndqPolicy     int           // what to do when the indication queue is full
+	IndBlockTime   time.Duration // max time blocked for IndqPolicyBlock
+	StreamMaxSize  int           // max bytes of a large message streamed in frames
+	StreamTimeout  time.Duration // max time to receive all frames of a message
+	TxRate         int           // max tx bytes per second of a peer, 0 for unlimited
+	RxRate         int           // max rx bytes per second of a peer, 0 for unlimited
+	TxRateTotal    int           // max tx bytes per second of all peers, 0 for unlimited
+	HsAddrCheck    int           // check level of ip claimed in inbound handshake
+	HsAuthRequired bool          // handshakes not signed with a nonce refused, whatever version agreed
+	HsNonceWindow  time.Duration // max clock skew of the time in a handshake nonce
+	IpPrefer       int           // address family dialed first, see IpPrefer*
+	ClientVersion  string        // client version announced in handshake
+	DhtPort        uint16        // dht tcp port announced in handshake, 0 if dht not run
+	Transport      PeerTransport // transport to dial with, tcp if nil
+	WsPort         uint16        // tcp port for websocket announced in handshake, 0 to disable
+	Encryption     int           // encryption of tcp peer connections, see PeerEnc*
+	Advertised     bool          // address advertised by configuration, not switched to nat one
+	ProtoNum       uint32        // local protocol number
+	Protocols      []Protocol    // local protocol table
+	BanList        string        // file bans of peers persisted to, not persisted if empty
+	KnownPeers     string        // file peers handshaked persisted to, not persisted if empty
 }
 
 // Configuration about table manager
@@ -290,11 +415,13 @@ type Cfg4TabManager struct {
 	Name           string                // node name
 	NodeDB         string                // node database
 	NoHistory      bool                  // do not use history of nodes
+	DataKey        []byte                // key to encrypt values at rest, plain if empty
 	BootstrapNode  bool                  // bootstrap node flag
 	SnidMaskBits   int                   // mask bits for subnet identity
 	SubNetNodeList map[SubNetworkID]Node // sub network node identities
 	SubNetIdList   []SubNetworkID        // sub network identity list. do not put the identity
 	// of the local node in this list.
+	Advertised bool // address advertised by configuration, not switched to nat one
 }
 
 // Configuration about protocols supported
@@ -309,22 +436,61 @@ type Cfg4DhtRouteManager struct {
 	NodeId        NodeID        // local node identity
 	RandomQryNum  int           // times to try query for a random peer identity
 	Period        time.Duration // timer period to fire a bootstrap
+	BucketSize    int           // max peers held in a bucket
+	MaxLatency    time.Duration // max latency in metric
 }
 
 // Configuration about dht query manager
 type Cfg4DhtQryManager struct {
-	Local          *Node         // pointer to local node specification
-	MaxPendings    int           // max pendings can be held in the list
-	MaxActInsts    int           // max concurrent actived instances for one query
-	QryExpired     time.Duration // duration to get expired for a query
-	QryInstExpired time.Duration // duration to get expired for a query instance
+	Local          *Node            // pointer to local node specification
+	MaxPendings    int              // max pendings can be held in the list
+	MaxActInsts    int              // max concurrent actived instances for one query
+	QryExpired     time.Duration    // duration to get expired for a query
+	QryInstExpired time.Duration    // duration to get expired for a query instance
+	Advertised     bool             // address advertised by configuration, not switched to nat one
+	Vivaldi        bool             // prefer providers with low latency predicted by network coordinates
+	Replications   []DhtReplication // replication of puts by namespace, the longest prefix matched applied
+	QryMaxWidth    int              // max number of peers queried for a query
+	QryMaxDepth    int              // max depth for a query
+}
+
+// Tunables of dht queries and route table, zero fields for defaults
+type DhtTuning struct {
+	QryMaxWidth     int           // max number of peers queried for a query
+	QryMaxDepth     int           // max depth for a query
+	BucketSize      int           // max peers held in a bucket of route table
+	MaxLatency      time.Duration // max latency in metric of route table
+	BspRandomQryNum int           // random lookups for a bootstrap round
+	BspPeriod       time.Duration // period of bootstrap rounds
+}
+
+// Replication of puts for a namespace, which is the set of keys with the prefix
+type DhtReplication struct {
+	Prefix []byte // key prefix of the namespace, empty for any key
+	R      int    // number of peers a value should be put to
+	Q      int    // number of peers should acknowledge the storage, 0 for no verification
+}
+
+// Access policies of dht namespace
+const (
+	DhtAclOpen      = iota // any peer may write
+	DhtAclAllowlist        // only the writers listed may write
+	DhtAclLocalOnly        // only the local node may write, not published to peers
+)
+
+// Access policy for a namespace, which is the set of keys with the prefix
+type DhtAcl struct {
+	Prefix  []byte   // key prefix of the namespace, empty for any key
+	Policy  int      // access policy, DhtAclXXX
+	Writers []NodeID // writers allowed for DhtAclAllowlist
 }
 
 // Configuration about dht listener management
 type Cfg4DhtLsnManager struct {
-	IP      net.IP // ip address
-	PortTcp uint16 // port number for tcp
-	PortUdp uint16 // port number for udp
+	IP        net.IP        // ip address
+	PortTcp   uint16        // port number for tcp
+	PortUdp   uint16        // port number for udp
+	Transport PeerTransport // transport to listen on, tcp if nil
 }
 
 // Configuration about dht connection manager
@@ -334,6 +500,8 @@ type Cfg4DhtConManager struct {
 	MaxCon        int           // max number of connection
 	MinCon        int           // min number of connection
 	HsTimeout     time.Duration // handshake timeout duration
+	Advertised    bool          // address advertised by configuration, not switched to nat one
+	Transport     PeerTransport // transport to dial with, tcp if nil
 }
 
 // configuration about dht file data store
@@ -348,6 +516,7 @@ type Cfg4DhtFileDatastore struct {
 	ShardFuncName string // shard function name
 	PadLength     int    // padding length
 	Sync          bool   // sync file store flag
+	Key           []byte // key to encrypt values at rest, plain if empty
 }
 
 // Configuration about nat
@@ -380,6 +549,88 @@ const (
 	DftTcpPort = 30304
 	DftDhtPort = 40405
 	DftSnmBits = 0
+
+	DftSeedGraceTime = time.Second * 30 // default grace time of peers of a seed-only node
+	DftSlotOutMin    = 25               // default min outbound slots in percent
+	DftSlotOutMax    = 75               // default max outbound slots in percent
+
+	DftRxBlockTimeout = time.Second * 2 // default max time rx blocked for a full queue
+	DftRxGrowMax      = 2048            // default max rx packages pending beyond the queue
+
+	DftIndBlockTimeout = time.Second * 2 // default max time blocked for a full indication queue
+
+	DftStreamMaxSize = 1024 * 1024 * 64 // default max bytes of a message streamed
+	DftStreamTimeout = time.Second * 60 // default max time to receive a message streamed
+
+	DftAcceptResume   = 90              // default inbounds in percent the accepter paused resumed at
+	DftAcceptMinPause = time.Second * 2 // default min duration the accepter paused
+
+	DftSelfProbeInterval = time.Minute * 10 // default interval of the reachability self probe
+
+	DftGossipFanout = 16 // default peers a message is broadcast to in a dynamic sub network
+
+	DftIpMaxInbounds = 4  // default max inbound connections from a remote ip
+	DftIpAcceptRate  = 30 // default max connections accepted from a remote ip per minute
+
+	DftHsNonceWindow = time.Minute * 5 // default max clock skew of the time in a handshake nonce
+
+	DftDhtQryMaxWidth     = 64               // default max number of peers queried for a query
+	DftDhtQryMaxDepth     = 8                // default max depth for a query
+	DftDhtBucketSize      = 32               // default bucket size of route table
+	DftDhtMaxLatency      = time.Second * 60 // default max latency in metric of route table
+	DftDhtBspRandomQryNum = 1                // default random lookups for a bootstrap round
+	DftDhtBspPeriod       = time.Minute * 1  // default period of bootstrap rounds
+)
+
+// Bounds of dht tunables
+const (
+	dhtQryMaxWidthLimit = 1024
+	dhtQryMaxDepthLimit = 64
+	dhtBucketSizeMin    = 8 // not less than the nearest peers asked for a query
+	dhtBucketSizeLimit  = 256
+	dhtBspQryNumLimit   = 64
+	dhtBspPeriodMin     = time.Second
+)
+
+// Levels of checking the ip claimed in an inbound handshake against the
+// remote address of the connection
+const (
+	HsAddrCheckNone     = 0 // not checked
+	HsAddrCheckWarn     = 1 // mismatches logged only
+	HsAddrCheckOverride = 2 // the claimed ip overridden by the observed one
+	HsAddrCheckReject   = 3 // mismatches rejected
+)
+
+// Address family dialed first for a peer announcing both, the other one is
+// dialed after the happy eyeballs stagger, or at once if the first failed.
+const (
+	IpPreferAny = 0 // the address the peer announced as its primary one
+	IpPreferV4  = 1 // ipv4
+	IpPreferV6  = 2 // ipv6
+)
+
+// Encryption of tcp peer connections, negotiated in handshake: a connection is
+// encrypted with tls if both sides are not PeerEncNone, and refused if one side
+// is PeerEncRequired while the other is PeerEncNone.
+const (
+	PeerEncNone          = 0 // not encrypted
+	PeerEncOpportunistic = 1 // encrypted if the peer supports
+	PeerEncRequired      = 2 // peers not supporting are refused
+)
+
+// Policies applied when the rx queue of a peer instance is full
+const (
+	RxqPolicyDrop  = 0 // drop the newest package
+	RxqPolicyBlock = 1 // stop reading until queued or timeout, the tcp window then closed
+	RxqPolicyGrow  = 2 // keep the package pending, up to RxGrowMax packages
+)
+
+// Policies applied when the indication queue of the peer manager is full, see
+// GetInstIndChannel of package peer
+const (
+	IndqPolicyBlock      = 0 // block till queued or timeout, the newest dropped then
+	IndqPolicyDropOldest = 1 // drop the oldest indication queued
+	IndqPolicyDropNewest = 2 // drop the newest indication
 )
 
 var DefaultLocalNode = Node{
@@ -427,8 +678,8 @@ func P2pDefaultConfig(bsUrls []string) *Config {
 		BootstrapNode:      false,
 		Local:              DefaultLocalNode,
 		CheckAddress:       false,
-		ProtoNum:           1,
-		Protocols:          []Protocol{{Pid: 0, Ver: [4]byte{0, 1, 0, 0}}},
+		ProtoNum:           2,
+		Protocols:          P2pDefaultProtocols(),
 		SnidMaskBits:       0,
 		SubNetKeyList:      map[SubNetworkID]ecdsa.PrivateKey{},
 		SubNetNodeList:     map[SubNetworkID]Node{},
@@ -444,8 +695,10 @@ func P2pDefaultConfig(bsUrls []string) *Config {
 		DhtLocal: DefaultDhtLocalNode,
 		DhtRutCfg: Cfg4DhtRouteManager{
 			NodeId:       NodeID{0},
-			RandomQryNum: 1,
-			Period:       time.Minute * 1,
+			RandomQryNum: DftDhtBspRandomQryNum,
+			Period:       DftDhtBspPeriod,
+			BucketSize:   DftDhtBucketSize,
+			MaxLatency:   DftDhtMaxLatency,
 		},
 		DhtQryCfg: Cfg4DhtQryManager{
 			Local:          &DefaultDhtLocalNode,
@@ -453,6 +706,8 @@ func P2pDefaultConfig(bsUrls []string) *Config {
 			MaxActInsts:    8,
 			QryExpired:     time.Second * 60,
 			QryInstExpired: time.Second * 16,
+			QryMaxWidth:    DftDhtQryMaxWidth,
+			QryMaxDepth:    DftDhtQryMaxDepth,
 		},
 		DhtConCfg: Cfg4DhtConManager{
 			Local:     &DefaultDhtLocalNode,
@@ -508,8 +763,8 @@ func P2pDefaultBootstrapConfig(bsUrls []string) *Config {
 		NoAccept:           true,
 		BootstrapNode:      true,
 		Local:              DefaultLocalNode,
-		ProtoNum:           1,
-		Protocols:          []Protocol{{Pid: 0, Ver: [4]byte{0, 1, 0, 0}}},
+		ProtoNum:           2,
+		Protocols:          P2pDefaultProtocols(),
 		SnidMaskBits:       0,
 		SubNetKeyList:      map[SubNetworkID]ecdsa.PrivateKey{},
 		SubNetNodeList:     map[SubNetworkID]Node{},
@@ -524,8 +779,10 @@ func P2pDefaultBootstrapConfig(bsUrls []string) *Config {
 		DhtLocal: DefaultDhtLocalNode,
 		DhtRutCfg: Cfg4DhtRouteManager{
 			NodeId:       NodeID{0},
-			RandomQryNum: 1,
-			Period:       time.Minute * 1,
+			RandomQryNum: DftDhtBspRandomQryNum,
+			Period:       DftDhtBspPeriod,
+			BucketSize:   DftDhtBucketSize,
+			MaxLatency:   DftDhtMaxLatency,
 		},
 		DhtQryCfg: Cfg4DhtQryManager{
 			Local:          &DefaultDhtLocalNode,
@@ -533,6 +790,8 @@ func P2pDefaultBootstrapConfig(bsUrls []string) *Config {
 			MaxActInsts:    8,
 			QryExpired:     time.Second * 60,
 			QryInstExpired: time.Second * 16,
+			QryMaxWidth:    DftDhtQryMaxWidth,
+			QryMaxDepth:    DftDhtQryMaxDepth,
 		},
 		DhtConCfg: Cfg4DhtConManager{
 			MaxCon:    512,
@@ -650,7 +909,7 @@ func P2pSetConfig(name string, cfg *Config) (string, P2pCfgErrno) {
 		cfgLog.Debug("P2pSetConfig: invalid ip address")
 		return name, P2pCfgEnoNodeId
 	}
-	cfgLog.Debug("P2pSetConfig: local node identity: \n\t%s", P2pNodeId2HexString(cfg.Local.ID))
+	cfgLog.Debug("P2pSetConfig: local node identity: \n\t%s", P2pNodeId2String(cfg.Local.ID))
 
 	cfg.DhtLocal.ID = cfg.Local.ID
 	config[name] = cfg
@@ -675,30 +934,73 @@ func P2pSubNetId2HexString(id SubNetworkID) string {
 
 // Hex-string to node identity
 func P2pHexString2NodeId(hex string) *NodeID {
-	var nid = NodeID{byte(0)}
-	if len(hex) != NodeIDBytes*2 {
-		cfgLog.Debug("P2pHexString2NodeId: invalid length: %d", len(hex))
+	var nid NodeID
+	if err := hexutil.DecodeFixed(hex, nid[:]); err != nil {
+		cfgLog.Debug("P2pHexString2NodeId: invalid string: %s, %s", hex, err.Error())
 		return nil
 	}
-	for cidx, c := range hex {
-		if c >= '0' && c <= '9' {
-			c = c - '0'
-		} else if c >= 'a' && c <= 'f' {
-			c = c - 'a' + 10
-		} else if c >= 'A' && c <= 'F' {
-			c = c - 'A' + 10
-		} else {
-			cfgLog.Debug("P2pHexString2NodeId: invalid string: %s", hex)
-			return nil
-		}
-		bidx := cidx >> 1
-		if cidx&0x01 == 0 {
-			nid[bidx] = byte(c << 4)
-		} else {
-			nid[bidx] += byte(c)
+	return &nid
+}
+
+// Textual node identity: prefix + base58(id + checksum), where checksum is the
+// first 4 bytes of double sha256 of the id. Hex strings are still accepted
+// wherever a node identity string is parsed.
+const (
+	NodeIdTextPrefix   = "ynode"
+	nodeIdChecksumSize = 4
+)
+
+func nodeIdChecksum(id []byte) []byte {
+	h := sha256.Sum256(id)
+	h = sha256.Sum256(h[:])
+	return h[:nodeIdChecksumSize]
+}
+
+// Node identity to canonical textual format
+func P2pNodeId2String(id NodeID) string {
+	data := make([]byte, 0, NodeIDBytes+nodeIdChecksumSize)
+	data = append(data, id[:]...)
+	data = append(data, nodeIdChecksum(id[:])...)
+	return NodeIdTextPrefix + base58.Encode(data)
+}
+
+// Canonical textual format or hex string to node identity
+func P2pString2NodeId(str string) (*NodeID, error) {
+	str = strings.TrimSpace(str)
+	if !strings.HasPrefix(str, NodeIdTextPrefix) {
+		if nid := P2pHexString2NodeId(str); nid != nil {
+			return nid, nil
 		}
+		return nil, fmt.Errorf("invalid node identity: %s", str)
 	}
-	return &nid
+	data, err := base58.Decode(strings.TrimPrefix(str, NodeIdTextPrefix))
+	if err != nil {
+		return nil, fmt.Errorf("invalid node identity: %s, %s", str, err.Error())
+	}
+	if len(data) != NodeIDBytes+nodeIdChecksumSize {
+		return nil, fmt.Errorf("invalid node identity length: %d", len(data))
+	}
+	if !bytes.Equal(nodeIdChecksum(data[:NodeIDBytes]), data[NodeIDBytes:]) {
+		return nil, fmt.Errorf("node identity checksum mismatch: %s", str)
+	}
+	var nid NodeID
+	copy(nid[:], data[:NodeIDBytes])
+	return &nid, nil
+}
+
+// Node identity marshals as the canonical textual format, in JSON and config
+func (id NodeID) MarshalText() ([]byte, error) {
+	return []byte(P2pNodeId2String(id)), nil
+}
+
+// Canonical textual format or hex string to node identity
+func (id *NodeID) UnmarshalText(input []byte) error {
+	nid, err := P2pString2NodeId(string(input))
+	if err != nil {
+		return err
+	}
+	*id = *nid
+	return nil
 }
 
 // Get default data directory
@@ -852,6 +1154,51 @@ func p2pSetupLocalNodeId(cfg *Config) P2pCfgErrno {
 	return P2pCfgEnoNone
 }
 
+// Check if advertised address is configured
+func p2pIsAdvertised(cfg *Config) bool {
+	return len(cfg.Advertise.IP) != 0 || cfg.Advertise.UDP != 0 || cfg.Advertise.TCP != 0
+}
+
+func p2pIsDhtAdvertised(cfg *Config) bool {
+	return len(cfg.DhtAdvertise.IP) != 0 || cfg.DhtAdvertise.TCP != 0
+}
+
+// Node announced to others: the local one with advertised fields overlapped
+func p2pAdvertiseNode(cfg *Config) Node {
+	n := cfg.Local
+	if len(cfg.Advertise.IP) != 0 {
+		n.IP = cfg.Advertise.IP
+	}
+	if len(cfg.Advertise.AltIP) != 0 {
+		n.AltIP = cfg.Advertise.AltIP
+	}
+	if cfg.Advertise.UDP != 0 {
+		n.UDP = cfg.Advertise.UDP
+	}
+	if cfg.Advertise.TCP != 0 {
+		n.TCP = cfg.Advertise.TCP
+	}
+	return n
+}
+
+// Dht node announced to others, it's shared by the dht query and connection
+// managers as the DhtLocal does.
+func p2pDhtAdvertiseNode(cfg *Config) *Node {
+	if !p2pIsDhtAdvertised(cfg) {
+		return &cfg.DhtLocal
+	}
+	ip := cfg.DhtAdvertise.IP
+	tcp := cfg.DhtAdvertise.TCP
+	cfg.DhtAdvertise = cfg.DhtLocal
+	if len(ip) != 0 {
+		cfg.DhtAdvertise.IP = ip
+	}
+	if tcp != 0 {
+		cfg.DhtAdvertise.TCP = tcp
+	}
+	return &cfg.DhtAdvertise
+}
+
 // Trans node identity to public key
 func P2pNodeId2Pubkey(id []byte) *ecdsa.PublicKey {
 	data := make([]byte, 1+NodeIDBytes)
@@ -914,6 +1261,22 @@ func P2pSetLocalIpAddr(cfg *Config, ip string, udpp uint16, tcpp uint16) P2pCfgE
 	return P2pCfgEnoNone
 }
 
+// Set local ip address of the other family than the one set by P2pSetLocalIpAddr,
+// to listen on and be announced besides for dual stack, empty string to clear.
+func P2pSetLocalAltIpAddr(cfg *Config, ip string) P2pCfgErrno {
+	cfg.Local.AltIP = nil
+	if ip == "" {
+		return P2pCfgEnoNone
+	}
+	alt := net.ParseIP(ip)
+	if alt == nil || (alt.To4() == nil) == (cfg.Local.IP.To4() == nil) {
+		cfgLog.Debug("P2pSetLocalAltIpAddr: invalid ip: %s, local: %s", ip, cfg.Local.IP.String())
+		return P2pCfgEnoParameter
+	}
+	cfg.Local.AltIP = alt
+	return P2pCfgEnoNone
+}
+
 // Set local dht port
 func P2pSetLocalDhtPort(cfg *Config, port uint16) P2pCfgErrno {
 	cfg.DhtLocal.UDP = 0
@@ -929,6 +1292,40 @@ func P2pSetLocalDhtIpAddr(cfg *Config, ip string, port uint16) P2pCfgErrno {
 	return P2pCfgEnoNone
 }
 
+// Set address announced to others, the local one is still listened on.
+// zero ip or port means the local one.
+func P2pSetAdvertiseIpAddr(cfg *Config, ip string, udpp uint16, tcpp uint16) P2pCfgErrno {
+	cfg.Advertise.IP = nil
+	if ip != "" {
+		if cfg.Advertise.IP = net.ParseIP(ip); cfg.Advertise.IP == nil {
+			cfgLog.Debug("P2pSetAdvertiseIpAddr: invalid ip: %s", ip)
+			return P2pCfgEnoParameter
+		}
+	}
+	cfg.Advertise.UDP = udpp
+	cfg.Advertise.TCP = tcpp
+	return P2pCfgEnoNone
+}
+
+// Set address announced to others for dht application
+func P2pSetAdvertiseDhtIpAddr(cfg *Config, ip string, port uint16) P2pCfgErrno {
+	cfg.DhtAdvertise.IP = nil
+	if ip != "" {
+		if cfg.DhtAdvertise.IP = net.ParseIP(ip); cfg.DhtAdvertise.IP == nil {
+			cfgLog.Debug("P2pSetAdvertiseDhtIpAddr: invalid ip: %s", ip)
+			return P2pCfgEnoParameter
+		}
+	}
+	cfg.DhtAdvertise.UDP = 0
+	cfg.DhtAdvertise.TCP = port
+	return P2pCfgEnoNone
+}
+
+// Get node announced to others
+func P2pAdvertiseNode(cfg *Config) Node {
+	return p2pAdvertiseNode(cfg)
+}
+
 // Setup local node identity
 func P2pSetupLocalNodeId(cfg *Config) P2pCfgErrno {
 	return p2pSetupLocalNodeId(cfg)
@@ -958,9 +1355,9 @@ func P2pSetupBootstrapNodes(urls []string) []*Node {
 		strIp := strs[0]
 		strUdpPort := strs[1]
 		strTcpPort := strs[2]
-		pid := P2pHexString2NodeId(strNodeId)
-		if pid == nil {
-			cfgLog.Debug("P2pSetupBootstrapNodes: P2pHexString2NodeId failed, strNodeId: %s", strNodeId)
+		pid, err := P2pString2NodeId(strNodeId)
+		if err != nil {
+			cfgLog.Debug("P2pSetupBootstrapNodes: P2pString2NodeId failed, err: %s", err.Error())
 			return nil
 		}
 
@@ -986,130 +1383,331 @@ func P2pSetupBootstrapNodes(urls []string) []*Node {
 }
 
 // Get configuration of neighbor discovering manager
-func P2pConfig4UdpNgbManager(name string) *Cfg4UdpNgbManager {
+func (cfg *Config) Config4UdpNgbManager() *Cfg4UdpNgbManager {
+	adv := p2pAdvertiseNode(cfg)
 	return &Cfg4UdpNgbManager{
-		IP:             config[name].Local.IP,
-		UDP:            config[name].Local.UDP,
-		TCP:            config[name].Local.TCP,
-		ID:             config[name].Local.ID,
-		NetworkType:    config[name].NetworkType,
-		SubNetNodeList: config[name].SubNetNodeList,
-		SubNetIdList:   config[name].SubNetIdList,
+		IP:             adv.IP,
+		UDP:            adv.UDP,
+		TCP:            adv.TCP,
+		ID:             cfg.Local.ID,
+		NetworkType:    cfg.NetworkType,
+		SubNetNodeList: cfg.SubNetNodeList,
+		SubNetIdList:   cfg.SubNetIdList,
 	}
 }
 
 // Get configuration of neighbor discovering listener
-func P2pConfig4UdpNgbListener(name string) *Cfg4UdpNgbListener {
+func (cfg *Config) Config4UdpNgbListener() *Cfg4UdpNgbListener {
 	return &Cfg4UdpNgbListener{
-		IP:        config[name].Local.IP,
-		UDP:       config[name].Local.UDP,
-		TCP:       config[name].Local.TCP,
-		ID:        config[name].Local.ID,
-		CheckAddr: config[name].CheckAddress,
+		IP:        cfg.Local.IP,
+		UDP:       cfg.Local.UDP,
+		TCP:       cfg.Local.TCP,
+		ID:        cfg.Local.ID,
+		CheckAddr: cfg.CheckAddress,
 	}
 }
 
 // Get configuration of peer listener
-func P2pConfig4PeerListener(name string) *Cfg4PeerListener {
+func (cfg *Config) Config4PeerListener() *Cfg4PeerListener {
 	return &Cfg4PeerListener{
-		IP:   config[name].Local.IP,
-		Port: config[name].Local.TCP,
-		ID:   config[name].Local.ID,
+		IP:        cfg.Local.IP,
+		AltIP:     cfg.Local.AltIP,
+		Port:      cfg.Local.TCP,
+		ID:        cfg.Local.ID,
+		Transport: cfg.PeerTransport,
+		WsPort:    cfg.WsPort,
 	}
 }
 
 // Get configuration of peer manager
-func P2pConfig4PeerManager(name string) *Cfg4PeerManager {
+func (cfg *Config) Config4PeerManager() *Cfg4PeerManager {
+	adv := p2pAdvertiseNode(cfg)
 	return &Cfg4PeerManager{
-		CfgName:            name,
-		NetworkType:        config[name].NetworkType,
-		IP:                 config[name].Local.IP,
-		Port:               config[name].Local.TCP,
-		UDP:                config[name].Local.UDP,
-		ID:                 config[name].Local.ID,
-		StaticMaxPeers:     config[name].StaticMaxPeers,
-		StaticMaxOutbounds: config[name].StaticMaxOutbounds,
-		StaticMaxInBounds:  config[name].StaticMaxInbounds,
-		StaticNodes:        config[name].StaticNodes,
-		StaticNetId:        config[name].StaticNetId,
-		NoDial:             config[name].NoDial,
-		NoAccept:           config[name].NoAccept,
-		ProtoNum:           config[name].ProtoNum,
-		Protocols:          config[name].Protocols,
-		SubNetKeyList:      config[name].SubNetKeyList,
-		SubNetNodeList:     config[name].SubNetNodeList,
-		SubNetMaxPeers:     config[name].SubNetMaxPeers,
-		SubNetMaxOutbounds: config[name].SubNetMaxOutbounds,
-		SubNetMaxInBounds:  config[name].SubNetMaxInBounds,
-		SubNetIdList:       config[name].SubNetIdList,
+		CfgName:            cfg.CfgName,
+		NetworkType:        cfg.NetworkType,
+		IP:                 adv.IP,
+		AltIP:              adv.AltIP,
+		Port:               adv.TCP,
+		UDP:                adv.UDP,
+		ID:                 cfg.Local.ID,
+		StaticMaxPeers:     cfg.StaticMaxPeers,
+		StaticMaxOutbounds: cfg.StaticMaxOutbounds,
+		StaticMaxInBounds:  cfg.StaticMaxInbounds,
+		StaticNodes:        cfg.StaticNodes,
+		StaticNetId:        cfg.StaticNetId,
+		NoDial:             cfg.NoDial,
+		NoAccept:           cfg.NoAccept,
+		SeedOnly:           cfg.SeedOnly,
+		SeedGraceTime:      cfg.SeedGraceTime,
+		AdaptiveSlots:      cfg.AdaptiveSlots,
+		SlotOutMin:         cfg.SlotOutboundMin,
+		SlotOutMax:         cfg.SlotOutboundMax,
+		RxqPolicy:          cfg.RxQueuePolicy,
+		RxBlockTime:        cfg.RxBlockTimeout,
+		RxGrowMax:          cfg.RxGrowMax,
+		IndqPolicy:         cfg.IndQueuePolicy,
+		IndBlockTime:       cfg.IndBlockTimeout,
+		StreamMaxSize:      cfg.StreamMaxSize,
+		StreamTimeout:      cfg.StreamTimeout,
+		TxRate:             cfg.PeerTxRate,
+		RxRate:             cfg.PeerRxRate,
+		TxRateTotal:        cfg.TotalTxRate,
+		HsAddrCheck:        cfg.HsAddrCheck,
+		HsAuthRequired:     cfg.HsAuthRequired,
+		HsNonceWindow:      cfg.HsNonceWindow,
+		IpPrefer:           cfg.IpPreference,
+		ClientVersion:      cfg.ClientVersion,
+		DhtPort:            p2pDhtAnnouncePort(cfg),
+		Transport:          cfg.PeerTransport,
+		WsPort:             cfg.WsPort,
+		Encryption:         cfg.Encryption,
+		SubNetEncryption:   cfg.SubNetEncryption,
+		CompressDisabled:   cfg.CompressDisabled,
+		Proxy:              cfg.PeerProxy,
+		SubNetProxy:        cfg.SubNetProxy,
+		Advertised:         p2pIsAdvertised(cfg),
+		ProtoNum:           cfg.ProtoNum,
+		Protocols:          cfg.Protocols,
+		SubNetKeyList:      cfg.SubNetKeyList,
+		SubNetNodeList:     cfg.SubNetNodeList,
+		SubNetMaxPeers:     cfg.SubNetMaxPeers,
+		SubNetMaxOutbounds: cfg.SubNetMaxOutbounds,
+		SubNetMaxInBounds:  cfg.SubNetMaxInBounds,
+		AcceptResume:       cfg.AcceptResume,
+		AcceptMinPause:     cfg.AcceptMinPause,
+		SelfProbeInterval:  cfg.SelfProbeInterval,
+		IpMaxInbounds:      cfg.IpMaxInbounds,
+		IpAcceptRate:       cfg.IpAcceptRate,
+		SubNetIdList:       cfg.SubNetIdList,
+		BanList:            p2pBanListFile(cfg),
+		KnownPeers:         p2pKnownPeersFile(cfg),
+	}
+}
+
+// dht port announced to chain peers, so they can bootstrap dht from chain peers
+func p2pDhtAnnouncePort(cfg *Config) uint16 {
+	if cfg.DhtDisabled {
+		return 0
 	}
+	return p2pDhtAdvertiseNode(cfg).TCP
+}
+
+// ban list under the instance directory, not persisted without data directory
+func p2pBanListFile(cfg *Config) string {
+	if len(cfg.NodeDataDir) == 0 {
+		return ""
+	}
+	return filepath.Join(cfg.NodeDataDir, cfg.Name, BanListFileName)
+}
+
+// known peers under the instance directory, not persisted without data directory
+func p2pKnownPeersFile(cfg *Config) string {
+	if len(cfg.NodeDataDir) == 0 {
+		return ""
+	}
+	return filepath.Join(cfg.NodeDataDir, cfg.Name, KnownPeersFileName)
 }
 
 // Get configuration of table manager
-func P2pConfig4TabManager(name string) *Cfg4TabManager {
+func (cfg *Config) Config4TabManager() *Cfg4TabManager {
 	return &Cfg4TabManager{
-		Local:          config[name].Local,
-		BootstrapNodes: config[name].BootstrapNodes,
-		DataDir:        config[name].NodeDataDir,
-		Name:           config[name].Name,
-		NodeDB:         config[name].NodeDatabase,
-		NoHistory:      config[name].NoNdbHistory,
-		BootstrapNode:  config[name].BootstrapNode,
-		NetworkType:    config[name].NetworkType,
-		SnidMaskBits:   config[name].SnidMaskBits,
-		SubNetNodeList: config[name].SubNetNodeList,
-		SubNetIdList:   config[name].SubNetIdList,
+		Local:          p2pAdvertiseNode(cfg),
+		BootstrapNodes: cfg.BootstrapNodes,
+		DataDir:        cfg.NodeDataDir,
+		Name:           cfg.Name,
+		NodeDB:         cfg.NodeDatabase,
+		NoHistory:      cfg.NoNdbHistory,
+		DataKey:        cfg.DataKey,
+		BootstrapNode:  cfg.BootstrapNode,
+		NetworkType:    cfg.NetworkType,
+		SnidMaskBits:   cfg.SnidMaskBits,
+		SubNetNodeList: cfg.SubNetNodeList,
+		SubNetIdList:   cfg.SubNetIdList,
+		Advertised:     p2pIsAdvertised(cfg),
 	}
 }
 
 // Get protocols
-func P2pConfig4Protocols(name string) *Cfg4Protocols {
+func (cfg *Config) Config4Protocols() *Cfg4Protocols {
 	return &Cfg4Protocols{
-		ProtoNum:  config[name].ProtoNum,
-		Protocols: config[name].Protocols,
+		ProtoNum:  cfg.ProtoNum,
+		Protocols: cfg.Protocols,
+	}
+}
+
+// Get configuration for dht route manager
+func (cfg *Config) Config4DhtRouteManager() *Cfg4DhtRouteManager {
+	cfg.DhtRutCfg.NodeId = cfg.DhtLocal.ID
+	cfg.DhtRutCfg.BootstrapNode = cfg.BootstrapNode
+	return &cfg.DhtRutCfg
+}
+
+// Get configuration for dht query manager
+func (cfg *Config) Config4DhtQryManager() *Cfg4DhtQryManager {
+	cfg.DhtQryCfg.Local = p2pDhtAdvertiseNode(cfg)
+	cfg.DhtQryCfg.Advertised = p2pIsDhtAdvertised(cfg)
+	return &cfg.DhtQryCfg
+}
+
+// Fill zero fields of dht tunables with defaults and check them
+func P2pCheckDhtTuning(t *DhtTuning) error {
+	if t.QryMaxWidth == 0 {
+		t.QryMaxWidth = DftDhtQryMaxWidth
+	}
+	if t.QryMaxDepth == 0 {
+		t.QryMaxDepth = DftDhtQryMaxDepth
+	}
+	if t.BucketSize == 0 {
+		t.BucketSize = DftDhtBucketSize
+	}
+	if t.MaxLatency == 0 {
+		t.MaxLatency = DftDhtMaxLatency
+	}
+	if t.BspRandomQryNum == 0 {
+		t.BspRandomQryNum = DftDhtBspRandomQryNum
+	}
+	if t.BspPeriod == 0 {
+		t.BspPeriod = DftDhtBspPeriod
+	}
+	switch {
+	case t.QryMaxWidth < 1 || t.QryMaxWidth > dhtQryMaxWidthLimit:
+		return fmt.Errorf("dht query max width out of [1, %d]: %d", dhtQryMaxWidthLimit, t.QryMaxWidth)
+	case t.QryMaxDepth < 1 || t.QryMaxDepth > dhtQryMaxDepthLimit:
+		return fmt.Errorf("dht query max depth out of [1, %d]: %d", dhtQryMaxDepthLimit, t.QryMaxDepth)
+	case t.BucketSize < dhtBucketSizeMin || t.BucketSize > dhtBucketSizeLimit:
+		return fmt.Errorf("dht bucket size out of [%d, %d]: %d", dhtBucketSizeMin, dhtBucketSizeLimit, t.BucketSize)
+	case t.MaxLatency < 0:
+		return fmt.Errorf("dht max latency negative: %s", t.MaxLatency)
+	case t.BspRandomQryNum < 1 || t.BspRandomQryNum > dhtBspQryNumLimit:
+		return fmt.Errorf("dht bootstrap queries out of [1, %d]: %d", dhtBspQryNumLimit, t.BspRandomQryNum)
+	case t.BspPeriod < dhtBspPeriodMin:
+		return fmt.Errorf("dht bootstrap period less than %s: %s", dhtBspPeriodMin, t.BspPeriod)
+	}
+	return nil
+}
+
+// Set dht tunables to configuration, zero fields for defaults
+func P2pSetDhtTuning(cfg *Config, t DhtTuning) P2pCfgErrno {
+	if err := P2pCheckDhtTuning(&t); err != nil {
+		cfgLog.Debug("P2pSetDhtTuning: %s", err.Error())
+		return P2pCfgEnoParameter
+	}
+	cfg.DhtQryCfg.QryMaxWidth = t.QryMaxWidth
+	cfg.DhtQryCfg.QryMaxDepth = t.QryMaxDepth
+	cfg.DhtRutCfg.BucketSize = t.BucketSize
+	cfg.DhtRutCfg.MaxLatency = t.MaxLatency
+	cfg.DhtRutCfg.RandomQryNum = t.BspRandomQryNum
+	cfg.DhtRutCfg.Period = t.BspPeriod
+	return P2pCfgEnoNone
+}
+
+// Get access policies of dht namespaces
+func (cfg *Config) Config4DhtAcls() []DhtAcl {
+	return cfg.DhtAcls
+}
+
+// Get configuration for dht file data store
+func (cfg *Config) Config4DhtFileDatastore() *Cfg4DhtFileDatastore {
+	dir := cfg.DhtFdsCfg.Path
+	inst := cfg.Name
+	cfg.DhtFdsCfg.Path = filepath.Join(dir, inst)
+	cfg.DhtFdsCfg.Key = cfg.DataKey
+	return &cfg.DhtFdsCfg
+}
+
+// Get configuration for dht listener manager
+func (cfg *Config) Config4DhtLsnManager() *Cfg4DhtLsnManager {
+	return &Cfg4DhtLsnManager{
+		IP:        cfg.DhtLocal.IP,
+		PortTcp:   cfg.DhtLocal.TCP,
+		PortUdp:   cfg.DhtLocal.UDP,
+		Transport: cfg.DhtTransport,
 	}
 }
 
+// Get configuration for dht connection manager
+func (cfg *Config) Config4DhtConManager() *Cfg4DhtConManager {
+	cfg.DhtConCfg.Local = p2pDhtAdvertiseNode(cfg)
+	cfg.DhtConCfg.BootstrapNode = cfg.BootstrapNode
+	cfg.DhtConCfg.Advertised = p2pIsDhtAdvertised(cfg)
+	cfg.DhtConCfg.Transport = cfg.DhtTransport
+	return &cfg.DhtConCfg
+}
+
+// Get configuration for nat
+func (cfg *Config) Config4NatManager() *Cfg4NatManager {
+	return &cfg.NatCfg
+}
+
+//
+// Followings get configurations by name from the global registry, user tasks
+// should better get them from the configuration injected by their scheduler,
+// see function SchGetTaskConfig in scheduler pls.
+//
+
+// Get configuration of neighbor discovering manager
+func P2pConfig4UdpNgbManager(name string) *Cfg4UdpNgbManager {
+	return config[name].Config4UdpNgbManager()
+}
+
+// Get configuration of neighbor discovering listener
+func P2pConfig4UdpNgbListener(name string) *Cfg4UdpNgbListener {
+	return config[name].Config4UdpNgbListener()
+}
+
+// Get configuration of peer listener
+func P2pConfig4PeerListener(name string) *Cfg4PeerListener {
+	return config[name].Config4PeerListener()
+}
+
+// Get configuration of peer manager
+func P2pConfig4PeerManager(name string) *Cfg4PeerManager {
+	return config[name].Config4PeerManager()
+}
+
+// Get configuration of table manager
+func P2pConfig4TabManager(name string) *Cfg4TabManager {
+	return config[name].Config4TabManager()
+}
+
+// Get protocols
+func P2pConfig4Protocols(name string) *Cfg4Protocols {
+	return config[name].Config4Protocols()
+}
+
 // Get configuration for dht route manager
 func P2pConfig4DhtRouteManager(name string) *Cfg4DhtRouteManager {
-	config[name].DhtRutCfg.NodeId = config[name].DhtLocal.ID
-	config[name].DhtRutCfg.BootstrapNode = config[name].BootstrapNode
-	return &config[name].DhtRutCfg
+	return config[name].Config4DhtRouteManager()
 }
 
 // Get configuration for dht query manager
 func P2pConfig4DhtQryManager(name string) *Cfg4DhtQryManager {
-	config[name].DhtQryCfg.Local = &config[name].DhtLocal
-	return &config[name].DhtQryCfg
+	return config[name].Config4DhtQryManager()
+}
+
+// Get access policies of dht namespaces
+func P2pConfig4DhtAcls(name string) []DhtAcl {
+	return config[name].Config4DhtAcls()
 }
 
 // Get configuration for dht file data store
 func P2pConfig4DhtFileDatastore(name string) *Cfg4DhtFileDatastore {
-	dir := config[name].DhtFdsCfg.Path
-	inst := config[name].Name
-	config[name].DhtFdsCfg.Path = filepath.Join(dir, inst)
-	return &config[name].DhtFdsCfg
+	return config[name].Config4DhtFileDatastore()
 }
 
 // Get configuration for dht listener manager
 func P2pConfig4DhtLsnManager(name string) *Cfg4DhtLsnManager {
-	return &Cfg4DhtLsnManager{
-		IP:      config[name].DhtLocal.IP,
-		PortTcp: config[name].DhtLocal.TCP,
-		PortUdp: config[name].DhtLocal.UDP,
-	}
+	return config[name].Config4DhtLsnManager()
 }
 
 // Get configuration for dht connection manager
 func P2pConfig4DhtConManager(name string) *Cfg4DhtConManager {
-	config[name].DhtConCfg.Local = &config[name].DhtLocal
-	config[name].DhtConCfg.BootstrapNode = config[name].BootstrapNode
-	return &config[name].DhtConCfg
+	return config[name].Config4DhtConManager()
 }
 
 // Get configuration for nat
 func P2pConfig4NatManager(name string) *Cfg4NatManager {
-	return &config[name].NatCfg
+	return config[name].Config4NatManager()
 }
 
 // elliptic.P256
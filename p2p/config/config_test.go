@@ -0,0 +1,73 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mr-tron/base58"
+)
+
+func TestNodeIdString(t *testing.T) {
+	var id NodeID
+	for i := range id {
+		id[i] = byte(i * 7)
+	}
+
+	str := P2pNodeId2String(id)
+	if !strings.HasPrefix(str, NodeIdTextPrefix) {
+		t.Fatalf("no prefix: %s", str)
+	}
+	nid, err := P2pString2NodeId(str)
+	if err != nil || *nid != id {
+		t.Fatalf("round trip: %v, %v", nid, err)
+	}
+	if nid, err = P2pString2NodeId(P2pNodeId2HexString(id)); err != nil || *nid != id {
+		t.Errorf("hex: %v, %v", nid, err)
+	}
+
+	var tid NodeID
+	if text, _ := id.MarshalText(); tid.UnmarshalText(text) != nil || tid != id {
+		t.Errorf("text marshal: %x", tid)
+	}
+
+	// a byte of the id changed, the checksum mismatched
+	data, _ := base58.Decode(strings.TrimPrefix(str, NodeIdTextPrefix))
+	data[0] ^= 0x01
+	if _, err := P2pString2NodeId(NodeIdTextPrefix + base58.Encode(data)); err == nil {
+		t.Errorf("bad checksum accepted")
+	}
+
+	bad := []string{
+		"",
+		"xnode" + strings.TrimPrefix(str, NodeIdTextPrefix),
+		NodeIdTextPrefix,
+		NodeIdTextPrefix + "0OIl",
+		str[:len(str)-1],
+		strings.TrimPrefix(str, NodeIdTextPrefix),
+	}
+	for _, s := range bad {
+		if _, err := P2pString2NodeId(s); err == nil {
+			t.Errorf("accepted: %q", s)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"bytes"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Access policies of namespaces, enforced when values or providers are put by
+// peers. the policy of the namespace with the longest prefix matched is applied,
+// and keys not in any namespace configured are open to all.
+//
+type dhtAcls []config.DhtAcl
+
+func (acls dhtAcls) match(key []byte) *config.DhtAcl {
+	var acl *config.DhtAcl
+	for idx := range acls {
+		if !bytes.HasPrefix(key, acls[idx].Prefix) {
+			continue
+		}
+		if acl == nil || len(acls[idx].Prefix) > len(acl.Prefix) {
+			acl = &acls[idx]
+		}
+	}
+	return acl
+}
+
+//
+// Check if the key can be written by the peer
+//
+func (acls dhtAcls) writable(key []byte, writer *config.NodeID) bool {
+	acl := acls.match(key)
+	if acl == nil {
+		return true
+	}
+	switch acl.Policy {
+	case config.DhtAclOpen:
+		return true
+	case config.DhtAclAllowlist:
+		for idx := range acl.Writers {
+			if acl.Writers[idx] == *writer {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//
+// Check if the key is in a namespace kept in local only
+//
+func (acls dhtAcls) localOnly(key []byte) bool {
+	acl := acls.match(key)
+	return acl != nil && acl.Policy == config.DhtAclLocalOnly
+}
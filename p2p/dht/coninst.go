@@ -71,18 +71,19 @@ type txPkgId struct {
 // Dht connection instance
 //
 type ConInst struct {
-	sdl           *sch.Scheduler    // pointer to scheduler
-	sdlName       string            // scheduler name
-	name          string            // task name
-	bootstrapNode bool              // bootstrap node flag
-	tep           sch.SchUserTaskEp // task entry
-	local         *config.Node      // pointer to local node specification
-	ptnMe         interface{}       // pointer to myself task node
-	ptnDhtMgr     interface{}       // pointer to dht manager task node
-	ptnRutMgr     interface{}       // pointer to route manager task node
-	ptnDsMgr      interface{}       // pointer to data store manager task node
-	ptnPrdMgr     interface{}       // pointer to provider manager task node
-	ptnConMgr     interface{}       // pointer to connection manager task node
+	sdl           *sch.Scheduler       // pointer to scheduler
+	sdlName       string               // scheduler name
+	name          string               // task name
+	bootstrapNode bool                 // bootstrap node flag
+	tep           sch.SchUserTaskEp    // task entry
+	local         *config.Node         // pointer to local node specification
+	transport     config.PeerTransport // transport to dial with, nil for tcp
+	ptnMe         interface{}          // pointer to myself task node
+	ptnDhtMgr     interface{}          // pointer to dht manager task node
+	ptnRutMgr     interface{}          // pointer to route manager task node
+	ptnDsMgr      interface{}          // pointer to data store manager task node
+	ptnPrdMgr     interface{}          // pointer to provider manager task node
+	ptnConMgr     interface{}          // pointer to connection manager task node
 
 	//
 	// Notice: this is the pointer to the task which asks to establish this connection instance,
@@ -645,7 +646,7 @@ func (conInst *ConInst) rutMgrNearestRsp(msg *sch.MsgDhtRutMgrNearestRsp) sch.Sc
 			Nodes: nodes,
 			Pcs:   msg.Pcs.([]int),
 			Id:    findNode.Id,
-			Extra: nil,
+			Extra: vivaldiOf(conInst.sdl.SchGetP2pCfgName()).localBytes(),
 		}
 
 		dhtMsg = DhtMessage{
@@ -836,6 +837,10 @@ func (conInst *ConInst) protoMsgInd(msg *sch.MsgDhtQryInstProtoMsgInd) sch.SchEr
 		gvr, _ := msg.Msg.(*GetValueRsp)
 		eno, txPkg = conInst.checkTxWaitResponse(MID_GETVALUE_RSP, int64(gvr.Id))
 
+	case sch.EvDhtConInstPutValueAck, sch.EvDhtConInstPutValueBusy:
+		pong, _ := msg.Msg.(*Pong)
+		eno, txPkg = conInst.checkTxWaitResponse(MID_PONG, pong.Seq)
+
 	default:
 		ciLog.Debug("protoMsgInd: invalid indication, for: %d", msg.ForWhat)
 		return sch.SchEnoParameter
@@ -1008,7 +1013,6 @@ func (conInst *ConInst) connect2Peer() DhtErrno {
 	}
 
 	peer := conInst.hsInfo.peer
-	dialer := &net.Dialer{Timeout: ciConn2PeerTimeout}
 	addr := &net.TCPAddr{IP: peer.IP, Port: int(peer.TCP)}
 
 	ciLog.Debug("connect2Peer: try to connect, " +
@@ -1020,7 +1024,13 @@ func (conInst *ConInst) connect2Peer() DhtErrno {
 	var conn net.Conn
 	var err error
 
-	if conn, err = dialer.Dial("tcp", addr.String()); err != nil {
+	if conInst.transport != nil {
+		conn, err = conInst.transport.Dial(addr.String(), ciConn2PeerTimeout)
+	} else {
+		dialer := &net.Dialer{Timeout: ciConn2PeerTimeout}
+		conn, err = dialer.Dial("tcp", addr.String())
+	}
+	if err != nil {
 		ciLog.Debug("connect2Peer: " +
 			"dial failed, inst: %s, dir: %d, local: %s, to: %s, err: %s",
 			conInst.name, conInst.dir, conInst.local.IP.String(),
@@ -1839,6 +1849,21 @@ func (conInst *ConInst) getPing(ping *Ping) DhtErrno {
 // Handler for "MID_PONG" from peer
 //
 func (conInst *ConInst) getPong(pong *Pong) DhtErrno {
+	if extra := string(pong.Extra); extra == PutValueAck || extra == PutValueBusy {
+		forWhat := sch.EvDhtConInstPutValueAck
+		if extra == PutValueBusy {
+			forWhat = sch.EvDhtConInstPutValueBusy
+		}
+		ind := sch.MsgDhtQryInstProtoMsgInd{
+			From:    &pong.From,
+			Msg:     pong,
+			ForWhat: forWhat,
+		}
+		msg := sch.SchMessage{}
+		conInst.sdl.SchMakeMessage(&msg, conInst.ptnMe, conInst.ptnMe, sch.EvDhtQryInstProtoMsgInd, &ind)
+		conInst.sdl.SchSendMessage(&msg)
+		return DhtEnoNone
+	}
 	pongInd := sch.MsgDhtRutPingInd{
 		ConInst: conInst,
 		Msg:     pong,
@@ -31,6 +31,7 @@ import (
 	ggio "github.com/gogo/protobuf/io"
 	lru "github.com/hashicorp/golang-lru"
 	config "github.com/yeeco/gyee/p2p/config"
+	identity "github.com/yeeco/gyee/p2p/identity"
 	p2plog "github.com/yeeco/gyee/p2p/logger"
 	nat "github.com/yeeco/gyee/p2p/nat"
 	sch "github.com/yeeco/gyee/p2p/scheduler"
@@ -81,11 +82,13 @@ const (
 // Connection manager configuration
 //
 type conMgrCfg struct {
-	local         *config.Node  // pointer to local node specification
-	bootstarpNode bool          // bootstrap node flag
-	maxCon        int           // max number of connection
-	minCon        int           // min number of connection
-	hsTimeout     time.Duration // handshake timeout duration
+	local         *config.Node         // pointer to local node specification
+	bootstarpNode bool                 // bootstrap node flag
+	maxCon        int                  // max number of connection
+	minCon        int                  // min number of connection
+	hsTimeout     time.Duration        // handshake timeout duration
+	advertised    bool                 // local is the advertised address, nat mapping not applied
+	transport     config.PeerTransport // transport to dial with, nil for tcp
 }
 
 //
@@ -129,6 +132,7 @@ type ConMgr struct {
 	pasStatus     int                          // public addr switching status
 	tidMonitor    int                          // monitor timer identity
 	instInClosing map[conInstIdentity]*ConInst // instance in closing waiting for response from instance
+	idReg         *identity.Registry           // peers known by both chain and dht stacks
 }
 
 //
@@ -246,6 +250,7 @@ func (conMgr *ConMgr) poweron(ptn interface{}) sch.SchErrno {
 	conMgr.sdl = sdl
 	conMgr.sdlName = sdl.SchGetP2pCfgName()
 	conMgr.ptnMe = ptn
+	conMgr.idReg = identity.Of(conMgr.sdlName)
 
 	_, conMgr.ptnRutMgr = sdl.SchGetUserTaskNode(RutMgrName)
 	_, conMgr.ptnQryMgr = sdl.SchGetUserTaskNode(QryMgrName)
@@ -516,6 +521,25 @@ func (conMgr *ConMgr) handshakeRsp(msg *sch.MsgDhtConInstHandshakeRsp) sch.SchEr
 		panic("handshakeRsp: invalid direction")
 	}
 
+	if conMgr.idReg.Banned(msg.Peer.ID) {
+
+		//
+		// banned by the chain peer manager, maybe while the instance was in
+		// handshaking, done it.
+		//
+
+		connLog.ForceDebug("handshakeRsp: peer banned, sdl: %s, inst: %s, dir: %d",
+			conMgr.sdlName, ci.name, ci.dir)
+
+		rsp2TasksPending(ci, msg, DhtEnoBanned)
+		if msg.Dir == ConInstDirInbound {
+			delete(conMgr.ibInstTemp, ci.name)
+		} else if duped, dup := conMgr.ciTab[cid]; dup && duped == ci {
+			delete(conMgr.ciTab, cid)
+		}
+		return conMgr.sdl.SchTaskDone(ci.ptnMe, ci.name, sch.SchEnoKilled)
+	}
+
 	if msg.Dir == ConInstDirInbound {
 		delete(conMgr.ibInstTemp, ci.name)
 	}
@@ -583,6 +607,12 @@ func (conMgr *ConMgr) handshakeRsp(msg *sch.MsgDhtConInstHandshakeRsp) sch.SchEr
 	}
 	conMgr.instCache.Add(&key, ci)
 
+	//
+	// link the peer to that of the chain stack
+	//
+	conMgr.idReg.SetAddress(identity.StackDht, msg.Peer)
+	conMgr.idReg.Connected(identity.StackDht, msg.Peer.ID, true)
+
 	//
 	// update the route manager
 	//
@@ -766,6 +796,11 @@ func (conMgr *ConMgr) connctReq(msg *sch.MsgDhtConMgrConnectReq) sch.SchErrno {
 		return yes, inst
 	}
 
+	if conMgr.idReg.Banned(msg.Peer.ID) {
+		connLog.Debug("connctReq: peer banned, owner: %s", msg.Name)
+		return rsp2Sender(DhtEnoBanned, ConInstDirUnknown)
+	}
+
 	if yes, ci := isInstInClosing(); yes {
 		connLog.Debug("connctReq: in closing, inst: %s , owner: %s", ci.name, msg.Name)
 		return rsp2Sender(DhtErrno(DhtEnoResource), ci.dir)
@@ -941,7 +976,7 @@ func (conMgr *ConMgr) sendReq(msg *sch.MsgDhtConMgrSendReq) sch.SchErrno {
 	}
 
 	if ci == nil {
-		connLog.Debug("sendReq: not found, peer id: %x", msg.Peer.ID)
+		connLog.Debug("sendReq: not found, peer id: %s", config.P2pNodeId2String(msg.Peer.ID))
 		return sch.SchEnoResource
 	}
 
@@ -1148,7 +1183,7 @@ func (conMgr *ConMgr) rutPeerRemoveInd(msg *sch.MsgDhtRutPeerRemovedInd) sch.Sch
 // NAT ready indication handler
 //
 func (conMgr *ConMgr) natReadyInd(msg *sch.MsgNatMgrReadyInd) sch.SchErrno {
-	if msg.NatType == config.NATT_NONE {
+	if msg.NatType == config.NATT_NONE || conMgr.cfg.advertised {
 		conMgr.natTcpResult = true
 		conMgr.pubTcpIp = conMgr.cfg.local.IP
 		conMgr.pubTcpPort = int(conMgr.cfg.local.TCP)
@@ -1237,12 +1272,14 @@ func (conMgr *ConMgr) monitorTimer() sch.SchErrno {
 // Get configuration for connection mananger
 //
 func (conMgr *ConMgr) getConfig() DhtErrno {
-	cfg := config.P2pConfig4DhtConManager(conMgr.sdl.SchGetP2pCfgName())
+	cfg := conMgr.sdl.SchGetTaskConfig(conMgr.ptnMe).Config4DhtConManager()
 	conMgr.cfg.local = cfg.Local
 	conMgr.cfg.bootstarpNode = cfg.BootstrapNode
 	conMgr.cfg.maxCon = cfg.MaxCon
 	conMgr.cfg.minCon = cfg.MinCon
 	conMgr.cfg.hsTimeout = cfg.HsTimeout
+	conMgr.cfg.advertised = cfg.Advertised
+	conMgr.cfg.transport = cfg.Transport
 	return DhtEnoNone
 }
 
@@ -1320,6 +1357,7 @@ func (conMgr *ConMgr) setupConInst(ci *ConInst, srcTask interface{}, peer *confi
 	}
 
 	ci.local = conMgr.cfg.local
+	ci.transport = conMgr.cfg.transport
 	ci.ptnConMgr = conMgr.ptnMe
 	_, ci.ptnDhtMgr = conMgr.sdl.SchGetUserTaskNode(DhtMgrName)
 	_, ci.ptnRutMgr = conMgr.sdl.SchGetUserTaskNode(RutMgrName)
@@ -1409,6 +1447,7 @@ func (conMgr *ConMgr) instClosedInd(msg *sch.MsgDhtConInstStatusInd) sch.SchErrn
 					dir:  ci.dir,
 				}
 				conMgr.instCache.Remove(&key)
+				conMgr.idReg.Connected(identity.StackDht, ci.hsInfo.peer.ID, false)
 			}
 			connLog.Debug("instClosedInd: found: %t, err: %t", found, err)
 		}
@@ -156,6 +156,10 @@ type DsMgr struct {
 	ldsCfg      LeveldbDatastoreConfig // levelDB stat store configuration
 	tmMgr       *TimerManager          // timer manager
 	tidTick     int                    // tick timer identity
+	hotKeys     *dsHotKeys             // hot keys detection
+	hotAnnounce map[DsKey]bool         // hot keys being announced
+	acls        dhtAcls                // access policies of namespaces
+	ingest      *dsIngest              // put-value from peers queued to the storer
 }
 
 //
@@ -170,6 +174,8 @@ func NewDsMgr() *DsMgr {
 		ldsCfg:      LeveldbDatastoreConfig{},
 		tmMgr:       NewTimerManager(),
 		tidTick:     sch.SchInvalidTid,
+		hotKeys:     newDsHotKeys(),
+		hotAnnounce: make(map[DsKey]bool, 0),
 	}
 
 	dsMgr.tep = dsMgr.dsMgrProc
@@ -248,6 +254,9 @@ func (dsMgr *DsMgr) dsMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno
 	case sch.EvDhtDsMgrGetValReq:
 		eno = dsMgr.getValReq(msg.Body.(*sch.MsgDhtDsMgrGetValReq))
 
+	case sch.EvDhtDsMgrStoredInd:
+		eno = dsMgr.storedInd(msg.Body.(*sch.MsgDhtDsMgrStoredInd))
+
 	case sch.EvDhtRutMgrNearestRsp:
 		eno = dsMgr.rutMgrNearestRsp(msg.Body.(*sch.MsgDhtRutMgrNearestRsp))
 
@@ -282,30 +291,45 @@ func (dsMgr *DsMgr) Put(k []byte, v DsValue, kt time.Duration) DhtErrno {
 
 	if kt != DsMgrDurInf {
 
-		ptm, eno := dsMgr.tmMgr.GetTimer(kt, nil, nil)
-		if eno != TmEnoNone {
-			dsLog.Debug("Put: GetTimer failed, eno: %d", eno)
-			return DhtEnoTimer
-		}
-
-		tm := ptm.(*timer)
-		dsMgr.tmMgr.SetTimerData(tm, tm)
-		dsMgr.tmMgr.SetTimerKey(tm, k)
-		dsMgr.tmMgr.SetTimerHandler(tm, dsMgr.cleanUpTimerCb)
-
-		tm.to = time.Now().Add(kt)
-		ek := dsMgr.makeExpiredKey(k, tm.to)
-		if eno = dsMgr.dsExp.Put(ek, k, sch.Keep4Ever); eno != DhtEnoNone {
+		to := time.Now().Add(kt)
+		ek := dsMgr.makeExpiredKey(k, to)
+		if eno := dsMgr.dsExp.Put(ek, k, sch.Keep4Ever); eno != DhtEnoNone {
 			dsLog.Debug("Put: failed, eno: %d", eno)
 			return DhtEnoDatastore
 		}
 
-		if err := dsMgr.tmMgr.StartTimer(tm); err != nil {
-			dsLog.Debug("Put: StartTimer failed, error: %s", err.Error())
-			dsMgr.ds.Delete(k)
-			dsMgr.dsExp.Delete(ek)
-			return DhtEnoTimer
-		}
+		return dsMgr.startCleanUpTimer(k, kt, to)
+	}
+
+	return DhtEnoNone
+}
+
+//
+// start timer to clean up (key, value) pair, which is expired at "to", the pair
+// is deleted if failed.
+//
+func (dsMgr *DsMgr) startCleanUpTimer(k []byte, kt time.Duration, to time.Time) DhtErrno {
+
+	ek := dsMgr.makeExpiredKey(k, to)
+	ptm, eno := dsMgr.tmMgr.GetTimer(kt, nil, nil)
+	if eno != TmEnoNone {
+		dsLog.Debug("startCleanUpTimer: GetTimer failed, eno: %d", eno)
+		dsMgr.ds.Delete(k)
+		dsMgr.dsExp.Delete(ek)
+		return DhtEnoTimer
+	}
+
+	tm := ptm.(*timer)
+	dsMgr.tmMgr.SetTimerData(tm, tm)
+	dsMgr.tmMgr.SetTimerKey(tm, k)
+	dsMgr.tmMgr.SetTimerHandler(tm, dsMgr.cleanUpTimerCb)
+	tm.to = to
+
+	if err := dsMgr.tmMgr.StartTimer(tm); err != nil {
+		dsLog.Debug("startCleanUpTimer: StartTimer failed, error: %s", err.Error())
+		dsMgr.ds.Delete(k)
+		dsMgr.dsExp.Delete(ek)
+		return DhtEnoTimer
 	}
 
 	return DhtEnoNone
@@ -348,6 +372,7 @@ func (dsMgr *DsMgr) poweron(ptn interface{}) sch.SchErrno {
 	_, dsMgr.ptnDhtMgr = sdl.SchGetUserTaskNode(DhtMgrName)
 	_, dsMgr.ptnQryMgr = sdl.SchGetUserTaskNode(QryMgrName)
 	_, dsMgr.ptnRutMgr = sdl.SchGetUserTaskNode(RutMgrName)
+	dsMgr.acls = sdl.SchGetTaskConfig(ptn).Config4DhtAcls()
 
 	if dsMgr.ptnDhtMgr == nil ||
 		dsMgr.ptnQryMgr == nil ||
@@ -421,6 +446,9 @@ func (dsMgr *DsMgr) poweron(ptn interface{}) sch.SchErrno {
 		return sch.SchEnoUserTask
 	}
 
+	dsMgr.ingest = newDsIngest()
+	go dsMgr.storer(dsMgr.ingest)
+
 	return sch.SchEnoNone
 }
 
@@ -429,6 +457,10 @@ func (dsMgr *DsMgr) poweron(ptn interface{}) sch.SchErrno {
 //
 func (dsMgr *DsMgr) poweroff(ptn interface{}) sch.SchErrno {
 	dsLog.Debug("poweroff: task will be done ...")
+	if dsMgr.ingest != nil {
+		dsMgr.ingest.stop()
+		dsMgr.ingest = nil
+	}
 	dsMgr.ds.Close()
 	dsMgr.dsExp.Close()
 	return dsMgr.sdl.SchTaskDone(dsMgr.ptnMe, dsMgr.name, sch.SchEnoKilled)
@@ -472,9 +504,13 @@ func (dsMgr *DsMgr) localAddValReq(msg *sch.MsgDhtDsMgrAddValReq) sch.SchErrno {
 	}
 
 	//
-	// publish it to our neighbors
+	// publish it to our neighbors, except it's in a local only namespace
 	//
 
+	if dsMgr.acls.localOnly(k[0:]) {
+		return dsMgr.localAddValRsp(sch.EvDhtMgrPutValueRsp, k[0:], nil, DhtEnoNone)
+	}
+
 	qry := sch.MsgDhtQryMgrQueryStartReq{
 		Target:  k,
 		Msg:     msg,
@@ -533,6 +569,12 @@ func (dsMgr *DsMgr) qryMgrQueryResultInd(msg *sch.MsgDhtQryMgrQueryResultInd) sc
 
 	if msg.ForWhat == MID_PUTVALUE {
 
+		if dsMgr.hotAnnounce[msg.Target] {
+			dsLog.Debug("qryMgrQueryResultInd: hot key announced, key: %x, eno: %d", msg.Target, msg.Eno)
+			delete(dsMgr.hotAnnounce, msg.Target)
+			return sch.SchEnoNone
+		}
+
 		return dsMgr.localAddValRsp(sch.EvDhtMgrPutValueRsp, msg.Target[0:], msg.Peers, DhtErrno(msg.Eno))
 
 	} else if msg.ForWhat == MID_GETVALUE_REQ {
@@ -553,25 +595,96 @@ func (dsMgr *DsMgr) qryMgrQueryResultInd(msg *sch.MsgDhtQryMgrQueryResultInd) sc
 func (dsMgr *DsMgr) putValReq(msg *sch.MsgDhtDsMgrPutValReq) sch.SchErrno {
 
 	//
-	// we are requested to put value from remote peer
+	// we are requested to put value from remote peer, values are queued to the
+	// storer and acknowledged when written, see storedInd.
 	//
 
 	pv, _ := msg.Msg.(*PutValue)
-	dsk := DsKey{}
+	conInst := msg.ConInst.(*ConInst)
+	req := dsIngestReq{
+		conInst: conInst,
+		id:      pv.Id,
+		ackReq:  string(pv.Extra) == PutValueAckReq,
+		kt:      pv.KT,
+		values:  make([]dsIngestValue, 0, len(pv.Values)),
+	}
+
+	//
+	// a record cached for get-value is kept for the time carried
+	//
 
+	if cacheKT, ok := PutValueCacheKT(pv.Extra); ok {
+		req.kt = cacheKT
+	}
+
+	allowed := true
 	for _, v := range pv.Values {
 
-		copy(dsk[0:], v.Key)
-		dsLog.Debug("putValReq: key: %x", dsk)
+		dv := dsIngestValue{val: v.Val}
+		copy(dv.key[0:], v.Key)
+		dsLog.Debug("putValReq: key: %x", dv.key)
 
-		if eno := dsMgr.store(&dsk, v.Val, pv.KT); eno != DhtEnoNone {
-			dsLog.Debug("putValReq: store failed, eno: %d", eno)
+		if !dsMgr.acls.writable(v.Key, &conInst.hsInfo.peer.ID) {
+			dsLog.Debug("putValReq: not allowed, key: %x, peer: %x", dv.key, conInst.hsInfo.peer.ID)
+			allowed = false
+			continue
 		}
+		req.values = append(req.values, dv)
+	}
+
+	//
+	// values not allowed are never acknowledged, and those discarded for the
+	// storer is saturated are replied "busy", so the sender retries at others
+	// at once rather than waits till timeout.
+	//
+
+	req.ackReq = req.ackReq && allowed
+	if len(req.values) == 0 {
+		return sch.SchEnoNone
+	}
+
+	if dsMgr.ingest == nil || !dsMgr.ingest.enqueue(&req) {
+		dsLog.Debug("putValReq: storer busy, peer: %s, id: %d", config.P2pNodeId2String(conInst.hsInfo.peer.ID), pv.Id)
+		return dsMgr.putValRsp(conInst, pv.Id, PutValueBusy)
 	}
 
 	return sch.SchEnoNone
 }
 
+//
+// reply put-value request with a pong, see PutValueAck and PutValueBusy
+//
+func (dsMgr *DsMgr) putValRsp(conInst *ConInst, id int64, extra string) sch.SchErrno {
+
+	dhtMsg := DhtMessage{
+		Mid: MID_PONG,
+		Pong: &Pong{
+			From:  *conInst.local,
+			To:    conInst.hsInfo.peer,
+			Seq:   id,
+			Extra: []byte(extra),
+		},
+	}
+
+	dhtPkg := DhtPackage{}
+	if eno := dhtMsg.GetPackage(&dhtPkg); eno != DhtEnoNone {
+		dsLog.Debug("putValRsp: GetPackage failed, eno: %d", eno)
+		return sch.SchEnoUserTask
+	}
+
+	txReq := sch.MsgDhtConInstTxDataReq{
+		Task:    dsMgr.ptnMe,
+		WaitRsp: false,
+		WaitMid: -1,
+		WaitSeq: -1,
+		Payload: &dhtPkg,
+	}
+
+	schMsg := sch.SchMessage{}
+	dsMgr.sdl.SchMakeMessage(&schMsg, dsMgr.ptnMe, conInst.ptnMe, sch.EvDhtConInstTxDataReq, &txReq)
+	return dsMgr.sdl.SchSendMessage(&schMsg)
+}
+
 //
 // get value request handler
 //
@@ -590,7 +703,7 @@ func (dsMgr *DsMgr) getValReq(msg *sch.MsgDhtDsMgrGetValReq) sch.SchErrno {
 		Nodes: nil,
 		Pcs:   nil,
 		Id:    gvReq.Id,
-		Extra: nil,
+		Extra: vivaldiOf(dsMgr.sdl.SchGetP2pCfgName()).localBytes(),
 	}
 
 	dsk := DsKey{}
@@ -624,16 +737,39 @@ func (dsMgr *DsMgr) getValReq(msg *sch.MsgDhtDsMgrGetValReq) sch.SchErrno {
 		return dsMgr.sdl.SchSendMessage(&schMsg)
 	}
 
+	nearest2Peer := func() sch.SchErrno {
+		fnReq := sch.MsgDhtRutMgrNearestReq{
+			Target:  dsk,
+			Max:     rutMgrMaxNearest,
+			NtfReq:  false,
+			Task:    dsMgr.ptnMe,
+			ForWhat: MID_FINDNODE,
+			Msg:     msg,
+		}
+		schMsg := sch.SchMessage{}
+		conInst.sdl.SchMakeMessage(&schMsg, dsMgr.ptnMe, dsMgr.ptnRutMgr, sch.EvDhtRutMgrNearestReq, &fnReq)
+		return conInst.sdl.SchSendMessage(&schMsg)
+	}
+
 	//
-	// check local data store
+	// check local data store. for a hot key, those beyond the rate limited are
+	// responsed with the nearest nodes to retry elsewhere.
 	//
 
 	if val := dsMgr.fromStore(&dsk); len(val) > 0 {
-		gvRsp.Value = &DhtValue{
-			Key: dsk[0:],
-			Val: val,
+		announce, serve := dsMgr.hotKeys.request(&dsk, time.Now())
+		if announce {
+			dsMgr.hotKeyAnnounce(&dsk, val)
 		}
-		return rsp2Peer()
+		if serve {
+			gvRsp.Value = &DhtValue{
+				Key: dsk[0:],
+				Val: val,
+			}
+			return rsp2Peer()
+		}
+		dsLog.Debug("getValReq: hot key, retry elsewhere, key: %x", dsk)
+		return nearest2Peer()
 	}
 
 	//
@@ -658,17 +794,7 @@ func (dsMgr *DsMgr) getValReq(msg *sch.MsgDhtDsMgrGetValReq) sch.SchErrno {
 	// we have to ask route manager for nearest for key requested
 	//
 
-	fnReq := sch.MsgDhtRutMgrNearestReq{
-		Target:  dsk,
-		Max:     rutMgrMaxNearest,
-		NtfReq:  false,
-		Task:    dsMgr.ptnMe,
-		ForWhat: MID_FINDNODE,
-		Msg:     msg,
-	}
-	schMsg := sch.SchMessage{}
-	conInst.sdl.SchMakeMessage(&schMsg, dsMgr.ptnMe, dsMgr.ptnRutMgr, sch.EvDhtRutMgrNearestReq, &fnReq)
-	return conInst.sdl.SchSendMessage(&schMsg)
+	return nearest2Peer()
 }
 
 //
@@ -702,7 +828,7 @@ func (dsMgr *DsMgr) rutMgrNearestRsp(msg *sch.MsgDhtRutMgrNearestRsp) sch.SchErr
 		Nodes: nodes,
 		Pcs:   msg.Pcs.([]int),
 		Id:    req.Id,
-		Extra: nil,
+		Extra: vivaldiOf(dsMgr.sdl.SchGetP2pCfgName()).localBytes(),
 	}
 
 	dhtMsg := DhtMessage{
@@ -736,6 +862,27 @@ func (dsMgr *DsMgr) qryStartRsp(msg *sch.MsgDhtQryMgrQueryStartRsp) sch.SchErrno
 	return sch.SchEnoNone
 }
 
+//
+// Announce a hot key to more peers by putting it again, the result is not
+// reported to the application.
+//
+func (dsMgr *DsMgr) hotKeyAnnounce(k *DsKey, val []byte) sch.SchErrno {
+	if dsMgr.hotAnnounce[*k] {
+		return sch.SchEnoNone
+	}
+	dsLog.Debug("hotKeyAnnounce: key: %x", *k)
+	dsMgr.hotAnnounce[*k] = true
+	qry := sch.MsgDhtQryMgrQueryStartReq{
+		Target:  *k,
+		Msg:     &sch.MsgDhtDsMgrAddValReq{Key: k[0:], Val: val, KT: DsMgrDurInf},
+		ForWhat: MID_PUTVALUE,
+		Seq:     GetQuerySeqNo(dsMgr.sdl.SchGetP2pCfgName()),
+	}
+	schMsg := sch.SchMessage{}
+	dsMgr.sdl.SchMakeMessage(&schMsg, dsMgr.ptnMe, dsMgr.ptnQryMgr, sch.EvDhtQryMgrQueryStartReq, &qry)
+	return dsMgr.sdl.SchSendMessage(&schMsg)
+}
+
 //
 // get value from store by key
 //
@@ -836,7 +983,7 @@ func (dsMgr *DsMgr) localGetValRsp(key []byte, val []byte, eno DhtErrno) sch.Sch
 //
 func (dsMgr *DsMgr) getFileDatastoreConfig(fdc *FileDatastoreConfig) DhtErrno {
 
-	cfg := config.P2pConfig4DhtFileDatastore(dsMgr.sdl.SchGetP2pCfgName())
+	cfg := dsMgr.sdl.SchGetTaskConfig(dsMgr.ptnMe).Config4DhtFileDatastore()
 	dsMgr.fdsCfg = FileDatastoreConfig{
 		path:          path.Join(cfg.Path, "fds"),
 		shardFuncName: cfg.ShardFuncName,
@@ -854,13 +1001,14 @@ func (dsMgr *DsMgr) getFileDatastoreConfig(fdc *FileDatastoreConfig) DhtErrno {
 //
 func (dsMgr *DsMgr) getLeveldbDatastoreConfig(ldc *LeveldbDatastoreConfig) DhtErrno {
 	// currently use the same parent dir as that file datastore.
-	cfg := config.P2pConfig4DhtFileDatastore(dsMgr.sdl.SchGetP2pCfgName())
+	cfg := dsMgr.sdl.SchGetTaskConfig(dsMgr.ptnMe).Config4DhtFileDatastore()
 	dsMgr.ldsCfg = LeveldbDatastoreConfig{
 		Path:                   path.Join(cfg.Path, "lds"),
 		OpenFilesCacheCapacity: 500,
 		BlockCacheCapacity:     8 * opt.MiB,
 		BlockSize:              4 * opt.MiB,
 		FilterBits:             10,
+		Key:                    cfg.Key,
 	}
 
 	*ldc = dsMgr.ldsCfg
@@ -23,6 +23,7 @@ package dht
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	p2plog "github.com/yeeco/gyee/p2p/logger"
 	sch "github.com/yeeco/gyee/p2p/scheduler"
@@ -74,6 +75,8 @@ const (
 	DhtEnoTimer                         // timer errors
 	DhtEnoBootstrapNode                 // bootstarp node related
 	DhtEnoNatMapping                    // casued by nat mapping
+	DhtEnoReplication                   // storage not acknowledged by enough peers
+	DhtEnoBanned                        // peer banned
 	DhtEnoUnknown                       // unknown
 )
 
@@ -101,6 +104,7 @@ type DhtMgr struct {
 	ptnShMgr  interface{}       // pointer to task node of dht shell manager
 	cbLock    sync.Mutex        // lock for callback to be installed/removed
 	cbf       DhtCallback       // callback entry
+	resCache  *dhtResultCache   // results of find-node and get-value cached
 }
 
 //
@@ -113,7 +117,8 @@ type DhtCallback func(mgr interface{}, mid int, msg interface{}) int
 //
 func NewDhtMgr() *DhtMgr {
 	dhtMgr := DhtMgr{
-		name: DhtMgrName,
+		name:     DhtMgrName,
+		resCache: newDhtResultCache(),
 	}
 	dhtMgr.tep = dhtMgr.dhtMgrProc
 	return &dhtMgr
@@ -335,6 +340,16 @@ func (dhtMgr *DhtMgr) findPeerReq(msg *sch.MsgDhtQryMgrQueryStartReq) sch.SchErr
 		dhtLog.Debug("findPeerReq: unknown what's for: %d", msg.ForWhat)
 		return sch.SchEnoParameter
 	}
+	if ind, ok := dhtMgr.resCache.get(MID_FINDNODE, &msg.Target, time.Now()).(*sch.MsgDhtQryMgrQueryResultInd); ok {
+		dhtLog.Debug("findPeerReq: cached, target: %x", msg.Target)
+		rsp := sch.MsgDhtQryMgrQueryStartRsp{
+			Target: msg.Target,
+			Eno:    DhtEnoNone.GetEno(),
+		}
+		dhtMgr.qryMgrQueryStartRsp(&rsp)
+		cached := *ind
+		return dhtMgr.findPeerRsp(&cached)
+	}
 	return dhtMgr.dispMsg(dhtMgr.ptnQryMgr, sch.EvDhtQryMgrQueryStartReq, msg)
 }
 
@@ -342,6 +357,9 @@ func (dhtMgr *DhtMgr) findPeerReq(msg *sch.MsgDhtQryMgrQueryStartReq) sch.SchErr
 // find peer response handler
 //
 func (dhtMgr *DhtMgr) findPeerRsp(msg *sch.MsgDhtQryMgrQueryResultInd) sch.SchErrno {
+	if DhtErrno(msg.Eno) == DhtEnoNone && msg.ForWhat == MID_FINDNODE {
+		dhtMgr.resCache.put(MID_FINDNODE, &msg.Target, msg, time.Now())
+	}
 	if dhtMgr.ptnShMgr != nil {
 		ind := sch.MsgDhtShEventInd{
 			Evt: sch.EvDhtMgrFindPeerRsp,
@@ -484,6 +502,11 @@ func (dhtMgr *DhtMgr) getProviderRsp(msg *sch.MsgDhtMgrGetProviderRsp) sch.SchEr
 // put value request handler
 //
 func (dhtMgr *DhtMgr) putValueReq(msg *sch.MsgDhtMgrPutValueReq) sch.SchErrno {
+	if len(msg.Key) == DsKeyLength {
+		var k DsKey
+		copy(k[0:], msg.Key)
+		dhtMgr.resCache.invalidate(MID_GETVALUE_REQ, &k)
+	}
 	req := sch.MsgDhtDsMgrAddValReq{
 		Key: msg.Key,
 		Val: msg.Val,
@@ -534,6 +557,15 @@ func (dhtMgr *DhtMgr) putValueRsp(msg *sch.MsgDhtMgrPutValueRsp) sch.SchErrno {
 // get value request handler
 //
 func (dhtMgr *DhtMgr) getValueReq(msg *sch.MsgDhtMgrGetValueReq) sch.SchErrno {
+	if len(msg.Key) == DsKeyLength {
+		var k DsKey
+		copy(k[0:], msg.Key)
+		if rsp, ok := dhtMgr.resCache.get(MID_GETVALUE_REQ, &k, time.Now()).(*sch.MsgDhtMgrGetValueRsp); ok {
+			dhtLog.Debug("getValueReq: cached, key: %x", k)
+			cached := *rsp
+			return dhtMgr.getValueRsp(&cached)
+		}
+	}
 	return dhtMgr.dispMsg(dhtMgr.ptnDsMgr, sch.EvDhtMgrGetValueReq, msg)
 }
 
@@ -541,6 +573,11 @@ func (dhtMgr *DhtMgr) getValueReq(msg *sch.MsgDhtMgrGetValueReq) sch.SchErrno {
 // get value response handler
 //
 func (dhtMgr *DhtMgr) getValueRsp(msg *sch.MsgDhtMgrGetValueRsp) sch.SchErrno {
+	if DhtErrno(msg.Eno) == DhtEnoNone && len(msg.Key) == DsKeyLength && len(msg.Val) > 0 {
+		var k DsKey
+		copy(k[0:], msg.Key)
+		dhtMgr.resCache.put(MID_GETVALUE_REQ, &k, msg, time.Now())
+	}
 	if dhtMgr.ptnShMgr != nil {
 		ind := sch.MsgDhtShEventInd{
 			Evt: sch.EvDhtMgrGetValueRsp,
@@ -0,0 +1,178 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"time"
+
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+//
+// Ingestion of put-value from peers: values are not written in the datastore
+// manager task, but queued to a storer goroutine, which writes them in batches
+// and tells the manager when a batch is done, so the manager and connection
+// instances are not stalled by a slow disk. the queue is bounded, when it's
+// saturated, the values are discarded and the peer is replied "PutValueBusy".
+//
+
+const (
+	dsIngestQueueSize = 256 // max put-value requests queued to the storer
+	dsIngestBatchSize = 32  // max put-value requests written in a batch
+)
+
+// value to be written
+type dsIngestValue struct {
+	key DsKey     // key
+	val []byte    // value
+	to  time.Time // time expired, zero if the value is kept for ever
+}
+
+// put-value request from peer
+type dsIngestReq struct {
+	conInst *ConInst        // connection instance the request received from
+	id      int64           // identity of the request, for acknowledgement
+	ackReq  bool            // acknowledgement required
+	kt      time.Duration   // duration to keep values
+	values  []dsIngestValue // values allowed to be written
+	stored  bool            // all values allowed written ok
+}
+
+type dsIngest struct {
+	queue chan *dsIngestReq // requests to be written
+	done  chan struct{}     // closed when the storer exits
+}
+
+func newDsIngest() *dsIngest {
+	return &dsIngest{
+		queue: make(chan *dsIngestReq, dsIngestQueueSize),
+		done:  make(chan struct{}),
+	}
+}
+
+// queue a request to the storer, false if the queue is saturated
+func (ingest *dsIngest) enqueue(req *dsIngestReq) bool {
+	select {
+	case ingest.queue <- req:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop the storer, requests queued are written before it exits
+func (ingest *dsIngest) stop() {
+	close(ingest.queue)
+	<-ingest.done
+}
+
+// the storer goroutine
+func (dsMgr *DsMgr) storer(ingest *dsIngest) {
+	defer close(ingest.done)
+	for req := range ingest.queue {
+		batch := []*dsIngestReq{req}
+	_drain:
+		for len(batch) < dsIngestBatchSize {
+			select {
+			case req, ok := <-ingest.queue:
+				if !ok {
+					break _drain
+				}
+				batch = append(batch, req)
+			default:
+				break _drain
+			}
+		}
+		for _, req := range batch {
+			dsMgr.write(req)
+		}
+		ind := sch.MsgDhtDsMgrStoredInd{
+			Batch: batch,
+		}
+		msg := sch.SchMessage{}
+		dsMgr.sdl.SchMakeMessage(&msg, dsMgr.ptnMe, dsMgr.ptnMe, sch.EvDhtDsMgrStoredInd, &ind)
+		if eno := dsMgr.sdl.SchSendMessage(&msg); eno != sch.SchEnoNone {
+			dsLog.Debug("storer: SchSendMessage failed, eno: %d, batch: %d", eno, len(batch))
+		}
+	}
+}
+
+// write values of a request in the storer, the cleanup timers are started by
+// the manager task when the batch is done, see storedInd.
+func (dsMgr *DsMgr) write(req *dsIngestReq) {
+	req.stored = true
+	for idx := range req.values {
+		v := &req.values[idx]
+		ddsr := DhtDatastoreRecord{
+			Key:   v.key[0:],
+			Value: v.val,
+			Extra: nil,
+		}
+		dsr := new(DsRecord)
+		if eno := ddsr.EncDsRecord(dsr); eno != DhtEnoNone {
+			dsLog.Debug("write: EncDsRecord failed, eno: %d", eno)
+			req.stored = false
+			continue
+		}
+		if eno := dsMgr.ds.Put(dsr.Key[0:], dsr.Value, req.kt); eno != DhtEnoNone {
+			dsLog.Debug("write: Put failed, eno: %d", eno)
+			req.stored = false
+			continue
+		}
+		if !dsMgrApplyCleanupTimer || req.kt == DsMgrDurInf {
+			continue
+		}
+		v.to = time.Now().Add(req.kt)
+		ek := dsMgr.makeExpiredKey(dsr.Key[0:], v.to)
+		if eno := dsMgr.dsExp.Put(ek, dsr.Key[0:], sch.Keep4Ever); eno != DhtEnoNone {
+			dsLog.Debug("write: Put expired failed, eno: %d", eno)
+			v.to = time.Time{}
+			req.stored = false
+		}
+	}
+}
+
+// batch written by the storer handler
+func (dsMgr *DsMgr) storedInd(msg *sch.MsgDhtDsMgrStoredInd) sch.SchErrno {
+	batch, _ := msg.Batch.([]*dsIngestReq)
+	for _, req := range batch {
+		for idx := range req.values {
+			v := &req.values[idx]
+			if v.to.IsZero() {
+				continue
+			}
+			if eno := dsMgr.startCleanUpTimer(v.key[0:], req.kt, v.to); eno != DhtEnoNone {
+				dsLog.Debug("storedInd: startCleanUpTimer failed, eno: %d", eno)
+				req.stored = false
+			}
+		}
+
+		//
+		// acknowledge the storage if asked for, nothing replied if failed, so the
+		// sender would try other peers when it's timeout.
+		//
+
+		if req.stored && req.ackReq {
+			dsMgr.putValRsp(req.conInst, req.id, PutValueAck)
+		}
+	}
+	return sch.SchEnoNone
+}
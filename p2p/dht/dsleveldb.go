@@ -54,11 +54,13 @@ type LeveldbDatastoreConfig struct {
 	BlockCacheCapacity     int
 	BlockSize              int
 	FilterBits             int
+	Key                    []byte // key to encrypt values at rest, plain if empty
 }
 
 type LeveldbDatastore struct {
 	ldsCfg *LeveldbDatastoreConfig
 	ls     *persistent.LevelStorage
+	store  persistent.Storage // ls, or values encrypted over it
 }
 
 func NewLeveldbDatastore(cfg *LeveldbDatastoreConfig) *LeveldbDatastore {
@@ -71,11 +73,21 @@ func NewLeveldbDatastore(cfg *LeveldbDatastoreConfig) *LeveldbDatastore {
 		return nil
 	}
 	ds.ls = ls
+	ds.store = ls
+	if len(cfg.Key) > 0 {
+		es, err := persistent.NewEncryptedStorage(ls, cfg.Key)
+		if err != nil {
+			dsdbLog.Debug("NewLeveldbDatastore: encryption failed, error: %s", err.Error())
+			ls.Close()
+			return nil
+		}
+		ds.store = es
+	}
 	return &ds
 }
 
 func (lds *LeveldbDatastore) Put(k []byte, v DsValue, kt time.Duration) DhtErrno {
-	if err := lds.ls.Put(k[0:], v.([]byte)); err != nil {
+	if err := lds.store.Put(k[0:], v.([]byte)); err != nil {
 		dsdbLog.Debug("Put: failed, error: %s", err.Error())
 		return DhtEnoDatastore
 	}
@@ -84,7 +96,7 @@ func (lds *LeveldbDatastore) Put(k []byte, v DsValue, kt time.Duration) DhtErrno
 
 func (lds *LeveldbDatastore) Get(k []byte) (eno DhtErrno, value DsValue) {
 	err := error(nil)
-	value, err = lds.ls.Get(k[0:])
+	value, err = lds.store.Get(k[0:])
 	if err != nil {
 		dsdbLog.Debug("Get: failed, error: %s", err.Error())
 		eno = DhtEnoDatastore
@@ -96,7 +108,7 @@ func (lds *LeveldbDatastore) Get(k []byte) (eno DhtErrno, value DsValue) {
 }
 
 func (lds *LeveldbDatastore) Delete(k []byte) DhtErrno {
-	if err := lds.ls.Del(k[0:]); err != nil {
+	if err := lds.store.Del(k[0:]); err != nil {
 		dsdbLog.Debug("Delete: failed, error: %s", err.Error())
 		return DhtEnoDatastore
 	}
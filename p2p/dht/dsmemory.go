@@ -21,6 +21,7 @@
 package dht
 
 import (
+	"sync"
 	"time"
 
 	p2plog "github.com/yeeco/gyee/p2p/logger"
@@ -47,7 +48,8 @@ func (log dsmemLogger) Debug(fmt string, args ...interface{}) {
 // Data store based on "map" in memory, for test only
 //
 type MapDatastore struct {
-	ds map[DsKey]DsValue // (key, value) map
+	lock sync.Mutex        // sync for ds, it's written by the storer of the manager
+	ds   map[DsKey]DsValue // (key, value) map
 }
 
 //
@@ -65,6 +67,8 @@ func NewMapDatastore() *MapDatastore {
 func (mds *MapDatastore) Put(k []byte, v DsValue, kt time.Duration) DhtErrno {
 	dsKey := DsKey{}
 	copy(dsKey[0:], k)
+	mds.lock.Lock()
+	defer mds.lock.Unlock()
 	mds.ds[dsKey] = v
 	return DhtEnoNone
 }
@@ -75,11 +79,13 @@ func (mds *MapDatastore) Put(k []byte, v DsValue, kt time.Duration) DhtErrno {
 func (mds *MapDatastore) Get(k []byte) (eno DhtErrno, value DsValue) {
 	dsKey := DsKey{}
 	copy(dsKey[0:], k)
+	mds.lock.Lock()
+	defer mds.lock.Unlock()
 	v, ok := mds.ds[dsKey]
 	if !ok {
 		return DhtEnoNotFound, nil
 	}
-	return DhtEnoNone, &v
+	return DhtEnoNone, v
 }
 
 //
@@ -88,6 +94,8 @@ func (mds *MapDatastore) Get(k []byte) (eno DhtErrno, value DsValue) {
 func (mds *MapDatastore) Delete(k []byte) DhtErrno {
 	dsKey := DsKey{}
 	copy(dsKey[0:], k)
+	mds.lock.Lock()
+	defer mds.lock.Unlock()
 	delete(mds.ds, dsKey)
 	return DhtEnoNone
 }
@@ -96,6 +104,8 @@ func (mds *MapDatastore) Delete(k []byte) DhtErrno {
 // Clsoe
 //
 func (mds *MapDatastore) Close() DhtErrno {
+	mds.lock.Lock()
+	defer mds.lock.Unlock()
 	mds.ds = nil
 	return DhtEnoNone
 }
@@ -0,0 +1,105 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"time"
+)
+
+//
+// Hot key detection: the "get-value" requests from peers are counted for each key
+// in a fixed window. a key is taken as "hot" when the count reaches the threshold,
+// then, the value is announced to the network again so more peers would store it,
+// and it's served only a limited number of times per window, those beyond are told
+// to retry elsewhere by the nearest nodes instead of the value.
+//
+const (
+	dsHotWindow    = time.Second * 10 // window to count requests for a key
+	dsHotThreshold = 64               // requests in a window to take a key as hot
+	dsHotServe     = 16               // max values served in a window for a hot key
+	dsHotKeep      = time.Minute * 5  // duration a key keeps hot since it's detected
+	dsHotMaxKeys   = 1024 * 4         // max keys tracked
+)
+
+type dsHotKey struct {
+	winBeg time.Time // window begin time
+	reqs   int       // requests in window
+	served int       // values served in window
+	hotEnd time.Time // time the key cools down, zero if it's not hot
+}
+
+type dsHotKeys struct {
+	keys map[DsKey]*dsHotKey // keys tracked
+}
+
+func newDsHotKeys() *dsHotKeys {
+	return &dsHotKeys{
+		keys: make(map[DsKey]*dsHotKey, 0),
+	}
+}
+
+//
+// Count a request for key, returns if the key just becomes hot and if the
+// value should be served.
+//
+func (hks *dsHotKeys) request(k *DsKey, now time.Time) (announce bool, serve bool) {
+	hk, ok := hks.keys[*k]
+	if !ok {
+		if len(hks.keys) >= dsHotMaxKeys {
+			hks.purge(now)
+		}
+		if len(hks.keys) >= dsHotMaxKeys {
+			return false, true
+		}
+		hk = &dsHotKey{winBeg: now}
+		hks.keys[*k] = hk
+	}
+
+	if now.Sub(hk.winBeg) >= dsHotWindow {
+		hk.winBeg = now
+		hk.reqs = 0
+		hk.served = 0
+	}
+	hk.reqs++
+
+	hot := now.Before(hk.hotEnd)
+	if !hot && hk.reqs >= dsHotThreshold {
+		hk.hotEnd = now.Add(dsHotKeep)
+		hot, announce = true, true
+	}
+
+	if hot && hk.served >= dsHotServe {
+		return announce, false
+	}
+	hk.served++
+	return announce, true
+}
+
+//
+// Remove keys neither hot nor counted in current window
+//
+func (hks *dsHotKeys) purge(now time.Time) {
+	for k, hk := range hks.keys {
+		if now.Sub(hk.winBeg) >= dsHotWindow && !now.Before(hk.hotEnd) {
+			delete(hks.keys, k)
+		}
+	}
+}
@@ -0,0 +1,613 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"io"
+	"net"
+
+	"github.com/gogo/protobuf/proto"
+	config "github.com/yeeco/gyee/p2p/config"
+	p2plog "github.com/yeeco/gyee/p2p/logger"
+)
+
+//
+// debug
+//
+type kadLogger struct {
+	debug__ bool
+}
+
+var kadLog = kadLogger{
+	debug__: false,
+}
+
+func (log kadLogger) Debug(fmt string, args ...interface{}) {
+	if log.debug__ {
+		p2plog.Debug(fmt, args...)
+	}
+}
+
+//
+// Interop with libp2p kad-dht: the "Message" of protocol "/ipfs/kad/1.0.0", which
+// is length(uvarint) delimited on a stream, and the multiaddr peer records carried
+// in it, are encoded and decoded here, and translated from/to the dht messages of
+// gyee. the secure channel and the stream muxer negotiated by libp2p before a kad
+// stream is opened are not covered, they are up to the transport.
+// see: https://github.com/libp2p/specs/tree/master/kad-dht
+//
+const KadProtocolId = "/ipfs/kad/1.0.0"
+
+const (
+	KadPutValue     = 0 // PUT_VALUE
+	KadGetValue     = 1 // GET_VALUE
+	KadAddProvider  = 2 // ADD_PROVIDER
+	KadGetProviders = 3 // GET_PROVIDERS
+	KadFindNode     = 4 // FIND_NODE
+	KadPing         = 5 // PING
+)
+
+const (
+	KadNotConnected  = 0 // NOT_CONNECTED
+	KadConnected     = 1 // CONNECTED
+	KadCanConnect    = 2 // CAN_CONNECT
+	KadCannotConnect = 3 // CANNOT_CONNECT
+)
+
+const (
+	kadMaxMsgSize = 1024 * 1024 * 4 // max size of message
+	kadMaCodeIp4  = 0x04            // multiaddr code of "ip4"
+	kadMaCodeTcp  = 0x06            // multiaddr code of "tcp"
+	kadMaCodeIp6  = 0x29            // multiaddr code of "ip6"
+	kadMaCodeUdp  = 0x0111          // multiaddr code of "udp"
+	kadKeyEcdsa   = 3               // key type "ECDSA" of libp2p
+	kadMhSha256   = 0x12            // multihash code of "sha2-256"
+)
+
+type KadRecord struct {
+	Key          []byte // key
+	Value        []byte // value
+	TimeReceived string // time received, RFC3339
+}
+
+type KadPeer struct {
+	Id         []byte   // peer identity, multihash
+	Addrs      [][]byte // multiaddrs, binary
+	Connection int32    // connection type
+}
+
+type KadMessage struct {
+	Type            int32      // message type
+	ClusterLevelRaw int32      // cluster level, not used
+	Key             []byte     // key
+	Record          *KadRecord // record
+	CloserPeers     []KadPeer  // closer peers
+	ProviderPeers   []KadPeer  // provider peers
+}
+
+//
+// Encode message
+//
+func (km *KadMessage) Marshal() []byte {
+	b := proto.NewBuffer(nil)
+	if km.Type != 0 {
+		b.EncodeVarint(1<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(km.Type))
+	}
+	if len(km.Key) > 0 {
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		b.EncodeRawBytes(km.Key)
+	}
+	if km.Record != nil {
+		b.EncodeVarint(3<<3 | proto.WireBytes)
+		b.EncodeRawBytes(km.Record.marshal())
+	}
+	for idx := range km.CloserPeers {
+		b.EncodeVarint(8<<3 | proto.WireBytes)
+		b.EncodeRawBytes(km.CloserPeers[idx].marshal())
+	}
+	for idx := range km.ProviderPeers {
+		b.EncodeVarint(9<<3 | proto.WireBytes)
+		b.EncodeRawBytes(km.ProviderPeers[idx].marshal())
+	}
+	if km.ClusterLevelRaw != 0 {
+		b.EncodeVarint(10<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(km.ClusterLevelRaw))
+	}
+	return b.Bytes()
+}
+
+//
+// Decode message, fields unknown are skipped
+//
+func (km *KadMessage) Unmarshal(buf []byte) DhtErrno {
+	*km = KadMessage{}
+	return kadDecode(buf, func(field int, wire int, r *kadReader) bool {
+		switch {
+		case field == 1 && wire == proto.WireVarint:
+			u, ok := r.uvarint()
+			km.Type = int32(u)
+			return ok
+		case field == 10 && wire == proto.WireVarint:
+			u, ok := r.uvarint()
+			km.ClusterLevelRaw = int32(u)
+			return ok
+		case field == 2 && wire == proto.WireBytes:
+			var ok bool
+			km.Key, ok = r.bytes()
+			return ok
+		case field == 3 && wire == proto.WireBytes:
+			raw, ok := r.bytes()
+			km.Record = new(KadRecord)
+			return ok && km.Record.unmarshal(raw) == DhtEnoNone
+		case (field == 8 || field == 9) && wire == proto.WireBytes:
+			raw, ok := r.bytes()
+			p := KadPeer{}
+			if !ok || p.unmarshal(raw) != DhtEnoNone {
+				return false
+			}
+			if field == 8 {
+				km.CloserPeers = append(km.CloserPeers, p)
+			} else {
+				km.ProviderPeers = append(km.ProviderPeers, p)
+			}
+			return true
+		}
+		return r.skip(wire)
+	})
+}
+
+func (kr *KadRecord) marshal() []byte {
+	b := proto.NewBuffer(nil)
+	if len(kr.Key) > 0 {
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		b.EncodeRawBytes(kr.Key)
+	}
+	if len(kr.Value) > 0 {
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		b.EncodeRawBytes(kr.Value)
+	}
+	if len(kr.TimeReceived) > 0 {
+		b.EncodeVarint(5<<3 | proto.WireBytes)
+		b.EncodeStringBytes(kr.TimeReceived)
+	}
+	return b.Bytes()
+}
+
+func (kr *KadRecord) unmarshal(buf []byte) DhtErrno {
+	return kadDecode(buf, func(field int, wire int, r *kadReader) bool {
+		var ok bool
+		switch {
+		case field == 1 && wire == proto.WireBytes:
+			kr.Key, ok = r.bytes()
+		case field == 2 && wire == proto.WireBytes:
+			kr.Value, ok = r.bytes()
+		case field == 5 && wire == proto.WireBytes:
+			var raw []byte
+			raw, ok = r.bytes()
+			kr.TimeReceived = string(raw)
+		default:
+			ok = r.skip(wire)
+		}
+		return ok
+	})
+}
+
+func (kp *KadPeer) marshal() []byte {
+	b := proto.NewBuffer(nil)
+	if len(kp.Id) > 0 {
+		b.EncodeVarint(1<<3 | proto.WireBytes)
+		b.EncodeRawBytes(kp.Id)
+	}
+	for _, a := range kp.Addrs {
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		b.EncodeRawBytes(a)
+	}
+	if kp.Connection != 0 {
+		b.EncodeVarint(3<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(kp.Connection))
+	}
+	return b.Bytes()
+}
+
+func (kp *KadPeer) unmarshal(buf []byte) DhtErrno {
+	return kadDecode(buf, func(field int, wire int, r *kadReader) bool {
+		switch {
+		case field == 1 && wire == proto.WireBytes:
+			var ok bool
+			kp.Id, ok = r.bytes()
+			return ok
+		case field == 2 && wire == proto.WireBytes:
+			raw, ok := r.bytes()
+			kp.Addrs = append(kp.Addrs, raw)
+			return ok
+		case field == 3 && wire == proto.WireVarint:
+			u, ok := r.uvarint()
+			kp.Connection = int32(u)
+			return ok
+		}
+		return r.skip(wire)
+	})
+}
+
+//
+// Reader of protobuf wire format
+//
+type kadReader struct {
+	buf []byte // bytes not read yet
+}
+
+func (r *kadReader) uvarint() (uint64, bool) {
+	u, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		return 0, false
+	}
+	r.buf = r.buf[n:]
+	return u, true
+}
+
+func (r *kadReader) bytes() ([]byte, bool) {
+	l, ok := r.uvarint()
+	if !ok || l > uint64(len(r.buf)) {
+		return nil, false
+	}
+	b := append([]byte{}, r.buf[:l]...)
+	r.buf = r.buf[l:]
+	return b, true
+}
+
+func (r *kadReader) skip(wire int) bool {
+	switch wire {
+	case proto.WireVarint:
+		_, ok := r.uvarint()
+		return ok
+	case proto.WireBytes:
+		_, ok := r.bytes()
+		return ok
+	case proto.WireFixed64, proto.WireFixed32:
+		n := 8
+		if wire == proto.WireFixed32 {
+			n = 4
+		}
+		if len(r.buf) < n {
+			return false
+		}
+		r.buf = r.buf[n:]
+		return true
+	}
+	return false
+}
+
+func kadDecode(buf []byte, field func(field int, wire int, r *kadReader) bool) DhtErrno {
+	r := &kadReader{buf: buf}
+	for len(r.buf) > 0 {
+		tag, ok := r.uvarint()
+		if !ok {
+			kadLog.Debug("kadDecode: invalid tag")
+			return DhtEnoSerialization
+		}
+		if !field(int(tag>>3), int(tag&0x07), r) {
+			kadLog.Debug("kadDecode: invalid field: %d, wire: %d", tag>>3, tag&0x07)
+			return DhtEnoSerialization
+		}
+	}
+	return DhtEnoNone
+}
+
+//
+// Write message delimited by its length
+//
+func KadWriteMessage(w io.Writer, km *KadMessage) DhtErrno {
+	pl := km.Marshal()
+	hdr := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(hdr, uint64(len(pl)))
+	if _, err := w.Write(append(hdr[:n], pl...)); err != nil {
+		kadLog.Debug("KadWriteMessage: write failed, err: %s", err.Error())
+		return DhtEnoOs
+	}
+	return DhtEnoNone
+}
+
+//
+// Read message delimited by its length
+//
+func KadReadMessage(r io.ByteReader, km *KadMessage) DhtErrno {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		kadLog.Debug("KadReadMessage: read size failed, err: %s", err.Error())
+		return DhtEnoOs
+	}
+	if size > kadMaxMsgSize {
+		kadLog.Debug("KadReadMessage: message too big: %d", size)
+		return DhtEnoProtocol
+	}
+	buf := make([]byte, size)
+	for idx := range buf {
+		if buf[idx], err = r.ReadByte(); err != nil {
+			kadLog.Debug("KadReadMessage: read payload failed, err: %s", err.Error())
+			return DhtEnoOs
+		}
+	}
+	return km.Unmarshal(buf)
+}
+
+//
+// Peer identity of libp2p for node: the sha2-256 multihash of the protobuf encoded
+// "PublicKey", which is of type "ECDSA" with the PKIX public key as data.
+//
+func KadPeerId(id *config.NodeID) []byte {
+	pub := config.P2pNodeId2Pubkey(id[:])
+	if pub == nil || pub.X == nil {
+		return nil
+	}
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		kadLog.Debug("KadPeerId: marshal failed, err: %s", err.Error())
+		return nil
+	}
+	b := proto.NewBuffer(nil)
+	b.EncodeVarint(1<<3 | proto.WireVarint)
+	b.EncodeVarint(kadKeyEcdsa)
+	b.EncodeVarint(2<<3 | proto.WireBytes)
+	b.EncodeRawBytes(der)
+	sum := sha256.Sum256(b.Bytes())
+	return append([]byte{kadMhSha256, byte(len(sum))}, sum[:]...)
+}
+
+//
+// Multiaddrs of node, "/ip4|ip6/<ip>/tcp/<port>" and "/ip4|ip6/<ip>/udp/<port>"
+//
+func KadNodeAddrs(n *config.Node) [][]byte {
+	var ip []byte
+	var code uint64
+	if ip4 := n.IP.To4(); ip4 != nil {
+		ip, code = ip4, kadMaCodeIp4
+	} else if ip6 := n.IP.To16(); ip6 != nil {
+		ip, code = ip6, kadMaCodeIp6
+	} else {
+		return nil
+	}
+	addr := func(proto uint64, port uint16) []byte {
+		b := make([]byte, 0, 2*binary.MaxVarintLen64+len(ip)+2)
+		b = kadAppendUvarint(b, code)
+		b = append(b, ip...)
+		b = kadAppendUvarint(b, proto)
+		return append(b, byte(port>>8), byte(port))
+	}
+	addrs := make([][]byte, 0, 2)
+	if n.TCP != 0 {
+		addrs = append(addrs, addr(kadMaCodeTcp, n.TCP))
+	}
+	if n.UDP != 0 {
+		addrs = append(addrs, addr(kadMaCodeUdp, n.UDP))
+	}
+	return addrs
+}
+
+//
+// Setup address of node from multiaddrs, those not understood are ignored
+//
+func KadAddrsNode(addrs [][]byte, n *config.Node) bool {
+	ok := false
+	for _, a := range addrs {
+		code, l := binary.Uvarint(a)
+		if l <= 0 {
+			continue
+		}
+		a = a[l:]
+		var ip net.IP
+		switch {
+		case code == kadMaCodeIp4 && len(a) >= net.IPv4len:
+			ip, a = net.IP(append([]byte{}, a[:net.IPv4len]...)), a[net.IPv4len:]
+		case code == kadMaCodeIp6 && len(a) >= net.IPv6len:
+			ip, a = net.IP(append([]byte{}, a[:net.IPv6len]...)), a[net.IPv6len:]
+		default:
+			continue
+		}
+		code, l = binary.Uvarint(a)
+		if l <= 0 || len(a[l:]) < 2 {
+			continue
+		}
+		port := binary.BigEndian.Uint16(a[l:])
+		if n.IP != nil && !n.IP.Equal(ip) {
+			continue
+		}
+		switch code {
+		case kadMaCodeTcp:
+			n.IP, n.TCP, ok = ip, port, true
+		case kadMaCodeUdp:
+			n.IP, n.UDP, ok = ip, port, true
+		}
+	}
+	return ok
+}
+
+func kadAppendUvarint(b []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	return append(b, buf[:n]...)
+}
+
+//
+// Translate dht message to kad message. since the peer identities of libp2p are
+// hashes, the node identities can't be recovered from them, so a resolver mapping
+// them back is needed for the nodes in the translated responses.
+//
+type KadPeerResolver func(pid []byte) (config.NodeID, bool)
+
+func KadFromDhtMessage(dhtMsg *DhtMessage) (*KadMessage, DhtErrno) {
+	peers := func(nodes []*config.Node, pcs []int) []KadPeer {
+		kps := make([]KadPeer, 0, len(nodes))
+		for idx, n := range nodes {
+			kp := KadPeer{
+				Id:         KadPeerId(&n.ID),
+				Addrs:      KadNodeAddrs(n),
+				Connection: KadNotConnected,
+			}
+			if idx < len(pcs) {
+				kp.Connection = int32(pcs[idx])
+			}
+			kps = append(kps, kp)
+		}
+		return kps
+	}
+	km := new(KadMessage)
+	switch dhtMsg.Mid {
+	case MID_FINDNODE:
+		km.Type = KadFindNode
+		km.Key = append([]byte{}, dhtMsg.FindNode.Target[:]...)
+	case MID_NEIGHBORS:
+		km.Type = KadFindNode
+		km.CloserPeers = peers(dhtMsg.Neighbors.Nodes, dhtMsg.Neighbors.Pcs)
+	case MID_PUTVALUE:
+		if len(dhtMsg.PutValue.Values) != 1 {
+			kadLog.Debug("KadFromDhtMessage: one value per message, got: %d", len(dhtMsg.PutValue.Values))
+			return nil, DhtEnoNotSup
+		}
+		v := &dhtMsg.PutValue.Values[0]
+		km.Type = KadPutValue
+		km.Key = v.Key
+		km.Record = &KadRecord{Key: v.Key, Value: v.Val}
+	case MID_GETVALUE_REQ:
+		km.Type = KadGetValue
+		km.Key = dhtMsg.GetValueReq.Key
+	case MID_GETVALUE_RSP:
+		gvr := dhtMsg.GetValueRsp
+		km.Type = KadGetValue
+		km.Key = gvr.Key
+		if gvr.Value != nil {
+			km.Record = &KadRecord{Key: gvr.Value.Key, Value: gvr.Value.Val}
+		}
+		km.CloserPeers = peers(gvr.Nodes, gvr.Pcs)
+	case MID_PUTPROVIDER:
+		pp := dhtMsg.PutProvider
+		km.Type = KadAddProvider
+		km.Key = pp.Provider.Key
+		km.ProviderPeers = peers(pp.Provider.Nodes, pp.Pcs)
+	case MID_GETPROVIDER_REQ:
+		km.Type = KadGetProviders
+		km.Key = dhtMsg.GetProviderReq.Key
+	case MID_GETPROVIDER_RSP:
+		gpr := dhtMsg.GetProviderRsp
+		km.Type = KadGetProviders
+		km.Key = gpr.Key
+		if gpr.Provider != nil {
+			km.ProviderPeers = peers(gpr.Provider.Nodes, nil)
+		}
+		km.CloserPeers = peers(gpr.Nodes, gpr.Pcs)
+	case MID_PING, MID_PONG:
+		km.Type = KadPing
+	default:
+		kadLog.Debug("KadFromDhtMessage: not supported, mid: %d", dhtMsg.Mid)
+		return nil, DhtEnoNotSup
+	}
+	return km, DhtEnoNone
+}
+
+//
+// Translate kad message to dht message. since requests and responses share types
+// in kad, "rsp" tells which is expected. peers not resolved are dropped.
+//
+func (km *KadMessage) ToDhtMessage(from, to *config.Node, rsp bool, resolver KadPeerResolver) (*DhtMessage, DhtErrno) {
+	nodes := func(kps []KadPeer) ([]*config.Node, []int) {
+		ns := make([]*config.Node, 0, len(kps))
+		pcs := make([]int, 0, len(kps))
+		for idx := range kps {
+			if resolver == nil {
+				break
+			}
+			id, ok := resolver(kps[idx].Id)
+			if !ok {
+				kadLog.Debug("ToDhtMessage: peer not resolved: %x", kps[idx].Id)
+				continue
+			}
+			n := &config.Node{ID: id}
+			if !KadAddrsNode(kps[idx].Addrs, n) {
+				kadLog.Debug("ToDhtMessage: no address for peer: %x", kps[idx].Id)
+				continue
+			}
+			ns = append(ns, n)
+			pcs = append(pcs, int(kps[idx].Connection))
+		}
+		return ns, pcs
+	}
+	dhtMsg := new(DhtMessage)
+	switch {
+	case km.Type == KadFindNode && !rsp:
+		fn := &FindNode{From: *from, To: *to}
+		if len(km.Key) == len(fn.Target) {
+			copy(fn.Target[:], km.Key)
+		} else {
+			fn.Target = sha256.Sum256(km.Key)
+		}
+		dhtMsg.Mid, dhtMsg.FindNode = MID_FINDNODE, fn
+	case km.Type == KadFindNode && rsp:
+		nbs := &Neighbors{From: *from, To: *to}
+		nbs.Nodes, nbs.Pcs = nodes(km.CloserPeers)
+		dhtMsg.Mid, dhtMsg.Neighbors = MID_NEIGHBORS, nbs
+	case km.Type == KadPutValue && !rsp:
+		if km.Record == nil {
+			return nil, DhtEnoProtocol
+		}
+		dhtMsg.Mid = MID_PUTVALUE
+		dhtMsg.PutValue = &PutValue{
+			From:   *from,
+			To:     *to,
+			Values: []DhtValue{{Key: km.Record.Key, Val: km.Record.Value}},
+		}
+	case km.Type == KadGetValue && !rsp:
+		dhtMsg.Mid = MID_GETVALUE_REQ
+		dhtMsg.GetValueReq = &GetValueReq{From: *from, To: *to, Key: km.Key}
+	case km.Type == KadGetValue && rsp:
+		gvr := &GetValueRsp{From: *from, To: *to, Key: km.Key}
+		if km.Record != nil && len(km.Record.Value) > 0 {
+			gvr.Value = &DhtValue{Key: km.Record.Key, Val: km.Record.Value}
+		}
+		gvr.Nodes, gvr.Pcs = nodes(km.CloserPeers)
+		dhtMsg.Mid, dhtMsg.GetValueRsp = MID_GETVALUE_RSP, gvr
+	case km.Type == KadAddProvider && !rsp:
+		pp := &PutProvider{From: *from, To: *to, Provider: &DhtProvider{Key: km.Key}}
+		pp.Provider.Nodes, pp.Pcs = nodes(km.ProviderPeers)
+		dhtMsg.Mid, dhtMsg.PutProvider = MID_PUTPROVIDER, pp
+	case km.Type == KadGetProviders && !rsp:
+		dhtMsg.Mid = MID_GETPROVIDER_REQ
+		dhtMsg.GetProviderReq = &GetProviderReq{From: *from, To: *to, Key: km.Key}
+	case km.Type == KadGetProviders && rsp:
+		gpr := &GetProviderRsp{From: *from, To: *to, Key: km.Key}
+		if len(km.ProviderPeers) > 0 {
+			gpr.Provider = &DhtProvider{Key: km.Key}
+			gpr.Provider.Nodes, _ = nodes(km.ProviderPeers)
+		}
+		gpr.Nodes, gpr.Pcs = nodes(km.CloserPeers)
+		dhtMsg.Mid, dhtMsg.GetProviderRsp = MID_GETPROVIDER_RSP, gpr
+	case km.Type == KadPing && !rsp:
+		dhtMsg.Mid, dhtMsg.Ping = MID_PING, &Ping{From: *from, To: *to}
+	case km.Type == KadPing && rsp:
+		dhtMsg.Mid, dhtMsg.Pong = MID_PONG, &Pong{From: *from, To: *to}
+	default:
+		kadLog.Debug("ToDhtMessage: not supported, type: %d, rsp: %t", km.Type, rsp)
+		return nil, DhtEnoNotSup
+	}
+	return dhtMsg, DhtEnoNone
+}
@@ -0,0 +1,192 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"net"
+	"reflect"
+	"testing"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func kadHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad hex: %s", s)
+	}
+	return b
+}
+
+func TestKadMessageGolden(t *testing.T) {
+	km := KadMessage{
+		Type: KadFindNode,
+		Key:  []byte("ab"),
+		CloserPeers: []KadPeer{{
+			Id:         []byte{0x12, 0x01, 0xaa},
+			Addrs:      [][]byte{{0x04, 1, 2, 3, 4, 0x06, 0x75, 0x30}},
+			Connection: KadConnected,
+		}},
+	}
+	want := kadHex(t, "0804"+"12026162"+"4211"+"0a031201aa"+"1208040102030406"+"7530"+"1801")
+	if got := km.Marshal(); !bytes.Equal(got, want) {
+		t.Errorf("marshal got %x, want %x", got, want)
+	}
+	got := KadMessage{}
+	if eno := got.Unmarshal(want); eno != DhtEnoNone || !reflect.DeepEqual(got, km) {
+		t.Errorf("unmarshal got %+v, eno: %d", got, eno)
+	}
+}
+
+func TestKadMessageRoundTrip(t *testing.T) {
+	km := KadMessage{
+		Type:            KadGetValue,
+		ClusterLevelRaw: 3,
+		Key:             []byte("key"),
+		Record:          &KadRecord{Key: []byte("key"), Value: []byte("value"), TimeReceived: "2018-01-01T00:00:00Z"},
+		CloserPeers:     []KadPeer{{Id: []byte{1}, Addrs: [][]byte{{2}, {3}}}, {Id: []byte{4}, Connection: KadCanConnect}},
+		ProviderPeers:   []KadPeer{{Id: []byte{5}, Connection: KadCannotConnect}},
+	}
+	buf := bytes.Buffer{}
+	if eno := KadWriteMessage(&buf, &km); eno != DhtEnoNone {
+		t.Fatalf("KadWriteMessage failed, eno: %d", eno)
+	}
+	if eno := KadWriteMessage(&buf, &KadMessage{Type: KadPing}); eno != DhtEnoNone {
+		t.Fatalf("KadWriteMessage failed, eno: %d", eno)
+	}
+	r := bufio.NewReader(&buf)
+	got := KadMessage{}
+	if eno := KadReadMessage(r, &got); eno != DhtEnoNone || !reflect.DeepEqual(got, km) {
+		t.Errorf("read got %+v, eno: %d", got, eno)
+	}
+	if eno := KadReadMessage(r, &got); eno != DhtEnoNone || got.Type != KadPing {
+		t.Errorf("read got %+v, eno: %d", got, eno)
+	}
+	if eno := KadReadMessage(r, &got); eno != DhtEnoOs {
+		t.Errorf("read at end got eno: %d", eno)
+	}
+
+	// fields unknown are skipped
+	unknown := append(kadHex(t, "2001"+"3a0100"+"4d01020304"+"5101020304050607"+"08"), km.Marshal()...)
+	if eno := got.Unmarshal(unknown); eno != DhtEnoNone || !reflect.DeepEqual(got, km) {
+		t.Errorf("unmarshal with unknown fields got %+v, eno: %d", got, eno)
+	}
+}
+
+func TestKadMessageInvalid(t *testing.T) {
+	valid := (&KadMessage{Type: KadPutValue, Key: []byte("k"), Record: &KadRecord{Value: []byte("v")}}).Marshal()
+	for name, buf := range map[string][]byte{
+		"tag unterminated":     {0x80},
+		"varint unterminated":  {0x08, 0x80},
+		"bytes truncated":      {0x12, 0x05, 'a'},
+		"bytes length missing": {0x12},
+		"fixed32 truncated":    {0x0d, 0x01},
+		"fixed64 truncated":    {0x09, 0x01, 0x02, 0x03, 0x04},
+		"group":                {0x0b},
+		"record truncated":     {0x1a, 0x02, 0x0a, 0x05},
+		"peer truncated":       {0x42, 0x02, 0x0a, 0x05},
+		"message truncated":    valid[:len(valid)-1],
+	} {
+		km := KadMessage{}
+		if eno := km.Unmarshal(buf); eno != DhtEnoSerialization {
+			t.Errorf("%s: got eno: %d", name, eno)
+		}
+	}
+
+	// oversized and short messages on a stream
+	hdr := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(hdr, kadMaxMsgSize+1)
+	km := KadMessage{}
+	if eno := KadReadMessage(bytes.NewReader(hdr[:n]), &km); eno != DhtEnoProtocol {
+		t.Errorf("oversized got eno: %d", eno)
+	}
+	n = binary.PutUvarint(hdr, uint64(len(valid)+1))
+	if eno := KadReadMessage(bytes.NewReader(append(hdr[:n], valid...)), &km); eno != DhtEnoOs {
+		t.Errorf("short got eno: %d", eno)
+	}
+}
+
+func TestKadPeerIdGolden(t *testing.T) {
+	// the base point of P-256 as a node identity, the peer identity is the
+	// sha2-256 multihash of PublicKey{Type: ECDSA, Data: PKIX(point)}.
+	id := config.NodeID{}
+	copy(id[:], kadHex(t, "6b17d1f2e12c4247f8bce6e563a440f277037d812deb33a0f4a13945d898c296"+
+		"4fe342e2fe1a7f9b8ee7eb4a7c0f9e162bce33576b315ececbb6406837bf51f5"))
+	want := kadHex(t, "12201b4c933834e864cf3a783a11b099d6baae4fa70ca706ff368e55fab1bbb02ec6")
+	if got := KadPeerId(&id); !bytes.Equal(got, want) {
+		t.Errorf("peer id got %x, want %x", got, want)
+	}
+	if got := KadPeerId(&config.NodeID{}); got != nil {
+		t.Errorf("peer id of invalid point got %x", got)
+	}
+}
+
+func TestKadAddrsGolden(t *testing.T) {
+	for _, c := range []struct {
+		node  config.Node
+		addrs []string
+	}{
+		{config.Node{IP: net.ParseIP("127.0.0.1"), TCP: 4001, UDP: 1234},
+			[]string{"047f000001060fa1", "047f000001910204d2"}},
+		{config.Node{IP: net.ParseIP("::1"), TCP: 4001},
+			[]string{"2900000000000000000000000000000001060fa1"}},
+		{config.Node{IP: net.ParseIP("10.0.0.1"), UDP: 30303},
+			[]string{"040a0000019102765f"}},
+	} {
+		addrs := KadNodeAddrs(&c.node)
+		if len(addrs) != len(c.addrs) {
+			t.Fatalf("%s: addrs got %x", c.node.IP, addrs)
+		}
+		for idx := range addrs {
+			if hex.EncodeToString(addrs[idx]) != c.addrs[idx] {
+				t.Errorf("%s: addr got %x, want %s", c.node.IP, addrs[idx], c.addrs[idx])
+			}
+		}
+		n := config.Node{}
+		if !KadAddrsNode(addrs, &n) || !n.IP.Equal(c.node.IP) || n.TCP != c.node.TCP || n.UDP != c.node.UDP {
+			t.Errorf("%s: node got %+v", c.node.IP, n)
+		}
+	}
+
+	// addresses not understood are ignored, others kept
+	n := config.Node{}
+	addrs := [][]byte{
+		nil,
+		{0x80},
+		kadHex(t, "047f00"),
+		kadHex(t, "047f000001"),
+		kadHex(t, "047f00000106"),
+		kadHex(t, "047f0000018401"+"0fa1"),
+		kadHex(t, "0a7f000001060fa1"),
+		kadHex(t, "040a000001060fa1"),
+	}
+	if !KadAddrsNode(addrs, &n) || !n.IP.Equal(net.ParseIP("10.0.0.1")) || n.TCP != 4001 || n.UDP != 0 {
+		t.Errorf("node got %+v", n)
+	}
+	if KadAddrsNode(addrs[:7], &config.Node{}) {
+		t.Errorf("invalid addresses accepted")
+	}
+}
@@ -89,9 +89,10 @@ const (
 // Configuration
 //
 type lsnMgrCfg struct {
-	network string // network name like "tcp", "udp", only "tcp" supported currently
-	ip      net.IP // ip address
-	port    uint16 // port numbers
+	network   string               // network name like "tcp", "udp", only "tcp" supported currently
+	ip        net.IP               // ip address
+	port      uint16               // port numbers
+	transport config.PeerTransport // transport to listen on, nil for tcp
 }
 
 //
@@ -181,10 +182,11 @@ func (lsnMgr *LsnMgr) poweron(ptn interface{}) sch.SchErrno {
 		return sch.SchEnoInternal
 	}
 
-	cfg := config.P2pConfig4DhtLsnManager(lsnMgr.sdl.SchGetP2pCfgName())
+	cfg := lsnMgr.sdl.SchGetTaskConfig(lsnMgr.ptnMe).Config4DhtLsnManager()
 	lsnMgr.config.network = "tcp"
 	lsnMgr.config.ip = cfg.IP
 	lsnMgr.config.port = cfg.PortTcp
+	lsnMgr.config.transport = cfg.Transport
 
 	lsnMgr.status = lmsNull
 	lsnMgr.dispStaus()
@@ -320,7 +322,9 @@ func (lsnMgr *LsnMgr) driveSelf() sch.SchErrno {
 		"listener:[%s:%d], try accept again ...",
 		lsnMgr.config.ip.String(), lsnMgr.config.port)
 
-	lsnMgr.listener.(*net.TCPListener).SetDeadline(time.Now().Add(lmAcceptTimeout))
+	if dl, ok := lsnMgr.listener.(interface{ SetDeadline(time.Time) error }); ok {
+		dl.SetDeadline(time.Now().Add(lmAcceptTimeout))
+	}
 	con, err := lsnMgr.listener.Accept()
 
 	if err != nil {
@@ -391,7 +395,12 @@ func (lsnMgr *LsnMgr) setupListener() DhtErrno {
 	port := lsnMgr.config.port
 	lsnAddr := fmt.Sprintf("%s:%d", ip, port)
 
-	if lsnMgr.listener, err = net.Listen(network, lsnAddr); err != nil {
+	if lsnMgr.config.transport != nil {
+		lsnMgr.listener, err = lsnMgr.config.transport.Listen(lsnAddr)
+	} else {
+		lsnMgr.listener, err = net.Listen(network, lsnAddr)
+	}
+	if err != nil {
 		lsnLog.Debug("setupListener: " +
 			"listen failed, addr: %s, err: %s",
 			lsnAddr, err.Error())
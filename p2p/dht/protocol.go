@@ -22,6 +22,7 @@ package dht
 
 import (
 	"bytes"
+	"encoding/binary"
 	"net"
 	"time"
 
@@ -86,6 +87,40 @@ const (
 	MID_UNKNOWN         = 0xffffffff
 )
 
+//
+// Acknowledgement for put-value: a "PutValue" with "Extra" set to "PutValueAckReq"
+// asks the peer to reply a "Pong" with "Seq" set to the "Id" of the "PutValue" and
+// "Extra" set to "PutValueAck" after the values are stored. a peer whose storage
+// can not keep up replies "PutValueBusy" instead, the values are not stored and
+// the sender should retry them at other peers.
+//
+const (
+	PutValueAckReq = "put-ack-req" // acknowledgement required
+	PutValueAck    = "put-ack"     // values stored
+	PutValueBusy   = "put-busy"    // values discarded for the peer is busy, retry
+)
+
+//
+// Caching for get-value: a "PutValue" with "Extra" set by "PutValueCacheExtra" is
+// sent to cache the record at a peer next to the holder, for the time carried.
+//
+const PutValueCache = "put-cache"
+
+func PutValueCacheExtra(kt time.Duration) []byte {
+	extra := make([]byte, len(PutValueCache)+8)
+	copy(extra, PutValueCache)
+	binary.BigEndian.PutUint64(extra[len(PutValueCache):], uint64(kt))
+	return extra
+}
+
+func PutValueCacheKT(extra []byte) (time.Duration, bool) {
+	if len(extra) != len(PutValueCache)+8 || string(extra[0:len(PutValueCache)]) != PutValueCache {
+		return 0, false
+	}
+	kt := time.Duration(binary.BigEndian.Uint64(extra[len(PutValueCache):]))
+	return kt, kt > 0
+}
+
 //
 // Value
 //
@@ -734,6 +769,8 @@ func (dhtMsg *DhtMessage) GetGetProviderReqMessage(pbMsg *pb.DhtMessage_GetProvi
 	k := pbMsg.Key
 	dhtK := DhtKey(k)
 	gpr.Key = dhtK
+	gpr.Id = int64(*pbMsg.Id)
+	gpr.Extra = pbMsg.Extra
 
 	dhtMsg.reset()
 	dhtMsg.Mid = MID_GETPROVIDER_REQ
@@ -757,6 +794,7 @@ func (dhtMsg *DhtMessage) GetGetProviderRspMessage(pbMsg *pb.DhtMessage_GetProvi
 	gpr.From = *dhtMsg.getNode(pbMsg.From)
 	gpr.To = *dhtMsg.getNode(pbMsg.To)
 
+	gpr.Key = DhtKey(pbMsg.Key)
 	p := pbMsg.Provider
 	gpr.Provider = &DhtProvider{
 		Key: DhtKey(p.Key),
@@ -1195,6 +1233,7 @@ func (dhtMsg *DhtMessage) GetGetProviderRspPackage(dhtPkg *DhtPackage) DhtErrno
 
 	pbGpr.From = dhtMsg.setNode(&gpr.From, pb.DhtMessage_CONT_YES)
 	pbGpr.To = dhtMsg.setNode(&gpr.To, pb.DhtMessage_CONT_YES)
+	pbGpr.Key = gpr.Key
 
 	if gpr.Provider != nil {
 		pbGpr.Provider = &pb.DhtMessage_Provider{
@@ -80,6 +80,7 @@ type PrdMgr struct {
 	prdCache  *lru.Cache        // providers cache
 	lockCache sync.Mutex        // sync with cache operations
 	tmMgr     *TimerManager     // timer manager
+	acls      dhtAcls           // access policies of namespaces
 }
 
 //
@@ -179,7 +180,8 @@ func (prdMgr *PrdMgr) poweron(ptn interface{}) sch.SchErrno {
 	prdMgr.sdl = sch.SchGetScheduler(ptn)
 	prdMgr.ptnMe = ptn
 	_, prdMgr.ptnQryMgr = prdMgr.sdl.SchGetUserTaskNode(QryMgrName)
-	_, prdMgr.ptnDhtMgr = prdMgr.sdl.SchGetUserTaskNode(DsMgrName)
+	_, prdMgr.ptnDhtMgr = prdMgr.sdl.SchGetUserTaskNode(DhtMgrName)
+	prdMgr.acls = prdMgr.sdl.SchGetTaskConfig(ptn).Config4DhtAcls()
 
 	prdMgr.prdCache, _ = lru.New(prdCacheSize)
 	prdMgr.ds = NewMapDatastore()
@@ -287,9 +289,13 @@ func (prdMgr *PrdMgr) localAddProviderReq(msg *sch.MsgDhtPrdMgrAddProviderReq) s
 	}
 
 	//
-	// publish it to our neighbors
+	// publish it to our neighbors, except it's in a local only namespace
 	//
 
+	if prdMgr.acls.localOnly(k[0:]) {
+		return prdMgr.localAddProviderRsp(msg.Key, nil, DhtEnoNone)
+	}
+
 	qry := sch.MsgDhtQryMgrQueryStartReq{
 		Target:  k,
 		Msg:     msg,
@@ -422,6 +428,12 @@ func (prdMgr *PrdMgr) putProviderReq(msg *sch.MsgDhtPrdMgrPutProviderReq) sch.Sc
 	dsk := DsKey{}
 	pp := msg.Msg.(*PutProvider)
 	prd := pp.Provider
+	ci := msg.ConInst.(*ConInst)
+
+	if !prdMgr.acls.writable(prd.Key, &ci.hsInfo.peer.ID) {
+		prdLog.Debug("putProviderReq: not allowed, key: %x, peer: %x", prd.Key, ci.hsInfo.peer.ID)
+		return sch.SchEnoNone
+	}
 
 	copy(dsk[0:], prd.Key)
 	for _, n := range prd.Nodes {
@@ -456,7 +468,7 @@ func (prdMgr *PrdMgr) getProviderReq(msg *sch.MsgDhtPrdMgrGetProviderReq) sch.Sc
 		Nodes:    nil,
 		Pcs:      nil,
 		Id:       req.Id,
-		Extra:    nil,
+		Extra:    vivaldiOf(prdMgr.sdl.SchGetP2pCfgName()).localBytes(),
 	}
 
 	makeDhtPrd := func(dsk *DsKey, ps *PrdSet) *DhtProvider {
@@ -465,7 +477,8 @@ func (prdMgr *PrdMgr) getProviderReq(msg *sch.MsgDhtPrdMgrGetProviderReq) sch.Sc
 			Nodes: nil,
 		}
 		for _, p := range ps.set {
-			prd.Nodes = append(prd.Nodes, &p)
+			n := p
+			prd.Nodes = append(prd.Nodes, &n)
 		}
 		return &prd
 	}
@@ -492,6 +505,10 @@ func (prdMgr *PrdMgr) getProviderReq(msg *sch.MsgDhtPrdMgrGetProviderReq) sch.Sc
 	}
 
 	rsp.Provider = dhtPrd
+	rsp.Key = req.Key
+	for range dhtPrd.Nodes {
+		rsp.Pcs = append(rsp.Pcs, pcsConnNo)
+	}
 
 	//
 	// if providers got, we then response peer
@@ -571,7 +588,7 @@ func (prdMgr *PrdMgr) rutMgrNearestRsp(msg *sch.MsgDhtRutMgrNearestRsp) sch.SchE
 		Nodes:    nodes,
 		Pcs:      msg.Pcs.([]int),
 		Id:       req.Id,
-		Extra:    nil,
+		Extra:    vivaldiOf(prdMgr.sdl.SchGetP2pCfgName()).localBytes(),
 	}
 
 	dhtMsg := DhtMessage{
@@ -634,7 +651,20 @@ func (prdMgr *PrdMgr) prdFromStore(key *DsKey) *PrdSet {
 		return nil
 	}
 
-	return val.(*PrdSet)
+	dpsr := DhtProviderStoreRecord{}
+	if eno := dpsr.DecPsRecord(&PsRecord{Key: *key, Value: val}); eno != DhtEnoNone {
+		prdLog.Debug("prdFromStore: DecPsRecord failed, eno: %d", eno)
+		return nil
+	}
+
+	prdSet := PrdSet{
+		set:     make(map[DsKey]config.Node, len(dpsr.Providers)),
+		addTime: make(map[DsKey]time.Time, len(dpsr.Providers)),
+	}
+	for _, prd := range dpsr.Providers {
+		prdSet.append(DsKey(*rutMgrNodeId2Hash(prd.ID)), prd, time.Now())
+	}
+	return &prdSet
 }
 
 //
@@ -656,8 +686,7 @@ func (prdMgr *PrdMgr) store(key *DsKey, peerId *config.Node) DhtErrno {
 	}
 
 	if eno, val := prdMgr.ds.Get(key[0:]); eno == DhtEnoNone && val != nil {
-		psr := val.(*PsRecord)
-		if eno := dpsr.DecPsRecord(psr); eno != DhtEnoNone {
+		if eno := dpsr.DecPsRecord(&PsRecord{Key: *key, Value: val}); eno != DhtEnoNone {
 			prdLog.Debug("store: DecPsRecord failed, eno: %d", eno)
 			return eno
 		}
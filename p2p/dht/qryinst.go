@@ -471,6 +471,10 @@ func (qryInst *QryInst) connectRsp(msg *sch.MsgDhtConMgrConnectRsp) sch.SchErrno
 		sendReq.WaitRsp = true
 		sendReq.WaitMid = waitMid[icb.qryReq.ForWhat]
 		sendReq.WaitSeq = icb.qryReq.Seq
+	} else if icb.qryReq.ForWhat == MID_PUTVALUE && icb.ackReq {
+		sendReq.WaitRsp = true
+		sendReq.WaitMid = MID_PONG
+		sendReq.WaitSeq = icb.qryReq.Seq
 	} else {
 		sendReq.WaitRsp = false
 		sendReq.WaitMid = -1
@@ -485,10 +489,11 @@ func (qryInst *QryInst) connectRsp(msg *sch.MsgDhtConMgrConnectRsp) sch.SchErrno
 	// for "put-value" or "put-provider", we should send indication to query manager
 	// as following, since no responses are expected from peer in these cases.
 	// notice: the dht package might still not be sent at this moment, firstly it will
-	// be put into pending queue of a connection instance.
+	// be put into pending queue of a connection instance. if acknowledgement asked
+	// for "put-value", we wait it as a response.
 	//
 
-	if icb.qryReq.ForWhat == MID_PUTVALUE || icb.qryReq.ForWhat == MID_PUTPROVIDER {
+	if (icb.qryReq.ForWhat == MID_PUTVALUE && !icb.ackReq) || icb.qryReq.ForWhat == MID_PUTPROVIDER {
 
 		//
 		// tell query manager the result
@@ -586,6 +591,7 @@ func (qryInst *QryInst) protoMsgInd(msg *sch.MsgDhtQryInstProtoMsgInd) sch.SchEr
 			Provider: nil,
 			Value:    nil,
 			Pcs:      nbs.Pcs,
+			Coord:    nbs.Extra,
 		}
 
 		icb.sdl.SchMakeMessage(&msgResult, icb.ptnInst, icb.ptnQryMgr, sch.EvDhtQryInstResultInd, &ind)
@@ -618,6 +624,7 @@ func (qryInst *QryInst) protoMsgInd(msg *sch.MsgDhtQryInstProtoMsgInd) sch.SchEr
 				Provider: nil,
 				Value:    gvr.Value.Val,
 				Pcs:      gvr.Pcs,
+				Coord:    gvr.Extra,
 			}
 
 			icb.sdl.SchMakeMessage(&msgResult, icb.ptnInst, icb.ptnQryMgr, sch.EvDhtQryInstResultInd, &ind)
@@ -633,6 +640,7 @@ func (qryInst *QryInst) protoMsgInd(msg *sch.MsgDhtQryInstProtoMsgInd) sch.SchEr
 				Provider: nil,
 				Value:    nil,
 				Pcs:      gvr.Pcs,
+				Coord:    gvr.Extra,
 			}
 
 			icb.sdl.SchMakeMessage(&msgResult, icb.ptnInst, icb.ptnQryMgr, sch.EvDhtQryInstResultInd, &ind)
@@ -666,6 +674,7 @@ func (qryInst *QryInst) protoMsgInd(msg *sch.MsgDhtQryInstProtoMsgInd) sch.SchEr
 				Provider: (*sch.Provider)(gpr.Provider),
 				Value:    nil,
 				Pcs:      gpr.Pcs,
+				Coord:    gpr.Extra,
 			}
 
 			icb.sdl.SchMakeMessage(&msgResult, icb.ptnInst, icb.ptnQryMgr, sch.EvDhtQryInstResultInd, &ind)
@@ -681,11 +690,41 @@ func (qryInst *QryInst) protoMsgInd(msg *sch.MsgDhtQryInstProtoMsgInd) sch.SchEr
 				Provider: nil,
 				Value:    nil,
 				Pcs:      gpr.Pcs,
+				Coord:    gpr.Extra,
 			}
 
 			icb.sdl.SchMakeMessage(&msgResult, icb.ptnInst, icb.ptnQryMgr, sch.EvDhtQryInstResultInd, &ind)
 		}
 
+	case sch.EvDhtConInstPutValueAck, sch.EvDhtConInstPutValueBusy:
+
+		//
+		// a busy peer did not store the values, it's not acknowledged, so the
+		// query manager would retry with spare peers.
+		//
+
+		pong, ok := msg.Msg.(*Pong)
+		if !ok {
+			qiLog.Debug("protoMsgInd: mismatched type Pong, " +
+				"sdl: %s, inst: %s",
+				icb.sdlName, icb.name)
+			return sch.SchEnoMismatched
+		}
+
+		ind := sch.MsgDhtQryInstResultInd{
+			From:     pong.From,
+			Target:   icb.target,
+			ForWhat:  sch.EvDhtMgrPutValueReq,
+			Latency:  icb.endTime.Sub(icb.begTime),
+			Peers:    []*config.Node{&icb.to},
+			Provider: nil,
+			Value:    nil,
+			Pcs:      []int{pcsConnYes},
+			Acked:    msg.ForWhat == sch.EvDhtConInstPutValueAck,
+		}
+
+		icb.sdl.SchMakeMessage(&msgResult, icb.ptnInst, icb.ptnQryMgr, sch.EvDhtQryInstResultInd, &ind)
+
 	default:
 		qiLog.Debug("protoMsgInd: mismatched, " +
 			"sdl: %s, inst: %d, ForWhat: %d",
@@ -740,6 +779,7 @@ func (qryInst *QryInst) setupQryPkg() (DhtErrno, *DhtPackage) {
 			From:     *icb.local,
 			To:       icb.to,
 			Provider: &DhtProvider{Key: msg.Key, Nodes: []*config.Node{&msg.Prd}, Extra: nil},
+			Pcs:      []int{pcsConnYes},
 			Id:       icb.qryReq.Seq,
 			Extra:    nil,
 		}
@@ -757,6 +797,9 @@ func (qryInst *QryInst) setupQryPkg() (DhtErrno, *DhtPackage) {
 			Id:     icb.qryReq.Seq,
 			Extra:  nil,
 		}
+		if icb.ackReq {
+			pv.Extra = []byte(PutValueAckReq)
+		}
 
 		dhtMsg.Mid = MID_PUTVALUE
 		dhtMsg.PutValue = &pv
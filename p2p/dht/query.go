@@ -61,22 +61,27 @@ const (
 	qryMgrMaxPendings = 64                                        // max pendings can be held in the list
 	qryMgrMaxActInsts = 8                                         // max concurrent actived instances for one query
 	qryMgrQryExpired  = time.Second * 60                          // duration to get expired for a query
-	qryMgrQryMaxWidth = 64                                        // not the true "width", the max number of peers queryied
-	qryMgrQryMaxDepth = 8                                         // the max depth for a query
 	qryInstExpired    = time.Second * 16                          // duration to get expired for a query instance
 	natMapKeepTime    = nat.MinKeepDuration                       // NAT map keep time
 	natMapRefreshTime = nat.MinKeepDuration - nat.MinRefreshDelta // NAT map refresh time
+	qryMgrCacheKT     = time.Hour * 24                            // duration to keep a record cached next to the holder
+	qryMgrCacheMinKT  = time.Minute * 10                          // records would be kept shorter are not cached
 )
 
 //
 // Query manager configuration
 //
 type qryMgrCfg struct {
-	local          *config.Node  // pointer to local node specification
-	maxPendings    int           // max pendings can be held in the list
-	maxActInsts    int           // max concurrent actived instances for one query
-	qryExpired     time.Duration // duration to get expired for a query
-	qryInstExpired time.Duration // duration to get expired for a query instance
+	local          *config.Node            // pointer to local node specification
+	maxPendings    int                     // max pendings can be held in the list
+	maxActInsts    int                     // max concurrent actived instances for one query
+	qryExpired     time.Duration           // duration to get expired for a query
+	qryInstExpired time.Duration           // duration to get expired for a query instance
+	advertised     bool                    // local is the advertised address, nat mapping not applied
+	vivaldi        bool                    // network coordinate applied to prefer providers
+	replications   []config.DhtReplication // replication of puts by namespace
+	qryMaxWidth    int                     // not the true "width", the max number of peers queryied
+	qryMaxDepth    int                     // the max depth for a query
 }
 
 //
@@ -121,11 +126,17 @@ type qryCtrlBlock struct {
 	qryPending *list.List                          // pending peers to be queried, with type qryPendingInfo
 	qryActived map[config.NodeID]*qryInstCtrlBlock // queries activated
 	qryResult  *list.List                          // list of qryResultNodeInfo type object
-	qryTid     int                                 // query timer identity
+	qryTimers  sch.SchTimerOwner                   // query timers, killed when the block deleted
 	icbSeq     int                                 // query instance control block sequence number
 	rutNtfFlag bool                                // if notification asked for
 	width      int                                 // the current number of peer had been queried
 	depth      int                                 // the current max depth of query
+	repR       int                                 // replication factor for put-value
+	repQ       int                                 // acknowledgements required for put-value
+	repAcked   int                                 // acknowledgements received for put-value
+	repSpares  []*qryPendingInfo                   // candidates to retry with if not acknowledged enough
+	cacheNode  *config.Node                        // the closest peer responsed without value for get-value
+	cacheDist  int                                 // distance from cacheNode to the key
 }
 
 //
@@ -164,6 +175,7 @@ type qryInstCtrlBlock struct {
 	conBegTime time.Time                      // time to start connection
 	conEndTime time.Time                      // time connection established
 	depth      int                            // the current depth of the query instance
+	ackReq     bool                           // storage acknowledgement asked for put-value
 }
 
 //
@@ -184,6 +196,7 @@ type QryMgr struct {
 	natTcpResult bool                           // result about nap mapping for tcp
 	pubTcpIp     net.IP                         // should be same as pubUdpIp
 	pubTcpPort   int                            // public port form nat to be announced for tcp
+	viv          *vivaldi                       // network coordinate, nil if not applied
 }
 
 //
@@ -304,6 +317,9 @@ func (qryMgr *QryMgr) poweron(ptn interface{}) sch.SchErrno {
 		return sch.SchEnoUserTask
 	}
 	mapQrySeqLock[qryMgr.sdl.SchGetP2pCfgName()] = sync.Mutex{}
+	if qryMgr.qmCfg.vivaldi {
+		qryMgr.viv = vivaldiSetup(qryMgr.sdl)
+	}
 	return sch.SchEnoNone
 }
 
@@ -361,6 +377,22 @@ func (qryMgr *QryMgr) queryStartReq(sender interface{}, msg *sch.MsgDhtQryMgrQue
 		ForWhat: forWhat,
 	}
 
+	//
+	// for put-value, the nearest asked for are determined by the replication of
+	// the namespace, with spares to retry with when acknowledgements required.
+	//
+
+	var repR, repQ = 0, 0
+	if forWhat == MID_PUTVALUE {
+		repR, repQ = qryMgr.qryMgrReplication(&msg.Target)
+		if nearestReq.Max = repR; repQ > 0 {
+			nearestReq.Max = 2 * repR
+		}
+		if nearestReq.Max > rutMgrMaxNearestRep {
+			nearestReq.Max = rutMgrMaxNearestRep
+		}
+	}
+
 	if _, dup := qryMgr.qcbTab[msg.Target]; dup {
 		qryLog.Debug("queryStartReq: duplicated target: %x", msg.Target)
 		rsp.Eno = DhtEnoDuplicated.GetEno()
@@ -384,6 +416,12 @@ func (qryMgr *QryMgr) queryStartReq(sender interface{}, msg *sch.MsgDhtQryMgrQue
 	qcb.status = qsPreparing
 	qcb.width = 0
 	qcb.depth = 0
+	qcb.repR = repR
+	qcb.repQ = repQ
+	qcb.repAcked = 0
+	qcb.repSpares = nil
+	qcb.cacheNode = nil
+	qcb.cacheDist = -1
 	qryMgr.qcbTab[msg.Target] = qcb
 
 	qryLog.Debug("queryStartReq: qcb: %+v", *qcb)
@@ -495,6 +533,23 @@ func (qryMgr *QryMgr) rutNearestRsp(msg *sch.MsgDhtRutMgrNearestRsp) sch.SchErrn
 		return sch.SchEnoParameter
 	}
 
+	//
+	// for put-value, those beyond the replication factor are kept as spares
+	//
+
+	if forWhat == MID_PUTVALUE && qcb.repR > 0 && len(peers) > qcb.repR {
+		for idx := qcb.repR; idx < len(peers); idx++ {
+			pi := qryPendingInfo{
+				rutMgrBucketNode: *peers[idx],
+				depth:            0,
+			}
+			qcb.repSpares = append(qcb.repSpares, &pi)
+		}
+		peers = peers[0:qcb.repR]
+		pcs = pcs[0:qcb.repR]
+		dists = dists[0:qcb.repR]
+	}
+
 	//
 	// check if target found in local while updating the query result by the
 	// nearests reported.
@@ -657,7 +712,10 @@ func (qryMgr *QryMgr) instStatusInd(msg *sch.MsgDhtQryInstStatusInd) sch.SchErrn
 		if qcb.qryPending.Len() == 0 && len(qcb.qryActived) == 0 {
 			qryLog.Debug("instStatusInd: query done: %x", qcb.target)
 			if qcb.forWhat == MID_PUTVALUE || qcb.forWhat == MID_PUTPROVIDER {
-				if dhtEno := qryMgr.qryMgrResultReport(qcb, DhtEnoNone.GetEno(), nil, nil, nil); dhtEno != DhtEnoNone {
+				if qryMgr.qryMgrPutRetry(qcb) {
+					return sch.SchEnoNone
+				}
+				if dhtEno := qryMgr.qryMgrResultReport(qcb, qryMgr.qryMgrPutResult(qcb), nil, nil, nil); dhtEno != DhtEnoNone {
 					qryLog.Debug("instStatusInd: qryMgrResultReport failed, dhtEno: %d", dhtEno)
 					return sch.SchEnoUserTask
 				}
@@ -750,6 +808,7 @@ func (qryMgr *QryMgr) instResultInd(msg *sch.MsgDhtQryInstResultInd) sch.SchErrn
 	}
 
 	updateReq2RutMgr(&from, latency)
+	qryMgr.viv.observe(from.ID, msg.Coord, latency)
 
 	target := msg.Target
 	if qcb = qryMgr.qcbTab[target]; qcb == nil {
@@ -764,6 +823,9 @@ func (qryMgr *QryMgr) instResultInd(msg *sch.MsgDhtQryInstResultInd) sch.SchErrn
 	}
 
 	depth := icb.depth
+	if msg.ForWhat == sch.EvDhtMgrPutValueReq && msg.Acked {
+		qcb.repAcked++
+	}
 
 	for idx, peer := range msg.Peers {
 
@@ -795,14 +857,20 @@ func (qryMgr *QryMgr) instResultInd(msg *sch.MsgDhtQryInstResultInd) sch.SchErrn
 		}
 
 	} else if msg.ForWhat == sch.EvDhtConInstGetValRsp {
+		dist := rutMgr.rutMgrLog2Dist((*Hash)(&target), rutMgrNodeId2Hash(from.ID))
 		if msg.Value != nil && len(msg.Value) > 0 {
 			qryMgr.qryMgrResultReport(qcb, DhtEnoNone.GetEno(), nil, msg.Value, nil)
+			qryMgr.qryMgrCacheValue(qcb, msg.Value, dist)
 			if dhtEno := qryMgr.qryMgrDelQcb(delQcb4TargetFound, qcb.target); dhtEno != DhtEnoNone {
 				qryLog.Debug("instResultInd: qryMgrDelQcb failed, eno: %d", dhtEno)
 				return sch.SchEnoUserTask
 			}
 			return sch.SchEnoNone
 		}
+		if qcb.cacheNode == nil || dist > qcb.cacheDist {
+			qcb.cacheNode = &from
+			qcb.cacheDist = dist
+		}
 	} else if msg.ForWhat == sch.EvDhtConInstGetProviderRsp {
 		if msg.Provider != nil {
 			qryMgr.qryMgrResultReport(qcb, DhtEnoNone.GetEno(), nil, nil, msg.Provider)
@@ -826,7 +894,7 @@ func (qryMgr *QryMgr) instResultInd(msg *sch.MsgDhtQryInstResultInd) sch.SchErrn
 	if msg.ForWhat == sch.EvDhtConInstNeighbors ||
 		msg.ForWhat == sch.EvDhtConInstGetProviderRsp ||
 		msg.ForWhat == sch.EvDhtConInstGetValRsp {
-		if qcb.depth > qryMgrQryMaxDepth || len(qcb.qryHistory) >= qryMgrQryMaxWidth {
+		if qcb.depth > qryMgr.qmCfg.qryMaxDepth || len(qcb.qryHistory) >= qryMgr.qmCfg.qryMaxWidth {
 			qryLog.Debug("instResultInd: limited to stop query, depth: %d, width: %d", qcb.depth, len(qcb.qryHistory))
 			if dhtEno := qryMgr.qryMgrResultReport(qcb, DhtEnoNotFound.GetEno(), nil, nil, nil); dhtEno != DhtEnoNone {
 				qryLog.Debug("instResultInd: qryMgrResultReport failed, dhtEno: %d", dhtEno)
@@ -868,7 +936,10 @@ func (qryMgr *QryMgr) instResultInd(msg *sch.MsgDhtQryInstResultInd) sch.SchErrn
 				qryLog.Debug("instResultInd: qryMgrResultReport failed, dhtEno: %d", dhtEno)
 			}
 		} else {
-			if dhtEno := qryMgr.qryMgrResultReport(qcb, DhtEnoNone.GetEno(), nil, nil, nil); dhtEno != DhtEnoNone {
+			if qryMgr.qryMgrPutRetry(qcb) {
+				return sch.SchEnoNone
+			}
+			if dhtEno := qryMgr.qryMgrResultReport(qcb, qryMgr.qryMgrPutResult(qcb), nil, nil, nil); dhtEno != DhtEnoNone {
 				qryLog.Debug("instResultInd: qryMgrResultReport failed, dhtEno: %d", dhtEno)
 			}
 		}
@@ -886,8 +957,8 @@ func (qryMgr *QryMgr) instResultInd(msg *sch.MsgDhtQryInstResultInd) sch.SchErrn
 // nat ready to work
 //
 func (qryMgr *QryMgr) natMgrReadyInd(msg *sch.MsgNatMgrReadyInd) sch.SchErrno {
-	qryLog.Debug("natMgrReadyInd: nat type: %s", msg.NatType)
-	if msg.NatType == config.NATT_NONE {
+	qryLog.Debug("natMgrReadyInd: nat type: %s, advertised: %t", msg.NatType, qryMgr.qmCfg.advertised)
+	if msg.NatType == config.NATT_NONE || qryMgr.qmCfg.advertised {
 		qryMgr.pubTcpIp = qryMgr.qmCfg.local.IP
 		qryMgr.pubTcpPort = int(qryMgr.qmCfg.local.TCP)
 	} else {
@@ -987,15 +1058,143 @@ func (qryMgr *QryMgr) natPubAddrUpdateInd(msg *sch.SchMessage) sch.SchErrno {
 // Get query manager configuration
 //
 func (qryMgr *QryMgr) qryMgrGetConfig() DhtErrno {
-	cfg := config.P2pConfig4DhtQryManager(qryMgr.sdl.SchGetP2pCfgName())
+	cfg := qryMgr.sdl.SchGetTaskConfig(qryMgr.ptnMe).Config4DhtQryManager()
 	qmCfg := &qryMgr.qmCfg
 	qmCfg.local = cfg.Local
 	qmCfg.maxActInsts = cfg.MaxActInsts
 	qmCfg.qryExpired = cfg.QryExpired
 	qmCfg.qryInstExpired = cfg.QryInstExpired
+	qmCfg.advertised = cfg.Advertised
+	qmCfg.vivaldi = cfg.Vivaldi
+	qmCfg.replications = cfg.Replications
+	if qmCfg.qryMaxWidth = cfg.QryMaxWidth; qmCfg.qryMaxWidth <= 0 {
+		qmCfg.qryMaxWidth = config.DftDhtQryMaxWidth
+	}
+	if qmCfg.qryMaxDepth = cfg.QryMaxDepth; qmCfg.qryMaxDepth <= 0 {
+		qmCfg.qryMaxDepth = config.DftDhtQryMaxDepth
+	}
+	return DhtEnoNone
+}
+
+//
+// Get replication factor and quorum for a key, the namespace with the longest
+// prefix matched applied, the nearest set of a query if none matched.
+//
+func (qryMgr *QryMgr) qryMgrReplication(key *config.DsKey) (int, int) {
+	r, q, best := rutMgrMaxNearest, 0, -1
+	for _, rep := range qryMgr.qmCfg.replications {
+		if len(rep.Prefix) > best && bytes.HasPrefix(key[0:], rep.Prefix) {
+			r, q, best = rep.R, rep.Q, len(rep.Prefix)
+		}
+	}
+	if r <= 0 {
+		r = rutMgrMaxNearest
+	} else if r > rutMgrMaxNearestRep {
+		r = rutMgrMaxNearestRep
+	}
+	if q < 0 {
+		q = 0
+	} else if q > r {
+		q = r
+	}
+	return r, q
+}
+
+//
+// Retry put-value with spare candidates when acknowledgements are not enough,
+// true returned if any instance activated.
+//
+func (qryMgr *QryMgr) qryMgrPutRetry(qcb *qryCtrlBlock) bool {
+	if qcb.forWhat != MID_PUTVALUE {
+		return false
+	}
+	for qcb.repAcked < qcb.repQ && len(qcb.repSpares) > 0 && len(qcb.qryActived) == 0 {
+		num := qcb.repQ - qcb.repAcked
+		if num > len(qcb.repSpares) {
+			num = len(qcb.repSpares)
+		}
+		qryLog.Debug("qryMgrPutRetry: target: %x, acked: %d, quorum: %d, retry: %d",
+			qcb.target, qcb.repAcked, qcb.repQ, num)
+		qcb.qryMgrQcbPutPending(qcb.repSpares[0:num], qryMgr.qmCfg.maxPendings)
+		qcb.repSpares = qcb.repSpares[num:]
+		qryMgr.qryMgrQcbPutActived(qcb)
+	}
+	return len(qcb.qryActived) > 0
+}
+
+//
+// Cache the value got at the closest peer which had not it, the time to keep
+// is halved for each bit the peer is farther than the holder from the key.
+// notice: "dist" is the length of the common prefix, the larger the closer.
+//
+func (qryMgr *QryMgr) qryMgrCacheValue(qcb *qryCtrlBlock, val []byte, holderDist int) DhtErrno {
+	if qcb.cacheNode == nil {
+		return DhtEnoNotFound
+	}
+
+	var kt = qryMgrCacheKT
+	if delta := holderDist - qcb.cacheDist; delta > 0 {
+		if delta >= 32 {
+			return DhtEnoNone
+		}
+		kt >>= uint(delta)
+	}
+	if kt < qryMgrCacheMinKT {
+		qryLog.Debug("qryMgrCacheValue: too far to be cached, key: %x, kt: %d", qcb.target, kt)
+		return DhtEnoNone
+	}
+
+	pv := PutValue{
+		From:   *qryMgr.qmCfg.local,
+		To:     *qcb.cacheNode,
+		Values: []DhtValue{{Key: qcb.target[0:], Val: val, Extra: nil}},
+		Id:     qcb.qryReq.Seq,
+		KT:     kt,
+		Extra:  PutValueCacheExtra(kt),
+	}
+	dhtMsg := DhtMessage{
+		Mid:      MID_PUTVALUE,
+		PutValue: &pv,
+	}
+	dhtPkg := DhtPackage{}
+	if eno := dhtMsg.GetPackage(&dhtPkg); eno != DhtEnoNone {
+		qryLog.Debug("qryMgrCacheValue: GetPackage failed, eno: %d", eno)
+		return eno
+	}
+
+	qryLog.Debug("qryMgrCacheValue: key: %x, peer: %x, kt: %d", qcb.target, qcb.cacheNode.ID, kt)
+
+	req := sch.MsgDhtConMgrSendReq{
+		Task:    qryMgr.ptnMe,
+		Peer:    qcb.cacheNode,
+		Data:    &dhtPkg,
+		WaitRsp: false,
+		WaitMid: -1,
+		WaitSeq: -1,
+	}
+	eno, ptnConMgr := qryMgr.sdl.SchGetUserTaskNode(ConMgrName)
+	if eno != sch.SchEnoNone || ptnConMgr == nil {
+		qryLog.Debug("qryMgrCacheValue: connection manager not found, eno: %d", eno)
+		return DhtEnoScheduler
+	}
+	schMsg := sch.SchMessage{}
+	qryMgr.sdl.SchMakeMessage(&schMsg, qryMgr.ptnMe, ptnConMgr, sch.EvDhtConMgrSendReq, &req)
+	qryMgr.sdl.SchSendMessage(&schMsg)
 	return DhtEnoNone
 }
 
+//
+// Result of put-value or put-provider query when it's done
+//
+func (qryMgr *QryMgr) qryMgrPutResult(qcb *qryCtrlBlock) int {
+	if qcb.forWhat == MID_PUTVALUE && qcb.repAcked < qcb.repQ {
+		qryLog.Debug("qryMgrPutResult: target: %x, acked: %d, quorum: %d",
+			qcb.target, qcb.repAcked, qcb.repQ)
+		return DhtEnoReplication.GetEno()
+	}
+	return DhtEnoNone.GetEno()
+}
+
 //
 // Delete query control blcok from manager
 //
@@ -1046,16 +1245,25 @@ func (qryMgr *QryMgr) qryMgrDelQcb(why int, target config.DsKey) DhtErrno {
 		return DhtEnoNotFound
 	}
 
+	qcb.qryTimers.KillTimers()
+
+	// the notifee is registered by the nearest request, which is sent before
+	// the query gets inited, so unregister it whatever the status is.
+	if qcb.rutNtfFlag == true {
+		req := sch.MsgDhtRutMgrStopNofiyReq{
+			Task:   qryMgr.ptnMe,
+			Target: qcb.target,
+		}
+		msg := sch.SchMessage{}
+		qryMgr.sdl.SchMakeMessage(&msg, qryMgr.ptnMe, qryMgr.ptnRutMgr, sch.EvDhtRutMgrStopNotifyReq, &req)
+		qryMgr.sdl.SchSendMessage(&msg)
+	}
+
 	if qcb.status != qsInited {
 		delete(qryMgr.qcbTab, target)
 		return DhtEnoNone
 	}
 
-	if qcb.qryTid != sch.SchInvalidTid {
-		qryMgr.sdl.SchKillTimer(qryMgr.ptnMe, qcb.qryTid)
-		qcb.qryTid = sch.SchInvalidTid
-	}
-
 	for _, icb := range qcb.qryActived {
 		po := sch.SchMessage{}
 		icb.sdl.SchMakeMessage(&po, qryMgr.ptnMe, icb.ptnInst, sch.EvSchPoweroff, nil)
@@ -1063,16 +1271,6 @@ func (qryMgr *QryMgr) qryMgrDelQcb(why int, target config.DsKey) DhtErrno {
 		icb.sdl.SchSendMessage(&po)
 	}
 
-	if qcb.rutNtfFlag == true {
-		req := sch.MsgDhtRutMgrStopNofiyReq{
-			Task:   qryMgr.ptnMe,
-			Target: qcb.target,
-		}
-		msg := sch.SchMessage{}
-		qryMgr.sdl.SchMakeMessage(&msg, qryMgr.ptnMe, qryMgr.ptnRutMgr, sch.EvDhtRutMgrStopNotifyReq, &req)
-		qryMgr.sdl.SchSendMessage(&msg)
-	}
-
 	delete(qryMgr.qcbTab, target)
 	return DhtEnoNone
 }
@@ -1204,7 +1402,7 @@ func (qryMgr *QryMgr) qryMgrQcbPutActived(qcb *qryCtrlBlock) (DhtErrno, int) {
 		act = append(act, el)
 
 		if _, dup := qcb.qryActived[pending.node.ID]; dup == true {
-			qryLog.Debug("qryMgrQcbPutActived: duplicated node: %X", pending.node.ID)
+			qryLog.Debug("qryMgrQcbPutActived: duplicated node: %s", config.P2pNodeId2String(pending.node.ID))
 			continue
 		}
 
@@ -1230,6 +1428,7 @@ func (qryMgr *QryMgr) qryMgrQcbPutActived(qcb *qryCtrlBlock) (DhtErrno, int) {
 			conBegTime: time.Time{},
 			conEndTime: time.Time{},
 			depth:      pending.depth,
+			ackReq:     qcb.forWhat == MID_PUTVALUE && qcb.repQ > 0,
 		}
 
 		qryLog.Debug("qryMgrQcbPutActived: ForWhat: %d", icb.qryReq.ForWhat)
@@ -1296,13 +1495,10 @@ func (qryMgr *QryMgr) qryMgrQcbStartTimer(qcb *qryCtrlBlock) DhtErrno {
 		Dur:   qryMgr.qmCfg.qryExpired,
 		Extra: qcb,
 	}
-	tid := sch.SchInvalidTid
-	eno := sch.SchEnoUnknown
-	if eno, tid = qryMgr.sdl.SchSetTimer(qryMgr.ptnMe, &td); eno != sch.SchEnoNone || tid == sch.SchInvalidTid {
-		qryLog.Debug("qryMgrQcbStartTimer: SchSetTimer failed, eno: %d", eno)
+	if eno, _ := qcb.qryTimers.SetTimer(qryMgr.sdl, qryMgr.ptnMe, &td); eno != sch.SchEnoNone {
+		qryLog.Debug("qryMgrQcbStartTimer: SetTimer failed, eno: %d", eno)
 		return DhtEnoScheduler
 	}
-	qcb.qryTid = tid
 	return DhtEnoNone
 }
 
@@ -1310,6 +1506,10 @@ func (qryMgr *QryMgr) qryMgrQcbStartTimer(qcb *qryCtrlBlock) DhtErrno {
 // Query control block timer handler
 //
 func (qryMgr *QryMgr) qcbTimerHandler(qcb *qryCtrlBlock) sch.SchErrno {
+	if qryMgr.qcbTab[qcb.target] != qcb {
+		qryLog.Debug("qcbTimerHandler: stale query control block, target: %x", qcb.target)
+		return sch.SchEnoMismatched
+	}
 	qryMgr.qryMgrResultReport(qcb, DhtEnoTimeout.GetEno(), nil, nil, nil)
 	qryMgr.qryMgrDelQcb(delQcb4Timeout, qcb.target)
 	return sch.SchEnoNone
@@ -1359,7 +1559,7 @@ func (qryMgr *QryMgr) qryMgrResultReport(
 	}
 
 	if prd != nil {
-		ind.Prds = prd.Nodes
+		ind.Prds = qryMgr.viv.sortNodes(prd.Nodes)
 	}
 
 	li := qcb.qryResult
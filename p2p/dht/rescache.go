@@ -0,0 +1,104 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"time"
+)
+
+//
+// Result cache: results of find-node and get-value succeeded are kept for a
+// while in the dht manager, so requests repeated for the same target or key are
+// answered at once without querying the network again. a value cached is
+// invalidated when a value is put for the same key.
+//
+
+const (
+	dhtCacheTTL        = time.Second * 30 // duration a result is kept
+	dhtCacheMaxEntries = 256              // max results cached
+)
+
+type dhtCacheKey struct {
+	forWhat int   // MID_FINDNODE or MID_GETVALUE_REQ
+	key     DsKey // target or key
+}
+
+type dhtCacheEntry struct {
+	result  interface{} // *sch.MsgDhtQryMgrQueryResultInd or *sch.MsgDhtMgrGetValueRsp
+	expired time.Time   // time to be expired
+}
+
+type dhtResultCache struct {
+	entries map[dhtCacheKey]*dhtCacheEntry // results cached
+}
+
+func newDhtResultCache() *dhtResultCache {
+	return &dhtResultCache{
+		entries: make(map[dhtCacheKey]*dhtCacheEntry, 0),
+	}
+}
+
+// Get result cached, nil if not found or expired
+func (rc *dhtResultCache) get(forWhat int, k *DsKey, now time.Time) interface{} {
+	ck := dhtCacheKey{forWhat: forWhat, key: *k}
+	ce, ok := rc.entries[ck]
+	if !ok {
+		return nil
+	}
+	if !now.Before(ce.expired) {
+		delete(rc.entries, ck)
+		return nil
+	}
+	return ce.result
+}
+
+// Cache result, it's not cached if the cache is full with results not expired.
+// a result already cached keeps its expiry, so those answered from the cache
+// would not keep it alive.
+func (rc *dhtResultCache) put(forWhat int, k *DsKey, result interface{}, now time.Time) {
+	ck := dhtCacheKey{forWhat: forWhat, key: *k}
+	if ce, ok := rc.entries[ck]; ok && now.Before(ce.expired) {
+		ce.result = result
+		return
+	}
+	if _, ok := rc.entries[ck]; !ok && len(rc.entries) >= dhtCacheMaxEntries {
+		rc.purge(now)
+		if len(rc.entries) >= dhtCacheMaxEntries {
+			return
+		}
+	}
+	rc.entries[ck] = &dhtCacheEntry{
+		result:  result,
+		expired: now.Add(dhtCacheTTL),
+	}
+}
+
+func (rc *dhtResultCache) invalidate(forWhat int, k *DsKey) {
+	delete(rc.entries, dhtCacheKey{forWhat: forWhat, key: *k})
+}
+
+func (rc *dhtResultCache) purge(now time.Time) {
+	for ck, ce := range rc.entries {
+		if !now.Before(ce.expired) {
+			delete(rc.entries, ck)
+		}
+	}
+}
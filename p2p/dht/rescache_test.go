@@ -0,0 +1,70 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDhtResultCache(t *testing.T) {
+	rc := newDhtResultCache()
+	now := time.Now()
+	k := DsKey{1}
+	rc.put(MID_GETVALUE_REQ, &k, "v1", now)
+	if rc.get(MID_FINDNODE, &k, now) != nil {
+		t.Fatalf("got for other query")
+	}
+	if v := rc.get(MID_GETVALUE_REQ, &k, now); v != "v1" {
+		t.Fatalf("got %v", v)
+	}
+
+	// put again, the result replaced but not kept alive
+	rc.put(MID_GETVALUE_REQ, &k, "v2", now.Add(dhtCacheTTL/2))
+	if v := rc.get(MID_GETVALUE_REQ, &k, now.Add(dhtCacheTTL/2)); v != "v2" {
+		t.Fatalf("got %v", v)
+	}
+	if v := rc.get(MID_GETVALUE_REQ, &k, now.Add(dhtCacheTTL)); v != nil {
+		t.Fatalf("got %v after expired", v)
+	}
+
+	// invalidated
+	rc.put(MID_GETVALUE_REQ, &k, "v3", now)
+	rc.invalidate(MID_GETVALUE_REQ, &k)
+	if v := rc.get(MID_GETVALUE_REQ, &k, now); v != nil {
+		t.Fatalf("got %v after invalidated", v)
+	}
+
+	// full, those expired purged for new ones
+	for i := 0; i < dhtCacheMaxEntries; i++ {
+		k := DsKey{byte(i), byte(i >> 8), 0xff}
+		rc.put(MID_FINDNODE, &k, i, now)
+	}
+	rc.put(MID_FINDNODE, &k, "full", now)
+	if v := rc.get(MID_FINDNODE, &k, now); v != nil {
+		t.Fatalf("cached when full")
+	}
+	later := now.Add(dhtCacheTTL)
+	rc.put(MID_FINDNODE, &k, "purged", later)
+	if v := rc.get(MID_FINDNODE, &k, later); v != "purged" || len(rc.entries) != 1 {
+		t.Fatalf("got %v, entries: %d", v, len(rc.entries))
+	}
+}
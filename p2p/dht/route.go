@@ -25,12 +25,12 @@ import (
 	"time"
 	"fmt"
 	"container/list"
-	"crypto/rand"
 	"crypto/sha256"
 	golog "log"
-	mrand "math/rand"
+	"sync/atomic"
 
 	config "github.com/yeeco/gyee/p2p/config"
+	identity "github.com/yeeco/gyee/p2p/identity"
 	p2plog "github.com/yeeco/gyee/p2p/logger"
 	sch "github.com/yeeco/gyee/p2p/scheduler"
 )
@@ -58,11 +58,11 @@ func (log rutMgrLogger) Debug(fmt string, args ...interface{}) {
 const (
 	RutMgrName             = sch.DhtRutMgrName   // Route manager name registered in scheduler
 	rutMgrMaxNearest       = 8                   // Max nearest peers can be retrieved for a time
-	rutMgrBucketSize       = 32                  // bucket size
+	rutMgrMaxNearestRep    = 64                  // Max nearest peers can be retrieved for replications
 	HashByteLength         = config.DhtKeyLength // 32 bytes(256 bits) hash applied
 	HashBitLength          = HashByteLength * 8  // hash bits
-	rutMgrMaxLatency       = time.Second * 60    // max latency in metric
 	rutMgrMaxNofifee       = 128                 // max notifees could be
+	rutMgrNotifeeTTL       = time.Minute * 3     // notifee expired if not unregistered
 	rutMgrUpdate4Handshake = 0                   // update for handshaking
 	rutMgrUpdate4Closed    = 1                   // update for connection instance closed
 	rutMgrUpdate4Query     = 2                   // update for query result
@@ -122,6 +122,16 @@ type rutMgrNotifee struct {
 	max      int                 // max nearest asked for
 	nearests []*rutMgrBucketNode // nearest peers
 	dists    []int               // distances of nearest peers
+	expired  time.Time           // time to be expired
+}
+
+//
+// Notifee statistics, for introspection
+//
+type RutMgrNotifeeStats struct {
+	Active   int64 // notifees registered currently
+	Expired  int64 // notifees removed for expired
+	Rejected int64 // registrations rejected for too much notifees
 }
 
 //
@@ -143,6 +153,8 @@ type RutMgr struct {
 	localNodeId   config.NodeID                      // local node identity
 	rutTab        rutMgrRouteTable                   // route table
 	ntfTab        map[rutMgrNotifeeId]*rutMgrNotifee // notifee table
+	ntfStats      RutMgrNotifeeStats                 // notifee statistics, accessed atomically
+	idReg         *identity.Registry                 // peers known by both chain and dht stacks
 }
 
 //
@@ -274,6 +286,7 @@ func (rutMgr *RutMgr) poweron(ptn interface{}) sch.SchErrno {
 	rutMgr.ptnMe = ptn
 	rutMgr.sdl = sch.SchGetScheduler(ptn)
 	rutMgr.sdlName = rutMgr.sdl.SchGetP2pNodeName()
+	rutMgr.idReg = identity.Of(rutMgr.sdl.SchGetP2pCfgName())
 
 	eno, rutMgr.ptnQryMgr = rutMgr.sdl.SchGetUserTaskNode(QryMgrName)
 	if eno != sch.SchEnoNone || rutMgr.ptnQryMgr == nil {
@@ -332,6 +345,8 @@ func (rutMgr *RutMgr) bootstarpTimerHandler() sch.SchErrno {
 
 	rutLog.Debug("bootstarpTimerHandler: bootstrap will be carried out ...")
 
+	rutMgr.rutMgrNotifeeCleanup(time.Now())
+
 	if len(rutMgr.bsTargets) != 0 {
 		rutLog.Debug("bootstarpTimerHandler: the previous is not completed")
 		return sch.SchEnoNone
@@ -341,7 +356,7 @@ func (rutMgr *RutMgr) bootstarpTimerHandler() sch.SchErrno {
 
 	for loop := 0; loop < rutMgr.bpCfg.randomQryNum; loop++ {
 		msg := sch.SchMessage{}
-		target := rutMgrRandomPeerId()
+		target := rutMgrRandomPeerId(sdl)
 		key := (*DsKey)(rutMgrNodeId2Hash(target))
 		req := sch.MsgDhtQryMgrQueryStartReq{
 			Target:  *key,
@@ -481,7 +496,7 @@ func (rutMgr *RutMgr) nearestReq(tskSender interface{}, req *sch.MsgDhtRutMgrNea
 			return rutMgr.sdl.SchSendMessage(&schMsg)
 		}
 
-		idx := mrand.Int31n(int32(len(bsns)))
+		idx := rutMgr.sdl.SchRandInt31n(int32(len(bsns)))
 		node := bsns[idx]
 		hash := rutMgrNodeId2Hash(node.ID)
 		bn := rutMgrBucketNode{
@@ -746,6 +761,7 @@ func (rutMgr *RutMgr) stopNotifyReq(req *sch.MsgDhtRutMgrStopNofiyReq) sch.SchEr
 		return sch.SchEnoUserTask
 	}
 	delete(rutMgr.ntfTab, nfi)
+	atomic.StoreInt64(&rutMgr.ntfStats.Active, int64(len(rutMgr.ntfTab)))
 	return sch.SchEnoNone
 }
 
@@ -760,12 +776,17 @@ func (rutMgr *RutMgr) conMgrBootstrapReq() sch.SchErrno {
 // Get route manager configuration
 //
 func (rutMgr *RutMgr) rutMgrGetRouteConfig() DhtErrno {
-	cfgName := rutMgr.sdl.SchGetP2pCfgName()
-	rutCfg := config.P2pConfig4DhtRouteManager(cfgName)
+	rutCfg := rutMgr.sdl.SchGetTaskConfig(rutMgr.ptnMe).Config4DhtRouteManager()
 	rutMgr.bootstrapNode = rutCfg.BootstrapNode
 	rutMgr.localNodeId = rutCfg.NodeId
 	rutMgr.bpCfg.randomQryNum = rutCfg.RandomQryNum
 	rutMgr.bpCfg.period = rutCfg.Period
+	if rutMgr.rutTab.bucketSize = rutCfg.BucketSize; rutMgr.rutTab.bucketSize <= 0 {
+		rutMgr.rutTab.bucketSize = config.DftDhtBucketSize
+	}
+	if rutMgr.rutTab.maxLatency = rutCfg.MaxLatency; rutMgr.rutTab.maxLatency <= 0 {
+		rutMgr.rutTab.maxLatency = config.DftDhtMaxLatency
+	}
 	return DhtEnoNone
 }
 
@@ -816,19 +837,20 @@ func (rutMgr *RutMgr) rutMgrStopBspTimer() DhtErrno {
 }
 
 //
-// Build random node identity
+// Build random node identity, it's a target to refresh the route table and
+// needs not be secure, so the random source of scheduler is applied.
 //
-func rutMgrRandomPeerId() config.NodeID {
+func rutMgrRandomPeerId(sdl *sch.Scheduler) config.NodeID {
 	var nid config.NodeID
-	rand.Read(nid[:])
+	sdl.SchRandRead(nid[:])
 	return nid
 }
 
 //
 // Build hash from random node identity
 //
-func rutMgrRandomHashPeerId() *Hash {
-	return rutMgrNodeId2Hash(rutMgrRandomPeerId())
+func rutMgrRandomHashPeerId(sdl *sch.Scheduler) *Hash {
+	return rutMgrNodeId2Hash(rutMgrRandomPeerId(sdl))
 }
 
 //
@@ -871,8 +893,6 @@ func (rutMgr *RutMgr) rutMgrLog2Dist(h1 *Hash, h2 *Hash) int {
 func (rutMgr *RutMgr) rutMgrSetupRouteTable() DhtErrno {
 	rt := &rutMgr.rutTab
 	rt.shaLocal = *rutMgrNodeId2Hash(rutMgr.localNodeId)
-	rt.bucketSize = rutMgrBucketSize
-	rt.maxLatency = rutMgrMaxLatency
 	rt.bucketTab = make([]*list.List, 0, HashBitLength+1)
 	rt.bucketTab = append(rt.bucketTab, list.New())
 	rt.metricTab = make(map[config.NodeID]*rutMgrPeerMetric, 0)
@@ -885,6 +905,7 @@ func (rutMgr *RutMgr) rutMgrSetupRouteTable() DhtErrno {
 func (rutMgr *RutMgr) rutMgrMetricSample(id config.NodeID, latency time.Duration) DhtErrno {
 
 	rt := &rutMgr.rutTab
+	rutMgr.idReg.Observe(id, latency)
 
 	if m, dup := rt.metricTab[id]; dup {
 		num := len(m.ltnSamples)
@@ -1098,7 +1119,8 @@ func (rutMgr *RutMgr) update(bn *rutMgrBucketNode, dist int) DhtErrno {
 	}
 
 	if eno == DhtEnoNotFound {
-		ewma = 0
+		// no sample by dht yet, take that of the chain stack if any
+		ewma, _ = rutMgr.idReg.Latency(bn.node.ID)
 	}
 
 	if ewma > rt.maxLatency {
@@ -1211,28 +1233,60 @@ func (rutMgr *RutMgr) rutMgrNotifeeReg(
 	bns []*rutMgrBucketNode,
 	ds []int) DhtErrno {
 
-	if len(rutMgr.ntfTab) >= rutMgrMaxNofifee {
-		rutLog.Debug("rutMgrNotifeeReg: too much notifees, max: %d", rutMgrMaxNofifee)
-		return DhtEnoResource
-	}
+	now := time.Now()
+	rutMgr.rutMgrNotifeeCleanup(now)
 
 	nid := rutMgrNotifeeId{
 		task:   task,
 		target: *id,
 	}
 
+	if _, dup := rutMgr.ntfTab[nid]; !dup && len(rutMgr.ntfTab) >= rutMgrMaxNofifee {
+		rutLog.Debug("rutMgrNotifeeReg: too much notifees, max: %d", rutMgrMaxNofifee)
+		atomic.AddInt64(&rutMgr.ntfStats.Rejected, 1)
+		return DhtEnoResource
+	}
+
 	ntfe := rutMgrNotifee{
 		id:       nid,
 		max:      max,
 		nearests: bns,
 		dists:    ds,
+		expired:  now.Add(rutMgrNotifeeTTL),
 	}
 
 	rutMgr.ntfTab[nid] = &ntfe
+	atomic.StoreInt64(&rutMgr.ntfStats.Active, int64(len(rutMgr.ntfTab)))
 
 	return DhtEnoNone
 }
 
+//
+// Remove notifees expired, those not unregistered by their owners for some
+// reasons, so they would not be accumulated to block registrations.
+//
+func (rutMgr *RutMgr) rutMgrNotifeeCleanup(now time.Time) {
+	for nid, ntf := range rutMgr.ntfTab {
+		if now.After(ntf.expired) {
+			rutLog.Debug("rutMgrNotifeeCleanup: expired, task: %p, target: %x", nid.task, nid.target)
+			delete(rutMgr.ntfTab, nid)
+			atomic.AddInt64(&rutMgr.ntfStats.Expired, 1)
+		}
+	}
+	atomic.StoreInt64(&rutMgr.ntfStats.Active, int64(len(rutMgr.ntfTab)))
+}
+
+//
+// Get notifee statistics, could be called out of the route manager task
+//
+func (rutMgr *RutMgr) NotifeeStats() RutMgrNotifeeStats {
+	return RutMgrNotifeeStats{
+		Active:   atomic.LoadInt64(&rutMgr.ntfStats.Active),
+		Expired:  atomic.LoadInt64(&rutMgr.ntfStats.Expired),
+		Rejected: atomic.LoadInt64(&rutMgr.ntfStats.Rejected),
+	}
+}
+
 //
 // Notify those tasks whom registered with notifees
 //
@@ -1324,10 +1378,10 @@ func (rutMgr *RutMgr) rutMgrNearest(target *config.DsKey, size int) (DhtErrno, [
 		return count
 	}
 
-	if size <= 0 || size > rutMgrMaxNearest {
+	if size <= 0 || size > rutMgrMaxNearestRep {
 		rutLog.Debug("rutMgrNearest: " +
 			"invalid size: %d, min: 1, max: %d",
-			size, rutMgrMaxNearest)
+			size, rutMgrMaxNearestRep)
 
 		dhtEno = DhtEnoParameter
 		goto _done
@@ -0,0 +1,65 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+func TestRutMgrNotifeeTeardown(t *testing.T) {
+	rutMgr := NewRutMgr()
+	task := new(int)
+	for i := 0; i < rutMgrMaxNofifee; i++ {
+		key := config.DsKey{byte(i), byte(i >> 8)}
+		if eno := rutMgr.rutMgrNotifeeReg(task, &key, rutMgrMaxNearest, nil, nil); eno != DhtEnoNone {
+			t.Fatalf("register %d failed, eno: %d", i, eno)
+		}
+	}
+
+	// full, but registering again for the same target refreshes
+	key := config.DsKey{0xff, 0xff}
+	if eno := rutMgr.rutMgrNotifeeReg(task, &key, rutMgrMaxNearest, nil, nil); eno != DhtEnoResource {
+		t.Fatalf("registered when full, eno: %d", eno)
+	}
+	if eno := rutMgr.rutMgrNotifeeReg(task, &config.DsKey{}, rutMgrMaxNearest, nil, nil); eno != DhtEnoNone {
+		t.Fatalf("refresh failed, eno: %d", eno)
+	}
+
+	// unregistered explicitly
+	if eno := rutMgr.stopNotifyReq(&sch.MsgDhtRutMgrStopNofiyReq{Task: task, Target: config.DsKey{}}); eno != sch.SchEnoNone {
+		t.Fatalf("stop notify failed, eno: %d", eno)
+	}
+	if eno := rutMgr.rutMgrNotifeeReg(task, &key, rutMgrMaxNearest, nil, nil); eno != DhtEnoNone {
+		t.Fatalf("register after unregistered failed, eno: %d", eno)
+	}
+
+	// all expired by the cleanup
+	rutMgr.rutMgrNotifeeCleanup(time.Now().Add(rutMgrNotifeeTTL + time.Second))
+	stats := rutMgr.NotifeeStats()
+	want := RutMgrNotifeeStats{Active: 0, Expired: rutMgrMaxNofifee, Rejected: 1}
+	if stats != want {
+		t.Fatalf("stats %+v, want %+v", stats, want)
+	}
+}
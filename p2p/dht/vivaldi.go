@@ -0,0 +1,283 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	p2plog "github.com/yeeco/gyee/p2p/logger"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+//
+// debug
+//
+type vivLogger struct {
+	debug__ bool
+}
+
+var vivLog = vivLogger{
+	debug__: false,
+}
+
+func (log vivLogger) Debug(fmt string, args ...interface{}) {
+	if log.debug__ {
+		p2plog.Debug(fmt, args...)
+	}
+}
+
+//
+// Vivaldi network coordinate: the latency between two nodes is predicted by the
+// euclidean distance of their coordinates plus the heights, which stand for the
+// access links. the local coordinate is adjusted by each round trip sampled from
+// a query, against the coordinate the peer piggybacked in its response(in the
+// "Extra" field), so the retriever can prefer providers with low predicted latency.
+// see: "Vivaldi: A Decentralized Network Coordinate System", SIGCOMM'04.
+//
+const (
+	vivDimension  = 4                      // dimension of the euclidean part
+	vivVersion    = 1                      // version of the encoded coordinate
+	vivCodedSize  = 1 + 8*(vivDimension+2) // version, vector, height, error
+	vivCc         = 0.25                   // tuning factor for coordinate movement
+	vivCe         = 0.25                   // tuning factor for error estimation
+	vivMaxError   = 1.5                    // max error, initial value
+	vivMinHeight  = 1.0e-2                 // min height, in millisecond
+	vivZeroThresh = 1.0e-6                 // threshold taken two points as same
+	vivMaxRtt     = 10 * time.Second       // samples beyond are ignored
+	vivMaxPeers   = 1024                   // max peer coordinates cached
+)
+
+type vivCoord struct {
+	vec    [vivDimension]float64 // euclidean part, in millisecond
+	height float64               // height, in millisecond
+	err    float64               // estimated relative error
+}
+
+type vivaldi struct {
+	lock  sync.Mutex                  // lock, shared by tasks of the dht instance
+	sdl   *sch.Scheduler              // scheduler, for its random source
+	local vivCoord                    // local coordinate
+	peers map[config.NodeID]*vivCoord // coordinates learnt from peers
+}
+
+//
+// Vivaldi instances of dht, one for each p2p configuration, created by the query
+// manager when it's enabled by configuration.
+//
+var vivMapLock sync.Mutex
+var vivMap = make(map[string]*vivaldi, 0)
+
+func vivaldiSetup(sdl *sch.Scheduler) *vivaldi {
+	name := sdl.SchGetP2pCfgName()
+	vivMapLock.Lock()
+	defer vivMapLock.Unlock()
+	if viv, ok := vivMap[name]; ok {
+		return viv
+	}
+	viv := &vivaldi{
+		sdl:   sdl,
+		local: vivCoord{height: vivMinHeight, err: vivMaxError},
+		peers: make(map[config.NodeID]*vivCoord, 0),
+	}
+	vivMap[name] = viv
+	return viv
+}
+
+func vivaldiOf(name string) *vivaldi {
+	vivMapLock.Lock()
+	defer vivMapLock.Unlock()
+	return vivMap[name]
+}
+
+//
+// Encode the local coordinate to be piggybacked, nil if not enabled
+//
+func (viv *vivaldi) localBytes() []byte {
+	if viv == nil {
+		return nil
+	}
+	viv.lock.Lock()
+	defer viv.lock.Unlock()
+	return viv.local.encode()
+}
+
+//
+// Update with a round trip sampled to peer and the coordinate it piggybacked
+//
+func (viv *vivaldi) observe(id config.NodeID, coded []byte, rtt time.Duration) {
+	if viv == nil || rtt <= 0 || rtt > vivMaxRtt {
+		return
+	}
+	remote, ok := vivDecode(coded)
+	if !ok {
+		vivLog.Debug("observe: invalid coordinate from: %x", id)
+		return
+	}
+	viv.lock.Lock()
+	defer viv.lock.Unlock()
+	viv.local.update(remote, float64(rtt)/float64(time.Millisecond), viv.sdl.SchRandFloat64)
+	if _, dup := viv.peers[id]; !dup && len(viv.peers) >= vivMaxPeers {
+		for k := range viv.peers {
+			delete(viv.peers, k)
+			break
+		}
+	}
+	viv.peers[id] = remote
+}
+
+//
+// Predict latency to peer, false if none of coordinate known for it
+//
+func (viv *vivaldi) predict(id config.NodeID) (time.Duration, bool) {
+	if viv == nil {
+		return 0, false
+	}
+	viv.lock.Lock()
+	defer viv.lock.Unlock()
+	remote, ok := viv.peers[id]
+	if !ok {
+		return 0, false
+	}
+	return time.Duration(viv.local.distance(remote) * float64(time.Millisecond)), true
+}
+
+//
+// Sort nodes by predicted latency, those unknown are kept behind in order
+//
+func (viv *vivaldi) sortNodes(nodes []*config.Node) []*config.Node {
+	if viv == nil || len(nodes) <= 1 {
+		return nodes
+	}
+	type predicted struct {
+		node  *config.Node
+		dur   time.Duration
+		known bool
+	}
+	pds := make([]predicted, len(nodes))
+	for idx, n := range nodes {
+		pds[idx].node = n
+		pds[idx].dur, pds[idx].known = viv.predict(n.ID)
+	}
+	sort.SliceStable(pds, func(i, j int) bool {
+		if pds[i].known != pds[j].known {
+			return pds[i].known
+		}
+		return pds[i].known && pds[i].dur < pds[j].dur
+	})
+	sorted := make([]*config.Node, len(nodes))
+	for idx := range pds {
+		sorted[idx] = pds[idx].node
+	}
+	return sorted
+}
+
+func (c *vivCoord) distance(r *vivCoord) float64 {
+	sum := 0.0
+	for i := 0; i < vivDimension; i++ {
+		d := c.vec[i] - r.vec[i]
+		sum += d * d
+	}
+	return math.Sqrt(sum) + c.height + r.height
+}
+
+func (c *vivCoord) update(r *vivCoord, rtt float64, rnd func() float64) {
+	dist := c.distance(r)
+	w := c.err / (c.err + r.err)
+	es := math.Abs(dist-rtt) / rtt
+	c.err = math.Min(es*vivCe*w+c.err*(1.0-vivCe*w), vivMaxError)
+
+	force := vivCc * w * (rtt - dist)
+	var unit [vivDimension]float64
+	mag := 0.0
+	for i := 0; i < vivDimension; i++ {
+		unit[i] = c.vec[i] - r.vec[i]
+		mag += unit[i] * unit[i]
+	}
+	mag = math.Sqrt(mag)
+	if mag > vivZeroThresh {
+		for i := 0; i < vivDimension; i++ {
+			c.vec[i] += force * unit[i] / mag
+		}
+		c.height = math.Max(c.height+force*(c.height+r.height)/dist, vivMinHeight)
+		return
+	}
+
+	// same point, push away in a random direction
+	mag = 0.0
+	for i := 0; i < vivDimension; i++ {
+		unit[i] = rnd() - 0.5
+		mag += unit[i] * unit[i]
+	}
+	mag = math.Sqrt(mag)
+	for i := 0; i < vivDimension; i++ {
+		c.vec[i] += force * unit[i] / mag
+	}
+}
+
+func (c *vivCoord) encode() []byte {
+	buf := make([]byte, vivCodedSize)
+	buf[0] = vivVersion
+	off := 1
+	put := func(f float64) {
+		binary.BigEndian.PutUint64(buf[off:], math.Float64bits(f))
+		off += 8
+	}
+	for i := 0; i < vivDimension; i++ {
+		put(c.vec[i])
+	}
+	put(c.height)
+	put(c.err)
+	return buf
+}
+
+func vivDecode(buf []byte) (*vivCoord, bool) {
+	if len(buf) != vivCodedSize || buf[0] != vivVersion {
+		return nil, false
+	}
+	off := 1
+	get := func() float64 {
+		f := math.Float64frombits(binary.BigEndian.Uint64(buf[off:]))
+		off += 8
+		return f
+	}
+	c := new(vivCoord)
+	for i := 0; i < vivDimension; i++ {
+		c.vec[i] = get()
+	}
+	c.height = get()
+	c.err = get()
+	valid := func(f float64) bool { return !math.IsNaN(f) && !math.IsInf(f, 0) }
+	for i := 0; i < vivDimension; i++ {
+		if !valid(c.vec[i]) {
+			return nil, false
+		}
+	}
+	if !valid(c.height) || !valid(c.err) || c.height < 0 || c.err <= 0 {
+		return nil, false
+	}
+	c.err = math.Min(c.err, vivMaxError)
+	return c, true
+}
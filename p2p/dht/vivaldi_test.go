@@ -0,0 +1,141 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package dht
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestVivaldiConverge(t *testing.T) {
+	// nodes on a plane with access links, the latency is the distance between
+	// them plus both heights.
+	const nodes = 16
+	rnd := rand.New(rand.NewSource(1))
+	type point struct{ x, y, h float64 }
+	pts := make([]point, nodes)
+	for i := range pts {
+		pts[i] = point{rnd.Float64() * 100, rnd.Float64() * 100, 1 + rnd.Float64()*10}
+	}
+	rtt := func(i, j int) float64 {
+		return math.Hypot(pts[i].x-pts[j].x, pts[i].y-pts[j].y) + pts[i].h + pts[j].h
+	}
+
+	coords := make([]vivCoord, nodes)
+	for i := range coords {
+		coords[i] = vivCoord{height: vivMinHeight, err: vivMaxError}
+	}
+	for round := 0; round < 20000; round++ {
+		i, j := rnd.Intn(nodes), rnd.Intn(nodes)
+		if i == j {
+			continue
+		}
+		remote := coords[j]
+		coords[i].update(&remote, rtt(i, j), rnd.Float64)
+	}
+
+	errs := make([]float64, 0, nodes*nodes)
+	for i := 0; i < nodes; i++ {
+		for j := i + 1; j < nodes; j++ {
+			errs = append(errs, math.Abs(coords[i].distance(&coords[j])-rtt(i, j))/rtt(i, j))
+		}
+		if coords[i].err >= vivMaxError/2 {
+			t.Errorf("node %d: error estimation not decreased: %f", i, coords[i].err)
+		}
+	}
+	sort.Float64s(errs)
+	if median := errs[len(errs)/2]; median > 0.05 {
+		t.Errorf("median relative error: %f", median)
+	}
+	if p90 := errs[len(errs)*9/10]; p90 > 0.15 {
+		t.Errorf("90th percentile relative error: %f", p90)
+	}
+}
+
+func TestVivaldiCoding(t *testing.T) {
+	c := vivCoord{vec: [vivDimension]float64{1.5, -2, 0, 1e6}, height: 3.25, err: 0.5}
+	buf := c.encode()
+	if len(buf) != vivCodedSize || buf[0] != vivVersion {
+		t.Fatalf("encoded got %x", buf)
+	}
+	got, ok := vivDecode(buf)
+	if !ok || *got != c {
+		t.Fatalf("decoded got %+v, %t", got, ok)
+	}
+
+	// the error is clamped
+	c.err = vivMaxError * 2
+	if got, ok = vivDecode(c.encode()); !ok || got.err != vivMaxError {
+		t.Errorf("error not clamped: %+v", got)
+	}
+
+	bad := map[string][]byte{
+		"empty":     nil,
+		"truncated": buf[:vivCodedSize-1],
+		"oversized": append(append([]byte{}, buf...), 0),
+		"version":   append([]byte{vivVersion + 1}, buf[1:]...),
+	}
+	for name, coord := range map[string]vivCoord{
+		"nan":             {vec: [vivDimension]float64{math.NaN()}, height: 1, err: 1},
+		"inf":             {height: math.Inf(1), err: 1},
+		"negative height": {height: -1, err: 1},
+		"zero error":      {height: 1, err: 0},
+	} {
+		bad[name] = coord.encode()
+	}
+	for name, buf := range bad {
+		if _, ok := vivDecode(buf); ok {
+			t.Errorf("%s: decoded", name)
+		}
+	}
+}
+
+func TestVivaldiSortNodes(t *testing.T) {
+	viv := &vivaldi{
+		local: vivCoord{height: vivMinHeight, err: vivMaxError},
+		peers: make(map[config.NodeID]*vivCoord, 0),
+	}
+	near, far, unknown := &config.Node{}, &config.Node{}, &config.Node{}
+	near.ID[0], far.ID[0], unknown.ID[0] = 1, 2, 3
+
+	viv.observe(near.ID, (&vivCoord{vec: [vivDimension]float64{10}, height: 1, err: 1}).encode(), 5*time.Millisecond)
+	viv.observe(far.ID, (&vivCoord{vec: [vivDimension]float64{-100}, height: 1, err: 1}).encode(), 200*time.Millisecond)
+	viv.observe(far.ID, []byte{0}, 10*time.Millisecond)
+	viv.observe(unknown.ID, (&vivCoord{height: 1, err: 1}).encode(), vivMaxRtt+1)
+
+	if _, ok := viv.predict(unknown.ID); ok {
+		t.Errorf("predicted with sample ignored")
+	}
+	dn, _ := viv.predict(near.ID)
+	df, _ := viv.predict(far.ID)
+	if dn >= df {
+		t.Errorf("predicted near: %s, far: %s", dn, df)
+	}
+	sorted := viv.sortNodes([]*config.Node{unknown, far, near})
+	if sorted[0] != near || sorted[1] != far || sorted[2] != unknown {
+		t.Errorf("sorted got %x %x %x", sorted[0].ID[0], sorted[1].ID[0], sorted[2].ID[0])
+	}
+}
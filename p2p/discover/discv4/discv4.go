@@ -0,0 +1,692 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package discv4
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/yeeco/gyee/crypto/secp256k1"
+	config "github.com/yeeco/gyee/p2p/config"
+	p2plog "github.com/yeeco/gyee/p2p/logger"
+	"golang.org/x/crypto/sha3"
+)
+
+//
+// debug
+//
+type discv4Logger struct {
+	debug__ bool
+}
+
+var dv4Log = discv4Logger{
+	debug__: false,
+}
+
+func (log discv4Logger) Debug(fmt string, args ...interface{}) {
+	if log.debug__ {
+		p2plog.Debug(fmt, args...)
+	}
+}
+
+//
+// Adapter of the ethereum devp2p discovery v4: it parses and responds the "ping"
+// and "findnode" packets, and pings the nodes in a ethereum-style node list("enode"
+// urls) then asks them for more nodes. a gyee node running the adapter appends its
+// gyee record(identity and ports) to the tail of its "ping" and "pong" packets,
+// which is ignored by the ethereum implementations, so the gyee nodes found by the
+// adapter can be taken as bootstrap nodes of the gyee discovery, that is, gyee
+// bootstraps can be seeded from an ethereum-style node list during early network
+// growth. a separated udp port is applied, since the packets of discv4 can not be
+// multiplexed with those of the gyee discovery.
+// see: https://github.com/ethereum/devp2p/blob/master/discv4.md
+//
+const (
+	PingPacket      = 1 // ping
+	PongPacket      = 2 // pong
+	FindnodePacket  = 3 // findnode
+	NeighborsPacket = 4 // neighbors
+)
+
+const (
+	dv4Version       = 4                        // version in "ping"
+	dv4HashSize      = 32                       // size of hash leading a packet
+	dv4SigSize       = 65                       // size of signature
+	dv4HeadSize      = dv4HashSize + dv4SigSize // size of head
+	dv4MaxPacketSize = 1280                     // max packet size
+	dv4Expiration    = 20 * time.Second         // expiration of packets sent
+	dv4BondExpire    = 24 * time.Hour           // duration an endpoint proof kept
+	dv4ReplyTimeout  = 2 * time.Second          // time to wait for neighbors
+	dv4MaxNeighbors  = 12                       // max nodes in a "neighbors" packet
+	dv4MaxNodes      = 1024                     // max nodes kept
+	dv4FindFanout    = 16                       // max bonded nodes asked in a refresh
+	dv4GyeeTag       = "gyee"                   // tag of gyee record in tail
+	DftRefresh       = 10 * time.Second         // default refresh interval
+)
+
+type NodeID [64]byte
+
+type rpcEndpoint struct {
+	IP  net.IP // ip address
+	UDP uint16 // udp port
+	TCP uint16 // tcp port
+}
+
+type rpcNode struct {
+	IP  net.IP // ip address
+	UDP uint16 // udp port
+	TCP uint16 // tcp port
+	ID  NodeID // public key
+}
+
+type ping struct {
+	Version    uint           // version
+	From       rpcEndpoint    // sender
+	To         rpcEndpoint    // receiver
+	Expiration uint64         // expiration, unix time in second
+	Rest       []rlp.RawValue `rlp:"tail"`
+}
+
+type pong struct {
+	To         rpcEndpoint    // endpoint the ping sent from
+	ReplyTok   []byte         // hash of the ping
+	Expiration uint64         // expiration, unix time in second
+	Rest       []rlp.RawValue `rlp:"tail"`
+}
+
+type findnode struct {
+	Target     NodeID         // target
+	Expiration uint64         // expiration, unix time in second
+	Rest       []rlp.RawValue `rlp:"tail"`
+}
+
+type neighbors struct {
+	Nodes      []rpcNode      // nodes
+	Expiration uint64         // expiration, unix time in second
+	Rest       []rlp.RawValue `rlp:"tail"`
+}
+
+type gyeeRecord struct {
+	Tag string        // dv4GyeeTag
+	ID  config.NodeID // gyee node identity
+	UDP uint16        // gyee udp port
+	TCP uint16        // gyee tcp port
+}
+
+//
+// Node found
+//
+type Node struct {
+	ID       NodeID       // public key of discv4
+	IP       net.IP       // ip address
+	UDP      uint16       // udp port
+	TCP      uint16       // tcp port
+	Gyee     *config.Node // gyee node, nil if it's not a gyee one
+	lastPong time.Time    // last time it proved the endpoint
+}
+
+//
+// Configuration
+//
+type Config struct {
+	Key       []byte        // secp256k1 private key, generated if nil
+	IP        net.IP        // ip to listen
+	Port      uint16        // udp port to listen
+	BootNodes []string      // "enode" urls
+	Local     *config.Node  // local gyee node announced, nil if none
+	Refresh   time.Duration // refresh interval
+}
+
+type Adapter struct {
+	cfg   Config               // configuration
+	key   []byte               // private key
+	id    NodeID               // local identity
+	conn  *net.UDPConn         // udp connection
+	lock  sync.Mutex           // lock for the following
+	nodes map[NodeID]*Node     // nodes found
+	pings map[string][]byte    // hash of pings sent by address
+	finds map[string]time.Time // findnode sent by address
+	boots []*Node              // bootstrap nodes
+	done  chan struct{}        // closed when stopped
+	wg    sync.WaitGroup       // for routines
+}
+
+//
+// Create adapter
+//
+func NewAdapter(cfg *Config) (*Adapter, error) {
+	ada := &Adapter{
+		cfg:   *cfg,
+		key:   cfg.Key,
+		nodes: make(map[NodeID]*Node, 0),
+		pings: make(map[string][]byte, 0),
+		finds: make(map[string]time.Time, 0),
+		boots: make([]*Node, 0),
+		done:  make(chan struct{}),
+	}
+	if ada.cfg.Refresh <= 0 {
+		ada.cfg.Refresh = DftRefresh
+	}
+	if ada.key == nil {
+		ada.key = secp256k1.NewPrivateKey()
+	}
+	pub, err := secp256k1.GetPublicKey(ada.key)
+	if err != nil {
+		return nil, err
+	}
+	copy(ada.id[:], pub[1:])
+	for _, u := range cfg.BootNodes {
+		n, err := ParseEnode(u)
+		if err != nil {
+			return nil, err
+		}
+		ada.boots = append(ada.boots, n)
+	}
+	return ada, nil
+}
+
+//
+// Parse "enode://<hex public key>@<ip>:<tcp port>[?discport=<udp port>]"
+//
+func ParseEnode(rawurl string) (*Node, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "enode" || u.User == nil {
+		return nil, fmt.Errorf("invalid enode url: %s", rawurl)
+	}
+	n := new(Node)
+	if id, err := hex.DecodeString(u.User.String()); err != nil || len(id) != len(n.ID) {
+		return nil, fmt.Errorf("invalid enode identity: %s", rawurl)
+	} else {
+		copy(n.ID[:], id)
+	}
+	if n.IP = net.ParseIP(u.Hostname()); n.IP == nil {
+		return nil, fmt.Errorf("invalid enode ip: %s", rawurl)
+	}
+	if ip4 := n.IP.To4(); ip4 != nil {
+		n.IP = ip4
+	}
+	tcp, err := strconv.ParseUint(u.Port(), 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid enode port: %s", rawurl)
+	}
+	n.TCP, n.UDP = uint16(tcp), uint16(tcp)
+	if dp := u.Query().Get("discport"); dp != "" {
+		udp, err := strconv.ParseUint(dp, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid enode discport: %s", rawurl)
+		}
+		n.UDP = uint16(udp)
+	}
+	return n, nil
+}
+
+//
+// Start the adapter
+//
+func (ada *Adapter) Start() error {
+	addr := &net.UDPAddr{IP: ada.cfg.IP, Port: int(ada.cfg.Port)}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		dv4Log.Debug("Start: ListenUDP failed, err: %s", err.Error())
+		return err
+	}
+	ada.conn = conn
+	ada.wg.Add(2)
+	go ada.readLoop()
+	go ada.refreshLoop()
+	return nil
+}
+
+//
+// Stop the adapter
+//
+func (ada *Adapter) Stop() {
+	close(ada.done)
+	if ada.conn != nil {
+		ada.conn.Close()
+	}
+	ada.wg.Wait()
+}
+
+//
+// Nodes proved their endpoints
+//
+func (ada *Adapter) Nodes() []*Node {
+	ada.lock.Lock()
+	defer ada.lock.Unlock()
+	nodes := make([]*Node, 0, len(ada.nodes))
+	for _, n := range ada.nodes {
+		if ada.bonded(n) {
+			cn := *n
+			nodes = append(nodes, &cn)
+		}
+	}
+	return nodes
+}
+
+//
+// Gyee bootstrap urls("id@ip:udp:tcp") of the gyee nodes found
+//
+func (ada *Adapter) GyeeBootstrapNodes() []string {
+	urls := make([]string, 0)
+	for _, n := range ada.Nodes() {
+		if g := n.Gyee; g != nil {
+			urls = append(urls, fmt.Sprintf("%s@%s:%d:%d", config.P2pNodeId2String(g.ID), g.IP.String(), g.UDP, g.TCP))
+		}
+	}
+	return urls
+}
+
+//
+// Run an adapter for duration, returns the gyee bootstrap urls found
+//
+func Seed(cfg *Config, duration time.Duration) ([]string, error) {
+	ada, err := NewAdapter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := ada.Start(); err != nil {
+		return nil, err
+	}
+	time.Sleep(duration)
+	urls := ada.GyeeBootstrapNodes()
+	ada.Stop()
+	return urls, nil
+}
+
+func (ada *Adapter) bonded(n *Node) bool {
+	return time.Since(n.lastPong) < dv4BondExpire
+}
+
+func (ada *Adapter) readLoop() {
+	defer ada.wg.Done()
+	buf := make([]byte, dv4MaxPacketSize)
+	for {
+		bys, from, err := ada.conn.ReadFromUDP(buf)
+		if err != nil {
+			select {
+			case <-ada.done:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			dv4Log.Debug("readLoop: ReadFromUDP failed, err: %s", err.Error())
+			return
+		}
+		if err := ada.handlePacket(buf[:bys], from); err != nil {
+			dv4Log.Debug("readLoop: from: %s, err: %s", from.String(), err.Error())
+		}
+	}
+}
+
+func (ada *Adapter) refreshLoop() {
+	defer ada.wg.Done()
+	ticker := time.NewTicker(ada.cfg.Refresh)
+	defer ticker.Stop()
+	for {
+		ada.refresh()
+		select {
+		case <-ada.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+//
+// Ping those bootstrap nodes not bonded, and ask some bonded nodes for more
+//
+func (ada *Adapter) refresh() {
+	ada.lock.Lock()
+	toPing := make([]*Node, 0)
+	toFind := make([]*Node, 0)
+	for _, b := range ada.boots {
+		if n, ok := ada.nodes[b.ID]; !ok || !ada.bonded(n) {
+			toPing = append(toPing, b)
+		}
+	}
+	for _, n := range ada.nodes {
+		if !ada.bonded(n) {
+			toPing = append(toPing, n)
+		} else if len(toFind) < dv4FindFanout {
+			toFind = append(toFind, n)
+		}
+	}
+	ada.lock.Unlock()
+
+	for _, n := range toPing {
+		ada.sendPing(n)
+	}
+	var target NodeID
+	rand.Read(target[:])
+	for _, n := range toFind {
+		ada.sendFindnode(n, target)
+	}
+}
+
+func (ada *Adapter) localEndpoint() rpcEndpoint {
+	la := ada.conn.LocalAddr().(*net.UDPAddr)
+	return rpcEndpoint{IP: la.IP, UDP: uint16(la.Port), TCP: 0}
+}
+
+func (ada *Adapter) tail() []rlp.RawValue {
+	if ada.cfg.Local == nil {
+		return nil
+	}
+	rec := gyeeRecord{
+		Tag: dv4GyeeTag,
+		ID:  ada.cfg.Local.ID,
+		UDP: ada.cfg.Local.UDP,
+		TCP: ada.cfg.Local.TCP,
+	}
+	raw, err := rlp.EncodeToBytes(&rec)
+	if err != nil {
+		return nil
+	}
+	return []rlp.RawValue{raw}
+}
+
+func gyeeFromTail(rest []rlp.RawValue, ip net.IP) *config.Node {
+	for _, raw := range rest {
+		rec := gyeeRecord{}
+		if rlp.DecodeBytes(raw, &rec) == nil && rec.Tag == dv4GyeeTag {
+			return &config.Node{IP: ip, UDP: rec.UDP, TCP: rec.TCP, ID: rec.ID}
+		}
+	}
+	return nil
+}
+
+func expiration() uint64 {
+	return uint64(time.Now().Add(dv4Expiration).Unix())
+}
+
+func expired(ts uint64) bool {
+	return time.Unix(int64(ts), 0).Before(time.Now())
+}
+
+func (ada *Adapter) sendPing(n *Node) {
+	to := &net.UDPAddr{IP: n.IP, Port: int(n.UDP)}
+	req := &ping{
+		Version:    dv4Version,
+		From:       ada.localEndpoint(),
+		To:         rpcEndpoint{IP: n.IP, UDP: n.UDP, TCP: n.TCP},
+		Expiration: expiration(),
+		Rest:       ada.tail(),
+	}
+	hash, err := ada.send(to, PingPacket, req)
+	if err != nil {
+		return
+	}
+	ada.lock.Lock()
+	ada.pings[to.String()] = hash
+	if _, ok := ada.nodes[n.ID]; !ok && len(ada.nodes) < dv4MaxNodes {
+		cn := *n
+		cn.lastPong = time.Time{}
+		ada.nodes[n.ID] = &cn
+	}
+	ada.lock.Unlock()
+}
+
+func (ada *Adapter) sendFindnode(n *Node, target NodeID) {
+	to := &net.UDPAddr{IP: n.IP, Port: int(n.UDP)}
+	req := &findnode{
+		Target:     target,
+		Expiration: expiration(),
+	}
+	if _, err := ada.send(to, FindnodePacket, req); err != nil {
+		return
+	}
+	ada.lock.Lock()
+	ada.finds[to.String()] = time.Now()
+	ada.lock.Unlock()
+}
+
+func (ada *Adapter) send(to *net.UDPAddr, ptype byte, req interface{}) ([]byte, error) {
+	packet, hash, err := encodePacket(ada.key, ptype, req)
+	if err != nil {
+		dv4Log.Debug("send: encodePacket failed, ptype: %d, err: %s", ptype, err.Error())
+		return nil, err
+	}
+	if _, err := ada.conn.WriteToUDP(packet, to); err != nil {
+		dv4Log.Debug("send: WriteToUDP failed, to: %s, err: %s", to.String(), err.Error())
+		return nil, err
+	}
+	return hash, nil
+}
+
+func (ada *Adapter) handlePacket(buf []byte, from *net.UDPAddr) error {
+	ptype, fromID, hash, body, err := decodePacket(buf)
+	if err != nil {
+		return err
+	}
+	ip := from.IP
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	switch ptype {
+	case PingPacket:
+		req := ping{}
+		if err := rlp.DecodeBytes(body, &req); err != nil {
+			return err
+		}
+		if expired(req.Expiration) {
+			return errors.New("ping expired")
+		}
+		rsp := &pong{
+			To:         rpcEndpoint{IP: ip, UDP: uint16(from.Port), TCP: req.From.TCP},
+			ReplyTok:   hash,
+			Expiration: expiration(),
+			Rest:       ada.tail(),
+		}
+		ada.send(from, PongPacket, rsp)
+		ada.lock.Lock()
+		n, ok := ada.nodes[fromID]
+		if !ok && len(ada.nodes) < dv4MaxNodes {
+			n = &Node{ID: fromID}
+			ada.nodes[fromID] = n
+		}
+		if n != nil {
+			n.IP, n.UDP, n.TCP = ip, uint16(from.Port), req.From.TCP
+			if g := gyeeFromTail(req.Rest, ip); g != nil {
+				n.Gyee = g
+			}
+		}
+		pingBack := n != nil && !ada.bonded(n)
+		ada.lock.Unlock()
+		if pingBack {
+			ada.sendPing(n)
+		}
+
+	case PongPacket:
+		rsp := pong{}
+		if err := rlp.DecodeBytes(body, &rsp); err != nil {
+			return err
+		}
+		if expired(rsp.Expiration) {
+			return errors.New("pong expired")
+		}
+		ada.lock.Lock()
+		defer ada.lock.Unlock()
+		if tok, ok := ada.pings[from.String()]; !ok || !bytes.Equal(tok, rsp.ReplyTok) {
+			return errors.New("unsolicited pong")
+		}
+		delete(ada.pings, from.String())
+		n, ok := ada.nodes[fromID]
+		if !ok {
+			if len(ada.nodes) >= dv4MaxNodes {
+				return nil
+			}
+			n = &Node{ID: fromID, IP: ip, UDP: uint16(from.Port)}
+			ada.nodes[fromID] = n
+		}
+		n.lastPong = time.Now()
+		if g := gyeeFromTail(rsp.Rest, ip); g != nil {
+			n.Gyee = g
+		}
+
+	case FindnodePacket:
+		req := findnode{}
+		if err := rlp.DecodeBytes(body, &req); err != nil {
+			return err
+		}
+		if expired(req.Expiration) {
+			return errors.New("findnode expired")
+		}
+		ada.lock.Lock()
+		n, ok := ada.nodes[fromID]
+		if !ok || !ada.bonded(n) {
+			ada.lock.Unlock()
+			return errors.New("findnode from unknown node")
+		}
+		closest := ada.closest(req.Target)
+		ada.lock.Unlock()
+		for beg := 0; beg < len(closest); beg += dv4MaxNeighbors {
+			end := beg + dv4MaxNeighbors
+			if end > len(closest) {
+				end = len(closest)
+			}
+			rsp := &neighbors{Nodes: closest[beg:end], Expiration: expiration()}
+			ada.send(from, NeighborsPacket, rsp)
+		}
+
+	case NeighborsPacket:
+		rsp := neighbors{}
+		if err := rlp.DecodeBytes(body, &rsp); err != nil {
+			return err
+		}
+		if expired(rsp.Expiration) {
+			return errors.New("neighbors expired")
+		}
+		ada.lock.Lock()
+		defer ada.lock.Unlock()
+		if sent, ok := ada.finds[from.String()]; !ok || time.Since(sent) > dv4ReplyTimeout {
+			return errors.New("unsolicited neighbors")
+		}
+		for _, rn := range rsp.Nodes {
+			if rn.ID == ada.id || rn.UDP == 0 || len(ada.nodes) >= dv4MaxNodes {
+				continue
+			}
+			if _, ok := ada.nodes[rn.ID]; !ok {
+				ada.nodes[rn.ID] = &Node{ID: rn.ID, IP: rn.IP, UDP: rn.UDP, TCP: rn.TCP}
+			}
+		}
+
+	default:
+		return fmt.Errorf("unknown packet type: %d", ptype)
+	}
+	return nil
+}
+
+//
+// Bonded nodes closest to target, by xor distance of the hashes
+//
+func (ada *Adapter) closest(target NodeID) []rpcNode {
+	th := keccak256(target[:])
+	nodes := make([]*Node, 0, len(ada.nodes))
+	for _, n := range ada.nodes {
+		if ada.bonded(n) {
+			nodes = append(nodes, n)
+		}
+	}
+	dist := func(n *Node) []byte {
+		h := keccak256(n.ID[:])
+		for i := range h {
+			h[i] ^= th[i]
+		}
+		return h
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return bytes.Compare(dist(nodes[i]), dist(nodes[j])) < 0
+	})
+	if len(nodes) > dv4MaxNeighbors {
+		nodes = nodes[:dv4MaxNeighbors]
+	}
+	rns := make([]rpcNode, 0, len(nodes))
+	for _, n := range nodes {
+		rns = append(rns, rpcNode{IP: n.IP, UDP: n.UDP, TCP: n.TCP, ID: n.ID})
+	}
+	return rns
+}
+
+func keccak256(data ...[]byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		h.Write(d)
+	}
+	return h.Sum(nil)
+}
+
+//
+// packet = hash || signature || packet-type || packet-data
+// hash = keccak256(signature || packet-type || packet-data)
+// signature = sign(keccak256(packet-type || packet-data))
+//
+func encodePacket(key []byte, ptype byte, req interface{}) (packet, hash []byte, err error) {
+	data, err := rlp.EncodeToBytes(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	body := append([]byte{ptype}, data...)
+	sig, err := secp256k1.Sign(keccak256(body), key)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash = keccak256(sig, body)
+	packet = make([]byte, 0, dv4HeadSize+len(body))
+	packet = append(packet, hash...)
+	packet = append(packet, sig...)
+	packet = append(packet, body...)
+	if len(packet) > dv4MaxPacketSize {
+		return nil, nil, errors.New("packet too big")
+	}
+	return packet, hash, nil
+}
+
+func decodePacket(buf []byte) (ptype byte, fromID NodeID, hash []byte, data []byte, err error) {
+	if len(buf) < dv4HeadSize+1 {
+		return 0, fromID, nil, nil, errors.New("packet too small")
+	}
+	hash, sig, body := buf[:dv4HashSize], buf[dv4HashSize:dv4HeadSize], buf[dv4HeadSize:]
+	if !bytes.Equal(hash, keccak256(buf[dv4HashSize:])) {
+		return 0, fromID, nil, nil, errors.New("bad hash")
+	}
+	pub, err := secp256k1.RecoverPubkey(keccak256(body), sig)
+	if err != nil {
+		return 0, fromID, nil, nil, err
+	}
+	copy(fromID[:], pub[1:])
+	return body[0], fromID, append([]byte{}, hash...), body[1:], nil
+}
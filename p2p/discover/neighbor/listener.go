@@ -22,15 +22,12 @@ package neighbor
 
 import (
 	"crypto/ecdsa"
-	"fmt"
 	"net"
-	"os"
 	"sync"
-	"syscall"
-	"time"
 
 	config "github.com/yeeco/gyee/p2p/config"
 	umsg "github.com/yeeco/gyee/p2p/discover/udpmsg"
+	"github.com/yeeco/gyee/p2p/discover/udpmux"
 	p2plog "github.com/yeeco/gyee/p2p/logger"
 	sch "github.com/yeeco/gyee/p2p/scheduler"
 )
@@ -68,7 +65,8 @@ type ListenerManager struct {
 	name      string            // name
 	tep       sch.SchUserTaskEp // entry
 	cfg       listenerConfig    // configuration
-	conn      *net.UDPConn      // udp connection
+	mux       *udpmux.UdpMux    // udp socket shared with other protocols
+	rxChan    chan *udpPacket   // packets from the socket to the reader
 	addr      net.UDPAddr       // real udp address
 	state     int               // state
 	ptnMe     interface{}       // pointer to myself task
@@ -76,6 +74,14 @@ type ListenerManager struct {
 	lock      sync.Mutex        // lock for stop udp reader
 }
 
+// packet received from the shared socket
+type udpPacket struct {
+	buf  []byte       // copy of the datagram
+	from *net.UDPAddr // source address
+}
+
+const udpReaderQueueSize = 256 // max packets pending for the reader, more are dropped
+
 // listener manager task state
 const (
 	LmsNull    = iota // not be inited, configurations are all invalid
@@ -121,7 +127,7 @@ func (lsnMgr *ListenerManager) lsnMgrProc(ptn interface{}, msg *sch.SchMessage)
 
 func (lsnMgr *ListenerManager) setupConfig() sch.SchErrno {
 	var ptCfg *config.Cfg4UdpNgbListener = nil
-	if ptCfg = config.P2pConfig4UdpNgbListener(lsnMgr.sdl.SchGetP2pCfgName()); ptCfg == nil {
+	if ptCfg = lsnMgr.sdl.SchGetTaskConfig(lsnMgr.ptnMe).Config4UdpNgbListener(); ptCfg == nil {
 		return sch.SchEnoConfig
 	}
 	lsnMgr.cfg.IP = ptCfg.IP
@@ -133,32 +139,54 @@ func (lsnMgr *ListenerManager) setupConfig() sch.SchErrno {
 }
 
 func (lsnMgr *ListenerManager) setupUdpConn() sch.SchErrno {
-	var conn *net.UDPConn = nil
-	var realAddr *net.UDPAddr = nil
-
-	strAddr := fmt.Sprintf("%s:%d", lsnMgr.cfg.IP.String(), lsnMgr.cfg.UDP)
-	udpAddr, err := net.ResolveUDPAddr("udp", strAddr)
-	if err != nil {
-		lsnLog.Debug("setupUdpConn: ResolveUDPAddr failed, err: %s", err.Error())
+	// the socket is shared by the udp protocols on the same address, the discovery
+	// registers itself as the untagged one, see package udpmux for details.
+	mux, eno := udpmux.UdpMuxOpen(lsnMgr.cfg.IP, lsnMgr.cfg.UDP)
+	if eno != udpmux.UdpMuxEnoNone {
+		lsnLog.Debug("setupUdpConn: UdpMuxOpen failed, eno: %d", eno)
 		return sch.SchEnoOS
 	}
 
-	conn, err = net.ListenUDP("udp", udpAddr)
-	if err != nil || conn == nil {
-		lsnLog.Debug("setupUdpConn: ListenUDP failed, err: %s", err.Error())
+	lsnMgr.lock.Lock()
+	defer lsnMgr.lock.Unlock()
+	lsnMgr.rxChan = make(chan *udpPacket, udpReaderQueueSize)
+	if eno = mux.Register(udpmux.UdpMuxProtoDiscover, lsnMgr); eno != udpmux.UdpMuxEnoNone {
+		lsnLog.Debug("setupUdpConn: Register failed, eno: %d", eno)
+		lsnMgr.rxChan = nil
+		mux.Close()
 		return sch.SchEnoOS
 	}
+	lsnMgr.addr = mux.LocalAddr()
+	lsnMgr.mux = mux
+	lsnLog.Debug("setupUdpConn: real address: %s", lsnMgr.addr.String())
+	return sch.SchEnoNone
+}
 
-	realAddr = conn.LocalAddr().(*net.UDPAddr)
-	if realAddr == nil {
-		lsnLog.Debug("setupUdpConn: LocalAddr failed")
-		return sch.SchEnoOS
+func (lsnMgr *ListenerManager) UdpMuxRecv(buf []byte, from *net.UDPAddr) {
+	lsnMgr.lock.Lock()
+	defer lsnMgr.lock.Unlock()
+	if lsnMgr.rxChan == nil {
+		return
+	}
+	pkt := udpPacket{
+		buf:  append([]byte(nil), buf...),
+		from: from,
+	}
+	select {
+	case lsnMgr.rxChan <- &pkt:
+	default:
+		lsnLog.Debug("UdpMuxRecv: reader queue full, dropped, from: %s", from.String())
 	}
-	lsnLog.Debug("setupUdpConn: real address: %s", realAddr.String())
+}
 
-	lsnMgr.addr = *realAddr
-	lsnMgr.conn = conn
-	return sch.SchEnoNone
+func (lsnMgr *ListenerManager) UdpMuxClosed(eno udpmux.UdpMuxErrno) {
+	lsnLog.Debug("UdpMuxClosed: eno: %d", eno)
+	lsnMgr.lock.Lock()
+	defer lsnMgr.lock.Unlock()
+	if lsnMgr.rxChan != nil {
+		close(lsnMgr.rxChan)
+		lsnMgr.rxChan = nil
+	}
 }
 
 func (lsnMgr *ListenerManager) start() sch.SchErrno {
@@ -188,7 +216,7 @@ func (lsnMgr *ListenerManager) canStart() sch.SchErrno {
 func (lsnMgr *ListenerManager) canStop() sch.SchErrno {
 	if lsnMgr.state == LmsStarted &&
 		lsnMgr.ptnReader != nil &&
-		lsnMgr.conn != nil {
+		lsnMgr.mux != nil {
 		return sch.SchEnoNone
 	}
 	return sch.SchEnoMismatched
@@ -243,7 +271,7 @@ func (lsnMgr *ListenerManager) procStart() sch.SchErrno {
 	var udpReader = NewUdpReader()
 	udpReader.lsnMgr = lsnMgr
 	udpReader.sdl = lsnMgr.sdl
-	udpReader.conn = lsnMgr.conn
+	udpReader.rxChan = lsnMgr.rxChan
 	udpReader.chkAddr = lsnMgr.cfg.CheckAddr
 	eno, ptnLoop = lsnMgr.sdl.SchCreateTask(&udpReader.desc)
 	if eno != sch.SchEnoNone {
@@ -255,18 +283,24 @@ func (lsnMgr *ListenerManager) procStart() sch.SchErrno {
 }
 
 func (lsnMgr *ListenerManager) procStop() sch.SchErrno {
-	// To stop the reader task, we check if it's started, and simply close the connection
-	// if it is. Notice that we should not try to "done" the reader here for it's a task
-	// without a mailbox, it's a longlong loop than one scheduled by messages, so it can
-	// be really done by itself to break its' loop.
+	// To stop the reader task, we check if it's started, and simply close the packet
+	// channel if it is. Notice that we should not try to "done" the reader here for it's
+	// a task without a mailbox, it's a longlong loop than one scheduled by messages, so
+	// it can be really done by itself to break its' loop. The socket is released but it
+	// would be really closed only when no other protocols opened it.
 	lsnMgr.lock.Lock()
 	defer lsnMgr.lock.Unlock()
 	if eno := lsnMgr.canStop(); eno != sch.SchEnoNone {
 		lsnLog.Debug("procStop: we can't stop, eno: %d", eno)
 		return eno
 	}
-	lsnMgr.conn.Close()
-	lsnMgr.conn = nil
+	lsnMgr.mux.Unregister(udpmux.UdpMuxProtoDiscover)
+	lsnMgr.mux.Close()
+	lsnMgr.mux = nil
+	if lsnMgr.rxChan != nil {
+		close(lsnMgr.rxChan)
+		lsnMgr.rxChan = nil
+	}
 	return lsnMgr.nextState(LmsStopped)
 }
 
@@ -278,7 +312,6 @@ func (lsnMgr *ListenerManager) nblDataReq(ptn interface{}, msg interface{}) sch.
 
 // Reader task on UDP connection
 const udpReaderName = sch.NgbReaderName
-const udpMaxMsgSize = 1024 * 32
 
 var noDog = sch.SchWatchDog{
 	HaveDog: false,
@@ -290,7 +323,7 @@ type UdpReaderTask struct {
 	name      string                 // name
 	tep       sch.SchUserTaskEp      // entry
 	priKey    *ecdsa.PrivateKey      // local node private key
-	conn      *net.UDPConn           // udp connection
+	rxChan    chan *udpPacket        // packets from the shared socket
 	ptnMe     interface{}            // pointer to myself task
 	ptnNgbMgr interface{}            // pointer to neighbor manager task
 	desc      sch.SchTaskDescription // description
@@ -307,8 +340,8 @@ type UdpMsgInd struct {
 func NewUdpReader() *UdpReaderTask {
 	var udpReader = UdpReaderTask{
 		name: udpReaderName,
-		tep:  nil,
-		conn: nil,
+		tep:    nil,
+		rxChan: nil,
 		desc: sch.SchTaskDescription{
 			Name:   udpReaderName,
 			MbSize: 0,
@@ -330,37 +363,24 @@ func (udpReader *UdpReaderTask) TaskProc4Scheduler(ptn interface{}, msg *sch.Sch
 
 func (udpReader *UdpReaderTask) udpReaderLoop(ptn interface{}, _ *sch.SchMessage) sch.SchErrno {
 	var eno = sch.SchEnoNone
-	buf := make([]byte, udpMaxMsgSize)
 	udpReader.ptnMe = ptn
 	_, udpReader.ptnNgbMgr = udpReader.sdl.SchGetUserTaskNode(NgbMgrName)
 	udpReader.priKey = udpReader.sdl.SchGetP2pConfig().PrivateKey
 	udpReader.udpMsg.Key = udpReader.priKey
 
-	// We just read until errors fired from udp, for example, when
-	// the mamager is asked to stop the reader, it can close the
-	// connection. See function procStop for details please.
+	// We just read until the packet channel closed, for example, when
+	// the mamager is asked to stop the reader, or the shared socket is
+	// broken. See function procStop and UdpMuxClosed for details please.
 	// When a message recevied, the reader decode it to get an UDP
 	// discover protocol message, it than create a protocol task to
 	// deal with the message received.
 
-_loop:
-	for {
-		if NgbProtoReadTimeout > 0 {
-			if err := udpReader.conn.SetReadDeadline(time.Now().Add(NgbProtoReadTimeout)); err != nil {
-				eno = sch.SchEnoOS
-				break _loop
-			}
-		}
-		bys, peer, err := udpReader.conn.ReadFromUDP(buf)
-		if err != nil && udpReader.canErrIgnored(err) != true {
-			eno = sch.SchEnoOS
-			break _loop
-		}
-		udpReader.msgHandler(&buf, bys, peer)
+	for pkt := range udpReader.rxChan {
+		udpReader.msgHandler(&pkt.buf, len(pkt.buf), pkt.from)
 	}
 	// Here we get out, but this might be caused by abnormal cases than we
 	// are closed by manager task, we check this: if it is the later, the
-	// connection pointer held by manager must be nil, see lsnMgr.procStop
+	// socket pointer held by manager must be nil, see lsnMgr.procStop
 	// for details pls.
 	// If it's an abnormal case that the reader task still in running, we
 	// need to make it done.
@@ -370,7 +390,7 @@ _loop:
 	}
 
 	udpReader.lsnMgr.lock.Lock()
-	if udpReader.lsnMgr.conn == nil {
+	if udpReader.lsnMgr.mux == nil {
 		eno = udpReader.sdl.SchTaskDone(udpReader.ptnMe, udpReader.name, eno)
 		udpReader.lsnMgr.lock.Unlock()
 		goto _udpReaderLoop_exit
@@ -384,21 +404,6 @@ _udpReaderLoop_exit:
 	return eno
 }
 
-func (udpReader *UdpReaderTask) canErrIgnored(err error) bool {
-	const WSAEMSGSIZE = syscall.Errno(10040)
-	if opErr, ok := err.(*net.OpError); ok {
-		if opErr.Temporary() {
-			return true
-		}
-		if sce, ok := opErr.Err.(*os.SyscallError); ok {
-			if sce.Err == WSAEMSGSIZE {
-				return true
-			}
-		}
-	}
-	return false
-}
-
 func (udpReader *UdpReaderTask) msgHandler(pbuf *[]byte, len int, from *net.UDPAddr) sch.SchErrno {
 	var eno umsg.UdpMsgErrno
 	if eno := udpReader.udpMsg.SetRawMessage(pbuf, len, from); eno != umsg.UdpMsgEnoNone {
@@ -426,7 +431,7 @@ func (udpReader *UdpReaderTask) msgHandler(pbuf *[]byte, len int, from *net.UDPA
 func (lsnMgr *ListenerManager) sendUdpMsg(buf []byte, toAddr *net.UDPAddr) sch.SchErrno {
 	lsnMgr.lock.Lock()
 	defer lsnMgr.lock.Unlock()
-	if lsnMgr.conn == nil {
+	if lsnMgr.mux == nil {
 		lsnLog.Debug("sendUdpMsg: invalid UDP connection")
 		return sch.SchEnoInternal
 	}
@@ -434,17 +439,8 @@ func (lsnMgr *ListenerManager) sendUdpMsg(buf []byte, toAddr *net.UDPAddr) sch.S
 		lsnLog.Debug("sendUdpMsg: empty to send")
 		return sch.SchEnoParameter
 	}
-	if err := lsnMgr.conn.SetWriteDeadline(time.Now().Add(NgbProtoWriteTimeout)); err != nil {
-		lsnLog.Debug("sendUdpMsg: SetDeadline failed, err: %s", err.Error())
-		return sch.SchEnoOS
-	}
-	sent, err := lsnMgr.conn.WriteToUDP(buf, toAddr)
-	if err != nil {
-		lsnLog.Debug("sendUdpMsg: WriteToUDP failed, err: %s", err.Error())
-		return sch.SchEnoOS
-	}
-	if sent != len(buf) {
-		lsnLog.Debug("sendUdpMsg: WriteToUDP failed, len: %d, sent: %d", len(buf), sent)
+	if eno := lsnMgr.mux.WriteTo(udpmux.UdpMuxProtoDiscover, buf, toAddr, NgbProtoWriteTimeout); eno != udpmux.UdpMuxEnoNone {
+		lsnLog.Debug("sendUdpMsg: WriteTo failed, eno: %d", eno)
 		return sch.SchEnoOS
 	}
 	return sch.SchEnoNone
@@ -61,6 +61,7 @@ const (
 	NgbMgrEnoDuplicated
 	NgbMgrEnoMismatched
 	NgbMgrEnoScheduler
+	NgbMgrEnoNotBonded
 )
 
 type NgbMgrErrno int
@@ -82,6 +83,8 @@ type neighborInst struct {
 	msgBody interface{}       // message body
 	tidFN   int               // FindNode timer identity
 	tidPP   int               // Pingpong timer identity
+	ngbPgs  *um.Neighbors     // pages of Neighbors reassembled
+	fnDone  bool              // FindNode responsed to table manager
 }
 
 // Protocol handler errno
@@ -101,11 +104,14 @@ type NgbProtoErrno int
 // Timeouts, zero value would be no timeout
 const (
 	NgbProtoWriteTimeout            = 8 * time.Second  // for underlying sending
-	NgbProtoReadTimeout             = 0                // for underlying receiving
 	NgbProtoPingResponseTimeout     = 20 * time.Second // for ping
 	NgbProtoFindNodeResponseTimeout = 20 * time.Second // for find node
 )
 
+// Max bytes of a Neighbors page: the min mtu of ipv6 less the ip and udp headers,
+// so a page would not be fragmented on any path.
+const ngbMaxPageSize = 1280 - 40 - 8
+
 func (inst *neighborInst) TaskProc4Scheduler(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 	return inst.tep(ptn, msg)
 }
@@ -237,7 +243,7 @@ func (inst *neighborInst) NgbProtoPingRsp(msg *um.Pong) NgbProtoErrno {
 }
 
 func (inst *neighborInst) NgbProtoFindNodeRsp(msg *um.Neighbors) NgbProtoErrno {
-	if inst.msgType != um.UdpMsgTypeFindNode || inst.msgBody == nil {
+	if inst.msgType != um.UdpMsgTypeFindNode || inst.msgBody == nil || inst.fnDone {
 		return NgbProtoEnoParameter
 	}
 
@@ -246,6 +252,14 @@ func (inst *neighborInst) NgbProtoFindNodeRsp(msg *um.Neighbors) NgbProtoErrno {
 		return NgbProtoEnoParameter
 	}
 
+	if msg.Total > 0 {
+		all, eno := inst.ngbPage(msg)
+		if all == nil {
+			return eno
+		}
+		msg = all
+	}
+
 	if inst.tidFN != sch.SchInvalidTid {
 		if eno := inst.sdl.SchKillTimer(inst.ptn, inst.tidFN); eno != sch.SchEnoNone {
 			return NgbProtoEnoScheduler
@@ -253,6 +267,44 @@ func (inst *neighborInst) NgbProtoFindNodeRsp(msg *um.Neighbors) NgbProtoErrno {
 		inst.tidFN = sch.SchInvalidTid
 	}
 
+	return inst.findNodeDone(msg)
+}
+
+// a paged response: collect nodes until all pages got, pages might be lost or
+// duplicated, the timer is kept until the total reached. the nodes of all pages
+// are returned when completed, nil otherwise.
+func (inst *neighborInst) ngbPage(msg *um.Neighbors) (*um.Neighbors, NgbProtoErrno) {
+	if msg.Total > tab.TabFindNodeRspMax {
+		ngbLog.Debug("ngbPage: too much nodes, total: %d", msg.Total)
+		return nil, NgbProtoEnoParameter
+	}
+	if inst.ngbPgs == nil {
+		pgs := *msg
+		pgs.Nodes = make([]*um.Node, 0, msg.Total)
+		inst.ngbPgs = &pgs
+	} else if msg.Total != inst.ngbPgs.Total {
+		ngbLog.Debug("ngbPage: total mismatched, total: %d, first: %d", msg.Total, inst.ngbPgs.Total)
+		return nil, NgbProtoEnoParameter
+	}
+	for _, n := range msg.Nodes {
+		dup := false
+		for _, got := range inst.ngbPgs.Nodes {
+			if got.NodeId == n.NodeId {
+				dup = true
+				break
+			}
+		}
+		if !dup && len(inst.ngbPgs.Nodes) < inst.ngbPgs.Total {
+			inst.ngbPgs.Nodes = append(inst.ngbPgs.Nodes, n)
+		}
+	}
+	if len(inst.ngbPgs.Nodes) < inst.ngbPgs.Total {
+		return nil, NgbProtoEnoNone
+	}
+	return inst.ngbPgs, NgbProtoEnoNone
+}
+
+func (inst *neighborInst) findNodeDone(msg *um.Neighbors) NgbProtoErrno {
 	rsp := sch.NblFindNodeRsp{}
 	rsp.Result = (NgbProtoEnoNone << 16) + tab.TabMgrEnoNone
 	rsp.FindNode = inst.msgBody.(*um.FindNode)
@@ -261,10 +313,22 @@ func (inst *neighborInst) NgbProtoFindNodeRsp(msg *um.Neighbors) NgbProtoErrno {
 	inst.sdl.SchMakeMessage(&schMsg, inst.ptn, inst.ngbMgr.ptnTab, sch.EvNblFindNodeRsp, &rsp)
 	inst.sdl.SchSendMessage(&schMsg)
 	inst.cleanMap(inst.name)
+	inst.fnDone = true
 	return NgbProtoEnoNone
 }
 
 func (inst *neighborInst) NgbProtoFindNodeTimeout() NgbProtoErrno {
+	inst.tidFN = sch.SchInvalidTid
+	if inst.fnDone {
+		return NgbProtoEnoNone
+	}
+	if inst.ngbPgs != nil && len(inst.ngbPgs.Nodes) > 0 {
+		// some pages lost, take those got than nothing
+		ngbLog.Debug("NgbProtoFindNodeTimeout: pages lost, got: %d, total: %d",
+			len(inst.ngbPgs.Nodes), inst.ngbPgs.Total)
+		return inst.findNodeDone(inst.ngbPgs)
+	}
+	inst.fnDone = true
 	rsp := sch.NblFindNodeRsp{}
 	rsp.Result = (NgbProtoEnoTimeout << 16) + tab.TabMgrEnoTimeout
 	rsp.FindNode = inst.msgBody.(*um.FindNode)
@@ -576,6 +640,28 @@ func (ngbMgr *NeighborManager) FindNodeHandler(findNode *um.FindNode, from *net.
 		return NgbMgrEnoTimeout
 	}
 
+	strPeerNodeId := config.P2pNodeId2HexString(findNode.From.NodeId)
+	strSubNetId := config.P2pSubNetId2HexString(findNode.SubNetId)
+	strPeerNodeId = strSubNetId + strPeerNodeId
+
+	// FindNode from a node not bonded is ignored, else one can fill the tables of
+	// others with spoofed addresses. the table manager is told to bond it, so its'
+	// queries would be responsed later.
+	srcIp := findNode.From.IP
+	if from != nil {
+		srcIp = from.IP
+	}
+	if !ngbMgr.tabMgr.TabIsBonded(findNode.SubNetId, tab.NodeID(findNode.From.NodeId), srcIp) {
+		ngbLog.Debug("FindNodeHandler: not bonded, ignored, subnet: %x, id: %s",
+			findNode.SubNetId, config.P2pNodeId2String(findNode.From.NodeId))
+		if ngbMgr.checkMap(strPeerNodeId, um.UdpMsgTypeFindNode) == false {
+			schMsg := sch.SchMessage{}
+			ngbMgr.sdl.SchMakeMessage(&schMsg, ngbMgr.ptnMe, ngbMgr.ptnTab, sch.EvNblQueriedInd, findNode)
+			ngbMgr.sdl.SchSendMessage(&schMsg)
+		}
+		return NgbMgrEnoNotBonded
+	}
+
 	nodes := make([]*tab.Node, 0)
 	umNodes := make([]*um.Node, 0)
 	if findNode.SubNetId != config.AnySubNet {
@@ -669,28 +755,11 @@ func (ngbMgr *NeighborManager) FindNodeHandler(findNode *um.FindNode, from *net.
 		}
 	}
 
-	pum := new(um.UdpMsg)
-	if eno := pum.Encode(um.UdpMsgTypeNeighbors, &neighbors); eno != um.UdpMsgEnoNone {
-		ngbLog.Debug("FindNodeHandler: Encode failed")
-		return NgbMgrEnoEncode
-	}
-
-	buf, bytes := pum.GetRawMessage()
-	if buf == nil || bytes <= 0 {
-		ngbLog.Debug("FindNodeHandler: GetRawMessage failed")
-		return NgbMgrEnoEncode
-	}
-
-	pum.DebugMessageToPeer()
-
-	if eno := sendUdpMsg(ngbMgr.sdl, ngbMgr.ptnLsn, ngbMgr.ptnMe, buf, &toAddr); eno != sch.SchEnoNone {
-		ngbLog.Debug("FindNodeHandler: sendUdpMsg failed")
-		return NgbMgrEnoUdp
+	if eno := ngbMgr.sendNeighbors(&neighbors, &toAddr); eno != NgbMgrEnoNone {
+		ngbLog.Debug("FindNodeHandler: sendNeighbors failed, eno: %d", eno)
+		return eno
 	}
 
-	strPeerNodeId := config.P2pNodeId2HexString(findNode.From.NodeId)
-	strSubNetId := config.P2pSubNetId2HexString(findNode.SubNetId)
-	strPeerNodeId = strSubNetId + strPeerNodeId
 	if ngbMgr.checkMap(strPeerNodeId, um.UdpMsgTypeFindNode) == false {
 		schMsg := sch.SchMessage{}
 		ngbMgr.sdl.SchMakeMessage(&schMsg, ngbMgr.ptnMe, ngbMgr.ptnTab, sch.EvNblQueriedInd, findNode)
@@ -700,6 +769,89 @@ func (ngbMgr *NeighborManager) FindNodeHandler(findNode *um.FindNode, from *net.
 	return NgbMgrEnoNone
 }
 
+func (ngbMgr *NeighborManager) sendNeighbors(ngb *um.Neighbors, toAddr *net.UDPAddr) NgbMgrErrno {
+	pums, eno := ngbPages(ngb)
+	if eno != NgbMgrEnoNone {
+		return eno
+	}
+	for _, pum := range pums {
+		buf, bytes := pum.GetRawMessage()
+		if buf == nil || bytes <= 0 {
+			ngbLog.Debug("sendNeighbors: GetRawMessage failed")
+			return NgbMgrEnoEncode
+		}
+
+		pum.DebugMessageToPeer()
+
+		if eno := sendUdpMsg(ngbMgr.sdl, ngbMgr.ptnLsn, ngbMgr.ptnMe, buf, toAddr); eno != sch.SchEnoNone {
+			ngbLog.Debug("sendNeighbors: sendUdpMsg failed")
+			return NgbMgrEnoUdp
+		}
+	}
+	return NgbMgrEnoNone
+}
+
+// the nodes are packed into pages as many as possible for each, each page is
+// not greater than ngbMaxPageSize except that a single node can't be fitted.
+// the total is not set when one page is enough, for those peers not paged.
+func ngbPages(ngb *um.Neighbors) ([]*um.UdpMsg, NgbMgrErrno) {
+	nodes := ngb.Nodes
+	page := *ngb
+	encode := func(beg, end int) *um.UdpMsg {
+		page.Nodes = nodes[beg:end]
+		pum := new(um.UdpMsg)
+		if eno := pum.Encode(um.UdpMsgTypeNeighbors, &page); eno != um.UdpMsgEnoNone {
+			return nil
+		}
+		return pum
+	}
+	pum := encode(0, len(nodes))
+	if pum == nil {
+		ngbLog.Debug("ngbPages: Encode failed")
+		return nil, NgbMgrEnoEncode
+	} else if pum.Len <= ngbMaxPageSize {
+		return []*um.UdpMsg{pum}, NgbMgrEnoNone
+	}
+	page.Total = len(nodes)
+
+	// bytes taken by each node in a page, so a page is encoded once, but in case
+	// the length prefix of the message grows.
+	base := encode(0, 0)
+	if base == nil {
+		ngbLog.Debug("ngbPages: Encode failed")
+		return nil, NgbMgrEnoEncode
+	}
+	sizes := make([]int, len(nodes))
+	for idx := range nodes {
+		if pum = encode(idx, idx+1); pum == nil {
+			ngbLog.Debug("ngbPages: Encode failed")
+			return nil, NgbMgrEnoEncode
+		}
+		sizes[idx] = pum.Len - base.Len
+	}
+
+	pums := make([]*um.UdpMsg, 0)
+	for beg := 0; beg < len(nodes); {
+		end, size := beg+1, base.Len+sizes[beg]
+		for end < len(nodes) && size+sizes[end] <= ngbMaxPageSize {
+			size += sizes[end]
+			end++
+		}
+		pum = encode(beg, end)
+		for pum != nil && pum.Len > ngbMaxPageSize && end-beg > 1 {
+			end--
+			pum = encode(beg, end)
+		}
+		if pum == nil {
+			ngbLog.Debug("ngbPages: Encode failed")
+			return nil, NgbMgrEnoEncode
+		}
+		pums = append(pums, pum)
+		beg = end
+	}
+	return pums, NgbMgrEnoNone
+}
+
 func (ngbMgr *NeighborManager) NeighborsHandler(nbs *um.Neighbors, from *net.UDPAddr) NgbMgrErrno {
 	_ = from
 	if ngbMgr.checkDestNode(&nbs.To, nbs.SubNetId) == false {
@@ -715,7 +867,8 @@ func (ngbMgr *NeighborManager) NeighborsHandler(nbs *um.Neighbors, from *net.UDP
 	strSubNetId := config.P2pSubNetId2HexString(nbs.SubNetId)
 	strPeerNodeId = strSubNetId + strPeerNodeId
 	if ngbMgr.checkMap(strPeerNodeId, um.UdpMsgTypeFindNode) == false {
-		ngbLog.Debug("NeighborsHandler: not found, id: %s", strPeerNodeId)
+		ngbLog.Debug("NeighborsHandler: not found, subnet: %x, id: %s",
+			nbs.SubNetId, config.P2pNodeId2String(nbs.From.NodeId))
 		return NgbMgrEnoNotFound
 	}
 
@@ -752,7 +905,7 @@ func (ngbMgr *NeighborManager) FindNodeReq(findNode *um.FindNode) NgbMgrErrno {
 		ngbMgr:  ngbMgr,
 		ptn:     nil,
 		name:    strPeerNodeId,
-		tskName: fmt.Sprintf("FindNode:%d:%s", ngbMgr.fnInstSeq, strPeerNodeId),
+		tskName: fmt.Sprintf("FindNode:%d:%x:%s", ngbMgr.fnInstSeq, findNode.SubNetId, config.P2pNodeId2String(findNode.To.NodeId)),
 		msgType: um.UdpMsgTypeFindNode,
 		msgBody: findNode,
 		tidFN:   sch.SchInvalidTid,
@@ -834,7 +987,7 @@ func (ngbMgr *NeighborManager) PingpongReq(ping *um.Ping) NgbMgrErrno {
 		ngbMgr:  ngbMgr,
 		ptn:     nil,
 		name:    strPeerNodeId,
-		tskName: fmt.Sprintf("Ping:%d:%s", ngbMgr.ppInstSeq, strPeerNodeId),
+		tskName: fmt.Sprintf("Ping:%d:%x:%s", ngbMgr.ppInstSeq, ping.SubNetId, config.P2pNodeId2String(ping.To.NodeId)),
 		msgType: um.UdpMsgTypePing,
 		msgBody: ping,
 		tidFN:   sch.SchInvalidTid,
@@ -959,8 +1112,8 @@ func expired(ts uint64) bool {
 
 func (ngbMgr *NeighborManager) setupConfig() sch.SchErrno {
 	var ptCfg *config.Cfg4UdpNgbManager = nil
-	if ptCfg = config.P2pConfig4UdpNgbManager(ngbMgr.sdl.SchGetP2pCfgName()); ptCfg == nil {
-		ngbLog.Debug("setupConfig: P2pConfig4UdpNgbManager failed")
+	if ptCfg = ngbMgr.sdl.SchGetTaskConfig(ngbMgr.ptnMe).Config4UdpNgbManager(); ptCfg == nil {
+		ngbLog.Debug("setupConfig: Config4UdpNgbManager failed")
 		return sch.SchEnoConfig
 	}
 	ngbMgr.cfg.IP = ptCfg.IP
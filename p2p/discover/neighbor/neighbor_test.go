@@ -0,0 +1,147 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package neighbor
+
+import (
+	"net"
+	"testing"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	tab "github.com/yeeco/gyee/p2p/discover/table"
+	um "github.com/yeeco/gyee/p2p/discover/udpmsg"
+)
+
+func testNeighbors(n int) *um.Neighbors {
+	ngb := &um.Neighbors{
+		From:  um.Node{IP: net.ParseIP("10.0.0.1"), UDP: 30303, TCP: 30303},
+		To:    um.Node{IP: net.ParseIP("10.0.0.2"), UDP: 30303, TCP: 30303},
+		Id:    1,
+		Nodes: make([]*um.Node, 0, n),
+	}
+	for idx := 0; idx < n; idx++ {
+		node := &um.Node{IP: net.ParseIP("10.1.0.1"), UDP: uint16(idx), TCP: uint16(idx)}
+		node.NodeId[0], node.NodeId[1] = byte(idx>>8), byte(idx)
+		if idx%2 == 1 {
+			node.IP = net.ParseIP("fd00::1")
+		}
+		ngb.Nodes = append(ngb.Nodes, node)
+	}
+	return ngb
+}
+
+func decodePages(t *testing.T, pums []*um.UdpMsg) []*um.Neighbors {
+	pages := make([]*um.Neighbors, 0, len(pums))
+	for _, pum := range pums {
+		buf, bytes := pum.GetRawMessage()
+		if bytes > ngbMaxPageSize {
+			t.Errorf("page too large: %d", bytes)
+		}
+		rx := um.NewUdpMsg()
+		rx.SetRawMessage(&buf, bytes, &net.UDPAddr{})
+		if eno := rx.Decode(); eno != um.UdpMsgEnoNone {
+			t.Fatalf("Decode failed, eno: %d", eno)
+		}
+		pages = append(pages, rx.GetDecodedMsg().(*um.Neighbors))
+	}
+	return pages
+}
+
+func TestNgbPages(t *testing.T) {
+	// one page is enough, not paged
+	pums, eno := ngbPages(testNeighbors(4))
+	if eno != NgbMgrEnoNone || len(pums) != 1 {
+		t.Fatalf("ngbPages got %d pages, eno: %d", len(pums), eno)
+	}
+	if page := decodePages(t, pums)[0]; page.Total != 0 || len(page.Nodes) != 4 {
+		t.Errorf("single page got total: %d, nodes: %d", page.Total, len(page.Nodes))
+	}
+
+	ngb := testNeighbors(tab.TabFindNodeRspMax)
+	if pums, eno = ngbPages(ngb); eno != NgbMgrEnoNone || len(pums) < 2 {
+		t.Fatalf("ngbPages got %d pages, eno: %d", len(pums), eno)
+	}
+	pages := decodePages(t, pums)
+
+	// pages reordered and duplicated
+	inst := &neighborInst{}
+	var all *um.Neighbors
+	for idx := len(pages) - 1; idx >= 0; idx-- {
+		if all != nil {
+			t.Fatalf("completed before the last page")
+		}
+		page := pages[idx]
+		if page.Total != len(ngb.Nodes) {
+			t.Fatalf("page total got %d", page.Total)
+		}
+		all, _ = inst.ngbPage(page)
+		if idx == len(pages)/2 {
+			if all, _ = inst.ngbPage(page); all != nil {
+				t.Fatalf("completed by a duplicated page")
+			}
+		}
+	}
+	if all == nil || len(all.Nodes) != len(ngb.Nodes) {
+		t.Fatalf("reassembled got %v", all)
+	}
+	seen := make(map[config.NodeID]bool, len(all.Nodes))
+	for _, n := range all.Nodes {
+		seen[n.NodeId] = true
+	}
+	for _, n := range ngb.Nodes {
+		if !seen[n.NodeId] {
+			t.Errorf("node lost: %x", n.NodeId[:2])
+		}
+	}
+
+	// a page lost, never completed
+	inst = &neighborInst{}
+	for _, page := range pages[1:] {
+		if all, _ = inst.ngbPage(page); all != nil {
+			t.Fatalf("completed with a page lost")
+		}
+	}
+	if len(inst.ngbPgs.Nodes) != len(ngb.Nodes)-len(pages[0].Nodes) {
+		t.Errorf("nodes got %d", len(inst.ngbPgs.Nodes))
+	}
+}
+
+func TestNgbPageTotal(t *testing.T) {
+	inst := &neighborInst{}
+	page := &um.Neighbors{Total: tab.TabFindNodeRspMax + 1}
+	if all, eno := inst.ngbPage(page); all != nil || eno != NgbProtoEnoParameter || inst.ngbPgs != nil {
+		t.Errorf("too large total accepted, eno: %d", eno)
+	}
+
+	page = testNeighbors(2)
+	page.Total = 4
+	if all, eno := inst.ngbPage(page); all != nil || eno != NgbProtoEnoNone {
+		t.Fatalf("first page got eno: %d", eno)
+	}
+	page = testNeighbors(4)
+	page.Nodes, page.Total = page.Nodes[2:], 3
+	if all, eno := inst.ngbPage(page); all != nil || eno != NgbProtoEnoParameter {
+		t.Errorf("total mismatched accepted, eno: %d", eno)
+	}
+	page.Total = 4
+	if all, eno := inst.ngbPage(page); all == nil || eno != NgbProtoEnoNone || len(all.Nodes) != 4 {
+		t.Errorf("last page got eno: %d", eno)
+	}
+}
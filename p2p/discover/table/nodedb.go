@@ -36,6 +36,7 @@ import (
 
 	config "github.com/yeeco/gyee/p2p/config"
 	p2plog "github.com/yeeco/gyee/p2p/logger"
+	"github.com/yeeco/gyee/persistent"
 )
 
 //
@@ -64,8 +65,9 @@ var (
 )
 
 type nodeDB struct {
-	lvl  *leveldb.DB // Pointer to level database
-	self NodeID      // Identity of the owner node of this database
+	lvl    *leveldb.DB             // Pointer to level database
+	self   NodeID                  // Identity of the owner node of this database
+	cipher *persistent.ValueCipher // Encrypts values at rest if not nil
 }
 
 var (
@@ -89,11 +91,18 @@ var (
 	findfailKey = rootKey + ":ffa" // find fail
 )
 
-func newNodeDB(path string, version int, self NodeID) (*nodeDB, error) {
+func newNodeDB(path string, version int, self NodeID, key []byte) (*nodeDB, error) {
 	if path == "" {
 		return newMemoryNodeDB(self)
 	}
-	return newPersistentNodeDB(path, version, self)
+	var vc *persistent.ValueCipher
+	if len(key) > 0 {
+		var err error
+		if vc, err = persistent.NewValueCipher(key); err != nil {
+			return nil, err
+		}
+	}
+	return newPersistentNodeDB(path, version, self, vc)
 }
 
 func newMemoryNodeDB(self NodeID) (*nodeDB, error) {
@@ -107,7 +116,7 @@ func newMemoryNodeDB(self NodeID) (*nodeDB, error) {
 	}, nil
 }
 
-func newPersistentNodeDB(path string, version int, self NodeID) (*nodeDB, error) {
+func newPersistentNodeDB(path string, version int, self NodeID, vc *persistent.ValueCipher) (*nodeDB, error) {
 	opts := &opt.Options{OpenFilesCacheCapacity: 5}
 	db, err := leveldb.OpenFile(path, opts)
 	if _, iscorrupted := err.(*errors.ErrCorrupted); iscorrupted {
@@ -122,26 +131,54 @@ func newPersistentNodeDB(path string, version int, self NodeID) (*nodeDB, error)
 	verKey := makeKey(idEx, versionKey)
 	currentVer := make([]byte, binary.MaxVarintLen64)
 	currentVer = currentVer[:binary.PutVarint(currentVer, int64(version))]
-	blob, err := db.Get(verKey, nil)
+	ndb := &nodeDB{
+		lvl:    db,
+		self:   self,
+		cipher: vc,
+	}
+	blob, err := ndb.get(verKey)
 	switch err {
 	case leveldb.ErrNotFound:
-		if err := db.Put(verKey, currentVer, nil); err != nil {
+		if err := ndb.put(verKey, currentVer); err != nil {
 			db.Close()
 			return nil, err
 		}
-	case nil:
-		if !bytes.Equal(blob, currentVer) {
+	case nil, persistent.ErrDecrypt:
+		// a database written in plain, or encrypted by another key, is truncated
+		// as that of another version
+		if err != nil || !bytes.Equal(blob, currentVer) {
 			db.Close()
 			if err = os.RemoveAll(path); err != nil {
 				return nil, err
 			}
-			return newPersistentNodeDB(path, version, self)
+			return newPersistentNodeDB(path, version, self, vc)
 		}
 	}
-	return &nodeDB{
-		lvl:  db,
-		self: self,
-	}, nil
+	return ndb, nil
+}
+
+// get value of key, decrypted if values encrypted at rest
+func (db *nodeDB) get(key []byte) ([]byte, error) {
+	blob, err := db.lvl.Get(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return db.open(key, blob)
+}
+
+func (db *nodeDB) open(key, blob []byte) ([]byte, error) {
+	if db.cipher == nil {
+		return blob, nil
+	}
+	return db.cipher.Open(key, blob)
+}
+
+// put value of key, encrypted if values encrypted at rest
+func (db *nodeDB) put(key, value []byte) error {
+	if db.cipher != nil {
+		value = db.cipher.Seal(key, value)
+	}
+	return db.lvl.Put(key, value, nil)
 }
 
 func makeKey(id []byte, field string) []byte {
@@ -157,7 +194,7 @@ func splitKey(key []byte) (id NodeIdEx, field string) {
 func (db *nodeDB) fetchInt64(key []byte) int64 {
 	// in our application, we need to fetch timestamp value which stored as int64 type,
 	// see function storeInt64 please.
-	blob, err := db.lvl.Get(key, nil)
+	blob, err := db.get(key)
 	if err != nil {
 		return 0
 	}
@@ -173,14 +210,14 @@ func (db *nodeDB) storeInt64(key []byte, n int64) error {
 	// in our application, timestamps about ping/pong/update are applied.
 	blob := make([]byte, binary.MaxVarintLen64)
 	blob = blob[:binary.PutVarint(blob, n)]
-	return db.lvl.Put(key, blob, nil)
+	return db.put(key, blob)
 }
 
 func (db *nodeDB) node(snid SubNetworkID, id NodeID) *Node {
 	idEx := make([]byte, 0)
 	idEx = append(idEx, id[:]...)
 	idEx = append(idEx, snid[:]...)
-	blob, err := db.lvl.Get(makeKey(idEx, rootKey), nil)
+	blob, err := db.get(makeKey(idEx, rootKey))
 	if err != nil {
 		return nil
 	}
@@ -201,7 +238,7 @@ func (db *nodeDB) updateNode(snid SubNetworkID, node *Node) error {
 	var idEx = make([]byte, 0)
 	idEx = append(idEx, node.ID[:]...)
 	idEx = append(idEx, snid[:]...)
-	return db.lvl.Put(makeKey(idEx, rootKey), blob, nil)
+	return db.put(makeKey(idEx, rootKey), blob)
 }
 
 func (db *nodeDB) deleteNode(snid SubNetworkID, id NodeID) error {
@@ -319,7 +356,7 @@ seek:
 		}
 
 		// check if we got some
-		n, nSnid := nextNode(it)
+		n, nSnid := db.nextNode(it)
 		if n == nil {
 			continue seek
 		}
@@ -355,15 +392,20 @@ seek:
 	return nodes
 }
 
-func nextNode(it iterator.Iterator) (*Node, *SubNetworkID) {
+func (db *nodeDB) nextNode(it iterator.Iterator) (*Node, *SubNetworkID) {
 	for end := false; !end; end = !it.Next() {
 		_, field := splitKey(it.Key())
 		if field != rootKey {
 			continue
 		}
+		blob, err := db.open(it.Key(), it.Value())
+		if err != nil {
+			ndbLog.Debug("nextNode: open failed")
+			continue
+		}
 		var n Node
 		var snid = AnySubNet
-		if err := DecodeBytes(it.Value(), &n, &snid); err != nil {
+		if err := DecodeBytes(blob, &n, &snid); err != nil {
 			ndbLog.Debug("nextNode: DecodeBytes failed")
 			continue
 		}
@@ -24,8 +24,6 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"errors"
-	"fmt"
-	"math/rand"
 	"net"
 	"os"
 	"path"
@@ -78,6 +76,7 @@ const (
 	TabMgrEnoResource
 	TabMgrEnoRemove
 	TabMgrEnoBootstrap
+	TabMgrEnoNotBonded
 )
 
 type TabMgrErrno int
@@ -126,9 +125,12 @@ const (
 	findNodeMinInterval = 4 * time.Second    // min interval for two queries to same node
 	findNodeExpiration  = 8 * time.Second    // should be (NgbProtoFindNodeResponseTimeout + delta)
 	pingpongExpiration  = 8 * time.Second    // should be (NgbProtoPingResponseTimeout + delta)
+	lookupExpiration    = 16 * time.Second   // should be (findNodeExpiration + pingpongExpiration)
+	lookupMaxRounds     = 4                  // max rounds of queries for a lookup
 	seedMaxCount        = 32                 // wanted number of seeds
 	seedMaxAge          = 1 * 24 * time.Hour // max age can seeds be
 	nodeReboundDuration = 1 * time.Minute    // duration for a node to be rebound
+	nodeBondExpiration  = 24 * time.Hour     // pong received within this duration, a node is bonded
 	nodeAutoCleanCycle  = time.Hour          // Time period for running the expiration task.
 )
 
@@ -176,11 +178,13 @@ type tabConfig struct {
 	name           string                       // node name
 	nodeDb         string                       // node database
 	noHistory      bool                         // no history node database
+	dataKey        []byte                       // key to encrypt values of node database, plain if empty
 	bootstrapNode  bool                         // bootstrap flag of local node
 	snidMaskBits   int                          // mask bits for subnet identity
 	subNetNodeList map[SubNetworkID]config.Node // sub network node identities
 	subNetIdList   []SubNetworkID               // sub network identity list. do not put the identity
 	// of the local node in this list.
+	advertised bool // local is the advertised address, nat mapping not applied
 }
 
 //
@@ -195,10 +199,11 @@ const (
 )
 
 const (
-	TabInstQPendingMax = 16  // max nodes in pending for quering
-	TabInstBPendingMax = 128 // max nodes in pending for bounding
-	TabInstQueringMax  = 8   // max concurrency quering instances
-	TabInstBondingMax  = 64  // max concurrency bonding instances
+	TabInstQPendingMax = 16         // max nodes in pending for quering
+	TabInstBPendingMax = 128        // max nodes in pending for bounding
+	TabInstQueringMax  = 8          // max concurrency quering instances
+	TabInstBondingMax  = 64         // max concurrency bonding instances
+	TabFindNodeRspMax  = bucketSize // max nodes in a response to FindNode
 )
 
 type instCtrlBlock struct {
@@ -212,6 +217,15 @@ type instCtrlBlock struct {
 	pot   time.Time    // pong received time
 }
 
+//
+// Lookup in progress, it's carried by the lookup timer
+//
+type lookupCtrlBlock struct {
+	req     *sch.MsgTabLookupReq // lookup request
+	queried map[NodeID]bool      // nodes queried for the lookup
+	rounds  int                  // rounds of queries done
+}
+
 //
 // FindNode pending item
 //
@@ -321,9 +335,15 @@ func (tabMgr *TableManager) tabMgrProc(ptn interface{}, msg *sch.SchMessage) sch
 	case sch.EvTabFindNodeTimer:
 		eno = tabMgr.tabMgrFindNodeTimerHandler(msg.Body.(*instCtrlBlock))
 
+	case sch.EvTabLookupTimer:
+		eno = tabMgr.tabMgrLookupTimerHandler(msg.Body.(*lookupCtrlBlock))
+
 	case sch.EvTabRefreshReq:
 		eno = tabMgr.tabMgrRefreshReq(msg.Body.(*sch.MsgTabRefreshReq))
 
+	case sch.EvTabLookupReq:
+		eno = tabMgr.tabMgrLookupReq(msg.Body.(*sch.MsgTabLookupReq))
+
 	case sch.EvNblFindNodeRsp:
 		eno = tabMgr.tabMgrFindNodeRsp(msg.Body.(*sch.NblFindNodeRsp))
 
@@ -403,10 +423,9 @@ func (tabMgr *TableManager) tabMgrPoweron(ptn interface{}) TabMgrErrno {
 	}
 
 	// Since the system is just powered on at this moment, we start table
-	// refreshing bellow. Before doing this, we update the random seed for
-	// the underlying.
+	// refreshing bellow. The random source of scheduler is applied, which
+	// is seeded by configuration.
 
-	rand.Seed(time.Now().UnixNano())
 	tabMgr.refreshing = false
 
 	// setup table manager for AnySubNet type. at this moment, if this type
@@ -698,6 +717,127 @@ func (tabMgr *TableManager) tabMgrRefreshReq(msg *sch.MsgTabRefreshReq) TabMgrEr
 	return tabMgr.tabRefresh(&msg.Snid, nil)
 }
 
+func (tabMgr *TableManager) tabMgrLookupReq(msg *sch.MsgTabLookupReq) TabMgrErrno {
+	// Unlike the refreshing, the target is kept as it is, and the lookup goes in
+	// rounds: the closest nodes not queried yet are queried in a round, and those
+	// they reported are bound into the buckets by the time the lookup timer of the
+	// round expired. it's done when none of the closest nodes is new, or the max
+	// rounds reached, and the closest nodes found are reported to the requester.
+	if msg.Done == nil {
+		tabLog.Debug("tabMgrLookupReq: invalid parameters")
+		return TabMgrEnoParameter
+	}
+	mgr, ok := tabMgr.subNetMgrList[msg.Snid]
+	if !ok {
+		tabLog.Debug("tabMgrLookupReq: invalid subnet: %x", msg.Snid)
+		tabMgr.tabLookupResp(msg, TabMgrEnoNotFound, nil)
+		return TabMgrEnoNotFound
+	}
+	if !tabMgr.natUdpResult {
+		tabLog.Debug("tabMgrLookupReq: nat mapping not established")
+		tabMgr.tabLookupResp(msg, TabMgrEnoUdp, nil)
+		return TabMgrEnoUdp
+	}
+
+	lcb := &lookupCtrlBlock{
+		req:     msg,
+		queried: make(map[NodeID]bool, 0),
+	}
+	if eno := tabMgr.tabLookupRound(mgr, lcb); eno != TabMgrEnoNone {
+		return tabMgr.tabLookupDone(mgr, lcb, eno)
+	}
+	return TabMgrEnoNone
+}
+
+func (tabMgr *TableManager) tabMgrLookupTimerHandler(lcb *lookupCtrlBlock) TabMgrErrno {
+	mgr, ok := tabMgr.subNetMgrList[lcb.req.Snid]
+	if !ok {
+		tabLog.Debug("tabMgrLookupTimerHandler: invalid subnet: %x", lcb.req.Snid)
+		tabMgr.tabLookupResp(lcb.req, TabMgrEnoNotFound, nil)
+		return TabMgrEnoNotFound
+	}
+	if lcb.rounds >= lookupMaxRounds {
+		return tabMgr.tabLookupDone(mgr, lcb, TabMgrEnoNone)
+	}
+	if eno := tabMgr.tabLookupRound(mgr, lcb); eno != TabMgrEnoNone {
+		return tabMgr.tabLookupDone(mgr, lcb, eno)
+	}
+	return TabMgrEnoNone
+}
+
+// query the closest nodes not queried yet and set the timer for the round,
+// TabMgrEnoNotFound returned if no such nodes.
+func (tabMgr *TableManager) tabLookupRound(mgr *TableManager, lcb *lookupCtrlBlock) TabMgrErrno {
+	target := NodeID(lcb.req.Target)
+	closest := mgr.tabClosest(Closest4Querying, target, -1, TabInstQPendingMax)
+	if len(closest) == 0 && lcb.rounds == 0 {
+		closest = mgr.cfg.bootstrapNodes
+	}
+	nodes := make([]*Node, 0, len(closest))
+	for _, n := range closest {
+		if !lcb.queried[n.ID] {
+			lcb.queried[n.ID] = true
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == 0 {
+		return TabMgrEnoNotFound
+	}
+	if eno := mgr.tabQuery(&target, nodes); eno != TabMgrEnoNone {
+		tabLog.Debug("tabLookupRound: tabQuery failed, eno: %d", eno)
+	}
+	lcb.rounds++
+
+	var td = sch.TimerDescription{
+		Name:  TabMgrName + "_Lookup",
+		Utid:  sch.TabLookupTimerId,
+		Tmt:   sch.SchTmTypeAbsolute,
+		Dur:   lookupExpiration,
+		Extra: lcb,
+	}
+	if eno, _ := tabMgr.sdl.SchSetTimer(tabMgr.ptnMe, &td); eno != sch.SchEnoNone {
+		tabLog.Debug("tabLookupRound: SchSetTimer failed, eno: %d", eno)
+		return TabMgrEnoScheduler
+	}
+	return TabMgrEnoNone
+}
+
+// report the closest nodes found, no closer ones is not a failure
+func (tabMgr *TableManager) tabLookupDone(mgr *TableManager, lcb *lookupCtrlBlock, eno TabMgrErrno) TabMgrErrno {
+	if eno == TabMgrEnoNotFound {
+		eno = TabMgrEnoNone
+	}
+	if eno != TabMgrEnoNone {
+		tabMgr.tabLookupResp(lcb.req, eno, nil)
+		return eno
+	}
+	tabLog.Debug("tabLookupDone: target: %x, rounds: %d, queried: %d",
+		lcb.req.Target, lcb.rounds, len(lcb.queried))
+	closest := mgr.tabClosest(Closest4Querying, NodeID(lcb.req.Target), -1, maxBonding)
+	nodes := make([]*config.Node, 0, len(closest))
+	for _, n := range closest {
+		node := n.Node
+		nodes = append(nodes, &node)
+	}
+	tabMgr.tabLookupResp(lcb.req, TabMgrEnoNone, nodes)
+	return TabMgrEnoNone
+}
+
+func (tabMgr *TableManager) tabLookupResp(msg *sch.MsgTabLookupReq, eno TabMgrErrno, nodes []*config.Node) {
+	rsp := sch.MsgTabLookupRsp{
+		Result: int(eno),
+		Snid:   msg.Snid,
+		Target: msg.Target,
+		Nodes:  nodes,
+	}
+	// never block the table manager task, the requester might have gone
+	select {
+	case msg.Done <- &rsp:
+	default:
+		tabLog.Debug("tabLookupResp: requester not waiting, target: %x", msg.Target)
+	}
+}
+
 func (tabMgr *TableManager) tabMgrFindNodeRsp(msg *sch.NblFindNodeRsp) TabMgrErrno {
 	snid := msg.FindNode.SubNetId
 	mgr, ok := tabMgr.subNetMgrList[snid]
@@ -958,8 +1098,10 @@ func (tabMgr *TableManager) tabMgrQueriedInd(findNode *um.FindNode) TabMgrErrno
 }
 
 func (tabMgr *TableManager) tabMgrNatReadyInd(msg *sch.MsgNatMgrReadyInd) TabMgrErrno {
-	tabLog.Debug("tabMgrNatReadyInd: nat type: %s", msg.NatType)
-	if msg.NatType == config.NATT_NONE {
+	tabLog.Debug("tabMgrNatReadyInd: nat type: %s, advertised: %t", msg.NatType, tabMgr.cfg.advertised)
+	if msg.NatType == config.NATT_NONE || tabMgr.cfg.advertised {
+		// the advertised address is announced as it is, the nat would not be
+		// asked to map ports then, see config.P2pSetAdvertiseIpAddr.
 		tabMgr.pubTcpIp = tabMgr.cfg.local.IP
 		tabMgr.pubTcpPort = int(tabMgr.cfg.local.TCP)
 		tabMgr.natTcpResult = true
@@ -1252,9 +1394,9 @@ func (ndbc *NodeDbCleaner) ndbcAutoCleanTimerHandler() TabMgrErrno {
 }
 
 func (tabMgr *TableManager) tabGetConfig(tabCfg *tabConfig) TabMgrErrno {
-	cfg := config.P2pConfig4TabManager(tabMgr.sdl.SchGetP2pCfgName())
+	cfg := tabMgr.sdl.SchGetTaskConfig(tabMgr.ptnMe).Config4TabManager()
 	if cfg == nil {
-		tabLog.Debug("tabGetConfig: P2pConfig4TabManager failed")
+		tabLog.Debug("tabGetConfig: Config4TabManager failed")
 		return TabMgrEnoConfig
 	}
 
@@ -1263,10 +1405,12 @@ func (tabMgr *TableManager) tabGetConfig(tabCfg *tabConfig) TabMgrErrno {
 	tabCfg.name = cfg.Name
 	tabCfg.nodeDb = cfg.NodeDB
 	tabCfg.noHistory = cfg.NoHistory
+	tabCfg.dataKey = cfg.DataKey
 	tabCfg.bootstrapNode = cfg.BootstrapNode
 	tabCfg.snidMaskBits = cfg.SnidMaskBits
 	tabCfg.subNetNodeList = cfg.SubNetNodeList
 	tabCfg.subNetIdList = cfg.SubNetIdList
+	tabCfg.advertised = cfg.Advertised
 
 	tabCfg.bootstrapNodes = make([]*Node, len(cfg.BootstrapNodes))
 	for idx, n := range cfg.BootstrapNodes {
@@ -1317,7 +1461,7 @@ func (tabMgr *TableManager) tabNodeDbPrepare() TabMgrErrno {
 		}
 	}
 
-	db, err := newNodeDB(dbPath, ndbVersion, NodeID(tabMgr.cfg.local.ID))
+	db, err := newNodeDB(dbPath, ndbVersion, NodeID(tabMgr.cfg.local.ID), tabMgr.cfg.dataKey)
 	if err != nil {
 		tabLog.Debug("tabNodeDbPrepare: newNodeDB failed, err: %s", err.Error())
 		return TabMgrEnoDatabase
@@ -1409,7 +1553,7 @@ func (tabMgr *TableManager) tabRefresh(snid *SubNetworkID, tid *NodeID) TabMgrEr
 	// if target identity is nil, create randomly, and always force the target
 	// identity to get a same subnet identity specified.
 	if tid == nil {
-		rand.Read(target[:])
+		tabMgr.sdl.SchRandRead(target[:])
 	} else {
 		target = *tid
 	}
@@ -1822,7 +1966,7 @@ func (tabMgr *TableManager) tabUpdateNodeDb4Bounding(pn *Node, pit *time.Time, p
 	if node := tabMgr.nodeDb.node(snid, pn.ID); node == nil {
 		if err := tabMgr.nodeDb.updateNode(snid, pn); err != nil {
 			tabLog.Debug("tabUpdateNodeDb4Bounding: updateNode fialed, err: %s, node: %s",
-				err.Error(), fmt.Sprintf("%X", pn.ID))
+				err.Error(), config.P2pNodeId2String(pn.ID))
 			return TabMgrEnoDatabase
 		}
 	}
@@ -1830,7 +1974,7 @@ func (tabMgr *TableManager) tabUpdateNodeDb4Bounding(pn *Node, pit *time.Time, p
 	if pit != nil {
 		if err := tabMgr.nodeDb.updateLastPing(snid, pn.ID, *pit); err != nil {
 			tabLog.Debug("tabUpdateNodeDb4Bounding: updateLastPing fialed, err: %s, node: %s",
-				err.Error(), fmt.Sprintf("%X", pn.ID))
+				err.Error(), config.P2pNodeId2String(pn.ID))
 			return TabMgrEnoDatabase
 		}
 	}
@@ -1838,7 +1982,7 @@ func (tabMgr *TableManager) tabUpdateNodeDb4Bounding(pn *Node, pit *time.Time, p
 	if pot != nil {
 		if err := tabMgr.nodeDb.updateLastPong(snid, pn.ID, *pot); err != nil {
 			tabLog.Debug("tabUpdateNodeDb4Bounding: updateLastPong fialed, err: %s, node: %s",
-				err.Error(), fmt.Sprintf("%X", pn.ID))
+				err.Error(), config.P2pNodeId2String(pn.ID))
 			return TabMgrEnoDatabase
 		}
 	}
@@ -1911,7 +2055,12 @@ func (tabMgr *TableManager) tabUpdateBootstarpNode(n *um.Node) TabMgrErrno {
 		return TabMgrEnoDatabase
 	}
 
+	// taken as bonded, see tabIsBonded
 	var now = time.Now()
+	if err := tabMgr.nodeDb.updateLastPong(snid, id, now); err != nil {
+		tabLog.Debug("tabUpdateBootstarpNode: updateLastPong failed, err: %s", err.Error())
+		return TabMgrEnoDatabase
+	}
 	var umn = um.Node{
 		IP:     node.IP,
 		UDP:    node.UDP,
@@ -2157,6 +2306,13 @@ func (tabMgr *TableManager) tabBucketAddNode(n *um.Node, lastQuery *time.Time, l
 		return TabMgrEnoNone
 	}
 
+	// a node not bonded, say, no pong from it recently, is never inserted, this
+	// blocks filling the table with spoofed addresses. see tabIsBonded also.
+	if time.Since(*lastPong) > nodeBondExpiration {
+		tabLog.Debug("tabBucketAddNode: not bonded, snid: %x, ip: %s", tabMgr.snid, n.IP.String())
+		return TabMgrEnoNotBonded
+	}
+
 	var be = new(bucketEntry)
 
 	// if bucket not full, insert node
@@ -2214,7 +2370,7 @@ func (tabMgr *TableManager) tabBucketAddNode(n *um.Node, lastQuery *time.Time, l
 	}
 	index = tempIdx
 
-	beKickedIdx = rand.Int()%len(kicked)
+	beKickedIdx = tabMgr.sdl.SchRandIntn(len(kicked))
 
 kickSelected:
 
@@ -2517,6 +2673,17 @@ func (tabMgr *TableManager) tabShouldBoundDbNode(id NodeID) bool {
 	return tabMgr.tabShouldBound(id)
 }
 
+func (tabMgr *TableManager) tabIsBonded(id NodeID, ip net.IP) bool {
+	// bonded: a pong from the node at the address recently, which means that the
+	// address is not spoofed, since the pong is responsed to our ping.
+	snid := tabMgr.snid
+	node := tabMgr.nodeDb.node(snid, id)
+	if node == nil || (ip != nil && !node.IP.Equal(ip)) {
+		return false
+	}
+	return time.Since(tabMgr.nodeDb.lastPong(snid, id)) <= nodeBondExpiration
+}
+
 func (tabMgr *TableManager) TabBucketAddNode(snid SubNetworkID, n *um.Node, lastQuery *time.Time, lastPing *time.Time, lastPong *time.Time) TabMgrErrno {
 	mgr, ok := tabMgr.subNetMgrList[snid]
 	if !ok {
@@ -2530,6 +2697,19 @@ func (tabMgr *TableManager) TabBucketAddNode(snid SubNetworkID, n *um.Node, last
 	return mgr.tabBucketAddNode(n, lastQuery, lastPing, lastPong)
 }
 
+func (tabMgr *TableManager) TabIsBonded(snid SubNetworkID, id NodeID, ip net.IP) bool {
+	if snid != AnySubNet {
+		mgr, ok := tabMgr.subNetMgrList[snid]
+		return ok && mgr.tabIsBonded(id, ip)
+	}
+	for _, mgr := range tabMgr.subNetMgrList {
+		if mgr.tabIsBonded(id, ip) {
+			return true
+		}
+	}
+	return false
+}
+
 func (tabMgr *TableManager) TabUpdateNode(snid SubNetworkID, umn *um.Node) TabMgrErrno {
 	mgr, ok := tabMgr.subNetMgrList[snid]
 	if !ok {
@@ -2558,6 +2738,19 @@ func (tabMgr *TableManager) TabUpdateNode(snid SubNetworkID, umn *um.Node) TabMg
 	return TabMgrEnoNone
 }
 
+func (tabMgr *TableManager) TabUpdateBoundTime(snid SubNetworkID, id NodeID, lastPing *time.Time, lastPong *time.Time) TabMgrErrno {
+	mgr, ok := tabMgr.subNetMgrList[snid]
+	if !ok {
+		if tabLog.debug__ {
+			tabLog.Debug("TabUpdateBoundTime: none of manager instance for subnet: %x", snid)
+		}
+		return TabMgrEnoNotFound
+	}
+	mgr.lock.Lock()
+	defer mgr.lock.Unlock()
+	return mgr.tabBucketUpdateBoundTime(id, lastPing, lastPong)
+}
+
 const Closest4Querying = 1
 const Closest4Queried = 0
 
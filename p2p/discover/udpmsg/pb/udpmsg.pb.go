@@ -401,6 +401,7 @@ type UdpMessage_Neighbors struct {
 	Id               *uint64                    `protobuf:"varint,4,req,name=Id" json:"Id,omitempty"`
 	Expiration       *uint64                    `protobuf:"varint,7,opt,name=Expiration" json:"Expiration,omitempty"`
 	Extra            []byte                     `protobuf:"bytes,8,opt,name=Extra" json:"Extra,omitempty"`
+	Total            *uint32                    `protobuf:"varint,9,opt,name=Total" json:"Total,omitempty"`
 	XXX_unrecognized []byte                     `json:"-"`
 }
 
@@ -465,6 +466,13 @@ func (m *UdpMessage_Neighbors) GetExtra() []byte {
 	return nil
 }
 
+func (m *UdpMessage_Neighbors) GetTotal() uint32 {
+	if m != nil && m.Total != nil {
+		return *m.Total
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*UdpMessage)(nil), "udpmsg.pb.UdpMessage")
 	proto.RegisterType((*UdpMessage_SubNetworkID)(nil), "udpmsg.pb.UdpMessage.SubNetworkID")
@@ -992,6 +1000,11 @@ func (m *UdpMessage_Neighbors) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintUdpmsg(dAtA, i, uint64(len(m.Extra)))
 		i += copy(dAtA[i:], m.Extra)
 	}
+	if m.Total != nil {
+		dAtA[i] = 0x48
+		i++
+		i = encodeVarintUdpmsg(dAtA, i, uint64(*m.Total))
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
@@ -1226,6 +1239,9 @@ func (m *UdpMessage_Neighbors) Size() (n int) {
 		l = len(m.Extra)
 		n += 1 + l + sovUdpmsg(uint64(l))
 	}
+	if m.Total != nil {
+		n += 1 + sovUdpmsg(uint64(*m.Total))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -2842,6 +2858,26 @@ func (m *UdpMessage_Neighbors) Unmarshal(dAtA []byte) error {
 				m.Extra = []byte{}
 			}
 			iNdEx = postIndex
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Total", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowUdpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= (uint32(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Total = &v
 		default:
 			iNdEx = preIndex
 			skippy, err := skipUdpmsg(dAtA[iNdEx:])
@@ -2984,40 +3020,41 @@ var (
 func init() { proto.RegisterFile("udpmsg.proto", fileDescriptorUdpmsg) }
 
 var fileDescriptorUdpmsg = []byte{
-	// 559 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xec, 0x95, 0xdf, 0x8e, 0x93, 0x40,
-	0x14, 0x87, 0x97, 0x61, 0x68, 0xe9, 0x59, 0x76, 0x43, 0x26, 0x66, 0x33, 0xe9, 0x05, 0xe2, 0x5e,
-	0x11, 0x2f, 0x1a, 0xd3, 0x4b, 0x8d, 0x26, 0xdb, 0x85, 0x56, 0x12, 0x97, 0x92, 0x29, 0xfb, 0x00,
-	0x6d, 0x40, 0x24, 0x9b, 0x32, 0x04, 0xd8, 0xb8, 0xbe, 0x89, 0x2f, 0xe0, 0xbd, 0x8f, 0xe1, 0xa5,
-	0x8f, 0x60, 0xea, 0x85, 0x3e, 0x86, 0x61, 0xa0, 0x6c, 0xd5, 0xb6, 0xf1, 0xef, 0x85, 0x89, 0x57,
-	0xe5, 0x9c, 0xfc, 0xbe, 0x19, 0xce, 0x97, 0x99, 0x02, 0xda, 0x75, 0x98, 0x2d, 0x8b, 0x78, 0x90,
-	0xe5, 0xbc, 0xe4, 0xa4, 0xb7, 0xae, 0x16, 0xa7, 0x6f, 0x8e, 0x01, 0x2e, 0xc3, 0xec, 0x22, 0x2a,
-	0x8a, 0x79, 0x1c, 0x91, 0x47, 0xd0, 0x5d, 0x16, 0x71, 0xf0, 0x2a, 0x8b, 0xa8, 0x64, 0x22, 0xeb,
-	0x78, 0x78, 0x6f, 0xd0, 0x66, 0x07, 0xb7, 0xb9, 0x41, 0xf3, 0x5b, 0x05, 0xd9, 0x9a, 0x20, 0x03,
-	0xc0, 0x59, 0x92, 0xc6, 0x14, 0x99, 0x92, 0x75, 0x38, 0xec, 0x6f, 0x27, 0xfd, 0x24, 0x8d, 0x99,
-	0xc8, 0x89, 0x3c, 0x4f, 0x63, 0x2a, 0xef, 0xcd, 0x73, 0x91, 0xe7, 0x69, 0x4c, 0x1e, 0x82, 0xfa,
-	0x3c, 0x49, 0x43, 0x8f, 0x87, 0x11, 0xc5, 0x82, 0x31, 0xb6, 0x33, 0xe3, 0x26, 0xc5, 0xda, 0x3c,
-	0x79, 0x0c, 0xbd, 0x34, 0x4a, 0xe2, 0x17, 0x0b, 0x9e, 0x17, 0x54, 0x11, 0xf0, 0xdd, 0xed, 0xb0,
-	0xb7, 0x8e, 0xb1, 0x5b, 0xa2, 0x6f, 0x80, 0x36, 0xbb, 0x5e, 0x78, 0x51, 0xf9, 0x92, 0xe7, 0x57,
-	0xae, 0x4d, 0x8e, 0x01, 0xb9, 0xa1, 0x50, 0xa4, 0x31, 0xe4, 0x86, 0x7d, 0x06, 0x58, 0x6c, 0x53,
-	0xf5, 0xfd, 0xb6, 0xef, 0x13, 0x1d, 0xe4, 0x4b, 0xdb, 0xa7, 0xc8, 0x44, 0xd6, 0x11, 0xab, 0x1e,
-	0xab, 0x4e, 0x70, 0xee, 0x53, 0xb9, 0xee, 0x04, 0xe7, 0x3e, 0x39, 0x81, 0x4e, 0xc5, 0xba, 0x21,
-	0xc5, 0x82, 0x6b, 0xaa, 0xfe, 0x5b, 0x04, 0xd8, 0x6f, 0x3c, 0x8d, 0x73, 0xbe, 0x14, 0xcb, 0xee,
-	0xf4, 0x24, 0xe6, 0x15, 0x39, 0x72, 0x1f, 0x50, 0xc0, 0xc5, 0x9e, 0xfb, 0xd3, 0x28, 0xe0, 0x64,
-	0x0c, 0x5a, 0xc5, 0xd4, 0xc3, 0xb9, 0x21, 0x95, 0x4d, 0xd9, 0x3a, 0x1c, 0x9e, 0x6e, 0xa7, 0x36,
-	0x15, 0xb0, 0xaf, 0x38, 0xf2, 0x04, 0xd4, 0x76, 0x0d, 0x2c, 0x76, 0xfe, 0x91, 0x35, 0x5a, 0xa6,
-	0x11, 0xaa, 0x98, 0xc8, 0xc2, 0x95, 0x50, 0x62, 0x00, 0x38, 0x37, 0x59, 0x92, 0xcf, 0xcb, 0x84,
-	0xa7, 0xb4, 0x63, 0x4a, 0x16, 0x66, 0x1b, 0x1d, 0x72, 0x07, 0x14, 0xe7, 0xa6, 0xcc, 0xe7, 0xb4,
-	0x6b, 0x4a, 0x96, 0xc6, 0xea, 0xa2, 0x56, 0xc6, 0xff, 0x2b, 0xfb, 0x09, 0x65, 0x9f, 0x11, 0xa8,
-	0xeb, 0xfb, 0xf2, 0x57, 0xb5, 0xf5, 0x41, 0xbd, 0x98, 0x17, 0x57, 0xa3, 0xa4, 0x2c, 0xc4, 0xe9,
-	0x57, 0x58, 0x5b, 0x7f, 0xa7, 0x14, 0xff, 0x01, 0xa5, 0xca, 0x2f, 0x28, 0x3d, 0x81, 0x4e, 0x30,
-	0xcf, 0xe3, 0xa8, 0xa4, 0x9d, 0xfa, 0x2a, 0xd6, 0x55, 0xa3, 0xba, 0xbb, 0x43, 0xb5, 0xba, 0x5b,
-	0x75, 0x6f, 0x53, 0xf5, 0x27, 0x04, 0xbd, 0xf6, 0xdf, 0xe5, 0x9f, 0x38, 0xa2, 0xf5, 0xdc, 0xb8,
-	0x9d, 0xfb, 0x77, 0xfd, 0x3e, 0x00, 0xa5, 0x7a, 0xc7, 0x82, 0x76, 0xc4, 0x0b, 0xed, 0x1b, 0xa3,
-	0x0e, 0x7e, 0x63, 0xba, 0xbb, 0xdb, 0xb4, 0xba, 0x61, 0xfa, 0xd4, 0x87, 0xc3, 0x8d, 0x2f, 0x14,
-	0x51, 0x01, 0xfb, 0xae, 0x37, 0xd1, 0x0f, 0xc4, 0xd3, 0xd4, 0x9b, 0xe8, 0x12, 0xd1, 0x40, 0x1d,
-	0xbb, 0x9e, 0xed, 0x4d, 0x6d, 0x47, 0x47, 0xe4, 0x08, 0x7a, 0x9e, 0xe3, 0x4e, 0x9e, 0x8e, 0xa6,
-	0x6c, 0xa6, 0xcb, 0x44, 0x07, 0x6d, 0x16, 0x9c, 0x3d, 0x73, 0xce, 0x6c, 0x9b, 0x39, 0xb3, 0x99,
-	0x8e, 0x47, 0xfa, 0xbb, 0x95, 0x21, 0xbd, 0x5f, 0x19, 0xd2, 0x87, 0x95, 0x21, 0xbd, 0xfe, 0x68,
-	0x1c, 0x7c, 0x09, 0x00, 0x00, 0xff, 0xff, 0x5f, 0x5d, 0xa5, 0x19, 0x53, 0x07, 0x00, 0x00,
+	// 565 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xec, 0x95, 0xc1, 0x6e, 0x94, 0x4e,
+	0x1c, 0xc7, 0xcb, 0x30, 0xec, 0xb2, 0xbf, 0xd2, 0x86, 0x4c, 0xfe, 0x69, 0x26, 0x7b, 0xe0, 0x8f,
+	0x3d, 0x11, 0x0f, 0x1b, 0xd3, 0xa3, 0x46, 0x93, 0x6e, 0x61, 0x57, 0x12, 0xcb, 0x92, 0x59, 0xfa,
+	0x00, 0x6c, 0x40, 0x24, 0x75, 0x19, 0x02, 0x34, 0xd6, 0x37, 0xf1, 0x31, 0xbc, 0xfb, 0x02, 0x1e,
+	0x7d, 0x04, 0xb3, 0x5e, 0x7c, 0x09, 0x13, 0xc3, 0xc0, 0xd2, 0x35, 0xee, 0x5a, 0x6d, 0xf4, 0x60,
+	0xe2, 0x89, 0xf9, 0xfd, 0xf2, 0xfd, 0x0c, 0x33, 0x9f, 0xcc, 0x00, 0x68, 0x57, 0x51, 0xbe, 0x2c,
+	0x93, 0x51, 0x5e, 0xf0, 0x8a, 0x93, 0xc1, 0xba, 0x5a, 0x1c, 0xbf, 0x3b, 0x04, 0xb8, 0x88, 0xf2,
+	0xf3, 0xb8, 0x2c, 0xc3, 0x24, 0x26, 0x8f, 0xa0, 0xbf, 0x2c, 0x93, 0xe0, 0x75, 0x1e, 0x53, 0xc9,
+	0x44, 0xd6, 0xe1, 0xc9, 0xbd, 0x51, 0x97, 0x1d, 0xdd, 0xe4, 0x46, 0xed, 0xb3, 0x0e, 0xb2, 0x35,
+	0x41, 0x46, 0x80, 0xf3, 0x34, 0x4b, 0x28, 0x32, 0x25, 0x6b, 0xff, 0x64, 0xb8, 0x9d, 0xf4, 0xd3,
+	0x2c, 0x61, 0x22, 0x27, 0xf2, 0x3c, 0x4b, 0xa8, 0xfc, 0xc3, 0x3c, 0x17, 0x79, 0x9e, 0x25, 0xe4,
+	0x21, 0xa8, 0xcf, 0xd3, 0x2c, 0xf2, 0x78, 0x14, 0x53, 0x2c, 0x18, 0x63, 0x3b, 0x33, 0x69, 0x53,
+	0xac, 0xcb, 0x93, 0xc7, 0x30, 0xc8, 0xe2, 0x34, 0x79, 0xb1, 0xe0, 0x45, 0x49, 0x15, 0x01, 0xff,
+	0xbf, 0x1d, 0xf6, 0xd6, 0x31, 0x76, 0x43, 0x0c, 0x0d, 0xd0, 0xe6, 0x57, 0x0b, 0x2f, 0xae, 0x5e,
+	0xf1, 0xe2, 0xd2, 0xb5, 0xc9, 0x21, 0x20, 0x37, 0x12, 0x8a, 0x34, 0x86, 0xdc, 0x68, 0xc8, 0x00,
+	0x8b, 0xd7, 0xd4, 0x7d, 0xbf, 0xeb, 0xfb, 0x44, 0x07, 0xf9, 0xc2, 0xf6, 0x29, 0x32, 0x91, 0x75,
+	0xc0, 0xea, 0x61, 0xdd, 0x09, 0xce, 0x7c, 0x2a, 0x37, 0x9d, 0xe0, 0xcc, 0x27, 0x47, 0xd0, 0xab,
+	0x59, 0x37, 0xa2, 0x58, 0x70, 0x6d, 0x35, 0x7c, 0x8b, 0x00, 0xfb, 0xad, 0xa7, 0x49, 0xc1, 0x97,
+	0x62, 0xda, 0x9d, 0x9e, 0xc4, 0x7e, 0x45, 0x8e, 0xdc, 0x07, 0x14, 0x70, 0x8a, 0x6e, 0x4d, 0xa3,
+	0x80, 0x93, 0x09, 0x68, 0x35, 0xd3, 0x6c, 0xce, 0x8d, 0xa8, 0x6c, 0xca, 0xd6, 0xfe, 0xc9, 0xf1,
+	0x76, 0x6a, 0x53, 0x01, 0xfb, 0x86, 0x23, 0x4f, 0x40, 0xed, 0xe6, 0xc0, 0x26, 0xfa, 0xc9, 0x39,
+	0x3a, 0xa6, 0x15, 0xaa, 0x98, 0xc8, 0xc2, 0xb5, 0x50, 0x62, 0x00, 0x38, 0xd7, 0x79, 0x5a, 0x84,
+	0x55, 0xca, 0x33, 0xda, 0x33, 0x25, 0x0b, 0xb3, 0x8d, 0x0e, 0xf9, 0x0f, 0x14, 0xe7, 0xba, 0x2a,
+	0x42, 0xda, 0x37, 0x25, 0x4b, 0x63, 0x4d, 0xd1, 0x28, 0xe3, 0xff, 0x94, 0xfd, 0x82, 0xb2, 0xcf,
+	0x08, 0xd4, 0xf5, 0x7d, 0xf9, 0xa3, 0xda, 0x86, 0xa0, 0x9e, 0x87, 0xe5, 0xe5, 0x38, 0xad, 0x4a,
+	0x71, 0xfa, 0x15, 0xd6, 0xd5, 0xdf, 0x29, 0xc5, 0xbf, 0x41, 0xa9, 0x72, 0x07, 0xa5, 0x47, 0xd0,
+	0x0b, 0xc2, 0x22, 0x89, 0x2b, 0xda, 0x6b, 0xae, 0x62, 0x53, 0xb5, 0xaa, 0xfb, 0x3b, 0x54, 0xab,
+	0xbb, 0x55, 0x0f, 0x36, 0x55, 0x7f, 0x41, 0x30, 0xe8, 0xbe, 0x2e, 0x7f, 0xdd, 0x11, 0xbd, 0x8b,
+	0xcf, 0x07, 0xa0, 0xd4, 0x6b, 0x2a, 0x69, 0xcf, 0x94, 0x6f, 0x59, 0x76, 0x13, 0x6c, 0x4d, 0xe3,
+	0x1d, 0xa6, 0xfb, 0xbb, 0x4d, 0xab, 0x1b, 0xa6, 0xeb, 0x6e, 0xc0, 0xab, 0xf0, 0xa5, 0xf0, 0x7f,
+	0xc0, 0x9a, 0xe2, 0xd8, 0x87, 0xfd, 0x8d, 0xff, 0x16, 0x51, 0x01, 0xfb, 0xae, 0x37, 0xd5, 0xf7,
+	0xc4, 0x68, 0xe6, 0x4d, 0x75, 0x89, 0x68, 0xa0, 0x4e, 0x5c, 0xcf, 0xf6, 0x66, 0xb6, 0xa3, 0x23,
+	0x72, 0x00, 0x03, 0xcf, 0x71, 0xa7, 0x4f, 0xc7, 0x33, 0x36, 0xd7, 0x65, 0xa2, 0x83, 0x36, 0x0f,
+	0x4e, 0x9f, 0x39, 0xa7, 0xb6, 0xcd, 0x9c, 0xf9, 0x5c, 0xc7, 0x63, 0xfd, 0xfd, 0xca, 0x90, 0x3e,
+	0xac, 0x0c, 0xe9, 0xe3, 0xca, 0x90, 0xde, 0x7c, 0x32, 0xf6, 0xbe, 0x0e, 0x00, 0x18, 0xdc, 0x1b,
+	0x9d, 0x69, 0x07, 0x00, 0x00,
 }
@@ -127,6 +127,7 @@ type (
 		Id           uint64         // message identity
 		Expiration   uint64         // time to expired of this message
 		Extra        []byte         // extra info
+		Total        int            // nodes of all pages, 0 for a response not paged
 	}
 )
 
@@ -397,6 +398,7 @@ func (pum *UdpMsg) GetNeighbors() interface{} {
 	ngb.Id = *pbNgb.Id
 	ngb.Expiration = *pbNgb.Expiration
 	ngb.Extra = append(ngb.Extra, pbNgb.Extra...)
+	ngb.Total = int(pbNgb.GetTotal())
 
 	ngb.Nodes = make([]*Node, len(pbNgb.Nodes))
 	for idx, n := range pbNgb.Nodes {
@@ -757,6 +759,11 @@ func (pum *UdpMsg) EncodeNeighbors(ngb *Neighbors) UdpMsgErrno {
 
 	pbNgb.Extra = append(pbNgb.Extra, ngb.Extra...)
 
+	if ngb.Total > 0 {
+		pbNgb.Total = new(uint32)
+		*pbNgb.Total = uint32(ngb.Total)
+	}
+
 	pbNgb.Nodes = make([]*pb.UdpMessage_Node, len(ngb.Nodes))
 
 	for idx, n := range ngb.Nodes {
@@ -844,7 +851,7 @@ func (n *Node) CompareWith(n2 *Node) int {
 //
 
 func (n *Node) Srting() string {
-	NodeId := "\t" + "NodeId: " + fmt.Sprintf("%x", n.NodeId) + "\n"
+	NodeId := "\t" + "NodeId: " + config.P2pNodeId2String(n.NodeId) + "\n"
 	IP := "\t" + "IP: " + n.IP.String() + "\n"
 	UDP := "\t" + "UDP: " + fmt.Sprintf("%d", n.UDP) + "\n"
 	TCP := "\t" + "TCP: " + fmt.Sprintf("%d", n.TCP) + "\n"
@@ -933,7 +940,8 @@ func (neighbors *Neighbors) String() string {
 		}
 		Id := "Id: " + fmt.Sprintf("%d", neighbors.Id) + "\n"
 		Expiration := "Expiration: " + fmt.Sprintf("%d", neighbors.Expiration) + "\n"
-		strPing += From + To + FromSubNetId + SubNetId + Nodes + Id + Expiration
+		Total := "Total: " + fmt.Sprintf("%d", neighbors.Total) + "\n"
+		strPing += From + To + FromSubNetId + SubNetId + Nodes + Id + Expiration + Total
 		return strPing
 	}
 }
@@ -0,0 +1,339 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package udpmux
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	p2plog "github.com/yeeco/gyee/p2p/logger"
+)
+
+//
+// debug
+//
+type udpMuxLogger struct {
+	debug__ bool
+}
+
+var muxLog = udpMuxLogger{
+	debug__: false,
+}
+
+func (log udpMuxLogger) Debug(fmt string, args ...interface{}) {
+	if log.debug__ {
+		p2plog.Debug(fmt, args...)
+	}
+}
+
+//
+// errno
+//
+type UdpMuxErrno int
+
+const (
+	UdpMuxEnoNone = UdpMuxErrno(iota)
+	UdpMuxEnoParameter
+	UdpMuxEnoDuplicated
+	UdpMuxEnoNotFound
+	UdpMuxEnoClosed
+	UdpMuxEnoOS
+)
+
+func (eno UdpMuxErrno) Error() string {
+	return fmt.Sprintf("UdpMuxErrno: %d", eno)
+}
+
+//
+// Protocol tag: one byte leading the datagram of a protocol other than the discovery.
+// the low three bits of a tag are all set, which is the wire type 7 never applied
+// by protobuf, so a tag can not be confused with the first byte of a discovery
+// message, which is a protobuf one without any tag for compatible with those
+// peers not muxed.
+//
+type UdpMuxProto byte
+
+const (
+	UdpMuxProtoDiscover = UdpMuxProto(0x00) // neighbor discovery, untagged
+	UdpMuxProtoProbe    = UdpMuxProto(0x0f) // reachability self probe of the peer manager
+	udpMuxTagMask       = 0x07              // mask of the low three bits
+)
+
+func (proto UdpMuxProto) tagged() bool {
+	return proto != UdpMuxProtoDiscover
+}
+
+func (proto UdpMuxProto) valid() bool {
+	return !proto.tagged() || proto&udpMuxTagMask == udpMuxTagMask
+}
+
+//
+// Handler registered for a protocol. UdpMuxRecv is called in the reader of the
+// socket, the buffer is reused after it returns, so the handler must copy it out
+// if it's kept. UdpMuxClosed is called when the socket fails or is closed.
+//
+type UdpMuxHandler interface {
+	UdpMuxRecv(buf []byte, from *net.UDPAddr)
+	UdpMuxClosed(eno UdpMuxErrno)
+}
+
+const udpMuxMaxMsgSize = 1024 * 32 // max datagram size
+
+//
+// Socket shared by all protocols registered on it
+//
+type UdpMux struct {
+	key      string                        // key in the socket table
+	conn     *net.UDPConn                  // udp connection
+	addr     net.UDPAddr                   // real udp address
+	refs     int                           // number of opener
+	lock     sync.Mutex                    // lock for handlers
+	wrLock   sync.Mutex                    // lock for writing
+	handlers map[UdpMuxProto]UdpMuxHandler // handlers registered
+	closed   bool                          // closed flag
+}
+
+//
+// Socket table: one socket per address family and address
+//
+var muxLock sync.Mutex
+var muxTab = make(map[string]*UdpMux, 0)
+
+func udpMuxNetwork(ip net.IP) string {
+	if ip == nil || ip.To4() != nil {
+		return "udp4"
+	}
+	return "udp6"
+}
+
+//
+// Open the socket for address, it's created for the first opener and shared
+// by those followed. each open should be paired with a Close.
+//
+func UdpMuxOpen(ip net.IP, port uint16) (*UdpMux, UdpMuxErrno) {
+	if ip == nil {
+		ip = net.IPv4zero
+	}
+	network := udpMuxNetwork(ip)
+	udpAddr := &net.UDPAddr{IP: ip, Port: int(port)}
+	key := fmt.Sprintf("%s:%s", network, udpAddr.String())
+
+	muxLock.Lock()
+	defer muxLock.Unlock()
+
+	// port 0 asks for an ephemeral one, which is never shared
+	if mux, ok := muxTab[key]; ok && port != 0 {
+		mux.refs++
+		muxLog.Debug("UdpMuxOpen: shared, key: %s, refs: %d", key, mux.refs)
+		return mux, UdpMuxEnoNone
+	}
+
+	conn, err := net.ListenUDP(network, udpAddr)
+	if err != nil || conn == nil {
+		muxLog.Debug("UdpMuxOpen: ListenUDP failed, err: %s", err.Error())
+		return nil, UdpMuxEnoOS
+	}
+	realAddr, ok := conn.LocalAddr().(*net.UDPAddr)
+	if !ok || realAddr == nil {
+		muxLog.Debug("UdpMuxOpen: LocalAddr failed")
+		conn.Close()
+		return nil, UdpMuxEnoOS
+	}
+	key = fmt.Sprintf("%s:%s", network, realAddr.String())
+	muxLog.Debug("UdpMuxOpen: key: %s", key)
+
+	mux := &UdpMux{
+		key:      key,
+		conn:     conn,
+		addr:     *realAddr,
+		refs:     1,
+		handlers: make(map[UdpMuxProto]UdpMuxHandler, 0),
+	}
+	muxTab[key] = mux
+	go mux.readerLoop()
+	return mux, UdpMuxEnoNone
+}
+
+//
+// Close the socket for an opener, it's really closed when the last one gone
+//
+func (mux *UdpMux) Close() UdpMuxErrno {
+	muxLock.Lock()
+	defer muxLock.Unlock()
+	if mux.refs <= 0 {
+		return UdpMuxEnoClosed
+	}
+	if mux.refs--; mux.refs > 0 {
+		return UdpMuxEnoNone
+	}
+	if muxTab[mux.key] == mux {
+		delete(muxTab, mux.key)
+	}
+	mux.lock.Lock()
+	mux.closed = true
+	mux.lock.Unlock()
+	mux.conn.Close()
+	return UdpMuxEnoNone
+}
+
+//
+// Get the real local address of the socket
+//
+func (mux *UdpMux) LocalAddr() net.UDPAddr {
+	return mux.addr
+}
+
+//
+// Register handler for a protocol
+//
+func (mux *UdpMux) Register(proto UdpMuxProto, handler UdpMuxHandler) UdpMuxErrno {
+	if !proto.valid() || handler == nil {
+		return UdpMuxEnoParameter
+	}
+	mux.lock.Lock()
+	defer mux.lock.Unlock()
+	if mux.closed {
+		return UdpMuxEnoClosed
+	}
+	if _, dup := mux.handlers[proto]; dup {
+		muxLog.Debug("Register: duplicated, key: %s, proto: %x", mux.key, proto)
+		return UdpMuxEnoDuplicated
+	}
+	mux.handlers[proto] = handler
+	return UdpMuxEnoNone
+}
+
+//
+// Unregister handler for a protocol
+//
+func (mux *UdpMux) Unregister(proto UdpMuxProto) UdpMuxErrno {
+	mux.lock.Lock()
+	defer mux.lock.Unlock()
+	if _, ok := mux.handlers[proto]; !ok {
+		return UdpMuxEnoNotFound
+	}
+	delete(mux.handlers, proto)
+	return UdpMuxEnoNone
+}
+
+//
+// Write datagram of protocol to address, the tag is prepended if needed
+//
+func (mux *UdpMux) WriteTo(proto UdpMuxProto, buf []byte, to *net.UDPAddr, timeout time.Duration) UdpMuxErrno {
+	if !proto.valid() || len(buf) == 0 || to == nil {
+		return UdpMuxEnoParameter
+	}
+	if proto.tagged() {
+		buf = append([]byte{byte(proto)}, buf...)
+	}
+	mux.wrLock.Lock()
+	defer mux.wrLock.Unlock()
+	if timeout > 0 {
+		if err := mux.conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+			muxLog.Debug("WriteTo: SetWriteDeadline failed, err: %s", err.Error())
+			return UdpMuxEnoOS
+		}
+	}
+	sent, err := mux.conn.WriteToUDP(buf, to)
+	if err != nil {
+		muxLog.Debug("WriteTo: WriteToUDP failed, err: %s", err.Error())
+		return UdpMuxEnoOS
+	}
+	if sent != len(buf) {
+		muxLog.Debug("WriteTo: WriteToUDP failed, len: %d, sent: %d", len(buf), sent)
+		return UdpMuxEnoOS
+	}
+	return UdpMuxEnoNone
+}
+
+func (mux *UdpMux) readerLoop() {
+	buf := make([]byte, udpMuxMaxMsgSize)
+	for {
+		bys, from, err := mux.conn.ReadFromUDP(buf)
+		if err != nil {
+			if canErrIgnored(err) {
+				continue
+			}
+			muxLog.Debug("readerLoop: ReadFromUDP failed, key: %s, err: %s", mux.key, err.Error())
+			break
+		}
+		if bys == 0 {
+			continue
+		}
+		proto := UdpMuxProtoDiscover
+		if tag := UdpMuxProto(buf[0]); tag.tagged() && tag.valid() {
+			proto = tag
+		}
+		mux.lock.Lock()
+		handler := mux.handlers[proto]
+		mux.lock.Unlock()
+		if handler == nil {
+			continue
+		}
+		if proto.tagged() {
+			handler.UdpMuxRecv(buf[1:bys], from)
+		} else {
+			handler.UdpMuxRecv(buf[0:bys], from)
+		}
+	}
+
+	// the socket is closed by the last opener or broken, tell all handlers,
+	// and take the broken one out of the table so a new open would rebind.
+	mux.lock.Lock()
+	eno := UdpMuxEnoClosed
+	if !mux.closed {
+		eno = UdpMuxEnoOS
+		mux.closed = true
+	}
+	handlers := mux.handlers
+	mux.handlers = make(map[UdpMuxProto]UdpMuxHandler, 0)
+	mux.lock.Unlock()
+
+	if eno == UdpMuxEnoOS {
+		muxLock.Lock()
+		if muxTab[mux.key] == mux {
+			delete(muxTab, mux.key)
+		}
+		muxLock.Unlock()
+	}
+	for _, handler := range handlers {
+		handler.UdpMuxClosed(eno)
+	}
+}
+
+func canErrIgnored(err error) bool {
+	const WSAEMSGSIZE = syscall.Errno(10040)
+	if opErr, ok := err.(*net.OpError); ok {
+		if opErr.Temporary() {
+			return true
+		}
+		if sce, ok := opErr.Err.(*os.SyscallError); ok {
+			if sce.Err == WSAEMSGSIZE {
+				return true
+			}
+		}
+	}
+	return false
+}
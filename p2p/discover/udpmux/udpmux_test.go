@@ -0,0 +1,196 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package udpmux
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+type testHandler struct {
+	recv   chan []byte
+	closed chan UdpMuxErrno
+}
+
+func newTestHandler() *testHandler {
+	return &testHandler{
+		recv:   make(chan []byte, 8),
+		closed: make(chan UdpMuxErrno, 1),
+	}
+}
+
+func (h *testHandler) UdpMuxRecv(buf []byte, from *net.UDPAddr) {
+	h.recv <- append([]byte{}, buf...)
+}
+
+func (h *testHandler) UdpMuxClosed(eno UdpMuxErrno) {
+	h.closed <- eno
+}
+
+func (h *testHandler) expect(t *testing.T, what string, want []byte) {
+	t.Helper()
+	select {
+	case got := <-h.recv:
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: got %x, want %x", what, got, want)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("%s: nothing received", what)
+	}
+}
+
+func (h *testHandler) expectNone(t *testing.T, what string) {
+	t.Helper()
+	select {
+	case got := <-h.recv:
+		t.Errorf("%s: unexpected %x", what, got)
+	case <-time.After(time.Millisecond * 100):
+	}
+}
+
+func TestUdpMuxTag(t *testing.T) {
+	for _, c := range []struct {
+		proto UdpMuxProto
+		valid bool
+	}{
+		{UdpMuxProtoDiscover, true},
+		{UdpMuxProtoProbe, true},
+		{UdpMuxProto(0x07), true},
+		{UdpMuxProto(0x08), false}, // field 1, varint: a discovery message
+		{UdpMuxProto(0x0a), false}, // field 1, length delimited
+	} {
+		if c.proto.valid() != c.valid {
+			t.Errorf("tag %x: valid got %t", byte(c.proto), !c.valid)
+		}
+	}
+}
+
+func TestUdpMuxDemux(t *testing.T) {
+	ip := net.ParseIP("127.0.0.1")
+	mux, eno := UdpMuxOpen(ip, 0)
+	if eno != UdpMuxEnoNone {
+		t.Fatalf("UdpMuxOpen failed, eno: %d", eno)
+	}
+	peer, eno := UdpMuxOpen(ip, 0)
+	if eno != UdpMuxEnoNone {
+		t.Fatalf("UdpMuxOpen failed, eno: %d", eno)
+	}
+	defer peer.Close()
+
+	discover, probe := newTestHandler(), newTestHandler()
+	if eno := mux.Register(UdpMuxProtoDiscover, discover); eno != UdpMuxEnoNone {
+		t.Fatalf("Register failed, eno: %d", eno)
+	}
+	if eno := mux.Register(UdpMuxProtoProbe, probe); eno != UdpMuxEnoNone {
+		t.Fatalf("Register failed, eno: %d", eno)
+	}
+	if eno := mux.Register(UdpMuxProtoProbe, probe); eno != UdpMuxEnoDuplicated {
+		t.Errorf("Register again got eno: %d", eno)
+	}
+	if eno := mux.Register(UdpMuxProto(0x08), probe); eno != UdpMuxEnoParameter {
+		t.Errorf("Register invalid tag got eno: %d", eno)
+	}
+
+	to := mux.LocalAddr()
+	send := func(proto UdpMuxProto, buf []byte) {
+		if eno := peer.WriteTo(proto, buf, &to, time.Second); eno != UdpMuxEnoNone {
+			t.Fatalf("WriteTo failed, eno: %d", eno)
+		}
+	}
+
+	// untagged datagrams are passed as they are, tagged ones without the tag
+	send(UdpMuxProtoDiscover, []byte{0x08, 0x01})
+	discover.expect(t, "discovery", []byte{0x08, 0x01})
+	send(UdpMuxProtoProbe, []byte{0x01, 0x02})
+	probe.expect(t, "probe", []byte{0x01, 0x02})
+
+	// a discovery message beginning with a byte like a tag is not confused
+	send(UdpMuxProtoDiscover, []byte{0x0a, 0x00})
+	discover.expect(t, "discovery 0x0a", []byte{0x0a, 0x00})
+	probe.expectNone(t, "discovery 0x0a")
+
+	// tagged ones without handler are discarded
+	send(UdpMuxProto(0x07), []byte{0x01})
+	discover.expectNone(t, "unregistered tag")
+	probe.expectNone(t, "unregistered tag")
+
+	if eno := mux.Unregister(UdpMuxProtoProbe); eno != UdpMuxEnoNone {
+		t.Errorf("Unregister failed, eno: %d", eno)
+	}
+	if eno := mux.Unregister(UdpMuxProtoProbe); eno != UdpMuxEnoNotFound {
+		t.Errorf("Unregister again got eno: %d", eno)
+	}
+	send(UdpMuxProtoProbe, []byte{0x01})
+	probe.expectNone(t, "unregistered probe")
+
+	if eno := mux.Close(); eno != UdpMuxEnoNone {
+		t.Fatalf("Close failed, eno: %d", eno)
+	}
+	select {
+	case eno := <-discover.closed:
+		if eno != UdpMuxEnoClosed {
+			t.Errorf("closed got eno: %d", eno)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("handler not told closed")
+	}
+	if eno := mux.Close(); eno != UdpMuxEnoClosed {
+		t.Errorf("Close again got eno: %d", eno)
+	}
+}
+
+func TestUdpMuxShared(t *testing.T) {
+	ip := net.ParseIP("127.0.0.1")
+	first, eno := UdpMuxOpen(ip, 0)
+	if eno != UdpMuxEnoNone {
+		t.Fatalf("UdpMuxOpen failed, eno: %d", eno)
+	}
+	port := uint16(first.LocalAddr().Port)
+	second, eno := UdpMuxOpen(ip, port)
+	if eno != UdpMuxEnoNone || second != first {
+		t.Fatalf("UdpMuxOpen not shared, eno: %d", eno)
+	}
+	h := newTestHandler()
+	first.Register(UdpMuxProtoDiscover, h)
+
+	// the socket is kept till the last opener closed
+	first.Close()
+	select {
+	case <-h.closed:
+		t.Fatalf("closed with an opener left")
+	case <-time.After(time.Millisecond * 100):
+	}
+	second.Close()
+	select {
+	case <-h.closed:
+	case <-time.After(time.Second):
+		t.Fatalf("not closed by the last opener")
+	}
+
+	// the address can be opened again
+	again, eno := UdpMuxOpen(ip, port)
+	if eno != UdpMuxEnoNone || again == first {
+		t.Fatalf("UdpMuxOpen again failed, eno: %d", eno)
+	}
+	again.Close()
+}
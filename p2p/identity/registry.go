@@ -0,0 +1,305 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package identity
+
+import (
+	"sync"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Identity registry: the chain peer manager and the dht connection manager of a
+// node connect to the same node identities independently, the registry links
+// what they learnt about a peer: bans, latency samples, addresses of both stacks
+// and connections of both stacks. a peer can have several endpoints of a stack,
+// such as ipv4 and ipv6 ones, or the address it announces and that observed
+// behind a nat; the latest ones are kept, dialers can try them all. there is
+// one registry for each node, shared by the p2p instances of the node, keyed by
+// the node name. when too many peers kept, the one updated earliest is evicted,
+// except peers connected or banned.
+//
+
+const (
+	MaxPeers     = 4096 // max peers kept by a registry
+	MaxEndpoints = 4    // max endpoints kept for each stack of a peer
+	ewmaAlpha    = 0.2  // weight of a new latency sample
+)
+
+// Stacks a peer connected by
+const (
+	StackChain = iota // chain peer manager
+	StackDht          // dht connection manager
+	stackNum
+)
+
+// What known about a peer
+type Peer struct {
+	ID      config.NodeID           // node identity
+	Chain   *config.Node            // chain address, nil if not known
+	Dht     *config.Node            // dht address, nil if not known
+	Conns   [stackNum]int           // connections of each stack
+	Addrs   [stackNum][]config.Node // endpoints of each stack, latest first
+	Latency time.Duration           // EWMA of round trip time samples of both stacks, 0 if none
+	Banned  time.Time               // time the ban expires, zero if not banned
+	Updated time.Time               // time updated last
+}
+
+type Registry struct {
+	lock  sync.Mutex              // shared by tasks of both stacks
+	peers map[config.NodeID]*Peer // peers by node identity
+}
+
+var regMapLock sync.Mutex
+var regMap = make(map[string]*Registry, 0)
+
+// Get the registry of node, created if not exist
+func Of(name string) *Registry {
+	regMapLock.Lock()
+	defer regMapLock.Unlock()
+	if reg, ok := regMap[name]; ok {
+		return reg
+	}
+	reg := NewRegistry()
+	regMap[name] = reg
+	return reg
+}
+
+// Drop the registry of node, when the node is stopped
+func Drop(name string) {
+	regMapLock.Lock()
+	defer regMapLock.Unlock()
+	delete(regMap, name)
+}
+
+// methods of a nil registry do nothing, so users need not check it
+func NewRegistry() *Registry {
+	return &Registry{peers: make(map[config.NodeID]*Peer, 0)}
+}
+
+// peer of id, created if not exist, lock must be held
+func (reg *Registry) peer(id config.NodeID) *Peer {
+	if p, ok := reg.peers[id]; ok {
+		return p
+	}
+	if len(reg.peers) >= MaxPeers {
+		reg.evict()
+	}
+	p := &Peer{ID: id}
+	reg.peers[id] = p
+	return p
+}
+
+func (reg *Registry) evict() {
+	var old *Peer
+	now := time.Now()
+	for _, p := range reg.peers {
+		if p.Conns[StackChain] > 0 || p.Conns[StackDht] > 0 || now.Before(p.Banned) {
+			continue
+		}
+		if old == nil || p.Updated.Before(old.Updated) {
+			old = p
+		}
+	}
+	if old != nil {
+		delete(reg.peers, old.ID)
+	}
+}
+
+// Get what known about a peer
+func (reg *Registry) Lookup(id config.NodeID) (Peer, bool) {
+	if reg == nil {
+		return Peer{}, false
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	p, ok := reg.peers[id]
+	if !ok {
+		return Peer{}, false
+	}
+	return *p, true
+}
+
+// Ban a peer till expired, for both stacks
+func (reg *Registry) Ban(id config.NodeID, expired time.Time) {
+	if reg == nil {
+		return
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	p := reg.peer(id)
+	p.Banned = expired
+	p.Updated = time.Now()
+}
+
+func (reg *Registry) Unban(id config.NodeID) {
+	if reg == nil {
+		return
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	if p, ok := reg.peers[id]; ok {
+		p.Banned = time.Time{}
+	}
+}
+
+func (reg *Registry) Banned(id config.NodeID) bool {
+	if reg == nil {
+		return false
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	p, ok := reg.peers[id]
+	return ok && time.Now().Before(p.Banned)
+}
+
+// Apply a round trip time sample of a peer, from any stack
+func (reg *Registry) Observe(id config.NodeID, rtt time.Duration) {
+	if reg == nil {
+		return
+	}
+	if rtt <= 0 {
+		return
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	p := reg.peer(id)
+	if p.Latency == 0 {
+		p.Latency = rtt
+	} else {
+		p.Latency = time.Duration((1.0-ewmaAlpha)*float64(p.Latency) + ewmaAlpha*float64(rtt))
+	}
+	p.Updated = time.Now()
+}
+
+// Latency of a peer, false if no samples
+func (reg *Registry) Latency(id config.NodeID) (time.Duration, bool) {
+	if reg == nil {
+		return 0, false
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	p, ok := reg.peers[id]
+	if !ok || p.Latency == 0 {
+		return 0, false
+	}
+	return p.Latency, true
+}
+
+// Set address of a peer learnt by a stack, it's also added as an endpoint
+func (reg *Registry) SetAddress(stack int, node *config.Node) {
+	if reg == nil {
+		return
+	}
+	n := *node
+	n.IP = append(n.IP[:0:0], node.IP...)
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	p := reg.peer(node.ID)
+	switch stack {
+	case StackChain:
+		p.Chain = &n
+	case StackDht:
+		p.Dht = &n
+	}
+	p.addEndpoint(stack, n)
+	p.Updated = time.Now()
+}
+
+// Add an endpoint of a peer learnt by a stack, without changing its address
+func (reg *Registry) AddEndpoint(stack int, node *config.Node) {
+	if reg == nil || stack < 0 || stack >= stackNum {
+		return
+	}
+	n := *node
+	n.IP = append(n.IP[:0:0], node.IP...)
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	p := reg.peer(node.ID)
+	p.addEndpoint(stack, n)
+	p.Updated = time.Now()
+}
+
+// Endpoints of a peer known by a stack, latest first
+func (reg *Registry) Endpoints(stack int, id config.NodeID) []config.Node {
+	if reg == nil || stack < 0 || stack >= stackNum {
+		return nil
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	p, ok := reg.peers[id]
+	if !ok {
+		return nil
+	}
+	return append([]config.Node(nil), p.Addrs[stack]...)
+}
+
+// a new slice is made, not to change those copied out by Lookup
+func (p *Peer) addEndpoint(stack int, n config.Node) {
+	addrs := make([]config.Node, 0, MaxEndpoints)
+	addrs = append(addrs, n)
+	for _, a := range p.Addrs[stack] {
+		if len(addrs) >= MaxEndpoints {
+			break
+		}
+		if !a.IP.Equal(n.IP) || a.TCP != n.TCP {
+			addrs = append(addrs, a)
+		}
+	}
+	p.Addrs[stack] = addrs
+}
+
+// Count connections of a peer by a stack, up for connected and !up for closed.
+// a peer connected is not evicted.
+func (reg *Registry) Connected(stack int, id config.NodeID, up bool) {
+	if reg == nil {
+		return
+	}
+	if stack < 0 || stack >= stackNum {
+		return
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	p := reg.peer(id)
+	if up {
+		p.Conns[stack]++
+	} else if p.Conns[stack] > 0 {
+		p.Conns[stack]--
+	}
+	p.Updated = time.Now()
+}
+
+// Peers connected by both stacks
+func (reg *Registry) Linked() []Peer {
+	if reg == nil {
+		return nil
+	}
+	reg.lock.Lock()
+	defer reg.lock.Unlock()
+	peers := make([]Peer, 0)
+	for _, p := range reg.peers {
+		if p.Conns[StackChain] > 0 && p.Conns[StackDht] > 0 {
+			peers = append(peers, *p)
+		}
+	}
+	return peers
+}
@@ -0,0 +1,100 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package identity
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestRegistryLink(t *testing.T) {
+	reg := Of("test")
+	defer Drop("test")
+	if Of("test") != reg {
+		t.Fatalf("Of() returns another registry for the same name")
+	}
+
+	id := config.NodeID{1, 2, 3}
+	reg.SetAddress(StackChain, &config.Node{ID: id, IP: net.ParseIP("10.0.0.1"), TCP: 30303})
+	reg.SetAddress(StackDht, &config.Node{ID: id, IP: net.ParseIP("10.0.0.1"), TCP: 40405})
+	reg.Connected(StackChain, id, true)
+	if len(reg.Linked()) != 0 {
+		t.Errorf("peer connected by chain only is linked")
+	}
+	reg.Connected(StackDht, id, true)
+	linked := reg.Linked()
+	if len(linked) != 1 || linked[0].Chain.TCP != 30303 || linked[0].Dht.TCP != 40405 {
+		t.Errorf("Linked() got %+v", linked)
+	}
+	reg.Connected(StackDht, id, false)
+	reg.Connected(StackDht, id, false)
+	if p, _ := reg.Lookup(id); p.Conns[StackDht] != 0 || p.Conns[StackChain] != 1 {
+		t.Errorf("connections got %v", p.Conns)
+	}
+
+	reg.Ban(id, time.Now().Add(time.Hour))
+	if !reg.Banned(id) {
+		t.Errorf("peer banned by chain not banned")
+	}
+	reg.Unban(id)
+	reg.Ban(config.NodeID{4}, time.Now().Add(-time.Second))
+	if reg.Banned(id) || reg.Banned(config.NodeID{4}) {
+		t.Errorf("peer unbanned or expired still banned")
+	}
+}
+
+func TestRegistryLatency(t *testing.T) {
+	reg := NewRegistry()
+	id := config.NodeID{1}
+	if _, ok := reg.Latency(id); ok {
+		t.Errorf("latency of unknown peer found")
+	}
+	reg.Observe(id, -1)
+	reg.Observe(id, 100*time.Millisecond)
+	reg.Observe(id, 200*time.Millisecond)
+	if l, ok := reg.Latency(id); !ok || l != 120*time.Millisecond {
+		t.Errorf("Latency() got %v, %t, want %v", l, ok, 120*time.Millisecond)
+	}
+
+	var nilReg *Registry
+	nilReg.Observe(id, time.Second)
+	if nilReg.Banned(id) || len(nilReg.Linked()) != 0 {
+		t.Errorf("nil registry not empty")
+	}
+}
+
+func TestRegistryEvict(t *testing.T) {
+	reg := NewRegistry()
+	kept := config.NodeID{0xff}
+	reg.Connected(StackChain, kept, true)
+	for i := 0; i < MaxPeers+10; i++ {
+		reg.Observe(config.NodeID{byte(i), byte(i >> 8), 1}, time.Millisecond)
+	}
+	if len(reg.peers) != MaxPeers {
+		t.Errorf("peers kept got %d, want %d", len(reg.peers), MaxPeers)
+	}
+	if _, ok := reg.Lookup(kept); !ok {
+		t.Errorf("connected peer evicted")
+	}
+}
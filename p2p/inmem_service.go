@@ -64,6 +64,18 @@ func NewInmemService() (*InmemService, error) {
 	return is, nil
 }
 
+// SetOutLink sets the mean delay in ms and the percent lost of messages sent,
+// to simulate the link of the node to others. it's to be called before started.
+func (is *InmemService) SetOutLink(delay int, miss int) {
+	is.lock.Lock()
+	defer is.lock.Unlock()
+	if delay < 1 {
+		delay = 1
+	}
+	is.outDelay = delay
+	is.outMiss = miss
+}
+
 func (is *InmemService) Start() error {
 	is.lock.Lock()
 	defer is.lock.Unlock()
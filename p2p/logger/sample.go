@@ -0,0 +1,190 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Sampling of logs for noisy debug paths: a call site checks its tag before
+// logging, and the rule set for the tag decides if it's emitted: one in every N,
+// or limited by a token bucket. tags without rules are always emitted. rules can
+// be set or cleared at any time, so debug can be turned on for a busy path in
+// production without flooding the logs.
+//
+const (
+	SampleEvery  = "every"  // one in every N
+	SampleBucket = "bucket" // token bucket, Rate tokens per second, Burst at most
+)
+
+type Sampling struct {
+	Kind  string  // SampleEvery or SampleBucket
+	Every uint64  // N for SampleEvery
+	Rate  float64 // tokens per second for SampleBucket
+	Burst float64 // bucket size for SampleBucket
+}
+
+type sampler struct {
+	rule    Sampling  // rule applied
+	count   uint64    // logs counted for SampleEvery
+	tokens  float64   // tokens left for SampleBucket
+	last    time.Time // last time tokens refilled
+	dropped uint64    // logs dropped since last one emitted
+}
+
+var sampleLock sync.Mutex
+var samplers = make(map[string]*sampler, 0)
+
+//
+// Set sampling rule for tag, the one set before is replaced
+//
+func SetSampling(tag string, rule Sampling) error {
+	switch rule.Kind {
+	case SampleEvery:
+		if rule.Every == 0 {
+			return errors.New("SetSampling: zero N")
+		}
+	case SampleBucket:
+		if rule.Rate <= 0 || rule.Burst < 1 {
+			return errors.New("SetSampling: invalid rate or burst")
+		}
+	default:
+		return fmt.Errorf("SetSampling: invalid kind: %s", rule.Kind)
+	}
+	sampleLock.Lock()
+	defer sampleLock.Unlock()
+	samplers[tag] = &sampler{
+		rule:   rule,
+		tokens: rule.Burst,
+		last:   time.Now(),
+	}
+	return nil
+}
+
+//
+// Clear sampling rule for tag, all logs with it are emitted then
+//
+func ClearSampling(tag string) {
+	sampleLock.Lock()
+	defer sampleLock.Unlock()
+	delete(samplers, tag)
+}
+
+//
+// Parse sampling rule, in format "tag:every:N" or "tag:bucket:rate:burst"
+//
+func ParseSampling(spec string) (string, Sampling, error) {
+	rule := Sampling{}
+	fields := strings.Split(spec, ":")
+	if len(fields) < 3 || len(fields[0]) == 0 {
+		return "", rule, fmt.Errorf("ParseSampling: invalid spec: %s", spec)
+	}
+	tag := fields[0]
+	rule.Kind = fields[1]
+	var err error
+	switch {
+	case rule.Kind == SampleEvery && len(fields) == 3:
+		rule.Every, err = strconv.ParseUint(fields[2], 10, 64)
+	case rule.Kind == SampleBucket && len(fields) == 4:
+		if rule.Rate, err = strconv.ParseFloat(fields[2], 64); err == nil {
+			rule.Burst, err = strconv.ParseFloat(fields[3], 64)
+		}
+	default:
+		err = errors.New("unknown kind or wrong number of fields")
+	}
+	if err != nil {
+		return "", rule, fmt.Errorf("ParseSampling: invalid spec: %s, error: %s", spec, err.Error())
+	}
+	return tag, rule, nil
+}
+
+//
+// Check if a log with tag should be emitted, and the number of logs dropped
+// since last one emitted
+//
+func sample(tag string) (bool, uint64) {
+	sampleLock.Lock()
+	defer sampleLock.Unlock()
+	s, ok := samplers[tag]
+	if !ok {
+		return true, 0
+	}
+	emit := false
+	switch s.rule.Kind {
+	case SampleEvery:
+		emit = s.count%s.rule.Every == 0
+		s.count++
+	case SampleBucket:
+		now := time.Now()
+		s.tokens += now.Sub(s.last).Seconds() * s.rule.Rate
+		if s.tokens > s.rule.Burst {
+			s.tokens = s.rule.Burst
+		}
+		s.last = now
+		if emit = s.tokens >= 1; emit {
+			s.tokens--
+		}
+	}
+	if !emit {
+		s.dropped++
+		return false, 0
+	}
+	dropped := s.dropped
+	s.dropped = 0
+	return true, dropped
+}
+
+//
+// Check if a log with tag should be emitted. a call site logging more lines for
+// one event should check once and emit all of them or none.
+//
+func Sampled(tag string) bool {
+	emit, _ := sample(tag)
+	return emit
+}
+
+//
+// Debug with sampling by tag, the number of logs dropped is appended
+//
+func SampledDebug(tag string, format string, args ...interface{}) {
+	emit, dropped := sample(tag)
+	if !emit {
+		return
+	}
+	text := fmt.Sprintf(format, args...)
+	if dropped > 0 {
+		text = fmt.Sprintf("%s (sampled, %d dropped)", text, dropped)
+	}
+	if !LogPosition {
+		log.Print(text)
+	} else {
+		_, file, line, _ := runtime.Caller(Skip)
+		log.Printf("%s\nfile: %s, line: %d", text, file, line)
+	}
+}
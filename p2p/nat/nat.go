@@ -513,7 +513,7 @@ _rsp2sender:
 }
 
 func (natMgr *NatManager) getConfig() NatEno {
-	cfg := config.P2pConfig4NatManager(natMgr.sdl.SchGetP2pCfgName())
+	cfg := natMgr.sdl.SchGetTaskConfig(natMgr.ptnMe).Config4NatManager()
 	natMgr.cfg.natType = fmt.Sprintf("%s", cfg.NatType)
 	natMgr.cfg.gwIp = append(natMgr.cfg.gwIp, cfg.GwIp...)
 	return NatEnoNone
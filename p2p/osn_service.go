@@ -25,11 +25,16 @@
 package p2p
 
 import (
+	"encoding/hex"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	yeeCfg "github.com/yeeco/gyee/config"
 	"github.com/yeeco/gyee/p2p/config"
+	p2plog "github.com/yeeco/gyee/p2p/logger"
+	"github.com/yeeco/gyee/p2p/peer"
 	yeelog "github.com/yeeco/gyee/utils/logging"
 	"github.com/yeeco/gyee/p2p/shell"
 )
@@ -61,12 +66,21 @@ func OsnServiceConfig(cfg *YeShellConfig, cfgFromFie interface{}) error {
 	//											节点身份不加区分，即一个节点要么同时是这两部分的
 	//											bootstrap节点，要么都不是；
 	//
+	// SeedOnly				bool				仅对bootstrap节点有效。bootstrap节点缺省不主动连接
+	//											也不接受连接；设置之后接受连接，在SeedGraceTime
+	//											之后主动断开，为新加入的节点保留连接资源；
+	//
+	// SeedGraceTime		time.Duration		SeedOnly模式下保持一个peer连接的时长；
+	//
 	// BootstrapNodes		[]string			peer部分的bootstrap节点列表；
 	//
 	// DhtBootstrapNodes	[]string			dht部分的bootstrap节点列表；
 	//
 	// LocalNodeIp			string				本地peer部分的IP地址
 	//
+	// LocalNodeAltIp		string				双栈节点另一地址族的IP地址，在握手中一并宣告，
+	//											为空则为单栈；
+	//
 	// LocalUdpPort			uint16				本地peer部分的UDP端口
 	//
 	// LocalTcpPort			uint16				本地peer部分的TCP端口
@@ -75,12 +89,119 @@ func OsnServiceConfig(cfg *YeShellConfig, cfgFromFie interface{}) error {
 	//
 	// LocalDhtPort			uint16				本地dht部分的TCP端口
 	//
+	// AdvertiseIp			string				向其他节点宣告的IP地址，用于监听地址（如0.0.0.0）
+	//											与对外地址不同的情形，如处于负载均衡之后；为空
+	//											则宣告本地地址。配置之后不再切换到nat映射的地址；
+	//
+	// AdvertiseUdpPort		uint16				向其他节点宣告的peer部分UDP端口，0为本地端口；
+	//
+	// AdvertiseTcpPort		uint16				向其他节点宣告的peer部分TCP端口，0为本地端口；
+	//
+	// AdvertiseDhtPort		uint16				向其他节点宣告的dht部分TCP端口，0为本地端口；
+	//
 	// NodeDataDir			string				本次实例的数据目录；
 	//
 	// NodeDatabase			string				本次实例的leveldb数据库名称（数据库所在目录）；
 	//
+	// DataKey				[]byte				节点数据库和dht存储的value静态加密（AES-GCM）的密钥，
+	//											由应用从节点的秘密派生，为空时不加密；
+	//
 	// SubNetMaskBits		int					子网所使用的掩码的比特数，0-15；
 	//
+	// AdaptiveSlots		bool				根据连接情况动态调整各子网inbound/outbound的配额：
+	//											inbound稀少（如处于nat之后）时向outbound倾斜，
+	//											inbound已满（公网可达的节点）时向inbound倾斜；
+	//
+	// SlotOutboundMin		int					AdaptiveSlots调整时outbound配额的下限，以子网
+	//											inbound+outbound总配额的百分比表示；
+	//
+	// SlotOutboundMax		int					AdaptiveSlots调整时outbound配额的上限，百分比；
+	//
+	// RxQueuePolicy		int					peer的接收队列满（应用来不及处理）时的策略：
+	//											config.RxqPolicyDrop，丢弃新收到的包；
+	//											config.RxqPolicyBlock，暂停读取连接直到入队，
+	//											借助tcp窗口对发送方形成反压，超过RxBlockTimeout
+	//											仍未入队则丢弃；config.RxqPolicyGrow，暂存
+	//											新收到的包，最多RxGrowMax个；配置文件中分别
+	//											为"drop"，"block"，"grow"；
+	//
+	// RxBlockTimeout		time.Duration		RxqPolicyBlock策略下最长的阻塞时长；
+	//
+	// RxGrowMax			int					RxqPolicyGrow策略下最多暂存的包数；
+	//
+	// StreamMaxSize		int					大于单帧上限的消息被分成多帧发送，由接收方重组，
+	//											本参数为这类消息的最大字节数；
+	//
+	// StreamTimeout		time.Duration		接收一个分帧消息全部帧的最长时间，超时则丢弃；
+	//
+	// AcceptResume			int					inbound连接总数达到上限时暂停接受连接，降至上限的
+	//											该百分比以下才恢复，避免在上限附近反复暂停和恢复；
+	//
+	// AcceptMinPause		time.Duration		暂停接受连接的最短时长，未满则推迟恢复；
+	//
+	// SelfProbeInterval	time.Duration		请几个已连接的peer探测本节点公布的tcp/udp地址是否可达的
+	//											周期，启动后先探测一次；不可达则不公布为可拨入，也不转发
+	//											消息；配置为负数则关闭探测；
+	//
+	// IpMaxInbounds		int					同一IP同时可建立的inbound连接数上限（含未完成握手的），
+	//											避免单个主机占满inbound连接；配置为负数则不限制；
+	//
+	// IpAcceptRate			int					每分钟接受同一IP连接的次数上限，按令牌桶控制，突发量为
+	//											一分钟的次数；配置为负数则不限制；环回地址和静态节点不受
+	//											以上两项限制；
+	//
+	// PeerTxRate			int					每个peer发送的带宽上限（字节/秒），0为不限制；
+	//											按令牌桶控制，突发量为一秒的流量，ping不受限；
+	//
+	// PeerRxRate			int					每个peer接收的带宽上限（字节/秒），0为不限制；
+	//											超出时暂停读取连接，借助tcp窗口对发送方限速；
+	//
+	// TotalTxRate			int					所有peer发送的总带宽上限（字节/秒），0为不限制；
+	//											各子网轮流分得相同的份额，子网内的peer轮流发送；
+	//
+	// HsAddrCheck			int					对inbound握手中对方声称的IP与连接的实际源IP进行
+	//											检查：config.HsAddrCheckNone，不检查；
+	//											config.HsAddrCheckWarn，不一致时仅记录日志；
+	//											config.HsAddrCheckOverride，以实际源IP代替；
+	//											config.HsAddrCheckReject，不一致则拒绝连接；
+	//											配置文件中分别为"none"，"warn"，"override"，
+	//											"reject"；
+	//
+	// HsAuthRequired		bool				要求对方的握手带有对nonce的签名（AuthSig），否则拒绝连接；
+	//											为false时仅对协商的p2p协议版本不低于
+	//											config.P2pProtoVerAuth的peer要求，兼容旧版本节点；
+	//
+	// HsNonceWindow		time.Duration		握手nonce中的时间与本地时钟之差的上限，超出则拒绝，
+	//											该时长内见过的nonce不得重放；时钟偏差较大的节点
+	//											可以调大；
+	//
+	// IpPreference			int					对宣告了双栈地址的peer优先拨号的地址族：
+	//											config.IpPreferAny，对方宣告的主地址；
+	//											config.IpPreferV4，ipv4；config.IpPreferV6，ipv6；
+	//											另一地址族在错开的延时之后或首选失败时拨号；
+	//											配置文件中分别为"any"，"v4"，"v6"；
+	//
+	// WsPort				uint16				peer部分websocket连接的TCP端口，在握手中宣告，
+	//											用于只允许http(s)出站的网络，0为不开启；
+	//
+	// Encryption			int					peer部分tcp连接的加密策略：config.PeerEncNone，
+	//											不加密；config.PeerEncOpportunistic，双方都支持
+	//											时以tls加密；config.PeerEncRequired，拒绝不支持
+	//											加密的peer；配置文件中分别为"none"，
+	//											"opportunistic"，"required"；
+	//
+	// SubNetEncryption		map[SubNetworkID]int	按子网配置加密策略，覆盖Encryption；配置文件中
+	//											每项格式为"子网ID(hex):策略"；
+	//
+	// ProxyAddr			string				经由socks5代理拨号peer，格式为"host:port"，为空
+	//											则直接拨号；ProxyUser/ProxyPassword为代理的用户
+	//											名和密码，为空则不认证；
+	//
+	// SubNetProxy			map[SubNetworkID]bool	按子网配置是否经由代理拨号，未列出的子网都经由
+	//											代理；配置文件中每项格式为"子网ID(hex):true|false"；
+	//
+	// CompressDisabled		bool				发送给peer的大的用户包不压缩，即使对方支持；
+	//
 	// EvKeepTime			time.Duration		event在dht中保留的时长；
 	//
 	// DedupTime			time.Duration		去重时钟管理器进行清理的周期时长；
@@ -93,6 +214,65 @@ func OsnServiceConfig(cfg *YeShellConfig, cfgFromFie interface{}) error {
 	//
 	// GatewayIp			string				当nat类型配置为"pmp"的时候相应的网关IP地址
 	//
+	// DisableChain			bool				不运行peer部分（chain overlay），不创建相应的
+	//											静态任务，也不监听LocalUdpPort/LocalTcpPort；
+	//											用于只提供dht存储的节点；
+	//
+	// DisableDht			bool				不运行dht部分，不创建相应的静态任务，也不监听
+	//											LocalDhtPort；用于只依赖静态peer的验证器。两者
+	//											不能同时为true；
+	//
+	// DhtChainBootstrap	bool				没有配置DhtBootstrapNodes时，从已连接的chain peer中
+	//											随机选取在握手中通告了dht端口的节点进行dht盲连接，
+	//											避免只配置了chain bootstrap节点的网络中dht无法启动；
+	//
+	// DhtVivaldi			bool				dht根据查询的往返时延计算网络坐标（Vivaldi），并在
+	//											应答中携带本地坐标；获取provider时按预测的时延
+	//											对结果排序，时延低的在前；
+	//
+	// DhtReplications		[]DhtReplication	按名字空间（key的前缀）配置dht put的副本数R，以及
+	//											需要确认存储的节点数Q：Q大于0时，put要求对方在
+	//											存储后应答确认，确认数不足Q时从其余的近邻节点中
+	//											继续尝试；前缀匹配最长者生效，没有匹配的按缺省
+	//											副本数且不要求确认。配置文件中每项格式为
+	//											"前缀(hex，可空):R:Q"；
+	//
+	// DhtAcls				[]DhtAcl			按名字空间（key的前缀）配置dht的写权限，对端的
+	//											PutValue/PutProvider据此检查：DhtAclOpen，任何
+	//											节点可写；DhtAclAllowlist，仅Writers中的节点可写
+	//											（比如只允许验证器写协议记录）；DhtAclLocalOnly，
+	//											仅本地可写，本地写入时也不发布到其他节点。前缀
+	//											匹配最长者生效，没有匹配的为DhtAclOpen。配置文件
+	//											中每项格式为"前缀(hex，可空):open"，"前缀:local"，
+	//											或者"前缀:allow:节点ID,节点ID,..."；
+	//
+	// DhtTuning			DhtTuning			dht查询和路由表的可调参数：QryMaxWidth，一次查询
+	//											最多询问的节点数；QryMaxDepth，查询的最大深度；
+	//											BucketSize，路由表每个桶的节点数；MaxLatency，
+	//											路由表接受的最大时延；BspRandomQryNum，每轮
+	//											bootstrap随机查询的次数；BspPeriod，bootstrap
+	//											的周期。为0的项取缺省值，超出范围时配置失败。
+	//											配置文件中为[network.dht_tuning]一节，时长以秒计；
+	//
+	// Discv4Enabled		bool				运行以太坊devp2p discv4的适配器：在Discv4Port上
+	//											应答ping/findnode，并在ping/pong的尾部附带本节点
+	//											的gyee记录；启动时（非bootstrap节点）先用
+	//											Discv4Nodes中的以太坊格式节点列表（enode url）
+	//											探测Discv4SeedTime时长，发现的gyee节点追加到
+	//											BootstrapNodes中；
+	//
+	// RandSeed				int64				p2p各调度器随机数源的种子，用于节点候选的选择、
+	//											路由表刷新的目标、冲突连接的延时等（密钥、签名
+	//											仍使用crypto/rand）；指定非0值时结果可以复现，
+	//											便于仿真和测试；为0时以当前时间为种子；
+	//
+	// LogSamplings			map[string]Sampling	按标签（调用点）对调试日志采样，用于消息繁忙的路径，
+	//											比如"peerMgrProc"，"peerInstProc"：SampleEvery，
+	//											每N条输出一条；SampleBucket，令牌桶限速，每秒Rate
+	//											条，最多积累Burst条。配置文件中每项格式为
+	//											"标签:every:N"或"标签:bucket:Rate:Burst"；运行中
+	//											也可以调用p2plog.SetSampling/ClearSampling修改；
+	//
 	// 注：如前所述，本函数应由应用根据具体情况（cfgFromFie的结构设计）实现并调用，但这不是必须的，应用
 	// 可以用任何方法构造合理的YeShellConfig结构，然后调用NewOsnService得到服务实例。
 	//
@@ -117,6 +297,7 @@ func OsnServiceConfig(cfg *YeShellConfig, cfgFromFie interface{}) error {
 
 	cfg.Validator = p2p.Validator
 	cfg.BootstrapNode = p2p.BootstrapNode
+	cfg.SeedOnly = p2p.SeedOnly
 	cfg.BootstrapNodes = make([]string, 0)
 	cfg.BootstrapNodes = append(cfg.BootstrapNodes, p2p.BootstrapNodes...)
 	cfg.DhtBootstrapNodes = make([]string, 0)
@@ -127,6 +308,7 @@ func OsnServiceConfig(cfg *YeShellConfig, cfgFromFie interface{}) error {
 	} else {
 		cfg.LocalNodeIp = p2p.LocalNodeIp
 	}
+	cfg.LocalNodeAltIp = p2p.LocalNodeAltIp
 
 	if p2p.LocalUdpPort == 0 {
 		yeelog.Logger.Infof("OsnServiceConfig: default LocalUdpPort: %s", cfg.LocalUdpPort)
@@ -152,6 +334,11 @@ func OsnServiceConfig(cfg *YeShellConfig, cfgFromFie interface{}) error {
 		cfg.LocalDhtPort = p2p.LocalDhtPort
 	}
 
+	cfg.AdvertiseIp = p2p.AdvertiseIp
+	cfg.AdvertiseUdpPort = p2p.AdvertiseUdpPort
+	cfg.AdvertiseTcpPort = p2p.AdvertiseTcpPort
+	cfg.AdvertiseDhtPort = p2p.AdvertiseDhtPort
+
 	if len(p2p.NodeDataDir) == 0 {
 		yeelog.Logger.Infof("OsnServiceConfig: default NodeDataDir: %s", cfg.NodeDataDir)
 	} else {
@@ -163,12 +350,114 @@ func OsnServiceConfig(cfg *YeShellConfig, cfgFromFie interface{}) error {
 	} else {
 		cfg.NodeDatabase = p2p.NodeDatabase
 	}
+	cfg.DataKey = p2p.DataKey
 
 	cfg.SubNetMaskBits = p2p.SubNetMaskBits
 	if cfg.SubNetMaskBits < 0 {
 		cfg.SubNetMaskBits = 0
 	}
 
+	cfg.AdaptiveSlots = p2p.AdaptiveSlots
+	if p2p.SlotOutboundMin > 0 {
+		cfg.SlotOutboundMin = p2p.SlotOutboundMin
+	}
+	if p2p.SlotOutboundMax > 0 {
+		cfg.SlotOutboundMax = p2p.SlotOutboundMax
+	}
+	if cfg.SlotOutboundMin >= cfg.SlotOutboundMax || cfg.SlotOutboundMax >= 100 {
+		return errors.New("OsnServiceConfig: invalid outbound slot bounds")
+	}
+
+	switch p2p.RxQueuePolicy {
+	case "", "drop":
+		cfg.RxQueuePolicy = config.RxqPolicyDrop
+	case "block":
+		cfg.RxQueuePolicy = config.RxqPolicyBlock
+	case "grow":
+		cfg.RxQueuePolicy = config.RxqPolicyGrow
+	default:
+		return errors.New("OsnServiceConfig: invalid rx queue policy: " + p2p.RxQueuePolicy)
+	}
+	if p2p.RxGrowMax > 0 {
+		cfg.RxGrowMax = p2p.RxGrowMax
+	}
+	switch p2p.HsAddrCheck {
+	case "", "none":
+		cfg.HsAddrCheck = config.HsAddrCheckNone
+	case "warn":
+		cfg.HsAddrCheck = config.HsAddrCheckWarn
+	case "override":
+		cfg.HsAddrCheck = config.HsAddrCheckOverride
+	case "reject":
+		cfg.HsAddrCheck = config.HsAddrCheckReject
+	default:
+		return errors.New("OsnServiceConfig: invalid handshake address check: " + p2p.HsAddrCheck)
+	}
+	cfg.HsAuthRequired = p2p.HsAuthRequired
+	switch p2p.IpPreference {
+	case "", "any":
+		cfg.IpPreference = config.IpPreferAny
+	case "v4":
+		cfg.IpPreference = config.IpPreferV4
+	case "v6":
+		cfg.IpPreference = config.IpPreferV6
+	default:
+		return errors.New("OsnServiceConfig: invalid ip preference: " + p2p.IpPreference)
+	}
+
+	cfg.WsPort = p2p.WsPort
+	enc, err := parsePeerEncryption(p2p.Encryption)
+	if err != nil {
+		return err
+	}
+	cfg.Encryption = enc
+	if len(p2p.SubNetEncryption) > 0 {
+		cfg.SubNetEncryption = make(map[config.SubNetworkID]int, 0)
+	}
+	for _, spec := range p2p.SubNetEncryption {
+		snid, policy, err := parseSubNetSpec(spec)
+		if err != nil {
+			return err
+		}
+		if cfg.SubNetEncryption[snid], err = parsePeerEncryption(policy); err != nil {
+			return err
+		}
+	}
+
+	if len(p2p.ProxyAddr) == 0 && (len(p2p.ProxyUser) != 0 || len(p2p.ProxyPassword) != 0) {
+		return errors.New("OsnServiceConfig: proxy user configured without proxy address")
+	}
+	cfg.ProxyAddr = p2p.ProxyAddr
+	cfg.ProxyUser = p2p.ProxyUser
+	cfg.ProxyPassword = p2p.ProxyPassword
+	if len(p2p.SubNetProxy) > 0 {
+		cfg.SubNetProxy = make(map[config.SubNetworkID]bool, 0)
+	}
+	for _, spec := range p2p.SubNetProxy {
+		snid, enabled, err := parseSubNetSpec(spec)
+		if err != nil {
+			return err
+		}
+		if cfg.SubNetProxy[snid], err = strconv.ParseBool(enabled); err != nil {
+			return errors.Errorf("OsnServiceConfig: invalid subnet proxy: %s", spec)
+		}
+	}
+	cfg.CompressDisabled = p2p.CompressDisabled
+	if p2p.StreamMaxSize > 0 {
+		cfg.StreamMaxSize = p2p.StreamMaxSize
+	}
+	if p2p.AcceptResume < 0 || p2p.AcceptResume > 100 {
+		return errors.New("OsnServiceConfig: invalid accept resume percent")
+	} else if p2p.AcceptResume > 0 {
+		cfg.AcceptResume = p2p.AcceptResume
+	}
+	if p2p.PeerTxRate < 0 || p2p.PeerRxRate < 0 || p2p.TotalTxRate < 0 {
+		return errors.New("OsnServiceConfig: invalid peer bandwidth rate")
+	}
+	cfg.PeerTxRate = p2p.PeerTxRate
+	cfg.PeerRxRate = p2p.PeerRxRate
+	cfg.TotalTxRate = p2p.TotalTxRate
+
 	factor := int64(time.Second /time.Nanosecond)
 	if p2p.EvKeepTime <= 0 {
 		yeelog.Logger.Infof("OsnServiceConfig: default EvKeepTime: %d(s)", int64(cfg.EvKeepTime)/factor)
@@ -188,12 +477,200 @@ func OsnServiceConfig(cfg *YeShellConfig, cfgFromFie interface{}) error {
 		cfg.BootstrapTime = time.Duration(int64(p2p.BootstrapTime) * factor)
 	}
 
+	if p2p.SeedGraceTime <= 0 {
+		yeelog.Logger.Infof("OsnServiceConfig: default SeedGraceTime: %d(s)", int64(cfg.SeedGraceTime)/factor)
+	} else {
+		cfg.SeedGraceTime = time.Duration(int64(p2p.SeedGraceTime) * factor)
+	}
+
+	if p2p.RxBlockTimeout <= 0 {
+		yeelog.Logger.Infof("OsnServiceConfig: default RxBlockTimeout: %d(s)", int64(cfg.RxBlockTimeout)/factor)
+	} else {
+		cfg.RxBlockTimeout = time.Duration(int64(p2p.RxBlockTimeout) * factor)
+	}
+
+	if p2p.StreamTimeout <= 0 {
+		yeelog.Logger.Infof("OsnServiceConfig: default StreamTimeout: %d(s)", int64(cfg.StreamTimeout)/factor)
+	} else {
+		cfg.StreamTimeout = time.Duration(int64(p2p.StreamTimeout) * factor)
+	}
+
+	if p2p.AcceptMinPause <= 0 {
+		yeelog.Logger.Infof("OsnServiceConfig: default AcceptMinPause: %d(s)", int64(cfg.AcceptMinPause)/factor)
+	} else {
+		cfg.AcceptMinPause = time.Duration(int64(p2p.AcceptMinPause) * factor)
+	}
+
+	if p2p.HsNonceWindow <= 0 {
+		yeelog.Logger.Infof("OsnServiceConfig: default HsNonceWindow: %d(s)", int64(cfg.HsNonceWindow)/factor)
+	} else {
+		cfg.HsNonceWindow = time.Duration(int64(p2p.HsNonceWindow) * factor)
+	}
+
+	if p2p.SelfProbeInterval < 0 {
+		cfg.SelfProbeInterval = 0
+	} else if p2p.SelfProbeInterval == 0 {
+		yeelog.Logger.Infof("OsnServiceConfig: default SelfProbeInterval: %d(s)", int64(cfg.SelfProbeInterval)/factor)
+	} else {
+		cfg.SelfProbeInterval = time.Duration(int64(p2p.SelfProbeInterval) * factor)
+	}
+
+	if p2p.IpMaxInbounds < 0 {
+		cfg.IpMaxInbounds = 0
+	} else if p2p.IpMaxInbounds > 0 {
+		cfg.IpMaxInbounds = p2p.IpMaxInbounds
+	}
+	if p2p.IpAcceptRate < 0 {
+		cfg.IpAcceptRate = 0
+	} else if p2p.IpAcceptRate > 0 {
+		cfg.IpAcceptRate = p2p.IpAcceptRate
+	}
+
 	cfg.NatType = p2p.NatType
 	cfg.GatewayIp = p2p.GatewayIp
 
+	if p2p.DisableChain && p2p.DisableDht {
+		return errors.New("OsnServiceConfig: chain and dht can not be both disabled")
+	}
+	cfg.DisableChain = p2p.DisableChain
+	cfg.DisableDht = p2p.DisableDht
+	cfg.DhtChainBootstrap = p2p.DhtChainBootstrap
+	cfg.DhtVivaldi = p2p.DhtVivaldi
+
+	cfg.DhtReplications = make([]config.DhtReplication, 0)
+	for _, spec := range p2p.DhtReplication {
+		rep, err := parseDhtReplication(spec)
+		if err != nil {
+			return err
+		}
+		cfg.DhtReplications = append(cfg.DhtReplications, rep)
+	}
+
+	cfg.DhtAcls = make([]config.DhtAcl, 0)
+	for _, spec := range p2p.DhtAcl {
+		acl, err := parseDhtAcl(spec)
+		if err != nil {
+			return err
+		}
+		cfg.DhtAcls = append(cfg.DhtAcls, acl)
+	}
+
+	cfg.DhtTuning = config.DhtTuning{
+		QryMaxWidth:     p2p.DhtTuning.QryMaxWidth,
+		QryMaxDepth:     p2p.DhtTuning.QryMaxDepth,
+		BucketSize:      p2p.DhtTuning.BucketSize,
+		MaxLatency:      time.Duration(int64(p2p.DhtTuning.MaxLatency) * factor),
+		BspRandomQryNum: p2p.DhtTuning.BootstrapQryNum,
+		BspPeriod:       time.Duration(int64(p2p.DhtTuning.BootstrapPeriod) * factor),
+	}
+	if err := config.P2pCheckDhtTuning(&cfg.DhtTuning); err != nil {
+		return errors.Errorf("OsnServiceConfig: %s", err.Error())
+	}
+
+	cfg.Discv4Enabled = p2p.Discv4Enabled
+	if p2p.Discv4Port != 0 {
+		cfg.Discv4Port = p2p.Discv4Port
+	}
+	cfg.Discv4Nodes = p2p.Discv4Nodes
+	if p2p.Discv4SeedTime > 0 {
+		cfg.Discv4SeedTime = time.Duration(int64(p2p.Discv4SeedTime) * factor)
+	}
+
+	cfg.RandSeed = p2p.RandSeed
+
+	if len(p2p.LogSampling) > 0 {
+		cfg.LogSamplings = make(map[string]p2plog.Sampling, 0)
+	}
+	for _, spec := range p2p.LogSampling {
+		tag, rule, err := p2plog.ParseSampling(spec)
+		if err != nil {
+			return err
+		}
+		cfg.LogSamplings[tag] = rule
+	}
+
 	return nil
 }
 
+func parsePeerEncryption(policy string) (int, error) {
+	switch policy {
+	case "", "none":
+		return config.PeerEncNone, nil
+	case "opportunistic":
+		return config.PeerEncOpportunistic, nil
+	case "required":
+		return config.PeerEncRequired, nil
+	}
+	return config.PeerEncNone, errors.New("OsnServiceConfig: invalid encryption: " + policy)
+}
+
+func parseSubNetSpec(spec string) (config.SubNetworkID, string, error) {
+	snid := config.SubNetworkID{}
+	fields := strings.Split(spec, ":")
+	if len(fields) != 2 {
+		return snid, "", errors.Errorf("OsnServiceConfig: invalid subnet spec: %s", spec)
+	}
+	id, err := hex.DecodeString(fields[0])
+	if err != nil || len(id) != config.SubNetIdBytes {
+		return snid, "", errors.Errorf("OsnServiceConfig: invalid subnet identity: %s", spec)
+	}
+	copy(snid[:], id)
+	return snid, fields[1], nil
+}
+
+func parseDhtAcl(spec string) (config.DhtAcl, error) {
+	acl := config.DhtAcl{}
+	fields := strings.SplitN(spec, ":", 3)
+	if len(fields) < 2 {
+		return acl, errors.Errorf("OsnServiceConfig: invalid dht acl: %s", spec)
+	}
+	prefix, err := hex.DecodeString(fields[0])
+	if err != nil {
+		return acl, errors.Errorf("OsnServiceConfig: invalid dht acl prefix: %s", spec)
+	}
+	acl.Prefix = prefix
+	switch {
+	case fields[1] == "open" && len(fields) == 2:
+		acl.Policy = config.DhtAclOpen
+	case fields[1] == "local" && len(fields) == 2:
+		acl.Policy = config.DhtAclLocalOnly
+	case fields[1] == "allow" && len(fields) == 3:
+		acl.Policy = config.DhtAclAllowlist
+		for _, str := range strings.Split(fields[2], ",") {
+			id, err := config.P2pString2NodeId(str)
+			if err != nil {
+				return acl, errors.Errorf("OsnServiceConfig: invalid dht acl writer: %s", spec)
+			}
+			acl.Writers = append(acl.Writers, *id)
+		}
+	default:
+		return acl, errors.Errorf("OsnServiceConfig: invalid dht acl policy: %s", spec)
+	}
+	return acl, nil
+}
+
+func parseDhtReplication(spec string) (config.DhtReplication, error) {
+	rep := config.DhtReplication{}
+	fields := strings.Split(spec, ":")
+	if len(fields) != 3 {
+		return rep, errors.Errorf("OsnServiceConfig: invalid dht replication: %s", spec)
+	}
+	prefix, err := hex.DecodeString(fields[0])
+	if err != nil {
+		return rep, errors.Errorf("OsnServiceConfig: invalid dht replication prefix: %s", spec)
+	}
+	r, err := strconv.Atoi(fields[1])
+	if err != nil || r <= 0 {
+		return rep, errors.Errorf("OsnServiceConfig: invalid dht replication factor: %s", spec)
+	}
+	q, err := strconv.Atoi(fields[2])
+	if err != nil || q < 0 || q > r {
+		return rep, errors.Errorf("OsnServiceConfig: invalid dht replication quorum: %s", spec)
+	}
+	rep.Prefix, rep.R, rep.Q = prefix, r, q
+	return rep, nil
+}
+
 func NewOsnServiceWithCfg(cfg *yeeCfg.Config) (*OsnService, error) {
 	yeShellCfg := DefaultYeShellConfig
 	if err := OsnServiceConfig(&yeShellCfg, cfg); err != nil {
@@ -250,6 +727,14 @@ func (osns *OsnService) DhtSetValue(key []byte, value []byte) error {
 	return osns.yeShMgr.DhtSetValue(key, value)
 }
 
+func (osns *OsnService) DhtSetValues(kvs []DhtKeyValue) []error {
+	return osns.yeShMgr.(*YeShellManager).DhtSetValues(kvs)
+}
+
+func (osns *OsnService) DhtGetValues(keys [][]byte) ([][]byte, []error) {
+	return osns.yeShMgr.(*YeShellManager).DhtGetValues(keys)
+}
+
 func (osns *OsnService) RegChainProvider(cp ChainProvider) {
 	osns.yeShMgr.RegChainProvider(cp)
 }
@@ -265,3 +750,103 @@ func (osns *OsnService) GetLocalNode() *config.Node {
 func (osns *OsnService) GetLocalDhtNode() *config.Node {
 	return osns.yeShMgr.(*YeShellManager).GetLocalDhtNode()
 }
+
+func (osns *OsnService) GetPeerVersions() (map[string]int, error) {
+	return osns.yeShMgr.(*YeShellManager).GetPeerVersions()
+}
+
+func (osns *OsnService) GetAcceptStats() (*AcceptStats, error) {
+	return osns.yeShMgr.(*YeShellManager).GetAcceptStats()
+}
+
+func (osns *OsnService) GetKillStats() ([]KillStat, error) {
+	return osns.yeShMgr.(*YeShellManager).GetKillStats()
+}
+
+func (osns *OsnService) GetMsgStats() ([]peer.MsgStat, error) {
+	return osns.yeShMgr.(*YeShellManager).GetMsgStats()
+}
+
+func (osns *OsnService) GetActivePeers() ([]string, error) {
+	return osns.yeShMgr.(*YeShellManager).GetActivePeers()
+}
+
+func (osns *OsnService) GetChainInfoFrom(peer string, kind string, key []byte) ([]byte, error) {
+	return osns.yeShMgr.(*YeShellManager).GetChainInfoFrom(peer, kind, key)
+}
+
+func (osns *OsnService) Peers() ([]PeerSummary, error) {
+	return osns.yeShMgr.(*YeShellManager).Peers()
+}
+
+func (osns *OsnService) SetHead(height uint64) {
+	osns.yeShMgr.(*YeShellManager).SetHead(height)
+}
+
+func (osns *OsnService) SetPeerHead(peer string, height uint64) {
+	osns.yeShMgr.(*YeShellManager).SetPeerHead(peer, height)
+}
+
+func (osns *OsnService) GetPartition() (*PartitionEvent, error) {
+	return osns.yeShMgr.(*YeShellManager).GetPartition()
+}
+
+func (osns *OsnService) WatchPartition(ch chan *PartitionEvent) {
+	osns.yeShMgr.(*YeShellManager).WatchPartition(ch)
+}
+
+func (osns *OsnService) UnwatchPartition(ch chan *PartitionEvent) {
+	osns.yeShMgr.(*YeShellManager).UnwatchPartition(ch)
+}
+
+func (osns *OsnService) RegisterFastPath(msgType string, size int) (*peer.FastRing, error) {
+	return osns.yeShMgr.(*YeShellManager).RegisterFastPath(msgType, size)
+}
+
+func (osns *OsnService) UnregisterFastPath(msgType string) error {
+	return osns.yeShMgr.(*YeShellManager).UnregisterFastPath(msgType)
+}
+
+func (osns *OsnService) RegisterProtocol(pid uint32, ver [4]byte, handler peer.ProtocolHandler) error {
+	return osns.yeShMgr.(*YeShellManager).RegisterProtocol(pid, ver, handler)
+}
+
+func (osns *OsnService) RegisterProtocolPool(pid uint32, ver [4]byte, handler peer.ProtocolHandler, pool peer.ProtocolPool) error {
+	return osns.yeShMgr.(*YeShellManager).RegisterProtocolPool(pid, ver, handler, pool)
+}
+
+func (osns *OsnService) UnregisterProtocol(pid uint32) error {
+	return osns.yeShMgr.(*YeShellManager).UnregisterProtocol(pid)
+}
+
+func (osns *OsnService) AddStatic(url string) error {
+	return osns.yeShMgr.(*YeShellManager).AddStatic(url)
+}
+
+func (osns *OsnService) RemovePeer(id string) error {
+	return osns.yeShMgr.(*YeShellManager).RemovePeer(id)
+}
+
+func (osns *OsnService) BanPeer(id string, duration time.Duration) error {
+	return osns.yeShMgr.(*YeShellManager).BanPeer(id, duration)
+}
+
+func (osns *OsnService) UnbanPeer(id string) error {
+	return osns.yeShMgr.(*YeShellManager).UnbanPeer(id)
+}
+
+func (osns *OsnService) GetNodeInfo() (*NodeInfo, error) {
+	return osns.yeShMgr.(*YeShellManager).GetNodeInfo()
+}
+
+func (osns *OsnService) Ping(id string, size int, count int) (*PingStats, error) {
+	return osns.yeShMgr.(*YeShellManager).Ping(id, size, count)
+}
+
+func (osns *OsnService) Lookup(snid config.SubNetworkID, target config.NodeID) ([]*config.Node, error) {
+	return osns.yeShMgr.(*YeShellManager).Lookup(snid, target)
+}
+
+func (osns *OsnService) DhtFindNode(target *config.NodeID, done chan interface{}) error {
+	return osns.yeShMgr.(*YeShellManager).DhtFindNode(target, done)
+}
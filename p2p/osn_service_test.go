@@ -0,0 +1,91 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	yeeCfg "github.com/yeeco/gyee/config"
+	"github.com/yeeco/gyee/p2p/config"
+)
+
+func TestOsnServiceConfigToml(t *testing.T) {
+	const network = `
+[network]
+app_type = 2
+local_node_alt_ip = "fd00::1"
+hs_auth_required = true
+hs_nonce_window = 600
+ip_preference = "v6"
+ws_port = 8080
+encryption = "opportunistic"
+subnet_encryption = ["8000:required", "0001:none"]
+proxy_addr = "127.0.0.1:1080"
+proxy_user = "user"
+proxy_password = "password"
+subnet_proxy = ["0001:false"]
+compress_disabled = true
+`
+	yc := new(yeeCfg.Config)
+	if _, err := toml.Decode(network, yc); err != nil {
+		t.Fatalf("decode failed: %s", err.Error())
+	}
+	cfg := DefaultYeShellConfig
+	if err := OsnServiceConfig(&cfg, yc); err != nil {
+		t.Fatalf("OsnServiceConfig failed: %s", err.Error())
+	}
+	snid := config.SubNetworkID{0x00, 0x01}
+	if cfg.LocalNodeAltIp != "fd00::1" || cfg.IpPreference != config.IpPreferV6 || cfg.WsPort != 8080 {
+		t.Fatalf("address options not set: %s, %d, %d", cfg.LocalNodeAltIp, cfg.IpPreference, cfg.WsPort)
+	}
+	if !cfg.HsAuthRequired || cfg.HsNonceWindow != time.Minute*10 {
+		t.Fatalf("handshake options not set: %t, %s", cfg.HsAuthRequired, cfg.HsNonceWindow)
+	}
+	if cfg.Encryption != config.PeerEncOpportunistic ||
+		cfg.SubNetEncryption[config.ZeroSubNet] != config.PeerEncRequired ||
+		cfg.SubNetEncryption[snid] != config.PeerEncNone {
+		t.Fatalf("encryption not set: %d, %v", cfg.Encryption, cfg.SubNetEncryption)
+	}
+	if cfg.ProxyAddr != "127.0.0.1:1080" || cfg.ProxyUser != "user" || cfg.ProxyPassword != "password" ||
+		cfg.SubNetProxy[snid] != false || len(cfg.SubNetProxy) != 1 {
+		t.Fatalf("proxy not set: %s, %v", cfg.ProxyAddr, cfg.SubNetProxy)
+	}
+	if !cfg.CompressDisabled {
+		t.Fatalf("compression not disabled")
+	}
+
+	for _, bad := range []string{
+		`encryption = "always"`,
+		`subnet_encryption = ["80:required"]`,
+		`subnet_proxy = ["8000:maybe"]`,
+		`ip_preference = "v5"`,
+		`proxy_user = "user"`,
+	} {
+		yc := new(yeeCfg.Config)
+		toml.Decode("[network]\napp_type = 2\n"+bad, yc)
+		cfg := DefaultYeShellConfig
+		if OsnServiceConfig(&cfg, yc) == nil {
+			t.Fatalf("invalid option accepted: %s", bad)
+		}
+	}
+}
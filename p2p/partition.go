@@ -0,0 +1,342 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package p2p
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/p2p/config"
+	"github.com/yeeco/gyee/p2p/dht"
+)
+
+//
+// Partition detector: a possible network partition is raised when any of the
+// signals below is seen, and cleared when none of them is seen any more:
+// 1) peers lost: long-lived peers(those active for LongLived at least) lost
+// within Window are LostRatio or more of the long-lived ones;
+// 2) head diverged: our head is HeadLag or more blocks away from the median of
+// the heads advertised by peers. p2p knows nothing about blocks, so heads are
+// reported by the consensus, see PartitionDetector;
+// 3) dht failures: DhtFailRatio or more of the dht queries done within Window
+// failed.
+// each of the signals needs MinSamples samples at least, to be not raised by
+// a few peers or queries. events are told to watchers registered, operators and
+// the consensus, which might delay finality claims while a partition possible.
+//
+
+const (
+	PartitionPeersLost   = "peersLost"    // long-lived peers lost
+	PartitionHeadDiverge = "headDiverged" // head diverged from the median of peers
+	PartitionDhtFailures = "dhtFailures"  // dht queries failed
+)
+
+const (
+	partitionTick       = time.Second * 5  // interval to check signals
+	DftPartitionWindow  = time.Minute      // default Window
+	DftPartitionLived   = time.Minute * 10 // default LongLived
+	DftPartitionLost    = 0.5              // default LostRatio
+	DftPartitionHeadLag = 10               // default HeadLag
+	DftPartitionHeadAge = time.Minute * 5  // default HeadAge
+	DftPartitionDhtFail = 0.5              // default DhtFailRatio
+	DftPartitionSamples = 4                // default MinSamples
+)
+
+// Tunables of the partition detector, zero fields for defaults
+type PartitionConfig struct {
+	Window       time.Duration // window lost peers and dht queries counted in
+	LongLived    time.Duration // min duration a peer active to be long-lived
+	LostRatio    float64       // ratio of long-lived peers lost to raise
+	HeadLag      uint64        // blocks away from the median head to raise
+	HeadAge      time.Duration // max age of heads advertised by peers
+	DhtFailRatio float64       // ratio of dht queries failed to raise
+	MinSamples   int           // min peers or queries for a signal
+}
+
+// Possible partition raised, or cleared when Possible is false
+type PartitionEvent struct {
+	Time       time.Time // time detected
+	Possible   bool      // possible partition, false if cleared
+	Signals    []string  // signals seen, PartitionXXX
+	LongLived  int       // long-lived peers, including those lost
+	PeersLost  int       // long-lived peers lost within the window
+	Head       uint64    // our head
+	MedianHead uint64    // median of heads advertised by peers
+	Heads      int       // heads advertised by peers
+	DhtQueries int       // dht queries done within the window
+	DhtFailed  int       // dht queries failed within the window
+}
+
+type peerHead struct {
+	height uint64    // height advertised
+	at     time.Time // time reported
+}
+
+type dhtOutcome struct {
+	failed bool      // failed
+	at     time.Time // time done
+}
+
+type partitionDetector struct {
+	lock     sync.Mutex                    // lock to protect all below
+	cfg      PartitionConfig               // configuration
+	since    map[config.NodeID]time.Time   // active peers and the time seen active
+	lost     []time.Time                   // long-lived peers lost, oldest first
+	head     uint64                        // our head
+	heads    map[string]peerHead           // heads advertised by peers
+	dhtDone  []dhtOutcome                  // dht queries done, oldest first
+	state    PartitionEvent                // the last event
+	watchers map[chan *PartitionEvent]bool // channels to tell events
+}
+
+func newPartitionDetector(cfg PartitionConfig) *partitionDetector {
+	if cfg.Window <= 0 {
+		cfg.Window = DftPartitionWindow
+	}
+	if cfg.LongLived <= 0 {
+		cfg.LongLived = DftPartitionLived
+	}
+	if cfg.LostRatio <= 0 {
+		cfg.LostRatio = DftPartitionLost
+	}
+	if cfg.HeadLag == 0 {
+		cfg.HeadLag = DftPartitionHeadLag
+	}
+	if cfg.HeadAge <= 0 {
+		cfg.HeadAge = DftPartitionHeadAge
+	}
+	if cfg.DhtFailRatio <= 0 {
+		cfg.DhtFailRatio = DftPartitionDhtFail
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = DftPartitionSamples
+	}
+	return &partitionDetector{
+		cfg:      cfg,
+		since:    make(map[config.NodeID]time.Time, 0),
+		heads:    make(map[string]peerHead, 0),
+		watchers: make(map[chan *PartitionEvent]bool, 0),
+	}
+}
+
+// Active peers polled, those gone since the last poll are lost
+func (pd *partitionDetector) activePeers(ids []config.NodeID, now time.Time) {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	active := make(map[config.NodeID]bool, len(ids))
+	for _, id := range ids {
+		active[id] = true
+		if _, ok := pd.since[id]; !ok {
+			pd.since[id] = now
+		}
+	}
+	for id, t := range pd.since {
+		if active[id] {
+			continue
+		}
+		if now.Sub(t) >= pd.cfg.LongLived {
+			pd.lost = append(pd.lost, now)
+		}
+		delete(pd.since, id)
+	}
+}
+
+func (pd *partitionDetector) setHead(height uint64) {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	pd.head = height
+}
+
+func (pd *partitionDetector) setPeerHead(peer string, height uint64, now time.Time) {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	pd.heads[peer] = peerHead{height: height, at: now}
+}
+
+func (pd *partitionDetector) dhtQueried(failed bool, now time.Time) {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	pd.dhtDone = append(pd.dhtDone, dhtOutcome{failed: failed, at: now})
+}
+
+// Check the signals, the event returned if the state changed, nil if not
+func (pd *partitionDetector) check(now time.Time) *PartitionEvent {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+
+	from := now.Add(-pd.cfg.Window)
+	for len(pd.lost) > 0 && pd.lost[0].Before(from) {
+		pd.lost = pd.lost[1:]
+	}
+	for len(pd.dhtDone) > 0 && pd.dhtDone[0].at.Before(from) {
+		pd.dhtDone = pd.dhtDone[1:]
+	}
+	for peer, ph := range pd.heads {
+		if now.Sub(ph.at) > pd.cfg.HeadAge {
+			delete(pd.heads, peer)
+		}
+	}
+
+	ev := PartitionEvent{
+		Time:      now,
+		PeersLost: len(pd.lost),
+		Head:      pd.head,
+		Heads:     len(pd.heads),
+	}
+	for _, t := range pd.since {
+		if now.Sub(t) >= pd.cfg.LongLived {
+			ev.LongLived++
+		}
+	}
+	ev.LongLived += ev.PeersLost
+	if ev.LongLived >= pd.cfg.MinSamples &&
+		float64(ev.PeersLost) >= pd.cfg.LostRatio*float64(ev.LongLived) {
+		ev.Signals = append(ev.Signals, PartitionPeersLost)
+	}
+
+	if ev.Heads >= pd.cfg.MinSamples {
+		heights := make([]uint64, 0, ev.Heads)
+		for _, ph := range pd.heads {
+			heights = append(heights, ph.height)
+		}
+		sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+		ev.MedianHead = heights[len(heights)/2]
+		if ev.MedianHead >= ev.Head+pd.cfg.HeadLag || ev.Head >= ev.MedianHead+pd.cfg.HeadLag {
+			ev.Signals = append(ev.Signals, PartitionHeadDiverge)
+		}
+	}
+
+	ev.DhtQueries = len(pd.dhtDone)
+	for _, o := range pd.dhtDone {
+		if o.failed {
+			ev.DhtFailed++
+		}
+	}
+	if ev.DhtQueries >= pd.cfg.MinSamples &&
+		float64(ev.DhtFailed) >= pd.cfg.DhtFailRatio*float64(ev.DhtQueries) {
+		ev.Signals = append(ev.Signals, PartitionDhtFailures)
+	}
+
+	ev.Possible = len(ev.Signals) > 0
+	changed := ev.Possible != pd.state.Possible || !sameSignals(ev.Signals, pd.state.Signals)
+	pd.state = ev
+	if !changed {
+		return nil
+	}
+	if ev.Possible {
+		log.Warn("possible network partition", "signals", ev.Signals,
+			"lost", ev.PeersLost, "longLived", ev.LongLived, "head", ev.Head, "median", ev.MedianHead,
+			"dhtFailed", ev.DhtFailed, "dhtQueries", ev.DhtQueries)
+	} else {
+		log.Info("network partition cleared")
+	}
+	for ch := range pd.watchers {
+		tell := ev
+		select {
+		case ch <- &tell:
+		default:
+		}
+	}
+	return &ev
+}
+
+func sameSignals(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// The last event checked
+func (pd *partitionDetector) current() *PartitionEvent {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	ev := pd.state
+	return &ev
+}
+
+// Events are told to ch without blocking, they are dropped if ch is full
+func (pd *partitionDetector) watch(ch chan *PartitionEvent, on bool) {
+	pd.lock.Lock()
+	defer pd.lock.Unlock()
+	if on {
+		pd.watchers[ch] = true
+	} else {
+		delete(pd.watchers, ch)
+	}
+}
+
+// Dht queries failed for the network, not for what asked is not there
+func dhtQueryFailed(eno int) bool {
+	switch dht.DhtErrno(eno) {
+	case dht.DhtEnoTimeout, dht.DhtEnoRoute, dht.DhtEnoResource:
+		return true
+	}
+	return false
+}
+
+func (yeShMgr *YeShellManager) partitionProc() {
+	ticker := time.NewTicker(partitionTick)
+	defer ticker.Stop()
+_ptnLoop:
+	for {
+		select {
+		case now := <-ticker.C:
+			if yeShMgr.ptChainShMgr != nil {
+				yeShMgr.partition.activePeers(yeShMgr.ptChainShMgr.GetActivePeers(), now)
+			}
+			yeShMgr.partition.check(now)
+		case <-yeShMgr.ptnDoneChan:
+			break _ptnLoop
+		}
+	}
+	yesLog.Debug("partitionProc: exit")
+}
+
+// SetHead tells the height of our head, see PartitionDetector
+func (yeShMgr *YeShellManager) SetHead(height uint64) {
+	yeShMgr.partition.setHead(height)
+}
+
+// SetPeerHead tells the height of the head advertised by peer, in canonical
+// textual format, see PartitionDetector
+func (yeShMgr *YeShellManager) SetPeerHead(peer string, height uint64) {
+	yeShMgr.partition.setPeerHead(peer, height, time.Now())
+}
+
+func (yeShMgr *YeShellManager) GetPartition() (*PartitionEvent, error) {
+	return yeShMgr.partition.current(), nil
+}
+
+func (yeShMgr *YeShellManager) WatchPartition(ch chan *PartitionEvent) {
+	yeShMgr.partition.watch(ch, true)
+}
+
+func (yeShMgr *YeShellManager) UnwatchPartition(ch chan *PartitionEvent) {
+	yeShMgr.partition.watch(ch, false)
+}
@@ -0,0 +1,93 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yeeco/gyee/p2p/config"
+	"github.com/yeeco/gyee/p2p/dht"
+)
+
+func TestPartitionDetector(t *testing.T) {
+	pd := newPartitionDetector(PartitionConfig{})
+	ch := make(chan *PartitionEvent, 8)
+	pd.watch(ch, true)
+	now := time.Now()
+
+	ids := make([]config.NodeID, 8)
+	for i := range ids {
+		ids[i][0] = byte(i + 1)
+	}
+	pd.activePeers(ids, now)
+	now = now.Add(DftPartitionLived)
+	if ev := pd.check(now); ev != nil {
+		t.Fatalf("raised with nothing seen: %+v", ev)
+	}
+
+	// half of the long-lived peers lost, then a new one seen is not counted
+	pd.activePeers(append(ids[4:], config.NodeID{0xff}), now)
+	ev := pd.check(now)
+	if ev == nil || !ev.Possible || !sameSignals(ev.Signals, []string{PartitionPeersLost}) ||
+		ev.PeersLost != 4 || ev.LongLived != 8 {
+		t.Fatalf("peers lost got %+v", ev)
+	}
+	if got := <-ch; got.PeersLost != ev.PeersLost {
+		t.Errorf("watcher told %+v", got)
+	}
+	if ev := pd.check(now); ev != nil {
+		t.Errorf("told again without changed: %+v", ev)
+	}
+
+	// out of the window, cleared
+	now = now.Add(DftPartitionWindow + time.Second)
+	if ev := pd.check(now); ev == nil || ev.Possible {
+		t.Fatalf("not cleared: %+v", ev)
+	}
+
+	// our head behind the median
+	pd.setHead(100)
+	for i, h := range []uint64{100, 120, 121, 122} {
+		pd.setPeerHead(config.P2pNodeId2String(ids[i]), h, now)
+	}
+	if ev := pd.check(now); ev == nil || !sameSignals(ev.Signals, []string{PartitionHeadDiverge}) || ev.MedianHead != 121 {
+		t.Fatalf("head diverged got %+v", ev)
+	}
+	pd.setHead(118)
+	if ev := pd.check(now); ev == nil || ev.Possible {
+		t.Fatalf("head caught up got %+v", ev)
+	}
+
+	// dht failures, those not found are not
+	for _, eno := range []dht.DhtErrno{dht.DhtEnoNone, dht.DhtEnoNotFound, dht.DhtEnoTimeout, dht.DhtEnoTimeout, dht.DhtEnoRoute} {
+		pd.dhtQueried(dhtQueryFailed(int(eno)), now)
+	}
+	if ev := pd.check(now); ev == nil || !sameSignals(ev.Signals, []string{PartitionDhtFailures}) ||
+		ev.DhtQueries != 5 || ev.DhtFailed != 3 {
+		t.Fatalf("dht failures got %+v", ev)
+	}
+
+	pd.watch(ch, false)
+	if got := pd.current(); !got.Possible {
+		t.Errorf("current got %+v", got)
+	}
+}
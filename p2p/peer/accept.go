@@ -0,0 +1,170 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"sync"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+//
+// Accepter pausing: the accepter is paused when the total inbound peers reach
+// the limit, and resumed only when they fall to the resume threshold, in percent
+// of the limit, and it has been paused for the min pause duration, so it's not
+// stopped and restarted for each inbound peer accepted and killed at the limit.
+// it's accessed in the peer manager task only, except the counters.
+//
+
+// Statistics of the accepter pausing, and connections closed for the quota per ip
+type AcceptStats struct {
+	Paused    bool  // accepter paused now
+	Pauses    int64 // times the accepter paused for inbound peers full
+	Resumes   int64 // times the accepter resumed
+	Deferred  int64 // times resuming deferred for the min pause duration
+	IpFull    int64 // connections closed for too many inbound instances of the remote ip
+	IpLimited int64 // connections closed for accepted too fast from the remote ip
+}
+
+type acceptPause struct {
+	paused   bool       // accepter paused
+	pausedAt time.Time  // time paused
+	tid      int        // timer to resume when the min pause duration elapsed
+	lock     sync.Mutex // sync for stats, read by GetAcceptStats
+	stats    AcceptStats
+}
+
+func newAcceptPause() *acceptPause {
+	return &acceptPause{
+		tid: sch.SchInvalidTid,
+	}
+}
+
+func (ap *acceptPause) count(paused bool, counter *int64) {
+	ap.lock.Lock()
+	defer ap.lock.Unlock()
+	ap.paused = paused
+	ap.stats.Paused = paused
+	*counter++
+}
+
+// Get statistics of the accepter pausing
+func (peMgr *PeerManager) GetAcceptStats() AcceptStats {
+	ap := peMgr.acceptPause
+	ap.lock.Lock()
+	defer ap.lock.Unlock()
+	return ap.stats
+}
+
+func (peMgr *PeerManager) acceptSetup() {
+	if peMgr.cfg.acceptResume <= 0 || peMgr.cfg.acceptResume > 100 {
+		peMgr.cfg.acceptResume = config.DftAcceptResume
+	}
+	if peMgr.cfg.acceptMinPause <= 0 {
+		peMgr.cfg.acceptMinPause = config.DftAcceptMinPause
+	}
+}
+
+// pause the accepter if the total inbound peers reach the limit
+func (peMgr *PeerManager) acceptPauseCheck() {
+	ap := peMgr.acceptPause
+	if peMgr.cfg.noAccept || ap.paused || peMgr.ibpTotalNum < peMgr.cfg.ibpNumTotal {
+		return
+	}
+	schMsg := sch.SchMessage{}
+	peMgr.sdl.SchMakeMessage(&schMsg, peMgr.ptnMe, peMgr.ptnLsn, sch.EvPeLsnStopReq, nil)
+	peMgr.sdl.SchSendMessage(&schMsg)
+	ap.pausedAt = time.Now()
+	ap.count(true, &ap.stats.Pauses)
+	peerLog.Debug("acceptPauseCheck: paused, ibpTotalNum: %d, ibpNumTotal: %d",
+		peMgr.ibpTotalNum, peMgr.cfg.ibpNumTotal)
+}
+
+// resume the accepter paused if the total inbound peers fall to the threshold,
+// it's deferred by a timer if the min pause duration not elapsed.
+func (peMgr *PeerManager) acceptResumeCheck() {
+	ap := peMgr.acceptPause
+	if peMgr.cfg.noAccept || ap.tid != sch.SchInvalidTid {
+		return
+	}
+	resume, left := peMgr.acceptResumable(time.Now())
+	if !resume {
+		return
+	}
+	if left > 0 {
+		td := sch.TimerDescription{
+			Name:  "_acceptResumeTimer",
+			Utid:  sch.PeAcceptResumeTimerId,
+			Tmt:   sch.SchTmTypeAbsolute,
+			Dur:   left,
+			Extra: nil,
+		}
+		eno := sch.SchEnoNone
+		if eno, ap.tid = peMgr.sdl.SchSetTimer(peMgr.ptnMe, &td); eno != sch.SchEnoNone {
+			peerLog.Debug("acceptResumeCheck: SchSetTimer failed, eno: %d", eno)
+			ap.tid = sch.SchInvalidTid
+		} else {
+			ap.lock.Lock()
+			ap.stats.Deferred++
+			ap.lock.Unlock()
+			return
+		}
+	}
+	schMsg := sch.SchMessage{}
+	peMgr.sdl.SchMakeMessage(&schMsg, peMgr.ptnMe, peMgr.ptnLsn, sch.EvPeLsnStartReq, nil)
+	peMgr.sdl.SchSendMessage(&schMsg)
+	ap.count(false, &ap.stats.Resumes)
+	peerLog.Debug("acceptResumeCheck: resumed, ibpTotalNum: %d, ibpNumTotal: %d",
+		peMgr.ibpTotalNum, peMgr.cfg.ibpNumTotal)
+}
+
+// if the accepter paused can be resumed, and the duration left to the min pause
+func (peMgr *PeerManager) acceptResumable(now time.Time) (bool, time.Duration) {
+	ap := peMgr.acceptPause
+	if !ap.paused || peMgr.ibpTotalNum > peMgr.cfg.ibpNumTotal*peMgr.cfg.acceptResume/100 {
+		return false, 0
+	}
+	if left := peMgr.cfg.acceptMinPause - now.Sub(ap.pausedAt); left > 0 {
+		return true, left
+	}
+	return true, 0
+}
+
+func (peMgr *PeerManager) acceptResumeTimerHandler() PeMgrErrno {
+	peMgr.acceptPause.tid = sch.SchInvalidTid
+	peMgr.acceptResumeCheck()
+	return PeMgrEnoNone
+}
+
+// the accepter is stopped or started with the peer manager, forget the pausing
+func (peMgr *PeerManager) acceptPauseReset() {
+	ap := peMgr.acceptPause
+	if ap.tid != sch.SchInvalidTid {
+		peMgr.sdl.SchKillTimer(peMgr.ptnMe, ap.tid)
+		ap.tid = sch.SchInvalidTid
+	}
+	ap.lock.Lock()
+	defer ap.lock.Unlock()
+	ap.paused = false
+	ap.stats.Paused = false
+}
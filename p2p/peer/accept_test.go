@@ -0,0 +1,57 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestAcceptResumable(t *testing.T) {
+	peMgr := &PeerManager{acceptPause: newAcceptPause()}
+	peMgr.cfg.ibpNumTotal = 20
+	peMgr.acceptSetup()
+	if peMgr.cfg.acceptResume != config.DftAcceptResume || peMgr.cfg.acceptMinPause != config.DftAcceptMinPause {
+		t.Fatalf("defaults got %d, %s", peMgr.cfg.acceptResume, peMgr.cfg.acceptMinPause)
+	}
+
+	now := time.Now()
+	peMgr.ibpTotalNum = 10
+	if resume, _ := peMgr.acceptResumable(now); resume {
+		t.Errorf("resumable while not paused")
+	}
+
+	peMgr.acceptPause.paused = true
+	peMgr.acceptPause.pausedAt = now
+	peMgr.ibpTotalNum = 19
+	if resume, _ := peMgr.acceptResumable(now.Add(time.Hour)); resume {
+		t.Errorf("resumable above the threshold")
+	}
+	peMgr.ibpTotalNum = 18
+	if resume, left := peMgr.acceptResumable(now.Add(time.Second)); !resume || left != config.DftAcceptMinPause-time.Second {
+		t.Errorf("in min pause got %t, %s", resume, left)
+	}
+	if resume, left := peMgr.acceptResumable(now.Add(config.DftAcceptMinPause)); !resume || left != 0 {
+		t.Errorf("after min pause got %t, %s", resume, left)
+	}
+}
@@ -0,0 +1,277 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"net"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+//
+// Runtime management of peers: static nodes added and peers removed by command
+// are handled in the peer manager task, as other requests changing peers are.
+// a static node added is dialed at once by an EvPeOutboundReq, and instances of
+// one removed are closed. peers banned are kept in the ban list, see banlist.go.
+//
+
+type NatStatus struct {
+	Ready   bool   // public address available
+	PubIp   net.IP // public tcp ip
+	PubPort int    // public tcp port
+}
+
+//
+// Add a static node and connect to it, the node is kept till removed
+//
+func (peMgr *PeerManager) AddStaticPeer(node *config.Node) PeMgrErrno {
+	if node == nil || node.IP == nil || node.TCP == 0 {
+		return PeMgrEnoParameter
+	}
+	return peMgr.staticReq(sch.EvPeMgrStaticAddReq, node)
+}
+
+//
+// Remove a static node, instances of it in the static sub network are closed
+//
+func (peMgr *PeerManager) RemoveStaticPeer(id config.NodeID) PeMgrErrno {
+	return peMgr.staticReq(sch.EvPeMgrStaticRemoveReq, &config.Node{ID: id})
+}
+
+//
+// Close a peer in all sub networks, and remove it from static nodes
+//
+func (peMgr *PeerManager) RemovePeer(id config.NodeID) PeMgrErrno {
+	return peMgr.adminReq(sch.PeMgrAdminRemovePeer, &config.Node{ID: id})
+}
+
+//
+// Ban a peer for duration, it's removed and not connected till the ban expires
+//
+func (peMgr *PeerManager) BanPeer(id config.NodeID, duration time.Duration) PeMgrErrno {
+	if duration <= 0 {
+		return PeMgrEnoParameter
+	}
+	expired := time.Now().Add(duration)
+	peMgr.banList.banNode(id, expired)
+	peMgr.idReg.Ban(id, expired)
+	return peMgr.RemovePeer(id)
+}
+
+func (peMgr *PeerManager) UnbanPeer(id config.NodeID) PeMgrErrno {
+	peMgr.idReg.Unban(id)
+	if !peMgr.banList.unbanNode(id) {
+		return PeMgrEnoNotfound
+	}
+	return PeMgrEnoNone
+}
+
+//
+// Ban a network for duration, peers connected from or to addresses in it are
+// closed, and no connection is accepted from or dialed to them till the ban expires
+//
+func (peMgr *PeerManager) BanNet(ipNet *net.IPNet, duration time.Duration) PeMgrErrno {
+	if ipNet == nil || duration <= 0 {
+		return PeMgrEnoParameter
+	}
+	peMgr.banList.banNet(ipNet, time.Now().Add(duration))
+	return peMgr.adminReq(sch.PeMgrAdminCloseBanned, &config.Node{})
+}
+
+func (peMgr *PeerManager) UnbanNet(ipNet *net.IPNet) PeMgrErrno {
+	if ipNet == nil {
+		return PeMgrEnoParameter
+	}
+	if !peMgr.banList.unbanNet(ipNet) {
+		return PeMgrEnoNotfound
+	}
+	return PeMgrEnoNone
+}
+
+// Get peers banned and the time bans expire
+func (peMgr *PeerManager) GetBannedPeers() map[config.NodeID]time.Time {
+	return peMgr.banList.bannedNodes()
+}
+
+// Get networks banned in CIDR and the time bans expire
+func (peMgr *PeerManager) GetBannedNets() map[string]time.Time {
+	return peMgr.banList.bannedNets()
+}
+
+func (peMgr *PeerManager) GetNatStatus() NatStatus {
+	peMgr.lock.Lock()
+	defer peMgr.lock.Unlock()
+	return NatStatus{
+		Ready:   peMgr.natResult,
+		PubIp:   peMgr.pubTcpIp,
+		PubPort: peMgr.pubTcpPort,
+	}
+}
+
+func (peMgr *PeerManager) adminReq(cmd int, node *config.Node) PeMgrErrno {
+	if !peMgr.isInited {
+		return PeMgrEnoScheduler
+	}
+	req := sch.MsgPeMgrAdminReq{
+		Cmd:  cmd,
+		Node: *node,
+	}
+	msg := sch.SchMessage{}
+	peMgr.sdl.SchMakeMessage(&msg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeMgrAdminReq, &req)
+	if peMgr.sdl.SchSendMessage(&msg) != sch.SchEnoNone {
+		return PeMgrEnoScheduler
+	}
+	return PeMgrEnoNone
+}
+
+func (peMgr *PeerManager) staticReq(event int, node *config.Node) PeMgrErrno {
+	if !peMgr.isInited {
+		return PeMgrEnoScheduler
+	}
+	req := sch.MsgPeMgrStaticReq{
+		Node: *node,
+	}
+	msg := sch.SchMessage{}
+	peMgr.sdl.SchMakeMessage(&msg, peMgr.ptnMe, peMgr.ptnMe, event, &req)
+	if peMgr.sdl.SchSendMessage(&msg) != sch.SchEnoNone {
+		return PeMgrEnoScheduler
+	}
+	return PeMgrEnoNone
+}
+
+func (peMgr *PeerManager) peMgrAdminReq(req *sch.MsgPeMgrAdminReq) PeMgrErrno {
+	switch req.Cmd {
+	case sch.PeMgrAdminRemovePeer:
+		return peMgr.adminRemovePeer(req.Node.ID)
+	case sch.PeMgrAdminCloseBanned:
+		return peMgr.adminCloseBanned()
+	case sch.PeMgrAdminSetStandby:
+		return peMgr.adminSetStandby(req.Nodes)
+	case sch.PeMgrAdminActivateStandby:
+		return peMgr.adminActivateStandby()
+	case sch.PeMgrAdminEcho:
+		return peMgr.adminEcho(req.Node.ID, req.Pkg.(*P2pPackage))
+	}
+	peerLog.Debug("peMgrAdminReq: invalid command: %d", req.Cmd)
+	return PeMgrEnoParameter
+}
+
+func (peMgr *PeerManager) adminAddStatic(node *config.Node) PeMgrErrno {
+	snid := peMgr.cfg.staticSubNetId
+	peMgr.lock.Lock()
+	for _, sn := range peMgr.cfg.staticNodes {
+		if sn.ID == node.ID {
+			peMgr.lock.Unlock()
+			return PeMgrEnoDuplicated
+		}
+	}
+	if len(peMgr.cfg.staticNodes) == 0 {
+		if _, ok := peMgr.nodes[snid]; !ok {
+			peMgr.nodes[snid] = make(map[PeerIdEx]*PeerInstance)
+			peMgr.workers[snid] = make(map[PeerIdEx]*PeerInstance)
+		}
+	}
+	// replaced but not appended in place, since instances may be reading it
+	statics := make([]*config.Node, 0, len(peMgr.cfg.staticNodes)+1)
+	statics = append(statics, peMgr.cfg.staticNodes...)
+	peMgr.cfg.staticNodes = append(statics, node)
+	peMgr.lock.Unlock()
+
+	peMgr.staticsStatus[PeerIdEx{Id: node.ID, Dir: PeInstDirOutbound}] = peerIdle
+	peMgr.staticsStatus[PeerIdEx{Id: node.ID, Dir: PeInstDirInbound}] = peerIdle
+	peerLog.ForceDebug("adminAddStatic: snid: %x, peer: %x", snid, node.ID)
+
+	schMsg := sch.SchMessage{}
+	peMgr.sdl.SchMakeMessage(&schMsg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeOutboundReq, &snid)
+	peMgr.sdl.SchSendMessage(&schMsg)
+	return PeMgrEnoNone
+}
+
+// remove a node from static ones, false returned if it's not static
+func (peMgr *PeerManager) staticRemove(id config.NodeID) bool {
+	found := false
+	peMgr.lock.Lock()
+	statics := make([]*config.Node, 0, len(peMgr.cfg.staticNodes))
+	for _, sn := range peMgr.cfg.staticNodes {
+		if sn.ID == id {
+			found = true
+			continue
+		}
+		statics = append(statics, sn)
+	}
+	peMgr.cfg.staticNodes = statics
+	peMgr.lock.Unlock()
+	delete(peMgr.staticsStatus, PeerIdEx{Id: id, Dir: PeInstDirOutbound})
+	delete(peMgr.staticsStatus, PeerIdEx{Id: id, Dir: PeInstDirInbound})
+	peMgr.dialBackoffs.forget(PeerIdEx{Id: id, Dir: PeInstDirOutbound})
+	return found
+}
+
+func (peMgr *PeerManager) adminRemoveStatic(id config.NodeID) PeMgrErrno {
+	if !peMgr.staticRemove(id) {
+		return PeMgrEnoNotfound
+	}
+	snid := peMgr.cfg.staticSubNetId
+	for _, dir := range []int{PeInstDirOutbound, PeInstDirInbound} {
+		if _, ok := peMgr.nodes[snid][PeerIdEx{Id: id, Dir: dir}]; ok {
+			peMgr.ClosePeer(&snid, &id)
+			break
+		}
+	}
+	peerLog.ForceDebug("adminRemoveStatic: snid: %x, peer: %x", snid, id)
+	return PeMgrEnoNone
+}
+
+func (peMgr *PeerManager) adminRemovePeer(id config.NodeID) PeMgrErrno {
+	found := peMgr.staticRemove(id)
+	for snid, nodes := range peMgr.nodes {
+		for _, dir := range []int{PeInstDirOutbound, PeInstDirInbound} {
+			if _, ok := nodes[PeerIdEx{Id: id, Dir: dir}]; ok {
+				found = true
+				snid := snid
+				peMgr.ClosePeer(&snid, &id)
+				break
+			}
+		}
+	}
+	if !found {
+		return PeMgrEnoNotfound
+	}
+	peerLog.ForceDebug("adminRemovePeer: peer: %x", id)
+	return PeMgrEnoNone
+}
+
+// close peers connected from or to banned networks
+func (peMgr *PeerManager) adminCloseBanned() PeMgrErrno {
+	for snid, nodes := range peMgr.nodes {
+		for idEx, inst := range nodes {
+			if inst.raddr == nil || !peMgr.banList.ipBanned(inst.raddr.IP) {
+				continue
+			}
+			snid, id := snid, idEx.Id
+			peerLog.ForceDebug("adminCloseBanned: snid: %x, peer: %s", snid, inst.raddr.String())
+			peMgr.ClosePeer(&snid, &id)
+		}
+	}
+	return PeMgrEnoNone
+}
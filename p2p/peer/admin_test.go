@@ -0,0 +1,59 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestRemoveStatic(t *testing.T) {
+	a, b := config.NodeID{1}, config.NodeID{2}
+	peMgr := &PeerManager{
+		staticsStatus: map[PeerIdEx]int{},
+		dialBackoffs:  newDialBackoffs(),
+		nodes:         map[SubNetworkID]map[PeerIdEx]*PeerInstance{},
+	}
+	peMgr.cfg.staticNodes = []*config.Node{{ID: a}, {ID: b}}
+	for _, id := range []config.NodeID{a, b} {
+		peMgr.staticsStatus[PeerIdEx{Id: id, Dir: PeInstDirOutbound}] = peerIdle
+		peMgr.staticsStatus[PeerIdEx{Id: id, Dir: PeInstDirInbound}] = peerIdle
+	}
+	statics := peMgr.cfg.staticNodes
+
+	if eno := peMgr.adminRemoveStatic(a); eno != PeMgrEnoNone {
+		t.Fatalf("remove got eno: %d", eno)
+	}
+	if len(peMgr.cfg.staticNodes) != 1 || peMgr.cfg.staticNodes[0].ID != b || peMgr.isStatic(a) {
+		t.Errorf("statics got %v", peMgr.cfg.staticNodes)
+	}
+	if len(peMgr.staticsStatus) != 2 {
+		t.Errorf("status got %v", peMgr.staticsStatus)
+	}
+	// replaced but not changed in place, see adminAddStatic
+	if len(statics) != 2 || statics[0].ID != a {
+		t.Errorf("statics changed in place")
+	}
+	if eno := peMgr.adminRemoveStatic(a); eno != PeMgrEnoNotfound {
+		t.Errorf("remove again got eno: %d", eno)
+	}
+}
@@ -0,0 +1,150 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"time"
+)
+
+//
+// Dial backoff: a peer failed to be dialed or handshaked outbound is not dialed
+// again till its backoff expires. the backoff starts at dialBackoffBase and is
+// doubled by each failure in a row, up to dialBackoffMax, randomized by a jitter
+// of 1/dialBackoffJitter of it so peers failed together are not retried together.
+// a peer failed dialBackoffDemote times in a row is demoted, and retried only
+// after dialBackoffDemoted. a peer activated outbound is forgotten, so are those
+// not failed again for dialBackoffForget. it's accessed in the peer manager task
+// only.
+//
+
+const (
+	dialBackoffBase     = durStaticRetryTimer // backoff after the first failure
+	dialBackoffMax      = time.Minute * 5     // max backoff before demoted
+	dialBackoffJitter   = 4                   // backoff randomized by 1/dialBackoffJitter of it
+	dialBackoffDemote   = 8                   // failures in a row a peer demoted after
+	dialBackoffDemoted  = time.Minute * 30    // backoff of a peer demoted
+	dialBackoffForget   = time.Hour           // a peer not failed in it is forgotten
+	dialBackoffPurgeNum = 1024                // entries to check for peers to be forgotten
+)
+
+type dialBackoff struct {
+	fails int       // failures in a row
+	last  time.Time // time failed last
+	next  time.Time // time the peer can be dialed again
+}
+
+type dialBackoffs struct {
+	tab map[PeerIdEx]*dialBackoff // backoff by peer
+}
+
+func newDialBackoffs() *dialBackoffs {
+	return &dialBackoffs{
+		tab: make(map[PeerIdEx]*dialBackoff, 0),
+	}
+}
+
+// backoff for the failures in a row
+func dialBackoffFor(fails int) time.Duration {
+	if fails >= dialBackoffDemote {
+		return dialBackoffDemoted
+	}
+	d := dialBackoffBase
+	for n := 1; n < fails && d < dialBackoffMax; n++ {
+		d <<= 1
+	}
+	if d > dialBackoffMax {
+		d = dialBackoffMax
+	}
+	return d
+}
+
+// a failure of idEx, randIntn is for the jitter, true returned if it's demoted
+func (db *dialBackoffs) failed(idEx PeerIdEx, now time.Time, randIntn func(int) int) bool {
+	if len(db.tab) >= dialBackoffPurgeNum {
+		db.purge(now)
+	}
+	b, ok := db.tab[idEx]
+	if !ok || now.Sub(b.last) > dialBackoffForget {
+		b = &dialBackoff{}
+		db.tab[idEx] = b
+	}
+	b.fails++
+	b.last = now
+	d := dialBackoffFor(b.fails)
+	if j := int(d/time.Millisecond) / dialBackoffJitter; j > 0 {
+		d += time.Duration(randIntn(2*j+1)-j) * time.Millisecond
+	}
+	b.next = now.Add(d)
+	return b.fails == dialBackoffDemote
+}
+
+// idEx activated, or not to be dialed any more
+func (db *dialBackoffs) forget(idEx PeerIdEx) {
+	delete(db.tab, idEx)
+}
+
+// check if idEx can be dialed, and the time it can be if not
+func (db *dialBackoffs) ready(idEx PeerIdEx, now time.Time) (bool, time.Time) {
+	if b, ok := db.tab[idEx]; ok && now.Before(b.next) {
+		return false, b.next
+	}
+	return true, now
+}
+
+func (db *dialBackoffs) purge(now time.Time) {
+	for idEx, b := range db.tab {
+		if now.Sub(b.last) > dialBackoffForget {
+			delete(db.tab, idEx)
+		}
+	}
+}
+
+// failure of an outbound instance to be dialed or handshaked
+func (peMgr *PeerManager) dialFailed(snid SubNetworkID, id PeerId) {
+	idEx := PeerIdEx{Id: id, Dir: PeInstDirOutbound}
+	if peMgr.dialBackoffs.failed(idEx, time.Now(), peMgr.sdl.SchRandIntn) {
+		peerLog.ForceDebug("dialFailed: demoted, snid: %x, peer: %x", snid, id)
+	}
+}
+
+// Delay to check static nodes again: durStaticRetryTimer, or till the backoff
+// first expired if all static nodes idle are backing off.
+func (peMgr *PeerManager) staticRetryDelay(now time.Time) time.Duration {
+	snid := peMgr.cfg.staticSubNetId
+	first := time.Time{}
+	for _, n := range peMgr.cfg.staticNodes {
+		idEx := PeerIdEx{Id: n.ID, Dir: PeInstDirOutbound}
+		if _, dup := peMgr.nodes[snid][idEx]; dup || peMgr.staticsStatus[idEx] != peerIdle {
+			continue
+		}
+		ok, next := peMgr.dialBackoffs.ready(idEx, now)
+		if ok {
+			return durStaticRetryTimer
+		}
+		if first.IsZero() || next.Before(first) {
+			first = next
+		}
+	}
+	if d := first.Sub(now); d > durStaticRetryTimer {
+		return d
+	}
+	return durStaticRetryTimer
+}
@@ -0,0 +1,101 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestDialBackoff(t *testing.T) {
+	db := newDialBackoffs()
+	idEx := PeerIdEx{Id: config.NodeID{1}, Dir: PeInstDirOutbound}
+	now := time.Now()
+	noJitter := func(n int) int { return n / 2 }
+
+	// doubled by each failure, up to the max, then demoted
+	want := []time.Duration{
+		dialBackoffBase, dialBackoffBase * 2, dialBackoffBase * 4, dialBackoffBase * 8,
+		dialBackoffBase * 16, dialBackoffBase * 32, dialBackoffBase * 64, dialBackoffDemoted,
+	}
+	for n, d := range want {
+		if ok, _ := db.ready(idEx, now); !ok {
+			t.Fatalf("failure %d: not ready", n)
+		}
+		demoted := db.failed(idEx, now, noJitter)
+		if demoted != (n+1 == dialBackoffDemote) {
+			t.Errorf("failure %d: demoted: %t", n, demoted)
+		}
+		if d > dialBackoffMax && d != dialBackoffDemoted {
+			d = dialBackoffMax
+		}
+		ok, next := db.ready(idEx, now)
+		if ok || next != now.Add(d) {
+			t.Fatalf("failure %d: ready: %t, backoff: %s, want: %s", n, ok, next.Sub(now), d)
+		}
+		now = next
+	}
+
+	// jitter in 1/dialBackoffJitter of the backoff
+	other := PeerIdEx{Id: config.NodeID{2}, Dir: PeInstDirOutbound}
+	db.failed(other, now, func(n int) int { return 0 })
+	if _, next := db.ready(other, now); next != now.Add(dialBackoffBase-dialBackoffBase/dialBackoffJitter) {
+		t.Errorf("jitter got backoff: %s", next.Sub(now))
+	}
+
+	// forgotten when activated, or not failed for long
+	db.forget(idEx)
+	if ok, _ := db.ready(idEx, now); !ok {
+		t.Errorf("not ready after forgotten")
+	}
+	db.failed(other, now.Add(dialBackoffForget+time.Second), noJitter)
+	if db.tab[other].fails != 1 {
+		t.Errorf("failures got %d after long", db.tab[other].fails)
+	}
+}
+
+func TestStaticRetryDelay(t *testing.T) {
+	peMgr := &PeerManager{
+		staticsStatus: map[PeerIdEx]int{},
+		nodes:         map[SubNetworkID]map[PeerIdEx]*PeerInstance{},
+		dialBackoffs:  newDialBackoffs(),
+	}
+	a, b := config.NodeID{1}, config.NodeID{2}
+	peMgr.cfg.staticNodes = []*config.Node{{ID: a}, {ID: b}}
+	for _, id := range []config.NodeID{a, b} {
+		peMgr.staticsStatus[PeerIdEx{Id: id, Dir: PeInstDirOutbound}] = peerIdle
+	}
+	now := time.Now()
+	noJitter := func(n int) int { return n / 2 }
+	for n := 0; n < 4; n++ {
+		peMgr.dialBackoffs.failed(PeerIdEx{Id: a, Dir: PeInstDirOutbound}, now, noJitter)
+	}
+	if d := peMgr.staticRetryDelay(now); d != durStaticRetryTimer {
+		t.Errorf("delay got %s with a node ready", d)
+	}
+	peMgr.dialBackoffs.failed(PeerIdEx{Id: b, Dir: PeInstDirOutbound}, now, noJitter)
+	peMgr.dialBackoffs.failed(PeerIdEx{Id: b, Dir: PeInstDirOutbound}, now, noJitter)
+	if d := peMgr.staticRetryDelay(now); d != dialBackoffBase*2 {
+		t.Errorf("delay got %s, want the first backoff to expire", d)
+	}
+}
@@ -0,0 +1,173 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"time"
+)
+
+//
+// Outbound bandwidth of the node: all piTx routines of the peer manager ask a
+// bandwidth manager for the bytes of a package before sending it, and the
+// manager grants them one by one, paced by a token bucket of the total rate,
+// see rateLimiter. requests are served by deficit round robin over sub networks,
+// each sub network gets a quantum of bytes a round, so sub networks share the
+// bandwidth equally whatever number of peers they have. requests of a sub
+// network are served in order, and since a piTx asks for one package at a time,
+// peers of a sub network take turns. the per peer limit of a peer is applied
+// before asking the manager.
+//
+
+const (
+	bwQuantum    = 64 * 1024 // bytes added to the deficit of a sub network a round
+	bwReqChanLen = 256       // size of request channel
+)
+
+type bwRequest struct {
+	snid    SubNetworkID  // sub network of the peer
+	size    int           // bytes to be sent
+	granted chan struct{} // closed when granted
+}
+
+type bwManager struct {
+	bucket  *rateLimiter                  // total rate
+	reqChan chan *bwRequest               // requests from piTx routines
+	stop    chan struct{}                 // closed to stop the manager
+	queues  map[SubNetworkID][]*bwRequest // pending requests by sub network
+	ring    []SubNetworkID                // sub networks with requests pending, in round order
+	deficit map[SubNetworkID]int          // bytes a sub network can still send this round
+}
+
+// nil for unlimited, a nil manager grants at once
+func newBwManager(rate int) *bwManager {
+	if rate <= 0 {
+		return nil
+	}
+	return &bwManager{
+		bucket:  newRateLimiter(rate),
+		reqChan: make(chan *bwRequest, bwReqChanLen),
+		stop:    make(chan struct{}),
+		queues:  make(map[SubNetworkID][]*bwRequest, 0),
+		ring:    make([]SubNetworkID, 0),
+		deficit: make(map[SubNetworkID]int, 0),
+	}
+}
+
+func (bw *bwManager) start() {
+	if bw != nil {
+		go bw.loop()
+	}
+}
+
+// stop the manager, requests pending and later are granted at once
+func (bw *bwManager) close() {
+	if bw != nil {
+		close(bw.stop)
+	}
+}
+
+// Wait till size bytes of sub network snid granted
+func (bw *bwManager) acquire(snid SubNetworkID, size int) {
+	if bw == nil {
+		return
+	}
+	req := &bwRequest{
+		snid:    snid,
+		size:    size,
+		granted: make(chan struct{}),
+	}
+	select {
+	case bw.reqChan <- req:
+	case <-bw.stop:
+		return
+	}
+	select {
+	case <-req.granted:
+	case <-bw.stop:
+	}
+}
+
+func (bw *bwManager) loop() {
+	for {
+		if len(bw.ring) == 0 {
+			select {
+			case req := <-bw.reqChan:
+				bw.enqueue(req)
+			case <-bw.stop:
+				return
+			}
+		}
+		// take all requests arrived, so they're scheduled fairly
+	_drain:
+		for {
+			select {
+			case req := <-bw.reqChan:
+				bw.enqueue(req)
+			default:
+				break _drain
+			}
+		}
+
+		req := bw.next()
+		if d := bw.bucket.take(req.size, time.Now()); d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-t.C:
+			case <-bw.stop:
+				t.Stop()
+				return
+			}
+		}
+		close(req.granted)
+	}
+}
+
+func (bw *bwManager) enqueue(req *bwRequest) {
+	q, ok := bw.queues[req.snid]
+	if !ok || len(q) == 0 {
+		bw.ring = append(bw.ring, req.snid)
+	}
+	bw.queues[req.snid] = append(q, req)
+}
+
+// Request to be granted next by deficit round robin, the ring must not be empty.
+// the sub network at the head of the ring serves requests while its deficit
+// covers them, and then goes to the tail with a quantum added.
+func (bw *bwManager) next() *bwRequest {
+	for {
+		snid := bw.ring[0]
+		q := bw.queues[snid]
+		if req := q[0]; req.size <= bw.deficit[snid] {
+			bw.deficit[snid] -= req.size
+			if q = q[1:]; len(q) == 0 {
+				// no deficit kept by an idle sub network
+				delete(bw.queues, snid)
+				delete(bw.deficit, snid)
+				bw.ring = bw.ring[1:]
+			} else {
+				bw.queues[snid] = q
+			}
+			return req
+		}
+		bw.ring = append(bw.ring[1:], snid)
+		bw.deficit[snid] += bwQuantum
+	}
+}
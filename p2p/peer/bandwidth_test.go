@@ -0,0 +1,53 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+)
+
+func TestBwManagerFairness(t *testing.T) {
+	bw := newBwManager(1)
+	snA, snB := SubNetworkID{0xa}, SubNetworkID{0xb}
+	// sub network a has many peers with small packages, b one peer with large ones
+	for i := 0; i < 400; i++ {
+		bw.enqueue(&bwRequest{snid: snA, size: 1024})
+	}
+	for i := 0; i < 20; i++ {
+		bw.enqueue(&bwRequest{snid: snB, size: 32 * 1024})
+	}
+	sent := map[SubNetworkID]int{}
+	for i := 0; i < 200; i++ {
+		req := bw.next()
+		sent[req.snid] += req.size
+	}
+	if a, b := sent[snA], sent[snB]; a < b-bwQuantum || a > b+bwQuantum {
+		t.Errorf("bytes granted not shared, a: %d, b: %d", a, b)
+	}
+
+	// requests granted when the manager stopped
+	bw = newBwManager(1)
+	bw.start()
+	bw.close()
+	bw.acquire(snA, 1024*1024)
+	var none *bwManager
+	none.acquire(snA, 1024*1024)
+}
@@ -0,0 +1,272 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Ban list: peers banned by identity, and addresses banned by ip or CIDR. it's
+// checked when accepting inbound connections, handshaking, and creating outbound
+// instances, so banned ones are not connected again till the bans expire. bans
+// are written to a json file under the node data directory when changed, and
+// loaded when the peer manager is powered on, so they survive restarts.
+//
+type banList struct {
+	lock sync.Mutex                  // updated by users, read by peer manager and instances
+	path string                      // file bans persisted to, kept in memory only if empty
+	ids  map[config.NodeID]time.Time // expired time by peer identity
+	nets map[string]*bannedNet       // banned networks by CIDR string
+}
+
+type bannedNet struct {
+	ipNet   *net.IPNet // network banned
+	expired time.Time  // time the ban expires
+}
+
+// ban entry in the file, one of Id and Net is set
+type banEntry struct {
+	Id      *config.NodeID `json:"id,omitempty"`
+	Net     string         `json:"net,omitempty"`
+	Expired time.Time      `json:"expired"`
+}
+
+var errBanNet = errors.New("peer: invalid ip or CIDR")
+
+func newBanList() *banList {
+	return &banList{
+		ids:  make(map[config.NodeID]time.Time, 0),
+		nets: make(map[string]*bannedNet, 0),
+	}
+}
+
+//
+// Parse an ip or a CIDR to the network banned, an ip is taken as a network of
+// the single address
+//
+func ParseBanNet(s string) (*net.IPNet, error) {
+	if _, ipNet, err := net.ParseCIDR(s); err == nil {
+		return ipNet, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, errBanNet
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+//
+// Load bans from file, merged with those added before. bans are persisted to the
+// file since then.
+//
+func (bl *banList) load(path string) error {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	bl.path = path
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var entries []banEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, e := range entries {
+		if now.After(e.Expired) {
+			continue
+		}
+		if e.Id != nil {
+			if expired, ok := bl.ids[*e.Id]; !ok || expired.Before(e.Expired) {
+				bl.ids[*e.Id] = e.Expired
+			}
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(e.Net)
+		if err != nil {
+			peerLog.Debug("banList.load: invalid network: %s", e.Net)
+			continue
+		}
+		if bn, ok := bl.nets[ipNet.String()]; !ok || bn.expired.Before(e.Expired) {
+			bl.nets[ipNet.String()] = &bannedNet{ipNet: ipNet, expired: e.Expired}
+		}
+	}
+	return bl.save()
+}
+
+// write bans to file, lock must be held
+func (bl *banList) save() error {
+	if len(bl.path) == 0 {
+		return nil
+	}
+	now := time.Now()
+	entries := make([]banEntry, 0, len(bl.ids)+len(bl.nets))
+	for id, expired := range bl.ids {
+		if now.After(expired) {
+			delete(bl.ids, id)
+			continue
+		}
+		id := id
+		entries = append(entries, banEntry{Id: &id, Expired: expired})
+	}
+	for key, bn := range bl.nets {
+		if now.After(bn.expired) {
+			delete(bl.nets, key)
+			continue
+		}
+		entries = append(entries, banEntry{Net: key, Expired: bn.expired})
+	}
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(bl.path), 0700); err != nil {
+		return err
+	}
+	// replaced by renaming, not to leave a partial file if crashed
+	tmp := bl.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, bl.path)
+}
+
+func (bl *banList) saveLocked() {
+	if err := bl.save(); err != nil {
+		peerLog.Debug("banList.save: failed, path: %s, err: %s", bl.path, err.Error())
+	}
+}
+
+func (bl *banList) banNode(id config.NodeID, expired time.Time) {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	bl.ids[id] = expired
+	bl.saveLocked()
+}
+
+func (bl *banList) banNet(ipNet *net.IPNet, expired time.Time) {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	bl.nets[ipNet.String()] = &bannedNet{ipNet: ipNet, expired: expired}
+	bl.saveLocked()
+}
+
+func (bl *banList) unbanNode(id config.NodeID) bool {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	if _, ok := bl.ids[id]; !ok {
+		return false
+	}
+	delete(bl.ids, id)
+	bl.saveLocked()
+	return true
+}
+
+func (bl *banList) unbanNet(ipNet *net.IPNet) bool {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	if _, ok := bl.nets[ipNet.String()]; !ok {
+		return false
+	}
+	delete(bl.nets, ipNet.String())
+	bl.saveLocked()
+	return true
+}
+
+func (bl *banList) nodeBanned(id config.NodeID) bool {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	expired, ok := bl.ids[id]
+	if ok && time.Now().After(expired) {
+		delete(bl.ids, id)
+		return false
+	}
+	return ok
+}
+
+func (bl *banList) ipBanned(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	now := time.Now()
+	for key, bn := range bl.nets {
+		if now.After(bn.expired) {
+			delete(bl.nets, key)
+			continue
+		}
+		if bn.ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// peer banned by identity or by address
+func (bl *banList) banned(node *config.Node) bool {
+	return bl.nodeBanned(node.ID) || bl.ipBanned(node.IP)
+}
+
+func (bl *banList) bannedNodes() map[config.NodeID]time.Time {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	now := time.Now()
+	bans := make(map[config.NodeID]time.Time, len(bl.ids))
+	for id, expired := range bl.ids {
+		if now.After(expired) {
+			delete(bl.ids, id)
+			continue
+		}
+		bans[id] = expired
+	}
+	return bans
+}
+
+func (bl *banList) bannedNets() map[string]time.Time {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+	now := time.Now()
+	bans := make(map[string]time.Time, len(bl.nets))
+	for key, bn := range bl.nets {
+		if now.After(bn.expired) {
+			delete(bl.nets, key)
+			continue
+		}
+		bans[key] = bn.expired
+	}
+	return bans
+}
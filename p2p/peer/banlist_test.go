@@ -0,0 +1,82 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestBanList(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gyee-banlist")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "inst", config.BanListFileName)
+
+	bl := newBanList()
+	if err := bl.load(path); err != nil {
+		t.Fatal(err)
+	}
+	id := config.NodeID{1, 2, 3}
+	bl.banNode(id, time.Now().Add(time.Hour))
+	bl.banNode(config.NodeID{4}, time.Now().Add(-time.Second))
+	cidr, _ := ParseBanNet("10.1.0.0/16")
+	bl.banNet(cidr, time.Now().Add(time.Hour))
+	ip, _ := ParseBanNet("2001:db8::1")
+	bl.banNet(ip, time.Now().Add(time.Hour))
+
+	// reloaded as restarted
+	bl = newBanList()
+	if err := bl.load(path); err != nil {
+		t.Fatal(err)
+	}
+	if !bl.nodeBanned(id) || bl.nodeBanned(config.NodeID{4}) {
+		t.Errorf("node bans after reload got %v", bl.bannedNodes())
+	}
+	node := config.Node{ID: config.NodeID{5}, IP: net.ParseIP("10.1.2.3")}
+	if !bl.banned(&node) {
+		t.Errorf("node in banned network not banned")
+	}
+	if bl.ipBanned(net.ParseIP("10.2.0.1")) || !bl.ipBanned(net.ParseIP("2001:db8::1")) {
+		t.Errorf("ip bans after reload got %v", bl.bannedNets())
+	}
+
+	if !bl.unbanNet(cidr) || bl.unbanNet(cidr) {
+		t.Errorf("unbanNet() of banned network failed")
+	}
+	bl = newBanList()
+	bl.load(path)
+	if bl.ipBanned(net.ParseIP("10.1.2.3")) || len(bl.bannedNets()) != 1 {
+		t.Errorf("unbanned network got %v", bl.bannedNets())
+	}
+
+	if _, err := ParseBanNet("10.1.2"); err == nil {
+		t.Errorf("ParseBanNet() of invalid ip succeeded")
+	}
+}
@@ -0,0 +1,87 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"errors"
+
+	"github.com/golang/snappy"
+)
+
+//
+// Compression of user packages: the algorithms supported are sent in handshake
+// as a mask, and the best one both sides support is applied to payloads of
+// PID_EXT packages not smaller than compressThreshold. a package tells the
+// algorithm its payload compressed with, those not worth compressing are sent
+// as they are. only snappy is supported for now, others can be added to the
+// mask as new bits.
+//
+
+const (
+	CompressNone      = 0              // not compressed
+	CompressSnappy    = 1 << 0         // snappy
+	compressSupported = CompressSnappy // algorithms supported locally
+	compressThreshold = 1024           // payloads smaller are not compressed
+)
+
+// Algorithms announced in handshake
+func (peMgr *PeerManager) compressionMask() uint32 {
+	if peMgr.cfg.compressDisabled {
+		return CompressNone
+	}
+	return compressSupported
+}
+
+// Algorithm applied with a peer, the best one both support
+func negotiateCompression(local, remote uint32) uint32 {
+	if local&remote&CompressSnappy != 0 {
+		return CompressSnappy
+	}
+	return CompressNone
+}
+
+// Compress the payload, returns the algorithm applied, CompressNone if it's not
+// compressed.
+func compressPayload(alg uint32, payload []byte) ([]byte, uint32) {
+	if alg != CompressSnappy || len(payload) < compressThreshold {
+		return payload, CompressNone
+	}
+	c := snappy.Encode(nil, payload)
+	if len(c) >= len(payload) {
+		return payload, CompressNone
+	}
+	return c, CompressSnappy
+}
+
+// Decompress the payload to length bytes, at most max.
+func decompressPayload(alg uint32, payload []byte, length int, max int) ([]byte, error) {
+	if alg != CompressSnappy {
+		return nil, errors.New("decompressPayload: algorithm not supported")
+	}
+	n, err := snappy.DecodedLen(payload)
+	if err != nil {
+		return nil, err
+	}
+	if n != length || n > max {
+		return nil, errors.New("decompressPayload: length mismatched")
+	}
+	return snappy.Decode(nil, payload)
+}
@@ -0,0 +1,78 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"bytes"
+	"testing"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestNegotiateCompression(t *testing.T) {
+	if alg := negotiateCompression(compressSupported, CompressSnappy|1<<7); alg != CompressSnappy {
+		t.Errorf("both support snappy, got %d", alg)
+	}
+	if alg := negotiateCompression(compressSupported, CompressNone); alg != CompressNone {
+		t.Errorf("peer supports none, got %d", alg)
+	}
+	if alg := negotiateCompression(CompressNone, compressSupported); alg != CompressNone {
+		t.Errorf("disabled locally, got %d", alg)
+	}
+}
+
+// Send a package with compression and receive it with what the receiver
+// negotiated
+func compressTestSend(t *testing.T, rxAlg uint32, payload []byte) (*P2pPackage, PeMgrErrno) {
+	tx, rx := newStreamTestPair(config.P2pProtoVerBase)
+	defer tx.conn.Close()
+	defer rx.conn.Close()
+	tx.compress = CompressSnappy
+	rx.compress = rxAlg
+	rx.maxPkgSize = 1 << 20
+	go (&P2pPackage{
+		Pid:           uint32(PID_EXT),
+		Mid:           uint32(MID_TX),
+		PayloadLength: uint32(len(payload)),
+		Payload:       payload,
+	}).SendPackage(tx)
+	upkg := new(P2pPackage)
+	return upkg, upkg.RecvPackage(rx)
+}
+
+func TestCompressedPackage(t *testing.T) {
+	payload := bytes.Repeat([]byte("compressible "), 1024)
+	upkg, eno := compressTestSend(t, CompressSnappy, payload)
+	if eno != PeMgrEnoNone || !bytes.Equal(upkg.Payload, payload) || upkg.PayloadLength != uint32(len(payload)) {
+		t.Fatalf("compressed package got eno: %d, length: %d", eno, len(upkg.Payload))
+	}
+
+	// compressed on the wire, refused by one not negotiated
+	if _, eno := compressTestSend(t, CompressNone, payload); eno != PeMgrEnoMessage {
+		t.Fatalf("compressed package not negotiated got eno: %d", eno)
+	}
+
+	// small ones sent as they are
+	small := payload[:compressThreshold-1]
+	if upkg, eno := compressTestSend(t, CompressNone, small); eno != PeMgrEnoNone || !bytes.Equal(upkg.Payload, small) {
+		t.Fatalf("small package got eno: %d, length: %d", eno, len(upkg.Payload))
+	}
+}
@@ -0,0 +1,230 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"sync"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	identity "github.com/yeeco/gyee/p2p/identity"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+//
+// Echo probe: for operators to diagnose the link to a peer, echo requests with
+// padding of a given size are queued to the peer as data packages, so they go
+// through the tx queue and the bandwidth limit as the traffic does, and the peer
+// responds with the padding echoed. the round trip time is measured from the
+// request queued to the response received.
+//
+
+const (
+	EchoMaxSize  = 64 * 1024              // max padding size of an echo request
+	EchoMaxCount = 100                    // max echo requests of a probe
+	echoInterval = 100 * time.Millisecond // interval between echo requests of a probe
+	echoTimeout  = 2 * time.Second        // responses not received in it after the last request are lost
+)
+
+// Statistics of a probe
+type EchoStats struct {
+	Sent     int           // echo requests sent
+	Received int           // echo responses received
+	Min      time.Duration // min round trip time
+	Avg      time.Duration // average round trip time
+	Max      time.Duration // max round trip time
+}
+
+// Ratio of requests without responses
+func (es EchoStats) Loss() float64 {
+	if es.Sent == 0 {
+		return 0
+	}
+	return float64(es.Sent-es.Received) / float64(es.Sent)
+}
+
+type echoPending struct {
+	id   config.NodeID      // peer the request sent to
+	sent time.Time          // time the request queued
+	rtts chan time.Duration // round trip times of the probe
+}
+
+type echoProbes struct {
+	lock    sync.Mutex              // sync for pending
+	seq     uint64                  // sequence of the last request
+	pending map[uint64]*echoPending // requests waiting responses, by sequence
+}
+
+func newEchoProbes() *echoProbes {
+	return &echoProbes{
+		pending: make(map[uint64]*echoPending, 0),
+	}
+}
+
+func (eps *echoProbes) add(id config.NodeID, rtts chan time.Duration) uint64 {
+	eps.lock.Lock()
+	defer eps.lock.Unlock()
+	eps.seq++
+	eps.pending[eps.seq] = &echoPending{id: id, sent: time.Now(), rtts: rtts}
+	return eps.seq
+}
+
+func (eps *echoProbes) remove(seq uint64) {
+	eps.lock.Lock()
+	defer eps.lock.Unlock()
+	delete(eps.pending, seq)
+}
+
+// a response from peer id received, false if it's not expected
+func (eps *echoProbes) respond(id config.NodeID, seq uint64) bool {
+	eps.lock.Lock()
+	defer eps.lock.Unlock()
+	ep, ok := eps.pending[seq]
+	if !ok || ep.id != id {
+		return false
+	}
+	delete(eps.pending, seq)
+	ep.rtts <- time.Since(ep.sent)
+	return true
+}
+
+// Probe the link to an active peer with count echo requests padded to size bytes,
+// it blocks till all responses received or timed out.
+func (peMgr *PeerManager) Echo(id config.NodeID, size int, count int) (EchoStats, PeMgrErrno) {
+	stats := EchoStats{}
+	if size < 0 || size > EchoMaxSize || count <= 0 || count > EchoMaxCount {
+		return stats, PeMgrEnoParameter
+	}
+	if !peMgr.isInited {
+		return stats, PeMgrEnoScheduler
+	}
+	if p, ok := peMgr.idReg.Lookup(id); !ok || p.Conns[identity.StackChain] == 0 {
+		return stats, PeMgrEnoNotfound
+	}
+
+	rtts := make(chan time.Duration, count)
+	seqs := make([]uint64, 0, count)
+	defer func() {
+		for _, seq := range seqs {
+			peMgr.echoes.remove(seq)
+		}
+	}()
+	padding := make([]byte, size)
+	for ; stats.Sent < count; stats.Sent++ {
+		if stats.Sent > 0 {
+			time.Sleep(echoInterval)
+		}
+		seq := peMgr.echoes.add(id, rtts)
+		seqs = append(seqs, seq)
+		upkg := new(P2pPackage)
+		if eno := upkg.echo(&Pingpong{Seq: seq, Extra: padding}, false); eno != PeMgrEnoNone {
+			return stats, eno
+		}
+		req := sch.MsgPeMgrAdminReq{
+			Cmd:  sch.PeMgrAdminEcho,
+			Node: config.Node{ID: id},
+			Pkg:  upkg,
+		}
+		msg := sch.SchMessage{}
+		peMgr.sdl.SchMakeMessage(&msg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeMgrAdminReq, &req)
+		if peMgr.sdl.SchSendMessage(&msg) != sch.SchEnoNone {
+			return stats, PeMgrEnoScheduler
+		}
+	}
+
+	var total time.Duration
+	tm := time.NewTimer(echoTimeout)
+	defer tm.Stop()
+	for stats.Received < stats.Sent {
+		select {
+		case rtt := <-rtts:
+			if stats.Received == 0 || rtt < stats.Min {
+				stats.Min = rtt
+			}
+			if rtt > stats.Max {
+				stats.Max = rtt
+			}
+			total += rtt
+			stats.Received++
+		case <-tm.C:
+			peerLog.Debug("Echo: timeout, peer: %x, sent: %d, received: %d", id, stats.Sent, stats.Received)
+			return stats.average(total), PeMgrEnoNone
+		}
+	}
+	return stats.average(total), PeMgrEnoNone
+}
+
+func (es EchoStats) average(total time.Duration) EchoStats {
+	if es.Received > 0 {
+		es.Avg = total / time.Duration(es.Received)
+	}
+	return es
+}
+
+// queue an echo request to an active instance of the peer, in any sub network
+func (peMgr *PeerManager) adminEcho(id config.NodeID, upkg *P2pPackage) PeMgrErrno {
+	for _, workers := range peMgr.workers {
+		for _, dir := range []int{PeInstDirOutbound, PeInstDirInbound} {
+			inst, ok := workers[PeerIdEx{Id: id, Dir: dir}]
+			if !ok || inst.state != peInstStateActivated {
+				continue
+			}
+			if inst.txQueue.Full(upkg.Prio) {
+				peerLog.Debug("adminEcho: discarded, tx queue full, inst: %s", inst.name)
+				return PeMgrEnoResource
+			}
+			upkg.Enqueued = time.Now()
+			inst.txQueue.Put(upkg)
+			inst.txPendNum += 1
+			return PeMgrEnoNone
+		}
+	}
+	peerLog.Debug("adminEcho: not found, peer: %x", id)
+	return PeMgrEnoNotfound
+}
+
+func (pi *PeerInstance) piP2pEchoProc(echo *Pingpong) PeMgrErrno {
+	// responded in ppChan as pong, see piP2pPingProc, the queue must be checked
+	// since it's running in piRx context.
+	if pi.state != peInstStateActivated || pi.conn == nil {
+		peerLog.Debug("piP2pEchoProc: discarded, inst: %s, state: %d", pi.name, pi.state)
+		return PeMgrEnoResource
+	}
+	if len(pi.ppChan) >= cap(pi.ppChan) {
+		peerLog.Debug("piP2pEchoProc: queue full, inst: %s, dir: %d", pi.name, pi.dir)
+		return PeMgrEnoResource
+	}
+	upkg := new(P2pPackage)
+	if eno := upkg.echo(echo, true); eno != PeMgrEnoNone {
+		peerLog.Debug("piP2pEchoProc: echo failed, inst: %s, eno: %d", pi.name, eno)
+		return eno
+	}
+	pi.ppChan <- upkg
+	return PeMgrEnoNone
+}
+
+func (pi *PeerInstance) piP2pEchoRspProc(echoRsp *Pingpong) PeMgrErrno {
+	if !pi.peMgr.echoes.respond(pi.node.ID, echoRsp.Seq) {
+		peerLog.Debug("piP2pEchoRspProc: not expected, inst: %s, seq: %d", pi.name, echoRsp.Seq)
+		return PeMgrEnoMismatched
+	}
+	return PeMgrEnoNone
+}
@@ -0,0 +1,79 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestEchoMessage(t *testing.T) {
+	padding := bytes.Repeat([]byte{0x5a}, 1000)
+	for _, rsp := range []bool{false, true} {
+		upkg := new(P2pPackage)
+		if eno := upkg.echo(&Pingpong{Seq: 7, Extra: padding}, rsp); eno != PeMgrEnoNone {
+			t.Fatalf("echo failed, eno: %d", eno)
+		}
+		msg := P2pMessage{}
+		if eno := upkg.GetMessage(&msg); eno != PeMgrEnoNone {
+			t.Fatalf("GetMessage failed, eno: %d", eno)
+		}
+		echo, mid := msg.Echo, MID_ECHO
+		if rsp {
+			echo, mid = msg.EchoRsp, MID_ECHORSP
+		}
+		if upkg.Pid != uint32(PID_P2P) || msg.Mid != uint32(mid) || echo == nil {
+			t.Fatalf("rsp: %t, got pid: %d, mid: %d, %+v", rsp, upkg.Pid, msg.Mid, msg)
+		}
+		if echo.Seq != 7 || !bytes.Equal(echo.Extra, padding) {
+			t.Errorf("rsp: %t, got seq: %d, padding: %d bytes", rsp, echo.Seq, len(echo.Extra))
+		}
+	}
+}
+
+func TestEchoProbes(t *testing.T) {
+	eps := newEchoProbes()
+	rtts := make(chan time.Duration, 2)
+	id := config.NodeID{1}
+	seq := eps.add(id, rtts)
+	lost := eps.add(id, rtts)
+	if eps.respond(config.NodeID{2}, seq) {
+		t.Errorf("response from another peer accepted")
+	}
+	if !eps.respond(id, seq) || len(rtts) != 1 {
+		t.Errorf("response not accepted")
+	}
+	if eps.respond(id, seq) {
+		t.Errorf("duplicated response accepted")
+	}
+	eps.remove(lost)
+	if eps.respond(id, lost) || len(eps.pending) != 0 {
+		t.Errorf("response to request removed accepted")
+	}
+
+	es := EchoStats{Sent: 4, Received: 2}.average(30 * time.Millisecond)
+	if es.Avg != 15*time.Millisecond || es.Loss() != 0.5 {
+		t.Errorf("stats got avg: %s, loss: %f", es.Avg, es.Loss())
+	}
+}
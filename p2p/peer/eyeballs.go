@@ -0,0 +1,188 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"net"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	identity "github.com/yeeco/gyee/p2p/identity"
+)
+
+//
+// Happy eyeballs dialing: a peer can have several endpoints in the identity
+// registry, ipv4 and ipv6 ones, or the one it announces and that observed behind
+// a nat. the address of the instance is dialed first, and if it's not connected
+// in dialStagger, or fails, the next endpoint is dialed in parallel, and so on,
+// alternating address families. the first connection made is taken, dials still
+// pending are abandoned, and connections they make later are closed. a dual
+// stack peer announces its address of the other family in handshake, which is
+// kept with the instance and dialed as well.
+//
+
+const (
+	dialStagger = 250 * time.Millisecond // delay before dialing the next endpoint
+)
+
+type dialResult struct {
+	conn net.Conn // connection made, nil if failed
+	err  error    // error of dialing
+}
+
+// endpoints to dial for the instance: the address of the instance first, or
+// the first one of the family preferred, see config.IpPrefer*
+func (pi *PeerInstance) piDialEndpoints() []*net.TCPAddr {
+	cands := []*net.TCPAddr{{IP: pi.node.IP, Port: int(pi.node.TCP)}}
+	if pi.node.AltIP != nil {
+		cands = append(cands, &net.TCPAddr{IP: pi.node.AltIP, Port: int(pi.node.TCP)})
+	}
+	for _, n := range pi.peMgr.idReg.Endpoints(identity.StackChain, pi.node.ID) {
+		if n.IP == nil || n.TCP == 0 || dialEndpointIn(cands, n.IP, int(n.TCP)) ||
+			pi.peMgr.selfAddrs.self(n.IP, int(n.TCP)) {
+			continue
+		}
+		cands = append(cands, &net.TCPAddr{IP: n.IP, Port: int(n.TCP)})
+	}
+	first := cands[0]
+	for _, addr := range cands {
+		if ipPreferred(addr.IP, pi.peMgr.cfg.ipPrefer) {
+			first = addr
+			break
+		}
+	}
+	same := make([]*net.TCPAddr, 0)
+	other := make([]*net.TCPAddr, 0)
+	for _, addr := range cands {
+		if addr == first {
+			continue
+		}
+		if (addr.IP.To4() == nil) == (first.IP.To4() == nil) {
+			same = append(same, addr)
+		} else {
+			other = append(other, addr)
+		}
+	}
+	addrs := []*net.TCPAddr{first}
+	for len(same) > 0 || len(other) > 0 {
+		if len(other) > 0 {
+			addrs = append(addrs, other[0])
+			other = other[1:]
+		}
+		if len(same) > 0 {
+			addrs = append(addrs, same[0])
+			same = same[1:]
+		}
+	}
+	return addrs
+}
+
+func dialEndpointIn(addrs []*net.TCPAddr, ip net.IP, port int) bool {
+	for _, addr := range addrs {
+		if addr.IP.Equal(ip) && addr.Port == port {
+			return true
+		}
+	}
+	return false
+}
+
+func ipPreferred(ip net.IP, prefer int) bool {
+	switch prefer {
+	case config.IpPreferV4:
+		return ip.To4() != nil
+	case config.IpPreferV6:
+		return ip.To4() == nil
+	}
+	return false
+}
+
+// Address of the other family than ip announced in a handshake, that is the
+// alternative one, or the primary if ip is the alternative one; nil if the peer
+// is single stack or announced something bogus.
+func hsOtherIP(hs *Handshake, ip net.IP) net.IP {
+	for _, other := range []net.IP{hs.AltIP, hs.IP} {
+		if other == nil || other.IsUnspecified() || other.Equal(ip) {
+			continue
+		}
+		if (other.To4() == nil) != (ip.To4() == nil) {
+			return append(net.IP{}, other...)
+		}
+	}
+	return nil
+}
+
+// Dial endpoints with staggered parallelism, returns the first connection made,
+// or the last error if all failed
+func dialEyeballs(dialer config.PeerTransport, addrs []*net.TCPAddr, timeout time.Duration, stagger time.Duration) (net.Conn, error) {
+	results := make(chan dialResult, len(addrs))
+	next, pending := 0, 0
+	start := func() {
+		addr := addrs[next]
+		next++
+		pending++
+		go func() {
+			conn, err := dialer.Dial(addr.String(), timeout)
+			results <- dialResult{conn: conn, err: err}
+		}()
+	}
+
+	start()
+	tm := time.NewTimer(stagger)
+	defer tm.Stop()
+	var err error
+	for pending > 0 {
+		var staggered <-chan time.Time
+		if next < len(addrs) {
+			staggered = tm.C
+		}
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				go func(n int) {
+					for ; n > 0; n-- {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+			peerLog.Debug("dialEyeballs: dial failed, err: %s", r.err.Error())
+			err = r.err
+			if next < len(addrs) {
+				// failed, not to wait the stagger for the next one
+				if !tm.Stop() {
+					select {
+					case <-tm.C:
+					default:
+					}
+				}
+				start()
+				tm.Reset(stagger)
+			}
+		case <-staggered:
+			start()
+			tm.Reset(stagger)
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,201 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	identity "github.com/yeeco/gyee/p2p/identity"
+)
+
+// transport delaying dials to some addresses, as if they were black holes
+type slowTransport struct {
+	config.PeerTransport
+	delays map[string]time.Duration
+	closed int32
+}
+
+type slowConn struct {
+	net.Conn
+	st *slowTransport
+}
+
+func (st *slowTransport) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	time.Sleep(st.delays[addr])
+	conn, err := st.PeerTransport.Dial(addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &slowConn{Conn: conn, st: st}, nil
+}
+
+func (sc *slowConn) Close() error {
+	atomic.AddInt32(&sc.st.closed, 1)
+	return sc.Conn.Close()
+}
+
+func TestDialEyeballs(t *testing.T) {
+	mn := NewMemNetwork()
+	server := mn.Transport(net.ParseIP("10.0.0.1"))
+	for _, addr := range []string{"10.0.0.1:30303", "[2001:db8::1]:30303"} {
+		lsn, err := server.Listen(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer lsn.Close()
+		go func() {
+			for {
+				if _, err := lsn.Accept(); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	st := &slowTransport{
+		PeerTransport: mn.Transport(net.ParseIP("10.0.0.2")),
+		delays:        map[string]time.Duration{"10.0.0.1:30303": 500 * time.Millisecond},
+	}
+	v4 := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 30303}
+	v6 := &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 30303}
+	refused := &net.TCPAddr{IP: net.ParseIP("10.0.0.9"), Port: 30303}
+
+	// the first one is slow, the second is dialed after the stagger and taken
+	start := time.Now()
+	conn, err := dialEyeballs(st, []*net.TCPAddr{v4, v6}, time.Second, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn.RemoteAddr().String() != v6.String() || time.Since(start) >= 500*time.Millisecond {
+		t.Errorf("connected to %s in %s", conn.RemoteAddr(), time.Since(start))
+	}
+	time.Sleep(600 * time.Millisecond)
+	if atomic.LoadInt32(&st.closed) != 1 {
+		t.Errorf("connection made later by the slow dial not closed")
+	}
+
+	// the first one failed, the second is dialed at once
+	start = time.Now()
+	if conn, err = dialEyeballs(st, []*net.TCPAddr{refused, v6}, time.Second, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if conn.RemoteAddr().String() != v6.String() || time.Since(start) >= time.Second {
+		t.Errorf("connected to %s in %s", conn.RemoteAddr(), time.Since(start))
+	}
+
+	if _, err = dialEyeballs(st, []*net.TCPAddr{refused}, time.Second, time.Second); err == nil {
+		t.Errorf("dialing refused endpoints succeeded")
+	}
+}
+
+func TestDialEndpoints(t *testing.T) {
+	id := config.NodeID{1}
+	reg := identity.NewRegistry()
+	for _, ip := range []string{"10.0.0.3", "2001:db8::1", "10.0.0.2", "10.0.0.1"} {
+		reg.AddEndpoint(identity.StackChain, &config.Node{ID: id, IP: net.ParseIP(ip), TCP: 30303})
+	}
+	pi := &PeerInstance{
+		peMgr: &PeerManager{idReg: reg, selfAddrs: newSelfAddrs()},
+		node:  config.Node{ID: id, IP: net.ParseIP("10.0.0.1"), TCP: 30303},
+	}
+	want := []string{"10.0.0.1:30303", "[2001:db8::1]:30303", "10.0.0.2:30303", "10.0.0.3:30303"}
+	addrs := pi.piDialEndpoints()
+	if len(addrs) != len(want) {
+		t.Fatalf("endpoints got %v, want %v", addrs, want)
+	}
+	for i, addr := range addrs {
+		if addr.String() != want[i] {
+			t.Errorf("endpoints got %v, want %v", addrs, want)
+			break
+		}
+	}
+}
+
+func TestDialPreference(t *testing.T) {
+	id := config.NodeID{1}
+	reg := identity.NewRegistry()
+	reg.AddEndpoint(identity.StackChain, &config.Node{ID: id, IP: net.ParseIP("10.0.0.2"), TCP: 30303})
+	pi := &PeerInstance{
+		peMgr: &PeerManager{idReg: reg, selfAddrs: newSelfAddrs()},
+		node: config.Node{
+			ID:    id,
+			IP:    net.ParseIP("10.0.0.1"),
+			AltIP: net.ParseIP("2001:db8::1"),
+			TCP:   30303,
+		},
+	}
+	for prefer, want := range map[int][]string{
+		config.IpPreferAny: {"10.0.0.1:30303", "[2001:db8::1]:30303", "10.0.0.2:30303"},
+		config.IpPreferV4:  {"10.0.0.1:30303", "[2001:db8::1]:30303", "10.0.0.2:30303"},
+		config.IpPreferV6:  {"[2001:db8::1]:30303", "10.0.0.1:30303", "10.0.0.2:30303"},
+	} {
+		pi.peMgr.cfg.ipPrefer = prefer
+		addrs := pi.piDialEndpoints()
+		if len(addrs) != len(want) {
+			t.Fatalf("prefer %d: endpoints got %v, want %v", prefer, addrs, want)
+		}
+		for i, addr := range addrs {
+			if addr.String() != want[i] {
+				t.Errorf("prefer %d: endpoints got %v, want %v", prefer, addrs, want)
+				break
+			}
+		}
+	}
+
+	// the other family taken from handshake, whichever is known
+	hs := &Handshake{IP: net.ParseIP("10.0.0.1"), AltIP: net.ParseIP("2001:db8::1")}
+	if ip := hsOtherIP(hs, hs.IP); !ip.Equal(hs.AltIP) {
+		t.Errorf("other of primary got %s", ip)
+	}
+	if ip := hsOtherIP(hs, hs.AltIP); !ip.Equal(hs.IP) {
+		t.Errorf("other of alternative got %s", ip)
+	}
+	hs.AltIP = net.ParseIP("10.0.0.2")
+	if ip := hsOtherIP(hs, hs.IP); ip != nil {
+		t.Errorf("other of the same family got %s", ip)
+	}
+}
+
+func TestHandshakeAltIP(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	tx := &PeerInstance{priKey: *key, conn: a, maxPkgSize: maxTcpmsgSize}
+	rx := &PeerInstance{peMgr: &PeerManager{hsNonces: newHsNonces()}, priKey: *key, conn: b, maxPkgSize: maxTcpmsgSize}
+	hs := &Handshake{
+		NodeId: *config.P2pPubkey2NodeId(&key.PublicKey),
+		IP:     net.ParseIP("10.0.0.1"),
+		TCP:    30303,
+		AltIP:  net.ParseIP("2001:db8::1"),
+	}
+	go new(P2pPackage).putHandshakeOutbound(tx, hs)
+	got, eno := new(P2pPackage).getHandshakeInbound(rx)
+	if eno != PeMgrEnoNone || !got.AltIP.Equal(hs.AltIP) {
+		t.Fatalf("handshake got %+v, eno: %d", got, eno)
+	}
+}
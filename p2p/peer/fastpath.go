@@ -0,0 +1,182 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+//
+// Fast path for latency-sensitive protocols(consensus votes, for example):
+// PID_EXT packages of a message identity registered are delivered by piRx
+// directly to a lock-free ring buffer consumed by the application, instead of
+// rxChan -> shell -> application. Notice:
+// 1) for packages from one connection, the order they are received is kept in
+// the ring buffer; nothing is guaranteed for packages from different
+// connections, nor between the fast path and rxChan;
+// 2) the deduplication of the shell is not applied, the application should
+// take care of it(and any relaying) by itself;
+// 3) when the ring buffer is full, the newest package is dropped, the rx queue
+// policy is not applied.
+//
+
+const (
+	FastRingMinSize = 64        // min slots of a ring buffer
+	FastRingMaxSize = 1024 * 64 // max slots of a ring buffer
+)
+
+type fastRingCell struct {
+	seq uint64        // sequence of the cell
+	pkg *P2pPackageRx // package
+}
+
+// FastRing is a bounded multi-producer multi-consumer queue: the rx routine of
+// each peer instance is a producer.
+type FastRing struct {
+	head    uint64         // position to push next
+	tail    uint64         // position to pop next
+	dropped uint64         // number of packages dropped for ring full
+	mask    uint64         // size - 1
+	cells   []fastRingCell // cells
+	ready   chan struct{}  // signaled when packages pushed
+}
+
+func newFastRing(size int) *FastRing {
+	n := FastRingMinSize
+	for n < size && n < FastRingMaxSize {
+		n <<= 1
+	}
+	r := &FastRing{
+		mask:  uint64(n - 1),
+		cells: make([]fastRingCell, n),
+		ready: make(chan struct{}, 1),
+	}
+	for i := range r.cells {
+		r.cells[i].seq = uint64(i)
+	}
+	return r
+}
+
+func (r *FastRing) push(pkg *P2pPackageRx) bool {
+	for {
+		pos := atomic.LoadUint64(&r.head)
+		c := &r.cells[pos&r.mask]
+		seq := atomic.LoadUint64(&c.seq)
+		if dif := int64(seq - pos); dif == 0 {
+			if atomic.CompareAndSwapUint64(&r.head, pos, pos+1) {
+				c.pkg = pkg
+				atomic.StoreUint64(&c.seq, pos+1)
+				select {
+				case r.ready <- struct{}{}:
+				default:
+				}
+				return true
+			}
+		} else if dif < 0 {
+			atomic.AddUint64(&r.dropped, 1)
+			return false
+		}
+	}
+}
+
+// Pop a package, false returned if the ring is empty.
+func (r *FastRing) Pop() (*P2pPackageRx, bool) {
+	for {
+		pos := atomic.LoadUint64(&r.tail)
+		c := &r.cells[pos&r.mask]
+		seq := atomic.LoadUint64(&c.seq)
+		if dif := int64(seq - (pos + 1)); dif == 0 {
+			if atomic.CompareAndSwapUint64(&r.tail, pos, pos+1) {
+				pkg := c.pkg
+				c.pkg = nil
+				atomic.StoreUint64(&c.seq, pos+r.mask+1)
+				return pkg, true
+			}
+		} else if dif < 0 {
+			return nil, false
+		}
+	}
+}
+
+// Ready is signaled when packages pushed, the consumer should Pop until the
+// ring is empty before waiting on it again.
+func (r *FastRing) Ready() <-chan struct{} {
+	return r.ready
+}
+
+// Number of packages dropped for the ring full
+func (r *FastRing) Dropped() uint64 {
+	return atomic.LoadUint64(&r.dropped)
+}
+
+type fastPaths struct {
+	lock sync.Mutex   // for registering
+	tab  atomic.Value // map[uint32]*FastRing, replaced on registering
+}
+
+func newFastPaths() *fastPaths {
+	fp := &fastPaths{}
+	fp.tab.Store(make(map[uint32]*FastRing, 0))
+	return fp
+}
+
+func (fp *fastPaths) get(mid uint32) *FastRing {
+	return fp.tab.Load().(map[uint32]*FastRing)[mid]
+}
+
+func (fp *fastPaths) update(mid uint32, r *FastRing) {
+	fp.lock.Lock()
+	defer fp.lock.Unlock()
+	old := fp.tab.Load().(map[uint32]*FastRing)
+	tab := make(map[uint32]*FastRing, len(old)+1)
+	for k, v := range old {
+		tab[k] = v
+	}
+	if r == nil {
+		delete(tab, mid)
+	} else {
+		tab[mid] = r
+	}
+	fp.tab.Store(tab)
+}
+
+// Register fast path for PID_EXT packages with message identity mid, a ring
+// buffer with size slots(rounded up to power of 2) returned.
+func (peMgr *PeerManager) RegisterFastPath(mid uint32, size int) (*FastRing, PeMgrErrno) {
+	if mid == uint32(MID_CHKK) || mid == uint32(MID_RPTK) ||
+		mid == uint32(MID_GCD) || mid == uint32(MID_PCD) {
+		peerLog.Debug("RegisterFastPath: not allowed, mid: %d", mid)
+		return nil, PeMgrEnoParameter
+	}
+	if peMgr.fastPaths.get(mid) != nil {
+		peerLog.Debug("RegisterFastPath: duplicated, mid: %d", mid)
+		return nil, PeMgrEnoDuplicated
+	}
+	r := newFastRing(size)
+	peMgr.fastPaths.update(mid, r)
+	return r, PeMgrEnoNone
+}
+
+// Unregister fast path, packages still in the ring buffer can be popped then.
+func (peMgr *PeerManager) UnregisterFastPath(mid uint32) {
+	peMgr.fastPaths.update(mid, nil)
+}
@@ -0,0 +1,148 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+func TestFastRing(t *testing.T) {
+	if r := newFastRing(100); len(r.cells) != 128 {
+		t.Errorf("size got %d, want 128", len(r.cells))
+	}
+	if r := newFastRing(0); len(r.cells) != FastRingMinSize {
+		t.Errorf("size got %d, want %d", len(r.cells), FastRingMinSize)
+	}
+	if r := newFastRing(FastRingMaxSize * 2); len(r.cells) != FastRingMaxSize {
+		t.Errorf("size got %d, want %d", len(r.cells), FastRingMaxSize)
+	}
+
+	r := newFastRing(FastRingMinSize)
+	if _, ok := r.Pop(); ok {
+		t.Fatalf("pop from empty ring")
+	}
+
+	// twice around the ring, in order, the newest dropped when full
+	for round := 0; round < 2; round++ {
+		for i := 0; i < FastRingMinSize; i++ {
+			if !r.push(&P2pPackageRx{PayloadLength: i}) {
+				t.Fatalf("push %d failed", i)
+			}
+		}
+		if r.push(&P2pPackageRx{}) {
+			t.Fatalf("pushed into full ring")
+		}
+		select {
+		case <-r.Ready():
+		default:
+			t.Fatalf("not signaled")
+		}
+		for i := 0; i < FastRingMinSize; i++ {
+			pkg, ok := r.Pop()
+			if !ok || pkg.PayloadLength != i {
+				t.Fatalf("pop %d got %v, %t", i, pkg, ok)
+			}
+		}
+		if _, ok := r.Pop(); ok {
+			t.Fatalf("pop from empty ring")
+		}
+	}
+	if r.Dropped() != 2 {
+		t.Errorf("dropped got %d, want 2", r.Dropped())
+	}
+}
+
+func TestFastRingConcurrent(t *testing.T) {
+	const producers = 4
+	const consumers = 4
+	const count = 20000
+	r := newFastRing(FastRingMinSize)
+
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				for !r.push(&P2pPackageRx{MsgId: p, PayloadLength: i}) {
+					runtime.Gosched()
+				}
+			}
+		}(p)
+	}
+
+	// each consumer sees packages of a producer in the order pushed, and all
+	// packages are popped exactly once
+	var lock sync.Mutex
+	seen := make([][]bool, producers)
+	for p := range seen {
+		seen[p] = make([]bool, count)
+	}
+	popped, failed := 0, ""
+	var cwg sync.WaitGroup
+	for c := 0; c < consumers; c++ {
+		cwg.Add(1)
+		go func() {
+			defer cwg.Done()
+			last := make([]int, producers)
+			for p := range last {
+				last[p] = -1
+			}
+			for {
+				lock.Lock()
+				done := popped == producers*count
+				lock.Unlock()
+				if done {
+					return
+				}
+				pkg, ok := r.Pop()
+				if !ok {
+					runtime.Gosched()
+					continue
+				}
+				p, i := pkg.MsgId, pkg.PayloadLength
+				lock.Lock()
+				if i <= last[p] {
+					failed = "out of order"
+				} else if seen[p][i] {
+					failed = "popped twice"
+				}
+				last[p] = i
+				seen[p][i] = true
+				popped++
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	cwg.Wait()
+	if failed != "" {
+		t.Fatal(failed)
+	}
+	if popped != producers*count {
+		t.Errorf("popped %d, want %d", popped, producers*count)
+	}
+	if _, ok := r.Pop(); ok {
+		t.Errorf("ring not empty")
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"container/list"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"net"
+	"sync"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	pb "github.com/yeeco/gyee/p2p/peer/pb"
+)
+
+//
+// Handshake authentication: the fields identifying the peer and where it's
+// reached are signed along with a nonce. the inbound side signs also the nonce
+// of the handshake it answers, so its handshake can't be replayed to another
+// dialer; the nonce of the outbound side carries the time it's made, and those
+// seen in a window are remembered, so its handshake can't be replayed to the
+// same or another listener.
+//
+// the signature over the node identity only is still sent in SignR, R, SignS
+// and S for legacy peers, the one described above goes in AuthSig. a peer
+// agreed on config.P2pProtoVerAuth or higher must send a valid AuthSig, those
+// not are refused; for a legacy peer, AuthSig is checked if sent, otherwise
+// the handshake is accepted with the legacy signature, unless hsAuthRequired
+// is configured.
+//
+
+const (
+	hsNonceRandLen = 16                     // random bytes in a nonce
+	hsNonceLen     = 8 + hsNonceRandLen     // time made in nanoseconds followed by random bytes
+	hsNoncesMax    = 1024 * 64              // max nonces remembered, the oldest forgotten beyond
+	hsAuthSigHalf  = 32                     // bytes of r and of s in AuthSig
+	hsDigestDomain = "gyee-p2p-handshake-2" // domain separation of the digest signed
+)
+
+var errHsAuthSig = errors.New("peer: handshake signature too long")
+
+func newHsNonce(now time.Time) []byte {
+	nonce := make([]byte, hsNonceLen)
+	binary.BigEndian.PutUint64(nonce, uint64(now.UnixNano()))
+	rand.Read(nonce[8:])
+	return nonce
+}
+
+// Digest signed for a handshake, answered is the nonce of the handshake it
+// answers, nil for that of the outbound side.
+func handshakeDigest(hs *pb.P2PMessage_Handshake, answered []byte) []byte {
+	h := sha256.New()
+	hsDigestBytes(h, []byte(hsDigestDomain))
+	hsDigestBytes(h, hs.SubNetId)
+	hsDigestBytes(h, hs.NodeId)
+	hsDigestBytes(h, net.IP(hs.IP).To16())
+	hsDigestUint32(h, hs.GetUDP())
+	hsDigestUint32(h, hs.GetTCP())
+	hsDigestUint32(h, hs.GetDhtPort())
+	hsDigestUint32(h, hs.GetEncryption())
+	hsDigestUint32(h, hs.GetCompression())
+	hsDigestBytes(h, net.IP(hs.AltIP).To16())
+	hsDigestBytes(h, hs.InstNonce)
+	hsDigestUint32(h, hs.GetWsPort())
+	hsDigestUint32(h, uint32(len(hs.Protocols)))
+	for _, p := range hs.Protocols {
+		hsDigestUint32(h, uint32(p.GetPid()))
+		hsDigestBytes(h, p.Ver)
+	}
+	hsDigestBytes(h, hs.Nonce)
+	hsDigestBytes(h, answered)
+	return h.Sum(nil)
+}
+
+func hsDigestBytes(h hash.Hash, b []byte) {
+	hsDigestUint32(h, uint32(len(b)))
+	h.Write(b)
+}
+
+func hsDigestUint32(h hash.Hash, v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	h.Write(b[:])
+}
+
+// Sign the digest for AuthSig: r and s, each left padded to hsAuthSigHalf bytes
+func hsAuthSign(priKey *ecdsa.PrivateKey, digest []byte) ([]byte, error) {
+	r, s, err := config.P2pSign(priKey, digest)
+	if err != nil {
+		return nil, err
+	}
+	rb, sb := r.Bytes(), s.Bytes()
+	if len(rb) > hsAuthSigHalf || len(sb) > hsAuthSigHalf {
+		return nil, errHsAuthSig
+	}
+	sig := make([]byte, 2*hsAuthSigHalf)
+	copy(sig[hsAuthSigHalf-len(rb):], rb)
+	copy(sig[2*hsAuthSigHalf-len(sb):], sb)
+	return sig, nil
+}
+
+// Verify AuthSig over the digest by the public key
+func hsAuthVerify(pubKey *ecdsa.PublicKey, digest []byte, sig []byte) bool {
+	if len(sig) != 2*hsAuthSigHalf {
+		return false
+	}
+	r := config.P2pBigInt(1, sig[:hsAuthSigHalf])
+	s := config.P2pBigInt(1, sig[hsAuthSigHalf:])
+	return config.P2pVerify(pubKey, digest, r, s)
+}
+
+// Nonces of inbound handshakes seen, by the time they are forgotten. those
+// are kept in the order seen, so the oldest are forgotten first when expired
+// or hsNoncesMax reached: a peer flooding fresh nonces can't have inbound
+// handshakes refused, it can only shorten the time a nonce is remembered.
+type hsNonces struct {
+	lock   sync.Mutex               // lock to protect seen and order
+	window time.Duration            // max clock skew of the time in a nonce
+	seen   map[string]*list.Element // nonces seen, to their elements in order
+	order  *list.List               // nonces seen, the oldest first, values are *hsNonceSeen
+}
+
+type hsNonceSeen struct {
+	nonce   string    // the nonce
+	expired time.Time // time it's forgotten
+}
+
+func newHsNonces() *hsNonces {
+	return &hsNonces{
+		window: config.DftHsNonceWindow,
+		seen:   make(map[string]*list.Element, 0),
+		order:  list.New(),
+	}
+}
+
+// Check the nonce is fresh and not seen, it's remembered if so
+func (hn *hsNonces) check(nonce []byte, now time.Time) bool {
+	if len(nonce) != hsNonceLen {
+		return false
+	}
+	made := time.Unix(0, int64(binary.BigEndian.Uint64(nonce)))
+	if made.Before(now.Add(-hn.window)) || made.After(now.Add(hn.window)) {
+		return false
+	}
+	hn.lock.Lock()
+	defer hn.lock.Unlock()
+	if _, dup := hn.seen[string(nonce)]; dup {
+		return false
+	}
+	for e := hn.order.Front(); e != nil; e = hn.order.Front() {
+		if ns := e.Value.(*hsNonceSeen); now.After(ns.expired) || hn.order.Len() >= hsNoncesMax {
+			delete(hn.seen, ns.nonce)
+			hn.order.Remove(e)
+			continue
+		}
+		break
+	}
+	ns := &hsNonceSeen{nonce: string(nonce), expired: made.Add(hn.window)}
+	hn.seen[ns.nonce] = hn.order.PushBack(ns)
+	return true
+}
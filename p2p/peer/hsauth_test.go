@@ -0,0 +1,183 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	pb "github.com/yeeco/gyee/p2p/peer/pb"
+)
+
+// protocols of the p2p internal protocol advertised, up to ver
+func hsAuthTestProtocols(ver [4]byte) []Protocol {
+	var protocols []Protocol
+	for _, p := range config.P2pDefaultProtocols() {
+		if bytes.Compare(p.Ver[:], ver[:]) <= 0 {
+			protocols = append(protocols, Protocol{Pid: p.Pid, Ver: p.Ver})
+		}
+	}
+	return protocols
+}
+
+func hsAuthTestMsg(key *ecdsa.PrivateKey, protocols []Protocol) *pb.P2PMessage_Handshake {
+	udp, tcp := uint32(30303), uint32(30303)
+	hs := &pb.P2PMessage_Handshake{
+		NodeId: config.P2pPubkey2NodeId(&key.PublicKey)[:],
+		IP:     net.ParseIP("10.0.0.1"),
+		UDP:    &udp,
+		TCP:    &tcp,
+	}
+	for _, p := range protocols {
+		pid := pb.ProtocolId(p.Pid)
+		hs.Protocols = append(hs.Protocols, &pb.P2PMessage_Protocol{Pid: &pid, Ver: p.Ver[:]})
+	}
+	return hs
+}
+
+func TestHandshakeSigned(t *testing.T) {
+	obKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ibKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	nonces := newHsNonces()
+	local := hsAuthTestProtocols(config.P2pProtoVerAuth)
+	ob := &PeerInstance{dir: PeInstDirOutbound, priKey: *obKey, localProtocols: local}
+	ib := &PeerInstance{dir: PeInstDirInbound, priKey: *ibKey, localProtocols: local, peMgr: &PeerManager{hsNonces: nonces}}
+	pkg := new(P2pPackage)
+
+	hs := hsAuthTestMsg(obKey, local)
+	if !pkg.signOutbound(ob, hs) || !pkg.verifyInbound(ib, hs, local) {
+		t.Fatalf("outbound handshake not verified")
+	}
+
+	// replayed to another listener sharing the nonces seen, or tampered
+	other := &PeerInstance{dir: PeInstDirInbound, localProtocols: local, peMgr: &PeerManager{hsNonces: nonces}}
+	if pkg.verifyInbound(other, hs, local) {
+		t.Fatalf("outbound handshake replayed")
+	}
+	hs = hsAuthTestMsg(obKey, local)
+	pkg.signOutbound(ob, hs)
+	*hs.TCP = 30304
+	if pkg.verifyInbound(ib, hs, local) {
+		t.Fatalf("outbound handshake tampered")
+	}
+	hs = hsAuthTestMsg(obKey, local)
+	pkg.signOutbound(ob, hs)
+	hs.Nonce = nil
+	if pkg.verifyInbound(ib, hs, local) {
+		t.Fatalf("outbound handshake not signed with nonce")
+	}
+
+	// the answer must be for the nonce of the outbound side
+	hs = hsAuthTestMsg(obKey, local)
+	pkg.signOutbound(ob, hs)
+	pkg.verifyInbound(ib, hs, local)
+	answer := hsAuthTestMsg(ibKey, local)
+	if !pkg.signOutbound(ib, answer) || !pkg.verifyInbound(ob, answer, local) {
+		t.Fatalf("inbound handshake not verified")
+	}
+	ob.hsNonce = newHsNonce(time.Now())
+	if pkg.verifyInbound(ob, answer, local) {
+		t.Fatalf("inbound handshake replayed")
+	}
+}
+
+func TestHandshakeLegacy(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	local := hsAuthTestProtocols(config.P2pProtoVerAuth)
+	legacy := hsAuthTestProtocols(config.P2pProtoVerExt)
+	peMgr := &PeerManager{hsNonces: newHsNonces()}
+	ib := &PeerInstance{dir: PeInstDirInbound, localProtocols: local, peMgr: peMgr}
+	pkg := new(P2pPackage)
+
+	// a legacy peer signs the node identity only
+	legacyMsg := func(protocols []Protocol) *pb.P2PMessage_Handshake {
+		hs := hsAuthTestMsg(key, protocols)
+		pkg.signOutbound(&PeerInstance{dir: PeInstDirOutbound, priKey: *key}, hs)
+		hs.Nonce, hs.AuthSig = nil, nil
+		return hs
+	}
+	if !pkg.verifyInbound(ib, legacyMsg(legacy), legacy) {
+		t.Fatalf("legacy handshake refused")
+	}
+	hs := legacyMsg(legacy)
+	hs.R[0] ^= 0xff
+	if pkg.verifyInbound(ib, hs, legacy) {
+		t.Fatalf("legacy handshake with bad signature accepted")
+	}
+
+	// not signed with nonce, while the version agreed or configuration requires
+	if pkg.verifyInbound(ib, legacyMsg(local), local) {
+		t.Fatalf("handshake not signed with nonce accepted, version agreed requires it")
+	}
+	peMgr.cfg.hsAuthRequired = true
+	if pkg.verifyInbound(ib, legacyMsg(legacy), legacy) {
+		t.Fatalf("handshake not signed with nonce accepted, configured to require it")
+	}
+}
+
+func TestHandshakeNonceFresh(t *testing.T) {
+	nonces := newHsNonces()
+	now := time.Now()
+	window := config.DftHsNonceWindow
+	if nonces.check(newHsNonce(now.Add(-window-time.Second)), now) {
+		t.Fatalf("stale nonce accepted")
+	}
+	if nonces.check(newHsNonce(now.Add(window+time.Second)), now) {
+		t.Fatalf("future nonce accepted")
+	}
+	for i := 0; i < 1024; i++ {
+		nonces.check(newHsNonce(now), now)
+	}
+	// those seen are purged once expired
+	later := now.Add(window * 2)
+	if !nonces.check(newHsNonce(later), later) || len(nonces.seen) != 1 {
+		t.Fatalf("nonces not purged, seen: %d", len(nonces.seen))
+	}
+
+	// the window configured
+	nonces.window = time.Minute * 30
+	if !nonces.check(newHsNonce(later.Add(-window*2)), later) {
+		t.Fatalf("nonce in the window configured refused")
+	}
+}
+
+func TestHandshakeNonceFull(t *testing.T) {
+	nonces := newHsNonces()
+	now := time.Now()
+	first := newHsNonce(now)
+	nonces.check(first, now)
+	for i := 1; i < hsNoncesMax; i++ {
+		nonces.check(newHsNonce(now), now)
+	}
+	// the oldest forgotten rather than refusing fresh ones
+	if !nonces.check(newHsNonce(now), now) || len(nonces.seen) != hsNoncesMax {
+		t.Fatalf("fresh nonce refused when full, seen: %d", len(nonces.seen))
+	}
+	if _, ok := nonces.seen[string(first)]; ok {
+		t.Fatalf("oldest nonce not forgotten")
+	}
+}
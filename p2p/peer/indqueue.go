@@ -0,0 +1,104 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"sync/atomic"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Indications queued to indChan are pulled by the user, see GetInstIndChannel
+// and RegisterInstIndCallback. when the user can't keep up and indChan is full,
+// the policy configured decides:
+// 1) config.IndqPolicyBlock: the peer manager task blocks till the indication
+// queued, it's dropped if it's still not queued after indBlockTime;
+// 2) config.IndqPolicyDropOldest: the oldest indication queued is dropped to
+// make room for the newest one;
+// 3) config.IndqPolicyDropNewest: the newest indication is dropped.
+// Overflows and indications dropped are counted, see GetIndStats.
+//
+
+type IndStats struct {
+	Overflows int64 // indications found indChan full
+	Dropped   int64 // indications dropped, the oldest or the newest
+}
+
+type indStats struct {
+	overflows int64 // updated in the peer manager task, read by GetIndStats
+	dropped   int64 // updated in the peer manager task, read by GetIndStats
+}
+
+func (peMgr *PeerManager) peMgrIndEnque(ind interface{}) PeMgrErrno {
+	select {
+	case peMgr.indChan <- ind:
+		return PeMgrEnoNone
+	default:
+	}
+	atomic.AddInt64(&peMgr.indStats.overflows, 1)
+	cfg := &peMgr.cfg
+	switch cfg.indqPolicy {
+
+	case config.IndqPolicyDropOldest:
+		for {
+			select {
+			case peMgr.indChan <- ind:
+				return PeMgrEnoNone
+			default:
+			}
+			select {
+			case <-peMgr.indChan:
+				atomic.AddInt64(&peMgr.indStats.dropped, 1)
+				peerLog.Debug("peMgrIndEnque: queue full, the oldest dropped")
+			default:
+			}
+		}
+
+	case config.IndqPolicyDropNewest:
+
+	default:
+		dur := cfg.indBlockTime
+		if dur <= 0 {
+			dur = config.DftIndBlockTimeout
+		}
+		tm := time.NewTimer(dur)
+		defer tm.Stop()
+		select {
+		case peMgr.indChan <- ind:
+			return PeMgrEnoNone
+		case <-tm.C:
+		}
+	}
+
+	atomic.AddInt64(&peMgr.indStats.dropped, 1)
+	peerLog.Debug("peMgrIndEnque: queue full, the newest dropped")
+	return PeMgrEnoResource
+}
+
+// Get statistics of indications overflowed the queue
+func (peMgr *PeerManager) GetIndStats() IndStats {
+	return IndStats{
+		Overflows: atomic.LoadInt64(&peMgr.indStats.overflows),
+		Dropped:   atomic.LoadInt64(&peMgr.indStats.dropped),
+	}
+}
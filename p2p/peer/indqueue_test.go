@@ -0,0 +1,88 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestIndOverflow(t *testing.T) {
+	peMgr := &PeerManager{indChan: make(chan interface{}, 2)}
+	enque := func(ind int) PeMgrErrno {
+		return peMgr.peMgrIndEnque(ind)
+	}
+	pull := func() []int {
+		inds := make([]int, 0)
+		for len(peMgr.indChan) > 0 {
+			inds = append(inds, (<-peMgr.indChan).(int))
+		}
+		return inds
+	}
+
+	// drop the newest
+	peMgr.cfg.indqPolicy = config.IndqPolicyDropNewest
+	enque(1)
+	enque(2)
+	if eno := enque(3); eno != PeMgrEnoResource {
+		t.Errorf("drop newest got eno: %d", eno)
+	}
+	if inds := pull(); len(inds) != 2 || inds[0] != 1 || inds[1] != 2 {
+		t.Errorf("drop newest got %v", inds)
+	}
+
+	// drop the oldest
+	peMgr.cfg.indqPolicy = config.IndqPolicyDropOldest
+	for ind := 1; ind <= 4; ind++ {
+		if eno := enque(ind); eno != PeMgrEnoNone {
+			t.Fatalf("drop oldest got eno: %d", eno)
+		}
+	}
+	if inds := pull(); len(inds) != 2 || inds[0] != 3 || inds[1] != 4 {
+		t.Errorf("drop oldest got %v", inds)
+	}
+
+	// block till pulled, or timeout
+	peMgr.cfg.indqPolicy = config.IndqPolicyBlock
+	peMgr.cfg.indBlockTime = time.Millisecond * 50
+	enque(1)
+	enque(2)
+	if eno := enque(3); eno != PeMgrEnoResource {
+		t.Errorf("block got eno: %d", eno)
+	}
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		<-peMgr.indChan
+	}()
+	peMgr.cfg.indBlockTime = time.Second * 5
+	if eno := enque(3); eno != PeMgrEnoNone {
+		t.Errorf("block got eno: %d", eno)
+	}
+	if inds := pull(); len(inds) != 2 || inds[0] != 2 || inds[1] != 3 {
+		t.Errorf("block got %v", inds)
+	}
+
+	if stats := peMgr.GetIndStats(); stats.Overflows != 5 || stats.Dropped != 4 {
+		t.Errorf("stats got %+v", stats)
+	}
+}
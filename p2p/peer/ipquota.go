@@ -0,0 +1,143 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"net"
+	"time"
+)
+
+//
+// Inbound quota per ip: connections accepted from a remote ip are limited both
+// in the number of inbound instances at the same time, handshaked or not, and in
+// rate, by a token bucket filled by ipAcceptRate per minute up to a burst of the
+// same, so a single host can't take all the inbound slots, nor keep the peer
+// manager busy with connections to be killed. loopback addresses and those of
+// static nodes are not limited. it's accessed in the peer manager task only.
+//
+
+const (
+	ipQuotaMaxBuckets = 4096 // max ips tracked for the rate, those idle are pruned
+)
+
+const (
+	ipQuotaOk      = iota // accepted
+	ipQuotaFull           // too many inbound instances of the ip
+	ipQuotaLimited        // accepted too fast from the ip
+)
+
+type ipBucket struct {
+	tokens float64   // accepts allowed
+	last   time.Time // time tokens updated
+}
+
+type ipQuota struct {
+	conns   map[string]int       // inbound instances by remote ip
+	buckets map[string]*ipBucket // accept tokens by remote ip
+}
+
+func newIpQuota() *ipQuota {
+	return &ipQuota{
+		conns:   make(map[string]int, 0),
+		buckets: make(map[string]*ipBucket, 0),
+	}
+}
+
+// Check a connection accepted from ip against the limits, not positive for
+// unlimited, and count it as an inbound instance if it's admitted.
+func (q *ipQuota) admit(ip net.IP, maxConns int, rate int, now time.Time) int {
+	key := ip.String()
+	if maxConns > 0 && q.conns[key] >= maxConns {
+		return ipQuotaFull
+	}
+	if rate > 0 {
+		burst := float64(rate)
+		b, ok := q.buckets[key]
+		if !ok {
+			if len(q.buckets) >= ipQuotaMaxBuckets {
+				q.prune(rate, now)
+			}
+			b = &ipBucket{tokens: burst, last: now}
+			q.buckets[key] = b
+		}
+		if elapsed := now.Sub(b.last); elapsed > 0 {
+			b.tokens += elapsed.Minutes() * float64(rate)
+			if b.tokens > burst {
+				b.tokens = burst
+			}
+		}
+		b.last = now
+		if b.tokens < 1 {
+			return ipQuotaLimited
+		}
+		b.tokens--
+	}
+	q.conns[key]++
+	return ipQuotaOk
+}
+
+// an inbound instance admitted killed
+func (q *ipQuota) released(ip net.IP) {
+	key := ip.String()
+	if q.conns[key]--; q.conns[key] <= 0 {
+		delete(q.conns, key)
+	}
+}
+
+// buckets refilled full are the same as those not tracked
+func (q *ipQuota) prune(rate int, now time.Time) {
+	for key, b := range q.buckets {
+		if now.Sub(b.last).Minutes()*float64(rate)+b.tokens >= float64(rate) {
+			delete(q.buckets, key)
+		}
+	}
+}
+
+func (peMgr *PeerManager) ipQuotaExempted(ip net.IP) bool {
+	if ip.IsLoopback() {
+		return true
+	}
+	for _, sn := range peMgr.cfg.staticNodes {
+		if sn.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// check a connection accepted against the quota of the remote ip, false if
+// it should be closed
+func (peMgr *PeerManager) ipQuotaAdmit(ip net.IP) bool {
+	ap := peMgr.acceptPause
+	switch peMgr.ipQuota.admit(ip, peMgr.cfg.ipMaxInbounds, peMgr.cfg.ipAcceptRate, time.Now()) {
+	case ipQuotaFull:
+		ap.lock.Lock()
+		ap.stats.IpFull++
+		ap.lock.Unlock()
+		return false
+	case ipQuotaLimited:
+		ap.lock.Lock()
+		ap.stats.IpLimited++
+		ap.lock.Unlock()
+		return false
+	}
+	return true
+}
@@ -0,0 +1,86 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestIpQuota(t *testing.T) {
+	q := newIpQuota()
+	ip, other := net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")
+	now := time.Now()
+
+	// inbounds of an ip capped, released ones make room
+	for i := 0; i < 2; i++ {
+		if r := q.admit(ip, 2, 0, now); r != ipQuotaOk {
+			t.Fatalf("admit %d got %d", i, r)
+		}
+	}
+	if r := q.admit(ip, 2, 0, now); r != ipQuotaFull {
+		t.Errorf("admit above cap got %d", r)
+	}
+	if r := q.admit(other, 2, 0, now); r != ipQuotaOk {
+		t.Errorf("admit of another ip got %d", r)
+	}
+	q.released(ip)
+	if r := q.admit(ip, 2, 0, now); r != ipQuotaOk {
+		t.Errorf("admit after released got %d", r)
+	}
+	q.released(ip)
+	q.released(ip)
+	q.released(other)
+	if len(q.conns) != 0 {
+		t.Errorf("conns left %v", q.conns)
+	}
+
+	// rate limited with a burst of a minute, refilled in time
+	for i := 0; i < 3; i++ {
+		if r := q.admit(ip, 0, 3, now); r != ipQuotaOk {
+			t.Fatalf("admit in burst %d got %d", i, r)
+		}
+	}
+	if r := q.admit(ip, 0, 3, now); r != ipQuotaLimited {
+		t.Errorf("admit above burst got %d", r)
+	}
+	if r := q.admit(ip, 0, 3, now.Add(20*time.Second)); r != ipQuotaOk {
+		t.Errorf("admit after refilled got %d", r)
+	}
+	q.prune(3, now.Add(time.Hour))
+	if len(q.buckets) != 0 {
+		t.Errorf("buckets left %d", len(q.buckets))
+	}
+}
+
+func TestIpQuotaExempted(t *testing.T) {
+	peMgr := &PeerManager{}
+	peMgr.cfg.staticNodes = []*config.Node{{ID: config.NodeID{1}, IP: net.ParseIP("10.0.0.3")}}
+	if !peMgr.ipQuotaExempted(net.ParseIP("127.0.0.1")) || !peMgr.ipQuotaExempted(net.ParseIP("10.0.0.3")) {
+		t.Errorf("loopback or static node not exempted")
+	}
+	if peMgr.ipQuotaExempted(net.ParseIP("10.0.0.1")) {
+		t.Errorf("exempted")
+	}
+}
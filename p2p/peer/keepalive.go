@@ -0,0 +1,65 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+//
+// Keepalive suppression: the pingpong counter is reset by anything received from
+// the peer in the last cycle, not only by pings, and the ping of a cycle is not
+// sent if data packages were also sent to the peer in it, since the traffic in
+// both directions tells each side the other is alive. at most
+// peInstMaxPingsSuppressed pings in a row are suppressed, for peers resetting
+// the counter by pings only, which close the connection after
+// PeInstMaxPingpongCnt cycles without any, and for the round trip time observed
+// from pongs.
+//
+
+const peInstMaxPingsSuppressed = PeInstMaxPingpongCnt - 2 // max pings suppressed in a row
+
+// a package received, called in piRx
+func (pi *PeerInstance) rxSeen(now time.Time) {
+	atomic.StoreInt64(&pi.rxLast, now.UnixNano())
+}
+
+// a data package sent, called in piTx
+func (pi *PeerInstance) txSeen(now time.Time) {
+	atomic.StoreInt64(&pi.txLast, now.UnixNano())
+}
+
+// check the traffic of the last cycle for the pingpong timer: if anything is
+// received from the peer, and if the ping can be suppressed
+func (pi *PeerInstance) ppTraffic(now time.Time) (alive bool, suppress bool) {
+	since := now.Add(-PeInstPingpongCycle).UnixNano()
+	if atomic.LoadInt64(&pi.rxLast) <= since {
+		pi.ppSuppressed = 0
+		return false, false
+	}
+	if atomic.LoadInt64(&pi.txLast) > since && pi.ppSuppressed < peInstMaxPingsSuppressed {
+		pi.ppSuppressed++
+		return true, true
+	}
+	pi.ppSuppressed = 0
+	return true, false
+}
@@ -0,0 +1,59 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPingpongTraffic(t *testing.T) {
+	pi := &PeerInstance{}
+	now := time.Now()
+	if alive, suppress := pi.ppTraffic(now); alive || suppress {
+		t.Errorf("idle got %t, %t", alive, suppress)
+	}
+
+	// received only, pinged
+	pi.rxSeen(now.Add(-time.Second))
+	if alive, suppress := pi.ppTraffic(now); !alive || suppress {
+		t.Errorf("rx only got %t, %t", alive, suppress)
+	}
+
+	// both directions, suppressed for a limited number of cycles in a row
+	pi.txSeen(now.Add(-time.Second))
+	for i := 0; i < peInstMaxPingsSuppressed; i++ {
+		if alive, suppress := pi.ppTraffic(now); !alive || !suppress {
+			t.Fatalf("cycle %d got %t, %t", i, alive, suppress)
+		}
+	}
+	if _, suppress := pi.ppTraffic(now); suppress {
+		t.Errorf("suppressed more than %d in a row", peInstMaxPingsSuppressed)
+	}
+	if _, suppress := pi.ppTraffic(now); !suppress {
+		t.Errorf("not suppressed after a ping")
+	}
+
+	// traffic older than a cycle
+	if alive, suppress := pi.ppTraffic(now.Add(PeInstPingpongCycle)); alive || suppress {
+		t.Errorf("stale got %t, %t", alive, suppress)
+	}
+}
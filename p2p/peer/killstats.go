@@ -0,0 +1,135 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+//
+// Statistics of peer instances killed, by sub network and cause. instances
+// killed before activated, for duplicated, resource limited or handshake failed,
+// are counted by the reason of peMgrKillInst; those activated and then closed are
+// counted by the reason they were asked to close for, see PEC_FOR_XXX in package
+// scheduler. the sub network of an inbound instance failed in handshake is not
+// known, it's counted with an identity of all zero bytes.
+//
+
+const (
+	KillForDupInbound  = "dupInbound"  // duplicated to an inbound instance of the peer
+	KillForDupOutbound = "dupOutbound" // duplicated to an outbound instance of the peer
+	KillForResource    = "resource"    // max peers, inbounds or outbounds reached
+	KillForHandshake   = "handshake"   // handshake failed
+	KillForDial        = "dial"        // outbound connection failed
+	KillForPingpong    = "pingpong"    // pingpong threshold reached
+	KillForLinkError   = "linkError"   // failed to read or write the connection
+	KillForCommand     = "command"     // closed by command or the user
+	KillForReconfig    = "reconfig"    // sub networks reconfigured
+	KillForSeedShed    = "seedShed"    // shed by a seed-only bootstrap node
+	KillForOther       = "other"       // none of the above
+)
+
+var killCauses = map[string]string{
+	PKI_FOR_IBW_DUPLICATED:    KillForDupInbound,
+	PKI_FOR_OB2IB_DUPLICATED:  KillForDupInbound,
+	PKI_FOR_OBW_DUPLICATED:    KillForDupOutbound,
+	PKI_FOR_IB2OB_DUPLICATED:  KillForDupOutbound,
+	PKI_FOR_TOOMUCH_WORKERS:   KillForResource,
+	PKI_FOR_TOOMUCH_INBOUNDS:  KillForResource,
+	PKI_FOR_TOOMUCH_OUTBOUNDS: KillForResource,
+	PKI_FOR_HANDSHAKE_FAILED:  KillForHandshake,
+	PKI_FOR_FAILED_INST_CFM:   KillForHandshake,
+	PKI_FOR_BOUNDOUT_FAILED:   KillForDial,
+	PKI_FOR_RECONFIG:          KillForReconfig,
+	sch.PEC_FOR_PINGPONG:      KillForPingpong,
+	sch.PEC_FOR_RXERROR:       KillForLinkError,
+	sch.PEC_FOR_TXERROR:       KillForLinkError,
+	sch.PEC_FOR_SETDEADLINE:   KillForLinkError,
+	sch.PEC_FOR_COMMAND:       KillForCommand,
+	sch.PEC_FOR_BEASKEDTO:     KillForCommand,
+	sch.PEC_FOR_RECONFIG:      KillForReconfig,
+	sch.PEC_FOR_RECONFIG_REQ:  KillForReconfig,
+	sch.PEC_FOR_SEEDSHED:      KillForSeedShed,
+}
+
+// cause counted for the reason an instance killed or closed for
+func killCause(why string) string {
+	if cause, ok := killCauses[why]; ok {
+		return cause
+	}
+	return KillForOther
+}
+
+type KillStat struct {
+	Snid  SubNetworkID // sub network identity
+	Cause string       // KillForXXX
+	Count int64        // number of instances killed
+}
+
+type killStatKey struct {
+	snid  SubNetworkID
+	cause string
+}
+
+type killStats struct {
+	lock sync.Mutex            // updated in the peer manager task, read by GetKillStats
+	tab  map[killStatKey]int64 // counters
+}
+
+func newKillStats() *killStats {
+	return &killStats{
+		tab: make(map[killStatKey]int64, 0),
+	}
+}
+
+// count an instance killed for why, the cause counted returned
+func (ks *killStats) count(snid SubNetworkID, why string) string {
+	cause := killCause(why)
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	ks.tab[killStatKey{snid: snid, cause: cause}]++
+	return cause
+}
+
+func (ks *killStats) snapshot() []KillStat {
+	ks.lock.Lock()
+	defer ks.lock.Unlock()
+	stats := make([]KillStat, 0, len(ks.tab))
+	for k, n := range ks.tab {
+		stats = append(stats, KillStat{Snid: k.snid, Cause: k.cause, Count: n})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if c := bytes.Compare(stats[i].Snid[:], stats[j].Snid[:]); c != 0 {
+			return c < 0
+		}
+		return stats[i].Cause < stats[j].Cause
+	})
+	return stats
+}
+
+// Get statistics of peer instances killed by sub network and cause
+func (peMgr *PeerManager) GetKillStats() []KillStat {
+	return peMgr.killStats.snapshot()
+}
@@ -0,0 +1,60 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+func TestKillStats(t *testing.T) {
+	snid := config.SubNetworkID{0x12, 0x34}
+	ks := newKillStats()
+	if cause := ks.count(snid, PKI_FOR_IBW_DUPLICATED); cause != KillForDupInbound {
+		t.Errorf("cause got %s, want %s", cause, KillForDupInbound)
+	}
+	ks.count(snid, PKI_FOR_OB2IB_DUPLICATED)
+	ks.count(snid, PKI_FOR_IB2OB_DUPLICATED)
+	ks.count(snid, sch.PEC_FOR_PINGPONG)
+	ks.count(SubNetworkID{}, PKI_FOR_HANDSHAKE_FAILED)
+	if cause := ks.count(snid, ""); cause != KillForOther {
+		t.Errorf("cause got %s, want %s", cause, KillForOther)
+	}
+
+	want := []KillStat{
+		{Snid: SubNetworkID{}, Cause: KillForHandshake, Count: 1},
+		{Snid: snid, Cause: KillForDupInbound, Count: 2},
+		{Snid: snid, Cause: KillForDupOutbound, Count: 1},
+		{Snid: snid, Cause: KillForOther, Count: 1},
+		{Snid: snid, Cause: KillForPingpong, Count: 1},
+	}
+	stats := ks.snapshot()
+	if len(stats) != len(want) {
+		t.Fatalf("stats got %v, want %v", stats, want)
+	}
+	for i := range want {
+		if stats[i] != want[i] {
+			t.Errorf("stats[%d] got %+v, want %+v", i, stats[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,268 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Known peers: peers handshaked in dynamic sub networks, with the time they were
+// seen last. they're written to a json file under the node data directory, and
+// loaded when the peer manager is powered on, to seed the random nodes, so a node
+// restarted reconnects to peers it knew without waiting the discovery. it's
+// accessed in the peer manager task only. statistics of peers are written to the
+// file with them, see peerstats.go; files of a list of peers only, written before
+// that, are still loaded.
+//
+
+const (
+	knownPeersMax          = 512                // max peers kept, the ones seen earliest are evicted
+	knownPeersMaxAge       = 7 * 24 * time.Hour // peers not seen in it are dropped when loaded
+	knownPeersSaveInterval = 1 * time.Minute    // min interval between two writes of the file
+)
+
+type knownPeerKey struct {
+	snid config.SubNetworkID // sub network identity
+	id   config.NodeID       // node identity
+}
+
+// peer entry in the file
+type knownPeer struct {
+	Snid     string        `json:"snid"` // sub network identity in hex
+	Id       config.NodeID `json:"id"`
+	IP       net.IP        `json:"ip"`
+	UDP      uint16        `json:"udp"`
+	TCP      uint16        `json:"tcp"`
+	LastSeen time.Time     `json:"lastSeen"`
+}
+
+// the file
+type knownPeersFile struct {
+	Peers []*knownPeer `json:"peers"`
+	Stats []*PeerStats `json:"stats"`
+}
+
+type knownPeers struct {
+	path  string                      // file peers persisted to, kept in memory only if empty
+	peers map[knownPeerKey]*knownPeer // peers by sub network and identity
+	stats *peerStats                  // statistics of peers
+	dirty bool                        // changed since written
+	saved time.Time                   // time written last
+}
+
+func newKnownPeers() *knownPeers {
+	return &knownPeers{
+		peers: make(map[knownPeerKey]*knownPeer, 0),
+		stats: newPeerStats(),
+	}
+}
+
+// Load peers from file, those not seen for long are dropped. peers are persisted
+// to the file since then.
+func (kp *knownPeers) load(path string) error {
+	kp.path = path
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var file knownPeersFile
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &file.Peers)
+	} else {
+		err = json.Unmarshal(data, &file)
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	kp.stats.load(file.Stats, now)
+	for _, e := range file.Peers {
+		var key knownPeerKey
+		snid, err := hex.DecodeString(e.Snid)
+		if err != nil || len(snid) != config.SubNetIdBytes || e.IP == nil || e.TCP == 0 {
+			peerLog.Debug("knownPeers.load: invalid peer: %+v", *e)
+			continue
+		}
+		if now.Sub(e.LastSeen) > knownPeersMaxAge {
+			continue
+		}
+		copy(key.snid[:], snid)
+		key.id = e.Id
+		kp.peers[key] = e
+	}
+	kp.evict()
+	return nil
+}
+
+// write peers to file if changed
+func (kp *knownPeers) save() error {
+	if len(kp.path) == 0 || !kp.dirty {
+		return nil
+	}
+	entries := make([]*knownPeer, 0, len(kp.peers))
+	for _, e := range kp.peers {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastSeen.After(entries[j].LastSeen)
+	})
+	file := knownPeersFile{
+		Peers: entries,
+		Stats: kp.stats.snapshot(),
+	}
+	data, err := json.MarshalIndent(&file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(kp.path), 0700); err != nil {
+		return err
+	}
+	// replaced by renaming, not to leave a partial file if crashed
+	tmp := kp.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, kp.path); err != nil {
+		return err
+	}
+	kp.dirty = false
+	kp.saved = time.Now()
+	return nil
+}
+
+func (kp *knownPeers) saveLogged() {
+	if err := kp.save(); err != nil {
+		peerLog.Debug("knownPeers.save: failed, path: %s, err: %s", kp.path, err.Error())
+	}
+}
+
+// a peer handshaked in sub network snid, the file is written at most once in
+// knownPeersSaveInterval
+func (kp *knownPeers) seen(snid config.SubNetworkID, node *config.Node) {
+	key := knownPeerKey{snid: snid, id: node.ID}
+	kp.peers[key] = &knownPeer{
+		Snid:     hex.EncodeToString(snid[:]),
+		Id:       node.ID,
+		IP:       node.IP,
+		UDP:      node.UDP,
+		TCP:      node.TCP,
+		LastSeen: time.Now(),
+	}
+	kp.evict()
+	kp.changed()
+}
+
+// peers or statistics changed, the file is written at most once in
+// knownPeersSaveInterval
+func (kp *knownPeers) changed() {
+	kp.dirty = true
+	if time.Since(kp.saved) >= knownPeersSaveInterval {
+		kp.saveLogged()
+	}
+}
+
+func (kp *knownPeers) evict() {
+	for len(kp.peers) > knownPeersMax {
+		var oldest knownPeerKey
+		var seen time.Time
+		for key, e := range kp.peers {
+			if seen.IsZero() || e.LastSeen.Before(seen) {
+				oldest, seen = key, e.LastSeen
+			}
+		}
+		delete(kp.peers, oldest)
+		kp.dirty = true
+	}
+}
+
+// peers known in sub network snid, the ones seen latest first
+func (kp *knownPeers) nodes(snid config.SubNetworkID) []*config.Node {
+	entries := make([]*knownPeer, 0)
+	for key, e := range kp.peers {
+		if key.snid == snid {
+			entries = append(entries, e)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastSeen.After(entries[j].LastSeen)
+	})
+	nodes := make([]*config.Node, 0, len(entries))
+	for _, e := range entries {
+		nodes = append(nodes, &config.Node{IP: e.IP, UDP: e.UDP, TCP: e.TCP, ID: e.Id})
+	}
+	return nodes
+}
+
+// seed random nodes of dynamic sub networks with peers known, they're connected
+// when the peer manager starts, before any node found by the discovery
+func (peMgr *PeerManager) knownPeersSeed() {
+	if peMgr.cfg.networkType != config.P2pNetworkTypeDynamic {
+		return
+	}
+	seeded := 0
+	for _, snid := range peMgr.cfg.subNetIdList {
+		if snid == peMgr.cfg.staticSubNetId {
+			continue
+		}
+	_nodes:
+		for _, n := range peMgr.knownPeers.nodes(snid) {
+			if len(peMgr.randoms[snid]) >= peMgr.cfg.subNetMaxPeers[snid] {
+				break
+			}
+			if peMgr.banList.banned(n) {
+				continue
+			}
+			for _, sn := range peMgr.cfg.staticNodes {
+				if sn.ID == n.ID {
+					continue _nodes
+				}
+			}
+			peMgr.randoms[snid] = append(peMgr.randoms[snid], n)
+			seeded++
+		}
+	}
+	peerLog.Debug("knownPeersSeed: peers known: %d, seeded: %d", len(peMgr.knownPeers.peers), seeded)
+}
+
+// record a peer activated in a dynamic sub network
+func (peMgr *PeerManager) knownPeersActivated(inst *PeerInstance) {
+	if peMgr.cfg.networkType != config.P2pNetworkTypeDynamic || inst.snid == peMgr.cfg.staticSubNetId {
+		return
+	}
+	if inst.node.TCP == 0 {
+		// the peer tells it's not dialable, see selfprobe.go
+		return
+	}
+	peMgr.knownPeers.seen(inst.snid, &inst.node)
+}
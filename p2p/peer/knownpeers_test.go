@@ -0,0 +1,115 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestKnownPeers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gyee-knownpeers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "inst", config.KnownPeersFileName)
+
+	kp := newKnownPeers()
+	if err := kp.load(path); err != nil {
+		t.Fatal(err)
+	}
+	snid := config.SubNetworkID{0x12, 0x34}
+	early := &config.Node{ID: config.NodeID{1}, IP: net.ParseIP("10.0.0.1"), UDP: 30303, TCP: 30303}
+	late := &config.Node{ID: config.NodeID{2}, IP: net.ParseIP("2001:db8::1"), UDP: 30303, TCP: 30304}
+	kp.seen(snid, early)
+	kp.seen(snid, late)
+	kp.seen(config.AnySubNet, early)
+	kp.peers[knownPeerKey{snid: snid, id: config.NodeID{3}}] = &knownPeer{
+		Snid:     "1234",
+		Id:       config.NodeID{3},
+		IP:       net.ParseIP("10.0.0.3"),
+		TCP:      30303,
+		LastSeen: time.Now().Add(-knownPeersMaxAge - time.Hour),
+	}
+	kp.dirty = true
+	if err := kp.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// reloaded as restarted, the one not seen for long is dropped
+	kp = newKnownPeers()
+	if err := kp.load(path); err != nil {
+		t.Fatal(err)
+	}
+	nodes := kp.nodes(snid)
+	if len(nodes) != 2 || nodes[0].ID != late.ID || nodes[1].ID != early.ID {
+		t.Fatalf("nodes after reload got %v", nodes)
+	}
+	if !nodes[0].IP.Equal(late.IP) || nodes[0].TCP != late.TCP || nodes[0].UDP != late.UDP {
+		t.Errorf("node after reload got %+v, want %+v", *nodes[0], *late)
+	}
+	if len(kp.nodes(config.AnySubNet)) != 1 {
+		t.Errorf("nodes of another sub network got %v", kp.nodes(config.AnySubNet))
+	}
+
+	for i := 0; i < knownPeersMax+10; i++ {
+		kp.seen(snid, &config.Node{ID: config.NodeID{byte(i), byte(i >> 8), 4}, IP: early.IP, TCP: 1})
+	}
+	if len(kp.peers) != knownPeersMax {
+		t.Errorf("peers kept got %d, want %d", len(kp.peers), knownPeersMax)
+	}
+}
+
+func TestKnownPeersSeed(t *testing.T) {
+	snid := config.SubNetworkID{0x12, 0x34}
+	peMgr := &PeerManager{
+		knownPeers: newKnownPeers(),
+		banList:    newBanList(),
+		randoms:    map[SubNetworkID][]*config.Node{},
+	}
+	peMgr.cfg.networkType = config.P2pNetworkTypeDynamic
+	peMgr.cfg.staticSubNetId = config.ZeroSubNet
+	peMgr.cfg.subNetIdList = []SubNetworkID{snid, config.ZeroSubNet}
+	peMgr.cfg.subNetMaxPeers = map[SubNetworkID]int{snid: 2}
+	peMgr.cfg.staticNodes = []*config.Node{{ID: config.NodeID{3}}}
+	for i := byte(1); i <= 4; i++ {
+		peMgr.knownPeers.seen(snid, &config.Node{ID: config.NodeID{i}, IP: net.ParseIP("10.0.0.1"), TCP: 30303})
+		peMgr.knownPeers.seen(config.ZeroSubNet, &config.Node{ID: config.NodeID{i}, IP: net.ParseIP("10.0.0.1"), TCP: 30303})
+		time.Sleep(time.Millisecond)
+	}
+	peMgr.banList.banNode(config.NodeID{4}, time.Now().Add(time.Hour))
+
+	peMgr.knownPeersSeed()
+	seeded := peMgr.randoms[snid]
+	if len(seeded) != 2 || seeded[0].ID != (config.NodeID{2}) || seeded[1].ID != (config.NodeID{1}) {
+		t.Errorf("seeded got %v", seeded)
+	}
+	if len(peMgr.randoms[config.ZeroSubNet]) != 0 {
+		t.Errorf("static sub network seeded")
+	}
+}
@@ -119,7 +119,7 @@ func (lsnMgr *ListenerManager) lsnMgrPoweron(ptn interface{}) sch.SchErrno {
 		return sch.SchEnoInternal
 	}
 
-	lsnMgr.cfg = config.P2pConfig4PeerListener(lsnMgr.sdl.SchGetP2pCfgName())
+	lsnMgr.cfg = lsnMgr.sdl.SchGetTaskConfig(lsnMgr.ptn).Config4PeerListener()
 	if lsnMgr.cfg == nil {
 		return sch.SchEnoConfig
 	}
@@ -129,16 +129,46 @@ func (lsnMgr *ListenerManager) lsnMgrPoweron(ptn interface{}) sch.SchErrno {
 
 func (lsnMgr *ListenerManager) lsnMgrSetupListener() sch.SchErrno {
 	var err error
-	lsnAddr := fmt.Sprintf("%s:%d", lsnMgr.cfg.IP.String(), lsnMgr.cfg.Port)
-	if lsnMgr.listener, err = net.Listen("tcp", lsnAddr); err != nil {
+	lsnAddr := net.JoinHostPort(lsnMgr.cfg.IP.String(), fmt.Sprint(lsnMgr.cfg.Port))
+	if lsnMgr.listener, err = peTransport(lsnMgr.cfg.Transport).Listen(lsnAddr); err != nil {
 		lsnLog.Debug("lsnMgrSetupListener: listen failed, addr: %s, err: %s", lsnAddr, err.Error())
 		return sch.SchEnoOS
 	}
 	lsnMgr.listenAddr = lsnMgr.listener.Addr().(*net.TCPAddr)
+	if lsnMgr.cfg.AltIP != nil {
+		lsnMgr.lsnMgrSetupAlt()
+	}
+	if lsnMgr.cfg.WsPort != 0 {
+		lsnMgr.lsnMgrSetupWs()
+	}
 	lsnLog.Debug("lsnMgrSetupListener: task inited ok, listening address: %s", lsnMgr.listenAddr.String())
 	return sch.SchEnoNone
 }
 
+// Listen on the address of the other family besides for dual stack, the one
+// configured only if it fails, which is the case when a wildcard address of a
+// dual stack socket is listened on already.
+func (lsnMgr *ListenerManager) lsnMgrSetupAlt() {
+	altAddr := net.JoinHostPort(lsnMgr.cfg.AltIP.String(), fmt.Sprint(lsnMgr.listenAddr.Port))
+	al, err := peTransport(lsnMgr.cfg.Transport).Listen(altAddr)
+	if err != nil {
+		lsnLog.Debug("lsnMgrSetupAlt: listen failed, addr: %s, err: %s", altAddr, err.Error())
+		return
+	}
+	lsnMgr.listener = newMuxListener(lsnMgr.listener, al)
+}
+
+// Listen on the websocket port besides, others only if it fails
+func (lsnMgr *ListenerManager) lsnMgrSetupWs() {
+	wsAddr := net.JoinHostPort(lsnMgr.cfg.IP.String(), fmt.Sprint(lsnMgr.cfg.WsPort))
+	wl, err := NewWsTransport().Listen(wsAddr)
+	if err != nil {
+		lsnLog.Debug("lsnMgrSetupWs: listen failed, addr: %s, err: %s", wsAddr, err.Error())
+		return
+	}
+	lsnMgr.listener = newMuxListener(lsnMgr.listener, wl)
+}
+
 func (lsnMgr *ListenerManager) lsnMgrPoweroff(ptn interface{}) sch.SchErrno {
 	lsnLog.Debug("lsnMgrPoweroff: task will be done, name: %s", lsnMgr.sdl.SchGetTaskName(ptn))
 	lsnMgr.lsnMgrStop()
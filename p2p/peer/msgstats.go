@@ -0,0 +1,208 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"sort"
+	"sync"
+
+	pb "github.com/yeeco/gyee/p2p/peer/pb"
+)
+
+//
+// Statistics of messages on the wire, per direction and Pid/Mid. Payload
+// bytes counted are those of the package payload, so the average size of
+// a message type is PayloadBytes / Count.
+//
+
+const (
+	MsgStatTx = "tx" // sent to peers
+	MsgStatRx = "rx" // received from peers
+)
+
+type MsgStat struct {
+	Dir          string // MsgStatTx or MsgStatRx
+	Pid          uint32 // protocol identity
+	Mid          uint32 // message identity
+	Name         string // readable name, "PID_xxx/MID_xxx"
+	Count        int64  // number of messages
+	PayloadBytes int64  // total payload bytes
+	AvgPayload   int64  // average payload bytes
+	DecodeFailed int64  // number of messages failed to be decoded
+	Dropped      int64  // number of messages dropped for rx queue full
+	PeerDropped  int64  // number of messages sent but dropped by peers for rx queue full, see MID_RXDROP
+	Expired      int64  // number of messages dropped for TTL expired before sent
+}
+
+type msgStatKey struct {
+	dir string
+	pid uint32
+	mid uint32
+}
+
+type msgStatCounter struct {
+	count        int64
+	payloadBytes int64
+	decodeFailed int64
+	dropped      int64
+	peerDropped  int64
+	expired      int64
+}
+
+type msgStats struct {
+	lock sync.Mutex                     // rx/tx routines of all instances update it
+	tab  map[msgStatKey]*msgStatCounter // counters
+}
+
+func newMsgStats() *msgStats {
+	return &msgStats{
+		tab: make(map[msgStatKey]*msgStatCounter, 0),
+	}
+}
+
+func (ms *msgStats) counter(dir string, pid uint32, mid uint32) *msgStatCounter {
+	key := msgStatKey{dir: dir, pid: pid, mid: mid}
+	c, ok := ms.tab[key]
+	if !ok {
+		c = new(msgStatCounter)
+		ms.tab[key] = c
+	}
+	return c
+}
+
+func (ms *msgStats) update(dir string, pid uint32, mid uint32, size int) {
+	if ms == nil {
+		return
+	}
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	c := ms.counter(dir, pid, mid)
+	c.count++
+	c.payloadBytes += int64(size)
+}
+
+func (ms *msgStats) decodeFailed(pid uint32, mid uint32) {
+	if ms == nil {
+		return
+	}
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.counter(MsgStatRx, pid, mid).decodeFailed++
+}
+
+func (ms *msgStats) dropped(pid uint32, mid uint32) {
+	if ms == nil {
+		return
+	}
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.counter(MsgStatRx, pid, mid).dropped++
+}
+
+func (ms *msgStats) peerDropped(pid uint32, mid uint32, n int64) {
+	if ms == nil {
+		return
+	}
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.counter(MsgStatTx, pid, mid).peerDropped += n
+}
+
+func (ms *msgStats) expired(pid uint32, mid uint32) {
+	if ms == nil {
+		return
+	}
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	ms.counter(MsgStatTx, pid, mid).expired++
+}
+
+func (ms *msgStats) snapshot() []MsgStat {
+	ms.lock.Lock()
+	defer ms.lock.Unlock()
+	stats := make([]MsgStat, 0, len(ms.tab))
+	for k, c := range ms.tab {
+		st := MsgStat{
+			Dir:          k.dir,
+			Pid:          k.pid,
+			Mid:          k.mid,
+			Name:         msgStatName(k.pid, k.mid),
+			Count:        c.count,
+			PayloadBytes: c.payloadBytes,
+			DecodeFailed: c.decodeFailed,
+			Dropped:      c.dropped,
+			PeerDropped:  c.peerDropped,
+			Expired:      c.expired,
+		}
+		if c.count > 0 {
+			st.AvgPayload = c.payloadBytes / c.count
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Dir != stats[j].Dir {
+			return stats[i].Dir < stats[j].Dir
+		}
+		if stats[i].Pid != stats[j].Pid {
+			return stats[i].Pid < stats[j].Pid
+		}
+		return stats[i].Mid < stats[j].Mid
+	})
+	return stats
+}
+
+func msgStatName(pid uint32, mid uint32) string {
+	return pb.ProtocolId(int32(pid)).String() + "/" + pb.MessageId(int32(mid)).String()
+}
+
+func (pi *PeerInstance) msgStatTx(pid uint32, mid uint32, size int) {
+	if pi.peMgr != nil {
+		pi.peMgr.msgStats.update(MsgStatTx, pid, mid, size)
+	}
+}
+
+func (pi *PeerInstance) msgStatRx(pid uint32, mid uint32, size int) {
+	if pi.peMgr != nil {
+		pi.peMgr.msgStats.update(MsgStatRx, pid, mid, size)
+	}
+}
+
+func (pi *PeerInstance) msgStatDecodeFailed(pid uint32, mid uint32) {
+	if pi.peMgr != nil {
+		pi.peMgr.msgStats.decodeFailed(pid, mid)
+	}
+}
+
+func (pi *PeerInstance) msgStatExpired(pid uint32, mid uint32) {
+	if pi.peMgr != nil {
+		pi.peMgr.msgStats.expired(pid, mid)
+	}
+}
+
+// Get statistics of messages sent and received by all peer instances
+func (peMgr *PeerManager) GetMsgStats() []MsgStat {
+	return peMgr.msgStats.snapshot()
+}
+
+// Report a message failed to be decoded by user of PID_EXT packages
+func (peMgr *PeerManager) MsgDecodeFailed(pid uint32, mid uint32) {
+	peMgr.msgStats.decodeFailed(pid, mid)
+}
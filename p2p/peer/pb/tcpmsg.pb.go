@@ -3,13 +3,13 @@
 
 package tcpmsg_pb
 
-import proto "github.com/golang/protobuf/proto"
-import fmt "fmt"
-import math "math"
-
-import github_com_golang_protobuf_proto "github.com/golang/protobuf/proto"
-
-import io "io"
+import (
+	fmt "fmt"
+	github_com_golang_protobuf_proto "github.com/golang/protobuf/proto"
+	proto "github.com/golang/protobuf/proto"
+	io "io"
+	math "math"
+)
 
 // Reference imports to suppress errors if they are not otherwise used.
 var _ = proto.Marshal
@@ -33,6 +33,7 @@ var ProtocolId_name = map[int32]string{
 	0:   "PID_P2P",
 	255: "PID_EXT",
 }
+
 var ProtocolId_value = map[string]int32{
 	"PID_P2P": 0,
 	"PID_EXT": 255,
@@ -43,9 +44,11 @@ func (x ProtocolId) Enum() *ProtocolId {
 	*p = x
 	return p
 }
+
 func (x ProtocolId) String() string {
 	return proto.EnumName(ProtocolId_name, int32(x))
 }
+
 func (x *ProtocolId) UnmarshalJSON(data []byte) error {
 	value, err := proto.UnmarshalJSONEnum(ProtocolId_value, data, "ProtocolId")
 	if err != nil {
@@ -54,8 +57,9 @@ func (x *ProtocolId) UnmarshalJSON(data []byte) error {
 	*x = ProtocolId(value)
 	return nil
 }
+
 func (ProtocolId) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{0}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{0}
 }
 
 type MessageId int32
@@ -72,6 +76,12 @@ const (
 	MessageId_MID_RPTK        MessageId = 8
 	MessageId_MID_GCD         MessageId = 9
 	MessageId_MID_PCD         MessageId = 10
+	MessageId_MID_FRAME       MessageId = 11
+	MessageId_MID_ECHO        MessageId = 12
+	MessageId_MID_ECHORSP     MessageId = 13
+	MessageId_MID_PROBE       MessageId = 14
+	MessageId_MID_PROBERSP    MessageId = 15
+	MessageId_MID_RXDROP      MessageId = 16
 	MessageId_MID_INVALID     MessageId = -1
 )
 
@@ -87,8 +97,15 @@ var MessageId_name = map[int32]string{
 	8:  "MID_RPTK",
 	9:  "MID_GCD",
 	10: "MID_PCD",
+	11: "MID_FRAME",
+	12: "MID_ECHO",
+	13: "MID_ECHORSP",
+	14: "MID_PROBE",
+	15: "MID_PROBERSP",
+	16: "MID_RXDROP",
 	-1: "MID_INVALID",
 }
+
 var MessageId_value = map[string]int32{
 	"MID_HANDSHAKE":   0,
 	"MID_PING":        1,
@@ -101,6 +118,12 @@ var MessageId_value = map[string]int32{
 	"MID_RPTK":        8,
 	"MID_GCD":         9,
 	"MID_PCD":         10,
+	"MID_FRAME":       11,
+	"MID_ECHO":        12,
+	"MID_ECHORSP":     13,
+	"MID_PROBE":       14,
+	"MID_PROBERSP":    15,
+	"MID_RXDROP":      16,
 	"MID_INVALID":     -1,
 }
 
@@ -109,9 +132,11 @@ func (x MessageId) Enum() *MessageId {
 	*p = x
 	return p
 }
+
 func (x MessageId) String() string {
 	return proto.EnumName(MessageId_name, int32(x))
 }
+
 func (x *MessageId) UnmarshalJSON(data []byte) error {
 	value, err := proto.UnmarshalJSONEnum(MessageId_value, data, "MessageId")
 	if err != nil {
@@ -120,8 +145,9 @@ func (x *MessageId) UnmarshalJSON(data []byte) error {
 	*x = MessageId(value)
 	return nil
 }
+
 func (MessageId) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{1}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1}
 }
 
 type KeyStatus int32
@@ -135,6 +161,7 @@ var KeyStatus_name = map[int32]string{
 	0: "KS_NOTEXIST",
 	1: "KS_EXIST",
 }
+
 var KeyStatus_value = map[string]int32{
 	"KS_NOTEXIST": 0,
 	"KS_EXIST":    1,
@@ -145,9 +172,11 @@ func (x KeyStatus) Enum() *KeyStatus {
 	*p = x
 	return p
 }
+
 func (x KeyStatus) String() string {
 	return proto.EnumName(KeyStatus_name, int32(x))
 }
+
 func (x *KeyStatus) UnmarshalJSON(data []byte) error {
 	value, err := proto.UnmarshalJSONEnum(KeyStatus_value, data, "KeyStatus")
 	if err != nil {
@@ -156,8 +185,9 @@ func (x *KeyStatus) UnmarshalJSON(data []byte) error {
 	*x = KeyStatus(value)
 	return nil
 }
+
 func (KeyStatus) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{2}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2}
 }
 
 type P2PPackage struct {
@@ -166,6 +196,7 @@ type P2PPackage struct {
 	ExtKey               []byte      `protobuf:"bytes,3,opt,name=ExtKey" json:"ExtKey,omitempty"`
 	PayloadLength        *uint32     `protobuf:"varint,4,req,name=PayloadLength" json:"PayloadLength,omitempty"`
 	Payload              []byte      `protobuf:"bytes,5,opt,name=Payload" json:"Payload,omitempty"`
+	Compression          *uint32     `protobuf:"varint,6,opt,name=Compression" json:"Compression,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
 	XXX_unrecognized     []byte      `json:"-"`
 	XXX_sizecache        int32       `json:"-"`
@@ -175,7 +206,7 @@ func (m *P2PPackage) Reset()         { *m = P2PPackage{} }
 func (m *P2PPackage) String() string { return proto.CompactTextString(m) }
 func (*P2PPackage) ProtoMessage()    {}
 func (*P2PPackage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{0}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{0}
 }
 func (m *P2PPackage) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -192,8 +223,8 @@ func (m *P2PPackage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 		return b[:n], nil
 	}
 }
-func (dst *P2PPackage) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_P2PPackage.Merge(dst, src)
+func (m *P2PPackage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PPackage.Merge(m, src)
 }
 func (m *P2PPackage) XXX_Size() int {
 	return m.Size()
@@ -239,6 +270,13 @@ func (m *P2PPackage) GetPayload() []byte {
 	return nil
 }
 
+func (m *P2PPackage) GetCompression() uint32 {
+	if m != nil && m.Compression != nil {
+		return *m.Compression
+	}
+	return 0
+}
+
 type P2PMessage struct {
 	Mid                  *MessageId            `protobuf:"varint,1,req,name=mid,enum=tcpmsg.pb.MessageId" json:"mid,omitempty"`
 	Handshake            *P2PMessage_Handshake `protobuf:"bytes,2,opt,name=handshake" json:"handshake,omitempty"`
@@ -253,7 +291,7 @@ func (m *P2PMessage) Reset()         { *m = P2PMessage{} }
 func (m *P2PMessage) String() string { return proto.CompactTextString(m) }
 func (*P2PMessage) ProtoMessage()    {}
 func (*P2PMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{1}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1}
 }
 func (m *P2PMessage) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -270,8 +308,8 @@ func (m *P2PMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 		return b[:n], nil
 	}
 }
-func (dst *P2PMessage) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_P2PMessage.Merge(dst, src)
+func (m *P2PMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PMessage.Merge(m, src)
 }
 func (m *P2PMessage) XXX_Size() int {
 	return m.Size()
@@ -322,7 +360,7 @@ func (m *P2PMessage_Protocol) Reset()         { *m = P2PMessage_Protocol{} }
 func (m *P2PMessage_Protocol) String() string { return proto.CompactTextString(m) }
 func (*P2PMessage_Protocol) ProtoMessage()    {}
 func (*P2PMessage_Protocol) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{1, 0}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1, 0}
 }
 func (m *P2PMessage_Protocol) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -339,8 +377,8 @@ func (m *P2PMessage_Protocol) XXX_Marshal(b []byte, deterministic bool) ([]byte,
 		return b[:n], nil
 	}
 }
-func (dst *P2PMessage_Protocol) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_P2PMessage_Protocol.Merge(dst, src)
+func (m *P2PMessage_Protocol) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PMessage_Protocol.Merge(m, src)
 }
 func (m *P2PMessage_Protocol) XXX_Size() int {
 	return m.Size()
@@ -378,6 +416,14 @@ type P2PMessage_Handshake struct {
 	SignS                *int32                 `protobuf:"varint,10,req,name=SignS" json:"SignS,omitempty"`
 	S                    []byte                 `protobuf:"bytes,11,req,name=S" json:"S,omitempty"`
 	Extra                []byte                 `protobuf:"bytes,12,opt,name=Extra" json:"Extra,omitempty"`
+	DhtPort              *uint32                `protobuf:"varint,13,opt,name=DhtPort" json:"DhtPort,omitempty"`
+	Encryption           *uint32                `protobuf:"varint,15,opt,name=Encryption" json:"Encryption,omitempty"`
+	Nonce                []byte                 `protobuf:"bytes,16,opt,name=Nonce" json:"Nonce,omitempty"`
+	Compression          *uint32                `protobuf:"varint,17,opt,name=Compression" json:"Compression,omitempty"`
+	AltIP                []byte                 `protobuf:"bytes,18,opt,name=AltIP" json:"AltIP,omitempty"`
+	InstNonce            []byte                 `protobuf:"bytes,19,opt,name=InstNonce" json:"InstNonce,omitempty"`
+	WsPort               *uint32                `protobuf:"varint,20,opt,name=WsPort" json:"WsPort,omitempty"`
+	AuthSig              []byte                 `protobuf:"bytes,21,opt,name=AuthSig" json:"AuthSig,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
 	XXX_unrecognized     []byte                 `json:"-"`
 	XXX_sizecache        int32                  `json:"-"`
@@ -387,7 +433,7 @@ func (m *P2PMessage_Handshake) Reset()         { *m = P2PMessage_Handshake{} }
 func (m *P2PMessage_Handshake) String() string { return proto.CompactTextString(m) }
 func (*P2PMessage_Handshake) ProtoMessage()    {}
 func (*P2PMessage_Handshake) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{1, 1}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1, 1}
 }
 func (m *P2PMessage_Handshake) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -404,8 +450,8 @@ func (m *P2PMessage_Handshake) XXX_Marshal(b []byte, deterministic bool) ([]byte
 		return b[:n], nil
 	}
 }
-func (dst *P2PMessage_Handshake) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_P2PMessage_Handshake.Merge(dst, src)
+func (m *P2PMessage_Handshake) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PMessage_Handshake.Merge(m, src)
 }
 func (m *P2PMessage_Handshake) XXX_Size() int {
 	return m.Size()
@@ -500,6 +546,62 @@ func (m *P2PMessage_Handshake) GetExtra() []byte {
 	return nil
 }
 
+func (m *P2PMessage_Handshake) GetDhtPort() uint32 {
+	if m != nil && m.DhtPort != nil {
+		return *m.DhtPort
+	}
+	return 0
+}
+
+func (m *P2PMessage_Handshake) GetEncryption() uint32 {
+	if m != nil && m.Encryption != nil {
+		return *m.Encryption
+	}
+	return 0
+}
+
+func (m *P2PMessage_Handshake) GetNonce() []byte {
+	if m != nil {
+		return m.Nonce
+	}
+	return nil
+}
+
+func (m *P2PMessage_Handshake) GetCompression() uint32 {
+	if m != nil && m.Compression != nil {
+		return *m.Compression
+	}
+	return 0
+}
+
+func (m *P2PMessage_Handshake) GetAltIP() []byte {
+	if m != nil {
+		return m.AltIP
+	}
+	return nil
+}
+
+func (m *P2PMessage_Handshake) GetInstNonce() []byte {
+	if m != nil {
+		return m.InstNonce
+	}
+	return nil
+}
+
+func (m *P2PMessage_Handshake) GetWsPort() uint32 {
+	if m != nil && m.WsPort != nil {
+		return *m.WsPort
+	}
+	return 0
+}
+
+func (m *P2PMessage_Handshake) GetAuthSig() []byte {
+	if m != nil {
+		return m.AuthSig
+	}
+	return nil
+}
+
 type P2PMessage_Ping struct {
 	Seq                  *uint64  `protobuf:"varint,1,req,name=seq" json:"seq,omitempty"`
 	Extra                []byte   `protobuf:"bytes,2,opt,name=Extra" json:"Extra,omitempty"`
@@ -512,7 +614,7 @@ func (m *P2PMessage_Ping) Reset()         { *m = P2PMessage_Ping{} }
 func (m *P2PMessage_Ping) String() string { return proto.CompactTextString(m) }
 func (*P2PMessage_Ping) ProtoMessage()    {}
 func (*P2PMessage_Ping) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{1, 2}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1, 2}
 }
 func (m *P2PMessage_Ping) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -529,8 +631,8 @@ func (m *P2PMessage_Ping) XXX_Marshal(b []byte, deterministic bool) ([]byte, err
 		return b[:n], nil
 	}
 }
-func (dst *P2PMessage_Ping) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_P2PMessage_Ping.Merge(dst, src)
+func (m *P2PMessage_Ping) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PMessage_Ping.Merge(m, src)
 }
 func (m *P2PMessage_Ping) XXX_Size() int {
 	return m.Size()
@@ -567,7 +669,7 @@ func (m *P2PMessage_Pong) Reset()         { *m = P2PMessage_Pong{} }
 func (m *P2PMessage_Pong) String() string { return proto.CompactTextString(m) }
 func (*P2PMessage_Pong) ProtoMessage()    {}
 func (*P2PMessage_Pong) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{1, 3}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{1, 3}
 }
 func (m *P2PMessage_Pong) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -584,8 +686,8 @@ func (m *P2PMessage_Pong) XXX_Marshal(b []byte, deterministic bool) ([]byte, err
 		return b[:n], nil
 	}
 }
-func (dst *P2PMessage_Pong) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_P2PMessage_Pong.Merge(dst, src)
+func (m *P2PMessage_Pong) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PMessage_Pong.Merge(m, src)
 }
 func (m *P2PMessage_Pong) XXX_Size() int {
 	return m.Size()
@@ -610,9 +712,7 @@ func (m *P2PMessage_Pong) GetExtra() []byte {
 	return nil
 }
 
-//
 // External application message
-//
 type ExtMessage struct {
 	Mid                  *MessageId               `protobuf:"varint,1,req,name=mid,enum=tcpmsg.pb.MessageId" json:"mid,omitempty"`
 	CheckKey             *ExtMessage_CheckKey     `protobuf:"bytes,2,opt,name=checkKey" json:"checkKey,omitempty"`
@@ -628,7 +728,7 @@ func (m *ExtMessage) Reset()         { *m = ExtMessage{} }
 func (m *ExtMessage) String() string { return proto.CompactTextString(m) }
 func (*ExtMessage) ProtoMessage()    {}
 func (*ExtMessage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{2}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2}
 }
 func (m *ExtMessage) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -645,8 +745,8 @@ func (m *ExtMessage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 		return b[:n], nil
 	}
 }
-func (dst *ExtMessage) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ExtMessage.Merge(dst, src)
+func (m *ExtMessage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExtMessage.Merge(m, src)
 }
 func (m *ExtMessage) XXX_Size() int {
 	return m.Size()
@@ -703,7 +803,7 @@ func (m *ExtMessage_CheckKey) Reset()         { *m = ExtMessage_CheckKey{} }
 func (m *ExtMessage_CheckKey) String() string { return proto.CompactTextString(m) }
 func (*ExtMessage_CheckKey) ProtoMessage()    {}
 func (*ExtMessage_CheckKey) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{2, 0}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 0}
 }
 func (m *ExtMessage_CheckKey) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -720,8 +820,8 @@ func (m *ExtMessage_CheckKey) XXX_Marshal(b []byte, deterministic bool) ([]byte,
 		return b[:n], nil
 	}
 }
-func (dst *ExtMessage_CheckKey) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ExtMessage_CheckKey.Merge(dst, src)
+func (m *ExtMessage_CheckKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExtMessage_CheckKey.Merge(m, src)
 }
 func (m *ExtMessage_CheckKey) XXX_Size() int {
 	return m.Size()
@@ -751,7 +851,7 @@ func (m *ExtMessage_ReportKey) Reset()         { *m = ExtMessage_ReportKey{} }
 func (m *ExtMessage_ReportKey) String() string { return proto.CompactTextString(m) }
 func (*ExtMessage_ReportKey) ProtoMessage()    {}
 func (*ExtMessage_ReportKey) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{2, 1}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 1}
 }
 func (m *ExtMessage_ReportKey) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -768,8 +868,8 @@ func (m *ExtMessage_ReportKey) XXX_Marshal(b []byte, deterministic bool) ([]byte
 		return b[:n], nil
 	}
 }
-func (dst *ExtMessage_ReportKey) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ExtMessage_ReportKey.Merge(dst, src)
+func (m *ExtMessage_ReportKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExtMessage_ReportKey.Merge(m, src)
 }
 func (m *ExtMessage_ReportKey) XXX_Size() int {
 	return m.Size()
@@ -807,7 +907,7 @@ func (m *ExtMessage_GetChainData) Reset()         { *m = ExtMessage_GetChainData
 func (m *ExtMessage_GetChainData) String() string { return proto.CompactTextString(m) }
 func (*ExtMessage_GetChainData) ProtoMessage()    {}
 func (*ExtMessage_GetChainData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{2, 2}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 2}
 }
 func (m *ExtMessage_GetChainData) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -824,8 +924,8 @@ func (m *ExtMessage_GetChainData) XXX_Marshal(b []byte, deterministic bool) ([]b
 		return b[:n], nil
 	}
 }
-func (dst *ExtMessage_GetChainData) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ExtMessage_GetChainData.Merge(dst, src)
+func (m *ExtMessage_GetChainData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExtMessage_GetChainData.Merge(m, src)
 }
 func (m *ExtMessage_GetChainData) XXX_Size() int {
 	return m.Size()
@@ -871,7 +971,7 @@ func (m *ExtMessage_PutChainData) Reset()         { *m = ExtMessage_PutChainData
 func (m *ExtMessage_PutChainData) String() string { return proto.CompactTextString(m) }
 func (*ExtMessage_PutChainData) ProtoMessage()    {}
 func (*ExtMessage_PutChainData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_tcpmsg_5dbd0ac46b92ee90, []int{2, 3}
+	return fileDescriptor_8bfe5b2d2751a4c4, []int{2, 3}
 }
 func (m *ExtMessage_PutChainData) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -888,8 +988,8 @@ func (m *ExtMessage_PutChainData) XXX_Marshal(b []byte, deterministic bool) ([]b
 		return b[:n], nil
 	}
 }
-func (dst *ExtMessage_PutChainData) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ExtMessage_PutChainData.Merge(dst, src)
+func (m *ExtMessage_PutChainData) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExtMessage_PutChainData.Merge(m, src)
 }
 func (m *ExtMessage_PutChainData) XXX_Size() int {
 	return m.Size()
@@ -929,6 +1029,9 @@ func (m *ExtMessage_PutChainData) GetData() []byte {
 }
 
 func init() {
+	proto.RegisterEnum("tcpmsg.pb.ProtocolId", ProtocolId_name, ProtocolId_value)
+	proto.RegisterEnum("tcpmsg.pb.MessageId", MessageId_name, MessageId_value)
+	proto.RegisterEnum("tcpmsg.pb.KeyStatus", KeyStatus_name, KeyStatus_value)
 	proto.RegisterType((*P2PPackage)(nil), "tcpmsg.pb.P2PPackage")
 	proto.RegisterType((*P2PMessage)(nil), "tcpmsg.pb.P2PMessage")
 	proto.RegisterType((*P2PMessage_Protocol)(nil), "tcpmsg.pb.P2PMessage.Protocol")
@@ -940,10 +1043,74 @@ func init() {
 	proto.RegisterType((*ExtMessage_ReportKey)(nil), "tcpmsg.pb.ExtMessage.ReportKey")
 	proto.RegisterType((*ExtMessage_GetChainData)(nil), "tcpmsg.pb.ExtMessage.GetChainData")
 	proto.RegisterType((*ExtMessage_PutChainData)(nil), "tcpmsg.pb.ExtMessage.PutChainData")
-	proto.RegisterEnum("tcpmsg.pb.ProtocolId", ProtocolId_name, ProtocolId_value)
-	proto.RegisterEnum("tcpmsg.pb.MessageId", MessageId_name, MessageId_value)
-	proto.RegisterEnum("tcpmsg.pb.KeyStatus", KeyStatus_name, KeyStatus_value)
 }
+
+func init() { proto.RegisterFile("tcpmsg.proto", fileDescriptor_8bfe5b2d2751a4c4) }
+
+var fileDescriptor_8bfe5b2d2751a4c4 = []byte{
+	// 955 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x94, 0xcf, 0x6e, 0xdb, 0x46,
+	0x10, 0xc6, 0x43, 0x4a, 0xb2, 0xc5, 0x11, 0x25, 0x6f, 0x36, 0x4e, 0xb1, 0x10, 0x0a, 0x55, 0x30,
+	0x8a, 0x46, 0x30, 0x0a, 0x1d, 0x74, 0x2c, 0xda, 0x83, 0x2c, 0xd2, 0x16, 0x41, 0x9b, 0x5a, 0x2c,
+	0x55, 0xd7, 0xe8, 0xc5, 0x60, 0x24, 0x82, 0x12, 0x6c, 0x93, 0x8a, 0x48, 0x01, 0xf1, 0xad, 0x40,
+	0x5f, 0xa2, 0x8f, 0xd0, 0x47, 0xe9, 0xb1, 0xa7, 0xde, 0x0a, 0x14, 0xee, 0x83, 0xa4, 0x98, 0xe5,
+	0x5f, 0xb7, 0x4e, 0x90, 0x46, 0xa7, 0xfd, 0x46, 0xbf, 0xf9, 0x38, 0xbb, 0x33, 0xbb, 0xa0, 0x27,
+	0x8b, 0xcd, 0x5d, 0x1c, 0x0c, 0x37, 0xdb, 0x28, 0x89, 0xa8, 0x96, 0xab, 0xd7, 0x47, 0x7f, 0x2a,
+	0x00, 0x7c, 0xc4, 0xb9, 0xb7, 0xb8, 0xf1, 0x02, 0x9f, 0xbe, 0x82, 0x1a, 0x5f, 0x2f, 0x99, 0xd2,
+	0x57, 0x07, 0x9d, 0xd1, 0xcb, 0x61, 0xc1, 0x0d, 0x39, 0x26, 0x2e, 0xa2, 0x5b, 0x6b, 0x29, 0x90,
+	0xa0, 0x5f, 0xc3, 0x9e, 0xf9, 0x36, 0xb9, 0x58, 0x2f, 0x99, 0xda, 0x57, 0x06, 0x9d, 0xd1, 0x61,
+	0x85, 0xbd, 0xf0, 0xe3, 0xd8, 0x0b, 0x7c, 0x6b, 0x29, 0x32, 0x86, 0x7e, 0x26, 0x69, 0xdb, 0xbf,
+	0x67, 0xb5, 0xbe, 0x32, 0xd0, 0x45, 0xa6, 0xe8, 0x97, 0xd0, 0xe6, 0xde, 0xfd, 0x6d, 0xe4, 0x2d,
+	0xcf, 0xfd, 0x30, 0x48, 0x56, 0xac, 0xde, 0x57, 0x07, 0x6d, 0xf1, 0x38, 0x48, 0x19, 0xec, 0x67,
+	0x01, 0xd6, 0x90, 0xe9, 0xb9, 0xa4, 0x7d, 0x68, 0x4d, 0xa2, 0xbb, 0xcd, 0xd6, 0x8f, 0xe3, 0x75,
+	0x14, 0xb2, 0xbd, 0xbe, 0x32, 0x68, 0x8b, 0x6a, 0xe8, 0xe8, 0xa7, 0x7d, 0xb9, 0xbf, 0xac, 0x24,
+	0xfa, 0x15, 0xd4, 0xee, 0x8a, 0xfd, 0x3d, 0x5d, 0x33, 0x02, 0xf4, 0x3b, 0xd0, 0x56, 0x5e, 0xb8,
+	0x8c, 0x57, 0xde, 0x8d, 0x2f, 0x77, 0xd8, 0x1a, 0x7d, 0x51, 0x3d, 0x8d, 0xc2, 0x71, 0x38, 0xcd,
+	0x31, 0x51, 0x66, 0xd0, 0x21, 0xd4, 0x37, 0xeb, 0x30, 0x90, 0xbb, 0x6d, 0x8d, 0xba, 0x4f, 0x67,
+	0xf2, 0x75, 0x18, 0x08, 0xc9, 0x49, 0x3e, 0x0a, 0x03, 0x56, 0xff, 0x20, 0x1f, 0x49, 0x3e, 0x0a,
+	0x83, 0xae, 0x09, 0xcd, 0xbc, 0x21, 0x1f, 0xdf, 0x32, 0x02, 0xb5, 0x4b, 0x7f, 0xcb, 0xd4, 0xbe,
+	0x3a, 0xd0, 0x05, 0x2e, 0xbb, 0x3f, 0xd7, 0x41, 0x2b, 0xea, 0xa7, 0x5d, 0x68, 0xba, 0xbb, 0xd7,
+	0x8e, 0x9f, 0x58, 0xa9, 0x9b, 0x2e, 0x0a, 0x8d, 0x0d, 0x74, 0xa2, 0xa5, 0x6f, 0x2d, 0xb3, 0xf4,
+	0x4c, 0xd1, 0x0e, 0xa8, 0x16, 0x67, 0x35, 0x19, 0x53, 0x2d, 0x8e, 0xdf, 0xf8, 0xde, 0xe0, 0x59,
+	0x1b, 0x71, 0x89, 0x91, 0xf9, 0x84, 0xb3, 0x46, 0x1a, 0x99, 0x4f, 0x38, 0x7e, 0x47, 0x96, 0xe6,
+	0xec, 0xee, 0xd8, 0x9e, 0x0c, 0x17, 0x9a, 0x7e, 0x0b, 0x5a, 0x5e, 0x76, 0xcc, 0xf6, 0xfb, 0xb5,
+	0x41, 0x6b, 0xd4, 0x7b, 0xcf, 0x69, 0x64, 0x98, 0x28, 0x13, 0xe8, 0x21, 0x34, 0xdc, 0x75, 0x10,
+	0x0a, 0xd6, 0xec, 0xab, 0x83, 0x86, 0x48, 0x05, 0xd5, 0x41, 0x11, 0x4c, 0x93, 0x25, 0x2a, 0x22,
+	0x67, 0x5c, 0x06, 0x25, 0xe3, 0x22, 0xe3, 0xb2, 0x56, 0xca, 0xb8, 0xc8, 0x98, 0x6f, 0x93, 0xad,
+	0xc7, 0x74, 0x39, 0x6e, 0xa9, 0xc0, 0x31, 0x34, 0x56, 0x09, 0x8f, 0xb6, 0x09, 0x6b, 0xcb, 0x41,
+	0xcb, 0x25, 0xed, 0x01, 0x98, 0xe1, 0x62, 0x7b, 0xbf, 0x49, 0x70, 0x0a, 0x0f, 0xe4, 0x9f, 0x95,
+	0x08, 0xfa, 0x39, 0x51, 0xb8, 0xf0, 0x19, 0x49, 0xfd, 0xa4, 0xf8, 0xf7, 0xf0, 0x3e, 0xff, 0xcf,
+	0xf0, 0x62, 0xde, 0xf8, 0x36, 0xb1, 0x38, 0xa3, 0x69, 0x9e, 0x14, 0xf4, 0x73, 0xd0, 0xac, 0x30,
+	0x4e, 0x52, 0xc7, 0x17, 0xf2, 0x9f, 0x32, 0x80, 0x9d, 0xfa, 0x21, 0x96, 0x45, 0x1e, 0x4a, 0xc3,
+	0x4c, 0x61, 0xf5, 0xe3, 0x5d, 0xb2, 0x72, 0xd7, 0x01, 0x7b, 0x99, 0x5e, 0xa2, 0x4c, 0x76, 0x87,
+	0x50, 0xc7, 0x51, 0xc4, 0x4e, 0xc5, 0xfe, 0x1b, 0xd9, 0xfa, 0xba, 0xc0, 0x65, 0x79, 0x0e, 0x6a,
+	0xe5, 0x1c, 0x24, 0x1f, 0x7d, 0x3c, 0x7f, 0xf4, 0x47, 0x1d, 0x00, 0xdf, 0x81, 0xff, 0x79, 0x05,
+	0xbf, 0x81, 0xe6, 0x62, 0xe5, 0x2f, 0x6e, 0xf0, 0xd5, 0x48, 0x6f, 0x60, 0x75, 0x12, 0x4a, 0xc3,
+	0xe1, 0x24, 0xa3, 0x44, 0xc1, 0xe3, 0xf5, 0xdd, 0xfa, 0x9b, 0x68, 0x5b, 0x3c, 0x39, 0x8f, 0xaf,
+	0x6f, 0x25, 0x59, 0xe4, 0x98, 0x28, 0x33, 0xe8, 0x29, 0xe8, 0x81, 0x9f, 0x4c, 0x56, 0xde, 0x3a,
+	0x34, 0xbc, 0xc4, 0xcb, 0xae, 0xe5, 0xd1, 0xd3, 0x0e, 0x67, 0x15, 0x52, 0x3c, 0xca, 0x43, 0x9f,
+	0xcd, 0xae, 0xe2, 0xd3, 0xf8, 0x90, 0x0f, 0xdf, 0x55, 0x7d, 0xaa, 0x79, 0xdd, 0x3e, 0x34, 0xf3,
+	0x4d, 0x96, 0x67, 0xac, 0x54, 0x7b, 0x32, 0x03, 0xad, 0xd8, 0x09, 0xbe, 0xcd, 0x6e, 0xe2, 0x25,
+	0xbb, 0xf8, 0x89, 0x43, 0xb6, 0xfd, 0xfb, 0xf4, 0x3f, 0x91, 0x31, 0xef, 0x69, 0xf2, 0x29, 0xe8,
+	0xd5, 0x8d, 0x61, 0xb3, 0xdd, 0xb2, 0xd9, 0xae, 0xff, 0x86, 0x52, 0xa8, 0xdb, 0xeb, 0x30, 0x7f,
+	0x10, 0xe4, 0x1a, 0xa9, 0xf4, 0xc4, 0x31, 0x84, 0xcb, 0xee, 0x8f, 0xa0, 0x57, 0x37, 0xf6, 0xa9,
+	0x3e, 0x48, 0x2d, 0xd3, 0x56, 0x48, 0x0a, 0xd7, 0xc7, 0xaf, 0x00, 0xca, 0x37, 0x8e, 0xb6, 0x60,
+	0x9f, 0x5b, 0xc6, 0x35, 0x1f, 0x71, 0xf2, 0x8c, 0xea, 0xa9, 0x30, 0xaf, 0xe6, 0xe4, 0x9d, 0x72,
+	0xfc, 0xab, 0x0a, 0x5a, 0x31, 0x5d, 0xf4, 0x39, 0xb4, 0x2f, 0x2c, 0xe3, 0x7a, 0x3a, 0x76, 0x0c,
+	0x77, 0x3a, 0xb6, 0x4d, 0x89, 0x37, 0x31, 0xc4, 0x2d, 0xe7, 0x8c, 0x28, 0x85, 0x9a, 0x39, 0x67,
+	0x44, 0xa5, 0x00, 0x7b, 0xa8, 0xe6, 0x57, 0xa4, 0x46, 0xdb, 0xa0, 0xe1, 0xda, 0xbc, 0x34, 0x9d,
+	0x39, 0xa9, 0xd3, 0x17, 0x70, 0x80, 0xf2, 0xe4, 0x7c, 0x36, 0xb1, 0xa7, 0xe6, 0xd8, 0x30, 0x05,
+	0x69, 0xe4, 0x8c, 0x0c, 0x92, 0xbd, 0xdc, 0x6c, 0x32, 0xb5, 0x6d, 0xb2, 0x9f, 0x2b, 0xc1, 0xe7,
+	0x36, 0x69, 0x62, 0xc9, 0xa8, 0xce, 0x26, 0x06, 0xd1, 0x72, 0xc1, 0x27, 0x06, 0x81, 0xdc, 0xe4,
+	0x54, 0x8c, 0x2f, 0x4c, 0xd2, 0xca, 0xd3, 0xcc, 0xc9, 0x74, 0x46, 0x74, 0x7a, 0x00, 0xad, 0x5c,
+	0x09, 0x97, 0x93, 0x76, 0x4e, 0x73, 0x31, 0x3b, 0x31, 0x49, 0x87, 0x12, 0xd0, 0x0b, 0x89, 0xc0,
+	0x01, 0xed, 0x00, 0xc8, 0xcf, 0x5e, 0x19, 0x62, 0xc6, 0x09, 0xa1, 0x2c, 0x75, 0xb0, 0x9c, 0xcb,
+	0xf1, 0xb9, 0x65, 0x90, 0x77, 0xf9, 0x4f, 0x39, 0x3e, 0x06, 0xad, 0x18, 0x11, 0xfc, 0x90, 0xed,
+	0x5e, 0x3b, 0xb3, 0xb9, 0x79, 0x65, 0xb9, 0xf3, 0xf4, 0x9c, 0x6c, 0xf7, 0x3a, 0x55, 0xca, 0x09,
+	0xf9, 0xed, 0xa1, 0xa7, 0xfc, 0xfe, 0xd0, 0x53, 0xfe, 0x7a, 0xe8, 0x29, 0xbf, 0xfc, 0xdd, 0x7b,
+	0xf6, 0xcf, 0x00, 0xc9, 0xda, 0xd7, 0xe0, 0x67, 0x08, 0x00, 0x00,
+}
+
 func (m *P2PPackage) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -990,6 +1157,11 @@ func (m *P2PPackage) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Payload)))
 		i += copy(dAtA[i:], m.Payload)
 	}
+	if m.Compression != nil {
+		dAtA[i] = 0x30
+		i++
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Compression))
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
@@ -1198,6 +1370,62 @@ func (m *P2PMessage_Handshake) MarshalTo(dAtA []byte) (int, error) {
 		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Extra)))
 		i += copy(dAtA[i:], m.Extra)
 	}
+	if m.DhtPort != nil {
+		dAtA[i] = 0x68
+		i++
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.DhtPort))
+	}
+	if m.Encryption != nil {
+		dAtA[i] = 0x78
+		i++
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Encryption))
+	}
+	if m.Nonce != nil {
+		dAtA[i] = 0x82
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.Nonce)))
+		i += copy(dAtA[i:], m.Nonce)
+	}
+	if m.Compression != nil {
+		dAtA[i] = 0x88
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.Compression))
+	}
+	if m.AltIP != nil {
+		dAtA[i] = 0x92
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.AltIP)))
+		i += copy(dAtA[i:], m.AltIP)
+	}
+	if m.InstNonce != nil {
+		dAtA[i] = 0x9a
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.InstNonce)))
+		i += copy(dAtA[i:], m.InstNonce)
+	}
+	if m.WsPort != nil {
+		dAtA[i] = 0xa0
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintTcpmsg(dAtA, i, uint64(*m.WsPort))
+	}
+	if m.AuthSig != nil {
+		dAtA[i] = 0xaa
+		i++
+		dAtA[i] = 0x1
+		i++
+		i = encodeVarintTcpmsg(dAtA, i, uint64(len(m.AuthSig)))
+		i += copy(dAtA[i:], m.AuthSig)
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
@@ -1529,6 +1757,9 @@ func (m *P2PPackage) Size() (n int) {
 		l = len(m.Payload)
 		n += 1 + l + sovTcpmsg(uint64(l))
 	}
+	if m.Compression != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Compression))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1632,6 +1863,34 @@ func (m *P2PMessage_Handshake) Size() (n int) {
 		l = len(m.Extra)
 		n += 1 + l + sovTcpmsg(uint64(l))
 	}
+	if m.DhtPort != nil {
+		n += 1 + sovTcpmsg(uint64(*m.DhtPort))
+	}
+	if m.Encryption != nil {
+		n += 1 + sovTcpmsg(uint64(*m.Encryption))
+	}
+	if m.Nonce != nil {
+		l = len(m.Nonce)
+		n += 2 + l + sovTcpmsg(uint64(l))
+	}
+	if m.Compression != nil {
+		n += 2 + sovTcpmsg(uint64(*m.Compression))
+	}
+	if m.AltIP != nil {
+		l = len(m.AltIP)
+		n += 2 + l + sovTcpmsg(uint64(l))
+	}
+	if m.InstNonce != nil {
+		l = len(m.InstNonce)
+		n += 2 + l + sovTcpmsg(uint64(l))
+	}
+	if m.WsPort != nil {
+		n += 2 + sovTcpmsg(uint64(*m.WsPort))
+	}
+	if m.AuthSig != nil {
+		l = len(m.AuthSig)
+		n += 2 + l + sovTcpmsg(uint64(l))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -1821,7 +2080,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -1849,7 +2108,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (ProtocolId(b) & 0x7F) << shift
+				v |= ProtocolId(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1870,7 +2129,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (MessageId(b) & 0x7F) << shift
+				v |= MessageId(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1890,7 +2149,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1899,6 +2158,9 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -1921,7 +2183,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint32(b) & 0x7F) << shift
+				v |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1942,7 +2204,7 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -1951,6 +2213,9 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -1959,6 +2224,26 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 				m.Payload = []byte{}
 			}
 			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Compression = &v
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTcpmsg(dAtA[iNdEx:])
@@ -1968,6 +2253,9 @@ func (m *P2PPackage) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2003,7 +2291,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -2031,7 +2319,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (MessageId(b) & 0x7F) << shift
+				v |= MessageId(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2052,7 +2340,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2061,6 +2349,9 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2085,7 +2376,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2094,6 +2385,9 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2118,7 +2412,7 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2127,6 +2421,9 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2146,6 +2443,9 @@ func (m *P2PMessage) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2178,7 +2478,7 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -2206,7 +2506,7 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (ProtocolId(b) & 0x7F) << shift
+				v |= ProtocolId(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2227,7 +2527,7 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2236,6 +2536,9 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2254,6 +2557,9 @@ func (m *P2PMessage_Protocol) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2289,7 +2595,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -2317,7 +2623,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2326,6 +2632,9 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2349,7 +2658,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2358,6 +2667,9 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2381,7 +2693,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2390,6 +2702,9 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2413,7 +2728,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint32(b) & 0x7F) << shift
+				v |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2434,7 +2749,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint32(b) & 0x7F) << shift
+				v |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2455,7 +2770,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint32(b) & 0x7F) << shift
+				v |= uint32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2476,7 +2791,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2485,6 +2800,9 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2507,7 +2825,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (int32(b) & 0x7F) << shift
+				v |= int32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2528,7 +2846,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2537,6 +2855,9 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2560,7 +2881,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (int32(b) & 0x7F) << shift
+				v |= int32(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2581,7 +2902,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2590,6 +2911,9 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2613,7 +2937,7 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2622,6 +2946,9 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2630,6 +2957,222 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 				m.Extra = []byte{}
 			}
 			iNdEx = postIndex
+		case 13:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field DhtPort", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.DhtPort = &v
+		case 15:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Encryption", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Encryption = &v
+		case 16:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Nonce", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Nonce = append(m.Nonce[:0], dAtA[iNdEx:postIndex]...)
+			if m.Nonce == nil {
+				m.Nonce = []byte{}
+			}
+			iNdEx = postIndex
+		case 17:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Compression", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.Compression = &v
+		case 18:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AltIP", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.AltIP = append(m.AltIP[:0], dAtA[iNdEx:postIndex]...)
+			if m.AltIP == nil {
+				m.AltIP = []byte{}
+			}
+			iNdEx = postIndex
+		case 19:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field InstNonce", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.InstNonce = append(m.InstNonce[:0], dAtA[iNdEx:postIndex]...)
+			if m.InstNonce == nil {
+				m.InstNonce = []byte{}
+			}
+			iNdEx = postIndex
+		case 20:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field WsPort", wireType)
+			}
+			var v uint32
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.WsPort = &v
+		case 21:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field AuthSig", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTcpmsg
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.AuthSig = append(m.AuthSig[:0], dAtA[iNdEx:postIndex]...)
+			if m.AuthSig == nil {
+				m.AuthSig = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipTcpmsg(dAtA[iNdEx:])
@@ -2639,6 +3182,9 @@ func (m *P2PMessage_Handshake) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2698,7 +3244,7 @@ func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -2726,7 +3272,7 @@ func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				v |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2747,7 +3293,7 @@ func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2756,6 +3302,9 @@ func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2773,6 +3322,9 @@ func (m *P2PMessage_Ping) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2805,7 +3357,7 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -2833,7 +3385,7 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				v |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2854,7 +3406,7 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2863,6 +3415,9 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2880,6 +3435,9 @@ func (m *P2PMessage_Pong) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2912,7 +3470,7 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -2940,7 +3498,7 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (MessageId(b) & 0x7F) << shift
+				v |= MessageId(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2961,7 +3519,7 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -2970,6 +3528,9 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -2994,7 +3555,7 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3003,6 +3564,9 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3027,7 +3591,7 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3036,6 +3600,9 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3060,7 +3627,7 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				msglen |= (int(b) & 0x7F) << shift
+				msglen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3069,6 +3636,9 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3088,6 +3658,9 @@ func (m *ExtMessage) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3119,7 +3692,7 @@ func (m *ExtMessage_CheckKey) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -3147,7 +3720,7 @@ func (m *ExtMessage_CheckKey) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3156,6 +3729,9 @@ func (m *ExtMessage_CheckKey) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3173,6 +3749,9 @@ func (m *ExtMessage_CheckKey) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3202,7 +3781,7 @@ func (m *ExtMessage_ReportKey) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -3230,7 +3809,7 @@ func (m *ExtMessage_ReportKey) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (KeyStatus(b) & 0x7F) << shift
+				v |= KeyStatus(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3251,7 +3830,7 @@ func (m *ExtMessage_ReportKey) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3260,6 +3839,9 @@ func (m *ExtMessage_ReportKey) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3277,6 +3859,9 @@ func (m *ExtMessage_ReportKey) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3309,7 +3894,7 @@ func (m *ExtMessage_GetChainData) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -3337,7 +3922,7 @@ func (m *ExtMessage_GetChainData) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				v |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3358,7 +3943,7 @@ func (m *ExtMessage_GetChainData) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3367,6 +3952,9 @@ func (m *ExtMessage_GetChainData) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3390,7 +3978,7 @@ func (m *ExtMessage_GetChainData) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3399,6 +3987,9 @@ func (m *ExtMessage_GetChainData) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3417,6 +4008,9 @@ func (m *ExtMessage_GetChainData) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3455,7 +4049,7 @@ func (m *ExtMessage_PutChainData) Unmarshal(dAtA []byte) error {
 			}
 			b := dAtA[iNdEx]
 			iNdEx++
-			wire |= (uint64(b) & 0x7F) << shift
+			wire |= uint64(b&0x7F) << shift
 			if b < 0x80 {
 				break
 			}
@@ -3483,7 +4077,7 @@ func (m *ExtMessage_PutChainData) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				v |= (uint64(b) & 0x7F) << shift
+				v |= uint64(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3504,7 +4098,7 @@ func (m *ExtMessage_PutChainData) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3513,6 +4107,9 @@ func (m *ExtMessage_PutChainData) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3536,7 +4133,7 @@ func (m *ExtMessage_PutChainData) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3545,6 +4142,9 @@ func (m *ExtMessage_PutChainData) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3568,7 +4168,7 @@ func (m *ExtMessage_PutChainData) Unmarshal(dAtA []byte) error {
 				}
 				b := dAtA[iNdEx]
 				iNdEx++
-				byteLen |= (int(b) & 0x7F) << shift
+				byteLen |= int(b&0x7F) << shift
 				if b < 0x80 {
 					break
 				}
@@ -3577,6 +4177,9 @@ func (m *ExtMessage_PutChainData) Unmarshal(dAtA []byte) error {
 				return ErrInvalidLengthTcpmsg
 			}
 			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if postIndex > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3595,6 +4198,9 @@ func (m *ExtMessage_PutChainData) Unmarshal(dAtA []byte) error {
 			if skippy < 0 {
 				return ErrInvalidLengthTcpmsg
 			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthTcpmsg
+			}
 			if (iNdEx + skippy) > l {
 				return io.ErrUnexpectedEOF
 			}
@@ -3674,10 +4280,13 @@ func skipTcpmsg(dAtA []byte) (n int, err error) {
 					break
 				}
 			}
-			iNdEx += length
 			if length < 0 {
 				return 0, ErrInvalidLengthTcpmsg
 			}
+			iNdEx += length
+			if iNdEx < 0 {
+				return 0, ErrInvalidLengthTcpmsg
+			}
 			return iNdEx, nil
 		case 3:
 			for {
@@ -3706,6 +4315,9 @@ func skipTcpmsg(dAtA []byte) (n int, err error) {
 					return 0, err
 				}
 				iNdEx = start + next
+				if iNdEx < 0 {
+					return 0, ErrInvalidLengthTcpmsg
+				}
 			}
 			return iNdEx, nil
 		case 4:
@@ -3724,60 +4336,3 @@ var (
 	ErrInvalidLengthTcpmsg = fmt.Errorf("proto: negative length found during unmarshaling")
 	ErrIntOverflowTcpmsg   = fmt.Errorf("proto: integer overflow")
 )
-
-func init() { proto.RegisterFile("tcpmsg.proto", fileDescriptor_tcpmsg_5dbd0ac46b92ee90) }
-
-var fileDescriptor_tcpmsg_5dbd0ac46b92ee90 = []byte{
-	// 802 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x94, 0xdd, 0x8e, 0xda, 0x46,
-	0x14, 0xc7, 0x63, 0x63, 0x76, 0xf1, 0xc1, 0xbb, 0x71, 0xa7, 0x69, 0x35, 0xe2, 0x82, 0x5a, 0xa8,
-	0x6a, 0x10, 0xaa, 0xb8, 0xe0, 0xb2, 0x6a, 0x2f, 0x88, 0xed, 0x80, 0xe5, 0x5d, 0x33, 0x1a, 0xbb,
-	0xab, 0x55, 0x6f, 0x90, 0x83, 0x47, 0x06, 0x6d, 0xd6, 0x26, 0x60, 0xa4, 0xf0, 0x26, 0x7d, 0x84,
-	0x4a, 0x7d, 0x84, 0xbe, 0x40, 0x7b, 0xd7, 0xab, 0x5e, 0x57, 0xdb, 0x07, 0x49, 0x35, 0xe3, 0xcf,
-	0xad, 0x48, 0x94, 0x86, 0xab, 0xf9, 0x1f, 0x7e, 0xe7, 0xcc, 0xf9, 0x1a, 0x83, 0x96, 0xad, 0xb6,
-	0xf7, 0xfb, 0x78, 0xbc, 0xdd, 0xa5, 0x59, 0x8a, 0xd4, 0x52, 0xbd, 0x1a, 0xfc, 0x26, 0x01, 0x90,
-	0x09, 0x21, 0xe1, 0xea, 0x2e, 0x8c, 0x19, 0x7a, 0x0e, 0x2d, 0xb2, 0x89, 0xb0, 0x64, 0xc8, 0xc3,
-	0xcb, 0xc9, 0x17, 0xe3, 0x8a, 0x1b, 0x13, 0xee, 0xb8, 0x4a, 0x5f, 0x3b, 0x11, 0xe5, 0x04, 0xfa,
-	0x16, 0xce, 0xec, 0xb7, 0xd9, 0xf5, 0x26, 0xc2, 0xb2, 0x21, 0x0d, 0x2f, 0x27, 0xcf, 0x1a, 0xec,
-	0x35, 0xdb, 0xef, 0xc3, 0x98, 0x39, 0x11, 0x2d, 0x18, 0xf4, 0xa5, 0xa0, 0x5d, 0x76, 0xc4, 0x2d,
-	0x43, 0x1a, 0x6a, 0xb4, 0x50, 0xe8, 0x6b, 0xb8, 0x20, 0xe1, 0xf1, 0x75, 0x1a, 0x46, 0x57, 0x2c,
-	0x89, 0xb3, 0x35, 0x56, 0x0c, 0x79, 0x78, 0x41, 0x1f, 0x1b, 0x11, 0x86, 0xf3, 0xc2, 0x80, 0xdb,
-	0xc2, 0xbd, 0x94, 0x83, 0x5f, 0xda, 0x22, 0xfb, 0xe2, 0x42, 0xf4, 0x0d, 0xb4, 0xee, 0xab, 0xec,
-	0x4f, 0x67, 0xc4, 0x01, 0xf4, 0x03, 0xa8, 0xeb, 0x30, 0x89, 0xf6, 0xeb, 0xf0, 0x8e, 0x89, 0xfc,
-	0xbb, 0x93, 0xaf, 0x9a, 0xb5, 0x56, 0x11, 0xc7, 0xf3, 0x12, 0xa3, 0xb5, 0x07, 0x1a, 0x83, 0xb2,
-	0xdd, 0x24, 0xb1, 0xa8, 0xa5, 0x3b, 0xe9, 0x9d, 0xf6, 0x24, 0x9b, 0x24, 0xa6, 0x82, 0x13, 0x7c,
-	0x9a, 0xc4, 0x58, 0xf9, 0x20, 0x9f, 0x0a, 0x3e, 0x4d, 0xe2, 0x9e, 0x0d, 0x9d, 0xb2, 0xdd, 0x1f,
-	0x3f, 0x10, 0x1d, 0x5a, 0x37, 0x6c, 0x87, 0x65, 0x43, 0x1e, 0x6a, 0x94, 0x1f, 0x7b, 0xbf, 0xca,
-	0xa0, 0x56, 0xf9, 0xa3, 0x1e, 0x74, 0xfc, 0xc3, 0x2b, 0x8f, 0x65, 0x4e, 0x1e, 0x4d, 0xa3, 0x95,
-	0xe6, 0xe3, 0xf1, 0xd2, 0x88, 0x39, 0x51, 0xe1, 0x5e, 0x28, 0x74, 0x09, 0xb2, 0x43, 0x70, 0x4b,
-	0xd8, 0x64, 0x87, 0xf0, 0x3b, 0x7e, 0xb4, 0x48, 0x31, 0x24, 0x7e, 0xe4, 0x96, 0xc0, 0x24, 0xb8,
-	0x9d, 0x5b, 0x02, 0x93, 0xf0, 0x7b, 0x44, 0x6a, 0xde, 0xe1, 0x1e, 0x9f, 0x09, 0x73, 0xa5, 0xd1,
-	0xf7, 0xa0, 0x96, 0x69, 0xef, 0xf1, 0xb9, 0xd1, 0x1a, 0x76, 0x27, 0xfd, 0xf7, 0x74, 0xa3, 0xc0,
-	0x68, 0xed, 0x80, 0x9e, 0x41, 0xdb, 0xdf, 0xc4, 0x09, 0xc5, 0x1d, 0x43, 0x1e, 0xb6, 0x69, 0x2e,
-	0x90, 0x06, 0x12, 0xc5, 0xaa, 0x48, 0x51, 0xa2, 0x25, 0xe3, 0x63, 0xa8, 0x19, 0x9f, 0x33, 0x3e,
-	0xee, 0xe6, 0x8c, 0xcf, 0x19, 0xfb, 0x6d, 0xb6, 0x0b, 0xb1, 0x26, 0x96, 0x29, 0x17, 0xbd, 0x31,
-	0x28, 0x7c, 0x64, 0xbc, 0xa2, 0x3d, 0x7b, 0x23, 0x5a, 0xa4, 0x50, 0x7e, 0xac, 0x79, 0xf9, 0xbf,
-	0x7c, 0xfa, 0xf1, 0xfc, 0xe0, 0x2f, 0x05, 0x80, 0xbf, 0x86, 0xff, 0xb9, 0xaa, 0xdf, 0x41, 0x67,
-	0xb5, 0x66, 0xab, 0x3b, 0xfe, 0x76, 0xf2, 0x4d, 0x6d, 0x76, 0xac, 0x0e, 0x38, 0x36, 0x0b, 0x8a,
-	0x56, 0x3c, 0x5f, 0xf3, 0x1d, 0xdb, 0xa6, 0xbb, 0xea, 0xe1, 0x3d, 0x5e, 0xf3, 0x86, 0x33, 0x2d,
-	0x31, 0x5a, 0x7b, 0xa0, 0x97, 0xa0, 0xc5, 0x2c, 0x33, 0xd7, 0xe1, 0x26, 0xb1, 0xc2, 0x2c, 0x2c,
-	0xd6, 0x77, 0x70, 0x3a, 0xc2, 0xac, 0x41, 0xd2, 0x47, 0x7e, 0x3c, 0xce, 0xf6, 0xd0, 0x88, 0xd3,
-	0xfe, 0x50, 0x1c, 0x72, 0x68, 0xc6, 0x69, 0xfa, 0xf5, 0x0c, 0xe8, 0x94, 0x45, 0xd6, 0x3d, 0x96,
-	0x9a, 0x33, 0x59, 0x80, 0x5a, 0x55, 0xc2, 0xbf, 0x50, 0x7e, 0x16, 0x66, 0x87, 0xfd, 0x89, 0x26,
-	0xbb, 0xec, 0x98, 0xff, 0x47, 0x0b, 0xe6, 0x3d, 0x43, 0x7e, 0x09, 0x5a, 0xb3, 0x30, 0x3e, 0x6c,
-	0xbf, 0x1e, 0xb6, 0xcf, 0xde, 0x20, 0x04, 0x8a, 0xbb, 0x49, 0xca, 0x87, 0x23, 0xce, 0x9c, 0xca,
-	0x3b, 0x2e, 0x9e, 0xa2, 0xcb, 0x8e, 0xbd, 0x9f, 0x40, 0x6b, 0x16, 0xf6, 0xa9, 0x71, 0x38, 0x15,
-	0xe5, 0xa3, 0x10, 0x14, 0x3f, 0x8f, 0x9e, 0x03, 0xd4, 0xdf, 0x02, 0xd4, 0x85, 0x73, 0xe2, 0x58,
-	0x4b, 0x32, 0x21, 0xfa, 0x13, 0xa4, 0xe5, 0xc2, 0xbe, 0x0d, 0xf4, 0x77, 0xd2, 0xe8, 0x0f, 0x09,
-	0xd4, 0x6a, 0xbb, 0xd0, 0x67, 0x70, 0x71, 0xed, 0x58, 0xcb, 0xf9, 0xd4, 0xb3, 0xfc, 0xf9, 0xd4,
-	0xb5, 0x05, 0xde, 0xe1, 0x26, 0xe2, 0x78, 0x33, 0x5d, 0xaa, 0xd4, 0xc2, 0x9b, 0xe9, 0x32, 0x02,
-	0x38, 0xe3, 0x2a, 0xb8, 0xd5, 0x5b, 0xe8, 0x02, 0x54, 0x7e, 0xb6, 0x6f, 0x6c, 0x2f, 0xd0, 0x15,
-	0xf4, 0x39, 0x3c, 0xe5, 0xf2, 0xc5, 0xd5, 0xc2, 0x74, 0xe7, 0xf6, 0xd4, 0xb2, 0xa9, 0xde, 0x2e,
-	0x19, 0x61, 0xd4, 0xcf, 0xca, 0x60, 0xe6, 0xdc, 0x75, 0xf5, 0xf3, 0x52, 0x51, 0x12, 0xb8, 0x7a,
-	0x87, 0xa7, 0xcc, 0xd5, 0xcc, 0xb4, 0x74, 0xb5, 0x14, 0xc4, 0xb4, 0x74, 0x40, 0x18, 0xba, 0x5c,
-	0x38, 0xde, 0xcd, 0xf4, 0xca, 0xb1, 0xf4, 0x77, 0xe5, 0x4f, 0x1a, 0x8d, 0x40, 0xad, 0x66, 0x88,
-	0x9e, 0x42, 0xd7, 0xf5, 0x97, 0xde, 0x22, 0xb0, 0x6f, 0x1d, 0x3f, 0xc8, 0x0b, 0x71, 0xfd, 0x65,
-	0xae, 0xa4, 0x17, 0xfa, 0xef, 0x0f, 0x7d, 0xe9, 0xcf, 0x87, 0xbe, 0xf4, 0xf7, 0x43, 0x5f, 0xfa,
-	0xf9, 0x9f, 0xfe, 0x93, 0x7f, 0x03, 0x00, 0x00, 0xff, 0xff, 0x6e, 0x3c, 0x06, 0xd3, 0x0e, 0x07,
-	0x00, 0x00,
-}
@@ -24,16 +24,17 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"fmt"
-	"math/rand"
 	"net"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	ggio "github.com/gogo/protobuf/io"
 	config "github.com/yeeco/gyee/p2p/config"
 	tab "github.com/yeeco/gyee/p2p/discover/table"
 	um "github.com/yeeco/gyee/p2p/discover/udpmsg"
+	identity "github.com/yeeco/gyee/p2p/identity"
 	p2plog "github.com/yeeco/gyee/p2p/logger"
 	nat "github.com/yeeco/gyee/p2p/nat"
 	sch "github.com/yeeco/gyee/p2p/scheduler"
@@ -64,6 +65,18 @@ func (log peerLogger) ForceDebug(fmt string, args ...interface{}) {
 	}
 }
 
+//
+// Check if logs of a message should be emitted, see p2plog.SetSampling for the
+// tags "peerMgrProc" and "peerInstProc" applied.
+//
+func (log peerLogger) Sampled(tag string) bool {
+	return log.debug__ && p2plog.Sampled(tag)
+}
+
+func (log peerLogger) ForceSampled(tag string) bool {
+	return log.debugForce__ && p2plog.Sampled(tag)
+}
+
 // Peer manager errno
 const (
 	PeMgrEnoNone = iota
@@ -82,6 +95,7 @@ const (
 	PeMgrEnoRecofig
 	PeMgrEnoSign
 	PeMgrEnoVerify
+	PeMgrEnoSelf
 	PeMgrEnoUnknown
 )
 
@@ -164,11 +178,39 @@ const (
 type peMgrConfig struct {
 	cfgName            string                            // p2p configuration name
 	ip                 net.IP                            // ip address
+	altIp              net.IP                            // ip address of the other family, nil if single stack
 	port               uint16                            // tcp port number
 	udp                uint16                            // udp port number, used with handshake procedure
 	noDial             bool                              // do not dial outbound
 	noAccept           bool                              // do not accept inbound
 	bootstrapNode      bool                              // local is a bootstrap node
+	seedOnly           bool                              // shed activated peers after seedGraceTime
+	seedGraceTime      time.Duration                     // duration an activated peer is kept in seed-only mode
+	adaptiveSlots      bool                              // shift inbound/outbound slots by what observed
+	slotOutMin         int                               // min outbound slots, in percent
+	slotOutMax         int                               // max outbound slots, in percent
+	rxqPolicy          int                               // policy applied when rx queue full
+	rxBlockTime        time.Duration                     // max time blocked for config.RxqPolicyBlock
+	rxGrowMax          int                               // max packages pending for config.RxqPolicyGrow
+	indqPolicy         int                               // policy applied when indication queue full
+	indBlockTime       time.Duration                     // max time blocked for config.IndqPolicyBlock
+	streamMaxSize      int                               // max bytes of a message streamed
+	streamTimeout      time.Duration                     // max time to receive a message streamed
+	txRate             int                               // max tx bytes per second of a peer, 0 for unlimited
+	txRateTotal        int                               // max tx bytes per second of all peers, 0 for unlimited
+	rxRate             int                               // max rx bytes per second of a peer, 0 for unlimited
+	hsAddrCheck        int                               // check level of ip claimed in inbound handshake
+	ipPrefer           int                               // address family dialed first, see config.IpPrefer*
+	clientVersion      string                            // client version announced in handshake
+	dhtPort            uint32                            // dht tcp port announced in handshake, 0 if dht not run
+	transport          config.PeerTransport              // transport for peer connections
+	wsPort             uint32                            // websocket tcp port announced in handshake, 0 if not run
+	encryption         int                               // encryption of tcp peer connections, see config.PeerEnc*
+	subNetEncryption   map[SubNetworkID]int              // encryption by sub network, overriding encryption
+	compressDisabled   bool                              // user packages not compressed
+	proxy              *socks5Dialer                     // dialer through the socks5 proxy, nil if none
+	subNetProxy        map[SubNetworkID]bool             // proxy enabled by sub network, all enabled if not listed
+	advertised         bool                              // ip and port are the advertised ones, not switched to nat
 	defaultCto         time.Duration                     // default connect outbound timeout
 	defaultHto         time.Duration                     // default handshake timeout
 	defaultAto         time.Duration                     // default active read/write timeout
@@ -188,6 +230,12 @@ type peMgrConfig struct {
 	subNetNodeList     map[SubNetworkID]config.Node      // sub-node identities
 	subNetIdList       []SubNetworkID                    // sub network identity list. do not put the identity
 	ibpNumTotal        int                               // total number of concurrency inbound peers
+	acceptResume       int                               // accepter paused resumed at inbound peers in percent of ibpNumTotal
+	acceptMinPause     time.Duration                     // min duration the accepter paused
+	selfProbeInterval  time.Duration                     // interval of the self probe, disabled if not positive
+	ipMaxInbounds      int                               // max inbound instances of a remote ip, not positive for unlimited
+	ipAcceptRate       int                               // max connections accepted from a remote ip per minute, not positive for unlimited
+	hsAuthRequired     bool                              // handshakes not signed with a nonce refused, whatever version agreed
 }
 
 // start/stop/addr-switching... related
@@ -240,8 +288,11 @@ type PeerManager struct {
 	indChan       chan interface{}                            // indication signal
 	indCb         P2pIndCallback                              // indication callback
 	indCbUserData interface{}                                 // user data pointer for callback
+	indStats      indStats                                    // statistics of indications overflowed
 	staticsStatus map[PeerIdEx]int                            // status about static nodes
 	caTids        map[string]int                              // conflict access timer identity
+	shedTids      map[string]int                              // seed-only shedding timer identity
+	slotTid       int                                         // adaptive slot timer identity
 	ocrTid        int                                         // OCR(outbound connect request) timestamp cleanup timer
 	tmLastOCR     map[SubNetworkID]map[PeerId]time.Time       // time of last outbound connect request for sub-netowerk
 	tmLastFNR     map[SubNetworkID]time.Time                  // time of last find node request sent for sub network
@@ -253,6 +304,25 @@ type PeerManager struct {
 	pubTcpPort    int                                         // public tcp port
 	pasStatus     int                                         // public addr switching status
 	pasBackup     []pasBackupItem                             // backup list for nat public address switching
+	msgStats      *msgStats                                   // statistics of messages on the wire
+	fastPaths     *fastPaths                                  // fast path ring buffers, see RegisterFastPath
+	peerVersions  *peerVersions                               // active peers by client version
+	banList       *banList                                    // peers and networks banned
+	bwMgr         *bwManager                                  // total tx bandwidth of peers, nil for unlimited
+	standby       *standbyPool                                // warm standby connections, see SetStandby
+	idReg         *identity.Registry                          // peers known by both chain and dht stacks
+	echoes        *echoProbes                                 // echo requests waiting responses, see Echo
+	knownPeers    *knownPeers                                 // peers handshaked, to reconnect when restarted
+	acceptPause   *acceptPause                                // accepter paused for inbound peers full
+	killStats     *killStats                                  // instances killed by sub network and cause
+	dialBackoffs  *dialBackoffs                               // backoff of peers failed outbound
+	protoHandlers *protoHandlers                              // handlers of protocols registered, see RegisterProtocol
+	selfProbe     *selfProbe                                  // reachability of the endpoints advertised, see selfprobe.go
+	instNonce     []byte                                      // random nonce of this instance announced in handshake
+	selfAddrs     *selfAddrs                                  // addresses dialed reaching ourselves, see selfdial.go
+	ipQuota       *ipQuota                                    // inbound instances and accepting rate by remote ip
+	tlsCerts      *tlsCerts                                   // certificates for tls connections, see secure.go
+	hsNonces      *hsNonces                                   // nonces of inbound handshakes seen, see hsauth.go
 }
 
 func NewPeerMgr() *PeerManager {
@@ -269,10 +339,29 @@ func NewPeerMgr() *PeerManager {
 		obpNum:        map[SubNetworkID]int{},
 		ibpTotalNum:   0,
 		indChan:       make(chan interface{}, maxIndicationQueueSize),
+		msgStats:      newMsgStats(),
+		fastPaths:     newFastPaths(),
+		peerVersions:  newPeerVersions(),
+		banList:       newBanList(),
+		standby:       newStandbyPool(),
+		echoes:        newEchoProbes(),
+		knownPeers:    newKnownPeers(),
+		tlsCerts:      newTlsCerts(),
+		hsNonces:      newHsNonces(),
+		acceptPause:   newAcceptPause(),
+		killStats:     newKillStats(),
+		dialBackoffs:  newDialBackoffs(),
+		protoHandlers: newProtoHandlers(),
+		selfProbe:     newSelfProbe(),
+		instNonce:     newInstNonce(),
+		selfAddrs:     newSelfAddrs(),
+		ipQuota:       newIpQuota(),
 		randoms:       map[SubNetworkID][]*config.Node{},
 		staticsStatus: map[PeerIdEx]int{},
 		caTids:        make(map[string]int, 0),
+		shedTids:      make(map[string]int, 0),
 		ocrTid:        sch.SchInvalidTid,
+		slotTid:       sch.SchInvalidTid,
 		tmLastOCR:     make(map[SubNetworkID]map[PeerId]time.Time, 0),
 		tmLastFNR:     make(map[SubNetworkID]time.Time, 0),
 		reCfg: PeerReconfig{
@@ -293,7 +382,10 @@ func (peMgr *PeerManager) TaskProc4Scheduler(ptn interface{}, msg *sch.SchMessag
 
 func (peMgr *PeerManager) peerMgrProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 
-	peerLog.Debug("peerMgrProc: name: %s, msg.Id: %d", peMgr.name, msg.Id)
+	sampled := peerLog.Sampled("peerMgrProc")
+	if sampled {
+		peerLog.Debug("peerMgrProc: name: %s, msg.Id: %d", peMgr.name, msg.Id)
+	}
 
 	if peMgr.msgFilter(msg) != PeMgrEnoNone {
 		peerLog.Debug("peerMgrProc: filtered out, id: %d", msg.Id)
@@ -319,12 +411,33 @@ func (peMgr *PeerManager) peerMgrProc(ptn interface{}, msg *sch.SchMessage) sch.
 	case sch.EvPeReconfigTimer:
 		eno = peMgr.reconfigTimerHandler()
 
+	case sch.EvPeSeedShedTimer:
+		eno = peMgr.seedShedTimerHandler(msg.Body)
+
+	case sch.EvPeSlotAdaptTimer:
+		eno = peMgr.slotAdaptTimerHandler()
+
+	case sch.EvPeAcceptResumeTimer:
+		eno = peMgr.acceptResumeTimerHandler()
+
+	case sch.EvPeSelfProbeTimer:
+		eno = peMgr.selfProbeTimerHandler()
+
 	case sch.EvPeOcrCleanupTimer:
 		peMgr.ocrTimestampCleanup()
 
 	case sch.EvPeMgrStartReq:
 		eno = peMgr.peMgrStartReq(msg.Body)
 
+	case sch.EvPeMgrAdminReq:
+		eno = peMgr.peMgrAdminReq(msg.Body.(*sch.MsgPeMgrAdminReq))
+
+	case sch.EvPeMgrStaticAddReq:
+		eno = peMgr.adminAddStatic(&msg.Body.(*sch.MsgPeMgrStaticReq).Node)
+
+	case sch.EvPeMgrStaticRemoveReq:
+		eno = peMgr.adminRemoveStatic(msg.Body.(*sch.MsgPeMgrStaticReq).Node.ID)
+
 	case sch.EvDcvFindNodeRsp:
 		eno = peMgr.peMgrDcvFindNodeRsp(msg.Body)
 
@@ -369,7 +482,9 @@ func (peMgr *PeerManager) peerMgrProc(ptn interface{}, msg *sch.SchMessage) sch.
 		eno = PeMgrEnoParameter
 	}
 
-	peerLog.Debug("peerMgrProc: get out, name: %s, msg.Id: %d", peMgr.name, msg.Id)
+	if sampled {
+		peerLog.Debug("peerMgrProc: get out, name: %s, msg.Id: %d", peMgr.name, msg.Id)
+	}
 
 	if eno != PeMgrEnoNone {
 		schEno = sch.SchEnoUserTask
@@ -388,7 +503,7 @@ func (peMgr *PeerManager) peMgrPoweron(ptn interface{}) PeMgrErrno {
 
 
 	var cfg *config.Cfg4PeerManager
-	if cfg = config.P2pConfig4PeerManager(peMgr.sdl.SchGetP2pCfgName()); cfg == nil {
+	if cfg = peMgr.sdl.SchGetTaskConfig(peMgr.ptnMe).Config4PeerManager(); cfg == nil {
 		peerLog.Debug("peMgrPoweron: inited, inst: %s", peMgr.sdl.SchGetP2pCfgName())
 		peMgr.inited <- PeMgrEnoConfig
 		return PeMgrEnoConfig
@@ -411,11 +526,34 @@ func (peMgr *PeerManager) peMgrPoweron(ptn interface{}) PeMgrErrno {
 	peMgr.cfg = peMgrConfig{
 		cfgName:       cfg.CfgName,
 		ip:            cfg.IP,
+		altIp:         cfg.AltIP,
 		port:          cfg.Port,
 		udp:           cfg.UDP,
 		noDial:        cfg.NoDial,
 		noAccept:      cfg.NoAccept,
 		bootstrapNode: cfg.BootstrapNode,
+		seedOnly:      cfg.SeedOnly,
+		seedGraceTime: cfg.SeedGraceTime,
+		adaptiveSlots: cfg.AdaptiveSlots,
+		slotOutMin:    cfg.SlotOutMin,
+		slotOutMax:    cfg.SlotOutMax,
+		rxqPolicy:     cfg.RxqPolicy,
+		rxBlockTime:   cfg.RxBlockTime,
+		rxGrowMax:     cfg.RxGrowMax,
+		indqPolicy:    cfg.IndqPolicy,
+		indBlockTime:  cfg.IndBlockTime,
+		streamMaxSize: cfg.StreamMaxSize,
+		streamTimeout: cfg.StreamTimeout,
+		txRate:        cfg.TxRate,
+		txRateTotal:   cfg.TxRateTotal,
+		rxRate:        cfg.RxRate,
+		hsAddrCheck:   cfg.HsAddrCheck,
+		ipPrefer:      cfg.IpPrefer,
+		clientVersion: cfg.ClientVersion,
+		dhtPort:       uint32(cfg.DhtPort),
+		wsPort:        uint32(cfg.WsPort),
+		transport:     peTransport(cfg.Transport),
+		advertised:    cfg.Advertised,
 		defaultCto:    defaultConnectTimeout,
 		defaultHto:    defaultHandshakeTimeout,
 		defaultAto:    defaultActivePeerTimeout,
@@ -432,16 +570,62 @@ func (peMgr *PeerManager) peMgrPoweron(ptn interface{}) PeMgrErrno {
 		subNetMaxPeers:     cfg.SubNetMaxPeers,
 		subNetMaxOutbounds: cfg.SubNetMaxOutbounds,
 		subNetMaxInBounds:  cfg.SubNetMaxInBounds,
+		encryption:         cfg.Encryption,
+		subNetEncryption:   cfg.SubNetEncryption,
+		compressDisabled:   cfg.CompressDisabled,
+		subNetProxy:        cfg.SubNetProxy,
 		subNetKeyList:      cfg.SubNetKeyList,
 		subNetNodeList:     cfg.SubNetNodeList,
 		subNetIdList:       cfg.SubNetIdList,
 		ibpNumTotal:        0,
+		acceptResume:       cfg.AcceptResume,
+		acceptMinPause:     cfg.AcceptMinPause,
+		selfProbeInterval:  cfg.SelfProbeInterval,
+		ipMaxInbounds:      cfg.IpMaxInbounds,
+		ipAcceptRate:       cfg.IpAcceptRate,
+		hsAuthRequired:     cfg.HsAuthRequired,
+	}
+
+	if cfg.HsNonceWindow > 0 {
+		peMgr.hsNonces.window = cfg.HsNonceWindow
+	}
+
+	if cfg.Proxy != nil {
+		peMgr.cfg.proxy = newSocks5Dialer(cfg.Proxy, peMgr.cfg.transport)
+	}
+
+	if len(cfg.BanList) > 0 {
+		if err := peMgr.banList.load(cfg.BanList); err != nil {
+			peerLog.Debug("peMgrPoweron: load ban list failed, path: %s, err: %s",
+				cfg.BanList, err.Error())
+		}
+	}
+
+	if len(cfg.KnownPeers) > 0 {
+		if err := peMgr.knownPeers.load(cfg.KnownPeers); err != nil {
+			peerLog.Debug("peMgrPoweron: load known peers failed, path: %s, err: %s",
+				cfg.KnownPeers, err.Error())
+		}
+	}
+
+	// the local address, not the public one switched to for nat, is where the
+	// datagrams of the self probe received on
+	peMgr.selfProbe.ip = cfg.IP
+	peMgr.selfProbe.udp = cfg.UDP
+
+	peMgr.bwMgr = newBwManager(peMgr.cfg.txRateTotal)
+	peMgr.bwMgr.start()
+
+	peMgr.idReg = identity.Of(peMgr.sdl.SchGetP2pCfgName())
+	for id, expired := range peMgr.banList.bannedNodes() {
+		peMgr.idReg.Ban(id, expired)
 	}
 
 	peMgr.cfg.ibpNumTotal = peMgr.cfg.staticMaxInBounds
 	for _, ibpNum := range peMgr.cfg.subNetMaxInBounds {
 		peMgr.cfg.ibpNumTotal += ibpNum
 	}
+	peMgr.acceptSetup()
 
 	for _, p := range cfg.Protocols {
 		peMgr.cfg.protocols = append(peMgr.cfg.protocols, Protocol{Pid: p.Pid, Ver: p.Ver})
@@ -489,6 +673,9 @@ func (peMgr *PeerManager) peMgrPoweron(ptn interface{}) PeMgrErrno {
 		// now, see power on order table in file static.go please.
 		peMgr.peMgrRecfg2DcvMgr()
 
+		// peers known before restarted are connected without waiting the discovery
+		peMgr.knownPeersSeed()
+
 	} else if peMgr.cfg.networkType == config.P2pNetworkTypeStatic {
 		staticSnid := peMgr.cfg.staticSubNetId
 		peMgr.nodes[staticSnid] = make(map[PeerIdEx]*PeerInstance)
@@ -525,6 +712,11 @@ func (peMgr *PeerManager) PeMgrStart() PeMgrErrno {
 func (peMgr *PeerManager) peMgrPoweroff(ptn interface{}) PeMgrErrno {
 	peerLog.Debug("peMgrPoweroff: task will be done, name: %s", sch.PeerMgrName)
 	close(peMgr.indChan)
+	peMgr.bwMgr.close()
+	for _, pi := range peMgr.peers {
+		peMgr.peerStatsEnded(pi, sch.PEC_FOR_COMMAND)
+	}
+	peMgr.knownPeers.saveLogged()
 	for _, pi := range peMgr.peers {
 		peerLog.ForceDebug("peMgrPoweroff: send EvSchPoweroff to inst: %s, dir: %d, state: %d",
 			pi.name, pi.dir, pi.state)
@@ -703,6 +895,20 @@ func (peMgr *PeerManager) peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 	var ibInd, _ = msg.(*msgConnAcceptedInd)
 	var peInst = new(PeerInstance)
 
+	if peMgr.banList.ipBanned(ibInd.remoteAddr.IP) {
+		peerLog.Debug("peMgrLsnConnAcceptedInd: banned, peer: %s", ibInd.remoteAddr.String())
+		ibInd.conn.Close()
+		return PeMgrEnoNone
+	}
+
+	// counted to the quota of the remote ip till killed, see ipquota.go
+	ipQuota := !peMgr.ipQuotaExempted(ibInd.remoteAddr.IP)
+	if ipQuota && !peMgr.ipQuotaAdmit(ibInd.remoteAddr.IP) {
+		peerLog.Debug("peMgrLsnConnAcceptedInd: ip quota exceeded, peer: %s", ibInd.remoteAddr.String())
+		ibInd.conn.Close()
+		return PeMgrEnoNone
+	}
+
 	*peInst = peerInstDefault
 	peInst.sdl = peMgr.sdl
 	peInst.peMgr = peMgr
@@ -717,9 +923,10 @@ func (peMgr *PeerManager) peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 	peInst.conn = ibInd.conn
 	peInst.laddr = ibInd.localAddr
 	peInst.raddr = ibInd.remoteAddr
+	peInst.ipQuota = ipQuota
 	peInst.dir = PeInstDirInbound
 
-	peInst.txChan = make(chan *P2pPackage, PeInstMaxP2packages)
+	peInst.txQueue = newTxQueue(PeInstMaxP2packages)
 	peInst.ppChan = make(chan *P2pPackage, PeInstMaxPings)
 	peInst.rxChan = make(chan *P2pPackageRx, PeInstMaxP2packages)
 	peInst.rxDone = make(chan PeMgrErrno)
@@ -744,6 +951,9 @@ func (peMgr *PeerManager) peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 
 	if eno, ptnInst = peMgr.sdl.SchCreateTask(&tskDesc); eno != sch.SchEnoNone || ptnInst == nil {
 		peerLog.Debug("peMgrLsnConnAcceptedInd: SchCreateTask failed, eno: %d", eno)
+		if ipQuota {
+			peMgr.ipQuota.released(peInst.raddr.IP)
+		}
 		return PeMgrEnoScheduler
 	}
 	peInst.ptnMe = ptnInst
@@ -754,16 +964,9 @@ func (peMgr *PeerManager) peMgrLsnConnAcceptedInd(msg interface{}) PeMgrErrno {
 	peMgr.sdl.SchMakeMessage(&schMsg, peMgr.ptnMe, peInst.ptnMe, sch.EvPeHandshakeReq, nil)
 	peMgr.sdl.SchSendMessage(&schMsg)
 
-	// Pause inbound peer accepter if necessary:
-	// we stop accepter simply, a duration of delay should be apply before pausing,
-	// this should be improved later.
-	if peMgr.ibpTotalNum++; peMgr.ibpTotalNum >= peMgr.cfg.ibpNumTotal {
-		if !peMgr.cfg.noAccept {
-			schMsg = sch.SchMessage{}
-			peMgr.sdl.SchMakeMessage(&schMsg, peMgr.ptnMe, peMgr.ptnLsn, sch.EvPeLsnStopReq, nil)
-			peMgr.sdl.SchSendMessage(&schMsg)
-		}
-	}
+	// Pause inbound peer accepter if necessary, see acceptPauseCheck
+	peMgr.ibpTotalNum++
+	peMgr.acceptPauseCheck()
 
 	return PeMgrEnoNone
 }
@@ -827,10 +1030,14 @@ func (peMgr *PeerManager) peMgrStaticSubNetOutbound() PeMgrErrno {
 		Id:  config.NodeID{},
 		Dir: PeInstDirOutbound,
 	}
+	var now = time.Now()
 	for _, n := range peMgr.cfg.staticNodes {
 		idEx.Id = n.ID
 		_, dup := peMgr.nodes[snid][idEx]
-		if !dup && peMgr.staticsStatus[idEx] == peerIdle {
+		if peMgr.selfAddrs.self(n.IP, int(n.TCP)) {
+			continue
+		}
+		if ready, _ := peMgr.dialBackoffs.ready(idEx, now); !dup && ready && peMgr.staticsStatus[idEx] == peerIdle {
 			candidates = append(candidates, n)
 			count++
 		}
@@ -840,7 +1047,7 @@ func (peMgr *PeerManager) peMgrStaticSubNetOutbound() PeMgrErrno {
 	var ok = 0
 	idEx = PeerIdEx{Id: config.NodeID{}, Dir: PeInstDirOutbound}
 	for cdNum := len(candidates); cdNum > 0; cdNum-- {
-		idx := rand.Intn(cdNum)
+		idx := peMgr.sdl.SchRandIntn(cdNum)
 		n := candidates[idx]
 		idEx.Id = n.ID
 		if idx != len(candidates)-1 {
@@ -883,9 +1090,13 @@ func (peMgr *PeerManager) peMgrDynamicSubNetOutbound(snid *SubNetworkID) PeMgrEr
 
 	var candidates = make([]*config.Node, 0)
 	var idEx PeerIdEx
+	var now = time.Now()
 	for _, n := range peMgr.randoms[*snid] {
 		idEx.Id = n.ID
 		idEx.Dir = PeInstDirOutbound
+		if ready, _ := peMgr.dialBackoffs.ready(idEx, now); !ready || peMgr.selfAddrs.self(n.IP, int(n.TCP)) {
+			continue
+		}
 		if _, ok := peMgr.nodes[*snid][idEx]; !ok {
 			idEx.Dir = PeInstDirInbound
 			if _, ok := peMgr.nodes[*snid][idEx]; !ok {
@@ -947,6 +1158,7 @@ func (peMgr *PeerManager) peMgrConnOutRsp(msg interface{}) PeMgrErrno {
 				dir:   PeInstDirOutbound,
 				name:  pi.name,
 			}
+			peMgr.dialFailed(rsp.snid, pi.node.ID)
 			if eno := peMgr.peMgrKillInst(&kip, PKI_FOR_BOUNDOUT_FAILED); eno != PeMgrEnoNone {
 				peerLog.Debug("peMgrConnOutRsp: peMgrKillInst failed, eno: %d", eno)
 				return eno
@@ -1013,6 +1225,11 @@ func (peMgr *PeerManager) peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 			// outbound instance, we request outbound at once.
 			idEx := PeerIdEx{Id: rsp.peNode.ID, Dir: rsp.dir}
 			peMgr.updateStaticStatus(rsp.snid, idEx, peerKilling)
+			if rsp.result == PeMgrEnoSelf {
+				peMgr.selfDialed(inst, rsp.peNode)
+			} else {
+				peMgr.dialFailed(rsp.snid, rsp.peNode.ID)
+			}
 			schMsg := sch.SchMessage{}
 			peMgr.sdl.SchMakeMessage(&schMsg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeOutboundReq, &inst.snid)
 			peMgr.sdl.SchSendMessage(&schMsg)
@@ -1185,37 +1402,34 @@ func (peMgr *PeerManager) peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 	close(cfmCh)
 	peMgr.workers[snid][idEx] = inst
 	peMgr.wrkNum[snid]++
+	peMgr.peerVersions.update(inst.clientVersion, 1)
 	peMgr.updateStaticStatus(snid, idEx, peerActivated)
+	if inst.dir == PeInstDirOutbound {
+		peMgr.dialBackoffs.forget(idEx)
+	}
+	peMgr.idRegActivated(inst)
+	peMgr.knownPeersActivated(inst)
+	peMgr.peerStatsActivated(inst)
 
-	if inst.dir == PeInstDirInbound &&
-		inst.networkType != config.P2pNetworkTypeStatic {
-		// Notice: even the network type is not static, the "snid" can be a static subnet
-		// in a configuration where "dynamic" and "static" are exist both. So, calling functions
-		// TabBucketAddNode or TabUpdateNode might be failed since these functions would not
-		// work for a static case.
-		lastQuery := time.Time{}
-		lastPing := time.Now()
-		lastPong := time.Now()
-		n := um.Node{
-			IP:     rsp.peNode.IP,
-			UDP:    rsp.peNode.UDP,
-			TCP:    rsp.peNode.TCP,
-			NodeId: rsp.peNode.ID,
-		}
-		tabEno := peMgr.tabMgr.TabBucketAddNode(snid, &n, &lastQuery, &lastPing, &lastPong)
-		if tabEno != tab.TabMgrEnoNone {
-			peerLog.Debug("peMgrHandshakeRsp: TabBucketAddNode failed, " +
-				"inst: %s, snid: %x, dir: %d, state: %d, eno: %d",
-				inst.name, inst.snid, inst.dir, inst.state, tabEno)
-		}
-		tabEno = peMgr.tabMgr.TabUpdateNode(snid, &n)
-		if tabEno != tab.TabMgrEnoNone {
-			peerLog.Debug("peMgrHandshakeRsp: TabUpdateNode failed, " +
-				"inst: %s, snid: %x, dir: %d, state: %d, eno: %d",
-				inst.name, inst.snid, inst.dir, inst.state, tabEno)
-		}
+	if peMgr.cfg.seedOnly {
+		peMgr.peMgrSeedShedProtect(inst)
+	}
+
+	if inst.networkType != config.P2pNetworkTypeStatic {
+		// the handshake is a round trip just completed, both for inbound and
+		// outbound, so update the table as a pingpong.
+		now := time.Now()
+		peMgr.peMgrTabUpdate(snid, inst, &now, &now)
+	}
+
+	// a standby peer is kept idle till the standby pool activated, see standby.go
+	if peMgr.standbyHold(inst) {
+		return PeMgrEnoNone
 	}
+	return peMgr.peMgrActiveInd(inst)
+}
 
+func (peMgr *PeerManager) peMgrActiveInd(inst *PeerInstance) PeMgrErrno {
 	// indicate activation of a peer instance to other modules:
 	// if shell task present, send EvShellPeerActiveInd to it and then return;
 	// else push the indication to queue(for callback method).
@@ -1223,20 +1437,23 @@ func (peMgr *PeerManager) peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 		P2pInst: peMgr.sdl,
 		RxChan:  inst.rxChan,
 		PeerInfo: &Handshake{
-			Snid:      inst.snid,
-			Dir:       inst.dir,
-			NodeId:    inst.node.ID,
-			IP:        net.IP{},
-			UDP:       uint32(inst.node.UDP),
-			TCP:       uint32(inst.node.TCP),
-			ProtoNum:  inst.protoNum,
-			Protocols: inst.protocols,
+			Snid:          inst.snid,
+			Dir:           inst.dir,
+			NodeId:        inst.node.ID,
+			IP:            net.IP{},
+			UDP:           uint32(inst.node.UDP),
+			TCP:           uint32(inst.node.TCP),
+			ProtoNum:      inst.protoNum,
+			Protocols:     inst.protocols,
+			ClientVersion: inst.clientVersion,
+			DhtPort:       inst.dhtPort,
+			Negotiated:    inst.negotiated,
 		},
 	}
 	i.PeerInfo.IP = append(i.PeerInfo.IP, inst.node.IP...)
 	if peMgr.ptnShell != nil {
 		ind2Sh := sch.MsgShellPeerActiveInd{
-			TxChan:   inst.txChan,
+			TxQueue:  inst.txQueue,
 			RxChan:   inst.rxChan,
 			PeerInfo: i.PeerInfo,
 			PeerInst: inst,
@@ -1249,6 +1466,44 @@ func (peMgr *PeerManager) peMgrHandshakeRsp(msg interface{}) PeMgrErrno {
 	return peMgr.peMgrIndEnque(&i)
 }
 
+func (peMgr *PeerManager) peMgrTabUpdate(snid SubNetworkID, inst *PeerInstance, lastPing *time.Time, lastPong *time.Time) {
+	// Notice: even the network type is not static, the "snid" can be a static subnet
+	// in a configuration where "dynamic" and "static" are exist both. So, calling functions
+	// TabBucketAddNode or TabUpdateNode might be failed since these functions would not
+	// work for a static case.
+	if peMgr.tabMgr == nil {
+		return
+	}
+	if inst.node.TCP == 0 {
+		// the peer tells it's not dialable, see selfprobe.go
+		return
+	}
+	n := um.Node{
+		IP:     inst.node.IP,
+		UDP:    inst.node.UDP,
+		TCP:    inst.node.TCP,
+		NodeId: inst.node.ID,
+	}
+	// for a node in bucket already, update the pingpong time only, keep
+	// the time it's queried.
+	tabEno := peMgr.tabMgr.TabUpdateBoundTime(snid, inst.node.ID, lastPing, lastPong)
+	if tabEno != tab.TabMgrEnoNone {
+		lastQuery := time.Time{}
+		tabEno = peMgr.tabMgr.TabBucketAddNode(snid, &n, &lastQuery, lastPing, lastPong)
+		if tabEno != tab.TabMgrEnoNone {
+			peerLog.Debug("peMgrTabUpdate: TabBucketAddNode failed, " +
+				"inst: %s, snid: %x, dir: %d, state: %d, eno: %d",
+				inst.name, inst.snid, inst.dir, inst.state, tabEno)
+		}
+	}
+	tabEno = peMgr.tabMgr.TabUpdateNode(snid, &n)
+	if tabEno != tab.TabMgrEnoNone {
+		peerLog.Debug("peMgrTabUpdate: TabUpdateNode failed, " +
+			"inst: %s, snid: %x, dir: %d, state: %d, eno: %d",
+			inst.name, inst.snid, inst.dir, inst.state, tabEno)
+	}
+}
+
 func (peMgr *PeerManager) peMgrCloseReq(msg *sch.SchMessage) PeMgrErrno {
 	// here it's asked to close a peer instance, this might happen in following cases:
 	// 1) the shell task ask to do this;
@@ -1271,7 +1526,11 @@ func (peMgr *PeerManager) peMgrCloseReq(msg *sch.SchMessage) PeMgrErrno {
 		peerLog.Debug("peMgrCloseReq: already in killing or killed, state: %d", inst.state)
 		return PeMgrEnoDuplicated
 	}
-	if ptnSender := peMgr.sdl.SchGetSender(msg); ptnSender != peMgr.ptnShell {
+	if len(inst.closeWhy) == 0 {
+		// the first cause is kept, the shell asks back with PEC_FOR_BEASKEDTO
+		inst.closeWhy = why
+	}
+	if ptnSender := peMgr.sdl.SchGetSender(msg); ptnSender != peMgr.ptnShell && !peMgr.standbyHeld(inst.ptnMe) {
 		// req.Ptn is nil, means the sender is peMgr.ptnShell, so need not to
 		// send EvShellPeerAskToCloseInd to it again.
 		if req.Ptn != nil {
@@ -1317,6 +1576,12 @@ func (peMgr *PeerManager) peMgrConnCloseCfm(msg interface{}) PeMgrErrno {
 	peerLog.ForceDebug("peMgrConnCloseCfm: inst: %s, snid: %x, dir: %d, state: %d",
 		cfm.name, cfm.snid, cfm.dir, cfm.state)
 
+	held := peMgr.standbyHeld(cfm.ptn)
+	why := ""
+	if inst, ok := peMgr.peers[cfm.ptn]; ok {
+		why = inst.closeWhy
+	}
+	cause := peMgr.killStats.count(cfm.snid, why)
 	if eno := peMgr.peMgrKillInst(&kip, PKI_FOR_CLOSE_CFM); eno != PeMgrEnoNone {
 		peerLog.ForceDebug("peMgrConnCloseCfm: peMgrKillInst failed, inst: %s, snid: %x, dir: %d, state: %d",
 			cfm.name, cfm.snid, cfm.dir, cfm.state)
@@ -1327,13 +1592,17 @@ func (peMgr *PeerManager) peMgrConnCloseCfm(msg interface{}) PeMgrErrno {
 		Snid:    cfm.snid,
 		PeerId:  cfm.peNode.ID,
 		Dir:     cfm.dir,
+		Why:     cause,
 	}
-	if peMgr.ptnShell != nil {
+	if held {
+		// the shell did not know the instance
+	} else if peMgr.ptnShell != nil {
 		ind2Sh := sch.MsgShellPeerCloseCfm{
 			Result: int(cfm.result),
 			Dir:    cfm.dir,
 			Snid:   cfm.snid,
 			PeerId: cfm.peNode.ID,
+			Why:    cause,
 		}
 		schMsg := sch.SchMessage{}
 		peMgr.sdl.SchMakeMessage(&schMsg, peMgr.ptnMe, peMgr.ptnShell, sch.EvShellPeerCloseCfm, &ind2Sh)
@@ -1382,7 +1651,9 @@ func (peMgr *PeerManager) peMgrConnCloseInd(msg interface{}) PeMgrErrno {
 }
 
 func (peMgr *PeerManager) natMgrReadyInd(msg *sch.MsgNatMgrReadyInd) PeMgrErrno {
-	if peMgr.natResult = msg.NatType == nat.NATT_NONE; peMgr.natResult {
+	// no mapping is made by the table manager when the address is advertised,
+	// see tabMgrNatReadyInd.
+	if peMgr.natResult = msg.NatType == nat.NATT_NONE || peMgr.cfg.advertised; peMgr.natResult {
 		peMgr.pubTcpIp = peMgr.cfg.ip
 		peMgr.pubTcpPort = int(peMgr.cfg.port)
 		if peMgr.inStartup == peMgrInStartup {
@@ -1481,6 +1752,7 @@ func (peMgr *PeerManager) natPubAddrUpdateInd(msg *sch.MsgNatMgrPubAddrUpdateInd
 
 func (peMgr *PeerManager) start() PeMgrErrno {
 	if peMgr.cfg.noAccept == false {
+		peMgr.acceptPauseReset()
 		msg := sch.SchMessage{}
 		peMgr.sdl.SchMakeMessage(&msg, peMgr.ptnMe, peMgr.ptnLsn, sch.EvPeLsnStartReq, nil)
 		peMgr.sdl.SchSendMessage(&msg)
@@ -1502,6 +1774,14 @@ func (peMgr *PeerManager) start() PeMgrErrno {
 	}
 	peerLog.Debug("start: ocrTid start ok")
 
+	if eno := peMgr.slotAdaptStart(); eno != PeMgrEnoNone {
+		return eno
+	}
+
+	if eno := peMgr.selfProbeStart(); eno != PeMgrEnoNone {
+		return eno
+	}
+
 	msg := sch.SchMessage{}
 	peMgr.sdl.SchMakeMessage(&msg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeOutboundReq, nil)
 	peMgr.sdl.SchSendMessage(&msg)
@@ -1524,7 +1804,16 @@ func (peMgr *PeerManager) stop(why interface{}) PeMgrErrno {
 	}
 	peMgr.caTids = make(map[string]int, 0)
 
+	peerLog.Debug("stop: kill shedTids")
+	for _, tid := range peMgr.shedTids {
+		if tid != sch.SchInvalidTid {
+			peMgr.sdl.SchKillTimer(peMgr.ptnMe, tid)
+		}
+	}
+	peMgr.shedTids = make(map[string]int, 0)
+
 	if peMgr.cfg.noAccept == false {
+		peMgr.acceptPauseReset()
 		msg := sch.SchMessage{}
 		peMgr.sdl.SchMakeMessage(&msg, peMgr.ptnMe, peMgr.ptnLsn, sch.EvPeLsnStopReq, nil)
 		peMgr.sdl.SchSendMessage(&msg)
@@ -1540,6 +1829,15 @@ func (peMgr *PeerManager) stop(why interface{}) PeMgrErrno {
 		peMgr.ocrTid = sch.SchInvalidTid
 	}
 
+	peerLog.Debug("stop: kill slotTid")
+	if peMgr.slotTid != sch.SchInvalidTid {
+		peMgr.sdl.SchKillTimer(peMgr.ptnMe, peMgr.slotTid)
+		peMgr.slotTid = sch.SchInvalidTid
+	}
+
+	peerLog.Debug("stop: stop self probe")
+	peMgr.selfProbeStop()
+
 	peerLog.Debug("stop: kill tidFindNode")
 	for _, tid := range peMgr.tidFindNode {
 		if tid != sch.SchInvalidTid {
@@ -1590,6 +1888,9 @@ func (peMgr *PeerManager) stop(why interface{}) PeMgrErrno {
 		// modules at all, and piTx/piRx must not start for them.
 		peerLog.ForceDebug("stop: send EvPeCloseReq, inst: %s, snid: %x, ip: %s, dir: %d",
 			pi.name, snid, pi.node.IP.String(), pi.dir)
+		if s, ok := why.(string); ok && len(pi.closeWhy) == 0 {
+			pi.closeWhy = s
+		}
 		req := sch.MsgPeCloseReq{
 			Ptn:  ptn,
 			Snid: snid,
@@ -1707,6 +2008,7 @@ func (peMgr *PeerManager) peMgrDataReq(msg interface{}) PeMgrErrno {
 	var inst *PeerInstance = nil
 	var idEx = PeerIdEx{}
 	var req = msg.(*sch.MsgPeDataReq)
+	defer peDataReqRelease(req)
 
 	idEx.Id = req.PeerId
 	idEx.Dir = PeInstDirOutbound
@@ -1724,14 +2026,14 @@ func (peMgr *PeerManager) peMgrDataReq(msg interface{}) PeMgrErrno {
 		return PeMgrEnoNotfound
 	}
 
-	if len(inst.txChan) >= cap(inst.txChan) {
-		peerLog.Debug("peMgrDataReq: discarded, tx queue full, inst: %s, snid: %x, dir: %d,",
-			inst.name, inst.snid, inst.dir)
+	_pkg := req.Pkg.(*P2pPackage)
+	if inst.txQueue.Full(_pkg.Prio) {
+		peerLog.Debug("peMgrDataReq: discarded, tx queue full, inst: %s, snid: %x, dir: %d, prio: %d",
+			inst.name, inst.snid, inst.dir, _pkg.Prio)
 		return PeMgrEnoResource
 	}
 
-	_pkg := req.Pkg.(*P2pPackage)
-	inst.txChan <- _pkg
+	inst.txQueue.Put(_pkg)
 	inst.txPendNum += 1
 
 	return PeMgrEnoNone
@@ -1739,6 +2041,11 @@ func (peMgr *PeerManager) peMgrDataReq(msg interface{}) PeMgrErrno {
 
 func (peMgr *PeerManager) peMgrCreateOutboundInst(snid *config.SubNetworkID, node *config.Node) PeMgrErrno {
 
+	if peMgr.banList.banned(node) {
+		peerLog.Debug("peMgrCreateOutboundInst: banned, snid: %x, peer: %x", *snid, node.ID)
+		return PeMgrEnoParameter
+	}
+
 	var eno = sch.SchEnoNone
 	var ptnInst interface{} = nil
 	var peInst = new(PeerInstance)
@@ -1753,7 +2060,7 @@ func (peMgr *PeerManager) peMgrCreateOutboundInst(snid *config.SubNetworkID, nod
 	peInst.hto = peMgr.cfg.defaultHto
 	peInst.ato = peMgr.cfg.defaultAto
 	peInst.maxPkgSize = peMgr.cfg.maxMsgSize
-	peInst.dialer = &net.Dialer{Timeout: peMgr.cfg.defaultCto}
+	peInst.dialer = peMgr.dialerFor(*snid)
 	peInst.conn = nil
 	peInst.laddr = nil
 	peInst.raddr = nil
@@ -1762,12 +2069,11 @@ func (peMgr *PeerManager) peMgrCreateOutboundInst(snid *config.SubNetworkID, nod
 	peInst.snid = *snid
 	peInst.priKey = peMgr.cfg.subNetKeyList[*snid]
 	peInst.localNode = peMgr.cfg.subNetNodeList[*snid]
-	peInst.localProtoNum = peMgr.cfg.protoNum
-	peInst.localProtocols = peMgr.cfg.protocols
+	peInst.localProtoNum, peInst.localProtocols = peMgr.localProtocols()
 
 	peInst.node = *node
 
-	peInst.txChan = make(chan *P2pPackage, PeInstMaxP2packages)
+	peInst.txQueue = newTxQueue(PeInstMaxP2packages)
 	peInst.ppChan = make(chan *P2pPackage, PeInstMaxPings)
 	peInst.rxChan = make(chan *P2pPackageRx, PeInstMaxP2packages)
 	peInst.rxDone = make(chan PeMgrErrno)
@@ -1860,6 +2166,14 @@ func (peMgr *PeerManager) peMgrKillInst(kip *kiParameters, why interface{}) PeMg
 		panic("peMgrKillInst: instance not found")
 	}
 
+	// those closed are counted by peMgrConnCloseCfm with the cause asked to close for
+	if why != PKI_FOR_CLOSE_CFM {
+		peMgr.killStats.count(peInst.snid, why.(string))
+		peMgr.peerStatsEnded(peInst, why.(string))
+	} else {
+		peMgr.peerStatsEnded(peInst, peInst.closeWhy)
+	}
+
 	if peInst.dir != dir {
 		peerLog.ForceDebug("peMgrKillInst: invalid parameters, inst: %s, kip: %s", peInst.name, kip.name)
 		panic("peMgrKillInst: direction mismatched")
@@ -1874,6 +2188,16 @@ func (peMgr *PeerManager) peMgrKillInst(kip *kiParameters, why interface{}) PeMg
 		peInst.conn.Close()
 	}
 
+	delete(peMgr.standby.held, ptn)
+
+	if why == PKI_FOR_CLOSE_CFM || why == PKI_FOR_RECONFIG {
+		idexx := PeerIdExx{Snid: peInst.snid, Node: peInst.node, Dir: peInst.dir}
+		if tid, ok := peMgr.shedTids[idexx.toString()]; ok {
+			peMgr.sdl.SchKillTimer(peMgr.ptnMe, tid)
+			delete(peMgr.shedTids, idexx.toString())
+		}
+	}
+
 	if why == PKI_FOR_CLOSE_CFM || why == PKI_FOR_RECONFIG {
 		snid := peInst.snid
 		idEx := PeerIdEx{Id: peInst.node.ID, Dir: peInst.dir}
@@ -1883,6 +2207,8 @@ func (peMgr *PeerManager) peMgrKillInst(kip *kiParameters, why interface{}) PeMg
 				peerLog.ForceDebug("peMgrKillInst: inst: %s, kip: %s", peInst.name, kip.name)
 				panic("peMgrKillInst: internal errors")
 			}
+			peMgr.peerVersions.update(peInst.clientVersion, -1)
+			peMgr.idReg.Connected(identity.StackChain, peInst.node.ID, false)
 		}
 	}
 
@@ -1901,6 +2227,9 @@ func (peMgr *PeerManager) peMgrKillInst(kip *kiParameters, why interface{}) PeMg
 			peerLog.ForceDebug("peMgrKillInst: inst: %s, kip: %s", peInst.name, kip.name)
 			panic("peMgrKillInst: internal errors")
 		}
+		if peInst.ipQuota {
+			peMgr.ipQuota.released(peInst.raddr.IP)
+		}
 		snid := peInst.snid
 		idEx := PeerIdEx{Id: peInst.node.ID, Dir: peInst.dir}
 		if why != PKI_FOR_HANDSHAKE_FAILED {
@@ -1928,12 +2257,7 @@ func (peMgr *PeerManager) peMgrKillInst(kip *kiParameters, why interface{}) PeMg
 		peMgr.updateStaticStatus(snid, idEx, peerIdle)
 	}
 
-	if peMgr.cfg.noAccept == false &&
-		peMgr.ibpTotalNum < peMgr.cfg.ibpNumTotal {
-		schMsg := sch.SchMessage{}
-		peMgr.sdl.SchMakeMessage(&schMsg, peMgr.ptnMe, peMgr.ptnLsn, sch.EvPeLsnStartReq, nil)
-		peMgr.sdl.SchSendMessage(&schMsg)
-	}
+	peMgr.acceptResumeCheck()
 
 	peInst.state = peInstStateKilled
 	peMgr.sdl.SchStopTask(ptn, kip.name)
@@ -1941,7 +2265,7 @@ func (peMgr *PeerManager) peMgrKillInst(kip *kiParameters, why interface{}) PeMg
 }
 
 func (peMgr *PeerManager) peMgrConflictAccessProtect(snid config.SubNetworkID, peer *config.Node, dir int) PeMgrErrno {
-	delay := conflictAccessDelayLower + rand.Intn(conflictAccessDelayUpper-conflictAccessDelayLower)
+	delay := conflictAccessDelayLower + peMgr.sdl.SchRandIntn(conflictAccessDelayUpper-conflictAccessDelayLower)
 	dur := time.Millisecond * time.Duration(delay)
 	idexx := PeerIdExx{
 		Snid: snid,
@@ -1983,6 +2307,66 @@ func (peMgr *PeerManager) peMgrCatHandler(msg interface{}) PeMgrErrno {
 	return PeMgrEnoNone
 }
 
+func (peMgr *PeerManager) peMgrSeedShedProtect(inst *PeerInstance) PeMgrErrno {
+	// a seed-only bootstrap node keeps an activated peer for a grace period, so
+	// the peer can learn addresses from us, then the peer is shed to keep slots
+	// free for new joiners.
+	dur := peMgr.cfg.seedGraceTime
+	if dur <= 0 {
+		dur = config.DftSeedGraceTime
+	}
+	idexx := PeerIdExx{
+		Snid: inst.snid,
+		Node: inst.node,
+		Dir:  inst.dir,
+	}
+
+	td := sch.TimerDescription{
+		Name:  "_seedShedTimer",
+		Utid:  sch.PeSeedShedTimerId,
+		Tmt:   sch.SchTmTypeAbsolute,
+		Dur:   dur,
+		Extra: &idexx,
+	}
+
+	peerLog.Debug("peMgrSeedShedProtect: set timer, dur: %d, idexx: %+v", td.Dur, idexx)
+
+	eno, tid := peMgr.sdl.SchSetTimer(peMgr.ptnMe, &td)
+	if eno != sch.SchEnoNone {
+		peerLog.Debug("peMgrSeedShedProtect: SchSetTimer failed, eno: %d", eno)
+		return PeMgrEnoScheduler
+	}
+	peMgr.shedTids[idexx.toString()] = tid
+	return PeMgrEnoNone
+}
+
+func (peMgr *PeerManager) seedShedTimerHandler(msg interface{}) PeMgrErrno {
+	idexx := msg.(*PeerIdExx)
+	delete(peMgr.shedTids, (*idexx).toString())
+	idEx := PeerIdEx{Id: idexx.Node.ID, Dir: idexx.Dir}
+	inst := peMgr.getWorkerInst(idexx.Snid, &idEx)
+	if inst == nil {
+		peerLog.Debug("seedShedTimerHandler: worker not found, idexx: %+v", *idexx)
+		return PeMgrEnoNotfound
+	}
+
+	// go the way a peer instance asks to be closed: the shell is informed first
+	// and it would then request us to close the instance, see peMgrCloseReq.
+	req := sch.MsgPeCloseReq{
+		Ptn:  inst.ptnMe,
+		Snid: inst.snid,
+		Node: inst.node,
+		Dir:  inst.dir,
+		Why:  sch.PEC_FOR_SEEDSHED,
+	}
+	schMsg := sch.SchMessage{}
+	peMgr.sdl.SchMakeMessage(&schMsg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeCloseReq, &req)
+	peMgr.sdl.SchSendMessage(&schMsg)
+	peerLog.Debug("seedShedTimerHandler: EvPeCloseReq sent, inst: %s, snid: %x, dir: %d",
+		inst.name, inst.snid, inst.dir)
+	return PeMgrEnoNone
+}
+
 func (peMgr *PeerManager) reconfigTimerHandler() PeMgrErrno {
 	peMgr.reCfgTid = sch.SchInvalidTid
 	for del := range peMgr.reCfg.delList {
@@ -2185,7 +2569,7 @@ func (peMgr *PeerManager) peMgrAsk4More(snid *SubNetworkID) PeMgrErrno {
 	}
 
 	peMgr.tmLastFNR[*snid] = time.Now()
-	dur := durStaticRetryTimer
+	dur := peMgr.staticRetryDelay(time.Now())
 
 	if *snid != peMgr.cfg.staticSubNetId {
 
@@ -2262,14 +2646,6 @@ func (peMgr *PeerManager) peMgrAsk4More(snid *SubNetworkID) PeMgrErrno {
 	return PeMgrEnoNone
 }
 
-func (peMgr *PeerManager) peMgrIndEnque(ind interface{}) PeMgrErrno {
-	if len(peMgr.indChan) >= cap(peMgr.indChan) {
-		panic("peMgrIndEnque: system overload")
-	}
-	peMgr.indChan <- ind
-	return PeMgrEnoNone
-}
-
 //
 // Dynamic peer instance task
 //
@@ -2299,52 +2675,78 @@ const PeInstMaxPingpongCnt = 4               // max pingpong counter value
 const PeInstPingpongCycle = time.Second * 16 // pingpong period
 
 type PeerInstance struct {
-	sdl    *sch.Scheduler      // pointer to scheduler
-	peMgr  *PeerManager        // pointer to peer manager
-	name   string              // name
-	tep    sch.SchUserTaskEp   // entry
-	ptnMe  interface{}         // the instance task node pointer
-	ptnMgr interface{}         // the peer manager task node pointer
-	state  peerInstState       // state
-	cto    time.Duration       // connect timeout value
-	hto    time.Duration       // handshake timeout value
-	ato    time.Duration       // active peer connection read/write timeout value
-	dialer *net.Dialer         // dialer to make outbound connection
-	conn   net.Conn            // connection
-	iow    ggio.WriteCloser    // IO writer
-	ior    ggio.ReadCloser     // IO reader
-	laddr  *net.TCPAddr        // local ip address
-	raddr  *net.TCPAddr        // remote ip address
-	dir    int                 // direction: outbound(+1) or inbound(-1)
-	snid   config.SubNetworkID // sub network identity
+	sdl    *sch.Scheduler       // pointer to scheduler
+	peMgr  *PeerManager         // pointer to peer manager
+	name   string               // name
+	tep    sch.SchUserTaskEp    // entry
+	ptnMe  interface{}          // the instance task node pointer
+	ptnMgr interface{}          // the peer manager task node pointer
+	state  peerInstState        // state
+	cto    time.Duration        // connect timeout value
+	hto    time.Duration        // handshake timeout value
+	ato    time.Duration        // active peer connection read/write timeout value
+	dialer config.PeerTransport // transport to make outbound connection
+	conn   net.Conn             // connection
+	iow    ggio.WriteCloser     // IO writer
+	ior    ggio.ReadCloser      // IO reader
+	laddr  *net.TCPAddr         // local ip address
+	raddr  *net.TCPAddr         // remote ip address
+	dir    int                  // direction: outbound(+1) or inbound(-1)
+	snid   config.SubNetworkID  // sub network identity
 
 	networkType    int              // network type
 	priKey         ecdsa.PrivateKey // local node private key
 	localNode      config.Node      // local "node" information
 	localProtoNum  uint32           // local protocol number
 	localProtocols []Protocol       // local protocol table
-
-	node        config.Node        // peer "node" information
-	protoNum    uint32             // peer protocol number
-	protocols   []Protocol         // peer protocol table
-	maxPkgSize  int                // max size of tcpmsg package
-	ppTid       int                // pingpong timer identity
-	rxChan      chan *P2pPackageRx // rx pending channel
-	txChan      chan *P2pPackage   // tx pending channel
-	ppChan      chan *P2pPackage   // ping channel
-	txPendNum   int                // tx pending number
-	txSeq       int64              // statistics sequence number
-	txOkCnt     int64              // tx ok counter
-	txFailedCnt int64              // tx failed counter
-	rxDone      chan PeMgrErrno    // RX chan
-	rxtxRuning  bool               // indicating that rx and tx routines are running
-	ppSeq       uint64             // pingpong sequence no.
-	ppCnt       int                // pingpong counter
-	rxEno       PeMgrErrno         // rx errno
-	txEno       PeMgrErrno         // tx errno
-	ppEno       PeMgrErrno         // pingpong errno
-	rxDiscard   int64              // number of rx messages discarded
-	rxOkCnt     int64              // number of rx messages accepted
+	hsNonce        []byte           // nonce of handshake sent, see hsauth.go
+	hsPeerNonce    []byte           // nonce of handshake received
+	compress       uint32           // compression applied to user packages, see compress.go
+
+	node          config.Node          // peer "node" information
+	protoNum      uint32               // peer protocol number
+	protocols     []Protocol           // peer protocol table
+	negotiated    []Protocol           // protocols agreed with peer, see negotiateProtocols
+	clientVersion string               // client version announced by peer
+	dhtPort       uint32               // dht tcp port announced by peer, 0 if not run
+	maxPkgSize    int                  // max size of tcpmsg package
+	ppTid         int                  // pingpong timer identity
+	rxChan        chan *P2pPackageRx   // rx pending channel
+	txQueue       *TxQueue             // tx pending queues by priority class
+	ppChan        chan *P2pPackage     // ping channel
+	txPendNum     int                  // tx pending number
+	txSeq         int64                // statistics sequence number
+	txOkCnt       int64                // tx ok counter
+	txFailedCnt   int64                // tx failed counter
+	txExpiredCnt  int64                // tx dropped for expired counter
+	rxDone        chan PeMgrErrno      // RX chan
+	rxtxRuning    bool                 // indicating that rx and tx routines are running
+	ppSeq         uint64               // pingpong sequence no.
+	ppSent        int64                // unix nano the ping of ppSeq sent, accessed atomically
+	ppCnt         int                  // pingpong counter
+	ppSuppressed  int                  // pings suppressed in a row, see keepalive.go
+	rxLast        int64                // unix nano anything received last, accessed atomically
+	txLast        int64                // unix nano data sent last, accessed atomically
+	rxEno         PeMgrErrno           // rx errno
+	txEno         PeMgrErrno           // tx errno
+	ppEno         PeMgrErrno           // pingpong errno
+	rxDiscard     int64                // number of rx messages discarded
+	rxOkCnt       int64                // number of rx messages accepted, accessed atomically
+	rxGrow        *rxGrowQueue         // rx packages pending for rxChan full, see piRxEnque
+	rxDropPending int                  // rx packages dropped not yet noticed to peer, see piRxDropNotice
+	rxDropPid     uint32               // pid of the latest rx package dropped
+	rxDropMid     uint32               // mid of the latest rx package dropped
+	rxDropNoticed time.Time            // time the peer noticed drops last
+	txStreamSeq   uint64               // sequence of streams sent, see piTxPackage
+	rxStreams     map[uint64]*rxStream // streams in reassembling, see piRxFrame
+	txLimiter     *rateLimiter         // tx bandwidth limiter, nil for unlimited
+	rxLimiter     *rateLimiter         // rx bandwidth limiter, nil for unlimited
+	closeWhy      string               // cause asked to close for, see peMgrCloseReq
+	probedAt      time.Time            // time the endpoints of peer probed, see piP2pProbeProc
+	ipQuota       bool                 // counted to the inbound quota of the remote ip, see ipquota.go
+	activated     time.Time            // time activated, zero if not or the session ended, see peerstats.go
+	txBytes       int64                // payload bytes sent, accessed atomically
+	rxBytes       int64                // payload bytes received, accessed atomically
 }
 
 var peerInstDefault = PeerInstance{
@@ -2417,7 +2819,10 @@ func (pi *PeerInstance) TaskProc4Scheduler(ptn interface{}, msg *sch.SchMessage)
 
 func (pi *PeerInstance) peerInstProc(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
 
-	peerLog.ForceDebug("peerInstProc: inst: %s, msg.Id: %d", pi.name, msg.Id)
+	sampled := peerLog.ForceSampled("peerInstProc")
+	if sampled {
+		peerLog.ForceDebug("peerInstProc: inst: %s, msg.Id: %d", pi.name, msg.Id)
+	}
 
 	var eno PeMgrErrno
 
@@ -2454,7 +2859,9 @@ func (pi *PeerInstance) peerInstProc(ptn interface{}, msg *sch.SchMessage) sch.S
 		eno = PeMgrEnoParameter
 	}
 
-	peerLog.ForceDebug("peerInstProc: get out, inst: %s, msg.Id: %d", pi.name, msg.Id)
+	if sampled {
+		peerLog.ForceDebug("peerInstProc: get out, inst: %s, msg.Id: %d", pi.name, msg.Id)
+	}
 
 	if eno != PeMgrEnoNone {
 		return sch.SchEnoUserTask
@@ -2494,17 +2901,18 @@ func (pi *PeerInstance) piConnOutReq(_ interface{}) PeMgrErrno {
 	}
 
 	var (
-		addr          = &net.TCPAddr{IP: pi.node.IP, Port: int(pi.node.TCP)}
-		conn net.Conn = nil
-		err  error
-		eno  PeMgrErrno = PeMgrEnoNone
+		addrs          = pi.piDialEndpoints()
+		addr           = addrs[0]
+		conn  net.Conn = nil
+		err   error
+		eno   PeMgrErrno = PeMgrEnoNone
 	)
 
-	peerLog.ForceDebug("piConnOutReq: outbound inst: %s, snid: %x, try to dial target: %s",
-		pi.name, pi.snid, addr.String())
+	peerLog.ForceDebug("piConnOutReq: outbound inst: %s, snid: %x, try to dial target: %s, endpoints: %d",
+		pi.name, pi.snid, addr.String(), len(addrs))
 
-	pi.dialer.Timeout = pi.cto
-	if conn, err = pi.dialer.Dial("tcp", addr.String()); err != nil {
+	conn, err = dialEyeballs(pi.dialer, addrs, pi.cto, dialStagger)
+	if err != nil {
 		peerLog.Debug("piConnOutReq: dial failed, local: %s, to: %s, err: %s",
 			fmt.Sprintf("%s:%d", pi.node.IP.String(), pi.node.TCP),
 			addr.String(), err.Error())
@@ -2618,7 +3026,12 @@ func (pi *PeerInstance) piPingpongReq(msg interface{}) PeMgrErrno {
 		peerLog.Debug("piPingpongReq: ping failed, inst: %s, eno: %d", pi.name, eno)
 		return eno
 	}
+	atomic.StoreInt64(&pi.ppSent, time.Now().UnixNano())
 	pi.ppChan <- upkg
+	if pi.networkType != config.P2pNetworkTypeStatic && pi.peMgr.tabMgr != nil {
+		now := time.Now()
+		pi.peMgr.tabMgr.TabUpdateBoundTime(pi.snid, pi.node.ID, &now, nil)
+	}
 	return PeMgrEnoNone
 }
 
@@ -2688,6 +3101,8 @@ func (pi *PeerInstance) piEstablishedInd(msg interface{}) PeMgrErrno {
 		return PeMgrEnoOs
 	}
 
+	pi.txLimiter = newRateLimiter(pi.peMgr.cfg.txRate)
+	pi.rxLimiter = newRateLimiter(pi.peMgr.cfg.rxRate)
 	go piTx(pi)
 	go piRx(pi)
 
@@ -2702,6 +3117,10 @@ func (pi *PeerInstance) piEstablishedInd(msg interface{}) PeMgrErrno {
 }
 
 func (pi *PeerInstance) piPingpongTimerHandler() PeMgrErrno {
+	alive, suppress := pi.ppTraffic(time.Now())
+	if alive {
+		pi.ppCnt = 0
+	}
 	if pi.ppCnt++; pi.ppCnt > PeInstMaxPingpongCnt {
 
 		peerLog.ForceDebug("piPingpongTimerHandler: send EvPeCloseReq, inst: %s, snid: %x, dir: %d,  ip: %s",
@@ -2721,6 +3140,9 @@ func (pi *PeerInstance) piPingpongTimerHandler() PeMgrErrno {
 		pi.sdl.SchSendMessage(&msg)
 		return pi.ppEno
 	}
+	if suppress {
+		return PeMgrEnoNone
+	}
 	pr := MsgPingpongReq{
 		seq: uint64(time.Now().UnixNano()),
 	}
@@ -2736,11 +3158,16 @@ func (pi *PeerInstance) piTxDataReq(_ interface{}) PeMgrErrno {
 }
 
 func (pi *PeerInstance) piRxDataInd(msg interface{}) PeMgrErrno {
-	return pi.piP2pPkgProc(msg.(*P2pPackage))
+	upkg := msg.(*P2pPackage)
+	defer peRxPkgRelease(upkg)
+	return pi.piP2pPkgProc(upkg)
 }
 
 func (pi *PeerInstance) checkHandshakeInfo(hs *Handshake) bool {
 	pass := false
+	if pi.peMgr.banList.nodeBanned(hs.NodeId) {
+		return false
+	}
 	if pi.peMgr.dynamicSubNetIdExist(&hs.Snid) {
 		pass = true
 	} else if pi.peMgr.staticSubNetIdExist(&hs.Snid) {
@@ -2754,6 +3181,38 @@ func (pi *PeerInstance) checkHandshakeInfo(hs *Handshake) bool {
 	return pass
 }
 
+func (pi *PeerInstance) checkHandshakeAddr(inst *PeerInstance, hs *Handshake) bool {
+	// check the ip claimed in handshake against the remote address of the
+	// inbound connection, since it would be fed into discovery later.
+	level := pi.peMgr.cfg.hsAddrCheck
+	if level == config.HsAddrCheckNone || inst.raddr == nil {
+		return true
+	}
+	observed := inst.raddr.IP
+	if hs.IP.Equal(observed) || (hs.AltIP != nil && hs.AltIP.Equal(observed)) {
+		return true
+	}
+	switch level {
+	case config.HsAddrCheckWarn:
+		peerLog.ForceDebug("checkHandshakeAddr: mismatched, claimed: %s, observed: %s",
+			hs.IP.String(), observed.String())
+	case config.HsAddrCheckOverride:
+		peerLog.Debug("checkHandshakeAddr: overridden, claimed: %s, observed: %s",
+			hs.IP.String(), observed.String())
+		if hs.AltIP != nil && (hs.AltIP.To4() == nil) == (observed.To4() == nil) {
+			// dialed on the other family, the primary one kept
+			hs.AltIP = append(net.IP{}, observed...)
+		} else {
+			hs.IP = append(net.IP{}, observed...)
+		}
+	default:
+		peerLog.Debug("checkHandshakeAddr: rejected, claimed: %s, observed: %s",
+			hs.IP.String(), observed.String())
+		return false
+	}
+	return true
+}
+
 func (pi *PeerInstance) piHandshakeInbound(inst *PeerInstance) PeMgrErrno {
 	var eno PeMgrErrno = PeMgrEnoNone
 	var pkg = new(P2pPackage)
@@ -2767,15 +3226,25 @@ func (pi *PeerInstance) piHandshakeInbound(inst *PeerInstance) PeMgrErrno {
 	peerLog.Debug("piHandshakeInbound: snid: %x, peer: %s, hs: %+v",
 		hs.Snid, hs.IP.String(), *hs)
 
-	if pi.checkHandshakeInfo(hs) != true {
+	// a handshake from ourselves is answered without checks, so that the
+	// dialing side detects the loop as well, see selfdial.go
+	self := pi.peMgr.selfHandshake(hs)
+
+	if !self && pi.checkHandshakeInfo(hs) != true {
 		peerLog.Debug("piHandshakeInbound: checkHandshakeInfo failed, snid: %x, peer: %s, hs: %+v",
 			hs.Snid, hs.IP.String(), *hs)
 		return PeMgrEnoNotfound
 	}
 
-	// backup info about protocols supported by peer. notice that here we can
-	// check against the ip and tcp port from handshake with that obtained from
-	// underlying network, but we not now.
+	if !self && pi.checkHandshakeAddr(inst, hs) != true {
+		peerLog.Debug("piHandshakeInbound: checkHandshakeAddr failed, snid: %x, peer: %s, remote: %s",
+			hs.Snid, hs.IP.String(), inst.raddr.String())
+		return PeMgrEnoVerify
+	}
+
+	// backup info about protocols supported by peer. notice that the tcp port
+	// from handshake can't be checked, the remote port of an inbound connection
+	// is not the one the peer listening on.
 	inst.snid = hs.Snid
 	pi.peMgr.setHandshakeParameters(inst, hs.Snid)
 
@@ -2785,6 +3254,10 @@ func (pi *PeerInstance) piHandshakeInbound(inst *PeerInstance) PeMgrErrno {
 	inst.node.UDP = uint16(hs.UDP)
 	inst.protoNum = hs.ProtoNum
 	inst.protocols = hs.Protocols
+	inst.negotiated = negotiateProtocols(inst.localProtocols, hs.Protocols)
+	inst.clientVersion = hs.ClientVersion
+	inst.node.AltIP = hsOtherIP(hs, inst.node.IP)
+	inst.dhtPort = hs.DhtPort
 
 	// write outbound handshake to remote peer
 	hs2peer := Handshake{}
@@ -2795,13 +3268,26 @@ func (pi *PeerInstance) piHandshakeInbound(inst *PeerInstance) PeMgrErrno {
 	hs2peer.TCP = uint32(inst.localNode.TCP)
 	hs2peer.ProtoNum = inst.localProtoNum
 	hs2peer.Protocols = inst.localProtocols
+	hs2peer.ClientVersion = pi.peMgr.cfg.clientVersion
+	hs2peer.DhtPort = pi.peMgr.cfg.dhtPort
+	hs2peer.WsPort = pi.peMgr.cfg.wsPort
+	hs2peer.AltIP = pi.peMgr.cfg.altIp
+	hs2peer.InstNonce = pi.peMgr.instNonce
+	hs2peer.Encryption = pi.peMgr.encryptionPolicy(inst.snid)
+	hs2peer.Compression = pi.peMgr.compressionMask()
+	inst.compress = negotiateCompression(hs2peer.Compression, hs.Compression)
 
 	if eno = pkg.putHandshakeOutbound(inst, &hs2peer); eno != PeMgrEnoNone {
 		peerLog.Debug("piHandshakeInbound: write outbound Handshake message failed, eno: %d", eno)
 		return eno
 	}
+	if self {
+		peerLog.Debug("piHandshakeInbound: dialed by ourselves, inst: %s, remote: %s",
+			inst.name, inst.raddr.String())
+		return PeMgrEnoSelf
+	}
 
-	return PeMgrEnoNone
+	return inst.piSecure(hs, hs2peer.Encryption)
 }
 
 func (pi *PeerInstance) piHandshakeOutbound(inst *PeerInstance) PeMgrErrno {
@@ -2815,8 +3301,20 @@ func (pi *PeerInstance) piHandshakeOutbound(inst *PeerInstance) PeMgrErrno {
 	hs.IP = append(hs.IP, pi.localNode.IP...)
 	hs.UDP = uint32(pi.localNode.UDP)
 	hs.TCP = uint32(pi.localNode.TCP)
+	if !pi.peMgr.Dialable() {
+		// not reached by peers probed, see selfprobe.go, so peers would not dial
+		hs.TCP = 0
+	}
 	hs.ProtoNum = pi.localProtoNum
 	hs.Protocols = append(hs.Protocols, pi.localProtocols...)
+	hs.ClientVersion = pi.peMgr.cfg.clientVersion
+	hs.DhtPort = pi.peMgr.cfg.dhtPort
+	hs.WsPort = pi.peMgr.cfg.wsPort
+	hs.AltIP = pi.peMgr.cfg.altIp
+	hs.InstNonce = pi.peMgr.instNonce
+	hs.Encryption = pi.peMgr.encryptionPolicy(pi.snid)
+	hs.Compression = pi.peMgr.compressionMask()
+	encryption, compression := hs.Encryption, hs.Compression
 
 	if eno = pkg.putHandshakeOutbound(inst, hs); eno != PeMgrEnoNone {
 		peerLog.Debug("piHandshakeOutbound: write outbound Handshake message failed, eno: %d", eno)
@@ -2829,6 +3327,14 @@ func (pi *PeerInstance) piHandshakeOutbound(inst *PeerInstance) PeMgrErrno {
 		return eno
 	}
 
+	// check if we dialed ourselves, before the identities checked, which
+	// can't tell behind some nats
+	if pi.peMgr.selfHandshake(hs) {
+		peerLog.Debug("piHandshakeOutbound: dialed ourselves, inst: %s, remote: %s",
+			inst.name, inst.raddr.String())
+		return PeMgrEnoSelf
+	}
+
 	// check handshake
 	if pi.checkHandshakeInfo(hs) != true {
 		peerLog.Debug("piHandshakeOutbound: checkHandshakeInfo failed, snid: %x, peer: %s, hs: %+v",
@@ -2843,10 +3349,12 @@ func (pi *PeerInstance) piHandshakeOutbound(inst *PeerInstance) PeMgrErrno {
 	}
 
 	// since it's an outbound peer, the peer node id is known before this
-	// handshake procedure carried out, we can check against these twos.
+	// handshake procedure carried out, we can check against these twos. the
+	// address known might be that of the other family of a dual stack peer,
+	// and the port that of websocket for light clients, see websocket.go.
 	if hs.NodeId != inst.node.ID ||
-		inst.node.TCP != uint16(hs.TCP) ||
-		bytes.Compare(inst.node.IP, hs.IP) != 0 {
+		(inst.node.TCP != uint16(hs.TCP) && (hs.WsPort == 0 || uint32(inst.node.TCP) != hs.WsPort)) ||
+		(bytes.Compare(inst.node.IP, hs.IP) != 0 && !inst.node.IP.Equal(hs.AltIP)) {
 		peerLog.Debug("piHandshakeOutbound: handshake mismathced, ip: %s, port: %d, id: %x",
 			hs.IP.String(), hs.TCP, hs.NodeId)
 		return PeMgrEnoMessage
@@ -2854,7 +3362,47 @@ func (pi *PeerInstance) piHandshakeOutbound(inst *PeerInstance) PeMgrErrno {
 
 	inst.protoNum = hs.ProtoNum
 	inst.protocols = hs.Protocols
-	return PeMgrEnoNone
+	inst.negotiated = negotiateProtocols(inst.localProtocols, hs.Protocols)
+	inst.clientVersion = hs.ClientVersion
+	inst.node.AltIP = hsOtherIP(hs, inst.node.IP)
+	inst.dhtPort = hs.DhtPort
+	inst.compress = negotiateCompression(compression, hs.Compression)
+	return inst.piSecure(hs, encryption)
+}
+
+//
+// Pools for bodies of the hottest events: the EvPeTxDataReq requests and the
+// EvPeRxDataInd packages, they are released by the handlers once processed.
+// notice: a package of PID_EXT is passed to the user, so it's never released.
+//
+var peDataReqPool = sync.Pool{
+	New: func() interface{} {
+		return new(sch.MsgPeDataReq)
+	},
+}
+
+var peRxPkgPool = sync.Pool{
+	New: func() interface{} {
+		return new(P2pPackage)
+	},
+}
+
+func peDataReqAlloc() *sch.MsgPeDataReq {
+	return peDataReqPool.Get().(*sch.MsgPeDataReq)
+}
+
+func peDataReqRelease(req *sch.MsgPeDataReq) {
+	*req = sch.MsgPeDataReq{}
+	peDataReqPool.Put(req)
+}
+
+func peRxPkgAlloc() *P2pPackage {
+	return peRxPkgPool.Get().(*P2pPackage)
+}
+
+func peRxPkgRelease(pkg *P2pPackage) {
+	*pkg = P2pPackage{}
+	peRxPkgPool.Put(pkg)
 }
 
 func SendPackage(pkg *P2pPackage2Peer) PeMgrErrno {
@@ -2879,14 +3427,18 @@ func SendPackage(pkg *P2pPackage2Peer) PeMgrErrno {
 		_pkg.Key = pkg.Key
 		_pkg.PayloadLength = uint32(pkg.PayloadLength)
 		_pkg.Payload = append(_pkg.Payload, pkg.Payload...)
-		req := sch.MsgPeDataReq{
-			SubNetId: pkg.SubNetId,
-			PeerId:   pid,
-			Pkg:      _pkg,
-		}
+		_pkg.Enqueued = time.Now()
+		_pkg.TTL = pkg.TTL
+		_pkg.Prio = pkg.Prio
+		req := peDataReqAlloc()
+		req.SubNetId = pkg.SubNetId
+		req.PeerId = pid
+		req.Pkg = _pkg
 		msg := sch.SchMessage{}
-		pkg.P2pInst.SchMakeMessage(&msg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeTxDataReq, &req)
-		pkg.P2pInst.SchSendMessage(&msg)
+		pkg.P2pInst.SchMakeMessage(&msg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeTxDataReq, req)
+		if pkg.P2pInst.SchSendMessage(&msg) != sch.SchEnoNone {
+			peDataReqRelease(req)
+		}
 	}
 	return PeMgrEnoNone
 }
@@ -2918,7 +3470,7 @@ func (peMgr *PeerManager) ClosePeer(snid *SubNetworkID, id *PeerId) PeMgrErrno {
 func piTx(pi *PeerInstance) PeMgrErrno {
 	// This function is "go" when an instance of peer is activated to work,
 	// inbound or outbound. When user try to close the peer, this routine
-	// would then exit for "txChan" closed.
+	// would then exit for "txQueue" closed.
 
 	defer func() {
 		if err := recover(); err != nil {
@@ -2971,12 +3523,12 @@ _txLoop:
 		ppkg = (*P2pPackage)(nil)
 		upkg = (*P2pPackage)(nil)
 
-		select {
-		case ppkg, okPP = <-pi.ppChan:
+		// pings and pongs first, then the higher priority classes, see TxQueue
+		if pkg, ok, pp := pi.txQueue.next(pi.ppChan); pp {
+			ppkg, okPP = pkg, ok
 			isPP = true
-			isData = false
-		case upkg, okData = <-pi.txChan:
-			isPP = false
+		} else {
+			upkg, okData = pkg, ok
 			isData = true
 		}
 
@@ -3025,9 +3577,18 @@ _txLoop:
 			pi.txPendNum -= 1
 			pi.txSeq += 1
 
-			if eno := upkg.SendPackage(pi); eno == PeMgrEnoNone {
+			if upkg.expired(time.Now()) {
+				// useless to the peer now, not to waste the bandwidth
+				pi.txExpiredCnt += 1
+				pi.msgStatExpired(upkg.Pid, upkg.Mid)
+				continue
+			}
+
+			if eno := pi.piTxPackage(upkg); eno == PeMgrEnoNone {
 
 				pi.txOkCnt += 1
+				pi.txSeen(time.Now())
+				atomic.AddInt64(&pi.txBytes, int64(upkg.PayloadLength))
 
 			} else {
 
@@ -3037,8 +3598,8 @@ _txLoop:
 		}
 
 		if pi.txSeq&0x3ff == 0 {
-			peerLog.Debug("piTx: inst: %s, snid: %x, dir: %d, txSeq: %d, txOkCnt: %d, txFailedCnt: %d",
-				pi.name, pi.snid, pi.dir, pi.txSeq, pi.txOkCnt, pi.txFailedCnt)
+			peerLog.Debug("piTx: inst: %s, snid: %x, dir: %d, txSeq: %d, txOkCnt: %d, txFailedCnt: %d, txExpiredCnt: %d",
+				pi.name, pi.snid, pi.dir, pi.txSeq, pi.txOkCnt, pi.txFailedCnt, pi.txExpiredCnt)
 		}
 	}
 
@@ -3067,6 +3628,7 @@ _rxLoop:
 		case done, ok = <-pi.rxDone:
 			peerLog.ForceDebug("piRx: done, inst: %s, snid: %x, dir: %d, done with: %d",
 				pi.name, pi.snid, pi.dir, done)
+			pi.piRxGrowStop()
 			if ok {
 				close(pi.rxDone)
 			}
@@ -3081,7 +3643,7 @@ _rxLoop:
 		}
 
 		// try reading the peer
-		upkg := new(P2pPackage)
+		upkg := peRxPkgAlloc()
 		if eno := upkg.RecvPackage(pi); eno != PeMgrEnoNone {
 
 			if eno == PeMgrEnoNetTemporary {
@@ -3124,6 +3686,20 @@ _rxLoop:
 		}
 
 		upkg.DebugPeerPackage()
+		pi.rxSeen(time.Now())
+		atomic.AddInt64(&pi.rxBytes, int64(upkg.PayloadLength))
+		pi.rxLimiter.wait(len(upkg.Payload))
+
+		if upkg.Pid == uint32(PID_EXT) && upkg.Mid == uint32(MID_FRAME) {
+			if !pi.p2pExtended() {
+				peerLog.Debug("piRx: frame from peer not streaming, discarded, inst: %s", pi.name)
+				pi.peMgr.msgStats.dropped(upkg.Pid, upkg.Mid)
+				continue
+			}
+			if upkg = pi.piRxFrame(upkg); upkg == nil {
+				continue
+			}
+		}
 
 		if upkg.Pid == uint32(PID_P2P) {
 
@@ -3133,45 +3709,71 @@ _rxLoop:
 
 		} else if upkg.Pid == uint32(PID_EXT) {
 
-			if len(pi.rxChan) >= cap(pi.rxChan) {
-
-				peerLog.Debug("piRx: queue full, inst: %s, snid: %x, dir: %d",
-					pi.name, pi.snid, pi.dir)
-
-				if pi.rxDiscard += 1; pi.rxDiscard&0x1f == 0 {
-					peerLog.Debug("piRx: inst: %s, snid: %x, dir: %d, rxDiscard: %d",
-						pi.name, pi.snid, pi.dir, pi.rxDiscard)
+			peerInfo := PeerInfo{}
+			pkgCb := P2pPackageRx{}
+			peerInfo.Protocols = nil
+			peerInfo.Snid = pi.snid
+			peerInfo.Dir = pi.dir
+			peerInfo.NodeId = pi.node.ID
+			peerInfo.IP = pi.node.IP
+			peerInfo.TCP = uint32(pi.node.TCP)
+			peerInfo.UDP = uint32(pi.node.UDP)
+			peerInfo.ProtoNum = pi.protoNum
+			peerInfo.Protocols = append(peerInfo.Protocols, pi.protocols...)
+			peerInfo.Negotiated = pi.negotiated
+			pkgCb.Ptn = pi.ptnMe
+			pkgCb.Payload = nil
+			pkgCb.PeerInfo = &peerInfo
+			pkgCb.ProtoId = int(upkg.Pid)
+			pkgCb.MsgId = int(upkg.Mid)
+			pkgCb.Key = upkg.Key
+			pkgCb.PayloadLength = int(upkg.PayloadLength)
+			pkgCb.Payload = append(pkgCb.Payload, upkg.Payload...)
+
+			if ring := pi.peMgr.fastPaths.get(upkg.Mid); ring != nil {
+				if ring.push(&pkgCb) {
+					pi.piRxOk()
+				} else {
+					pi.piRxDrop(&pkgCb)
 				}
+			} else if pi.piRxEnque(&pkgCb) {
+				peerLog.ForceDebug("piRx: done while enqueuing, inst: %s, snid: %x, dir: %d",
+					pi.name, pi.snid, pi.dir)
+				pi.piRxGrowStop()
+				close(pi.rxDone)
+				break _rxLoop
+			}
 
+		} else if pool := pi.peMgr.protoHandlers.get(upkg.Pid); pool != nil {
+
+			// protocol registered at runtime, see RegisterProtocol. the package is
+			// handled by the pool of the protocol, the payload is copied for that.
+			peerInfo := PeerInfo{
+				Snid:       pi.snid,
+				Dir:        pi.dir,
+				NodeId:     pi.node.ID,
+				IP:         pi.node.IP,
+				TCP:        uint32(pi.node.TCP),
+				UDP:        uint32(pi.node.UDP),
+				ProtoNum:   pi.protoNum,
+				Protocols:  pi.protocols,
+				Negotiated: pi.negotiated,
+			}
+			pkgCb := P2pPackageRx{
+				Ptn:           pi.ptnMe,
+				PeerInfo:      &peerInfo,
+				ProtoId:       int(upkg.Pid),
+				MsgId:         int(upkg.Mid),
+				Key:           upkg.Key,
+				PayloadLength: int(upkg.PayloadLength),
+				Payload:       append([]byte(nil), upkg.Payload...),
+			}
+			if pool.submit(&pkgCb) {
+				pi.piRxOk()
 			} else {
-
-				peerInfo := PeerInfo{}
-				pkgCb := P2pPackageRx{}
-				peerInfo.Protocols = nil
-				peerInfo.Snid = pi.snid
-				peerInfo.Dir = pi.dir
-				peerInfo.NodeId = pi.node.ID
-				peerInfo.IP = pi.node.IP
-				peerInfo.TCP = uint32(pi.node.TCP)
-				peerInfo.UDP = uint32(pi.node.UDP)
-				peerInfo.ProtoNum = pi.protoNum
-				peerInfo.Protocols = append(peerInfo.Protocols, pi.protocols...)
-				pkgCb.Ptn = pi.ptnMe
-				pkgCb.Payload = nil
-				pkgCb.PeerInfo = &peerInfo
-				pkgCb.ProtoId = int(upkg.Pid)
-				pkgCb.MsgId = int(upkg.Mid)
-				pkgCb.Key = upkg.Key
-				pkgCb.PayloadLength = int(upkg.PayloadLength)
-				pkgCb.Payload = append(pkgCb.Payload, upkg.Payload...)
-
-				pi.rxChan <- &pkgCb
-
-				if pi.rxOkCnt += 1; pi.rxOkCnt&0x3ff == 0 {
-					peerLog.Debug("piRx: inst: %s, snid: %x, dir: %d, rxOkCnt: %d",
-						pi.name, pi.snid, pi.dir, pi.rxOkCnt)
-				}
+				pi.piRxDrop(&pkgCb)
 			}
+
 		} else {
 			peerLog.Debug("piRx: discarded, inst: %s, snid: %x, dir: %d,  pid: %d",
 				pi.name, pi.snid, pi.dir, upkg.Pid)
@@ -3202,6 +3804,7 @@ func (pi *PeerInstance) piP2pPkgProc(upkg *P2pPackage) PeMgrErrno {
 	msg := P2pMessage{}
 	if eno := upkg.GetMessage(&msg); eno != PeMgrEnoNone {
 		peerLog.Debug("piP2pPkgProc: GetMessage failed, eno: %d", eno)
+		pi.msgStatDecodeFailed(upkg.Pid, upkg.Mid)
 		return eno
 	}
 
@@ -3221,6 +3824,21 @@ func (pi *PeerInstance) piP2pPkgProc(upkg *P2pPackage) PeMgrErrno {
 	case uint32(MID_PONG):
 		return pi.piP2pPongProc(msg.Pong)
 
+	case uint32(MID_ECHO):
+		return pi.piP2pEchoProc(msg.Echo)
+
+	case uint32(MID_ECHORSP):
+		return pi.piP2pEchoRspProc(msg.EchoRsp)
+
+	case uint32(MID_PROBE):
+		return pi.piP2pProbeProc(msg.Probe)
+
+	case uint32(MID_PROBERSP):
+		return pi.piP2pProbeRspProc(msg.ProbeRsp)
+
+	case uint32(MID_RXDROP):
+		return pi.piP2pRxDropProc(msg.RxDrop)
+
 	default:
 		peerLog.Debug("piP2pPkgProc: unknown mid: %d", msg.Mid)
 		return PeMgrEnoMessage
@@ -3263,11 +3881,47 @@ func (pi *PeerInstance) piP2pPingProc(ping *Pingpong) PeMgrErrno {
 
 func (pi *PeerInstance) piP2pPongProc(pong *Pingpong) PeMgrErrno {
 	// Currently, the heartbeat checking does not apply pong messages from
-	// peer, instead, a counter for ping messages and a timer are invoked,
-	// see it pls.
+	// peer, instead, a counter reset by anything received and a timer are
+	// invoked, see keepalive.go pls. But the pong tells the peer is alive,
+	// so the table is updated.
+	if pi.networkType != config.P2pNetworkTypeStatic && pi.peMgr.tabMgr != nil {
+		now := time.Now()
+		pi.peMgr.tabMgr.TabUpdateBoundTime(pi.snid, pi.node.ID, nil, &now)
+	}
+	// the round trip time of the latest ping goes to the identity registry,
+	// where it's shared with the dht route table
+	if pong.Seq == pi.ppSeq {
+		if sent := atomic.SwapInt64(&pi.ppSent, 0); sent != 0 {
+			pi.peMgr.idReg.Observe(pi.node.ID, time.Duration(time.Now().UnixNano()-sent))
+		}
+	}
 	return PeMgrEnoNone
 }
 
+// record a peer activated to the identity registry, with the dht address if it
+// announced one
+func (peMgr *PeerManager) idRegActivated(inst *PeerInstance) {
+	peMgr.idReg.SetAddress(identity.StackChain, &inst.node)
+	if inst.dir == PeInstDirInbound && inst.raddr != nil && !inst.raddr.IP.Equal(inst.node.IP) {
+		// the address observed, where the peer might be reached behind a nat
+		observed := inst.node
+		observed.IP = inst.raddr.IP
+		peMgr.idReg.AddEndpoint(identity.StackChain, &observed)
+	}
+	if inst.node.AltIP != nil {
+		alt := inst.node
+		alt.IP, alt.AltIP = inst.node.AltIP, nil
+		peMgr.idReg.AddEndpoint(identity.StackChain, &alt)
+	}
+	if inst.dhtPort != 0 {
+		dht := inst.node
+		dht.TCP = uint16(inst.dhtPort)
+		dht.UDP = 0
+		peMgr.idReg.SetAddress(identity.StackDht, &dht)
+	}
+	peMgr.idReg.Connected(identity.StackChain, inst.node.ID, true)
+}
+
 func (pis peerInstState) compare(s peerInstState) int {
 	// See definition about peerInstState pls.
 	if pis < 0 {
@@ -3408,8 +4062,8 @@ func (pi *PeerInstance) stopRxTx() {
 		if pi.conn != nil {
 			cleanIo()
 		}
-		if pi.txChan != nil {
-			close(pi.txChan)
+		if pi.txQueue != nil {
+			pi.txQueue.close()
 		}
 		if pi.rxDone != nil {
 			pi.rxDone <- PeMgrEnoNone
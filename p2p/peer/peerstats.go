@@ -0,0 +1,167 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Statistics of peers across reconnects and restarts: bytes, connections, the
+// length of sessions and the cause of the last disconnect, by node identity in
+// whatever sub network. they're kept with the known peers, so written to the
+// same file and loaded when restarted, see knownpeers.go, to tell peers flapping
+// or leeching chronically. a connection is counted when the instance activated,
+// the bytes and the length of the session when it's killed, or when the peer
+// manager is powered off.
+//
+
+type PeerStats struct {
+	Id             config.NodeID `json:"id"`
+	TxBytes        int64         `json:"txBytes"`        // payload bytes sent
+	RxBytes        int64         `json:"rxBytes"`        // payload bytes received
+	Connections    int64         `json:"connections"`    // sessions activated
+	Sessions       int64         `json:"sessions"`       // sessions ended
+	SessionTime    time.Duration `json:"sessionTime"`    // total length of sessions ended
+	LastSeen       time.Time     `json:"lastSeen"`       // time activated or ended last
+	LastDisconnect string        `json:"lastDisconnect"` // cause the last session ended for, KillForXXX
+}
+
+// Average length of sessions ended
+func (ps *PeerStats) AvgSession() time.Duration {
+	if ps.Sessions == 0 {
+		return 0
+	}
+	return ps.SessionTime / time.Duration(ps.Sessions)
+}
+
+type peerStats struct {
+	lock sync.Mutex                   // updated in the peer manager task, read by GetPeerStats
+	tab  map[config.NodeID]*PeerStats // statistics by peer
+}
+
+func newPeerStats() *peerStats {
+	return &peerStats{
+		tab: make(map[config.NodeID]*PeerStats, 0),
+	}
+}
+
+func (ps *peerStats) entry(id config.NodeID) *PeerStats {
+	e, ok := ps.tab[id]
+	if !ok {
+		e = &PeerStats{Id: id}
+		ps.tab[id] = e
+	}
+	return e
+}
+
+func (ps *peerStats) activated(id config.NodeID, now time.Time) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	e := ps.entry(id)
+	e.Connections++
+	e.LastSeen = now
+	ps.evict()
+}
+
+func (ps *peerStats) ended(id config.NodeID, tx, rx int64, length time.Duration, cause string, now time.Time) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	e := ps.entry(id)
+	e.TxBytes += tx
+	e.RxBytes += rx
+	e.Sessions++
+	e.SessionTime += length
+	e.LastSeen = now
+	e.LastDisconnect = cause
+	ps.evict()
+}
+
+// the ones seen earliest are evicted, as known peers are
+func (ps *peerStats) evict() {
+	for len(ps.tab) > knownPeersMax {
+		var oldest config.NodeID
+		var seen time.Time
+		for id, e := range ps.tab {
+			if seen.IsZero() || e.LastSeen.Before(seen) {
+				oldest, seen = id, e.LastSeen
+			}
+		}
+		delete(ps.tab, oldest)
+	}
+}
+
+// entries loaded from file, those not seen for long are dropped
+func (ps *peerStats) load(entries []*PeerStats, now time.Time) {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	for _, e := range entries {
+		if now.Sub(e.LastSeen) <= knownPeersMaxAge {
+			ps.tab[e.Id] = e
+		}
+	}
+	ps.evict()
+}
+
+// copies of entries, the ones seen latest first
+func (ps *peerStats) snapshot() []*PeerStats {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	entries := make([]*PeerStats, 0, len(ps.tab))
+	for _, e := range ps.tab {
+		c := *e
+		entries = append(entries, &c)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].LastSeen.After(entries[j].LastSeen)
+	})
+	return entries
+}
+
+// an instance activated, its session starts
+func (peMgr *PeerManager) peerStatsActivated(inst *PeerInstance) {
+	inst.activated = time.Now()
+	peMgr.knownPeers.stats.activated(inst.node.ID, inst.activated)
+	peMgr.knownPeers.changed()
+}
+
+// an instance killed or to be, its session ends for why, a reason of
+// peMgrKillInst or asked to close for
+func (peMgr *PeerManager) peerStatsEnded(inst *PeerInstance, why string) {
+	if inst.activated.IsZero() {
+		return
+	}
+	now := time.Now()
+	tx, rx := atomic.LoadInt64(&inst.txBytes), atomic.LoadInt64(&inst.rxBytes)
+	peMgr.knownPeers.stats.ended(inst.node.ID, tx, rx, now.Sub(inst.activated), killCause(why), now)
+	inst.activated = time.Time{}
+	peMgr.knownPeers.changed()
+}
+
+// Get statistics of peers across reconnects and restarts, the ones seen latest first
+func (peMgr *PeerManager) GetPeerStats() []*PeerStats {
+	return peMgr.knownPeers.stats.snapshot()
+}
@@ -0,0 +1,98 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+func TestPeerStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gyee-peerstats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, config.KnownPeersFileName)
+
+	// a file of peers only, as written before statistics kept
+	old, _ := json.Marshal([]*knownPeer{{
+		Snid:     "1234",
+		Id:       config.NodeID{1},
+		IP:       net.ParseIP("10.0.0.1"),
+		TCP:      30303,
+		LastSeen: time.Now(),
+	}})
+	if err := ioutil.WriteFile(path, old, 0600); err != nil {
+		t.Fatal(err)
+	}
+	peMgr := &PeerManager{knownPeers: newKnownPeers()}
+	if err := peMgr.knownPeers.load(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(peMgr.knownPeers.peers) != 1 {
+		t.Fatalf("peers of old file got %d", len(peMgr.knownPeers.peers))
+	}
+
+	// two sessions, the last one ended by power off
+	id := config.NodeID{7}
+	inst := &PeerInstance{node: config.Node{ID: id}}
+	peMgr.peerStatsActivated(inst)
+	inst.txBytes, inst.rxBytes = 100, 200
+	inst.activated = inst.activated.Add(-time.Minute)
+	peMgr.peerStatsEnded(inst, sch.PEC_FOR_PINGPONG)
+	peMgr.peerStatsEnded(inst, sch.PEC_FOR_RXERROR)
+
+	inst = &PeerInstance{node: config.Node{ID: id}}
+	peMgr.peerStatsActivated(inst)
+	inst.txBytes, inst.rxBytes = 1, 2
+	inst.activated = inst.activated.Add(-time.Minute * 3)
+	peMgr.peerStatsEnded(inst, sch.PEC_FOR_COMMAND)
+	if err := peMgr.knownPeers.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// reloaded as restarted
+	peMgr = &PeerManager{knownPeers: newKnownPeers()}
+	if err := peMgr.knownPeers.load(path); err != nil {
+		t.Fatal(err)
+	}
+	stats := peMgr.GetPeerStats()
+	if len(stats) != 1 || len(peMgr.knownPeers.peers) != 1 {
+		t.Fatalf("reloaded got %d stats, %d peers", len(stats), len(peMgr.knownPeers.peers))
+	}
+	ps := stats[0]
+	if ps.Id != id || ps.TxBytes != 101 || ps.RxBytes != 202 || ps.Connections != 2 || ps.Sessions != 2 ||
+		ps.LastDisconnect != KillForCommand {
+		t.Errorf("stats got %+v", *ps)
+	}
+	if avg := ps.AvgSession(); avg < time.Minute*2 || avg > time.Minute*2+time.Second {
+		t.Errorf("average session got %s", avg)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"sync"
+	"unicode"
+)
+
+//
+// Client versions of peers: each node announces its client version string in
+// the "Extra" field of handshake, and active peers are counted by the versions
+// they announced, so the upgrade rollout across the network can be tracked.
+//
+const (
+	ClientVersionUnknown = "unknown" // for peers announcing nothing, older ones
+	maxClientVersionLen  = 128       // longer versions announced are truncated
+)
+
+type peerVersions struct {
+	lock sync.Mutex     // updated by peer manager, read by users
+	tab  map[string]int // number of active peers by client version
+}
+
+func newPeerVersions() *peerVersions {
+	return &peerVersions{
+		tab: make(map[string]int, 0),
+	}
+}
+
+func (pv *peerVersions) update(ver string, delta int) {
+	pv.lock.Lock()
+	defer pv.lock.Unlock()
+	if pv.tab[ver] += delta; pv.tab[ver] <= 0 {
+		delete(pv.tab, ver)
+	}
+}
+
+func (pv *peerVersions) snapshot() map[string]int {
+	pv.lock.Lock()
+	defer pv.lock.Unlock()
+	vers := make(map[string]int, len(pv.tab))
+	for v, n := range pv.tab {
+		vers[v] = n
+	}
+	return vers
+}
+
+//
+// Get client version from what a peer announced, it's truncated and the
+// characters not printable are replaced, since it's logged and reported.
+//
+func clientVersionOf(extra []byte) string {
+	if len(extra) == 0 {
+		return ClientVersionUnknown
+	}
+	if len(extra) > maxClientVersionLen {
+		extra = extra[0:maxClientVersionLen]
+	}
+	ver := []rune(string(extra))
+	for i, r := range ver {
+		if !unicode.IsPrint(r) {
+			ver[i] = '?'
+		}
+	}
+	return string(ver)
+}
+
+// Get number of active peers by client version
+func (peMgr *PeerManager) GetPeerVersions() map[string]int {
+	return peMgr.peerVersions.snapshot()
+}
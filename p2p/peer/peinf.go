@@ -21,6 +21,8 @@
 package peer
 
 import (
+	"time"
+
 	sch "github.com/yeeco/gyee/p2p/scheduler"
 )
 
@@ -49,6 +51,8 @@ type P2pPackage2Peer struct {
 	Key           []byte         // message key
 	PayloadLength int            // payload length
 	Payload       []byte         // payload
+	TTL           time.Duration  // dropped if still not sent TTL after queued, 0 for never
+	Prio          int            // priority class to be sent in, see TxPrioXXX
 	Extra         interface{}    // extra info: user this field to tell p2p more about this message,
 	// for example, if broadcasting is wanted, then set IdList to nil
 	// and setup thie extra info field.
@@ -98,6 +102,7 @@ type P2pIndPeerClosedPara struct {
 	Snid    SubNetworkID   // sub network identity
 	PeerId  PeerId         // peer identity
 	Dir     int            // direction
+	Why     string         // cause closed for, see KillForXXX
 }
 
 type P2pIndCallback func(what int, para interface{}, userData interface{}) interface{}
@@ -0,0 +1,90 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"bytes"
+	"sort"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Protocol negotiation: the protocol table of a node lists the versions it
+// supports, a protocol identity might be listed more than once for versions.
+// when handshaked, each side intersects the tables, and takes the highest
+// version both support for each protocol identity. since the intersection is
+// symmetric, both sides agree on the same set without any more messages.
+//
+
+// protocols agreed with a peer, ordered by protocol identity
+func negotiateProtocols(local []Protocol, remote []Protocol) []Protocol {
+	best := make(map[uint32][4]byte, 0)
+	for _, l := range local {
+		for _, r := range remote {
+			if l.Pid != r.Pid || l.Ver != r.Ver {
+				continue
+			}
+			if ver, ok := best[l.Pid]; !ok || bytes.Compare(l.Ver[:], ver[:]) > 0 {
+				best[l.Pid] = l.Ver
+			}
+		}
+	}
+	agreed := make([]Protocol, 0, len(best))
+	for pid, ver := range best {
+		agreed = append(agreed, Protocol{Pid: pid, Ver: ver})
+	}
+	sort.Slice(agreed, func(i, j int) bool {
+		return agreed[i].Pid < agreed[j].Pid
+	})
+	return agreed
+}
+
+// Version of protocol pid agreed with the peer, false if none
+func (hs *Handshake) ProtocolVersion(pid uint32) ([4]byte, bool) {
+	for _, p := range hs.Negotiated {
+		if p.Pid == pid {
+			return p.Ver, true
+		}
+	}
+	return [4]byte{}, false
+}
+
+// Version of protocol pid agreed with the peer, false if none
+func (pi *PeerInfo) ProtocolVersion(pid uint32) ([4]byte, bool) {
+	return (*Handshake)(pi).ProtocolVersion(pid)
+}
+
+// if the p2p protocol in the table agreed is ver or higher
+func p2pAgreed(agreed []Protocol, ver [4]byte) bool {
+	for _, p := range agreed {
+		if p.Pid == uint32(PID_P2P) {
+			return bytes.Compare(p.Ver[:], ver[:]) >= 0
+		}
+	}
+	return false
+}
+
+// if the p2p protocol agreed with the peer is config.P2pProtoVerExt or higher,
+// the peer understands the messages added since, see MID_RXDROP
+func (pi *PeerInstance) p2pExtended() bool {
+	return p2pAgreed(pi.negotiated, config.P2pProtoVerExt)
+}
@@ -0,0 +1,61 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNegotiateProtocols(t *testing.T) {
+	local := []Protocol{
+		{Pid: 1, Ver: [4]byte{1, 0, 0, 0}},
+		{Pid: 1, Ver: [4]byte{1, 2, 0, 0}},
+		{Pid: 1, Ver: [4]byte{2, 0, 0, 0}},
+		{Pid: 0, Ver: [4]byte{0, 1, 0, 0}},
+		{Pid: 5, Ver: [4]byte{1, 0, 0, 0}},
+	}
+	remote := []Protocol{
+		{Pid: 0, Ver: [4]byte{0, 1, 0, 0}},
+		{Pid: 1, Ver: [4]byte{1, 2, 0, 0}},
+		{Pid: 1, Ver: [4]byte{1, 0, 0, 0}},
+		{Pid: 1, Ver: [4]byte{3, 0, 0, 0}},
+		{Pid: 5, Ver: [4]byte{2, 0, 0, 0}},
+	}
+	want := []Protocol{
+		{Pid: 0, Ver: [4]byte{0, 1, 0, 0}},
+		{Pid: 1, Ver: [4]byte{1, 2, 0, 0}},
+	}
+	if got := negotiateProtocols(local, remote); !reflect.DeepEqual(got, want) {
+		t.Errorf("negotiated got %v, want %v", got, want)
+	}
+	if got := negotiateProtocols(remote, local); !reflect.DeepEqual(got, want) {
+		t.Errorf("negotiated by peer got %v, want %v", got, want)
+	}
+
+	pi := PeerInfo{Negotiated: want}
+	if ver, ok := pi.ProtocolVersion(1); !ok || ver != want[1].Ver {
+		t.Errorf("version of pid 1 got %v %v", ver, ok)
+	}
+	if _, ok := pi.ProtocolVersion(5); ok {
+		t.Errorf("version of pid 5 agreed")
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+//
+// Protocols registered at runtime: upper modules can register a protocol
+// identity with a handler after the peer manager started. the protocol is
+// appended to the local protocol table, so it's advertised in handshakes with
+// peers connected since then, and negotiated with them, see negotiateProtocols.
+// peers connected before do not know it until they are reconnected. packages
+// of the protocol received are queued by piRx to the worker pool of it, and
+// passed to the handler by the workers, see workers.go. senders should check the
+// version agreed with a peer, see PeerInfo.ProtocolVersion.
+//
+
+// Handler of packages of a protocol registered, it's called by the workers of
+// the pool of the protocol, concurrently if more than one worker configured.
+type ProtocolHandler func(pkg *P2pPackageRx)
+
+type protoHandlers struct {
+	lock sync.Mutex   // for registering
+	tab  atomic.Value // map[uint32]*protoPool, replaced on registering
+}
+
+func newProtoHandlers() *protoHandlers {
+	ph := &protoHandlers{}
+	ph.tab.Store(make(map[uint32]*protoPool, 0))
+	return ph
+}
+
+func (ph *protoHandlers) get(pid uint32) *protoPool {
+	return ph.tab.Load().(map[uint32]*protoPool)[pid]
+}
+
+func (ph *protoHandlers) update(pid uint32, pp *protoPool) {
+	ph.lock.Lock()
+	defer ph.lock.Unlock()
+	old := ph.tab.Load().(map[uint32]*protoPool)
+	tab := make(map[uint32]*protoPool, len(old)+1)
+	for k, v := range old {
+		tab[k] = v
+	}
+	if prev, ok := tab[pid]; ok {
+		prev.stop()
+	}
+	if pp == nil {
+		delete(tab, pid)
+	} else {
+		tab[pid] = pp
+	}
+	ph.tab.Store(tab)
+}
+
+// Register protocol pid of version ver with handler, it's advertised to peers
+// connected since then. PID_P2P and PID_EXT are reserved. packages are handled
+// by a pool of DftProtocolPool, see RegisterProtocolPool.
+func (peMgr *PeerManager) RegisterProtocol(pid uint32, ver [4]byte, handler ProtocolHandler) PeMgrErrno {
+	return peMgr.RegisterProtocolPool(pid, ver, handler, DftProtocolPool)
+}
+
+// Register protocol pid as RegisterProtocol, with packages handled by a worker
+// pool configured by pool.
+func (peMgr *PeerManager) RegisterProtocolPool(pid uint32, ver [4]byte, handler ProtocolHandler, pool ProtocolPool) PeMgrErrno {
+	if pid == uint32(PID_P2P) || pid == uint32(PID_EXT) || handler == nil {
+		peerLog.Debug("RegisterProtocol: invalid parameters, pid: %d", pid)
+		return PeMgrEnoParameter
+	}
+	peMgr.lock.Lock()
+	defer peMgr.lock.Unlock()
+	if peMgr.protoHandlers.get(pid) != nil {
+		peerLog.Debug("RegisterProtocol: duplicated, pid: %d", pid)
+		return PeMgrEnoDuplicated
+	}
+	if len(peMgr.cfg.protocols) >= MaxProtocols {
+		peerLog.Debug("RegisterProtocol: too much protocols, pid: %d", pid)
+		return PeMgrEnoResource
+	}
+
+	// the table might be referred by instances handshaking, it's replaced but
+	// not changed in place.
+	protocols := make([]Protocol, 0, len(peMgr.cfg.protocols)+1)
+	protocols = append(protocols, peMgr.cfg.protocols...)
+	protocols = append(protocols, Protocol{Pid: pid, Ver: ver})
+	peMgr.cfg.protocols = protocols
+	peMgr.cfg.protoNum = uint32(len(protocols))
+	peMgr.protoHandlers.update(pid, newProtoPool(handler, pool))
+	peerLog.Debug("RegisterProtocol: pid: %d, ver: %v, pool: %+v", pid, ver, pool)
+	return PeMgrEnoNone
+}
+
+// Unregister protocol pid, it's not advertised to peers connected since then,
+// and packages of it received are discarded, so are those queued to the pool.
+func (peMgr *PeerManager) UnregisterProtocol(pid uint32) PeMgrErrno {
+	peMgr.lock.Lock()
+	defer peMgr.lock.Unlock()
+	if peMgr.protoHandlers.get(pid) == nil {
+		return PeMgrEnoNotfound
+	}
+	protocols := make([]Protocol, 0, len(peMgr.cfg.protocols))
+	for _, p := range peMgr.cfg.protocols {
+		if p.Pid != pid {
+			protocols = append(protocols, p)
+		}
+	}
+	peMgr.cfg.protocols = protocols
+	peMgr.cfg.protoNum = uint32(len(protocols))
+	peMgr.protoHandlers.update(pid, nil)
+	return PeMgrEnoNone
+}
+
+// local protocol table, for instances to handshake
+func (peMgr *PeerManager) localProtocols() (uint32, []Protocol) {
+	peMgr.lock.Lock()
+	defer peMgr.lock.Unlock()
+	return peMgr.cfg.protoNum, peMgr.cfg.protocols
+}
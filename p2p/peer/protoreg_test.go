@@ -0,0 +1,63 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+)
+
+func TestRegisterProtocol(t *testing.T) {
+	peMgr := &PeerManager{protoHandlers: newProtoHandlers()}
+	peMgr.cfg.protocols = []Protocol{{Pid: uint32(PID_P2P), Ver: [4]byte{0, 1, 0, 0}}}
+	peMgr.cfg.protoNum = 1
+	handler := func(pkg *P2pPackageRx) {}
+
+	if eno := peMgr.RegisterProtocol(uint32(PID_EXT), [4]byte{1}, handler); eno != PeMgrEnoParameter {
+		t.Errorf("register PID_EXT got eno %d", eno)
+	}
+	_, handshaking := peMgr.localProtocols()
+	if eno := peMgr.RegisterProtocol(7, [4]byte{1, 0, 0, 0}, handler); eno != PeMgrEnoNone {
+		t.Fatalf("register got eno %d", eno)
+	}
+	if eno := peMgr.RegisterProtocol(7, [4]byte{2, 0, 0, 0}, handler); eno != PeMgrEnoDuplicated {
+		t.Errorf("register again got eno %d", eno)
+	}
+	num, protocols := peMgr.localProtocols()
+	if num != 2 || len(protocols) != 2 || protocols[1] != (Protocol{Pid: 7, Ver: [4]byte{1, 0, 0, 0}}) {
+		t.Errorf("protocols got %d %v", num, protocols)
+	}
+	if len(handshaking) != 1 {
+		t.Errorf("table referred changed: %v", handshaking)
+	}
+	if peMgr.protoHandlers.get(7) == nil {
+		t.Errorf("handler not found")
+	}
+
+	if eno := peMgr.UnregisterProtocol(7); eno != PeMgrEnoNone {
+		t.Errorf("unregister got eno %d", eno)
+	}
+	if num, protocols = peMgr.localProtocols(); num != 1 || len(protocols) != 1 || peMgr.protoHandlers.get(7) != nil {
+		t.Errorf("protocols after unregistered got %d %v", num, protocols)
+	}
+	if eno := peMgr.UnregisterProtocol(7); eno != PeMgrEnoNotfound {
+		t.Errorf("unregister again got eno %d", eno)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"time"
+)
+
+//
+// Bandwidth limiting of a peer: a token bucket for each direction, filled by
+// rate bytes per second up to a burst of one second. piTx takes tokens for the
+// payload of a data package before sending it, and piRx for a package received
+// before reading the next one, so a peer sending too fast is throttled by the
+// tcp window. tokens can be borrowed, a package larger than the bucket waits
+// till the debt paid, so any package size works with any rate. frames of a large
+// message are limited one by one. pings sent are not limited, not to be timed
+// out by a busy link.
+//
+type rateLimiter struct {
+	rate   float64   // bytes per second
+	burst  float64   // max tokens
+	tokens float64   // tokens available, negative for debt
+	last   time.Time // time tokens updated
+}
+
+// nil for unlimited, a nil limiter never waits
+func newRateLimiter(rate int) *rateLimiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:   float64(rate),
+		burst:  float64(rate),
+		tokens: float64(rate),
+		last:   time.Now(),
+	}
+}
+
+//
+// Take n tokens, returns the time to wait before going on. a limiter is used by
+// one routine only, so it's not locked.
+//
+func (rl *rateLimiter) take(n int, now time.Time) time.Duration {
+	if rl == nil {
+		return 0
+	}
+	if elapsed := now.Sub(rl.last); elapsed > 0 {
+		rl.tokens += elapsed.Seconds() * rl.rate
+		if rl.tokens > rl.burst {
+			rl.tokens = rl.burst
+		}
+	}
+	rl.last = now
+	rl.tokens -= float64(n)
+	if rl.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-rl.tokens / rl.rate * float64(time.Second))
+}
+
+func (rl *rateLimiter) wait(n int) {
+	if d := rl.take(n, time.Now()); d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// wait for the limit of the peer and then the total limit of peers
+func (pi *PeerInstance) piTxWait(size int) {
+	pi.txLimiter.wait(size)
+	pi.peMgr.bwMgr.acquire(pi.snid, size)
+}
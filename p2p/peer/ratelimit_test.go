@@ -0,0 +1,55 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	if rl := newRateLimiter(0); rl != nil || rl.take(1<<20, time.Now()) != 0 {
+		t.Fatal("unlimited limiter waits")
+	}
+
+	rl := newRateLimiter(1000)
+	now := rl.last
+	// burst of one second
+	if d := rl.take(1000, now); d != 0 {
+		t.Errorf("take() within burst got %v", d)
+	}
+	if d := rl.take(500, now); d != 500*time.Millisecond {
+		t.Errorf("take() beyond burst got %v", d)
+	}
+	// debt paid after waited
+	now = now.Add(500 * time.Millisecond)
+	if d := rl.take(100, now); d != 100*time.Millisecond {
+		t.Errorf("take() after debt paid got %v", d)
+	}
+	// refilled up to the burst only
+	now = now.Add(time.Hour)
+	if d := rl.take(1000, now); d != 0 {
+		t.Errorf("take() after idle got %v", d)
+	}
+	if d := rl.take(3000, now); d != 3*time.Second {
+		t.Errorf("take() of large package got %v", d)
+	}
+}
@@ -0,0 +1,236 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Consumption contract of rxChan: the user of an activated peer instance
+// reads rxChan, and when it can't keep up and rxChan is full, the policy
+// configured decides:
+// 1) config.RxqPolicyDrop: the newest package is dropped;
+// 2) config.RxqPolicyBlock: piRx stops reading the connection until the
+// package queued, the tcp window to the peer then closed so it's sending is
+// slowed down, the package is dropped if it's still not queued after
+// rxBlockTime;
+// 3) config.RxqPolicyGrow: the package is kept pending, up to rxGrowMax
+// packages pending, the newest dropped then. those pending are flushed into
+// rxChan in order by piRxGrowProc as soon as it has room, so they're not
+// held on a quiet link.
+// The packages dropped are counted in the statistics, see GetMsgStats, and
+// the peer is noticed with MID_RXDROP at most once in rxDropNoticeInterval if
+// it's extended, see p2pExtended, so it knows it's sending too fast. Notices
+// received are counted as PeerDropped of the messages sent. The peer is not
+// scored down for drops, since they are caused by the local user.
+//
+
+const rxDropNoticeInterval = time.Second // min interval between drop notices to a peer
+
+func (pi *PeerInstance) piRxEnque(pkg *P2pPackageRx) bool {
+	// returns true if the instance is requested to be done while blocked,
+	// see stopRxTx.
+	cfg := &pi.peMgr.cfg
+	switch cfg.rxqPolicy {
+
+	case config.RxqPolicyBlock:
+		if pi.piRxTryEnque(pkg) {
+			return false
+		}
+		dur := cfg.rxBlockTime
+		if dur <= 0 {
+			dur = config.DftRxBlockTimeout
+		}
+		tm := time.NewTimer(dur)
+		defer tm.Stop()
+		select {
+		case pi.rxChan <- pkg:
+			pi.piRxOk()
+			return false
+		case <-pi.rxDone:
+			return true
+		case <-tm.C:
+		}
+
+	case config.RxqPolicyGrow:
+		max := cfg.rxGrowMax
+		if max <= 0 {
+			max = config.DftRxGrowMax
+		}
+		if pi.piRxGrowEnque(pkg, max) {
+			return false
+		}
+
+	default:
+		if pi.piRxTryEnque(pkg) {
+			return false
+		}
+	}
+
+	pi.piRxDrop(pkg)
+	return false
+}
+
+// Packages pending for config.RxqPolicyGrow, flushed by piRxGrowProc
+type rxGrowQueue struct {
+	lock    sync.Mutex      // lock to protect pending
+	pending []*P2pPackageRx // packages pending, the oldest first
+	kick    chan struct{}   // piRxGrowProc kicked for packages pending
+	stop    chan struct{}   // piRxGrowProc asked to exit
+	exit    chan struct{}   // piRxGrowProc exited
+}
+
+func (pi *PeerInstance) piRxGrowEnque(pkg *P2pPackageRx, max int) bool {
+	// the package is queued to rxChan at once only if none pending, so the
+	// order is kept; one being flushed is still pending till it's queued.
+	if pi.rxGrow == nil {
+		pi.rxGrow = &rxGrowQueue{
+			kick: make(chan struct{}, 1),
+			stop: make(chan struct{}),
+			exit: make(chan struct{}),
+		}
+		go pi.piRxGrowProc(pi.rxGrow)
+	}
+	q := pi.rxGrow
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if len(q.pending) == 0 && pi.piRxTryEnque(pkg) {
+		return true
+	}
+	if len(q.pending) >= max {
+		return false
+	}
+	q.pending = append(q.pending, pkg)
+	select {
+	case q.kick <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (pi *PeerInstance) piRxGrowProc(q *rxGrowQueue) {
+	defer close(q.exit)
+	for {
+		var pkg *P2pPackageRx
+		q.lock.Lock()
+		if len(q.pending) > 0 {
+			pkg = q.pending[0]
+		}
+		q.lock.Unlock()
+		if pkg == nil {
+			select {
+			case <-q.kick:
+				continue
+			case <-q.stop:
+				return
+			}
+		}
+		select {
+		case pi.rxChan <- pkg:
+			pi.piRxOk()
+			q.lock.Lock()
+			q.pending[0] = nil
+			q.pending = q.pending[1:]
+			q.lock.Unlock()
+		case <-q.stop:
+			return
+		}
+	}
+}
+
+func (pi *PeerInstance) piRxGrowStop() {
+	// called by piRx before it's done, rxChan is closed then, see stopRxTx.
+	if pi.rxGrow != nil {
+		close(pi.rxGrow.stop)
+		<-pi.rxGrow.exit
+		pi.rxGrow = nil
+	}
+}
+
+func (pi *PeerInstance) piRxTryEnque(pkg *P2pPackageRx) bool {
+	select {
+	case pi.rxChan <- pkg:
+		pi.piRxOk()
+		return true
+	default:
+	}
+	return false
+}
+
+func (pi *PeerInstance) piRxOk() {
+	if cnt := atomic.AddInt64(&pi.rxOkCnt, 1); cnt&0x3ff == 0 {
+		peerLog.Debug("piRx: inst: %s, snid: %x, dir: %d, rxOkCnt: %d",
+			pi.name, pi.snid, pi.dir, cnt)
+	}
+}
+
+func (pi *PeerInstance) piRxDrop(pkg *P2pPackageRx) {
+	peerLog.Debug("piRx: queue full, inst: %s, snid: %x, dir: %d",
+		pi.name, pi.snid, pi.dir)
+	pi.peMgr.msgStats.dropped(uint32(pkg.ProtoId), uint32(pkg.MsgId))
+	if pi.rxDiscard += 1; pi.rxDiscard&0x1f == 0 {
+		peerLog.Debug("piRx: inst: %s, snid: %x, dir: %d, rxDiscard: %d",
+			pi.name, pi.snid, pi.dir, pi.rxDiscard)
+	}
+	pi.rxDropPending++
+	pi.rxDropPid, pi.rxDropMid = uint32(pkg.ProtoId), uint32(pkg.MsgId)
+	pi.piRxDropNotice(time.Now())
+}
+
+func (pi *PeerInstance) piRxDropNotice(now time.Time) {
+	// the notice is queued to ppChan in piRx context, it's not blocked if the
+	// queue is full, the drops are then told in the next notice.
+	if !pi.p2pExtended() || now.Sub(pi.rxDropNoticed) < rxDropNoticeInterval {
+		return
+	}
+	extra := make([]byte, 8)
+	binary.BigEndian.PutUint32(extra[0:], pi.rxDropPid)
+	binary.BigEndian.PutUint32(extra[4:], pi.rxDropMid)
+	upkg := new(P2pPackage)
+	if eno := upkg.rxDrop(&Pingpong{Seq: uint64(pi.rxDropPending), Extra: extra}); eno != PeMgrEnoNone {
+		return
+	}
+	select {
+	case pi.ppChan <- upkg:
+		pi.rxDropPending = 0
+		pi.rxDropNoticed = now
+	default:
+	}
+}
+
+func (pi *PeerInstance) piP2pRxDropProc(notice *Pingpong) PeMgrErrno {
+	if len(notice.Extra) != 8 || notice.Seq == 0 {
+		peerLog.Debug("piP2pRxDropProc: invalid notice, inst: %s", pi.name)
+		return PeMgrEnoMessage
+	}
+	pid := binary.BigEndian.Uint32(notice.Extra[0:])
+	mid := binary.BigEndian.Uint32(notice.Extra[4:])
+	peerLog.Debug("piP2pRxDropProc: dropped by peer, inst: %s, snid: %x, dir: %d, pid: %d, mid: %d, count: %d",
+		pi.name, pi.snid, pi.dir, pid, mid, notice.Seq)
+	pi.peMgr.msgStats.peerDropped(pid, mid, int64(notice.Seq))
+	return PeMgrEnoNone
+}
@@ -0,0 +1,120 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestRxDropNotice(t *testing.T) {
+	peMgr := &PeerManager{msgStats: newMsgStats()}
+	peMgr.cfg.rxqPolicy = config.RxqPolicyDrop
+	pi := &PeerInstance{
+		peMgr:      peMgr,
+		rxChan:     make(chan *P2pPackageRx),
+		ppChan:     make(chan *P2pPackage, 4),
+		negotiated: []Protocol{{Pid: uint32(PID_P2P), Ver: config.P2pProtoVerExt}},
+	}
+	pkg := &P2pPackageRx{ProtoId: int(PID_EXT), MsgId: int(MID_TX)}
+
+	// the first drop noticed at once, the next ones in the interval are held
+	pi.piRxEnque(pkg)
+	pi.piRxEnque(pkg)
+	pi.piRxEnque(pkg)
+	if len(pi.ppChan) != 1 || pi.rxDropPending != 2 {
+		t.Fatalf("notices: %d, pending: %d", len(pi.ppChan), pi.rxDropPending)
+	}
+	upkg := <-pi.ppChan
+	msg := P2pMessage{}
+	if eno := upkg.GetMessage(&msg); eno != PeMgrEnoNone || msg.Mid != uint32(MID_RXDROP) || msg.RxDrop.Seq != 1 {
+		t.Fatalf("notice got eno: %d, msg: %+v", eno, msg)
+	}
+	pi.piRxDropNotice(pi.rxDropNoticed.Add(rxDropNoticeInterval))
+	if len(pi.ppChan) != 1 || pi.rxDropPending != 0 {
+		t.Fatalf("notices: %d, pending: %d", len(pi.ppChan), pi.rxDropPending)
+	}
+
+	// the peer counts drops noticed against the messages it sent
+	sender := &PeerInstance{peMgr: &PeerManager{msgStats: newMsgStats()}}
+	if eno := sender.piP2pRxDropProc(msg.RxDrop); eno != PeMgrEnoNone {
+		t.Fatalf("piP2pRxDropProc failed, eno: %d", eno)
+	}
+	for len(pi.ppChan) > 0 {
+		upkg = <-pi.ppChan
+		upkg.GetMessage(&msg)
+		if eno := sender.piP2pRxDropProc(msg.RxDrop); eno != PeMgrEnoNone {
+			t.Fatalf("piP2pRxDropProc failed, eno: %d", eno)
+		}
+	}
+	stats := sender.peMgr.GetMsgStats()
+	if len(stats) != 1 || stats[0].Dir != MsgStatTx || stats[0].Mid != uint32(MID_TX) || stats[0].PeerDropped != 3 {
+		t.Errorf("stats got %+v", stats)
+	}
+	if eno := sender.piP2pRxDropProc(&Pingpong{Seq: 1}); eno != PeMgrEnoMessage {
+		t.Errorf("invalid notice got eno: %d", eno)
+	}
+
+	// a peer agreed on the base version only is not noticed
+	pi.negotiated = []Protocol{{Pid: uint32(PID_P2P), Ver: config.P2pProtoVerBase}}
+	pi.rxDropNoticed = time.Time{}
+	pi.piRxEnque(pkg)
+	if len(pi.ppChan) != 0 {
+		t.Errorf("noticed to a peer not extended")
+	}
+}
+
+func TestRxGrowFlush(t *testing.T) {
+	peMgr := &PeerManager{msgStats: newMsgStats()}
+	peMgr.cfg.rxqPolicy = config.RxqPolicyGrow
+	peMgr.cfg.rxGrowMax = 2
+	pi := &PeerInstance{
+		peMgr:  peMgr,
+		rxChan: make(chan *P2pPackageRx, 1),
+		ppChan: make(chan *P2pPackage, 4),
+	}
+
+	// one queued, two pending, the one beyond rxGrowMax dropped
+	for mid := 0; mid < 4; mid++ {
+		pi.piRxEnque(&P2pPackageRx{ProtoId: int(PID_EXT), MsgId: mid})
+	}
+	if pi.rxDiscard != 1 {
+		t.Fatalf("rxDiscard: %d", pi.rxDiscard)
+	}
+
+	// those pending flushed in order with no more packages coming
+	for mid := 0; mid < 3; mid++ {
+		select {
+		case pkg := <-pi.rxChan:
+			if pkg.MsgId != mid {
+				t.Fatalf("got mid: %d, expected: %d", pkg.MsgId, mid)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("mid %d not flushed", mid)
+		}
+	}
+	pi.piRxGrowStop()
+	if pi.rxGrow != nil || pi.rxOkCnt != 3 {
+		t.Fatalf("rxGrow: %p, rxOkCnt: %d", pi.rxGrow, pi.rxOkCnt)
+	}
+}
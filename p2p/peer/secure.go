@@ -0,0 +1,187 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+
+	ggio "github.com/gogo/protobuf/io"
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Encryption of tcp peer connections: each side sends its policy for the sub
+// network in handshake, see config.PeerEnc*. when neither is config.PeerEncNone,
+// the connection is upgraded to tls after the handshake, the outbound side as
+// the client. the certificate is self-signed by the key of the sub network, and
+// the key presented by the peer is checked against the node identity in the
+// handshake, so the channel is bound to the peer the handshake authenticated.
+//
+
+const nodeCertLifetime = 24 * time.Hour * 365 * 10 // lifetime of node certificates
+
+// Certificates by sub network, built when first used
+type tlsCerts struct {
+	lock  sync.Mutex                        // lock to protect certs
+	certs map[SubNetworkID]*tls.Certificate // certificate by sub network
+}
+
+func newTlsCerts() *tlsCerts {
+	return &tlsCerts{
+		certs: make(map[SubNetworkID]*tls.Certificate, 0),
+	}
+}
+
+func (tc *tlsCerts) get(snid SubNetworkID, key *ecdsa.PrivateKey) (*tls.Certificate, error) {
+	tc.lock.Lock()
+	defer tc.lock.Unlock()
+	if cert, ok := tc.certs[snid]; ok {
+		return cert, nil
+	}
+	cert, err := newNodeCertificate(key)
+	if err != nil {
+		return nil, err
+	}
+	tc.certs[snid] = &cert
+	return &cert, nil
+}
+
+// Certificate self-signed by a node key
+func newNodeCertificate(key *ecdsa.PrivateKey) (tls.Certificate, error) {
+	if key == nil || key.Curve != elliptic.P256() {
+		return tls.Certificate{}, errors.New("newNodeCertificate: p256 key expected")
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(now.UnixNano()),
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(nodeCertLifetime),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+// Check the certificate presented by a peer is one self-signed by a node key,
+// the key returned.
+func verifyNodeCertificate(certs [][]byte) (*ecdsa.PublicKey, error) {
+	if len(certs) != 1 {
+		return nil, errors.New("verifyNodeCertificate: one certificate expected")
+	}
+	c, err := x509.ParseCertificate(certs[0])
+	if err != nil {
+		return nil, err
+	}
+	pub, ok := c.PublicKey.(*ecdsa.PublicKey)
+	if !ok || pub.Curve != elliptic.P256() {
+		return nil, errors.New("verifyNodeCertificate: p256 key expected")
+	}
+	if err := c.CheckSignature(c.SignatureAlgorithm, c.RawTBSCertificate, c.Signature); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// Encryption policy of a sub network
+func (peMgr *PeerManager) encryptionPolicy(snid SubNetworkID) uint32 {
+	if enc, ok := peMgr.cfg.subNetEncryption[snid]; ok {
+		return uint32(enc)
+	}
+	return uint32(peMgr.cfg.encryption)
+}
+
+// Returns if the connection should be encrypted, and if both sides agreed
+func negotiateEncryption(local, remote uint32) (encrypt bool, agreed bool) {
+	if local == config.PeerEncNone || remote == config.PeerEncNone {
+		return false, local != config.PeerEncRequired && remote != config.PeerEncRequired
+	}
+	return true, true
+}
+
+// Upgrade the connection to tls if negotiated, called when handshake messages
+// are exchanged.
+func (pi *PeerInstance) piSecure(hs *Handshake, local uint32) PeMgrErrno {
+	encrypt, agreed := negotiateEncryption(local, hs.Encryption)
+	if !agreed {
+		peerLog.Debug("piSecure: encryption not agreed, inst: %s, local: %d, remote: %d",
+			pi.name, local, hs.Encryption)
+		return PeMgrEnoMismatched
+	}
+	if !encrypt {
+		return PeMgrEnoNone
+	}
+
+	cert, err := pi.peMgr.tlsCerts.get(pi.snid, &pi.priKey)
+	if err != nil {
+		peerLog.Debug("piSecure: certificate failed, inst: %s, err: %s", pi.name, err.Error())
+		return PeMgrEnoInternal
+	}
+	peerId := hs.NodeId
+	conf := &tls.Config{
+		MinVersion:         tls.VersionTLS13,
+		Certificates:       []tls.Certificate{*cert},
+		ClientAuth:         tls.RequireAnyClientCert,
+		InsecureSkipVerify: true,
+		// no resumption, and no tickets written after the handshake
+		SessionTicketsDisabled: true,
+		VerifyPeerCertificate: func(certs [][]byte, _ [][]*x509.Certificate) error {
+			pub, err := verifyNodeCertificate(certs)
+			if err != nil {
+				return err
+			}
+			if *config.P2pPubkey2NodeId(pub) != peerId {
+				return errors.New("piSecure: certificate mismatched with node identity")
+			}
+			return nil
+		},
+	}
+
+	var tc *tls.Conn
+	if pi.dir == PeInstDirOutbound {
+		tc = tls.Client(pi.conn, conf)
+	} else {
+		tc = tls.Server(pi.conn, conf)
+	}
+	if pi.hto != 0 {
+		tc.SetDeadline(time.Now().Add(pi.hto))
+		defer tc.SetDeadline(time.Time{})
+	}
+	if err := tc.Handshake(); err != nil {
+		peerLog.Debug("piSecure: tls handshake failed, inst: %s, err: %s", pi.name, err.Error())
+		return PeMgrEnoVerify
+	}
+
+	// packages are read and written on the tls connection from now on
+	pi.conn = tc
+	pi.ior = ggio.NewDelimitedReader(tc, pi.maxPkgSize)
+	pi.iow = ggio.NewDelimitedWriter(tc)
+	return PeMgrEnoNone
+}
@@ -0,0 +1,130 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestNegotiateEncryption(t *testing.T) {
+	for _, v := range []struct {
+		local, remote   uint32
+		encrypt, agreed bool
+	}{
+		{config.PeerEncNone, config.PeerEncNone, false, true},
+		{config.PeerEncNone, config.PeerEncOpportunistic, false, true},
+		{config.PeerEncOpportunistic, config.PeerEncOpportunistic, true, true},
+		{config.PeerEncOpportunistic, config.PeerEncRequired, true, true},
+		{config.PeerEncRequired, config.PeerEncNone, false, false},
+		{config.PeerEncNone, config.PeerEncRequired, false, false},
+	} {
+		encrypt, agreed := negotiateEncryption(v.local, v.remote)
+		if encrypt != v.encrypt || agreed != v.agreed {
+			t.Errorf("local %d, remote %d: got %v, %v", v.local, v.remote, encrypt, agreed)
+		}
+	}
+}
+
+func secureTestInst(dir int, key *ecdsa.PrivateKey, conn net.Conn) *PeerInstance {
+	return &PeerInstance{
+		peMgr:      &PeerManager{tlsCerts: newTlsCerts(), hsNonces: newHsNonces()},
+		name:       "test",
+		dir:        dir,
+		priKey:     *key,
+		conn:       conn,
+		hto:        time.Second * 5,
+		maxPkgSize: maxTcpmsgSize,
+	}
+}
+
+// Connections over loopback, not a pipe, since tls writes a flight of records
+// and the peer might write an alert before reading them all.
+func secureTestConns(t *testing.T) (net.Conn, net.Conn) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	a, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a, b
+}
+
+// Upgrade both sides, the outbound one handshaked with peerId claimed by the
+// inbound one, returns results of the outbound and the inbound.
+func secureTestPair(t *testing.T, obKey, ibKey *ecdsa.PrivateKey, peerId config.NodeID) (*PeerInstance, *PeerInstance, PeMgrErrno, PeMgrErrno) {
+	a, b := secureTestConns(t)
+	ob := secureTestInst(PeInstDirOutbound, obKey, a)
+	ib := secureTestInst(PeInstDirInbound, ibKey, b)
+	ibEno := make(chan PeMgrErrno, 1)
+	go func() {
+		hs := &Handshake{NodeId: *config.P2pPubkey2NodeId(&obKey.PublicKey), Encryption: config.PeerEncRequired}
+		eno := ib.piSecure(hs, config.PeerEncOpportunistic)
+		if eno != PeMgrEnoNone {
+			b.Close()
+		}
+		ibEno <- eno
+	}()
+	hs := &Handshake{NodeId: peerId, Encryption: config.PeerEncOpportunistic}
+	obEno := ob.piSecure(hs, config.PeerEncRequired)
+	if obEno != PeMgrEnoNone {
+		a.Close()
+	}
+	return ob, ib, obEno, <-ibEno
+}
+
+func TestSecureConnection(t *testing.T) {
+	obKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ibKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	ob, ib, obEno, ibEno := secureTestPair(t, obKey, ibKey, *config.P2pPubkey2NodeId(&ibKey.PublicKey))
+	if obEno != PeMgrEnoNone || ibEno != PeMgrEnoNone {
+		t.Fatalf("upgrade failed, outbound: %d, inbound: %d", obEno, ibEno)
+	}
+	defer ob.conn.Close()
+	defer ib.conn.Close()
+	hs := &Handshake{NodeId: *config.P2pPubkey2NodeId(&obKey.PublicKey), IP: net.ParseIP("10.0.0.1"), TCP: 30303}
+	go new(P2pPackage).putHandshakeOutbound(ob, hs)
+	got, eno := new(P2pPackage).getHandshakeInbound(ib)
+	if eno != PeMgrEnoNone || got.NodeId != hs.NodeId || got.TCP != hs.TCP {
+		t.Fatalf("read on tls got %+v, eno: %d", got, eno)
+	}
+
+	// the inbound one presented a key other than the identity handshaked
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, _, obEno, _ = secureTestPair(t, obKey, ibKey, *config.P2pPubkey2NodeId(&other.PublicKey))
+	if obEno != PeMgrEnoVerify {
+		t.Fatalf("upgrade with identity mismatched, eno: %d", obEno)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+// a task taking the place of the peer manager, which hands messages sent to
+// it over a channel, so handlers can be driven by tests one by one
+type testTask struct {
+	msgs chan *sch.SchMessage
+}
+
+func (tt *testTask) TaskProc4Scheduler(ptn interface{}, msg *sch.SchMessage) sch.SchErrno {
+	m := *msg
+	tt.msgs <- &m
+	return sch.SchEnoNone
+}
+
+func (tt *testTask) wait(t *testing.T, id int) *sch.SchMessage {
+	for {
+		select {
+		case msg := <-tt.msgs:
+			if msg.Id == id {
+				return msg
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("event %d not received", id)
+			return nil
+		}
+	}
+}
+
+func newTestPeMgr(t *testing.T) (*PeerManager, *testTask) {
+	sdl, eno := sch.SchSchedulerInit(config.P2pDefaultConfig(nil))
+	if eno != sch.SchEnoNone {
+		t.Fatalf("SchSchedulerInit failed, eno: %d", eno)
+	}
+	tt := &testTask{msgs: make(chan *sch.SchMessage, 16)}
+	eno, ptn := sdl.SchCreateTask(&sch.SchTaskDescription{
+		Name:   "testPeMgr",
+		MbSize: 16,
+		Ep:     tt,
+		Wd:     &sch.SchWatchDog{HaveDog: false},
+		Flag:   sch.SchCreatedGo,
+	})
+	if eno != sch.SchEnoNone {
+		t.Fatalf("SchCreateTask failed, eno: %d", eno)
+	}
+	peMgr := &PeerManager{
+		sdl:      sdl,
+		ptnMe:    ptn,
+		workers:  make(map[SubNetworkID]map[PeerIdEx]*PeerInstance, 0),
+		shedTids: make(map[string]int, 0),
+	}
+	return peMgr, tt
+}
+
+func TestSeedShed(t *testing.T) {
+	peMgr, tt := newTestPeMgr(t)
+	peMgr.cfg.seedOnly = true
+	peMgr.cfg.seedGraceTime = 20 * time.Millisecond
+
+	snid := SubNetworkID{0x12, 0x34}
+	inst := &PeerInstance{
+		name:  "seedShedTest",
+		ptnMe: peMgr.ptnMe,
+		snid:  snid,
+		node:  config.Node{ID: config.NodeID{1}, IP: net.ParseIP("10.0.0.1"), TCP: 30303},
+		dir:   PeInstDirInbound,
+	}
+	idEx := PeerIdEx{Id: inst.node.ID, Dir: inst.dir}
+	peMgr.workers[snid] = map[PeerIdEx]*PeerInstance{idEx: inst}
+
+	start := time.Now()
+	if eno := peMgr.peMgrSeedShedProtect(inst); eno != PeMgrEnoNone {
+		t.Fatalf("peMgrSeedShedProtect failed, eno: %d", eno)
+	}
+	if len(peMgr.shedTids) != 1 {
+		t.Fatalf("shedTids got %v", peMgr.shedTids)
+	}
+
+	// the instance is kept for the grace time, then the close request goes
+	// the same way as one from the instance
+	msg := tt.wait(t, sch.EvPeSeedShedTimer)
+	if elapsed := time.Since(start); elapsed < peMgr.cfg.seedGraceTime {
+		t.Errorf("shed after %s, grace time %s", elapsed, peMgr.cfg.seedGraceTime)
+	}
+	if eno := peMgr.seedShedTimerHandler(msg.Body); eno != PeMgrEnoNone {
+		t.Fatalf("seedShedTimerHandler failed, eno: %d", eno)
+	}
+	if len(peMgr.shedTids) != 0 {
+		t.Errorf("shedTids left %v", peMgr.shedTids)
+	}
+	req, ok := tt.wait(t, sch.EvPeCloseReq).Body.(*sch.MsgPeCloseReq)
+	if !ok || req.Why != sch.PEC_FOR_SEEDSHED || req.Snid != snid || req.Node.ID != inst.node.ID ||
+		req.Dir != PeInstDirInbound {
+		t.Errorf("close request got %+v", req)
+	}
+
+	// the instance gone before the grace time ends, nothing to shed
+	delete(peMgr.workers[snid], idEx)
+	if eno := peMgr.peMgrSeedShedProtect(inst); eno != PeMgrEnoNone {
+		t.Fatalf("peMgrSeedShedProtect failed, eno: %d", eno)
+	}
+	msg = tt.wait(t, sch.EvPeSeedShedTimer)
+	if eno := peMgr.seedShedTimerHandler(msg.Body); eno != PeMgrEnoNotfound {
+		t.Errorf("seedShedTimerHandler got eno: %d", eno)
+	}
+}
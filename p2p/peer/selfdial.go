@@ -0,0 +1,99 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"net"
+	"sync"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Self dial detection: a node might dial one of its own addresses, learnt back
+// from discovery or configured as a static peer, and comparing node identities
+// alone can't tell behind some nats, where the address is that of another node
+// some time later. each peer manager makes a random instance nonce announced in
+// handshake, a handshake carrying the local one is from ourselves, which both
+// ends of the connection detect. the address dialed is marked as self then, and
+// not taken as a candidate any more.
+//
+
+const (
+	instNonceLen = 16 // random bytes of an instance nonce
+	selfAddrsMax = 64 // max addresses marked as self
+)
+
+func newInstNonce() []byte {
+	nonce := make([]byte, instNonceLen)
+	rand.Read(nonce)
+	return nonce
+}
+
+// Addresses reaching ourselves, by "ip:port"
+type selfAddrs struct {
+	lock  sync.Mutex          // lock to protect addrs
+	addrs map[string]struct{} // addresses marked
+}
+
+func newSelfAddrs() *selfAddrs {
+	return &selfAddrs{
+		addrs: make(map[string]struct{}, 0),
+	}
+}
+
+func (sa *selfAddrs) mark(ip net.IP, port int) {
+	if ip == nil {
+		return
+	}
+	sa.lock.Lock()
+	defer sa.lock.Unlock()
+	if len(sa.addrs) < selfAddrsMax {
+		sa.addrs[(&net.TCPAddr{IP: ip, Port: port}).String()] = struct{}{}
+	}
+}
+
+func (sa *selfAddrs) self(ip net.IP, port int) bool {
+	if ip == nil {
+		return false
+	}
+	sa.lock.Lock()
+	defer sa.lock.Unlock()
+	_, ok := sa.addrs[(&net.TCPAddr{IP: ip, Port: port}).String()]
+	return ok
+}
+
+func (peMgr *PeerManager) selfHandshake(hs *Handshake) bool {
+	return len(hs.InstNonce) != 0 && bytes.Equal(hs.InstNonce, peMgr.instNonce)
+}
+
+// Mark the addresses of the outbound instance found dialing ourselves: that of
+// the node dialed, and that connected to if it's another endpoint.
+func (peMgr *PeerManager) selfDialed(inst *PeerInstance, node *config.Node) {
+	peerLog.ForceDebug("selfDialed: marked as self, snid: %x, ip: %s, port: %d",
+		inst.snid, node.IP.String(), node.TCP)
+	peMgr.selfAddrs.mark(node.IP, int(node.TCP))
+	if inst.raddr != nil {
+		peMgr.selfAddrs.mark(inst.raddr.IP, inst.raddr.Port)
+	}
+}
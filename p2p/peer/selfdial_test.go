@@ -0,0 +1,94 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"testing"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestSelfDial(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	snid := SubNetworkID{0x12, 0x34}
+	local := config.Node{ID: *config.P2pPubkey2NodeId(&key.PublicKey), IP: net.ParseIP("10.0.0.1"), TCP: 30303}
+	peMgr := &PeerManager{
+		hsNonces:  newHsNonces(),
+		selfProbe: newSelfProbe(),
+		instNonce: newInstNonce(),
+		selfAddrs: newSelfAddrs(),
+	}
+	peMgr.cfg.subNetKeyList = map[SubNetworkID]ecdsa.PrivateKey{snid: *key}
+	peMgr.cfg.subNetNodeList = map[SubNetworkID]config.Node{snid: local}
+
+	// the public address dialed is known with the identity of another node,
+	// as it was behind the nat some time ago
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	public := &net.TCPAddr{IP: net.ParseIP("203.0.113.1"), Port: 30303}
+	ob := &PeerInstance{
+		peMgr:      peMgr,
+		dir:        PeInstDirOutbound,
+		snid:       snid,
+		priKey:     *key,
+		conn:       a,
+		maxPkgSize: maxTcpmsgSize,
+		node:       config.Node{ID: config.NodeID{9}, IP: public.IP, TCP: uint16(public.Port)},
+		localNode:  local,
+		raddr:      public,
+	}
+	ib := &PeerInstance{
+		peMgr:      peMgr,
+		dir:        PeInstDirInbound,
+		conn:       b,
+		maxPkgSize: maxTcpmsgSize,
+		raddr:      &net.TCPAddr{IP: net.ParseIP("10.0.0.254"), Port: 40000},
+	}
+	done := make(chan PeMgrErrno, 1)
+	go func() {
+		done <- ib.piHandshakeInbound(ib)
+	}()
+	if eno := ob.piHandshakeOutbound(ob); eno != PeMgrEnoSelf {
+		t.Fatalf("outbound side eno: %d, want self", eno)
+	}
+	if eno := <-done; eno != PeMgrEnoSelf {
+		t.Fatalf("inbound side eno: %d, want self", eno)
+	}
+
+	peMgr.selfDialed(ob, &ob.node)
+	if !peMgr.selfAddrs.self(public.IP, public.Port) {
+		t.Errorf("address dialed not marked as self")
+	}
+	if peMgr.selfAddrs.self(public.IP, public.Port+1) {
+		t.Errorf("another port marked as self")
+	}
+
+	// another instance is not ourselves
+	other := &PeerManager{instNonce: newInstNonce()}
+	if other.selfHandshake(&Handshake{InstNonce: peMgr.instNonce}) || other.selfHandshake(&Handshake{}) {
+		t.Errorf("handshake of another instance taken as self")
+	}
+}
@@ -0,0 +1,468 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	"github.com/yeeco/gyee/p2p/discover/udpmux"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+//
+// Self probe: some seconds after started, and then periodically, the node asks a
+// few active peers to probe the tcp and udp endpoints it advertises. a peer dials
+// the tcp endpoint, sends a datagram tagged UdpMuxProtoProbe with the nonce of the
+// round to the udp endpoint, and tells the tcp result in the response. results are
+// kept by address family, and gate whether the node advertises itself dialable,
+// the tcp port in handshakes of outbound instances is zero if not, and whether it
+// relays messages from peers. a peer probes only the ip it sees the connection
+// from, and once in selfProbeMinInterval for an instance, so it can't be used to
+// probe a third party. before any peer answered, the node is taken as reachable.
+//
+
+const (
+	SelfProbePeers       = 3                // max peers asked in a round
+	selfProbeDelay       = 30 * time.Second // delay of the first round, for peers to be connected
+	selfProbeRetry       = time.Minute      // delay of the next round if no peers can be asked
+	selfProbeWait        = 5 * time.Second  // responses and datagrams not received in it are lost
+	selfProbeDialTimeout = 3 * time.Second  // timeout for a peer to dial the tcp endpoint
+	selfProbeMinInterval = time.Minute      // min interval a peer probes for an instance
+)
+
+// Extra of a probe request: flags, the tcp port and the udp port to be probed
+const (
+	probeReqSize = 5
+	probeFlagUdp = byte(1) // the udp endpoint is asked to be probed
+)
+
+// Extra of a probe response: result of the tcp probe
+const (
+	probeRefused   = byte(0) // not probed, address mismatched or probed too often
+	probeTcpOk     = byte(1) // tcp endpoint connected
+	probeTcpFailed = byte(2) // tcp endpoint not connected
+)
+
+// Reachability of the endpoints in an address family
+type Reachability struct {
+	Family    string    // "ip4" or "ip6"
+	Asked     int       // peers asked to probe
+	Answered  int       // peers probed the tcp endpoint
+	TcpOk     bool      // tcp endpoint reached by any peer
+	UdpProbed bool      // udp endpoint asked to be probed
+	UdpOk     bool      // datagram received from any peer
+	Time      time.Time // time the round finished
+}
+
+// Status of the self probe
+type SelfProbeStatus struct {
+	Enabled  bool           // self probe enabled
+	Dialable bool           // advertised dialable
+	Relay    bool           // relaying messages from peers
+	Families []Reachability // reachability by address family, of the latest round answered
+}
+
+type probeRound struct {
+	nonce    uint64                   // nonce of the round
+	asked    map[config.NodeID]string // peers asked, the family of the address probed
+	answered map[config.NodeID]bool   // peers responded
+	families map[string]*Reachability // results by family
+}
+
+type selfProbe struct {
+	tid      int                      // timer of the next round, or the end of the round in progress
+	mux      *udpmux.UdpMux           // socket datagrams received on in a round, nil if udp not probed
+	ip       net.IP                   // local udp address
+	udp      uint16                   // local udp port
+	lock     sync.Mutex               // sync for the round and results, updated by instances and the socket reader
+	round    *probeRound              // round in progress, nil if none
+	results  map[string]*Reachability // results by family
+	dialable bool                     // advertised dialable
+	relay    bool                     // relaying messages from peers
+}
+
+func newSelfProbe() *selfProbe {
+	return &selfProbe{
+		tid:      sch.SchInvalidTid,
+		results:  make(map[string]*Reachability, 0),
+		dialable: true,
+		relay:    true,
+	}
+}
+
+func ipFamily(ip net.IP) string {
+	if ip.To4() != nil {
+		return "ip4"
+	}
+	return "ip6"
+}
+
+func newProbeRound(nonce uint64) *probeRound {
+	return &probeRound{
+		nonce:    nonce,
+		asked:    make(map[config.NodeID]string, 0),
+		answered: make(map[config.NodeID]bool, 0),
+		families: make(map[string]*Reachability, 0),
+	}
+}
+
+func (pr *probeRound) ask(id config.NodeID, ip net.IP, udp bool) {
+	family := ipFamily(ip)
+	r, ok := pr.families[family]
+	if !ok {
+		r = &Reachability{Family: family}
+		pr.families[family] = r
+	}
+	r.Asked++
+	r.UdpProbed = r.UdpProbed || udp
+	pr.asked[id] = family
+}
+
+// a response from peer id, false if it's not expected
+func (pr *probeRound) respond(id config.NodeID, nonce uint64, result byte) bool {
+	family, ok := pr.asked[id]
+	if !ok || nonce != pr.nonce || pr.answered[id] {
+		return false
+	}
+	pr.answered[id] = true
+	if result == probeTcpOk || result == probeTcpFailed {
+		r := pr.families[family]
+		r.Answered++
+		r.TcpOk = r.TcpOk || result == probeTcpOk
+	}
+	return true
+}
+
+// a datagram received from ip, false if it's not expected
+func (pr *probeRound) received(ip net.IP, nonce uint64) bool {
+	r, ok := pr.families[ipFamily(ip)]
+	if !ok || nonce != pr.nonce || !r.UdpProbed {
+		return false
+	}
+	r.UdpOk = true
+	return true
+}
+
+// results of a round finished are kept for the families answered, the others
+// keep those of rounds before
+func (sp *selfProbe) finish(pr *probeRound, now time.Time) {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+	if sp.round == pr {
+		sp.round = nil
+	}
+	for family, r := range pr.families {
+		if r.Answered > 0 || r.UdpOk {
+			r.Time = now
+			sp.results[family] = r
+		}
+	}
+	if len(sp.results) == 0 {
+		return
+	}
+	sp.dialable, sp.relay = false, false
+	for _, r := range sp.results {
+		if r.TcpOk {
+			sp.dialable = true
+			sp.relay = sp.relay || r.UdpOk || !r.UdpProbed
+		}
+	}
+}
+
+func (sp *selfProbe) respond(id config.NodeID, nonce uint64, result byte) bool {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+	return sp.round != nil && sp.round.respond(id, nonce, result)
+}
+
+func (sp *selfProbe) UdpMuxRecv(buf []byte, from *net.UDPAddr) {
+	if len(buf) != 8 {
+		return
+	}
+	nonce := binary.BigEndian.Uint64(buf)
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+	if sp.round == nil || !sp.round.received(from.IP, nonce) {
+		peerLog.Debug("UdpMuxRecv: not expected, from: %s", from.String())
+	}
+}
+
+func (sp *selfProbe) UdpMuxClosed(eno udpmux.UdpMuxErrno) {
+	peerLog.Debug("UdpMuxClosed: self probe socket closed, eno: %d", eno)
+}
+
+func (sp *selfProbe) status(enabled bool) SelfProbeStatus {
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+	status := SelfProbeStatus{
+		Enabled:  enabled,
+		Dialable: sp.dialable,
+		Relay:    sp.relay,
+		Families: make([]Reachability, 0, len(sp.results)),
+	}
+	for _, r := range sp.results {
+		status.Families = append(status.Families, *r)
+	}
+	sort.Slice(status.Families, func(i, j int) bool {
+		return status.Families[i].Family < status.Families[j].Family
+	})
+	return status
+}
+
+// Get status of the self probe
+func (peMgr *PeerManager) GetSelfProbeStatus() SelfProbeStatus {
+	return peMgr.selfProbe.status(peMgr.cfg.selfProbeInterval > 0)
+}
+
+// If the node advertises itself dialable
+func (peMgr *PeerManager) Dialable() bool {
+	sp := peMgr.selfProbe
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+	return sp.dialable
+}
+
+// If the node relays messages from peers
+func (peMgr *PeerManager) Relayable() bool {
+	sp := peMgr.selfProbe
+	sp.lock.Lock()
+	defer sp.lock.Unlock()
+	return sp.relay
+}
+
+func (peMgr *PeerManager) selfProbeStart() PeMgrErrno {
+	if peMgr.cfg.selfProbeInterval <= 0 {
+		return PeMgrEnoNone
+	}
+	return peMgr.selfProbeTimer(selfProbeDelay)
+}
+
+func (peMgr *PeerManager) selfProbeTimer(dur time.Duration) PeMgrErrno {
+	sp := peMgr.selfProbe
+	td := sch.TimerDescription{
+		Name:  "_selfProbeTimer",
+		Utid:  sch.PeSelfProbeTimerId,
+		Tmt:   sch.SchTmTypeAbsolute,
+		Dur:   dur,
+		Extra: nil,
+	}
+	eno := sch.SchEnoNone
+	if eno, sp.tid = peMgr.sdl.SchSetTimer(peMgr.ptnMe, &td); eno != sch.SchEnoNone {
+		peerLog.Debug("selfProbeTimer: SchSetTimer failed, eno: %d", eno)
+		sp.tid = sch.SchInvalidTid
+		return PeMgrEnoScheduler
+	}
+	return PeMgrEnoNone
+}
+
+func (peMgr *PeerManager) selfProbeTimerHandler() PeMgrErrno {
+	sp := peMgr.selfProbe
+	sp.tid = sch.SchInvalidTid
+	sp.lock.Lock()
+	pr := sp.round
+	sp.lock.Unlock()
+	if pr != nil {
+		peMgr.selfProbeFinish(pr)
+		return peMgr.selfProbeTimer(peMgr.cfg.selfProbeInterval)
+	}
+	if !peMgr.selfProbeRound() {
+		return peMgr.selfProbeTimer(selfProbeRetry)
+	}
+	return peMgr.selfProbeTimer(selfProbeWait)
+}
+
+// start a round, false if no peers can be asked
+func (peMgr *PeerManager) selfProbeRound() bool {
+	sp := peMgr.selfProbe
+	var nb [8]byte
+	if _, err := rand.Read(nb[:]); err != nil {
+		peerLog.Debug("selfProbeRound: rand failed, err: %s", err.Error())
+		return false
+	}
+	pr := newProbeRound(binary.BigEndian.Uint64(nb[:]))
+
+	// datagrams are received on the socket shared with the discovery, which runs
+	// in dynamic networks only
+	udp := false
+	if peMgr.cfg.networkType == config.P2pNetworkTypeDynamic {
+		mux, eno := udpmux.UdpMuxOpen(sp.ip, sp.udp)
+		if eno == udpmux.UdpMuxEnoNone {
+			if eno = mux.Register(udpmux.UdpMuxProtoProbe, sp); eno == udpmux.UdpMuxEnoNone {
+				sp.mux, udp = mux, true
+			} else {
+				mux.Close()
+			}
+		}
+		if !udp {
+			peerLog.Debug("selfProbeRound: udp not probed, eno: %d", eno)
+		}
+	}
+
+	for _, workers := range peMgr.workers {
+		for _, inst := range workers {
+			if len(pr.asked) >= SelfProbePeers {
+				break
+			}
+			if inst.state != peInstStateActivated || inst.localNode.IP.IsUnspecified() {
+				continue
+			}
+			if _, dup := pr.asked[inst.node.ID]; dup {
+				continue
+			}
+			extra := make([]byte, probeReqSize)
+			if udp {
+				extra[0] = probeFlagUdp
+			}
+			binary.BigEndian.PutUint16(extra[1:], inst.localNode.TCP)
+			binary.BigEndian.PutUint16(extra[3:], inst.localNode.UDP)
+			upkg := new(P2pPackage)
+			if eno := upkg.probe(&Pingpong{Seq: pr.nonce, Extra: extra}, false); eno != PeMgrEnoNone {
+				continue
+			}
+			if inst.txQueue.Full(upkg.Prio) {
+				peerLog.Debug("selfProbeRound: tx queue full, inst: %s", inst.name)
+				continue
+			}
+			upkg.Enqueued = time.Now()
+			inst.txQueue.Put(upkg)
+			inst.txPendNum += 1
+			pr.ask(inst.node.ID, inst.localNode.IP, udp)
+		}
+	}
+
+	if len(pr.asked) == 0 {
+		peerLog.Debug("selfProbeRound: no peers to be asked")
+		peMgr.selfProbeClose()
+		return false
+	}
+	sp.lock.Lock()
+	sp.round = pr
+	sp.lock.Unlock()
+	peerLog.Debug("selfProbeRound: peers asked: %d, udp: %t", len(pr.asked), udp)
+	return true
+}
+
+func (peMgr *PeerManager) selfProbeFinish(pr *probeRound) {
+	peMgr.selfProbeClose()
+	sp := peMgr.selfProbe
+	sp.finish(pr, time.Now())
+	for _, r := range sp.status(true).Families {
+		peerLog.Debug("selfProbeFinish: family: %s, asked: %d, answered: %d, tcp: %t, udp: %t",
+			r.Family, r.Asked, r.Answered, r.TcpOk, r.UdpOk)
+	}
+}
+
+func (peMgr *PeerManager) selfProbeClose() {
+	sp := peMgr.selfProbe
+	if sp.mux != nil {
+		sp.mux.Unregister(udpmux.UdpMuxProtoProbe)
+		sp.mux.Close()
+		sp.mux = nil
+	}
+}
+
+// the peer manager stopped, the round in progress is dropped
+func (peMgr *PeerManager) selfProbeStop() {
+	sp := peMgr.selfProbe
+	if sp.tid != sch.SchInvalidTid {
+		peMgr.sdl.SchKillTimer(peMgr.ptnMe, sp.tid)
+		sp.tid = sch.SchInvalidTid
+	}
+	peMgr.selfProbeClose()
+	sp.lock.Lock()
+	sp.round = nil
+	sp.lock.Unlock()
+}
+
+func (pi *PeerInstance) piP2pProbeProc(probe *Pingpong) PeMgrErrno {
+	if pi.state != peInstStateActivated || pi.conn == nil {
+		peerLog.Debug("piP2pProbeProc: discarded, inst: %s, state: %d", pi.name, pi.state)
+		return PeMgrEnoResource
+	}
+	now := time.Now()
+	if len(probe.Extra) < probeReqSize || pi.raddr == nil || !pi.raddr.IP.Equal(pi.node.IP) ||
+		now.Sub(pi.probedAt) < selfProbeMinInterval {
+		return pi.piP2pProbeRsp(probe.Seq, probeRefused)
+	}
+	pi.probedAt = now
+	udp := uint16(0)
+	if probe.Extra[0]&probeFlagUdp != 0 {
+		udp = binary.BigEndian.Uint16(probe.Extra[3:])
+	}
+	go pi.probeEndpoints(probe.Seq, binary.BigEndian.Uint16(probe.Extra[1:]), udp)
+	return PeMgrEnoNone
+}
+
+// probe endpoints of the peer, it's not run in piRx since the dialing blocks
+func (pi *PeerInstance) probeEndpoints(nonce uint64, tcp uint16, udp uint16) {
+	result := probeTcpFailed
+	tcpAddr := &net.TCPAddr{IP: pi.node.IP, Port: int(tcp)}
+	if conn, err := net.DialTimeout("tcp", tcpAddr.String(), selfProbeDialTimeout); err == nil {
+		conn.Close()
+		result = probeTcpOk
+	}
+	if udp != 0 {
+		udpAddr := &net.UDPAddr{IP: pi.node.IP, Port: int(udp)}
+		if conn, err := net.DialUDP("udp", nil, udpAddr); err == nil {
+			buf := make([]byte, 9)
+			buf[0] = byte(udpmux.UdpMuxProtoProbe)
+			binary.BigEndian.PutUint64(buf[1:], nonce)
+			conn.Write(buf)
+			conn.Close()
+		}
+	}
+	pi.piP2pProbeRsp(nonce, result)
+}
+
+// responded in ppChan as pong, see piP2pPingProc, it's not blocked if the queue
+// is full since it might be called out of piRx.
+func (pi *PeerInstance) piP2pProbeRsp(nonce uint64, result byte) PeMgrErrno {
+	upkg := new(P2pPackage)
+	if eno := upkg.probe(&Pingpong{Seq: nonce, Extra: []byte{result}}, true); eno != PeMgrEnoNone {
+		peerLog.Debug("piP2pProbeRsp: probe failed, inst: %s, eno: %d", pi.name, eno)
+		return eno
+	}
+	select {
+	case pi.ppChan <- upkg:
+		return PeMgrEnoNone
+	default:
+		peerLog.Debug("piP2pProbeRsp: queue full, inst: %s, dir: %d", pi.name, pi.dir)
+		return PeMgrEnoResource
+	}
+}
+
+func (pi *PeerInstance) piP2pProbeRspProc(probeRsp *Pingpong) PeMgrErrno {
+	result := probeRefused
+	if len(probeRsp.Extra) > 0 {
+		result = probeRsp.Extra[0]
+	}
+	if !pi.peMgr.selfProbe.respond(pi.node.ID, probeRsp.Seq, result) {
+		peerLog.Debug("piP2pProbeRspProc: not expected, inst: %s", pi.name)
+		return PeMgrEnoMismatched
+	}
+	return PeMgrEnoNone
+}
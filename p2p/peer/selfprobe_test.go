@@ -0,0 +1,84 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestSelfProbe(t *testing.T) {
+	ip4, ip6 := net.ParseIP("10.0.0.1"), net.ParseIP("2001:db8::1")
+	sp := newSelfProbe()
+	if !sp.dialable || !sp.relay {
+		t.Fatalf("not reachable before probed")
+	}
+
+	// no peers answered, nothing changed
+	pr := newProbeRound(7)
+	pr.ask(config.NodeID{1}, ip4, true)
+	sp.finish(pr, time.Now())
+	if !sp.dialable || !sp.relay || len(sp.results) != 0 {
+		t.Errorf("changed without answers, dialable: %t, relay: %t", sp.dialable, sp.relay)
+	}
+
+	// tcp reached in ip4 by one of the peers, udp not
+	pr = newProbeRound(8)
+	pr.ask(config.NodeID{1}, ip4, true)
+	pr.ask(config.NodeID{2}, ip4, true)
+	pr.ask(config.NodeID{3}, ip6, true)
+	if pr.respond(config.NodeID{1}, 7, probeTcpOk) || pr.respond(config.NodeID{4}, 8, probeTcpOk) {
+		t.Errorf("unexpected response accepted")
+	}
+	if !pr.respond(config.NodeID{1}, 8, probeTcpFailed) || !pr.respond(config.NodeID{2}, 8, probeTcpOk) ||
+		!pr.respond(config.NodeID{3}, 8, probeRefused) {
+		t.Errorf("response not accepted")
+	}
+	if pr.respond(config.NodeID{2}, 8, probeTcpFailed) {
+		t.Errorf("duplicated response accepted")
+	}
+	sp.finish(pr, time.Now())
+	status := sp.status(true)
+	if !status.Dialable || status.Relay || len(status.Families) != 1 {
+		t.Fatalf("status got %+v", status)
+	}
+	if r := status.Families[0]; r.Family != "ip4" || r.Asked != 2 || r.Answered != 2 || !r.TcpOk || r.UdpOk {
+		t.Errorf("ip4 got %+v", r)
+	}
+
+	// udp reached in ip4, tcp in neither
+	pr = newProbeRound(9)
+	pr.ask(config.NodeID{1}, ip4, true)
+	pr.ask(config.NodeID{3}, ip6, false)
+	if pr.received(ip4, 8) || pr.received(ip6, 9) || !pr.received(ip4, 9) {
+		t.Errorf("datagram checked wrongly")
+	}
+	pr.respond(config.NodeID{1}, 9, probeTcpFailed)
+	pr.respond(config.NodeID{3}, 9, probeTcpFailed)
+	sp.finish(pr, time.Now())
+	status = sp.status(true)
+	if status.Dialable || status.Relay || len(status.Families) != 2 || status.Families[1].Family != "ip6" {
+		t.Errorf("status got %+v", status)
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+//
+// Adaptive inbound/outbound slots for dynamic sub networks: the total slots
+// (maxInbound + maxOutbound) of a sub network is kept, one slot is moved each
+// period:
+// 1) inbound scarce while outbounds are full, the local is likely behind a nat
+// and can hardly be dialed, so move a slot to outbound;
+// 2) inbounds are full, the local is a well-known public endpoint, so move a
+// slot to inbound;
+// the outbound slots are always kept within [slotOutMin, slotOutMax] percent.
+//
+
+const (
+	slotAdaptPeriod    = time.Minute * 2 // period to check the slots
+	slotScarceInbounds = 4               // inbound is scarce if less than 1/4 of it's slots used
+)
+
+func (peMgr *PeerManager) slotAdaptStart() PeMgrErrno {
+	if !peMgr.cfg.adaptiveSlots ||
+		peMgr.cfg.networkType != config.P2pNetworkTypeDynamic ||
+		peMgr.cfg.noDial || peMgr.cfg.noAccept || peMgr.cfg.bootstrapNode {
+		return PeMgrEnoNone
+	}
+	if peMgr.cfg.slotOutMin <= 0 || peMgr.cfg.slotOutMin >= 100 {
+		peMgr.cfg.slotOutMin = config.DftSlotOutMin
+	}
+	if peMgr.cfg.slotOutMax <= peMgr.cfg.slotOutMin || peMgr.cfg.slotOutMax >= 100 {
+		peMgr.cfg.slotOutMax = config.DftSlotOutMax
+	}
+
+	td := sch.TimerDescription{
+		Name:  "_slotAdaptTimer",
+		Utid:  sch.PeSlotAdaptTimerId,
+		Tmt:   sch.SchTmTypePeriod,
+		Dur:   slotAdaptPeriod,
+		Extra: nil,
+	}
+	eno := sch.SchEnoNone
+	eno, peMgr.slotTid = peMgr.sdl.SchSetTimer(peMgr.ptnMe, &td)
+	if eno != sch.SchEnoNone || peMgr.slotTid == sch.SchInvalidTid {
+		peerLog.Debug("slotAdaptStart: SchSetTimer failed, eno: %d", eno)
+		return PeMgrEnoScheduler
+	}
+	peerLog.Debug("slotAdaptStart: slotTid start ok")
+	return PeMgrEnoNone
+}
+
+func (peMgr *PeerManager) slotAdaptTimerHandler() PeMgrErrno {
+	if peMgr.reCfgTid != sch.SchInvalidTid {
+		// sub networks are changing, check next time
+		return PeMgrEnoNone
+	}
+
+	changed := false
+	ibpNumTotal := peMgr.cfg.staticMaxInBounds
+	for _, snid := range peMgr.cfg.subNetIdList {
+		maxIn := peMgr.cfg.subNetMaxInBounds[snid]
+		maxOut := peMgr.cfg.subNetMaxOutbounds[snid]
+		total := maxIn + maxOut
+		if total < 2 || maxIn == 0 || maxOut == 0 {
+			ibpNumTotal += maxIn
+			continue
+		}
+
+		lo := total * peMgr.cfg.slotOutMin / 100
+		if lo < 1 {
+			lo = 1
+		}
+		hi := total * peMgr.cfg.slotOutMax / 100
+		if hi > total-1 {
+			hi = total - 1
+		}
+
+		ibp := peMgr.ibpNum[snid]
+		obp := peMgr.obpNum[snid]
+		if ibp*slotScarceInbounds < maxIn && obp >= maxOut && maxOut < hi {
+			maxOut++
+			maxIn--
+			peerLog.Debug("slotAdaptTimerHandler: to outbound, snid: %x, in: %d/%d, out: %d/%d",
+				snid, ibp, maxIn, obp, maxOut)
+			peMgr.cfg.subNetMaxOutbounds[snid] = maxOut
+			peMgr.cfg.subNetMaxInBounds[snid] = maxIn
+			changed = true
+			snidOut := snid
+			schMsg := sch.SchMessage{}
+			peMgr.sdl.SchMakeMessage(&schMsg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeOutboundReq, &snidOut)
+			peMgr.sdl.SchSendMessage(&schMsg)
+		} else if ibp >= maxIn && maxOut > lo {
+			maxOut--
+			maxIn++
+			peerLog.Debug("slotAdaptTimerHandler: to inbound, snid: %x, in: %d/%d, out: %d/%d",
+				snid, ibp, maxIn, obp, maxOut)
+			peMgr.cfg.subNetMaxOutbounds[snid] = maxOut
+			peMgr.cfg.subNetMaxInBounds[snid] = maxIn
+			changed = true
+		}
+		ibpNumTotal += maxIn
+	}
+
+	if !changed {
+		return PeMgrEnoNone
+	}
+
+	// the accepter might had been paused for total inbounds limited, see
+	// function acceptPauseCheck.
+	peMgr.cfg.ibpNumTotal = ibpNumTotal
+	peMgr.acceptResumeCheck()
+	return PeMgrEnoNone
+}
@@ -0,0 +1,219 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// SOCKS5 dialing (RFC 1928, and RFC 1929 for the user/password authentication):
+// a connection is made to the proxy with the transport configured, and the proxy
+// is asked to connect to the peer. the connection returned reports the peer as
+// its remote address, as the peer manager expects. the dialer is applied to the
+// sub networks it's enabled for, see config.PeerProxy.
+//
+
+const (
+	socks5Version    = 0x05 // protocol version
+	socks5AuthNone   = 0x00 // no authentication
+	socks5AuthPasswd = 0x02 // user/password authentication
+	socks5PasswdVer  = 0x01 // version of the user/password authentication
+	socks5CmdConnect = 0x01 // command connect
+	socks5AtypIPv4   = 0x01 // address type ipv4
+	socks5AtypDomain = 0x03 // address type domain name
+	socks5AtypIPv6   = 0x04 // address type ipv6
+	socks5RepOk      = 0x00 // reply succeeded
+)
+
+type socks5Dialer struct {
+	proxy   config.PeerProxy     // the proxy
+	forward config.PeerTransport // transport to reach the proxy
+}
+
+func newSocks5Dialer(proxy *config.PeerProxy, forward config.PeerTransport) *socks5Dialer {
+	return &socks5Dialer{
+		proxy:   *proxy,
+		forward: peTransport(forward),
+	}
+}
+
+// Dialer of outbound connections of a sub network
+func (peMgr *PeerManager) dialerFor(snid SubNetworkID) config.PeerTransport {
+	if peMgr.proxied(snid) {
+		return peMgr.cfg.proxy
+	}
+	return peMgr.cfg.transport
+}
+
+func (peMgr *PeerManager) proxied(snid SubNetworkID) bool {
+	if peMgr.cfg.proxy == nil {
+		return false
+	}
+	if enabled, ok := peMgr.cfg.subNetProxy[snid]; ok {
+		return enabled
+	}
+	return true
+}
+
+func (sd *socks5Dialer) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	pn, err := strconv.Atoi(port)
+	if err != nil || pn <= 0 || pn > 0xffff {
+		return nil, errors.New("socks5: invalid port: " + port)
+	}
+	conn, err := sd.forward.Dial(sd.proxy.Addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	if err = sd.negotiate(conn); err == nil {
+		err = sd.connect(conn, host, pn)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	raddr := &net.TCPAddr{IP: net.ParseIP(host), Port: pn}
+	return &socks5Conn{Conn: conn, raddr: raddr}, nil
+}
+
+func (sd *socks5Dialer) Listen(addr string) (net.Listener, error) {
+	return nil, errors.New("socks5: listening not supported")
+}
+
+// Negotiate the authentication method, and authenticate if asked
+func (sd *socks5Dialer) negotiate(conn net.Conn) error {
+	greeting := []byte{socks5Version, 1, socks5AuthNone}
+	if sd.proxy.User != "" {
+		greeting = []byte{socks5Version, 2, socks5AuthNone, socks5AuthPasswd}
+	}
+	if _, err := conn.Write(greeting); err != nil {
+		return err
+	}
+	var rsp [2]byte
+	if _, err := io.ReadFull(conn, rsp[:]); err != nil {
+		return err
+	}
+	if rsp[0] != socks5Version {
+		return errors.New("socks5: invalid version from proxy")
+	}
+	switch rsp[1] {
+	case socks5AuthNone:
+		return nil
+	case socks5AuthPasswd:
+		if sd.proxy.User == "" {
+			break
+		}
+		user, passwd := sd.proxy.User, sd.proxy.Password
+		if len(user) > 255 || len(passwd) > 255 {
+			return errors.New("socks5: user or password too long")
+		}
+		req := []byte{socks5PasswdVer, byte(len(user))}
+		req = append(req, user...)
+		req = append(req, byte(len(passwd)))
+		req = append(req, passwd...)
+		if _, err := conn.Write(req); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, rsp[:]); err != nil {
+			return err
+		}
+		if rsp[1] != socks5RepOk {
+			return errors.New("socks5: authentication failed")
+		}
+		return nil
+	}
+	return errors.New("socks5: no authentication method acceptable")
+}
+
+// Ask the proxy to connect to the host, which is an ip or a domain name
+func (sd *socks5Dialer) connect(conn net.Conn, host string, port int) error {
+	req := []byte{socks5Version, socks5CmdConnect, 0}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return errors.New("socks5: host name too long")
+		}
+		req = append(req, socks5AtypDomain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socks5AtypIPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socks5AtypIPv6)
+		req = append(req, ip.To16()...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// reply: version, reply code, reserved, and the address bound
+	var rsp [4]byte
+	if _, err := io.ReadFull(conn, rsp[:]); err != nil {
+		return err
+	}
+	if rsp[0] != socks5Version {
+		return errors.New("socks5: invalid version from proxy")
+	}
+	if rsp[1] != socks5RepOk {
+		return errors.New("socks5: connect failed, reply: " + strconv.Itoa(int(rsp[1])))
+	}
+	var skip int
+	switch rsp[3] {
+	case socks5AtypIPv4:
+		skip = net.IPv4len
+	case socks5AtypIPv6:
+		skip = net.IPv6len
+	case socks5AtypDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return err
+		}
+		skip = int(n[0])
+	default:
+		return errors.New("socks5: invalid address type from proxy")
+	}
+	_, err := io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
+
+// Connection through the proxy, the remote address is that of the peer
+type socks5Conn struct {
+	net.Conn
+	raddr *net.TCPAddr // address of the peer
+}
+
+func (sc *socks5Conn) RemoteAddr() net.Addr {
+	return sc.raddr
+}
@@ -0,0 +1,137 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+// Minimal socks5 proxy: user/password required, connect to ipv4 only
+func socks5TestProxy(t *testing.T, user, passwd string) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serve := func(c net.Conn) {
+		defer c.Close()
+		buf := make([]byte, 512)
+		if _, err := io.ReadFull(c, buf[:2]); err != nil {
+			return
+		}
+		io.ReadFull(c, buf[:buf[1]])
+		c.Write([]byte{socks5Version, socks5AuthPasswd})
+		io.ReadFull(c, buf[:2])
+		u := make([]byte, buf[1])
+		io.ReadFull(c, u)
+		io.ReadFull(c, buf[:1])
+		p := make([]byte, buf[0])
+		io.ReadFull(c, p)
+		if string(u) != user || string(p) != passwd {
+			c.Write([]byte{socks5PasswdVer, 1})
+			return
+		}
+		c.Write([]byte{socks5PasswdVer, socks5RepOk})
+		if _, err := io.ReadFull(c, buf[:10]); err != nil || buf[3] != socks5AtypIPv4 {
+			return
+		}
+		target := &net.TCPAddr{IP: net.IP(buf[4:8]), Port: int(buf[8])<<8 | int(buf[9])}
+		up, err := net.Dial("tcp", target.String())
+		if err != nil {
+			c.Write([]byte{socks5Version, 5, 0, socks5AtypIPv4, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		defer up.Close()
+		c.Write([]byte{socks5Version, socks5RepOk, 0, socks5AtypIPv4, 127, 0, 0, 1, 0, 1})
+		go io.Copy(up, c)
+		io.Copy(c, up)
+	}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serve(c)
+		}
+	}()
+	return l
+}
+
+func TestSocks5Dial(t *testing.T) {
+	proxy := socks5TestProxy(t, "tor", "secret")
+	defer proxy.Close()
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			c, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(c, c)
+		}
+	}()
+
+	sd := newSocks5Dialer(&config.PeerProxy{Addr: proxy.Addr().String(), User: "tor", Password: "secret"}, nil)
+	conn, err := sd.Dial(echo.Addr().String(), time.Second*5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.RemoteAddr().String() != echo.Addr().String() {
+		t.Errorf("remote address %s, want the peer %s", conn.RemoteAddr(), echo.Addr())
+	}
+	conn.SetDeadline(time.Now().Add(time.Second * 5))
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("echo got %q, %v", buf, err)
+	}
+
+	sd.proxy.Password = "wrong"
+	if _, err := sd.Dial(echo.Addr().String(), time.Second*5); err == nil {
+		t.Errorf("dialed with a wrong password")
+	}
+}
+
+func TestDialerFor(t *testing.T) {
+	peMgr := &PeerManager{}
+	snid := SubNetworkID{0x12, 0x34}
+	if _, ok := peMgr.dialerFor(snid).(*socks5Dialer); ok {
+		t.Fatalf("proxied without a proxy configured")
+	}
+	peMgr.cfg.proxy = newSocks5Dialer(&config.PeerProxy{Addr: "127.0.0.1:9050"}, nil)
+	peMgr.cfg.subNetProxy = map[SubNetworkID]bool{snid: false}
+	if _, ok := peMgr.dialerFor(snid).(*socks5Dialer); ok {
+		t.Errorf("proxied for a sub network disabled")
+	}
+	if _, ok := peMgr.dialerFor(config.AnySubNet).(*socks5Dialer); !ok {
+		t.Errorf("not proxied for a sub network not listed")
+	}
+}
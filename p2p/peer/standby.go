@@ -0,0 +1,166 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	config "github.com/yeeco/gyee/p2p/config"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+// Warm standby: a validator sets the members of the next committee as standby
+// nodes ahead of the rotation. they're added as static nodes, so connections to
+// them are made, handshaken and kept alive by pingpong as other static peers,
+// but an instance activated is held by the peer manager and not indicated to the
+// shell, so no traffic goes to it. when the committee rotates, the pool is
+// activated: instances held are indicated at once, without waiting connections
+// and handshakes, and the nodes stay static as the active committee, while those
+// of the committee before, not in the pool, are removed. nodes which are static
+// already when set standby are left as they are.
+type standbyPool struct {
+	nodes  map[config.NodeID]*config.Node // standby nodes, except those static already
+	active map[config.NodeID]*config.Node // nodes of the pool activated last time
+	held   map[interface{}]*PeerInstance  // instances activated but not indicated, by task node
+}
+
+func newStandbyPool() *standbyPool {
+	return &standbyPool{
+		nodes:  make(map[config.NodeID]*config.Node, 0),
+		active: make(map[config.NodeID]*config.Node, 0),
+		held:   make(map[interface{}]*PeerInstance, 0),
+	}
+}
+
+// Set the nodes to keep connections warm for, replacing those set before. the
+// pool is handled in the peer manager task.
+func (peMgr *PeerManager) SetStandby(nodes []*config.Node) PeMgrErrno {
+	return peMgr.standbyReq(sch.PeMgrAdminSetStandby, nodes)
+}
+
+// Activate the standby pool, as the committee rotated
+func (peMgr *PeerManager) ActivateStandby() PeMgrErrno {
+	return peMgr.standbyReq(sch.PeMgrAdminActivateStandby, nil)
+}
+
+func (peMgr *PeerManager) standbyReq(cmd int, nodes []*config.Node) PeMgrErrno {
+	if !peMgr.isInited {
+		return PeMgrEnoScheduler
+	}
+	req := sch.MsgPeMgrAdminReq{
+		Cmd:   cmd,
+		Nodes: nodes,
+	}
+	msg := sch.SchMessage{}
+	peMgr.sdl.SchMakeMessage(&msg, peMgr.ptnMe, peMgr.ptnMe, sch.EvPeMgrAdminReq, &req)
+	if peMgr.sdl.SchSendMessage(&msg) != sch.SchEnoNone {
+		return PeMgrEnoScheduler
+	}
+	return PeMgrEnoNone
+}
+
+func (peMgr *PeerManager) isStatic(id config.NodeID) bool {
+	peMgr.lock.Lock()
+	defer peMgr.lock.Unlock()
+	for _, sn := range peMgr.cfg.staticNodes {
+		if sn.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (peMgr *PeerManager) adminSetStandby(nodes []*config.Node) PeMgrErrno {
+	sb := peMgr.standby
+	self := peMgr.sdl.SchGetP2pConfig().Local.ID
+	set := make(map[config.NodeID]*config.Node, len(nodes))
+	for _, n := range nodes {
+		if n.ID == self {
+			continue
+		}
+		set[n.ID] = n
+	}
+	for id := range sb.nodes {
+		if _, ok := set[id]; ok {
+			continue
+		}
+		delete(sb.nodes, id)
+		if _, ok := sb.active[id]; !ok {
+			peMgr.adminRemovePeer(id)
+		}
+	}
+	for id, n := range set {
+		if _, ok := sb.nodes[id]; ok {
+			continue
+		}
+		// members of the active committee are connected already
+		if _, ok := sb.active[id]; ok {
+			sb.nodes[id] = n
+			continue
+		}
+		if peMgr.isStatic(id) || peMgr.banList.nodeBanned(id) {
+			continue
+		}
+		sb.nodes[id] = n
+		peMgr.adminAddStatic(n)
+	}
+	peerLog.ForceDebug("adminSetStandby: nodes: %d, standby: %d", len(nodes), len(sb.nodes))
+	return PeMgrEnoNone
+}
+
+func (peMgr *PeerManager) adminActivateStandby() PeMgrErrno {
+	sb := peMgr.standby
+	for id := range sb.active {
+		if _, ok := sb.nodes[id]; !ok {
+			peMgr.adminRemovePeer(id)
+		}
+	}
+	held := sb.held
+	sb.active = sb.nodes
+	sb.nodes = make(map[config.NodeID]*config.Node, 0)
+	sb.held = make(map[interface{}]*PeerInstance, 0)
+	peerLog.ForceDebug("adminActivateStandby: active: %d, held: %d", len(sb.active), len(held))
+	for _, inst := range held {
+		peMgr.peMgrActiveInd(inst)
+	}
+	return PeMgrEnoNone
+}
+
+// hold an instance just activated if it's a standby one
+func (peMgr *PeerManager) standbyHold(inst *PeerInstance) bool {
+	sb := peMgr.standby
+	if inst.snid != peMgr.cfg.staticSubNetId {
+		return false
+	}
+	if _, ok := sb.nodes[inst.node.ID]; !ok {
+		return false
+	}
+	if _, ok := sb.active[inst.node.ID]; ok {
+		return false
+	}
+	peerLog.ForceDebug("standbyHold: inst: %s, dir: %d", inst.name, inst.dir)
+	sb.held[inst.ptnMe] = inst
+	return true
+}
+
+// the shell is not told about an instance held, so it's closed without asking it
+func (peMgr *PeerManager) standbyHeld(ptn interface{}) bool {
+	_, ok := peMgr.standby.held[ptn]
+	return ok
+}
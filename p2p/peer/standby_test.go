@@ -0,0 +1,56 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestStandbyHold(t *testing.T) {
+	peMgr := &PeerManager{standby: newStandbyPool()}
+	peMgr.cfg.staticSubNetId = config.ZeroSubNet
+	standby := &config.Node{ID: config.NodeID{1}}
+	active := &config.Node{ID: config.NodeID{2}}
+	peMgr.standby.nodes[standby.ID] = standby
+	peMgr.standby.nodes[active.ID] = active
+	peMgr.standby.active[active.ID] = active
+
+	inst := func(node *config.Node, snid SubNetworkID, ptn string) *PeerInstance {
+		return &PeerInstance{node: *node, snid: snid, ptnMe: ptn}
+	}
+	if !peMgr.standbyHold(inst(standby, config.ZeroSubNet, "a")) || !peMgr.standbyHeld("a") {
+		t.Errorf("standby instance not held")
+	}
+	if peMgr.standbyHold(inst(standby, config.AnySubNet, "b")) {
+		t.Errorf("standby node connected in a dynamic sub network held")
+	}
+	if peMgr.standbyHold(inst(active, config.ZeroSubNet, "c")) {
+		t.Errorf("member of the active committee held")
+	}
+	if peMgr.standbyHold(inst(&config.Node{ID: config.NodeID{3}}, config.ZeroSubNet, "d")) {
+		t.Errorf("static node not in the pool held")
+	}
+	if len(peMgr.standby.held) != 1 {
+		t.Errorf("held got %d, want 1", len(peMgr.standby.held))
+	}
+}
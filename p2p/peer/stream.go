@@ -0,0 +1,203 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"encoding/binary"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Streaming of large messages: a PID_EXT package with payload larger than
+// streamFrameSize is split by piTx into MID_FRAME packages sent one by one,
+// and reassembled by piRx of the peer, so the max tcpmsg package size need not
+// be inflated. The payload of a frame is a header followed by a chunk of the
+// original payload, the header(big endian):
+//
+//	stream identity	8 bytes		sequence of streams of the sending instance
+//	frame sequence	4 bytes		0, 1, ... total-1
+//	total frames	4 bytes
+//	message length	4 bytes		bytes of the original payload
+//	message identity	4 bytes		the original Mid
+//
+// and the key of the original package is carried by each frame. Frames of a
+// stream are sent in order over the connection, a stream is discarded if any
+// frame missed, or it's larger than streamMaxSize, or not completed in
+// streamTimeout. Streaming is used only with peers agreed on P2pProtoVerExt
+// or higher, see PeerInstance.p2pExtended.
+//
+
+const (
+	streamFrameSize   = 1024 * 1024 // max bytes of original payload in a frame
+	streamFrameHdrLen = 24          // frame header length
+	streamMaxPending  = 4           // max streams in reassembling of an instance
+)
+
+type rxStream struct {
+	mid      uint32    // message identity
+	key      []byte    // message key
+	total    uint32    // total frames
+	next     uint32    // next frame expected
+	length   int       // message length
+	buf      []byte    // payload reassembled
+	deadline time.Time // time to be discarded
+}
+
+func (pi *PeerInstance) piTxPackage(upkg *P2pPackage) PeMgrErrno {
+	// peers agreed on a p2p protocol version older than P2pProtoVerExt do not
+	// know MID_FRAME, the package is sent as it is.
+	if upkg.Pid != uint32(PID_EXT) || len(upkg.Payload) <= streamFrameSize || !pi.p2pExtended() {
+		pi.piTxWait(len(upkg.Payload))
+		return upkg.SendPackage(pi)
+	}
+
+	maxSize := pi.peMgr.cfg.streamMaxSize
+	if maxSize <= 0 {
+		maxSize = config.DftStreamMaxSize
+	}
+	if len(upkg.Payload) > maxSize {
+		peerLog.Debug("piTxPackage: too large, discarded, inst: %s, mid: %d, length: %d",
+			pi.name, upkg.Mid, len(upkg.Payload))
+		return PeMgrEnoNone
+	}
+
+	pi.txStreamSeq++
+	total := (len(upkg.Payload) + streamFrameSize - 1) / streamFrameSize
+	for seq := 0; seq < total; seq++ {
+		chunk := upkg.Payload[seq*streamFrameSize:]
+		if len(chunk) > streamFrameSize {
+			chunk = chunk[:streamFrameSize]
+		}
+		payload := make([]byte, streamFrameHdrLen, streamFrameHdrLen+len(chunk))
+		binary.BigEndian.PutUint64(payload[0:], pi.txStreamSeq)
+		binary.BigEndian.PutUint32(payload[8:], uint32(seq))
+		binary.BigEndian.PutUint32(payload[12:], uint32(total))
+		binary.BigEndian.PutUint32(payload[16:], uint32(len(upkg.Payload)))
+		binary.BigEndian.PutUint32(payload[20:], upkg.Mid)
+		payload = append(payload, chunk...)
+		frame := P2pPackage{
+			Pid:           uint32(PID_EXT),
+			Mid:           uint32(MID_FRAME),
+			Key:           upkg.Key,
+			PayloadLength: uint32(len(payload)),
+			Payload:       payload,
+		}
+		pi.piTxWait(len(payload))
+		if eno := frame.SendPackage(pi); eno != PeMgrEnoNone {
+			return eno
+		}
+	}
+	return PeMgrEnoNone
+}
+
+func (pi *PeerInstance) piRxFrame(upkg *P2pPackage) *P2pPackage {
+	// a package returned when all frames of a stream received, nil if more
+	// frames expected or the frame is discarded.
+	if len(upkg.Payload) < streamFrameHdrLen {
+		peerLog.Debug("piRxFrame: invalid frame, inst: %s, length: %d", pi.name, len(upkg.Payload))
+		pi.msgStatDecodeFailed(upkg.Pid, upkg.Mid)
+		return nil
+	}
+	id := binary.BigEndian.Uint64(upkg.Payload[0:])
+	seq := binary.BigEndian.Uint32(upkg.Payload[8:])
+	total := binary.BigEndian.Uint32(upkg.Payload[12:])
+	length := int(binary.BigEndian.Uint32(upkg.Payload[16:]))
+	mid := binary.BigEndian.Uint32(upkg.Payload[20:])
+	chunk := upkg.Payload[streamFrameHdrLen:]
+
+	now := time.Now()
+	if pi.rxStreams == nil {
+		pi.rxStreams = make(map[uint64]*rxStream, 0)
+	}
+	for k, st := range pi.rxStreams {
+		if now.After(st.deadline) {
+			peerLog.Debug("piRxFrame: timeout, inst: %s, stream: %d, frames: %d/%d",
+				pi.name, k, st.next, st.total)
+			pi.peMgr.msgStats.dropped(upkg.Pid, st.mid)
+			delete(pi.rxStreams, k)
+		}
+	}
+
+	cfg := &pi.peMgr.cfg
+	st, ok := pi.rxStreams[id]
+	if seq == 0 {
+		maxSize := cfg.streamMaxSize
+		if maxSize <= 0 {
+			maxSize = config.DftStreamMaxSize
+		}
+		if ok || total == 0 || length <= 0 || length > maxSize ||
+			len(pi.rxStreams) >= streamMaxPending {
+			peerLog.Debug("piRxFrame: stream refused, inst: %s, stream: %d, total: %d, length: %d",
+				pi.name, id, total, length)
+			pi.peMgr.msgStats.dropped(upkg.Pid, mid)
+			delete(pi.rxStreams, id)
+			return nil
+		}
+		dur := cfg.streamTimeout
+		if dur <= 0 {
+			dur = config.DftStreamTimeout
+		}
+		st = &rxStream{
+			mid:      mid,
+			key:      append([]byte{}, upkg.Key...),
+			total:    total,
+			length:   length,
+			deadline: now.Add(dur),
+		}
+		pi.rxStreams[id] = st
+	} else if !ok || seq != st.next || total != st.total || mid != st.mid {
+		peerLog.Debug("piRxFrame: frame mismatched, inst: %s, stream: %d, seq: %d",
+			pi.name, id, seq)
+		pi.peMgr.msgStats.dropped(upkg.Pid, mid)
+		delete(pi.rxStreams, id)
+		return nil
+	}
+
+	if len(st.buf)+len(chunk) > st.length {
+		peerLog.Debug("piRxFrame: overflow, inst: %s, stream: %d, length: %d",
+			pi.name, id, st.length)
+		pi.msgStatDecodeFailed(upkg.Pid, st.mid)
+		delete(pi.rxStreams, id)
+		return nil
+	}
+	st.buf = append(st.buf, chunk...)
+	if st.next++; st.next < st.total {
+		return nil
+	}
+
+	delete(pi.rxStreams, id)
+	if len(st.buf) != st.length {
+		peerLog.Debug("piRxFrame: length mismatched, inst: %s, stream: %d, length: %d, real: %d",
+			pi.name, id, st.length, len(st.buf))
+		pi.msgStatDecodeFailed(upkg.Pid, st.mid)
+		return nil
+	}
+	pi.msgStatRx(upkg.Pid, st.mid, len(st.buf))
+	return &P2pPackage{
+		Pid:           upkg.Pid,
+		Mid:           st.mid,
+		Key:           st.key,
+		PayloadLength: uint32(len(st.buf)),
+		Payload:       st.buf,
+	}
+}
@@ -0,0 +1,201 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	ggio "github.com/gogo/protobuf/io"
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func newStreamTestPair(ver [4]byte) (tx *PeerInstance, rx *PeerInstance) {
+	a, b := net.Pipe()
+	peMgr := &PeerManager{msgStats: newMsgStats()}
+	negotiated := []Protocol{{Pid: uint32(PID_P2P), Ver: ver}}
+	tx = &PeerInstance{peMgr: peMgr, name: "tx", conn: a, iow: ggio.NewDelimitedWriter(a), negotiated: negotiated}
+	rx = &PeerInstance{peMgr: peMgr, name: "rx", conn: b, ior: ggio.NewDelimitedReader(b, 4*streamFrameSize), negotiated: negotiated}
+	return
+}
+
+func streamFrame(id uint64, seq, total uint32, length int, mid uint32, chunk []byte) *P2pPackage {
+	payload := make([]byte, streamFrameHdrLen, streamFrameHdrLen+len(chunk))
+	binary.BigEndian.PutUint64(payload[0:], id)
+	binary.BigEndian.PutUint32(payload[8:], seq)
+	binary.BigEndian.PutUint32(payload[12:], total)
+	binary.BigEndian.PutUint32(payload[16:], uint32(length))
+	binary.BigEndian.PutUint32(payload[20:], mid)
+	payload = append(payload, chunk...)
+	return &P2pPackage{
+		Pid:           uint32(PID_EXT),
+		Mid:           uint32(MID_FRAME),
+		Key:           []byte("key"),
+		PayloadLength: uint32(len(payload)),
+		Payload:       payload,
+	}
+}
+
+func TestStreamRoundTrip(t *testing.T) {
+	tx, rx := newStreamTestPair(config.P2pProtoVerExt)
+	defer tx.conn.Close()
+	defer rx.conn.Close()
+
+	payload := make([]byte, 2*streamFrameSize+streamFrameSize/2)
+	for i := range payload {
+		payload[i] = byte(i * 7)
+	}
+	done := make(chan PeMgrErrno, 1)
+	go func() {
+		done <- tx.piTxPackage(&P2pPackage{
+			Pid:           uint32(PID_EXT),
+			Mid:           uint32(MID_TX),
+			Key:           []byte("key"),
+			PayloadLength: uint32(len(payload)),
+			Payload:       payload,
+		})
+	}()
+
+	frames := 0
+	var msg *P2pPackage
+	for msg == nil {
+		upkg := new(P2pPackage)
+		if eno := upkg.RecvPackage(rx); eno != PeMgrEnoNone {
+			t.Fatalf("RecvPackage failed, eno: %d", eno)
+		}
+		if upkg.Mid != uint32(MID_FRAME) || len(upkg.Payload) > streamFrameHdrLen+streamFrameSize {
+			t.Fatalf("frame got mid: %d, length: %d", upkg.Mid, len(upkg.Payload))
+		}
+		frames++
+		msg = rx.piRxFrame(upkg)
+	}
+	if eno := <-done; eno != PeMgrEnoNone {
+		t.Fatalf("piTxPackage failed, eno: %d", eno)
+	}
+	if frames != 3 {
+		t.Errorf("frames got %d", frames)
+	}
+	if msg.Mid != uint32(MID_TX) || string(msg.Key) != "key" || !bytes.Equal(msg.Payload, payload) {
+		t.Errorf("message got mid: %d, key: %s, length: %d", msg.Mid, msg.Key, len(msg.Payload))
+	}
+	if len(rx.rxStreams) != 0 {
+		t.Errorf("streams pending: %d", len(rx.rxStreams))
+	}
+}
+
+func TestStreamNotExtended(t *testing.T) {
+	// a peer agreed on the base version gets the package as it is
+	tx, rx := newStreamTestPair(config.P2pProtoVerBase)
+	defer tx.conn.Close()
+	defer rx.conn.Close()
+
+	payload := make([]byte, streamFrameSize+1)
+	done := make(chan PeMgrErrno, 1)
+	go func() {
+		done <- tx.piTxPackage(&P2pPackage{
+			Pid:           uint32(PID_EXT),
+			Mid:           uint32(MID_TX),
+			PayloadLength: uint32(len(payload)),
+			Payload:       payload,
+		})
+	}()
+	upkg := new(P2pPackage)
+	if eno := upkg.RecvPackage(rx); eno != PeMgrEnoNone {
+		t.Fatalf("RecvPackage failed, eno: %d", eno)
+	}
+	if eno := <-done; eno != PeMgrEnoNone {
+		t.Fatalf("piTxPackage failed, eno: %d", eno)
+	}
+	if upkg.Mid != uint32(MID_TX) || len(upkg.Payload) != len(payload) {
+		t.Errorf("package got mid: %d, length: %d", upkg.Mid, len(upkg.Payload))
+	}
+}
+
+func TestStreamReassembly(t *testing.T) {
+	_, pi := newStreamTestPair(config.P2pProtoVerExt)
+	defer pi.conn.Close()
+	mid := uint32(MID_TX)
+	chunk := []byte("0123456789")
+
+	// frames in order
+	if pi.piRxFrame(streamFrame(1, 0, 2, 20, mid, chunk)) != nil {
+		t.Fatalf("completed with the first frame")
+	}
+	if msg := pi.piRxFrame(streamFrame(1, 1, 2, 20, mid, chunk)); msg == nil || len(msg.Payload) != 20 {
+		t.Fatalf("message got %+v", msg)
+	}
+
+	// a missed frame discards the stream
+	pi.piRxFrame(streamFrame(2, 0, 3, 30, mid, chunk))
+	if pi.piRxFrame(streamFrame(2, 2, 3, 30, mid, chunk)) != nil || pi.rxStreams[2] != nil {
+		t.Errorf("stream with frame missed not discarded")
+	}
+
+	// a duplicated frame discards the stream
+	pi.piRxFrame(streamFrame(3, 0, 3, 30, mid, chunk))
+	pi.piRxFrame(streamFrame(3, 1, 3, 30, mid, chunk))
+	if pi.piRxFrame(streamFrame(3, 1, 3, 30, mid, chunk)) != nil || pi.rxStreams[3] != nil {
+		t.Errorf("stream with frame duplicated not discarded")
+	}
+	if pi.piRxFrame(streamFrame(4, 0, 2, 20, mid, chunk)); pi.piRxFrame(streamFrame(4, 0, 2, 20, mid, chunk)) != nil ||
+		pi.rxStreams[4] != nil {
+		t.Errorf("stream started again not discarded")
+	}
+
+	// mismatched headers, lengths, and oversized streams
+	pi.piRxFrame(streamFrame(5, 0, 2, 20, mid, chunk))
+	if pi.piRxFrame(streamFrame(5, 1, 3, 20, mid, chunk)) != nil || pi.rxStreams[5] != nil {
+		t.Errorf("stream with total changed not discarded")
+	}
+	pi.piRxFrame(streamFrame(6, 0, 2, 15, mid, chunk))
+	if pi.piRxFrame(streamFrame(6, 1, 2, 15, mid, chunk)) != nil || pi.rxStreams[6] != nil {
+		t.Errorf("stream overflowed not discarded")
+	}
+	pi.piRxFrame(streamFrame(7, 0, 2, 25, mid, chunk))
+	if pi.piRxFrame(streamFrame(7, 1, 2, 25, mid, chunk)) != nil {
+		t.Errorf("stream shorter than announced completed")
+	}
+	if pi.piRxFrame(streamFrame(8, 0, 2, config.DftStreamMaxSize+1, mid, chunk)); pi.rxStreams[8] != nil {
+		t.Errorf("stream oversized accepted")
+	}
+	if pi.piRxFrame(&P2pPackage{Pid: uint32(PID_EXT), Mid: uint32(MID_FRAME), Payload: chunk}) != nil {
+		t.Errorf("truncated frame accepted")
+	}
+
+	// pending streams are limited
+	for id := uint64(10); id < 10+streamMaxPending+1; id++ {
+		pi.piRxFrame(streamFrame(id, 0, 2, 20, mid, chunk))
+	}
+	if len(pi.rxStreams) != streamMaxPending {
+		t.Errorf("streams pending: %d", len(pi.rxStreams))
+	}
+
+	// streams timed out are discarded
+	for _, st := range pi.rxStreams {
+		st.deadline = time.Now().Add(-time.Second)
+	}
+	if pi.piRxFrame(streamFrame(10, 1, 2, 20, mid, chunk)) != nil || len(pi.rxStreams) != 0 {
+		t.Errorf("streams timed out got %d", len(pi.rxStreams))
+	}
+}
@@ -77,6 +77,12 @@ const (
 	MID_RPTK      = pb.MessageId_MID_RPTK      // report key
 	MID_GCD       = pb.MessageId_MID_GCD       // get chain data
 	MID_PCD       = pb.MessageId_MID_PCD       // put chain data
+	MID_FRAME     = pb.MessageId_MID_FRAME     // frame of a large message streamed
+	MID_ECHO      = pb.MessageId_MID_ECHO      // echo request
+	MID_ECHORSP   = pb.MessageId_MID_ECHORSP   // echo response
+	MID_PROBE     = pb.MessageId_MID_PROBE     // reachability probe request
+	MID_PROBERSP  = pb.MessageId_MID_PROBERSP  // reachability probe response
+	MID_RXDROP    = pb.MessageId_MID_RXDROP    // rx queue drop notice
 
 	// external MID for PID_EXT
 	MID_TX          = pb.MessageId_MID_TX
@@ -108,14 +114,22 @@ type Protocol struct {
 // Handshake message
 //
 type Handshake struct {
-	Snid      SubNetworkID  // sub network identity
-	Dir       int           // direct
-	NodeId    config.NodeID // node identity
-	IP        net.IP        // ip address
-	UDP       uint32        // udp port number
-	TCP       uint32        // tcp port number
-	ProtoNum  uint32        // number of protocols supported
-	Protocols []Protocol    // version of protocol
+	Snid          SubNetworkID  // sub network identity
+	Dir           int           // direct
+	NodeId        config.NodeID // node identity
+	IP            net.IP        // ip address
+	UDP           uint32        // udp port number
+	TCP           uint32        // tcp port number
+	ProtoNum      uint32        // number of protocols supported
+	Protocols     []Protocol    // version of protocol
+	ClientVersion string        // client version, carried in "Extra"
+	DhtPort       uint32        // tcp port of dht, 0 if dht not run
+	Encryption    uint32        // encryption policy for the sub network, see config.PeerEnc*
+	Compression   uint32        // compression algorithms supported, see Compress*
+	AltIP         net.IP        // ip address of the other family, nil if single stack
+	InstNonce     []byte        // random nonce of the node instance, see selfdial.go
+	WsPort        uint32        // tcp port of websocket peer connections, 0 if not run
+	Negotiated    []Protocol    // protocols agreed with the peer, not on the wire
 }
 
 //
@@ -126,6 +140,98 @@ type Pingpong struct {
 	Extra []byte // extra info
 }
 
+//
+// Echo request or response, in bodies of ping and pong, with the padding or the
+// padding echoed in Extra. it's sent as data package, not written directly.
+//
+func (upkg *P2pPackage) echo(echo *Pingpong, rsp bool) PeMgrErrno {
+	pbEcho := pb.P2PMessage{
+		Mid: new(pb.MessageId),
+	}
+	if !rsp {
+		*pbEcho.Mid = MID_ECHO
+		pbEcho.Ping = &pb.P2PMessage_Ping{
+			Seq:   &echo.Seq,
+			Extra: echo.Extra,
+		}
+	} else {
+		*pbEcho.Mid = MID_ECHORSP
+		pbEcho.Pong = &pb.P2PMessage_Pong{
+			Seq:   &echo.Seq,
+			Extra: echo.Extra,
+		}
+	}
+	payload, err := proto.Marshal(&pbEcho)
+	if len(payload) == 0 || err != nil {
+		tcpmsgLog.Debug("echo: empty payload")
+		return PeMgrEnoMessage
+	}
+	upkg.Pid = uint32(PID_P2P)
+	upkg.Mid = uint32(*pbEcho.Mid)
+	upkg.PayloadLength = uint32(len(payload))
+	upkg.Payload = payload
+	return PeMgrEnoNone
+}
+
+//
+// Reachability probe request or response, in bodies of ping and pong, with the
+// nonce in Seq, and the result of the tcp probe in Extra of the response.
+//
+func (upkg *P2pPackage) probe(probe *Pingpong, rsp bool) PeMgrErrno {
+	pbProbe := pb.P2PMessage{
+		Mid: new(pb.MessageId),
+	}
+	if !rsp {
+		*pbProbe.Mid = MID_PROBE
+		pbProbe.Ping = &pb.P2PMessage_Ping{
+			Seq:   &probe.Seq,
+			Extra: probe.Extra,
+		}
+	} else {
+		*pbProbe.Mid = MID_PROBERSP
+		pbProbe.Pong = &pb.P2PMessage_Pong{
+			Seq:   &probe.Seq,
+			Extra: probe.Extra,
+		}
+	}
+	payload, err := proto.Marshal(&pbProbe)
+	if len(payload) == 0 || err != nil {
+		tcpmsgLog.Debug("probe: empty payload")
+		return PeMgrEnoMessage
+	}
+	upkg.Pid = uint32(PID_P2P)
+	upkg.Mid = uint32(*pbProbe.Mid)
+	upkg.PayloadLength = uint32(len(payload))
+	upkg.Payload = payload
+	return PeMgrEnoNone
+}
+
+//
+// Rx queue drop notice, in body of ping, with the number of packages dropped
+// since the last notice in Seq, and the Pid and Mid of the latest one dropped
+// in Extra(8 bytes, big endian).
+//
+func (upkg *P2pPackage) rxDrop(notice *Pingpong) PeMgrErrno {
+	pbNotice := pb.P2PMessage{
+		Mid: new(pb.MessageId),
+		Ping: &pb.P2PMessage_Ping{
+			Seq:   &notice.Seq,
+			Extra: notice.Extra,
+		},
+	}
+	*pbNotice.Mid = MID_RXDROP
+	payload, err := proto.Marshal(&pbNotice)
+	if len(payload) == 0 || err != nil {
+		tcpmsgLog.Debug("rxDrop: empty payload")
+		return PeMgrEnoMessage
+	}
+	upkg.Pid = uint32(PID_P2P)
+	upkg.Mid = uint32(MID_RXDROP)
+	upkg.PayloadLength = uint32(len(payload))
+	upkg.Payload = payload
+	return PeMgrEnoNone
+}
+
 //
 // Check key
 //
@@ -166,11 +272,14 @@ type PutChainData struct {
 // Package for TCP message
 //
 type P2pPackage struct {
-	Pid           uint32 // protocol identity
-	Mid           uint32 // message identity
-	Key           []byte // key of message
-	PayloadLength uint32 // payload length
-	Payload       []byte // payload
+	Pid           uint32        // protocol identity
+	Mid           uint32        // message identity
+	Key           []byte        // key of message
+	PayloadLength uint32        // payload length
+	Payload       []byte        // payload
+	Enqueued      time.Time     // time queued to be sent, not on the wire
+	TTL           time.Duration // dropped if still not sent TTL after queued, 0 for never, not on the wire
+	Prio          int           // priority class to be sent in, see TxPrioXXX, not on the wire
 }
 
 //
@@ -180,6 +289,11 @@ type P2pMessage struct {
 	Mid       uint32     // message identity
 	Ping      *Pingpong  // ping message
 	Pong      *Pingpong  // pong message
+	Echo      *Pingpong  // echo request message
+	EchoRsp   *Pingpong  // echo response message
+	Probe     *Pingpong  // reachability probe request message
+	ProbeRsp  *Pingpong  // reachability probe response message
+	RxDrop    *Pingpong  // rx queue drop notice message
 	Handshake *Handshake // handshake message
 	Chkk      *CheckKey  // check key message
 	Rptk      *ReportKey // report key message
@@ -244,8 +358,10 @@ func (upkg *P2pPackage) getHandshakeInbound(inst *PeerInstance) (*Handshake, PeM
 		tcpmsgLog.Debug("getHandshakeInbound:" +
 			"Unmarshal failed, err: %s",
 			err.Error())
+		inst.msgStatDecodeFailed(uint32(PID_P2P), uint32(MID_HANDSHAKE))
 		return nil, PeMgrEnoMessage
 	}
+	inst.msgStatRx(uint32(PID_P2P), uint32(*pbMsg.Mid), len(pkg.Payload))
 
 	if *pbMsg.Mid != MID_HANDSHAKE {
 		tcpmsgLog.Debug("getHandshakeInbound: " +
@@ -255,11 +371,6 @@ func (upkg *P2pPackage) getHandshakeInbound(inst *PeerInstance) (*Handshake, PeM
 	}
 
 	pbHS := pbMsg.Handshake
-	if upkg.verifyInbound(inst, pbHS) != true {
-		tcpmsgLog.Debug("putHandshakeOutbound: verifyInbound failed")
-		return nil, PeMgrEnoVerify
-	}
-
 	if pbHS == nil {
 		tcpmsgLog.Debug("getHandshakeInbound: " +
 			"invalid handshake message pointer: %p",
@@ -295,6 +406,15 @@ func (upkg *P2pPackage) getHandshakeInbound(inst *PeerInstance) (*Handshake, PeM
 	ptrMsg.UDP = *pbHS.UDP
 	ptrMsg.TCP = *pbHS.TCP
 	ptrMsg.ProtoNum = *pbHS.ProtoNum
+	ptrMsg.ClientVersion = clientVersionOf(pbHS.Extra)
+	ptrMsg.DhtPort = pbHS.GetDhtPort()
+	ptrMsg.WsPort = pbHS.GetWsPort()
+	ptrMsg.Encryption = pbHS.GetEncryption()
+	ptrMsg.Compression = pbHS.GetCompression()
+	if len(pbHS.AltIP) != 0 {
+		ptrMsg.AltIP = append(ptrMsg.AltIP, pbHS.AltIP...)
+	}
+	ptrMsg.InstNonce = append(ptrMsg.InstNonce, pbHS.InstNonce...)
 
 	ptrMsg.Protocols = make([]Protocol, len(pbHS.Protocols))
 	for i, p := range pbHS.Protocols {
@@ -302,6 +422,11 @@ func (upkg *P2pPackage) getHandshakeInbound(inst *PeerInstance) (*Handshake, PeM
 		copy(ptrMsg.Protocols[i].Ver[:], p.Ver)
 	}
 
+	if upkg.verifyInbound(inst, pbHS, ptrMsg.Protocols) != true {
+		tcpmsgLog.Debug("getHandshakeInbound: verifyInbound failed")
+		return nil, PeMgrEnoVerify
+	}
+
 	return ptrMsg, PeMgrEnoNone
 }
 
@@ -318,12 +443,31 @@ func (upkg *P2pPackage) putHandshakeOutbound(inst *PeerInstance, hs *Handshake)
 	pbHandshakeMsg.UDP = &hs.UDP
 	pbHandshakeMsg.ProtoNum = &hs.ProtoNum
 	pbHandshakeMsg.Protocols = make([]*pb.P2PMessage_Protocol, *pbHandshakeMsg.ProtoNum)
+	pbHandshakeMsg.Extra = append(pbHandshakeMsg.Extra, hs.ClientVersion...)
+	if hs.DhtPort != 0 {
+		pbHandshakeMsg.DhtPort = &hs.DhtPort
+	}
+	if hs.WsPort != 0 {
+		pbHandshakeMsg.WsPort = &hs.WsPort
+	}
+	if hs.Encryption != config.PeerEncNone {
+		pbHandshakeMsg.Encryption = &hs.Encryption
+	}
+	if hs.Compression != CompressNone {
+		pbHandshakeMsg.Compression = &hs.Compression
+	}
+	if len(hs.AltIP) != 0 {
+		pbHandshakeMsg.AltIP = append(pbHandshakeMsg.AltIP, hs.AltIP...)
+	}
+	if len(hs.InstNonce) != 0 {
+		pbHandshakeMsg.InstNonce = append(pbHandshakeMsg.InstNonce, hs.InstNonce...)
+	}
 
 	for i, p := range hs.Protocols {
 		pbProto := new(pb.P2PMessage_Protocol)
 		pbHandshakeMsg.Protocols[i] = pbProto
 		pbProto.Pid = new(pb.ProtocolId)
-		*pbProto.Pid = PID_P2P
+		*pbProto.Pid = pb.ProtocolId(p.Pid)
 		pbProto.Ver = append(pbProto.Ver, p.Ver[:]...)
 	}
 
@@ -365,6 +509,7 @@ func (upkg *P2pPackage) putHandshakeOutbound(inst *PeerInstance, hs *Handshake)
 		tcpmsgLog.Debug("putHandshakeOutbound: Write failed, err: %s", err.Error())
 		return PeMgrEnoOs
 	}
+	inst.msgStatTx(uint32(PID_P2P), uint32(MID_HANDSHAKE), len(pbPkg.Payload))
 
 	return PeMgrEnoNone
 }
@@ -417,6 +562,7 @@ func (upkg *P2pPackage) ping(inst *PeerInstance, ping *Pingpong, write bool) PeM
 			tcpmsgLog.Debug("ping: Write failed, err: %s", err.Error())
 			return PeMgrEnoOs
 		}
+		inst.msgStatTx(uint32(*pbPkg.Pid), uint32(*pbPkg.ExtMid), len(pbPkg.Payload))
 	}
 
 	return PeMgrEnoNone
@@ -468,6 +614,7 @@ func (upkg *P2pPackage) pong(inst *PeerInstance, pong *Pingpong, write bool) PeM
 			tcpmsgLog.Debug("pong: Write failed, err: %s", err.Error())
 			return PeMgrEnoOs
 		}
+		inst.msgStatTx(uint32(*pbPkg.Pid), uint32(*pbPkg.ExtMid), len(pbPkg.Payload))
 	}
 
 	return PeMgrEnoNone
@@ -525,6 +672,7 @@ func (upkg *P2pPackage) CheckKey(inst *PeerInstance, chkk *CheckKey, write bool)
 			tcpmsgLog.Debug("CheckKey: Write failed, err: %s", err.Error())
 			return PeMgrEnoOs
 		}
+		inst.msgStatTx(uint32(*pbPkg.Pid), uint32(*pbPkg.ExtMid), len(pbPkg.Payload))
 	}
 
 	return PeMgrEnoNone
@@ -584,6 +732,7 @@ func (upkg *P2pPackage) ReportKey(inst *PeerInstance, rptk *ReportKey, write boo
 			tcpmsgLog.Debug("ReportKey: Write failed, err: %s", err.Error())
 			return PeMgrEnoOs
 		}
+		inst.msgStatTx(uint32(*pbPkg.Pid), uint32(*pbPkg.ExtMid), len(pbPkg.Payload))
 	}
 
 	return PeMgrEnoNone
@@ -643,6 +792,7 @@ func (upkg *P2pPackage) GetChainData(inst *PeerInstance, gcd *GetChainData, writ
 			tcpmsgLog.Debug("GetChainData: Write failed, err: %s", err.Error())
 			return PeMgrEnoOs
 		}
+		inst.msgStatTx(uint32(*pbPkg.Pid), uint32(*pbPkg.ExtMid), len(pbPkg.Payload))
 	}
 
 	return PeMgrEnoNone
@@ -705,11 +855,20 @@ func (upkg *P2pPackage) PutChainData(inst *PeerInstance, pcd *PutChainData, writ
 			tcpmsgLog.Debug("PutChainData: Write failed, err: %s", err.Error())
 			return PeMgrEnoOs
 		}
+		inst.msgStatTx(uint32(*pbPkg.Pid), uint32(*pbPkg.ExtMid), len(pbPkg.Payload))
 	}
 
 	return PeMgrEnoNone
 }
 
+//
+// Check if a package queued is expired, a package without TTL or enqueued time
+// never expires
+//
+func (upkg *P2pPackage) expired(now time.Time) bool {
+	return upkg.TTL > 0 && !upkg.Enqueued.IsZero() && now.Sub(upkg.Enqueued) > upkg.TTL
+}
+
 //
 // Send user packege
 //
@@ -727,7 +886,17 @@ func (upkg *P2pPackage) SendPackage(inst *PeerInstance) PeMgrErrno {
 	pbPkg.ExtKey = upkg.Key
 	pbPkg.PayloadLength = new(uint32)
 	*pbPkg.PayloadLength = uint32(upkg.PayloadLength)
-	pbPkg.Payload = append(pbPkg.Payload, upkg.Payload...)
+	if upkg.Pid == uint32(PID_EXT) && inst.compress != CompressNone {
+		// the length is that of the payload not compressed, see compress.go
+		payload, alg := compressPayload(inst.compress, upkg.Payload)
+		if alg != CompressNone {
+			pbPkg.Payload = payload
+			pbPkg.Compression = &alg
+		}
+	}
+	if pbPkg.Compression == nil {
+		pbPkg.Payload = append(pbPkg.Payload, upkg.Payload...)
+	}
 
 	err := (error)(nil)
 	if inst.ato != time.Duration(0) {
@@ -744,6 +913,7 @@ func (upkg *P2pPackage) SendPackage(inst *PeerInstance) PeMgrErrno {
 		tcpmsgLog.Debug("SendPackage: Write failed, err: %s", err.Error())
 		return PeMgrEnoOs
 	}
+	inst.msgStatTx(uint32(*pbPkg.Pid), uint32(*pbPkg.ExtMid), len(pbPkg.Payload))
 	return PeMgrEnoNone
 }
 
@@ -773,7 +943,7 @@ func (upkg *P2pPackage) RecvPackage(inst *PeerInstance) PeMgrErrno {
 	}
 
 	pid := uint32(*pkg.Pid)
-	if pid != uint32(PID_P2P) && pid != uint32(PID_EXT) {
+	if pid != uint32(PID_P2P) && pid != uint32(PID_EXT) && inst.peMgr.protoHandlers.get(pid) == nil {
 		tcpmsgLog.Debug("RecvPackage: " +
 			"Invalid protocol identity: %d",
 			pid)
@@ -782,7 +952,7 @@ func (upkg *P2pPackage) RecvPackage(inst *PeerInstance) PeMgrErrno {
 
 	upkg.Pid = pid
 	upkg.PayloadLength = *pkg.PayloadLength
-	if upkg.Pid == uint32(PID_EXT) {
+	if upkg.Pid != uint32(PID_P2P) {
 		upkg.Mid = uint32(*pkg.ExtMid)
 		if len(pkg.ExtKey) > 0 {
 			upkg.Key = append(upkg.Key[0:], pkg.ExtKey...)
@@ -790,9 +960,32 @@ func (upkg *P2pPackage) RecvPackage(inst *PeerInstance) PeMgrErrno {
 			upkg.Key = make([]byte, 0)
 		}
 	}
+	if alg := pkg.GetCompression(); alg != CompressNone {
+		if pid != uint32(PID_EXT) || alg != inst.compress {
+			tcpmsgLog.Debug("RecvPackage: compression not negotiated, pid: %d, alg: %d", pid, alg)
+			return PeMgrEnoMessage
+		}
+		payload, err := decompressPayload(alg, pkg.Payload, int(upkg.PayloadLength), inst.maxPkgSize)
+		if err != nil {
+			tcpmsgLog.Debug("RecvPackage: decompress failed, err: %s", err.Error())
+			return PeMgrEnoMessage
+		}
+		pkg.Payload = payload
+	}
 	if upkg.PayloadLength > 0 {
 		upkg.Payload = append(upkg.Payload, pkg.Payload...)
 	}
+
+	extMid := MID_INVALID
+	if pkg.ExtMid != nil {
+		extMid = *pkg.ExtMid
+	}
+	mid := uint32(extMid)
+	if upkg.Pid == uint32(PID_P2P) {
+		// the decoded message tells the real one, see GetMessage
+		upkg.Mid = mid
+	}
+	inst.msgStatRx(pid, mid, len(pkg.Payload))
 	return PeMgrEnoNone
 }
 
@@ -814,6 +1007,11 @@ func (upkg *P2pPackage) GetMessage(pmsg *P2pMessage) PeMgrErrno {
 	pmsg.Handshake = nil
 	pmsg.Ping = nil
 	pmsg.Pong = nil
+	pmsg.Echo = nil
+	pmsg.EchoRsp = nil
+	pmsg.Probe = nil
+	pmsg.ProbeRsp = nil
+	pmsg.RxDrop = nil
 	if pmsg.Mid == uint32(MID_HANDSHAKE) {
 		hs := new(Handshake)
 		pmsg.Handshake = hs
@@ -836,6 +1034,31 @@ func (upkg *P2pPackage) GetMessage(pmsg *P2pMessage) PeMgrErrno {
 		pong.Seq = *pbMsg.Pong.Seq
 		pong.Extra = append(pong.Extra, pbMsg.Pong.Extra...)
 
+	} else if pmsg.Mid == uint32(MID_ECHO) && pbMsg.Ping != nil {
+		echo := new(Pingpong)
+		pmsg.Echo = echo
+		echo.Seq = *pbMsg.Ping.Seq
+		echo.Extra = append(echo.Extra, pbMsg.Ping.Extra...)
+	} else if pmsg.Mid == uint32(MID_ECHORSP) && pbMsg.Pong != nil {
+		echoRsp := new(Pingpong)
+		pmsg.EchoRsp = echoRsp
+		echoRsp.Seq = *pbMsg.Pong.Seq
+		echoRsp.Extra = append(echoRsp.Extra, pbMsg.Pong.Extra...)
+	} else if pmsg.Mid == uint32(MID_PROBE) && pbMsg.Ping != nil {
+		probe := new(Pingpong)
+		pmsg.Probe = probe
+		probe.Seq = *pbMsg.Ping.Seq
+		probe.Extra = append(probe.Extra, pbMsg.Ping.Extra...)
+	} else if pmsg.Mid == uint32(MID_PROBERSP) && pbMsg.Pong != nil {
+		probeRsp := new(Pingpong)
+		pmsg.ProbeRsp = probeRsp
+		probeRsp.Seq = *pbMsg.Pong.Seq
+		probeRsp.Extra = append(probeRsp.Extra, pbMsg.Pong.Extra...)
+	} else if pmsg.Mid == uint32(MID_RXDROP) && pbMsg.Ping != nil {
+		rxDrop := new(Pingpong)
+		pmsg.RxDrop = rxDrop
+		rxDrop.Seq = *pbMsg.Ping.Seq
+		rxDrop.Extra = append(rxDrop.Extra, pbMsg.Ping.Extra...)
 	} else {
 		tcpmsgLog.Debug("GetMessage: unknown message identity: %d", pmsg.Mid)
 		return PeMgrEnoMessage
@@ -894,6 +1117,11 @@ func (upkg *P2pPackage) GetExtMessage(extMsg *ExtMessage) PeMgrErrno {
 	return PeMgrEnoNone
 }
 
+//
+// Sign the handshake, see hsauth.go: over the node identity for legacy peers,
+// and with a nonce in AuthSig. the inbound side signs also the nonce of the
+// handshake it answers.
+//
 func (upkg *P2pPackage) signOutbound(inst *PeerInstance, hs *pb.P2PMessage_Handshake) bool {
 	r, s, err := config.P2pSign(&inst.priKey, hs.NodeId)
 	if err != nil {
@@ -906,14 +1134,72 @@ func (upkg *P2pPackage) signOutbound(inst *PeerInstance, hs *pb.P2PMessage_Hands
 	hs.SignS = new(int32)
 	*hs.SignS = int32(config.P2pSignBigInt(s))
 	hs.S = append(hs.S, config.P2pBigIntAbs2Bytes(s)...)
+
+	var answered []byte
+	hs.Nonce = newHsNonce(time.Now())
+	if inst.dir == PeInstDirOutbound {
+		inst.hsNonce = hs.Nonce
+	} else {
+		answered = inst.hsPeerNonce
+	}
+	if hs.AuthSig, err = hsAuthSign(&inst.priKey, handshakeDigest(hs, answered)); err != nil {
+		tcpmsgLog.Debug("signOutbound: hsAuthSign failed, error: %s", err.Error())
+		return false
+	}
 	return true
 }
 
-func (upkg *P2pPackage) verifyInbound(inst *PeerInstance, hs *pb.P2PMessage_Handshake) bool {
+//
+// Verify the handshake is signed by the node claimed. AuthSig is required if
+// config.P2pProtoVerAuth or higher agreed with the peer, or hsAuthRequired
+// configured, see hsauth.go. for the outbound side, AuthSig must answer the
+// nonce sent; for the inbound side, the nonce must be fresh.
+//
+func (upkg *P2pPackage) verifyInbound(inst *PeerInstance, hs *pb.P2PMessage_Handshake, protocols []Protocol) bool {
 	pubKey := config.P2pNodeId2Pubkey(hs.NodeId)
+	if pubKey.X == nil {
+		tcpmsgLog.Debug("verifyInbound: invalid node identity")
+		return false
+	}
+	if hs.SignR == nil || hs.SignS == nil {
+		tcpmsgLog.Debug("verifyInbound: not signed")
+		return false
+	}
 	r := config.P2pBigInt(int(*hs.SignR), hs.R)
 	s := config.P2pBigInt(int(*hs.SignS), hs.S)
-	return config.P2pVerify(pubKey, hs.NodeId, r, s)
+	if !config.P2pVerify(pubKey, hs.NodeId, r, s) {
+		return false
+	}
+
+	if len(hs.AuthSig) == 0 {
+		agreed := negotiateProtocols(inst.localProtocols, protocols)
+		if p2pAgreed(agreed, config.P2pProtoVerAuth) {
+			tcpmsgLog.Debug("verifyInbound: not signed with nonce, version agreed requires it")
+			return false
+		}
+		if inst.peMgr != nil && inst.peMgr.cfg.hsAuthRequired {
+			tcpmsgLog.Debug("verifyInbound: not signed with nonce, configured to require it")
+			return false
+		}
+		return true
+	}
+
+	var answered []byte
+	if inst.dir == PeInstDirOutbound {
+		answered = inst.hsNonce
+	}
+	if len(hs.Nonce) != hsNonceLen || !hsAuthVerify(pubKey, handshakeDigest(hs, answered), hs.AuthSig) {
+		tcpmsgLog.Debug("verifyInbound: nonce signature invalid")
+		return false
+	}
+	if inst.dir != PeInstDirOutbound {
+		if !inst.peMgr.hsNonces.check(hs.Nonce, time.Now()) {
+			tcpmsgLog.Debug("verifyInbound: nonce expired or replayed")
+			return false
+		}
+		inst.hsPeerNonce = hs.Nonce
+	}
+	return true
 }
 
 func (upkg *P2pPackage) String() string {
@@ -0,0 +1,53 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPackageExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		pkg     P2pPackage
+		expired bool
+	}{
+		{P2pPackage{}, false},
+		{P2pPackage{Enqueued: now.Add(-time.Hour)}, false},
+		{P2pPackage{TTL: time.Second}, false},
+		{P2pPackage{Enqueued: now.Add(-500 * time.Millisecond), TTL: time.Second}, false},
+		{P2pPackage{Enqueued: now.Add(-2 * time.Second), TTL: time.Second}, true},
+	}
+	for i, test := range tests {
+		if got := test.pkg.expired(now); got != test.expired {
+			t.Errorf("test %d: expired() got %t, want %t", i, got, test.expired)
+		}
+	}
+
+	pi := &PeerInstance{peMgr: &PeerManager{msgStats: newMsgStats()}}
+	pi.msgStatExpired(uint32(PID_EXT), 1)
+	pi.msgStatExpired(uint32(PID_EXT), 1)
+	stats := pi.peMgr.GetMsgStats()
+	if len(stats) != 1 || stats[0].Dir != MsgStatTx || stats[0].Expired != 2 {
+		t.Errorf("GetMsgStats() got %+v", stats)
+	}
+}
@@ -0,0 +1,295 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Transports for peer connections: the tcp one is applied if none is configured,
+// and an in-memory one is provided to run peers in tests without sockets, where
+// connections are made of pipes.
+//
+
+type tcpTransport struct{}
+
+func (tt tcpTransport) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	return dialer.Dial("tcp", addr)
+}
+
+func (tt tcpTransport) Listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
+
+func peTransport(t config.PeerTransport) config.PeerTransport {
+	if t == nil {
+		return tcpTransport{}
+	}
+	return t
+}
+
+//
+// In-memory network: listeners are registered by address, and each endpoint
+// got by function Transport dials from the ip address specified.
+//
+const (
+	memAcceptQueueSize = 16    // connections pending to be accepted
+	memPortBase        = 40000 // base of ports allocated for outbound connections
+)
+
+var (
+	errMemRefused = errors.New("connection refused")
+	errMemClosed  = errors.New("use of closed listener")
+	errMemInUse   = errors.New("address already in use")
+)
+
+type memTimeoutError struct{}
+
+func (memTimeoutError) Error() string   { return "i/o timeout" }
+func (memTimeoutError) Timeout() bool   { return true }
+func (memTimeoutError) Temporary() bool { return true }
+
+type MemNetwork struct {
+	lock      sync.Mutex              // lock to protect the network
+	listeners map[string]*memListener // listeners by address
+	nextPort  int                     // next port for outbound connections
+}
+
+type memEndpoint struct {
+	network *MemNetwork // network the endpoint belongs to
+	ip      net.IP      // ip address dialing from
+}
+
+type memListener struct {
+	network  *MemNetwork   // network the listener belongs to
+	addr     *net.TCPAddr  // address listening on
+	conns    chan net.Conn // connections pending to be accepted
+	done     chan struct{} // closed when listener closed
+	once     sync.Once     // to close once
+	lock     sync.Mutex    // lock to protect deadline
+	deadline time.Time     // deadline for accepting, zero for none
+}
+
+type memConn struct {
+	net.Conn              // one end of pipe
+	local    *net.TCPAddr // local address
+	remote   *net.TCPAddr // remote address
+}
+
+func NewMemNetwork() *MemNetwork {
+	return &MemNetwork{
+		listeners: make(map[string]*memListener, 0),
+		nextPort:  memPortBase,
+	}
+}
+
+func (mn *MemNetwork) Transport(ip net.IP) config.PeerTransport {
+	return &memEndpoint{
+		network: mn,
+		ip:      ip,
+	}
+}
+
+func (me *memEndpoint) Listen(addr string) (net.Listener, error) {
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mn := me.network
+	mn.lock.Lock()
+	defer mn.lock.Unlock()
+	key := tcpAddr.String()
+	if _, dup := mn.listeners[key]; dup {
+		return nil, &net.OpError{Op: "listen", Net: "mem", Addr: tcpAddr, Err: errMemInUse}
+	}
+	ml := &memListener{
+		network: mn,
+		addr:    tcpAddr,
+		conns:   make(chan net.Conn, memAcceptQueueSize),
+		done:    make(chan struct{}),
+	}
+	mn.listeners[key] = ml
+	return ml, nil
+}
+
+func (me *memEndpoint) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	tcpAddr, err := net.ResolveTCPAddr("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	mn := me.network
+	mn.lock.Lock()
+	ml, ok := mn.listeners[tcpAddr.String()]
+	local := &net.TCPAddr{IP: me.ip, Port: mn.nextPort}
+	mn.nextPort++
+	mn.lock.Unlock()
+	if !ok {
+		return nil, &net.OpError{Op: "dial", Net: "mem", Addr: tcpAddr, Err: errMemRefused}
+	}
+
+	c1, c2 := net.Pipe()
+	client := &memConn{Conn: c1, local: local, remote: ml.addr}
+	server := &memConn{Conn: c2, local: ml.addr, remote: local}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		tm := time.NewTimer(timeout)
+		defer tm.Stop()
+		expired = tm.C
+	}
+	select {
+	case ml.conns <- server:
+		return client, nil
+	case <-ml.done:
+		err = errMemRefused
+	case <-expired:
+		err = memTimeoutError{}
+	}
+	c1.Close()
+	c2.Close()
+	return nil, &net.OpError{Op: "dial", Net: "mem", Addr: tcpAddr, Err: err}
+}
+
+func (ml *memListener) Accept() (net.Conn, error) {
+	ml.lock.Lock()
+	deadline := ml.deadline
+	ml.lock.Unlock()
+	var expired <-chan time.Time
+	if !deadline.IsZero() {
+		tm := time.NewTimer(time.Until(deadline))
+		defer tm.Stop()
+		expired = tm.C
+	}
+	select {
+	case conn := <-ml.conns:
+		return conn, nil
+	case <-ml.done:
+		return nil, &net.OpError{Op: "accept", Net: "mem", Addr: ml.addr, Err: errMemClosed}
+	case <-expired:
+		return nil, &net.OpError{Op: "accept", Net: "mem", Addr: ml.addr, Err: memTimeoutError{}}
+	}
+}
+
+func (ml *memListener) SetDeadline(t time.Time) error {
+	ml.lock.Lock()
+	ml.deadline = t
+	ml.lock.Unlock()
+	return nil
+}
+
+func (ml *memListener) Close() error {
+	ml.once.Do(func() {
+		mn := ml.network
+		mn.lock.Lock()
+		delete(mn.listeners, ml.addr.String())
+		mn.lock.Unlock()
+		close(ml.done)
+	})
+	return nil
+}
+
+func (ml *memListener) Addr() net.Addr {
+	return ml.addr
+}
+
+func (mc *memConn) LocalAddr() net.Addr {
+	return mc.local
+}
+
+func (mc *memConn) RemoteAddr() net.Addr {
+	return mc.remote
+}
+
+//
+// Listener merging those of several transports or addresses, such as the ones
+// of the other address family and of websocket, see listener.go. the address
+// of it is that of the first one, as the one to be announced.
+//
+const muxRetryDelay = 10 * time.Millisecond // delay to accept again after a temporary error
+
+type muxListener struct {
+	listeners []net.Listener // listeners merged
+	conns     chan net.Conn  // connections accepted
+	done      chan struct{}  // closed when closed
+	once      sync.Once      // to close once
+}
+
+func newMuxListener(listeners ...net.Listener) *muxListener {
+	ml := &muxListener{
+		listeners: listeners,
+		conns:     make(chan net.Conn),
+		done:      make(chan struct{}),
+	}
+	for _, l := range listeners {
+		go ml.acceptFrom(l)
+	}
+	return ml
+}
+
+func (ml *muxListener) acceptFrom(l net.Listener) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(muxRetryDelay)
+				continue
+			}
+			lsnLog.Debug("acceptFrom: listener done, addr: %s, err: %s", l.Addr().String(), err.Error())
+			return
+		}
+		select {
+		case ml.conns <- conn:
+		case <-ml.done:
+			conn.Close()
+			return
+		}
+	}
+}
+
+func (ml *muxListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-ml.conns:
+		return conn, nil
+	case <-ml.done:
+		return nil, &net.OpError{Op: "accept", Net: "mux", Addr: ml.Addr(), Err: net.ErrClosed}
+	}
+}
+
+func (ml *muxListener) Close() error {
+	ml.once.Do(func() {
+		close(ml.done)
+		for _, l := range ml.listeners {
+			l.Close()
+		}
+	})
+	return nil
+}
+
+func (ml *muxListener) Addr() net.Addr {
+	return ml.listeners[0].Addr()
+}
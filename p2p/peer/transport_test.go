@@ -0,0 +1,134 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestMemNetwork(t *testing.T) {
+	mn := NewMemNetwork()
+	ta := mn.Transport(net.ParseIP("10.0.0.1"))
+	tb := mn.Transport(net.ParseIP("10.0.0.2"))
+
+	if _, err := tb.Dial("10.0.0.1:30303", time.Second); err == nil {
+		t.Fatal("dial without listener should fail")
+	}
+
+	lsn, err := ta.Listen("10.0.0.1:30303")
+	if err != nil {
+		t.Fatalf("listen failed: %s", err)
+	}
+	if _, err := ta.Listen("10.0.0.1:30303"); err == nil {
+		t.Fatal("listen twice on an address should fail")
+	}
+
+	go func() {
+		conn, err := lsn.Accept()
+		if err != nil {
+			return
+		}
+		buf := make([]byte, 4)
+		if _, err := conn.Read(buf); err == nil {
+			conn.Write(buf)
+		}
+		conn.Close()
+	}()
+
+	conn, err := tb.Dial("10.0.0.1:30303", time.Second)
+	if err != nil {
+		t.Fatalf("dial failed: %s", err)
+	}
+	if raddr := conn.RemoteAddr().(*net.TCPAddr); raddr.String() != "10.0.0.1:30303" {
+		t.Errorf("remote address mismatched: %s", raddr)
+	}
+	if laddr := conn.LocalAddr().(*net.TCPAddr); !laddr.IP.Equal(net.ParseIP("10.0.0.2")) {
+		t.Errorf("local address mismatched: %s", laddr)
+	}
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := conn.Read(buf); err != nil || string(buf) != "ping" {
+		t.Errorf("echo mismatched: %s, err: %v", buf, err)
+	}
+	conn.Close()
+
+	lsn.Close()
+	if _, err := lsn.Accept(); err == nil || err.(net.Error).Temporary() {
+		t.Error("accept on closed listener should fail permanently")
+	}
+	if _, err := tb.Dial("10.0.0.1:30303", time.Second); err == nil {
+		t.Error("dial to closed listener should fail")
+	}
+}
+
+func muxTestEcho(t *testing.T, ml net.Listener, conn net.Conn, what string) {
+	accepted, err := ml.Accept()
+	if err != nil {
+		t.Fatalf("%s: accept: %v", what, err)
+	}
+	defer accepted.Close()
+	if _, ok := accepted.RemoteAddr().(*net.TCPAddr); !ok {
+		t.Errorf("%s: remote address %T", what, accepted.RemoteAddr())
+	}
+	go io.Copy(accepted, accepted)
+	conn.SetDeadline(time.Now().Add(time.Second * 5))
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("%s: echo got %q, %v", what, buf, err)
+	}
+}
+
+func TestMuxListener(t *testing.T) {
+	l1, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l2, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ml := newMuxListener(l1, l2)
+	if ml.Addr().String() != l1.Addr().String() {
+		t.Errorf("listener address %s, want the first one %s", ml.Addr(), l1.Addr())
+	}
+	for i, l := range []net.Listener{l1, l2} {
+		conn, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		muxTestEcho(t, ml, conn, fmt.Sprintf("listener %d", i))
+		conn.Close()
+	}
+
+	// closed, the ones merged too
+	ml.Close()
+	if _, err := ml.Accept(); err == nil {
+		t.Errorf("accepted after closed")
+	}
+	if _, err := net.Dial("tcp", l2.Addr().String()); err == nil {
+		t.Errorf("dialed a listener merged after closed")
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+//
+// Tx queue of a peer instance: packages to be sent are queued by their priority
+// classes, piTx drains the higher classes first, so consensus messages are not
+// stuck behind blocks or bulk data. each class has its own channel, one class
+// full does not block the others. packages not tagged are queued as transactions.
+//
+
+const (
+	TxPrioNone      = iota // not tagged, queued as TxPrioTxs
+	TxPrioConsensus        // consensus messages, the highest
+	TxPrioBlocks           // block headers and blocks
+	TxPrioTxs              // transactions
+	TxPrioBulk             // bulk data, such as chain data sync, the lowest
+)
+
+const txPrioClasses = TxPrioBulk // number of classes
+
+type TxQueue struct {
+	queues [txPrioClasses]chan *P2pPackage // queues by class, the highest first
+}
+
+func newTxQueue(size int) *TxQueue {
+	q := TxQueue{}
+	for idx := range q.queues {
+		q.queues[idx] = make(chan *P2pPackage, size)
+	}
+	return &q
+}
+
+// queue of priority class prio
+func (q *TxQueue) queue(prio int) chan *P2pPackage {
+	if prio <= TxPrioNone || prio > TxPrioBulk {
+		prio = TxPrioTxs
+	}
+	return q.queues[prio-TxPrioConsensus]
+}
+
+// If the queue of priority class prio is full
+func (q *TxQueue) Full(prio int) bool {
+	ch := q.queue(prio)
+	return len(ch) >= cap(ch)
+}
+
+// Queue a package by its priority class, it's blocked if the queue is full, so
+// check it with Full before
+func (q *TxQueue) Put(pkg *P2pPackage) {
+	q.queue(pkg.Prio) <- pkg
+}
+
+// Number of packages queued
+func (q *TxQueue) Len() int {
+	n := 0
+	for _, ch := range q.queues {
+		n += len(ch)
+	}
+	return n
+}
+
+func (q *TxQueue) close() {
+	for _, ch := range q.queues {
+		close(ch)
+	}
+}
+
+// next package to be sent, blocked until one is available. pings and pongs in
+// ppChan are taken first, then packages by class. ok is false if the queue had
+// been closed.
+func (q *TxQueue) next(ppChan chan *P2pPackage) (pkg *P2pPackage, ok bool, isPP bool) {
+	select {
+	case pkg, ok = <-ppChan:
+		return pkg, ok, true
+	default:
+	}
+	for _, ch := range q.queues {
+		select {
+		case pkg, ok = <-ch:
+			return pkg, ok, false
+		default:
+		}
+	}
+	select {
+	case pkg, ok = <-ppChan:
+		return pkg, ok, true
+	case pkg, ok = <-q.queues[0]:
+	case pkg, ok = <-q.queues[1]:
+	case pkg, ok = <-q.queues[2]:
+	case pkg, ok = <-q.queues[3]:
+	}
+	return pkg, ok, false
+}
@@ -0,0 +1,55 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+)
+
+func TestTxQueue(t *testing.T) {
+	q := newTxQueue(2)
+	ppChan := make(chan *P2pPackage, 1)
+	for mid, prio := range []int{TxPrioBulk, TxPrioNone, TxPrioBlocks, TxPrioConsensus} {
+		q.Put(&P2pPackage{Mid: uint32(mid), Prio: prio})
+	}
+	ppChan <- &P2pPackage{Mid: 100}
+	if q.Len() != 4 {
+		t.Errorf("len got %d, want 4", q.Len())
+	}
+
+	q.Put(&P2pPackage{Mid: 4, Prio: TxPrioTxs})
+	if !q.Full(TxPrioTxs) || !q.Full(TxPrioNone) || q.Full(TxPrioConsensus) {
+		t.Errorf("full got %v %v %v", q.Full(TxPrioTxs), q.Full(TxPrioNone), q.Full(TxPrioConsensus))
+	}
+
+	// pings first, then by class, packages of a class in order
+	want := []uint32{100, 3, 2, 1, 4, 0}
+	for _, mid := range want {
+		pkg, ok, _ := q.next(ppChan)
+		if !ok || pkg.Mid != mid {
+			t.Fatalf("next got %+v, want mid %d", pkg, mid)
+		}
+	}
+	q.close()
+	if _, ok, isPP := q.next(ppChan); ok || isPP {
+		t.Errorf("next after closed got ok %v, isPP %v", ok, isPP)
+	}
+}
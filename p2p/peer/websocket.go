@@ -0,0 +1,194 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	"golang.org/x/net/websocket"
+)
+
+//
+// Websocket transport: peer connections carried in binary websocket frames, the
+// stream in them framed by P2pPackage as it is on tcp, so the handshake and all
+// after it are the same. the listener manager listens on the websocket port
+// besides when one is configured, and the port is announced in handshake. light
+// clients behind firewalls letting http through only can take the transport got
+// by NewWsTransport as config.PeerTransport, to dial nodes at their websocket
+// ports.
+//
+
+const (
+	wsPath             = "/gyee-p2p"        // path of the websocket endpoint
+	wsOrigin           = "http://gyee-p2p/" // origin of clients, not checked by listeners
+	wsHandshakeTimeout = 10 * time.Second   // max time to read the http request of a client
+)
+
+type wsTransport struct{}
+
+// Transport dialing and listening on websocket
+func NewWsTransport() config.PeerTransport {
+	return wsTransport{}
+}
+
+func (wt wsTransport) Dial(addr string, timeout time.Duration) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	wc, err := websocket.NewConfig("ws://"+addr+wsPath, wsOrigin)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	ws, err := websocket.NewClient(wc, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return newWsConn(ws, conn.LocalAddr().(*net.TCPAddr), conn.RemoteAddr().(*net.TCPAddr)), nil
+}
+
+func (wt wsTransport) Listen(addr string) (net.Listener, error) {
+	lsn, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	wl := &wsListener{
+		lsn:   lsn,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+	mux := http.NewServeMux()
+	mux.Handle(wsPath, websocket.Server{
+		Handler:   wl.serve,
+		Handshake: wsHandshake,
+	})
+	wl.srv = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: wsHandshakeTimeout,
+	}
+	go wl.srv.Serve(lsn)
+	return wl, nil
+}
+
+// Origins are not checked, clients are not browsers mostly, and peers are
+// identified by the handshake of this package anyway.
+func wsHandshake(*websocket.Config, *http.Request) error {
+	return nil
+}
+
+// Connection on websocket, addresses reported as tcp ones, as the peer manager
+// expects, see config.PeerTransport.
+type wsConn struct {
+	*websocket.Conn
+	laddr  *net.TCPAddr  // local address
+	raddr  *net.TCPAddr  // remote address
+	closed chan struct{} // closed when closed
+	once   sync.Once     // to close once
+}
+
+func newWsConn(ws *websocket.Conn, laddr, raddr *net.TCPAddr) *wsConn {
+	ws.PayloadType = websocket.BinaryFrame
+	return &wsConn{
+		Conn:   ws,
+		laddr:  laddr,
+		raddr:  raddr,
+		closed: make(chan struct{}),
+	}
+}
+
+func (wc *wsConn) LocalAddr() net.Addr {
+	return wc.laddr
+}
+
+func (wc *wsConn) RemoteAddr() net.Addr {
+	return wc.raddr
+}
+
+func (wc *wsConn) Close() error {
+	var err error
+	wc.once.Do(func() {
+		close(wc.closed)
+		err = wc.Conn.Close()
+	})
+	return err
+}
+
+type wsListener struct {
+	lsn   net.Listener  // tcp listener served
+	srv   *http.Server  // http server upgrading requests
+	conns chan net.Conn // connections accepted
+	done  chan struct{} // closed when closed
+	once  sync.Once     // to close once
+}
+
+// Serve a websocket connection upgraded, it's closed by the http server once
+// returned, so it's not till closed by the peer instance.
+func (wl *wsListener) serve(ws *websocket.Conn) {
+	req := ws.Request()
+	raddr, err := net.ResolveTCPAddr("tcp", req.RemoteAddr)
+	if err != nil {
+		return
+	}
+	laddr, ok := req.Context().Value(http.LocalAddrContextKey).(*net.TCPAddr)
+	if !ok {
+		return
+	}
+	wc := newWsConn(ws, laddr, raddr)
+	select {
+	case wl.conns <- wc:
+	case <-wl.done:
+		return
+	}
+	<-wc.closed
+}
+
+func (wl *wsListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-wl.conns:
+		return conn, nil
+	case <-wl.done:
+		return nil, &net.OpError{Op: "accept", Net: "ws", Addr: wl.Addr(), Err: net.ErrClosed}
+	}
+}
+
+// Close the listener, connections accepted are kept
+func (wl *wsListener) Close() error {
+	wl.once.Do(func() {
+		close(wl.done)
+		wl.srv.Close()
+	})
+	return nil
+}
+
+func (wl *wsListener) Addr() net.Addr {
+	return wl.lsn.Addr()
+}
@@ -0,0 +1,94 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestWsTransport(t *testing.T) {
+	wt := NewWsTransport()
+	wl, err := wt.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wl.Close()
+	tl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ml := newMuxListener(tl, wl)
+	defer ml.Close()
+
+	conn, err := wt.Dial(wl.Addr().String(), time.Second*5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.RemoteAddr().String() != wl.Addr().String() {
+		t.Errorf("remote address %s, want %s", conn.RemoteAddr(), wl.Addr())
+	}
+	muxTestEcho(t, ml, conn, "ws")
+
+	// handshake framed by P2pPackage as on tcp
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, _ := ml.Accept()
+		accepted <- c
+	}()
+	conn2, err := wt.Dial(wl.Addr().String(), time.Second*5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+	peer := <-accepted
+	if peer == nil {
+		t.Fatal("not accepted")
+	}
+	defer peer.Close()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tx := &PeerInstance{priKey: *key, conn: conn2, maxPkgSize: maxTcpmsgSize}
+	rx := &PeerInstance{peMgr: &PeerManager{hsNonces: newHsNonces()}, priKey: *key, conn: peer, maxPkgSize: maxTcpmsgSize}
+	hs := &Handshake{
+		NodeId: *config.P2pPubkey2NodeId(&key.PublicKey),
+		IP:     net.ParseIP("10.0.0.1"),
+		TCP:    30303,
+		WsPort: 30305,
+	}
+	go new(P2pPackage).putHandshakeOutbound(tx, hs)
+	got, eno := new(P2pPackage).getHandshakeInbound(rx)
+	if eno != PeMgrEnoNone || got.WsPort != hs.WsPort {
+		t.Fatalf("handshake got %+v, eno: %d", got, eno)
+	}
+
+	// closed, the listener takes no more
+	ml.Close()
+	if _, err := wt.Dial(wl.Addr().String(), time.Second); err == nil {
+		t.Errorf("dialed after the listener closed")
+	}
+}
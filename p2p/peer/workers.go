@@ -0,0 +1,134 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"encoding/binary"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+//
+// Worker pools of protocols registered: packages of a protocol registered at
+// runtime are queued by piRx to the pool of the protocol and passed to the
+// handler by the workers of it, so a slow handler stalls neither the rx routine
+// of the peer(and so the PID_EXT packages from the same peer, consensus ones for
+// example) nor the handlers of other protocols. Notice:
+// 1) at most Workers packages of a protocol are handled concurrently;
+// 2) when Ordered, the packages of a peer are always handled by the worker the
+// peer mapped to, one by one in the order they are received; otherwise they are
+// handled by any idle worker, nothing is guaranteed for the order;
+// 3) when the queue is full, the newest package is dropped and told to the peer
+// as those dropped for the rx queue full, see piRxDrop.
+//
+
+const (
+	ProtoPoolMaxWorkers = 64        // max workers of a pool
+	ProtoPoolMinQueue   = 16        // min packages queued of a pool
+	ProtoPoolMaxQueue   = 1024 * 16 // max packages queued of a pool
+)
+
+// Worker pool of a protocol registered, see RegisterProtocolPool
+type ProtocolPool struct {
+	Workers int  // handlers called concurrently at most
+	Queue   int  // packages queued waiting workers in total
+	Ordered bool // packages of a peer handled in order
+}
+
+// Pool for protocols registered by RegisterProtocol
+var DftProtocolPool = ProtocolPool{
+	Workers: 4,
+	Queue:   256,
+	Ordered: true,
+}
+
+type protoPool struct {
+	handler ProtocolHandler      // handler of the protocol
+	queues  []chan *P2pPackageRx // one shared by workers, or one for each when ordered
+	done    chan struct{}        // closed when the protocol unregistered
+}
+
+func newProtoPool(handler ProtocolHandler, cfg ProtocolPool) *protoPool {
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	} else if workers > ProtoPoolMaxWorkers {
+		workers = ProtoPoolMaxWorkers
+	}
+	size := cfg.Queue
+	if size < ProtoPoolMinQueue {
+		size = ProtoPoolMinQueue
+	} else if size > ProtoPoolMaxQueue {
+		size = ProtoPoolMaxQueue
+	}
+	qn := 1
+	if cfg.Ordered {
+		qn = workers
+	}
+	pp := &protoPool{
+		handler: handler,
+		queues:  make([]chan *P2pPackageRx, qn),
+		done:    make(chan struct{}),
+	}
+	for i := range pp.queues {
+		pp.queues[i] = make(chan *P2pPackageRx, (size+qn-1)/qn)
+	}
+	for w := 0; w < workers; w++ {
+		go pp.work(pp.queues[w%qn])
+	}
+	return pp
+}
+
+func (pp *protoPool) work(queue chan *P2pPackageRx) {
+	for {
+		select {
+		case pkg := <-queue:
+			pp.handler(pkg)
+		case <-pp.done:
+			return
+		}
+	}
+}
+
+// Queue a package to the pool, false if the queue is full
+func (pp *protoPool) submit(pkg *P2pPackageRx) bool {
+	queue := pp.queues[0]
+	if len(pp.queues) > 1 {
+		queue = pp.queues[protoPoolSlot(&pkg.PeerInfo.NodeId, len(pp.queues))]
+	}
+	select {
+	case queue <- pkg:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop the workers, packages queued are discarded
+func (pp *protoPool) stop() {
+	close(pp.done)
+}
+
+// Worker a peer mapped to, node identities are public keys, any bytes of them
+// are spread well enough.
+func protoPoolSlot(id *config.NodeID, n int) int {
+	return int(binary.BigEndian.Uint32(id[len(id)-4:]) % uint32(n))
+}
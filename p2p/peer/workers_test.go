@@ -0,0 +1,87 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package peer
+
+import (
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+)
+
+func TestProtoPoolOrdered(t *testing.T) {
+	slow, fast := config.NodeID{}, config.NodeID{}
+	slow[len(slow)-1], fast[len(fast)-1] = 0, 1
+	release := make(chan struct{})
+	handled := make(chan *P2pPackageRx, 64)
+	pp := newProtoPool(func(pkg *P2pPackageRx) {
+		if pkg.PeerInfo.NodeId == slow && pkg.MsgId == 0 {
+			<-release
+		}
+		handled <- pkg
+	}, ProtocolPool{Workers: 2, Queue: 64, Ordered: true})
+	defer pp.stop()
+
+	for mid := 0; mid < 4; mid++ {
+		for _, id := range []config.NodeID{slow, fast} {
+			if !pp.submit(&P2pPackageRx{PeerInfo: &PeerInfo{NodeId: id}, MsgId: mid}) {
+				t.Fatalf("submit failed, mid: %d", mid)
+			}
+		}
+	}
+
+	// the peer with a handler blocked stalls not the other one
+	for mid := 0; mid < 4; mid++ {
+		select {
+		case pkg := <-handled:
+			if pkg.PeerInfo.NodeId != fast || pkg.MsgId != mid {
+				t.Fatalf("handled %x:%d, want fast:%d", pkg.PeerInfo.NodeId[len(slow)-1], pkg.MsgId, mid)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("stalled by the slow peer")
+		}
+	}
+
+	// and the slow one is handled in order when released
+	close(release)
+	for mid := 0; mid < 4; mid++ {
+		if pkg := <-handled; pkg.PeerInfo.NodeId != slow || pkg.MsgId != mid {
+			t.Fatalf("handled %x:%d, want slow:%d", pkg.PeerInfo.NodeId[len(slow)-1], pkg.MsgId, mid)
+		}
+	}
+}
+
+func TestProtoPoolFull(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	pp := newProtoPool(func(pkg *P2pPackageRx) { <-block }, ProtocolPool{Workers: 1, Queue: 1})
+	defer pp.stop()
+	pkg := &P2pPackageRx{PeerInfo: &PeerInfo{}}
+	n := 0
+	for pp.submit(pkg) {
+		if n++; n > ProtoPoolMinQueue+1 {
+			t.Fatalf("queue not bounded")
+		}
+	}
+	if n < ProtoPoolMinQueue {
+		t.Fatalf("%d queued, want at least %d", n, ProtoPoolMinQueue)
+	}
+}
@@ -89,7 +89,7 @@ const (
 
 // EvShellPeerActiveInd
 type MsgShellPeerActiveInd struct {
-	TxChan   interface{} // channel for packages sending
+	TxQueue  interface{} // queue for packages sending, by priority class
 	RxChan   interface{} // channel for packages received
 	PeerInfo interface{} // handshake info about peer
 	PeerInst interface{} // peer instance
@@ -101,6 +101,7 @@ type MsgShellPeerCloseCfm struct {
 	Dir    int                 // direction
 	Snid   config.SubNetworkID // sub network identity
 	PeerId config.NodeID       // target node
+	Why    string              // cause closed for, see peer.KillForXXX
 }
 
 // EvShellPeerCloseInd
@@ -150,13 +151,15 @@ type MsgShellBroadcastReq struct {
 	Data      []byte          // payload bytes
 	LocalSnid []config.NodeID // local sut network identity
 	Exclude   *config.NodeID  // node to be excluded
+	TTL       time.Duration   // dropped if still not sent TTL after queued, 0 for never
 }
 
 // EvShellGetChainInfoReq
 type MsgShellGetChainInfoReq struct {
-	Seq			uint64		// sequence
-	Kind		string		// kind
-	Key			[]byte		// key
+	Seq			uint64			// sequence
+	Kind		string			// kind
+	Key			[]byte			// key
+	Peer		*config.NodeID	// peer asked, all active peers if nil
 }
 
 // EvShellGetChainInfoRsp
@@ -175,6 +178,7 @@ const (
 	TabRefreshTimerId  = 0
 	TabPingpongTimerId = 1
 	TabFindNodeTimerId = 2
+	TabLookupTimerId   = 3
 )
 
 const (
@@ -182,8 +186,10 @@ const (
 	EvTabRefreshTimer  = EvTimerBase + TabRefreshTimerId
 	EvTabPingpongTimer = EvTimerBase + TabPingpongTimerId
 	EvTabFindNodeTimer = EvTimerBase + TabFindNodeTimerId
+	EvTabLookupTimer   = EvTimerBase + TabLookupTimerId
 	EvTabRefreshReq    = EvTabMgrBase + 1
 	EvTabRefreshRsp    = EvTabMgrBase + 2
+	EvTabLookupReq     = EvTabMgrBase + 3
 )
 
 // EvTabRefreshReq
@@ -199,6 +205,21 @@ type MsgTabRefreshRsp struct {
 	Nodes []*config.Node      // nodes found
 }
 
+// EvTabLookupReq
+type MsgTabLookupReq struct {
+	Snid   config.SubNetworkID   // sub network identity
+	Target config.NodeID         // target identity to look up
+	Done   chan *MsgTabLookupRsp // channel for result
+}
+
+// result of EvTabLookupReq, posted to MsgTabLookupReq.Done
+type MsgTabLookupRsp struct {
+	Result int                 // result code
+	Snid   config.SubNetworkID // sub network identity
+	Target config.NodeID       // target identity looked up
+	Nodes  []*config.Node      // closest nodes found
+}
+
 //
 // NodeDb cleaner event
 //
@@ -306,9 +327,33 @@ type NblDataReq struct {
 // Peer manager event
 //
 const (
-	EvPeerMgrBase = 1600
+	EvPeerMgrBase          = 1600
+	EvPeMgrAdminReq        = EvPeerMgrBase + 1
+	EvPeMgrStaticAddReq    = EvPeerMgrBase + 2
+	EvPeMgrStaticRemoveReq = EvPeerMgrBase + 3
 )
 
+// EvPeMgrAdminReq
+const (
+	PeMgrAdminRemovePeer      = iota // close a peer in all sub networks and remove it from static nodes
+	PeMgrAdminCloseBanned            // close peers connected from or to banned networks
+	PeMgrAdminSetStandby             // keep connections to nodes warm but idle
+	PeMgrAdminActivateStandby        // peers of the standby pool go active
+	PeMgrAdminEcho                   // send an echo request to a peer
+)
+
+// EvPeMgrStaticAddReq, EvPeMgrStaticRemoveReq
+type MsgPeMgrStaticReq struct {
+	Node config.Node // static node, only the identity needed for removing
+}
+
+type MsgPeMgrAdminReq struct {
+	Cmd   int            // PeMgrAdminXXX
+	Node  config.Node    // peer node
+	Nodes []*config.Node // nodes for PeMgrAdminSetStandby
+	Pkg   interface{}    // echo request package for PeMgrAdminEcho
+}
+
 //
 // Peer listerner event
 //
@@ -327,6 +372,10 @@ const (
 	PeMinOcrCleanupTimerId  = 2
 	PeConflictAccessTimerId = 3
 	PeReconfigTimerId       = 4
+	PeSeedShedTimerId       = 5
+	PeSlotAdaptTimerId      = 6
+	PeAcceptResumeTimerId   = 7
+	PeSelfProbeTimerId      = 8
 )
 
 const (
@@ -336,6 +385,10 @@ const (
 	EvPeOcrCleanupTimer     = EvTimerBase + PeMinOcrCleanupTimerId
 	EvPeConflictAccessTimer = EvTimerBase + PeConflictAccessTimerId
 	EvPeReconfigTimer       = EvTimerBase + PeReconfigTimerId
+	EvPeSeedShedTimer       = EvTimerBase + PeSeedShedTimerId
+	EvPeSlotAdaptTimer      = EvTimerBase + PeSlotAdaptTimerId
+	EvPeAcceptResumeTimer   = EvTimerBase + PeAcceptResumeTimerId
+	EvPeSelfProbeTimer      = EvTimerBase + PeSelfProbeTimerId
 	EvPeConnOutReq          = EvPeerEstBase + 1
 	EvPeConnOutRsp          = EvPeerEstBase + 2
 	EvPeHandshakeReq        = EvPeerEstBase + 3
@@ -362,6 +415,7 @@ const (
 	PEC_FOR_RECONFIG     = "Reconfig"
 	PEC_FOR_RECONFIG_REQ = "ReconfigReq"
 	PEC_FOR_BEASKEDTO    = "EvShellPeerAskToCloseInd"
+	PEC_FOR_SEEDSHED     = "SeedShed"
 )
 
 type MsgPeCloseReq struct {
@@ -563,6 +617,8 @@ const (
 	EvDhtConInstNeighbors      = EvDhtConInstBase + 9
 	EvDhtConInstTxInd          = EvDhtConInstBase + 10
 	EvDhtConInstStartupReq     = EvDhtConInstBase + 11
+	EvDhtConInstPutValueAck    = EvDhtConInstBase + 12
+	EvDhtConInstPutValueBusy   = EvDhtConInstBase + 13
 )
 
 // EvDhtConInstHandshakeReq
@@ -725,6 +781,8 @@ type MsgDhtQryInstResultInd struct {
 	Provider *Provider      // providers for get-provider
 	Value    []byte         // value for get-value
 	Pcs      []int          // peer connection status, see dht.conMgrPeerConnStat pls
+	Coord    []byte         // network coordinate piggybacked by the peer, if any
+	Acked    bool           // storage acknowledged by the peer for put-value
 }
 
 // EvDhtQryInstStopRsp
@@ -872,6 +930,7 @@ const (
 	EvDhtDsMgrAddValReq = EvDhtDsMgrBase + 1
 	EvDhtDsMgrPutValReq = EvDhtDsMgrBase + 2
 	EvDhtDsMgrGetValReq = EvDhtDsMgrBase + 3
+	EvDhtDsMgrStoredInd = EvDhtDsMgrBase + 4
 )
 
 // EvDhtDsMgrAddValReq
@@ -895,6 +954,11 @@ type MsgDhtDsMgrGetValReq struct {
 	Msg     interface{} // the message pointer
 }
 
+// EvDhtDsMgrStoredInd
+type MsgDhtDsMgrStoredInd struct {
+	Batch interface{} // put-value requests written by the storer
+}
+
 //
 // DHT shell manager event
 //
@@ -0,0 +1,288 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+)
+
+//
+// Event registry: names of events and modules owning them, for diagnostics. the
+// events defined in file event.go are all registered here, modules can register
+// their private events with function EvRegister. notice that timer events are
+// identified by module only, since timer identities are allocated in module, so
+// they are not registered but named as "EvTimer+n".
+//
+type EvInfo struct {
+	Id     int    // event identity
+	Name   string // event name
+	Module string // module owns the event
+}
+
+const evModuleSpan = 100 // span of event identities of a module
+
+var evModules = map[int]string{
+	EvSchNull:          "scheduler",
+	EvShellBase:        "chain shell manager",
+	EvTabMgrBase:       "table manager",
+	EvDcvMgrBase:       "discover manager",
+	EvNblUdpBase:       "neighbor manager",
+	EvNblListennerBase: "neighbor listener",
+	EvPeerMgrBase:      "peer manager",
+	EvPeerLsnBase:      "peer listener",
+	EvPeerEstBase:      "peer instance",
+	EvDhtMgrBase:       "dht manager",
+	EvDhtLsnMgrBase:    "dht listener manager",
+	EvDhtConMgrBase:    "dht connection manager",
+	EvDhtConInstBase:   "dht connection instance",
+	EvDhtQryMgrBase:    "dht query manager",
+	EvDhtQryInstBase:   "dht query instance",
+	EvDhtRutMgrBase:    "dht route manager",
+	EvDhtPrdMgrBase:    "dht provider manager",
+	EvDhtDsMgrBase:     "dht datastore manager",
+	EvDhtShellBase:     "dht shell manager",
+	EvNatMgrBase:       "nat manager",
+}
+
+var evNames = map[int]string{
+	EvSchNull:        "EvSchNull",
+	EvSchPoweron:     "EvSchPoweron",
+	EvSchPoweroff:    "EvSchPoweroff",
+	EvSchDone:        "EvSchDone",
+	EvSchTaskCreated: "EvSchTaskCreated",
+
+	EvShellPeerActiveInd:     "EvShellPeerActiveInd",
+	EvShellPeerCloseCfm:      "EvShellPeerCloseCfm",
+	EvShellPeerCloseInd:      "EvShellPeerCloseInd",
+	EvShellPeerAskToCloseInd: "EvShellPeerAskToCloseInd",
+	EvShellReconfigReq:       "EvShellReconfigReq",
+	EvShellBroadcastReq:      "EvShellBroadcastReq",
+	EvShellSubnetUpdateReq:   "EvShellSubnetUpdateReq",
+	EvShellGetChainInfoReq:   "EvShellGetChainInfoReq",
+	EvShellGetChainInfoRsp:   "EvShellGetChainInfoRsp",
+
+	EvTabRefreshReq: "EvTabRefreshReq",
+	EvTabRefreshRsp: "EvTabRefreshRsp",
+	EvTabLookupReq:  "EvTabLookupReq",
+
+	EvDcvFindNodeReq: "EvDcvFindNodeReq",
+	EvDcvFindNodeRsp: "EvDcvFindNodeRsp",
+	EvDcvReconfigReq: "EvDcvReconfigReq",
+
+	EvNblFindNodeReq: "EvNblFindNodeReq",
+	EvNblFindNodeRsp: "EvNblFindNodeRsp",
+	EvNblPingpongReq: "EvNblPingpongReq",
+	EvNblPingpongRsp: "EvNblPingpongRsp",
+	EvNblPingedInd:   "EvNblPingedInd",
+	EvNblPongedInd:   "EvNblPongedInd",
+	EvNblQueriedInd:  "EvNblQueriedInd",
+	EvNblCleanMapReq: "EvNblCleanMapReq",
+
+	EvNblMsgInd:  "EvNblMsgInd",
+	EvNblStart:   "EvNblStart",
+	EvNblStop:    "EvNblStop",
+	EvNblDataReq: "EvNblDataReq",
+
+	EvPeMgrAdminReq:        "EvPeMgrAdminReq",
+	EvPeMgrStaticAddReq:    "EvPeMgrStaticAddReq",
+	EvPeMgrStaticRemoveReq: "EvPeMgrStaticRemoveReq",
+
+	EvPeLsnConnAcceptedInd: "EvPeLsnConnAcceptedInd",
+	EvPeLsnStartReq:        "EvPeLsnStartReq",
+	EvPeLsnStopReq:         "EvPeLsnStopReq",
+	EvPeLsnRestart:         "EvPeLsnRestart",
+
+	EvPeConnOutReq:     "EvPeConnOutReq",
+	EvPeConnOutRsp:     "EvPeConnOutRsp",
+	EvPeHandshakeReq:   "EvPeHandshakeReq",
+	EvPeHandshakeRsp:   "EvPeHandshakeRsp",
+	EvPePingpongReq:    "EvPePingpongReq",
+	EvPeCloseReq:       "EvPeCloseReq",
+	EvPeCloseCfm:       "EvPeCloseCfm",
+	EvPeCloseInd:       "EvPeCloseInd",
+	EvPeOutboundReq:    "EvPeOutboundReq",
+	EvPeEstablishedInd: "EvPeEstablishedInd",
+	EvPeMgrStartReq:    "EvPeMgrStartReq",
+	EvPeTxDataReq:      "EvPeTxDataReq",
+	EvPeRxDataInd:      "EvPeRxDataInd",
+
+	EvDhtMgrFindPeerReq:      "EvDhtMgrFindPeerReq",
+	EvDhtMgrPutProviderReq:   "EvDhtMgrPutProviderReq",
+	EvDhtMgrPutProviderRsp:   "EvDhtMgrPutProviderRsp",
+	EvDhtMgrGetProviderReq:   "EvDhtMgrGetProviderReq",
+	EvDhtMgrGetProviderRsp:   "EvDhtMgrGetProviderRsp",
+	EvDhtMgrPutValueReq:      "EvDhtMgrPutValueReq",
+	EvDhtMgrPutValueRsp:      "EvDhtMgrPutValueRsp",
+	EvDhtMgrPutValueLocalRsp: "EvDhtMgrPutValueLocalRsp",
+	EvDhtMgrGetValueReq:      "EvDhtMgrGetValueReq",
+	EvDhtMgrGetValueRsp:      "EvDhtMgrGetValueRsp",
+	EvDhtMgrQueryStopReq:     "EvDhtMgrQueryStopReq",
+	EvDhtBlindConnectReq:     "EvDhtBlindConnectReq",
+	EvDhtBlindConnectRsp:     "EvDhtBlindConnectRsp",
+
+	EvDhtLsnMgrStartReq:  "EvDhtLsnMgrStartReq",
+	EvDhtLsnMgrStopReq:   "EvDhtLsnMgrStopReq",
+	EvDhtLsnMgrPauseReq:  "EvDhtLsnMgrPauseReq",
+	EvDhtLsnMgrResumeReq: "EvDhtLsnMgrResumeReq",
+	EvDhtLsnMgrDriveSelf: "EvDhtLsnMgrDriveSelf",
+	EvDhtLsnMgrAcceptInd: "EvDhtLsnMgrAcceptInd",
+	EvDhtLsnMgrStatusInd: "EvDhtLsnMgrStatusInd",
+
+	EvDhtConMgrConnectReq:       "EvDhtConMgrConnectReq",
+	EvDhtConMgrConnectRsp:       "EvDhtConMgrConnectRsp",
+	EvDhtConMgrSendReq:          "EvDhtConMgrSendReq",
+	EvDhtConMgrSendCfm:          "EvDhtConMgrSendCfm",
+	EvDhtConMgrCloseReq:         "EvDhtConMgrCloseReq",
+	EvDhtConMgrCloseRsp:         "EvDhtConMgrCloseRsp",
+	EvDhtConMgrPubAddrSwitchBeg: "EvDhtConMgrPubAddrSwitchBeg",
+	EvDhtConMgrPubAddrSwitchEnd: "EvDhtConMgrPubAddrSwitchEnd",
+	EvDhtConMgrBootstrapReq:     "EvDhtConMgrBootstrapReq",
+
+	EvDhtConInstHandshakeReq:   "EvDhtConInstHandshakeReq",
+	EvDhtConInstHandshakeRsp:   "EvDhtConInstHandshakeRsp",
+	EvDhtConInstTxDataReq:      "EvDhtConInstTxDataReq",
+	EvDhtConInstStatusInd:      "EvDhtConInstStatusInd",
+	EvDhtConInstCloseReq:       "EvDhtConInstCloseReq",
+	EvDhtConInstCloseRsp:       "EvDhtConInstCloseRsp",
+	EvDhtConInstGetProviderRsp: "EvDhtConInstGetProviderRsp",
+	EvDhtConInstGetValRsp:      "EvDhtConInstGetValRsp",
+	EvDhtConInstNeighbors:      "EvDhtConInstNeighbors",
+	EvDhtConInstTxInd:          "EvDhtConInstTxInd",
+	EvDhtConInstStartupReq:     "EvDhtConInstStartupReq",
+	EvDhtConInstPutValueAck:    "EvDhtConInstPutValueAck",
+	EvDhtConInstPutValueBusy:   "EvDhtConInstPutValueBusy",
+
+	EvDhtQryMgrQueryStartReq:    "EvDhtQryMgrQueryStartReq",
+	EvDhtQryMgrQueryStartRsp:    "EvDhtQryMgrQueryStartRsp",
+	EvDhtQryMgrQueryStopReq:     "EvDhtQryMgrQueryStopReq",
+	EvDhtQryMgrQueryStopRsp:     "EvDhtQryMgrQueryStopRsp",
+	EvDhtQryMgrQueryResultInd:   "EvDhtQryMgrQueryResultInd",
+	EvDhtQryMgrPubAddrSwitchInd: "EvDhtQryMgrPubAddrSwitchInd",
+
+	EvDhtQryInstStartReq:    "EvDhtQryInstStartReq",
+	EvDhtQryInstResultInd:   "EvDhtQryInstResultInd",
+	EvDhtQryInstStatusInd:   "EvDhtQryInstStatusInd",
+	EvDhtQryInstProtoMsgInd: "EvDhtQryInstProtoMsgInd",
+
+	EvDhtRutMgrNearestReq:      "EvDhtRutMgrNearestReq",
+	EvDhtRutMgrNearestRsp:      "EvDhtRutMgrNearestRsp",
+	EvDhtRutMgrUpdateReq:       "EvDhtRutMgrUpdateReq",
+	EvDhtRutMgrNotificationInd: "EvDhtRutMgrNotificationInd",
+	EvDhtRutPeerRemovedInd:     "EvDhtRutPeerRemovedInd",
+	EvDhtRutMgrStopNotifyReq:   "EvDhtRutMgrStopNotifyReq",
+	EvDhtRutPingInd:            "EvDhtRutPingInd",
+	EvDhtRutPongInd:            "EvDhtRutPongInd",
+	EvDhtRutRefreshReq:         "EvDhtRutRefreshReq",
+
+	EvDhtPrdMgrAddProviderReq: "EvDhtPrdMgrAddProviderReq",
+	EvDhtPrdMgrAddProviderRsp: "EvDhtPrdMgrAddProviderRsp",
+	EvDhtPrdMgrPutProviderReq: "EvDhtPrdMgrPutProviderReq",
+	EvDhtPrdMgrGetProviderReq: "EvDhtPrdMgrGetProviderReq",
+
+	EvDhtDsMgrAddValReq: "EvDhtDsMgrAddValReq",
+	EvDhtDsMgrPutValReq: "EvDhtDsMgrPutValReq",
+	EvDhtDsMgrGetValReq: "EvDhtDsMgrGetValReq",
+	EvDhtDsMgrStoredInd: "EvDhtDsMgrStoredInd",
+
+	EvDhtShEventInd: "EvDhtShEventInd",
+
+	EvNatMgrDiscoverReq:      "EvNatMgrDiscoverReq",
+	EvNatMgrDiscoverRsp:      "EvNatMgrDiscoverRsp",
+	EvNatMgrMakeMapReq:       "EvNatMgrMakeMapReq",
+	EvNatMgrMakeMapRsp:       "EvNatMgrMakeMapRsp",
+	EvNatMgrRemoveMapReq:     "EvNatMgrRemoveMapReq",
+	EvNatMgrRemoveMapRsp:     "EvNatMgrRemoveMapRsp",
+	EvNatMgrGetPublicAddrReq: "EvNatMgrGetPublicAddrReq",
+	EvNatMgrGetPublicAddrRsp: "EvNatMgrGetPublicAddrRsp",
+	EvNatMgrPubAddrUpdateInd: "EvNatMgrPubAddrUpdateInd",
+	EvNatMgrReadyInd:         "EvNatMgrReadyInd",
+	EvNatPubAddrSwitchInd:    "EvNatPubAddrSwitchInd",
+}
+
+var evLock sync.RWMutex              // lock for registering events
+var evPrivModules = map[int]string{} // modules of private events registered
+
+//
+// Register a private event of module, the event defined in file event.go or
+// registered already could not be registered again.
+//
+func EvRegister(id int, name string, module string) SchErrno {
+	if id >= EvTimerBase && id < EvTimerBase+evModuleSpan || len(name) == 0 {
+		return SchEnoParameter
+	}
+	evLock.Lock()
+	defer evLock.Unlock()
+	if _, dup := evNames[id]; dup {
+		return SchEnoDuplicated
+	}
+	evNames[id] = name
+	evPrivModules[id] = module
+	return SchEnoNone
+}
+
+//
+// Get information about event
+//
+func EvLookup(id int) EvInfo {
+	info := EvInfo{
+		Id: id,
+	}
+	if id >= EvTimerBase && id < EvTimerBase+evModuleSpan {
+		info.Name = fmt.Sprintf("EvTimer+%d", id-EvTimerBase)
+		info.Module = "timer"
+		return info
+	}
+	evLock.RLock()
+	defer evLock.RUnlock()
+	name, ok := evNames[id]
+	if !ok {
+		name = "EvUnknown"
+	}
+	info.Name = name
+	if module, priv := evPrivModules[id]; priv {
+		info.Module = module
+	} else if info.Module, ok = evModules[id-id%evModuleSpan]; !ok {
+		info.Module = "unknown"
+	}
+	return info
+}
+
+//
+// Get name of event
+//
+func EvName(id int) string {
+	return EvLookup(id).Name
+}
+
+//
+// Get module owns the event
+//
+func EvModule(id int) string {
+	return EvLookup(id).Module
+}
+
+//
+// Event to string for logging, like "EvSchPoweron(1)"
+//
+func EvString(id int) string {
+	return fmt.Sprintf("%s(%d)", EvName(id), id)
+}
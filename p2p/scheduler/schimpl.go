@@ -22,9 +22,11 @@ package scheduler
 
 import (
 	"fmt"
+	"math/rand"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	config "github.com/yeeco/gyee/p2p/config"
@@ -105,6 +107,8 @@ func schSchedulerInit(cfg *config.Config) (*scheduler, SchErrno) {
 	sdl.p2pCfg = cfg
 	sdl.powerOff = false
 	sdl.appType = int(cfg.AppType)
+	sdl.rand = schNewRand(cfg.RandSeed)
+	schLog.Debug("schSchedulerInit: random seed: %d", sdl.rand.seed)
 
 	//
 	// make maps
@@ -121,6 +125,7 @@ func schSchedulerInit(cfg *config.Config) (*scheduler, SchErrno) {
 		sdl.schTaskNodePool[loop].last = &sdl.schTaskNodePool[(loop-1+schTaskNodePoolSize)&(schTaskNodePoolSize-1)]
 		sdl.schTaskNodePool[loop].next = &sdl.schTaskNodePool[(loop+1)&(schTaskNodePoolSize-1)]
 		sdl.schTaskNodePool[loop].task.tmIdxTab = make(map[*schTmcbNode]int)
+		sdl.schTaskNodePool[loop].task.tmPending = make(map[uint64]int)
 		sdl.schTaskNodePool[loop].task.goStatus = SchCreatedNull
 		sdl.schTaskNodePool[loop].task.killed = true
 	}
@@ -250,11 +255,13 @@ func (sdl *scheduler) schCommonTask(ptn *schTaskNode) SchErrno {
 
 			for {
 				select {
-				case <-*qtmMsg:
+				case m := <-*qtmMsg:
+					schMsgRelease(m)
 				case m := <-*queMsg:
 					if m.Mscb != nil {
 						m.Mscb(SchEnoDone)
 					}
+					schMsgRelease(m)
 				default:
 					break drainLoop1
 				}
@@ -307,6 +314,7 @@ taskLoop:
 						msg.Mscb(SchEnoPowerOff)
 					}
 				}
+				schMsgRelease(msg)
 			}
 
 		} else {
@@ -344,10 +352,21 @@ taskLoop:
 		}
 
 		//
-		// call user task
+		// discard timer event of a timer killed after it's queued, the owner
+		// might have been gone with the "Extra" of the timer.
+		//
+
+		if msg.tmGen != 0 && !sdl.schTimerEventValid(task, msg.tmGen) {
+			schMsgRelease(msg)
+			continue
+		}
+
+		//
+		// call user task, and release the message since it's processed
 		//
 
 		proc(ptn, msg)
+		schMsgRelease(msg)
 	}
 
 	//
@@ -968,30 +987,58 @@ func (sdl *scheduler) schSendTimerEvent(ptm *schTmcbNode) SchErrno {
 	//
 
 	var task = &ptm.tmcb.taskNode.task
-	var msg = schMessage{
+	var msg = schMsgAlloc()
+	*msg = schMessage{
 		sender: &rawTmTsk,
 		recver: ptm.tmcb.taskNode,
 		Id:     EvTimerBase + ptm.tmcb.utid,
 		Body:   ptm.tmcb.extra,
+		pooled: true,
+		tmGen:  ptm.tmcb.gen,
 	}
 
+	// history recorded before the message queued, since it might be released
+	// by the target once it's queued.
+	task.evTotal += 1
+	task.evHistory[task.evhIndex] = *msg
+	task.evhIndex = (task.evhIndex + 1) & (evHistorySize - 1)
+
 	if schTmqFork == false {
 		if len(*task.mailbox.que) + mbReserved >= cap(*task.mailbox.que) {
 			schLog.Debug("schSendTimerEvent: mailbox of target is full, sdl: %s, task: %s", sdl.p2pCfg.CfgName, task.name)
 			panic(fmt.Sprintf("system overload, sdl: %s, task: %s", sdl.p2pCfg.CfgName, task.name))
 		}
-		*task.mailbox.que <- &msg
+		task.tmPending[msg.tmGen]++
+		*task.mailbox.que <- msg
 	} else {
-		*task.mailbox.qtm <- &msg
+		task.tmPending[msg.tmGen]++
+		*task.mailbox.qtm <- msg
 	}
 
-	task.evTotal += 1
-	task.evHistory[task.evhIndex] = msg
-	task.evhIndex = (task.evhIndex + 1) & (evHistorySize - 1)
-
 	return SchEnoNone
 }
 
+//
+// Check if a timer event is still valid when it's going to be delivered: the
+// generation of the event would have been removed from the pending table when
+// the timer is killed. notice that the task lock would be held by the sender
+// when the event is queued, see function schSendTimerEvent.
+//
+func (sdl *scheduler) schTimerEventValid(task *schTask, gen uint64) bool {
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	count, ok := task.tmPending[gen]
+	if !ok || count <= 0 {
+		return false
+	}
+	if count--; count == 0 {
+		delete(task.tmPending, gen)
+	} else {
+		task.tmPending[gen] = count
+	}
+	return true
+}
+
 //
 // Create a single task
 //
@@ -1067,6 +1114,8 @@ func (sdl *scheduler) schCreateTask(taskDesc *schTaskDescription) (SchErrno, int
 	ptn.task.dog = *taskDesc.Wd
 	ptn.task.dieCb = taskDesc.DieCb
 	ptn.task.userData = taskDesc.UserDa
+	ptn.task.p2pCfg = sdl.p2pCfg
+	ptn.task.locals = make(map[string]interface{}, 0)
 
 	//
 	// task timer table
@@ -1485,8 +1534,15 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 	// or EvSchDone if currently in power off stage.
 	//
 
+	// the callback is taken out first, since a pooled message might be released
+	// by the target once it's queued.
+	cb := msg.Mscb
 	mscb := func(m *schMessage, e SchErrno) SchErrno {
-		if m.Mscb != nil {
+		if m == msg {
+			if cb != nil {
+				cb(e)
+			}
+		} else if m.Mscb != nil {
 			m.Mscb(e)
 		}
 		return e
@@ -1501,7 +1557,7 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 		default:
 			if msg.Keep == SchMsgKeepFromNone {
 				if schLog.debug__ {
-					schLog.ForceDebug("schSendMsg: in power off stage, sdl: %s, mid: %d", sdlName, msg.Id)
+					schLog.ForceDebug("schSendMsg: in power off stage, sdl: %s, mid: %s", sdlName, EvString(msg.Id))
 				}
 				sdl.lock.Unlock()
 				return mscb(msg, SchEnoPowerOff)
@@ -1510,13 +1566,13 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 	}
 
 	if msg.sender == nil {
-		schLog.ForceDebug("schSendMsg: invalid sender, sdl: %s, mid: %d", sdlName, msg.Id)
+		schLog.ForceDebug("schSendMsg: invalid sender, sdl: %s, mid: %s", sdlName, EvString(msg.Id))
 		sdl.lock.Unlock()
 		return mscb(msg, SchEnoParameter)
 	}
 
 	if msg.recver == nil {
-		schLog.ForceDebug("schSendMsg: invalid receiver, sdl: %s, mid: %d", sdlName, msg.Id)
+		schLog.ForceDebug("schSendMsg: invalid receiver, sdl: %s, mid: %s", sdlName, EvString(msg.Id))
 		sdl.lock.Unlock()
 		return mscb(msg, SchEnoParameter)
 	}
@@ -1545,8 +1601,8 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 	if len(targetName) == 0 {
 
 		schLog.ForceDebug("schSendMsg: receiver not found, " +
-			"sdl: %s, src: %s, ev: %d",
-			sdlName, source.name, msg.Id)
+			"sdl: %s, src: %s, ev: %s",
+			sdlName, source.name, EvString(msg.Id))
 
 		sdl.lock.Unlock()
 		return mscb(msg, SchEnoNotFound)
@@ -1555,8 +1611,8 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 	if len(msg.TgtName) > 0 && targetName != msg.TgtName {
 
 		schLog.ForceDebug("schSendMsg: receiver not found, " +
-			"sdl: %s, src: %s, tgt: %s, ev: %d",
-			sdlName, source.name, msg.TgtName, msg.Id)
+			"sdl: %s, src: %s, tgt: %s, ev: %s",
+			sdlName, source.name, msg.TgtName, EvString(msg.Id))
 
 		sdl.lock.Unlock()
 		return mscb(msg, SchEnoNotFound)
@@ -1583,8 +1639,8 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 
 				if schLog.debug__ {
 					schLog.ForceDebug("schSendMsg: duplicated, " +
-						"sdl: %s, mid: %d",
-						sdlName, msg.Id)
+						"sdl: %s, mid: %s",
+						sdlName, EvString(msg.Id))
 				}
 
 				return mscb(msg, SchEnoDuplicated)
@@ -1594,8 +1650,8 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 
 			if schLog.debug__ {
 				schLog.ForceDebug("schSendMsg: target in killing, " +
-					"sdl: %s, mid: %d",
-					sdlName, msg.Id)
+					"sdl: %s, mid: %s",
+					sdlName, EvString(msg.Id))
 			}
 
 			return mscb(msg, SchEnoMismatched)
@@ -1607,8 +1663,8 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 
 			if schLog.debug__ {
 				schLog.ForceDebug("schSendMsg: target had been killed, " +
-					"sdl: %s, mid: %d",
-					sdlName, msg.Id)
+					"sdl: %s, mid: %s",
+					sdlName, EvString(msg.Id))
 			}
 
 			return mscb(msg, SchEnoMismatched)
@@ -1624,8 +1680,8 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 	if len(msg.TgtName) > 0 && target.name != msg.TgtName {
 
 		schLog.ForceDebug("schSendMsg: receiver not found, " +
-			"sdl: %s, src: %s, tgt: %s, ev: %d, dst: %s",
-			sdlName, source.name, msg.TgtName, msg.Id, target.name)
+			"sdl: %s, src: %s, tgt: %s, ev: %s, dst: %s",
+			sdlName, source.name, msg.TgtName, EvString(msg.Id), target.name)
 
 		return mscb(msg, SchEnoMismatched)
 	}
@@ -1633,16 +1689,16 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 	msg2MailBox := func(msg *schMessage) SchErrno {
 		if target.mailbox.que == nil {
 			schLog.ForceDebug("schSendMsg: mailbox empty, " +
-				"sdl: %s, src: %s, ev: %d",
-				sdlName, source.name, msg.Id)
+				"sdl: %s, src: %s, ev: %s",
+				sdlName, source.name, EvString(msg.Id))
 			return SchEnoInternal
 		}
 
 		if len(*target.mailbox.que) + mbReserved >= cap(*target.mailbox.que) {
 
 			schLog.ForceDebug("schSendMsg: mailbox full, " +
-				"sdl: %s, src: %s, dst: %s, ev: %d",
-				sdlName, source.name, target.name, msg.Id)
+				"sdl: %s, src: %s, dst: %s, ev: %s",
+				sdlName, source.name, target.name, EvString(msg.Id))
 
 			target.discardMessages += 1
 			if target.discardMessages & 0x1f == 0 {
@@ -1654,10 +1710,10 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 			return SchEnoResource
 		}
 
-		*target.mailbox.que <- msg
 		target.evTotal += 1
 		target.evHistory[target.evhIndex] = *msg
 		target.evhIndex = (target.evhIndex + 1) & (evHistorySize - 1)
+		*target.mailbox.que <- msg
 
 		return SchEnoNone
 	}
@@ -1713,6 +1769,14 @@ func (sdl *scheduler) schSendMsg(msg *schMessage) (eno SchErrno) {
 // those extra put into timer when it's created.
 //
 func (sdl *scheduler) schSetTimer(ptn *schTaskNode, tdc *timerDescription) (SchErrno, int) {
+	eno, tid, _ := sdl.schSetTimerGen(ptn, tdc)
+	return eno, tid
+}
+
+//
+// Set a timer, the generation of the timer is returned besides the identity
+//
+func (sdl *scheduler) schSetTimerGen(ptn *schTaskNode, tdc *timerDescription) (SchErrno, int, uint64) {
 
 	//
 	// failed if in power off stage
@@ -1720,7 +1784,7 @@ func (sdl *scheduler) schSetTimer(ptn *schTaskNode, tdc *timerDescription) (SchE
 
 	if sdl.powerOff == true {
 		schLog.Debug("schSetTimer: in power off stage")
-		return SchEnoPowerOff, SchInvalidTid
+		return SchEnoPowerOff, SchInvalidTid, 0
 	}
 
 	//
@@ -1736,7 +1800,7 @@ func (sdl *scheduler) schSetTimer(ptn *schTaskNode, tdc *timerDescription) (SchE
 
 	if ptn == nil || tdc == nil {
 		schLog.Debug("schSetTimer: invalid parameter(s)")
-		return SchEnoParameter, schInvalidTid
+		return SchEnoParameter, schInvalidTid, 0
 	}
 
 	ptn.task.lock.Lock()
@@ -1754,7 +1818,7 @@ func (sdl *scheduler) schSetTimer(ptn *schTaskNode, tdc *timerDescription) (SchE
 
 	if tid >= schMaxTaskTimer {
 		schLog.Debug("schSetTimer: too much, timer table is full")
-		return SchEnoResource, schInvalidTid
+		return SchEnoResource, schInvalidTid, 0
 	}
 
 	//
@@ -1767,7 +1831,7 @@ func (sdl *scheduler) schSetTimer(ptn *schTaskNode, tdc *timerDescription) (SchE
 			"schGetTimerNode failed, eno: %d",
 			eno)
 
-		return eno, schInvalidTid
+		return eno, schInvalidTid, 0
 	}
 
 	ptm.tmcb.stopped = make(chan bool)
@@ -1791,6 +1855,8 @@ func (sdl *scheduler) schSetTimer(ptn *schTaskNode, tdc *timerDescription) (SchE
 	tcb.dur = tdc.Dur
 	tcb.taskNode = ptn
 	tcb.extra = tdc.Extra
+	tcb.gen = atomic.AddUint64(&sdl.tmGen, 1)
+	ptn.task.tmGenTab[tid] = tcb.gen
 
 	//
 	// go timer common task for timer
@@ -1798,20 +1864,30 @@ func (sdl *scheduler) schSetTimer(ptn *schTaskNode, tdc *timerDescription) (SchE
 
 	go sdl.schTimerCommonTask(ptm)
 
-	return SchEnoNone, tid
+	return SchEnoNone, tid, tcb.gen
 }
 
 //
 // Kill a timer
 //
 func (sdl *scheduler) schKillTimer(ptn *schTaskNode, tid int) SchErrno {
+	return sdl.schKillTimerGen(ptn, tid, 0)
+}
+
+//
+// Kill a timer of the generation specified, if the generation is zero, the
+// timer last set with the identity is killed. timer events of the generation
+// still queued in mailbox would be discarded, so the owner would never get an
+// event after the timer killed.
+//
+func (sdl *scheduler) schKillTimerGen(ptn *schTaskNode, tid int, gen uint64) SchErrno {
 
 	//
 	// para check
 	//
 
-	if ptn == nil || tid < 0 || tid > schMaxTaskTimer {
-		schLog.Debug("schKillTimer: invalid parameter(s)")
+	if ptn == nil || tid < 0 || tid >= schMaxTaskTimer {
+		schLog.Debug("schKillTimerGen: invalid parameter(s)")
 		return SchEnoParameter
 	}
 
@@ -1822,16 +1898,20 @@ func (sdl *scheduler) schKillTimer(ptn *schTaskNode, tid int) SchErrno {
 
 	ptn.task.lock.Lock()
 
+	if gen == 0 {
+		gen = ptn.task.tmGenTab[tid]
+	}
+	delete(ptn.task.tmPending, gen)
+
 	//
-	// Notice: when try to kill a timer, the timer might have been expired, but
-	// the message sent about this is still not received by user task. In this
-	// case, user task would get "SchEnoNotFound", and this is not fault of the
-	// scheduler really, but the user task would be confuse with the event about
-	// a timer killed failed(SchEnoNotFound) received later. We still not solve
-	// this issue now.
+	// Notice: when try to kill a timer, the timer might have been expired, and
+	// the event about this might be still not received by user task. Since the
+	// generation is removed from the pending table above, the event would be
+	// discarded by the scheduler and never be delivered. If the timer with the
+	// identity is of another generation, it's not the one to be killed.
 	//
 
-	if ptn.task.tmTab[tid] == nil {
+	if ptn.task.tmTab[tid] == nil || ptn.task.tmTab[tid].tmcb.gen != gen {
 		ptn.task.lock.Unlock()
 		return SchEnoNone
 	}
@@ -1846,6 +1926,15 @@ func (sdl *scheduler) schKillTimer(ptn *schTaskNode, tid int) SchErrno {
 	ptn.task.lock.Unlock()
 
 	if stopped := <-tcb.stopped; stopped {
+
+		//
+		// the timer might have sent an event before it got the stop signal
+		//
+
+		ptn.task.lock.Lock()
+		delete(ptn.task.tmPending, gen)
+		ptn.task.lock.Unlock()
+
 		return SchEnoNone
 	}
 
@@ -1858,6 +1947,9 @@ func (sdl *scheduler) schKillTimer(ptn *schTaskNode, tid int) SchErrno {
 func (sdl *scheduler) schKillTaskTimers(task *schTask) SchErrno {
 
 	task.lock.Lock()
+	for gen := range task.tmPending {
+		delete(task.tmPending, gen)
+	}
 	stopped := make([]chan bool, 0)
 	for tm := range task.tmIdxTab {
 		tm.tmcb.stop <- true
@@ -2031,6 +2123,78 @@ func (sdl *scheduler) schSetUserDataArea(ptn *schTaskNode, uda interface{}) SchE
 	return SchEnoNone
 }
 
+//
+// Get event history of task, the oldest first, events are described by names
+// and modules owning them, see file evname.go.
+//
+func (sdl *scheduler) schGetTaskEvHistory(ptn *schTaskNode) []string {
+	if ptn == nil {
+		return nil
+	}
+	task := &ptn.task
+	task.lock.Lock()
+	defer task.lock.Unlock()
+	count := int64(evHistorySize)
+	if task.evTotal < count {
+		count = task.evTotal
+	}
+	history := make([]string, 0, count)
+	for idx := task.evhIndex - int(count); idx < task.evhIndex; idx++ {
+		msg := &task.evHistory[idx&(evHistorySize-1)]
+		src := "nil"
+		if msg.sender != nil {
+			src = msg.sender.task.name
+		}
+		info := EvLookup(msg.Id)
+		history = append(history, fmt.Sprintf("%s: %s(%d), module: %s",
+			src, info.Name, info.Id, info.Module))
+	}
+	return history
+}
+
+//
+// Get p2p configuration injected when task created
+//
+func (sdl *scheduler) schGetTaskConfig(ptn *schTaskNode) *config.Config {
+	if ptn == nil || ptn.task.p2pCfg == nil {
+		return sdl.p2pCfg
+	}
+	return ptn.task.p2pCfg
+}
+
+//
+// Get value from task local storage, nil returned if not found
+//
+func (sdl *scheduler) schGetTaskLocal(ptn *schTaskNode, key string) interface{} {
+	if ptn == nil {
+		return nil
+	}
+	ptn.task.lock.Lock()
+	defer ptn.task.lock.Unlock()
+	return ptn.task.locals[key]
+}
+
+//
+// Set value into task local storage, the key is removed if value is nil
+//
+func (sdl *scheduler) schSetTaskLocal(ptn *schTaskNode, key string, val interface{}) SchErrno {
+	if ptn == nil {
+		schLog.Debug("schSetTaskLocal: invalid task node pointer")
+		return SchEnoParameter
+	}
+	ptn.task.lock.Lock()
+	defer ptn.task.lock.Unlock()
+	if ptn.task.locals == nil {
+		ptn.task.locals = make(map[string]interface{}, 0)
+	}
+	if val == nil {
+		delete(ptn.task.locals, key)
+	} else {
+		ptn.task.locals[key] = val
+	}
+	return SchEnoNone
+}
+
 //
 // Set the power off stage flag to tell the scheduler it's going to be turn off
 //
@@ -2098,6 +2262,85 @@ func (sdl *scheduler) schGetTaskMailboxSpace(ptn *schTaskNode) int {
 	return space
 }
 
+//
+// Check the static task table and the poweron order table before any task is
+// created: names should be unique, tasks in poweron order and those looked up
+// by tasks when powered on should be found, mailbox sizes should be sane. all
+// problems found are reported together, so one need not to guess them from a
+// nil pointer panic in poweron handlers.
+//
+func (sdl *scheduler) schCheckStaticTasks(tsd []TaskStaticDescription, tpo []string) SchErrno {
+
+	var problems []string
+	var report = func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	sdl.lock.Lock()
+	defer sdl.lock.Unlock()
+	var exist = func(name string) bool {
+		_, ok := sdl.tkMap[name]
+		return ok
+	}
+
+	static := make(map[string]*TaskStaticDescription, len(tsd))
+	for idx := 0; idx < len(tsd); idx++ {
+		desc := &tsd[idx]
+		name := strings.TrimSpace(desc.Name)
+		if len(name) == 0 {
+			report("task %d: empty name", idx)
+			continue
+		}
+		if _, dup := static[name]; dup {
+			report("task %s: duplicated in static task table", name)
+		} else if exist(name) {
+			report("task %s: duplicated with a running task", name)
+		}
+		static[name] = desc
+		if desc.Tep == nil {
+			report("task %s: nil task interface", name)
+		}
+		if desc.MbSize > schMaxMbSize {
+			report("task %s: mailbox size %d exceeds %d", name, desc.MbSize, schMaxMbSize)
+		} else if desc.MbSize > 0 && desc.MbSize <= mbReserved {
+			report("task %s: mailbox size %d not more than the reserved %d", name, desc.MbSize, mbReserved)
+		}
+	}
+
+	for idx := 0; idx < len(tsd); idx++ {
+		for _, dep := range tsd[idx].Deps {
+			if _, ok := static[dep]; !ok && !exist(dep) {
+				report("task %s: depended task %s not found", tsd[idx].Name, dep)
+			}
+		}
+	}
+
+	powered := make(map[string]bool, len(tpo))
+	for _, name := range tpo {
+		if powered[name] {
+			report("poweron order: task %s duplicated", name)
+			continue
+		}
+		powered[name] = true
+		if desc, ok := static[name]; ok {
+			if desc.Flag == SchCreatedGo {
+				report("poweron order: task %s would be powered on twice, its flag is SchCreatedGo", name)
+			}
+		} else if !exist(name) {
+			report("poweron order: task %s not found", name)
+		}
+	}
+
+	if len(problems) == 0 {
+		return SchEnoNone
+	}
+
+	schLog.ForceDebug("schCheckStaticTasks: sdl: %s, %d problem(s) found:\n\t%s",
+		sdl.p2pCfg.CfgName, len(problems), strings.Join(problems, "\n\t"))
+
+	return SchEnoConfig
+}
+
 //
 // Start scheduler
 //
@@ -2114,6 +2357,10 @@ func (sdl *scheduler) schSchedulerStart(tsd []TaskStaticDescription, tpo []strin
 		return SchEnoParameter, nil
 	}
 
+	if eno := sdl.schCheckStaticTasks(tsd, tpo); eno != SchEnoNone {
+		return eno, nil
+	}
+
 	var (
 		po = schMessage{
 			sender: &rawSchTsk,
@@ -2259,3 +2506,40 @@ func (sdl *scheduler) schSchedulerStart(tsd []TaskStaticDescription, tpo []strin
 
 	return SchEnoNone, &name2PtnMap
 }
+
+//
+// Create random source, seeded by time if the seed passed in is zero
+//
+func schNewRand(seed int64) *schRand {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &schRand{
+		seed: seed,
+		rnd:  rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (sr *schRand) intn(n int) int {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	return sr.rnd.Intn(n)
+}
+
+func (sr *schRand) int31n(n int32) int32 {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	return sr.rnd.Int31n(n)
+}
+
+func (sr *schRand) float64() float64 {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	return sr.rnd.Float64()
+}
+
+func (sr *schRand) read(buf []byte) {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	sr.rnd.Read(buf)
+}
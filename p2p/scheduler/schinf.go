@@ -121,6 +121,8 @@ type SchMessage struct {
 	Mscb    SchMsgSendCallback
 	TgtName	string				// target receiver task name
 	Keep	int					// keep even in power off stage
+	pooled	bool				// allocated from the message pool
+	tmGen	uint64				// generation of the timer for timer event
 }
 
 // Watch dog for a user task
@@ -190,6 +192,7 @@ type TaskStaticDescription struct {
 	Wd     SchWatchDog                     // watchdog
 	DieCb  func(task interface{}) SchErrno // callbacked when going to die
 	Flag   int                             // flag: start at once or to be suspended
+	Deps   []string                        // names of tasks looked up when powered on
 }
 
 // Scheduler init
@@ -270,9 +273,17 @@ _failed:
 
 func (sdl *Scheduler) SchSendMessage(msg *SchMessage) SchErrno {
 	// to ensure the message not be modified by the caller after calling
-	// this function, we make a copy.
-	_msg := *msg
-	return sdl.schSendMsg((*schMessage)(&_msg))
+	// this function, we make a copy, which is allocated from the pool and
+	// released after the receiver task processed it.
+	_msg := schMsgAlloc()
+	*_msg = *msg
+	_msg.pooled = true
+	_msg.tmGen = 0
+	eno := sdl.schSendMsg(_msg)
+	if eno != SchEnoNone {
+		schMsgRelease(_msg)
+	}
+	return eno
 }
 
 // Set sender of message
@@ -323,6 +334,65 @@ func (sdl *Scheduler) SchKillTimer(ptn interface{}, tid int) SchErrno {
 	return sdl.schKillTimer(ptn.(*schTaskNode), tid)
 }
 
+// Timer handle: a timer identified by its' generation besides the identity, so
+// killing a handle would never kill another timer set later with the same
+// identity, and it can be killed more than once. Events of a timer killed are
+// never delivered even they had been queued before the timer killed.
+type SchTimerHandle struct {
+	sdl *Scheduler   // scheduler
+	ptn *schTaskNode // owner task node
+	tid int          // timer identity
+	gen uint64       // timer generation
+}
+
+// Set timer and return its' handle
+func (sdl *Scheduler) SchSetTimerHandle(ptn interface{}, tdc *TimerDescription) (SchErrno, *SchTimerHandle) {
+	eno, tid, gen := sdl.schSetTimerGen(ptn.(*schTaskNode), (*timerDescription)(tdc))
+	if eno != SchEnoNone {
+		return eno, nil
+	}
+	return SchEnoNone, &SchTimerHandle{sdl: sdl, ptn: ptn.(*schTaskNode), tid: tid, gen: gen}
+}
+
+// Kill timer by handle, nothing done for nil handle or a handle killed
+func (th *SchTimerHandle) Kill() SchErrno {
+	if th == nil || th.gen == 0 {
+		return SchEnoNone
+	}
+	eno := th.sdl.schKillTimerGen(th.ptn, th.tid, th.gen)
+	th.gen = 0
+	return eno
+}
+
+// Timer owner: an object holding timers can embed this, and call KillTimers when
+// it's destroyed, so timers carrying the object as "Extra" are all killed with it.
+type SchTimerOwner struct {
+	handles []*SchTimerHandle // timer handles owned
+}
+
+// Set timer owned
+func (to *SchTimerOwner) SetTimer(sdl *Scheduler, ptn interface{}, tdc *TimerDescription) (SchErrno, *SchTimerHandle) {
+	eno, th := sdl.SchSetTimerHandle(ptn, tdc)
+	if eno == SchEnoNone {
+		live := to.handles[:0]
+		for _, h := range to.handles {
+			if h.gen != 0 {
+				live = append(live, h)
+			}
+		}
+		to.handles = append(live, th)
+	}
+	return eno, th
+}
+
+// Kill all timers owned
+func (to *SchTimerOwner) KillTimers() {
+	for _, th := range to.handles {
+		th.Kill()
+	}
+	to.handles = nil
+}
+
 // Done caller task or other task in async
 func (sdl *Scheduler) SchTaskDone(ptn interface{}, name string, eno SchErrno) SchErrno {
 	return sdl.schTaskDone(ptn.(*schTaskNode), name, eno)
@@ -352,6 +422,29 @@ func (sdl *Scheduler) SchSetUserDataArea(ptn interface{}, uda interface{}) SchEr
 	return sdl.schSetUserDataArea(ptn.(*schTaskNode), uda)
 }
 
+// Get event history of task by name, for diagnostics
+func (sdl *Scheduler) SchGetTaskEvHistory(name string) []string {
+	_, ptn := sdl.schGetTaskNodeByName(name)
+	return sdl.schGetTaskEvHistory(ptn)
+}
+
+// Get p2p configuration injected when task created, tasks should get their
+// configurations from this one than looking up the global registry by name.
+func (sdl *Scheduler) SchGetTaskConfig(ptn interface{}) *config.Config {
+	tn, _ := ptn.(*schTaskNode)
+	return sdl.schGetTaskConfig(tn)
+}
+
+// Get value from task local storage
+func (sdl *Scheduler) SchGetTaskLocal(ptn interface{}, key string) interface{} {
+	return sdl.schGetTaskLocal(ptn.(*schTaskNode), key)
+}
+
+// Set value into task local storage
+func (sdl *Scheduler) SchSetTaskLocal(ptn interface{}, key string, val interface{}) SchErrno {
+	return sdl.schSetTaskLocal(ptn.(*schTaskNode), key, val)
+}
+
 // Set the power off stage flag to tell the scheduler it's going to be turn off
 func (sdl *Scheduler) SchSetPoweroffStage() SchErrno {
 	schLog.Debug("SchSetPoweroffStage: prepare to power off, sdl: %s", sdl.p2pCfg.Name)
@@ -399,6 +492,30 @@ func (sdl *Scheduler) SchGetP2pConfig() *config.Config {
 	return sdl.p2pCfg
 }
 
+// Random source of scheduler: it's seeded by configuration, so choices made by
+// tasks with it are reproducible in simulations and tests when a seed is fixed.
+// it must not be applied where security is required, crypto/rand is for that.
+func (sdl *Scheduler) SchRandIntn(n int) int {
+	return sdl.rand.intn(n)
+}
+
+func (sdl *Scheduler) SchRandInt31n(n int32) int32 {
+	return sdl.rand.int31n(n)
+}
+
+func (sdl *Scheduler) SchRandFloat64() float64 {
+	return sdl.rand.float64()
+}
+
+func (sdl *Scheduler) SchRandRead(buf []byte) {
+	sdl.rand.read(buf)
+}
+
+// Get seed of the random source, to reproduce a run seeded by time
+func (sdl *Scheduler) SchRandSeed() int64 {
+	return sdl.rand.seed
+}
+
 // Set application type
 func (sdl *Scheduler) SchSetAppType(appType int) SchErrno {
 	sdl.appType = appType
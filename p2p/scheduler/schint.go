@@ -21,6 +21,7 @@
 package scheduler
 
 import (
+	"math/rand"
 	"sync"
 	"time"
 
@@ -32,6 +33,29 @@ import (
 //
 type schMessage = SchMessage
 
+//
+// Message pool: the messages copied by SchSendMessage and those for timer events
+// are allocated from the pool, and released by schCommonTask after the receiver
+// task processed them. so a user task must not keep the message pointer passed to
+// it after it returns, the body is not touched and can be kept.
+//
+var schMsgPool = sync.Pool{
+	New: func() interface{} {
+		return new(schMessage)
+	},
+}
+
+func schMsgAlloc() *schMessage {
+	return schMsgPool.Get().(*schMessage)
+}
+
+func schMsgRelease(msg *schMessage) {
+	if msg != nil && msg.pooled {
+		*msg = schMessage{}
+		schMsgPool.Put(msg)
+	}
+}
+
 //
 // User task entry point
 //
@@ -85,6 +109,7 @@ type schTimerCtrlBlock struct {
 	stopped  chan bool     // had been stopped
 	taskNode *schTaskNode  // pointer to owner task node
 	extra    interface{}   // extra data return to timer owner when expired
+	gen      uint64        // generation, unique for each timer set in scheduler
 }
 
 //
@@ -123,6 +148,8 @@ type schTask struct {
 	stopped         chan bool                     // stopped signal
 	tmTab           [schMaxTaskTimer]*schTmcbNode // timer node table
 	tmIdxTab        map[*schTmcbNode]int          // map time node pointer to its' index in tmTab
+	tmGenTab        [schMaxTaskTimer]uint64       // generation of the timer last set for each index
+	tmPending       map[uint64]int                // generation to number of timer events queued
 	dog             schWatchDog                   // wathch dog
 	dieCb           func(interface{}) SchErrno    // callbacked when going to die
 	goStatus        int                           // in going or suspended
@@ -130,6 +157,8 @@ type schTask struct {
 	evhIndex        int                           // event history index
 	evTotal         int64                         // total event number
 	userData        interface{}                   // data area pointer of user task
+	p2pCfg          *config.Config                // p2p configuration injected when task created
+	locals          map[string]interface{}        // task local storage
 	isStatic        bool                          // is static task
 	isPoweron       bool                          // if EvSchPoweron sent to task
 	delayMessages   []*schMessage                 // messages before EvSchPoweron
@@ -174,6 +203,17 @@ type scheduler struct {
 	schTaskNodePool  [schTaskNodePoolSize]schTaskNode  // task node pool
 	schTimerNodePool [schTimerNodePoolSize]schTmcbNode // timer node pool
 	powerOff         bool                              // power off stage flag
+	tmGen            uint64                            // timer generation counter
+	rand             *schRand                          // random source seeded by configuration
+}
+
+//
+// Random source of scheduler, shared by tasks
+//
+type schRand struct {
+	lock sync.Mutex // lock to protect the source
+	seed int64      // seed applied
+	rnd  *rand.Rand // the source
 }
 
 //
@@ -20,6 +20,8 @@
 
 package p2p
 
+import "time"
+
 /*
 inmem_service: 测试用inmem network
 p2p_service: 全广播p2p network
@@ -41,10 +43,135 @@ type RecfgCommand struct {
 	SubnetMaskBits int  // mask bits for sub network identity
 }
 
+// Implemented by services able to tell client versions of active peers
+type PeerVersionReporter interface {
+	GetPeerVersions() (map[string]int, error)
+}
+
+// Implemented by services able to tell how the accepter of inbound peers paused
+type AcceptStatsReporter interface {
+	GetAcceptStats() (*AcceptStats, error)
+}
+
+// Implemented by services able to tell peer instances killed by sub network and cause
+type KillStatsReporter interface {
+	GetKillStats() ([]KillStat, error)
+}
+
+// Implemented by services able to detect a possible network partition. heads
+// are told by the consensus, p2p knows nothing about blocks. events are sent to
+// channels watching without blocking, they are dropped if a channel is full; the
+// last one can be got any time, see PartitionEvent.
+type PartitionDetector interface {
+	SetHead(height uint64)
+	SetPeerHead(peer string, height uint64)
+	GetPartition() (*PartitionEvent, error)
+	WatchPartition(ch chan *PartitionEvent)
+	UnwatchPartition(ch chan *PartitionEvent)
+}
+
+// Implemented by services able to list peers known, with statistics across
+// reconnects and restarts, to tell peers flapping or leeching chronically
+type PeerLister interface {
+	Peers() ([]PeerSummary, error)
+}
+
+// Implemented by services able to manage peers at runtime, for admin commands
+type PeerAdmin interface {
+	AddStatic(url string) error
+	RemovePeer(id string) error
+	BanPeer(id string, duration time.Duration) error
+	UnbanPeer(id string) error
+	GetNodeInfo() (*NodeInfo, error)
+	Ping(id string, size int, count int) (*PingStats, error)
+}
+
+// Implemented by services able to keep connections to the committee of the next
+// epoch warm but idle, and to activate them when the committee rotates, so the
+// consensus needs not wait connections and handshakes. nodes are given in urls.
+type StandbyKeeper interface {
+	SetStandbyPeers(urls []string) error
+	ActivateStandbyPeers() error
+}
+
+// Implemented by services able to ask a given peer for chain info, so requests
+// can be spread across peers. peers are identified in canonical textual format.
+type PeerChainInfoGetter interface {
+	GetActivePeers() ([]string, error)
+	GetChainInfoFrom(peer string, kind string, key []byte) ([]byte, error)
+}
+
+// Addresses and status of the local node. the reachability is told by peers
+// asked to probe the endpoints advertised, "tcp+udp", "tcp", "udp" or "none"
+// by address family, empty if never probed.
+type NodeInfo struct {
+	Id        string               // node identity in canonical textual format
+	Addr      string               // chain address, "ip:udp:tcp"
+	DhtAddr   string               // dht address, "ip:udp:tcp"
+	NatReady  bool                 // public address available
+	PubAddr   string               // public tcp address, "ip:port"
+	Subnets   []string             // sub network identities in hex
+	Banned    map[string]time.Time // banned peers and networks, and the time bans expire
+	Dialable  bool                 // advertised dialable to peers
+	Relay     bool                 // relaying messages from peers
+	Reachable map[string]string    // endpoints reached by address family, "ip4" or "ip6"
+}
+
+// Statistics of echo requests sent to a peer, see PeerAdmin.Ping
+type PingStats struct {
+	Sent     int           // echo requests sent
+	Received int           // echo responses received
+	Loss     float64       // ratio of requests without responses
+	Min      time.Duration // min round trip time
+	Avg      time.Duration // average round trip time
+	Max      time.Duration // max round trip time
+}
+
+// Statistics of the accepter paused for inbound peers full, and connections closed
+// for the quota per remote ip, see AcceptStatsReporter
+type AcceptStats struct {
+	Paused    bool  // accepter paused now
+	Pauses    int64 // times the accepter paused
+	Resumes   int64 // times the accepter resumed
+	Deferred  int64 // times resuming deferred for the min pause duration
+	IpFull    int64 // connections closed for too many inbounds of the remote ip
+	IpLimited int64 // connections closed for accepted too fast from the remote ip
+}
+
+// Peer known and statistics of it, see PeerLister. bytes and the length of
+// sessions are counted when sessions end, the cause of the last disconnect is
+// one of those of KillStat.
+type PeerSummary struct {
+	Id             string        // node identity in canonical textual format
+	Active         bool          // connected now
+	TxBytes        int64         // payload bytes sent
+	RxBytes        int64         // payload bytes received
+	Connections    int64         // sessions activated
+	AvgSession     time.Duration // average length of sessions ended
+	LastSeen       time.Time     // time connected or disconnected last
+	LastDisconnect string        // cause of the last disconnect
+}
+
+// Peer instances killed in a sub network for a cause, see KillStatsReporter. the
+// causes are those of package p2p/peer, "dupInbound", "resource", "handshake"...
+type KillStat struct {
+	Snid  string // sub network identity in hex
+	Cause string // cause killed for
+	Count int64  // number of instances killed
+}
+
 type ChainProvider interface {
 	GetChainData(kind string, key []byte) []byte
 }
 
+// Implemented by chain providers wanting to know the peer asking for chain
+// data, to apply quotas per peer. peer is the node identity in canonical
+// textual format.
+type ChainPeerProvider interface {
+	ChainProvider
+	GetChainDataFor(peer string, kind string, key []byte) []byte
+}
+
 type Service interface {
 	Start() error
 	Stop()
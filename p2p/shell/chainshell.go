@@ -21,9 +21,9 @@
 package shell
 
 import (
-	"bytes"
 	"container/list"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
@@ -77,7 +77,7 @@ type shellPeerID struct {
 
 type shellPeerInst struct {
 	shellPeerID                         // shell peer identity
-	txChan      chan *peer.P2pPackage   // tx channel of peer instance
+	txQueue     *peer.TxQueue           // tx queue of peer instance
 	rxChan      chan *peer.P2pPackageRx // rx channel of peer instance
 	hsInfo      *peer.Handshake         // handshake info about peer
 	pi          *peer.PeerInstance      // peer instance pointer
@@ -131,6 +131,7 @@ type ShellManager struct {
 	deDupDone    chan bool                           // deduplication routine done channel
 	deDupLock    sync.Mutex                          // deduplication lock
 	deDupKeyLock sync.Mutex                          // deduplication key lock
+	gossip       *gossipBudget                       // fanout budgets of broadcasting, see gossip.go
 }
 
 //
@@ -210,6 +211,7 @@ func (shMgr *ShellManager) powerOn(ptn interface{}) sch.SchErrno {
 	_, shMgr.ptnNgbMgr = shMgr.sdl.SchGetUserTaskNode(sch.NgbLsnName)
 
 	shMgr.ptrPeMgr = shMgr.sdl.SchGetTaskObject(sch.PeerMgrName).(*peer.PeerManager)
+	shMgr.gossip = newGossipBudget(shMgr.sdl.SchGetP2pConfig(), shMgr.sdl.SchRandIntn)
 	shMgr.updateLocalSubnetInfo()
 
 	if shMgr.deDup {
@@ -228,7 +230,7 @@ func (shMgr *ShellManager) powerOff(ptn interface{}) sch.SchErrno {
 }
 
 func (shMgr *ShellManager) peerActiveInd(ind *sch.MsgShellPeerActiveInd) sch.SchErrno {
-	txChan, _ := ind.TxChan.(chan *peer.P2pPackage)
+	txQueue, _ := ind.TxQueue.(*peer.TxQueue)
 	rxChan, _ := ind.RxChan.(chan *peer.P2pPackageRx)
 	peerInfo, _ := ind.PeerInfo.(*peer.Handshake)
 	pi, _ := ind.PeerInst.(*peer.PeerInstance)
@@ -239,7 +241,7 @@ func (shMgr *ShellManager) peerActiveInd(ind *sch.MsgShellPeerActiveInd) sch.Sch
 	}
 	peerInst := shellPeerInst{
 		shellPeerID: peerId,
-		txChan:      txChan,
+		txQueue:     txQueue,
 		rxChan:      rxChan,
 		hsInfo:      peerInfo,
 		pi:          pi,
@@ -341,7 +343,8 @@ func (shMgr *ShellManager) peerCloseCfm(cfm *sch.MsgShellPeerCloseCfm) sch.SchEr
 		return sch.SchEnoMismatched
 	} else {
 		hsInfo := peerInst.hsInfo
-		chainLog.ForceDebug("peerCloseCfm: snid: %x, dir: %d, ip: %s", hsInfo.Snid, hsInfo.Dir, hsInfo.IP.String())
+		chainLog.ForceDebug("peerCloseCfm: snid: %x, dir: %d, ip: %s, why: %s",
+			hsInfo.Snid, hsInfo.Dir, hsInfo.IP.String(), cfm.Why)
 		delete(shMgr.peerActived, peerId)
 		return sch.SchEnoNone
 	}
@@ -398,6 +401,47 @@ func (shMgr *ShellManager) GetRxChan() chan *peer.P2pPackageRx {
 	return shMgr.rxChan
 }
 
+//
+// Get identities of active peers, each peer once though it might be connected
+// in more sub networks or directions
+//
+func (shMgr *ShellManager) GetActivePeers() []config.NodeID {
+	shMgr.peerLock.Lock()
+	defer shMgr.peerLock.Unlock()
+	seen := make(map[config.NodeID]bool, len(shMgr.peerActived))
+	ids := make([]config.NodeID, 0, len(shMgr.peerActived))
+	for _, pe := range shMgr.peerActived {
+		if pe.status == pisActive && !seen[pe.nodeId] {
+			seen[pe.nodeId] = true
+			ids = append(ids, pe.nodeId)
+		}
+	}
+	return ids
+}
+
+//
+// Get dht nodes of active peers announced their dht ports in handshake, the
+// dht of a peer shares the node identity and ip of it
+//
+func (shMgr *ShellManager) GetDhtPeers() []*config.Node {
+	shMgr.peerLock.Lock()
+	defer shMgr.peerLock.Unlock()
+	seen := make(map[config.NodeID]bool, len(shMgr.peerActived))
+	nodes := make([]*config.Node, 0)
+	for _, pe := range shMgr.peerActived {
+		if pe.status != pisActive || pe.hsInfo == nil || pe.hsInfo.DhtPort == 0 || seen[pe.nodeId] {
+			continue
+		}
+		seen[pe.nodeId] = true
+		nodes = append(nodes, &config.Node{
+			IP:  append(net.IP{}, pe.hsInfo.IP...),
+			TCP: uint16(pe.hsInfo.DhtPort),
+			ID:  pe.nodeId,
+		})
+	}
+	return nodes
+}
+
 func (shMgr *ShellManager) reconfigReq(req *sch.MsgShellReconfigReq) sch.SchErrno {
 	msg := sch.SchMessage{}
 	shMgr.sdl.SchMakeMessage(&msg, shMgr.ptnMe, shMgr.ptnPeMgr, sch.EvShellReconfigReq, req)
@@ -427,19 +471,13 @@ func (shMgr *ShellManager) broadcastReq(req *sch.MsgShellBroadcastReq) sch.SchEr
 			}
 		}
 
-		for id, pe := range shMgr.peerActived {
-			if pe.status != pisActive {
-				chainLog.Debug("broadcastReq: not active, snid: %x, peer: %s", id.snid, pe.hsInfo.IP.String())
+		for _, pe := range shMgr.broadcastPeers(req) {
+			if shMgr.deDup == false {
+				eno := shMgr.send2Peer(pe, req)
+				chainLog.Debug("broadcastReq: send2Peer result eno: %d", eno)
 			} else {
-				if req.Exclude == nil || (req.Exclude != nil && bytes.Compare(id.nodeId[0:], req.Exclude[0:]) != 0) {
-					if shMgr.deDup == false {
-						eno := shMgr.send2Peer(pe, req)
-						chainLog.Debug("broadcastReq: send2Peer result eno: %d", eno)
-					} else {
-						eno := shMgr.checkKey(pe, id, req)
-						chainLog.Debug("broadcastReq: checkKey result eno: %d", eno)
-					}
-				}
+				eno := shMgr.checkKey(pe, pe.shellPeerID, req)
+				chainLog.Debug("broadcastReq: checkKey result eno: %d", eno)
 			}
 		}
 	default:
@@ -457,11 +495,27 @@ func (shMgr *ShellManager) bcr2Package(req *sch.MsgShellBroadcastReq) *peer.P2pP
 	pkg.Key = req.Key
 	pkg.PayloadLength = uint32(len(req.Data))
 	pkg.Payload = req.Data
+	pkg.Enqueued = time.Now()
+	pkg.TTL = req.TTL
+	pkg.Prio = bcrPrio(req.MsgType)
 	return pkg
 }
 
+// priority class of a message broadcasted, see peer.TxQueue
+func bcrPrio(msgType int) int {
+	switch msgType {
+	case sch.MSBR_MT_EV:
+		return peer.TxPrioConsensus
+	case sch.MSBR_MT_BLKH, sch.MSBR_MT_BLK:
+		return peer.TxPrioBlocks
+	case sch.MSBR_MT_TX:
+		return peer.TxPrioTxs
+	}
+	return peer.TxPrioNone
+}
+
 func (shMgr *ShellManager) send2Peer(spi *shellPeerInst, req *sch.MsgShellBroadcastReq) sch.SchErrno {
-	if len(spi.txChan) >= cap(spi.txChan) {
+	if spi.txQueue.Full(bcrPrio(req.MsgType)) {
 		chainLog.Debug("send2Peer: discarded, tx queue full, snid: %x, dir: %d, peer: %x",
 			spi.snid, spi.dir, spi.nodeId)
 		if spi.txDiscrd += 1; spi.txDiscrd&0x1f == 0 {
@@ -474,7 +528,7 @@ func (shMgr *ShellManager) send2Peer(spi *shellPeerInst, req *sch.MsgShellBroadc
 		chainLog.Debug("send2Peer: bcr2Package failed")
 		return sch.SchEnoUserTask
 	} else {
-		spi.txChan <- pkg
+		spi.txQueue.Put(pkg)
 		return sch.SchEnoNone
 	}
 }
@@ -716,7 +770,7 @@ func (shMgr *ShellManager) deDupTimerCb(el *list.Element, data interface{}) inte
 }
 
 func (shMgr *ShellManager) checkKey2Peer(spi *shellPeerInst, ddk *deDupKey) error {
-	if len(spi.txChan) >= cap(spi.txChan) {
+	if spi.txQueue.Full(peer.TxPrioNone) {
 		chainLog.Debug("checkKey2Peer: discarded, tx queue full, snid: %x, dir: %d, peer: %x",
 			spi.snid, spi.dir, spi.nodeId)
 		if spi.txDiscrd += 1; spi.txDiscrd&0x1f == 0 {
@@ -732,13 +786,13 @@ func (shMgr *ShellManager) checkKey2Peer(spi *shellPeerInst, ddk *deDupKey) erro
 		chainLog.Debug("checkKey2Peer: CheckKey failed, eno: %d", eno)
 		return errors.New("checkKey2Peer: ReportKey failed")
 	}
-	spi.txChan <- upkg
+	spi.txQueue.Put(upkg)
 	return nil
 }
 
 func (shMgr *ShellManager) reportKey2Peer(spi *shellPeerInst, key *config.DsKey, status int32) error {
 
-	if len(spi.txChan) >= cap(spi.txChan) {
+	if spi.txQueue.Full(peer.TxPrioNone) {
 		chainLog.Debug("reportKey2Peer: discarded, tx queue full, snid: %x, dir: %d, peer: %x",
 			spi.hsInfo.Snid, spi.hsInfo.Dir, spi.hsInfo.NodeId)
 		if spi.txDiscrd += 1; spi.txDiscrd&0x1f == 0 {
@@ -755,12 +809,12 @@ func (shMgr *ShellManager) reportKey2Peer(spi *shellPeerInst, key *config.DsKey,
 		chainLog.Debug("reportKey2Peer: ReportKey failed, eno: %d", eno)
 		return errors.New("reportKey2Peer: ReportKey failed")
 	}
-	spi.txChan <- upkg
+	spi.txQueue.Put(upkg)
 	return nil
 }
 
 func (shMgr *ShellManager) getChainData2Peer(spi *shellPeerInst, req *sch.MsgShellGetChainInfoReq) error {
-	if len(spi.txChan) >= cap(spi.txChan) {
+	if spi.txQueue.Full(peer.TxPrioBulk) {
 		chainLog.Debug("getChainData2Peer: discarded, tx queue full, snid: %x, dir: %d, peer: %x",
 			spi.snid, spi.dir, spi.nodeId)
 		if spi.txDiscrd += 1; spi.txDiscrd&0x1f == 0 {
@@ -779,12 +833,13 @@ func (shMgr *ShellManager) getChainData2Peer(spi *shellPeerInst, req *sch.MsgShe
 		chainLog.Debug("getChainData2Peer: CheckKey failed, eno: %d", eno)
 		return errors.New("getChainData2Peer: ReportKey failed")
 	}
-	spi.txChan <- upkg
+	upkg.Prio = peer.TxPrioBulk
+	spi.txQueue.Put(upkg)
 	return nil
 }
 
 func (shMgr *ShellManager) putChainData2Peer(spi *shellPeerInst, rsp *sch.MsgShellGetChainInfoRsp) error {
-	if len(spi.txChan) >= cap(spi.txChan) {
+	if spi.txQueue.Full(peer.TxPrioBulk) {
 		chainLog.Debug("putChainData2Peer: discarded, tx queue full, snid: %x, dir: %d, peer: %x",
 			spi.snid, spi.dir, spi.nodeId)
 		if spi.txDiscrd += 1; spi.txDiscrd&0x1f == 0 {
@@ -804,7 +859,8 @@ func (shMgr *ShellManager) putChainData2Peer(spi *shellPeerInst, rsp *sch.MsgShe
 		chainLog.Debug("putChainData2Peer: CheckKey failed, eno: %d", eno)
 		return errors.New("putChainData2Peer: ReportKey failed")
 	}
-	spi.txChan <- upkg
+	upkg.Prio = peer.TxPrioBulk
+	spi.txQueue.Put(upkg)
 	return nil
 }
 
@@ -826,6 +882,10 @@ func (shMgr *ShellManager)getChainInfoReq(msg *sch.MsgShellGetChainInfoReq) sch.
 	defer shMgr.peerLock.Unlock()
 	failCount := 0
 	for _, pe := range shMgr.peerActived {
+		if msg.Peer != nil && pe.nodeId != *msg.Peer {
+			failCount += 1
+			continue
+		}
 		if pe.status == pisActive {
 			if err := shMgr.getChainData2Peer(pe, msg); err != nil {
 				chainLog.Debug("getChainInfoReq: getChainData2Peer failed, error: %s", err.Error())
@@ -0,0 +1,105 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package shell
+
+import (
+	"bytes"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+//
+// Gossip budgets: a message broadcast is sent to all active peers of a small
+// sub network, such as the static one of validators, but to a random sample of
+// "fanout" peers of a huge dynamic one, so that the bandwidth a message takes is
+// not O(peers) as the network grows; peers relaying what they receive carry it
+// further, and deduplication keeps it from looping. the budget of a sub network
+// is the fanout configured for it, the static one gets all peers if it's not
+// configured.
+//
+
+type gossipBudget struct {
+	fanout       int                         // fanout of dynamic sub networks, 0 for all
+	subNetFanout map[config.SubNetworkID]int // fanout by sub network, overriding fanout
+	staticSnid   config.SubNetworkID         // the static sub network
+	static       bool                        // network type static, all sub networks static
+	intn         func(n int) int             // random source of sampling
+}
+
+func newGossipBudget(cfg *config.Config, intn func(n int) int) *gossipBudget {
+	return &gossipBudget{
+		fanout:       cfg.GossipFanout,
+		subNetFanout: cfg.SubNetGossipFanout,
+		staticSnid:   cfg.StaticNetId,
+		static:       cfg.NetworkType == config.P2pNetworkTypeStatic,
+		intn:         intn,
+	}
+}
+
+// Fanout of a sub network, 0 for all peers
+func (gb *gossipBudget) fanoutOf(snid config.SubNetworkID) int {
+	if fanout, ok := gb.subNetFanout[snid]; ok {
+		return fanout
+	}
+	if gb.static || snid == gb.staticSnid {
+		return 0
+	}
+	return gb.fanout
+}
+
+// Sample peers down to the fanout, the order is shuffled if sampled
+func (gb *gossipBudget) sample(peers []*shellPeerInst, fanout int) []*shellPeerInst {
+	if fanout <= 0 || len(peers) <= fanout {
+		return peers
+	}
+	for i := 0; i < fanout; i++ {
+		j := i + gb.intn(len(peers)-i)
+		peers[i], peers[j] = peers[j], peers[i]
+	}
+	return peers[:fanout]
+}
+
+// Broadcast helper: active peers a request is sent to, those of each sub network
+// sampled by the budget of it.
+func (shMgr *ShellManager) broadcastPeers(req *sch.MsgShellBroadcastReq) []*shellPeerInst {
+	bySnid := make(map[config.SubNetworkID][]*shellPeerInst, 0)
+	for id, pe := range shMgr.peerActived {
+		if pe.status != pisActive {
+			chainLog.Debug("broadcastPeers: not active, snid: %x, peer: %s", id.snid, pe.hsInfo.IP.String())
+			continue
+		}
+		if req.Exclude != nil && bytes.Compare(id.nodeId[0:], req.Exclude[0:]) == 0 {
+			continue
+		}
+		bySnid[id.snid] = append(bySnid[id.snid], pe)
+	}
+	peers := make([]*shellPeerInst, 0)
+	for snid, pes := range bySnid {
+		fanout := shMgr.gossip.fanoutOf(snid)
+		if sampled := shMgr.gossip.sample(pes, fanout); len(sampled) < len(pes) {
+			chainLog.Debug("broadcastPeers: sampled, snid: %x, peers: %d, fanout: %d", snid, len(pes), fanout)
+			pes = sampled
+		}
+		peers = append(peers, pes...)
+	}
+	return peers
+}
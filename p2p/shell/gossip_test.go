@@ -0,0 +1,88 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  the gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  the gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package shell
+
+import (
+	"math/rand"
+	"testing"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	peer "github.com/yeeco/gyee/p2p/peer"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+func TestBroadcastFanout(t *testing.T) {
+	validators := config.SubNetworkID{0xff, 0xff}
+	huge := config.SubNetworkID{0x12, 0x34}
+	pinned := config.SubNetworkID{0x56, 0x78}
+	cfg := &config.Config{
+		NetworkType:        config.P2pNetworkTypeDynamic,
+		StaticNetId:        validators,
+		GossipFanout:       8,
+		SubNetGossipFanout: map[config.SubNetworkID]int{pinned: 2},
+	}
+	shMgr := NewShellMgr()
+	shMgr.gossip = newGossipBudget(cfg, rand.New(rand.NewSource(1)).Intn)
+
+	add := func(snid config.SubNetworkID, n int) {
+		for i := 0; i < n; i++ {
+			id := shellPeerID{snid: snid, nodeId: config.NodeID{byte(i), byte(i >> 8)}}
+			shMgr.peerActived[id] = &shellPeerInst{shellPeerID: id, hsInfo: &peer.Handshake{}, status: pisActive}
+		}
+	}
+	add(validators, 20)
+	add(huge, 100)
+	add(pinned, 5)
+
+	count := func(peers []*shellPeerInst) map[config.SubNetworkID]int {
+		got := make(map[config.SubNetworkID]int, 0)
+		for _, pe := range peers {
+			got[pe.snid]++
+		}
+		return got
+	}
+	got := count(shMgr.broadcastPeers(&sch.MsgShellBroadcastReq{}))
+	if got[validators] != 20 || got[huge] != 8 || got[pinned] != 2 {
+		t.Fatalf("peers broadcast to by sub network: %v", got)
+	}
+
+	// the one excluded is not sampled, a small dynamic sub network gets all
+	exclude := config.NodeID{1, 0}
+	shMgr.gossip.fanout = 200
+	got = count(shMgr.broadcastPeers(&sch.MsgShellBroadcastReq{Exclude: &exclude}))
+	if got[validators] != 19 || got[huge] != 99 || got[pinned] != 2 {
+		t.Fatalf("peers broadcast to with one excluded: %v", got)
+	}
+
+	// samples differ from message to message
+	shMgr.gossip.fanout = 8
+	seen := make(map[shellPeerID]bool, 0)
+	for i := 0; i < 20; i++ {
+		for _, pe := range shMgr.broadcastPeers(&sch.MsgShellBroadcastReq{}) {
+			if pe.snid == huge {
+				seen[pe.shellPeerID] = true
+			}
+		}
+	}
+	if len(seen) <= 8*2 {
+		t.Errorf("peers sampled not spread, seen: %d", len(seen))
+	}
+}
@@ -81,29 +81,29 @@ func P2pCreateStaticTaskTab(what P2pType) []sch.TaskStaticDescription {
 	if what == config.P2P_TYPE_CHAIN {
 
 		return []sch.TaskStaticDescription{
-			{Name: sch.NatMgrName, Tep: nat.NewNatMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: dcv.DcvMgrName, Tep: dcv.NewDcvMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
+			{Name: sch.NatMgrName, Tep: nat.NewNatMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{tab.TabMgrName}},
+			{Name: dcv.DcvMgrName, Tep: dcv.NewDcvMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{tab.TabMgrName, sch.PeerMgrName}},
 			{Name: tab.NdbcName, Tep: tab.NewNdbCleaner(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: ngb.LsnMgrName, Tep: ngb.NewLsnMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: ngb.NgbMgrName, Tep: ngb.NewNgbMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: tab.TabMgrName, Tep: tab.NewTabMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: peer.PeerLsnMgrName, Tep: peer.NewLsnMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: sch.PeerMgrName, Tep: peer.NewPeerMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: sch.ShMgrName, Tep: NewShellMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
+			{Name: ngb.LsnMgrName, Tep: ngb.NewLsnMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{ngb.NgbMgrName}},
+			{Name: ngb.NgbMgrName, Tep: ngb.NewNgbMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{tab.TabMgrName, ngb.LsnMgrName}},
+			{Name: tab.TabMgrName, Tep: tab.NewTabMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{ngb.NgbMgrName, dcv.DcvMgrName, nat.NatMgrName, sch.PeerMgrName}},
+			{Name: peer.PeerLsnMgrName, Tep: peer.NewLsnMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{sch.PeerMgrName}},
+			{Name: sch.PeerMgrName, Tep: peer.NewPeerMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{peer.PeerLsnMgrName, tab.TabMgrName, dcv.DcvMgrName, sch.ShMgrName}},
+			{Name: sch.ShMgrName, Tep: NewShellMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{sch.PeerMgrName, tab.TabMgrName, ngb.LsnMgrName}},
 		}
 
 	} else if what == config.P2P_TYPE_DHT {
 
 		return []sch.TaskStaticDescription{
-			{Name: sch.NatMgrName, Tep: nat.NewNatMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: dht.DhtMgrName, Tep: dht.NewDhtMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: dht.DsMgrName, Tep: dht.NewDsMgr(), MbSize: dht.DsMgrMailboxSize, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: dht.LsnMgrName, Tep: dht.NewLsnMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: dht.PrdMgrName, Tep: dht.NewPrdMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: dht.QryMgrName, Tep: dht.NewQryMgr(), MbSize: dht.QryMgrMailboxSize, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: dht.RutMgrName, Tep: dht.NewRutMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: dht.ConMgrName, Tep: dht.NewConMgr(), MbSize: dht.ConMgrMailboxSize, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
-			{Name: sch.DhtShMgrName, Tep: NewDhtShellMgr(), MbSize: ShMgrMailboxSize, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend},
+			{Name: sch.NatMgrName, Tep: nat.NewNatMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{dht.DhtMgrName}},
+			{Name: dht.DhtMgrName, Tep: dht.NewDhtMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{dht.QryMgrName, dht.ConMgrName, dht.RutMgrName, dht.PrdMgrName, dht.DsMgrName, sch.DhtShMgrName}},
+			{Name: dht.DsMgrName, Tep: dht.NewDsMgr(), MbSize: dht.DsMgrMailboxSize, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{dht.DhtMgrName, dht.QryMgrName, dht.RutMgrName}},
+			{Name: dht.LsnMgrName, Tep: dht.NewLsnMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{dht.ConMgrName}},
+			{Name: dht.PrdMgrName, Tep: dht.NewPrdMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{dht.QryMgrName, dht.DsMgrName}},
+			{Name: dht.QryMgrName, Tep: dht.NewQryMgr(), MbSize: dht.QryMgrMailboxSize, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{dht.DhtMgrName, dht.RutMgrName, nat.NatMgrName}},
+			{Name: dht.RutMgrName, Tep: dht.NewRutMgr(), MbSize: -1, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{dht.QryMgrName, dht.ConMgrName}},
+			{Name: dht.ConMgrName, Tep: dht.NewConMgr(), MbSize: dht.ConMgrMailboxSize, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{dht.RutMgrName, dht.QryMgrName, dht.LsnMgrName, dht.DhtMgrName}},
+			{Name: sch.DhtShMgrName, Tep: NewDhtShellMgr(), MbSize: ShMgrMailboxSize, DieCb: nil, Wd: noDog, Flag: sch.SchCreatedSuspend, Deps: []string{dht.DhtMgrName}},
 		}
 	}
 
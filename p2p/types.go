@@ -20,6 +20,8 @@
 
 package p2p
 
+import "time"
+
 const (
 	MessageTypeTx          = "tx"
 	MessageTypeEvent       = "ev"
@@ -32,4 +34,5 @@ type Message struct {
 	From    string
 	Key     []byte
 	Data    []byte
+	TTL     time.Duration // dropped if still not sent to a peer TTL after queued, 0 for never
 }
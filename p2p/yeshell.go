@@ -26,7 +26,8 @@ import (
 	"crypto/sha256"
 	"errors"
 	"fmt"
-	"math/rand"
+	"net"
+	"sort"
 	"sync"
 	"time"
 
@@ -34,9 +35,13 @@ import (
 	p2plog "github.com/yeeco/gyee/p2p/logger"
 	"github.com/yeeco/gyee/p2p/config"
 	"github.com/yeeco/gyee/p2p/dht"
+	"github.com/yeeco/gyee/p2p/discover/discv4"
+	tab "github.com/yeeco/gyee/p2p/discover/table"
+	"github.com/yeeco/gyee/p2p/identity"
 	"github.com/yeeco/gyee/p2p/peer"
 	sch "github.com/yeeco/gyee/p2p/scheduler"
 	p2psh "github.com/yeeco/gyee/p2p/shell"
+	"github.com/yeeco/gyee/version"
 )
 
 //
@@ -112,6 +117,8 @@ const (
 	PVBS = 128				// put value buffer size
 	GCITO = time.Second * 8	// duration for get chain information
 	GCIBS = 64				// get chain formation buffer size
+	LKTO = time.Second * 80	// lookup timeout, see lookupMaxRounds of the table manager
+	DVBW = 32				// max requests in flight for batch put/get value
 	gvkChBufSize = 32		// get value duplicated channel buffer size
 )
 
@@ -131,12 +138,27 @@ type putValueResult struct {
 }
 
 const GCIKEY_LEN = 32
+const GCIKEY_MAX = 2048		// max key length, keys longer than GCIKEY_LEN are hashed
 type getChainInfoKeyEx struct {
 	name	string				// name(kind)
 	key		[GCIKEY_LEN]byte	// key, obtained from a slice, "0"s padding
 	keyLen	int					// ken length
 }
 
+func newGetChainInfoKeyEx(kind string, key []byte) getChainInfoKeyEx {
+	kex := getChainInfoKeyEx{
+		name:   kind,
+		keyLen: len(key),
+	}
+	if len(key) > GCIKEY_LEN {
+		h := sha256.Sum256(key)
+		copy(kex.key[0:], h[0:])
+	} else {
+		copy(kex.key[0:], key)
+	}
+	return kex
+}
+
 type getChainInfoValEx struct {
 	gcdChan		chan []byte		// channel to sleep on
 	gcdTimer	*time.Timer		// timer for expiration
@@ -144,6 +166,8 @@ type getChainInfoValEx struct {
 }
 
 var yesInStopping = errors.New("yesmgr: in stopping")
+var yesChainDisabled = errors.New("yesmgr: chain overlay disabled")
+var yesDhtDisabled = errors.New("yesmgr: dht disabled")
 
 type YeShellManager struct {
 	name           string                           // unique name of the shell manager
@@ -170,6 +194,7 @@ type YeShellManager struct {
 	findNodeMap    map[yesKey]chan interface{}      // find node command map to channel
 	getProviderMap map[yesKey]chan interface{}      // find node command map to channel
 	putProviderMap map[yesKey]chan interface{}      // find node command map to channel
+	cmdLock        sync.Mutex                       // lock for find node and provider command maps
 	dhtEvChan      chan *sch.MsgDhtShEventInd       // dht event indication channel
 	dhtCsChan      chan *sch.MsgDhtConInstStatusInd // dht connection status indication channel
 	subscribers    *sync.Map                        // subscribers for incoming messages
@@ -184,6 +209,9 @@ type YeShellManager struct {
 	cp             ChainProvider                    // interface registered to p2p for "get chain data" message
 	gciLock		   sync.Mutex						// get chain data lock
 	gciMap         map[getChainInfoKeyEx]*getChainInfoValEx // map for get chain information
+	discv4         *discv4.Adapter                  // ethereum discv4 adapter, nil if not enabled
+	partition      *partitionDetector               // possible network partition detector
+	ptnDoneChan    chan bool                        // partition detector ticker channel
 }
 
 const MaxSubNetMaskBits = 15 // max number of mask bits for sub network identity
@@ -195,34 +223,91 @@ type YeShellConfig struct {
 	Name              string                              // node name, should be unique
 	Validator         bool                                // validator flag
 	BootstrapNode     bool                                // bootstrap node flag
+	SeedOnly          bool                                // bootstrap node accepts peers and sheds them after SeedGraceTime
+	SeedGraceTime     time.Duration                       // duration a peer is kept by a seed-only bootstrap node
 	BootstrapNodes    []string                            // bootstrap nodes
 	DhtBootstrapNodes []string                            // bootstrap nodes for dht
 	LocalNodeIp       string                              // local node ip for chain-peers
+	LocalNodeAltIp    string                              // local node ip of the other family for dual stack, empty if not
 	LocalUdpPort      uint16                              // local node udp port
 	LocalTcpPort      uint16                              // local node tcp port
 	LocalDhtIp        string                              // local dht ip
 	LocalDhtPort      uint16                              // local dht port
+	AdvertiseIp       string                              // ip announced to others, empty for the local one
+	AdvertiseUdpPort  uint16                              // udp port announced to others, 0 for the local one
+	AdvertiseTcpPort  uint16                              // tcp port announced to others, 0 for the local one
+	AdvertiseDhtPort  uint16                              // dht port announced to others, 0 for the local one
 	NodeDataDir       string                              // node data directory
 	NodeDatabase      string                              // node database
+	DataKey           []byte                              // key to encrypt values of node database and dht store at rest, plain if empty
 	SubNetMaskBits    int                                 // mask bits for sub network identity
+	AdaptiveSlots     bool                                // shift inbound/outbound slots by connectivity observed
+	SlotOutboundMin   int                                 // min outbound slots in percent, for AdaptiveSlots
+	SlotOutboundMax   int                                 // max outbound slots in percent, for AdaptiveSlots
+	RxQueuePolicy     int                                 // policy when rx queue of a peer full, config.RxqPolicyXXX
+	RxBlockTimeout    time.Duration                       // max time blocked for config.RxqPolicyBlock
+	RxGrowMax         int                                 // max packages pending for config.RxqPolicyGrow
+	StreamMaxSize     int                                 // max bytes of a large message streamed in frames
+	StreamTimeout     time.Duration                       // max time to receive all frames of a message
+	AcceptResume      int                                 // inbounds in percent of the limit the accepter paused resumed at
+	AcceptMinPause    time.Duration                       // min duration the accepter paused for inbounds full
+	SelfProbeInterval time.Duration                       // interval of the reachability self probe, 0 to disable
+	IpMaxInbounds     int                                 // max inbound connections from a remote ip, 0 for unlimited
+	GossipFanout      int                                 // chain peers a message is broadcast to in a dynamic sub network, 0 for all
+	SubNetGossip      map[config.SubNetworkID]int         // fanout by sub network, overriding GossipFanout
+	IpAcceptRate      int                                 // max connections accepted from a remote ip per minute, 0 for unlimited
+	PeerTxRate        int                                 // max tx bytes per second of a peer, 0 for unlimited
+	PeerRxRate        int                                 // max rx bytes per second of a peer, 0 for unlimited
+	TotalTxRate       int                                 // max tx bytes per second of all peers, 0 for unlimited
+	HsAddrCheck       int                                 // check level of ip claimed in inbound handshake, config.HsAddrCheckXXX
+	HsAuthRequired    bool                                // handshakes not signed with a nonce refused, whatever version agreed
+	HsNonceWindow     time.Duration                       // max clock skew of the time in a handshake nonce
+	IpPreference      int                                 // address family dialed first, config.IpPreferXXX
+	ClientVersion     string                              // client version announced in handshake, version.ClientVersion() if empty
 	EvKeepTime        time.Duration                       // duration for events kept by dht
 	DedupTime         time.Duration                       // duration for deduplication cleanup timer
 	BootstrapTime     time.Duration                       // duration for bootstrap blind connection
 	NatType           string                              // nat type, "none"/"pmp"/"upnp"
 	GatewayIp         string                              // gateway ip when nat type is "pmp"
+	DisableChain      bool                                // do not run the chain overlay (peer, discover)
+	DisableDht        bool                                // do not run the dht
+	DhtChainBootstrap bool                                // bootstrap dht from chain peers if no dht bootstrap nodes
+	DhtVivaldi        bool                                // prefer providers with low latency predicted by network coordinates
+	DhtReplications   []config.DhtReplication             // replication of dht puts by namespace
+	DhtAcls           []config.DhtAcl                     // access policies of dht namespaces
+	DhtTuning         config.DhtTuning                    // tunables of dht queries and route table, zero fields for defaults
+	Discv4Enabled     bool                                // run the ethereum discv4 adapter
+	Discv4Port        uint16                              // udp port for the discv4 adapter
+	Discv4Nodes       []string                            // ethereum-style node list("enode" urls) to seed from
+	Discv4SeedTime    time.Duration                       // duration to seed bootstrap nodes from Discv4Nodes
+	PeerTransport     config.PeerTransport                // transport for chain peers, tcp if nil
+	WsPort            uint16                              // tcp port for websocket chain peers, 0 to disable
+	Encryption        int                                 // encryption of tcp chain peers, see config.PeerEnc*
+	SubNetEncryption  map[config.SubNetworkID]int         // encryption by sub network, overriding Encryption
+	ProxyAddr         string                              // socks5 proxy to dial peers through, "host:port", empty if none
+	ProxyUser         string                              // user name of the proxy, empty if no authentication
+	ProxyPassword     string                              // password of the proxy user
+	SubNetProxy       map[config.SubNetworkID]bool        // proxy enabled by sub network, all enabled if not listed
+	CompressDisabled  bool                                // large user packages to chain peers not compressed
+	DhtTransport      config.PeerTransport                // transport for dht connections, tcp if nil
+	RandSeed          int64                               // seed for random sources of schedulers, 0 for seeding by time
+	LogSamplings      map[string]p2plog.Sampling          // sampling rules of noisy debug logs by tag
+	Partition         PartitionConfig                     // tunables of the partition detector, zero fields for defaults
 	localSnid         []config.SubNetworkID               // local sub network identities
 	localNode         map[config.SubNetworkID]config.Node // local sub nodes
 	dhtBootstrapNodes []*config.Node                      // dht bootstarp nodes
 }
 
 const (
-	ChainCfgIdx      = 0
-	DhtCfgIdx        = 1
-	DftEvKeepTime    = time.Minute * 1
-	DftDedupTime     = time.Second * 60
-	DftBootstrapTime = time.Second * 4
-	DftNatType       = config.NATT_NONE
-	DftGatewayIp     = "0.0.0.0"
+	ChainCfgIdx       = 0
+	DhtCfgIdx         = 1
+	DftEvKeepTime     = time.Minute * 1
+	DftDedupTime      = time.Second * 60
+	DftBootstrapTime  = time.Second * 4
+	DftNatType        = config.NATT_NONE
+	DftGatewayIp      = "0.0.0.0"
+	DftDiscv4Port     = 30303
+	DftDiscv4SeedTime = time.Second * 5
 )
 
 // Default yee shell configuration for convenience
@@ -231,6 +316,8 @@ var DefaultYeShellConfig = YeShellConfig{
 	Name:          config.DefaultNodeName,
 	Validator:     true,
 	BootstrapNode: false,
+	SeedOnly:      false,
+	SeedGraceTime: config.DftSeedGraceTime,
 	BootstrapNodes: []string{
 		"3CEF400192372CD94AAE8DCA465A4A48D4FFBF7E7364D5044CD003F07DCBB0D4EEA7E311D9ED0852890C2B72E79893F0CBA5238A09F7B441613218C3A0D4659B@192.168.1.109:30304:30304",
 	},
@@ -245,11 +332,31 @@ var DefaultYeShellConfig = YeShellConfig{
 	NodeDataDir:       config.P2pDefaultDataDir(true),
 	NodeDatabase:      config.DefaultNodeDatabase,
 	SubNetMaskBits:    config.DftSnmBits,
+	AdaptiveSlots:     false,
+	SlotOutboundMin:   config.DftSlotOutMin,
+	SlotOutboundMax:   config.DftSlotOutMax,
+	RxQueuePolicy:     config.RxqPolicyDrop,
+	RxBlockTimeout:    config.DftRxBlockTimeout,
+	RxGrowMax:         config.DftRxGrowMax,
+	StreamMaxSize:     config.DftStreamMaxSize,
+	StreamTimeout:     config.DftStreamTimeout,
+	AcceptResume:      config.DftAcceptResume,
+	AcceptMinPause:    config.DftAcceptMinPause,
+	SelfProbeInterval: config.DftSelfProbeInterval,
+	IpMaxInbounds:     config.DftIpMaxInbounds,
+	GossipFanout:      config.DftGossipFanout,
+	IpAcceptRate:      config.DftIpAcceptRate,
+	HsAddrCheck:       config.HsAddrCheckNone,
+	HsNonceWindow:     config.DftHsNonceWindow,
+	IpPreference:      config.IpPreferAny,
 	EvKeepTime:        DftEvKeepTime,
 	DedupTime:         DftDedupTime,
 	BootstrapTime:     DftBootstrapTime,
 	NatType:           DftNatType,
 	GatewayIp:         DftGatewayIp,
+	Discv4Enabled:     false,
+	Discv4Port:        DftDiscv4Port,
+	Discv4SeedTime:    DftDiscv4SeedTime,
 	localSnid:         make([]config.SubNetworkID, 0),
 	localNode:         make(map[config.SubNetworkID]config.Node, 0),
 	dhtBootstrapNodes: make([]*config.Node, 0),
@@ -281,14 +388,70 @@ func YeShellConfigToP2pCfg(yesCfg *YeShellConfig) ([]*config.Config, *YeShellCon
 
 	if yesCfg.BootstrapNode {
 		chainCfg = config.P2pDefaultBootstrapConfig(yesCfg.BootstrapNodes)
+		if yesCfg.SeedOnly {
+			// still not dial, but accept inbound for a while to tell joiners
+			// what we know, see peMgrSeedShedProtect.
+			chainCfg.NoAccept = false
+			chainCfg.SeedOnly = true
+			chainCfg.SeedGraceTime = yesCfg.SeedGraceTime
+		}
 	} else {
 		chainCfg = config.P2pDefaultConfig(yesCfg.BootstrapNodes)
 	}
 
 	chainCfg.AppType = config.P2P_TYPE_CHAIN
+	chainCfg.AdaptiveSlots = yesCfg.AdaptiveSlots
+	chainCfg.SlotOutboundMin = yesCfg.SlotOutboundMin
+	chainCfg.SlotOutboundMax = yesCfg.SlotOutboundMax
+	chainCfg.RxQueuePolicy = yesCfg.RxQueuePolicy
+	chainCfg.RxBlockTimeout = yesCfg.RxBlockTimeout
+	chainCfg.RxGrowMax = yesCfg.RxGrowMax
+	chainCfg.StreamMaxSize = yesCfg.StreamMaxSize
+	chainCfg.StreamTimeout = yesCfg.StreamTimeout
+	chainCfg.AcceptResume = yesCfg.AcceptResume
+	chainCfg.AcceptMinPause = yesCfg.AcceptMinPause
+	chainCfg.SelfProbeInterval = yesCfg.SelfProbeInterval
+	chainCfg.IpMaxInbounds = yesCfg.IpMaxInbounds
+	chainCfg.GossipFanout = yesCfg.GossipFanout
+	chainCfg.SubNetGossipFanout = yesCfg.SubNetGossip
+	chainCfg.IpAcceptRate = yesCfg.IpAcceptRate
+	chainCfg.DhtDisabled = yesCfg.DisableDht
+	chainCfg.PeerTxRate = yesCfg.PeerTxRate
+	chainCfg.PeerRxRate = yesCfg.PeerRxRate
+	chainCfg.TotalTxRate = yesCfg.TotalTxRate
+	chainCfg.HsAddrCheck = yesCfg.HsAddrCheck
+	chainCfg.HsAuthRequired = yesCfg.HsAuthRequired
+	chainCfg.HsNonceWindow = yesCfg.HsNonceWindow
+	chainCfg.IpPreference = yesCfg.IpPreference
+	if chainCfg.ClientVersion = yesCfg.ClientVersion; len(chainCfg.ClientVersion) == 0 {
+		chainCfg.ClientVersion = version.ClientVersion()
+	}
+	chainCfg.DhtQryCfg.Vivaldi = yesCfg.DhtVivaldi
+	chainCfg.DhtQryCfg.Replications = yesCfg.DhtReplications
+	if config.P2pSetDhtTuning(chainCfg, yesCfg.DhtTuning) != config.P2pCfgEnoNone {
+		yesLog.Debug("YeShellConfigToP2pCfg: P2pSetDhtTuning failed")
+		return nil, nil
+	}
+	chainCfg.DhtAcls = yesCfg.DhtAcls
+	chainCfg.PeerTransport = yesCfg.PeerTransport
+	chainCfg.WsPort = yesCfg.WsPort
+	chainCfg.Encryption = yesCfg.Encryption
+	chainCfg.SubNetEncryption = yesCfg.SubNetEncryption
+	if yesCfg.ProxyAddr != "" {
+		chainCfg.PeerProxy = &config.PeerProxy{
+			Addr:     yesCfg.ProxyAddr,
+			User:     yesCfg.ProxyUser,
+			Password: yesCfg.ProxyPassword,
+		}
+	}
+	chainCfg.SubNetProxy = yesCfg.SubNetProxy
+	chainCfg.CompressDisabled = yesCfg.CompressDisabled
+	chainCfg.DhtTransport = yesCfg.DhtTransport
+	chainCfg.RandSeed = yesCfg.RandSeed
 	chainCfg.Name = yesCfg.Name
 	chainCfg.NodeDataDir = yesCfg.NodeDataDir
 	chainCfg.DhtFdsCfg.Path = yesCfg.NodeDataDir
+	chainCfg.DataKey = yesCfg.DataKey
 	if yesCfg.NodeDatabase != "" {
 		chainCfg.NodeDatabase = yesCfg.NodeDatabase
 	}
@@ -302,6 +465,23 @@ func YeShellConfigToP2pCfg(yesCfg *YeShellConfig) ([]*config.Config, *YeShellCon
 		yesLog.Debug("YeShellConfigToP2pCfg: P2pSetLocalIpAddr failed")
 		return nil, nil
 	}
+	if config.P2pSetLocalAltIpAddr(chainCfg, yesCfg.LocalNodeAltIp) != config.P2pCfgEnoNone {
+		yesLog.Debug("YeShellConfigToP2pCfg: P2pSetLocalAltIpAddr failed")
+		return nil, nil
+	}
+	yesLog.Debug("YeShellConfigToP2pCfg: advertise addr: chain[%s:%d:%d], dht[%s:%d]",
+		yesCfg.AdvertiseIp, yesCfg.AdvertiseUdpPort, yesCfg.AdvertiseTcpPort,
+		yesCfg.AdvertiseIp, yesCfg.AdvertiseDhtPort)
+
+	if config.P2pSetAdvertiseIpAddr(chainCfg, yesCfg.AdvertiseIp, yesCfg.AdvertiseUdpPort,
+		yesCfg.AdvertiseTcpPort) != config.P2pCfgEnoNone {
+		yesLog.Debug("YeShellConfigToP2pCfg: P2pSetAdvertiseIpAddr failed")
+		return nil, nil
+	}
+	if config.P2pSetAdvertiseDhtIpAddr(chainCfg, yesCfg.AdvertiseIp, yesCfg.AdvertiseDhtPort) != config.P2pCfgEnoNone {
+		yesLog.Debug("YeShellConfigToP2pCfg: P2pSetAdvertiseDhtIpAddr failed")
+		return nil, nil
+	}
 	if config.P2pSetupLocalNodeId(chainCfg) != config.P2pCfgEnoNone {
 		yesLog.Debug("YeShellConfigToP2pCfg: P2pSetupLocalNodeId failed")
 		return nil, nil
@@ -364,6 +544,18 @@ func NewYeShellManager(yesCfg *YeShellConfig) *YeShellManager {
 		deDupMap:       make(map[[yesKeyBytes]byte]bool, 0),
 		ddtChan:        make(chan bool, 1),
 		gciMap:			make(map[getChainInfoKeyEx]*getChainInfoValEx, 0),
+		partition:      newPartitionDetector(yesCfg.Partition),
+		ptnDoneChan:    make(chan bool, 1),
+	}
+
+	for tag, rule := range yesCfg.LogSamplings {
+		if err := p2plog.SetSampling(tag, rule); err != nil {
+			yesLog.Debug("NewYeShellManager: SetSampling failed, tag: %s, error: %s", tag, err.Error())
+		}
+	}
+
+	if yesCfg.Discv4Enabled && !yesCfg.DisableChain && !yesCfg.BootstrapNode && len(yesCfg.Discv4Nodes) > 0 {
+		yeShellDiscv4Seed(yesCfg)
 	}
 
 	cfg, shellCfg := YeShellConfigToP2pCfg(yesCfg)
@@ -376,22 +568,61 @@ func NewYeShellManager(yesCfg *YeShellConfig) *YeShellManager {
 		yesLog.Debug("NewYeShellManager: nil configuration")
 		return nil
 	}
-
-	yeShMgr.chainInst, eno = p2psh.P2pCreateInstance(cfg[ChainCfgIdx])
-	if eno != sch.SchEnoNone || yeShMgr.chainInst == nil {
-		yesLog.Debug("NewYeShellManager: failed, eno: %d, error: %s", eno, eno.Error())
+	if shellCfg.DisableChain && shellCfg.DisableDht {
+		yesLog.Debug("NewYeShellManager: both chain and dht disabled")
 		return nil
 	}
 
-	yeShMgr.dhtInst, eno = p2psh.P2pCreateInstance(cfg[DhtCfgIdx])
-	if eno != sch.SchEnoNone || yeShMgr.dhtInst == nil {
-		yesLog.Debug("NewYeShellManager: failed, eno: %d, error: %s", eno, eno.Error())
-		return nil
+	// a disabled stack has no scheduler instance, so none of its static tasks
+	// would be created and none of its ports would be listened.
+	if !shellCfg.DisableChain {
+		yeShMgr.chainInst, eno = p2psh.P2pCreateInstance(cfg[ChainCfgIdx])
+		if eno != sch.SchEnoNone || yeShMgr.chainInst == nil {
+			yesLog.Debug("NewYeShellManager: failed, eno: %d, error: %s", eno, eno.Error())
+			return nil
+		}
+	}
+
+	if !shellCfg.DisableDht {
+		yeShMgr.dhtInst, eno = p2psh.P2pCreateInstance(cfg[DhtCfgIdx])
+		if eno != sch.SchEnoNone || yeShMgr.dhtInst == nil {
+			yesLog.Debug("NewYeShellManager: failed, eno: %d, error: %s", eno, eno.Error())
+			return nil
+		}
 	}
 
 	return &yeShMgr
 }
 
+//
+// Seed chain bootstrap nodes from the ethereum-style node list by the discv4
+// adapter, the gyee nodes found are appended to the bootstrap nodes configured.
+//
+func yeShellDiscv4Seed(yesCfg *YeShellConfig) {
+	dv4Cfg := discv4.Config{
+		IP:        net.ParseIP(yesCfg.LocalNodeIp),
+		Port:      yesCfg.Discv4Port,
+		BootNodes: yesCfg.Discv4Nodes,
+	}
+	urls, err := discv4.Seed(&dv4Cfg, yesCfg.Discv4SeedTime)
+	if err != nil {
+		yesLog.Debug("yeShellDiscv4Seed: failed, error: %s", err.Error())
+		return
+	}
+	known := make(map[string]bool, len(yesCfg.BootstrapNodes))
+	for _, url := range yesCfg.BootstrapNodes {
+		known[url] = true
+	}
+	bsns := append([]string{}, yesCfg.BootstrapNodes...)
+	for _, url := range urls {
+		if !known[url] {
+			yesLog.Debug("yeShellDiscv4Seed: seeded: %s", url)
+			bsns = append(bsns, url)
+		}
+	}
+	yesCfg.BootstrapNodes = bsns
+}
+
 func (yeShMgr *YeShellManager) Start() error {
 	var eno sch.SchErrno
 	var ok bool
@@ -401,80 +632,111 @@ func (yeShMgr *YeShellManager) Start() error {
 
 	yesLog.Debug("yeShMgr: start...")
 
-	dht.SetChConMgrReady(yeShMgr.dhtInst.SchGetP2pCfgName(), make(chan bool, 1))
-	if eno = p2psh.P2pStart(yeShMgr.dhtInst); eno != sch.SchEnoNone {
-		yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
-		return eno
-	}
-	yeShMgr.dhtSdlName = yeShMgr.dhtInst.SchGetP2pCfgName()
+	thisCfg := yeShMgr.config
 
-	eno, yeShMgr.ptnDhtShell = yeShMgr.dhtInst.SchGetUserTaskNode(sch.DhtShMgrName)
-	if eno != sch.SchEnoNone || yeShMgr.ptnDhtShell == nil {
-		yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
-		return nil
-	}
+	if yeShMgr.dhtInst != nil {
+		dht.SetChConMgrReady(yeShMgr.dhtInst.SchGetP2pCfgName(), make(chan bool, 1))
+		if eno = p2psh.P2pStart(yeShMgr.dhtInst); eno != sch.SchEnoNone {
+			yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
+			return eno
+		}
+		yeShMgr.dhtSdlName = yeShMgr.dhtInst.SchGetP2pCfgName()
 
-	yeShMgr.ptDhtShMgr, ok = yeShMgr.dhtInst.SchGetTaskObject(sch.DhtShMgrName).(*p2psh.DhtShellManager)
-	if !ok || yeShMgr.ptDhtShMgr == nil {
-		yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
-		return nil
-	}
+		eno, yeShMgr.ptnDhtShell = yeShMgr.dhtInst.SchGetUserTaskNode(sch.DhtShMgrName)
+		if eno != sch.SchEnoNone || yeShMgr.ptnDhtShell == nil {
+			yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
+			return nil
+		}
 
-	yeShMgr.ptDhtConMgr, ok = yeShMgr.dhtInst.SchGetTaskObject(sch.DhtConMgrName).(*dht.ConMgr)
-	if !ok || yeShMgr.ptDhtConMgr == nil {
-		yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
-		return nil
+		yeShMgr.ptDhtShMgr, ok = yeShMgr.dhtInst.SchGetTaskObject(sch.DhtShMgrName).(*p2psh.DhtShellManager)
+		if !ok || yeShMgr.ptDhtShMgr == nil {
+			yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
+			return nil
+		}
+
+		yeShMgr.ptDhtConMgr, ok = yeShMgr.dhtInst.SchGetTaskObject(sch.DhtConMgrName).(*dht.ConMgr)
+		if !ok || yeShMgr.ptDhtConMgr == nil {
+			yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
+			return nil
+		}
 	}
 
-	if eno := p2psh.P2pStart(yeShMgr.chainInst); eno != sch.SchEnoNone {
-		stopCh := make(chan bool, 0)
-		yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
-		p2psh.P2pStop(yeShMgr.dhtInst, stopCh)
-		return eno
+	if yeShMgr.chainInst != nil {
+		if eno := p2psh.P2pStart(yeShMgr.chainInst); eno != sch.SchEnoNone {
+			yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
+			if yeShMgr.dhtInst != nil {
+				stopCh := make(chan bool, 0)
+				p2psh.P2pStop(yeShMgr.dhtInst, stopCh)
+			}
+			return eno
+		}
+		yeShMgr.chainSdlName = yeShMgr.chainInst.SchGetP2pCfgName()
 	}
-	yeShMgr.chainSdlName = yeShMgr.chainInst.SchGetP2pCfgName()
 
 	yeShMgr.status = yesDhtStart
 
-	eno, yeShMgr.ptnChainShell = yeShMgr.chainInst.SchGetUserTaskNode(sch.ShMgrName)
-	if eno != sch.SchEnoNone || yeShMgr.ptnChainShell == nil {
-		yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
-		return nil
-	}
+	if yeShMgr.chainInst != nil {
+		eno, yeShMgr.ptnChainShell = yeShMgr.chainInst.SchGetUserTaskNode(sch.ShMgrName)
+		if eno != sch.SchEnoNone || yeShMgr.ptnChainShell == nil {
+			yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
+			return nil
+		}
 
-	yeShMgr.ptChainShMgr, ok = yeShMgr.chainInst.SchGetTaskObject(sch.ShMgrName).(*p2psh.ShellManager)
-	if !ok || yeShMgr.ptChainShMgr == nil {
-		yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
-		return nil
+		yeShMgr.ptChainShMgr, ok = yeShMgr.chainInst.SchGetTaskObject(sch.ShMgrName).(*p2psh.ShellManager)
+		if !ok || yeShMgr.ptChainShMgr == nil {
+			yesLog.Debug("Start: failed, eno: %d, error: %s", eno, eno.Error())
+			return nil
+		}
 	}
 
 	yesLog.Debug("Start: go shell routines...")
 
-	yeShMgr.dhtEvChan = yeShMgr.ptDhtShMgr.GetEventChan()
-	yeShMgr.dhtCsChan = yeShMgr.ptDhtShMgr.GetConnStatusChan()
-	yeShMgr.chainRxChan = yeShMgr.ptChainShMgr.GetRxChan()
 	yeShMgr.tmDedup = dht.NewTimerManager()
 	yeShMgr.deDupTiker = time.NewTicker(dht.OneTick)
 
-	go yeShMgr.dhtEvProc()
-	go yeShMgr.dhtCsProc()
-
-	thisCfg := yeShMgr.config
-	if thisCfg.BootstrapNode == false {
-		yesLog.Debug("Start: wait dht ready, inst: %s", yeShMgr.dhtInst.SchGetP2pCfgName())
-		if dht.DhtReady(yeShMgr.dhtInst.SchGetP2pCfgName()) {
-			yeShMgr.bsTicker = time.NewTicker(thisCfg.BootstrapTime)
-			yeShMgr.dhtBsChan = make(chan bool, 1)
-			go yeShMgr.dhtBootstrapProc()
-			go yeShMgr.dhtPutValProc()
-			go yeShMgr.dhtGetValProc()
+	if yeShMgr.dhtInst != nil {
+		yeShMgr.dhtEvChan = yeShMgr.ptDhtShMgr.GetEventChan()
+		yeShMgr.dhtCsChan = yeShMgr.ptDhtShMgr.GetConnStatusChan()
+
+		go yeShMgr.dhtEvProc()
+		go yeShMgr.dhtCsProc()
+
+		if thisCfg.BootstrapNode == false {
+			yesLog.Debug("Start: wait dht ready, inst: %s", yeShMgr.dhtInst.SchGetP2pCfgName())
+			if dht.DhtReady(yeShMgr.dhtInst.SchGetP2pCfgName()) {
+				yeShMgr.bsTicker = time.NewTicker(thisCfg.BootstrapTime)
+				yeShMgr.dhtBsChan = make(chan bool, 1)
+				go yeShMgr.dhtBootstrapProc()
+				go yeShMgr.dhtPutValProc()
+				go yeShMgr.dhtGetValProc()
+			}
 		}
 	}
 
 	yeShMgr.status = yesDhtReady
 
-	go yeShMgr.chainRxProc()
+	if yeShMgr.chainInst != nil {
+		yeShMgr.chainRxChan = yeShMgr.ptChainShMgr.GetRxChan()
+		go yeShMgr.chainRxProc()
+	}
 	go yeShMgr.deDupTickerProc()
+	go yeShMgr.partitionProc()
+
+	if thisCfg.Discv4Enabled && yeShMgr.chainInst != nil {
+		dv4Cfg := discv4.Config{
+			IP:        net.ParseIP(thisCfg.LocalNodeIp),
+			Port:      thisCfg.Discv4Port,
+			BootNodes: thisCfg.Discv4Nodes,
+			Local:     yeShMgr.GetLocalNode(),
+		}
+		if ada, err := discv4.NewAdapter(&dv4Cfg); err != nil {
+			yesLog.Debug("Start: discv4 NewAdapter failed, error: %s", err.Error())
+		} else if err := ada.Start(); err != nil {
+			yesLog.Debug("Start: discv4 Start failed, error: %s", err.Error())
+		} else {
+			yeShMgr.discv4 = ada
+		}
+	}
 
 	yeShMgr.status = yesChainReady
 
@@ -491,35 +753,50 @@ func (yeShMgr *YeShellManager) Stop() {
 	yesLog.Debug("Stop: close deduplication ticker")
 	yeShMgr.inStopping = true
 	close(yeShMgr.ddtChan)
+	close(yeShMgr.ptnDoneChan)
 
 	stopCh := make(chan bool, 1)
-	yesLog.Debug("Stop: stop dht")
-	p2psh.P2pStop(yeShMgr.dhtInst, stopCh)
-	<-stopCh
+	if yeShMgr.dhtInst != nil {
+		yesLog.Debug("Stop: stop dht")
+		p2psh.P2pStop(yeShMgr.dhtInst, stopCh)
+		<-stopCh
+		yesLog.Debug("Stop: dht stopped")
+		log.Info("Stop: dht done", yeShMgr.dhtSdlName)
+	}
 	close(yeShMgr.getValChan)
 	close(yeShMgr.putValChan)
-	yesLog.Debug("Stop: dht stopped")
-
-	log.Info("Stop: dht done", yeShMgr.dhtSdlName)
 
-	thisCfg := yeShMgr.config
-	if thisCfg.BootstrapNode == false {
+	if yeShMgr.dhtBsChan != nil {
 		yesLog.Debug("Stop: close dht bootstrap timer")
 		close(yeShMgr.dhtBsChan)
 	}
 
-	yesLog.Debug("Stop: stop chain")
-	p2psh.P2pStop(yeShMgr.chainInst, stopCh)
-	<-stopCh
-	yesLog.Debug("Stop: chain stopped")
+	if yeShMgr.discv4 != nil {
+		yesLog.Debug("Stop: stop discv4 adapter")
+		yeShMgr.discv4.Stop()
+		yeShMgr.discv4 = nil
+	}
 
-	log.Info("Stop: chain done", yeShMgr.chainSdlName)
+	if yeShMgr.chainInst != nil {
+		yesLog.Debug("Stop: stop chain")
+		p2psh.P2pStop(yeShMgr.chainInst, stopCh)
+		<-stopCh
+		yesLog.Debug("Stop: chain stopped")
+		log.Info("Stop: chain done", yeShMgr.chainSdlName)
+	}
+
+	// drop the identity registry shared by both stacks, a disabled one has no name
+	identity.Drop(yeShMgr.chainSdlName)
+	identity.Drop(yeShMgr.dhtSdlName)
 }
 
 func (yeShMgr *YeShellManager) Reconfig(reCfg *RecfgCommand) error {
 	if yeShMgr.inStopping {
 		return yesInStopping
 	}
+	if yeShMgr.chainInst == nil {
+		return yesChainDisabled
+	}
 	if reCfg == nil {
 		yesLog.Debug("Reconfig: invalid parameter")
 		return errors.New("nil reconfigurate command")
@@ -597,6 +874,9 @@ func (yeShMgr *YeShellManager) BroadcastMessage(message Message) error {
 	if yeShMgr.inStopping {
 		return yesInStopping
 	}
+	if yeShMgr.chainInst == nil {
+		return yesChainDisabled
+	}
 	var err error = nil
 	switch message.MsgType {
 	case MessageTypeTx:
@@ -617,6 +897,9 @@ func (yeShMgr *YeShellManager) BroadcastMessageOsn(message Message) error {
 	if yeShMgr.inStopping {
 		return yesInStopping
 	}
+	if yeShMgr.chainInst == nil {
+		return yesChainDisabled
+	}
 	var err error = nil
 	switch message.MsgType {
 	case MessageTypeTx:
@@ -655,10 +938,110 @@ func (yeShMgr *YeShellManager) UnRegister(subscriber *Subscriber) {
 }
 
 func (yeShMgr *YeShellManager) DhtGetValue(key []byte) ([]byte, error) {
+	ch, err := yeShMgr.dhtGetValueStart(key)
+	if err != nil {
+		return nil, err
+	}
+	return yeShMgr.dhtGetValueWait(key, ch)
+}
+
+func (yeShMgr *YeShellManager) DhtSetValue(key []byte, value []byte) error {
+	ch, err := yeShMgr.dhtSetValueStart(key, value)
+	if err != nil {
+		return err
+	}
+	return yeShMgr.dhtSetValueWait(key, ch)
+}
+
+//
+// Pair of key and value for batch putting
+//
+type DhtKeyValue struct {
+	Key []byte // key
+	Val []byte // value
+}
+
+//
+// Put values in a batch. the requests are pipelined, with at most DVBW ones in
+// flight, and they are issued in order of keys, so those landing near each other
+// go one after another, sharing the routes and the connections to the peers. the
+// errors are returned in the order of the pairs passed in, nil for those put ok.
+//
+func (yeShMgr *YeShellManager) DhtSetValues(kvs []DhtKeyValue) []error {
+	errs := make([]error, len(kvs))
+	order := dhtBatchOrder(len(kvs), func(i int) []byte { return kvs[i].Key })
+	chs := make([]chan bool, len(kvs))
+	for pos, idx := range order {
+		if pos >= DVBW {
+			wi := order[pos-DVBW]
+			if chs[wi] != nil {
+				errs[wi] = yeShMgr.dhtSetValueWait(kvs[wi].Key, chs[wi])
+			}
+		}
+		chs[idx], errs[idx] = yeShMgr.dhtSetValueStart(kvs[idx].Key, kvs[idx].Val)
+	}
+	for pos := len(order) - DVBW; pos < len(order); pos++ {
+		if pos < 0 {
+			continue
+		}
+		if wi := order[pos]; chs[wi] != nil {
+			errs[wi] = yeShMgr.dhtSetValueWait(kvs[wi].Key, chs[wi])
+		}
+	}
+	return errs
+}
+
+//
+// Get values in a batch, pipelined as DhtSetValues. the values and errors are
+// returned in the order of the keys passed in.
+//
+func (yeShMgr *YeShellManager) DhtGetValues(keys [][]byte) ([][]byte, []error) {
+	vals := make([][]byte, len(keys))
+	errs := make([]error, len(keys))
+	order := dhtBatchOrder(len(keys), func(i int) []byte { return keys[i] })
+	chs := make([]chan []byte, len(keys))
+	for pos, idx := range order {
+		if pos >= DVBW {
+			wi := order[pos-DVBW]
+			if chs[wi] != nil {
+				vals[wi], errs[wi] = yeShMgr.dhtGetValueWait(keys[wi], chs[wi])
+			}
+		}
+		chs[idx], errs[idx] = yeShMgr.dhtGetValueStart(keys[idx])
+	}
+	for pos := len(order) - DVBW; pos < len(order); pos++ {
+		if pos < 0 {
+			continue
+		}
+		if wi := order[pos]; chs[wi] != nil {
+			vals[wi], errs[wi] = yeShMgr.dhtGetValueWait(keys[wi], chs[wi])
+		}
+	}
+	return vals, errs
+}
+
+//
+// Indices of a batch sorted by keys
+//
+func dhtBatchOrder(num int, key func(i int) []byte) []int {
+	order := make([]int, num)
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return bytes.Compare(key(order[i]), key(order[j])) < 0
+	})
+	return order
+}
+
+func (yeShMgr *YeShellManager) dhtGetValueStart(key []byte) (chan []byte, error) {
 	sdl := yeShMgr.dhtSdlName
 	if yeShMgr.inStopping {
 		return nil, yesInStopping
 	}
+	if yeShMgr.dhtInst == nil {
+		return nil, yesDhtDisabled
+	}
 	if yeShMgr.ptDhtConMgr.IsBusy(){
 		return nil, sch.SchEnoResource
 	}
@@ -686,7 +1069,11 @@ func (yeShMgr *YeShellManager) DhtGetValue(key []byte) ([]byte, error) {
 	}
 
 	yesLog.DebugDht("DhtGetValue: pending, sdl: %s, key: %x", sdl, key)
+	return ch, nil
+}
 
+func (yeShMgr *YeShellManager) dhtGetValueWait(key []byte, ch chan []byte) ([]byte, error) {
+	sdl := yeShMgr.dhtSdlName
 	val, ok := <-ch
 	if !ok {
 		yesLog.DebugDht("DhtGetValue: failed, channel closed, sdl: %s, key: %x", sdl, key)
@@ -700,17 +1087,20 @@ func (yeShMgr *YeShellManager) DhtGetValue(key []byte) ([]byte, error) {
 	return val, nil
 }
 
-func (yeShMgr *YeShellManager) DhtSetValue(key []byte, value []byte) error {
+func (yeShMgr *YeShellManager) dhtSetValueStart(key []byte, value []byte) (chan bool, error) {
 	sdl := yeShMgr.dhtSdlName
 	if yeShMgr.inStopping {
-		return yesInStopping
+		return nil, yesInStopping
+	}
+	if yeShMgr.dhtInst == nil {
+		return nil, yesDhtDisabled
 	}
 	if yeShMgr.ptDhtConMgr.IsBusy(){
-		return sch.SchEnoResource
+		return nil, sch.SchEnoResource
 	}
 	if len(key) != yesKeyBytes || len(value) == 0 {
 		yesLog.DebugDht("DhtSetValue: invalid pair, sdl: %s, key: %x, length of value: %d", sdl, key, len(value))
-		return sch.SchEnoParameter
+		return nil, sch.SchEnoParameter
 	}
 
 	yesLog.DebugDht("DhtSetValue: sdl: %s, key: %x", sdl, key)
@@ -724,16 +1114,21 @@ func (yeShMgr *YeShellManager) DhtSetValue(key []byte, value []byte) error {
 	yeShMgr.dhtInst.SchMakeMessage(&msg, &sch.PseudoSchTsk, yeShMgr.ptnDhtShell, sch.EvDhtMgrPutValueReq, &req)
 	if eno := yeShMgr.dhtInst.SchSendMessage(&msg); eno != sch.SchEnoNone {
 		yesLog.DebugDht("DhtSetValue: failed, sdl: %s, key: %x, eno: %d, error: %s", sdl, key, eno, eno.Error())
-		return eno
+		return nil, eno
 	}
 
 	ch := make(chan bool, 1)
 	if err := yeShMgr.dhtPutValMapKey(key, PVTO, ch); err != nil {
 		yesLog.DebugDht("DhtSetValue: dhtPutValMapKey failed, sdl: %s, key: %x, error: %s", sdl, key, err.Error())
-		return err
+		return nil, err
 	}
 
 	yesLog.DebugDht("DhtSetValue: pending, sdl: %s, key: %x", sdl, key)
+	return ch, nil
+}
+
+func (yeShMgr *YeShellManager) dhtSetValueWait(key []byte, ch chan bool) error {
+	sdl := yeShMgr.dhtSdlName
 	result, ok := <-ch
 	if !ok {
 		yesLog.DebugDht("DhtSetValue: failed, channel closed, sdl: %s, key: %x", sdl, key)
@@ -753,16 +1148,39 @@ func (yeShMgr *YeShellManager) RegChainProvider(cp ChainProvider) {
 }
 
 func (yeShMgr *YeShellManager) GetChainInfo(kind string, key []byte) ([]byte, error) {
-	if key == nil || len(key) > GCIKEY_LEN || len(kind) == 0 {
+	return yeShMgr.getChainInfo(nil, kind, key)
+}
+
+func (yeShMgr *YeShellManager) GetChainInfoFrom(peer string, kind string, key []byte) ([]byte, error) {
+	nid, err := config.P2pString2NodeId(peer)
+	if err != nil {
+		return nil, err
+	}
+	return yeShMgr.getChainInfo(nid, kind, key)
+}
+
+func (yeShMgr *YeShellManager) GetActivePeers() ([]string, error) {
+	if yeShMgr.chainInst == nil || yeShMgr.ptChainShMgr == nil {
+		return nil, yesChainDisabled
+	}
+	ids := yeShMgr.ptChainShMgr.GetActivePeers()
+	peers := make([]string, 0, len(ids))
+	for _, id := range ids {
+		peers = append(peers, config.P2pNodeId2String(id))
+	}
+	return peers, nil
+}
+
+func (yeShMgr *YeShellManager) getChainInfo(peer *config.NodeID, kind string, key []byte) ([]byte, error) {
+	if yeShMgr.chainInst == nil {
+		return nil, yesChainDisabled
+	}
+	if key == nil || len(key) > GCIKEY_MAX || len(kind) == 0 {
 		yesLog.Debug("GetChainInfo: invalid invalid (kind,key) pair, sdl: %s, kind: %s, key: %x",
 			yeShMgr.chainSdlName, kind, key)
 		return nil, errors.New("GetChainInfo: invalid (kind,key) pair")
 	}
-	kex := getChainInfoKeyEx {
-		name: kind,
-		keyLen: len(key),
-	}
-	copy(kex.key[0:], key)
+	kex := newGetChainInfoKeyEx(kind, key)
 
 	yeShMgr.gciLock.Lock()
 	if _, dup := yeShMgr.gciMap[kex]; dup {
@@ -785,11 +1203,12 @@ func (yeShMgr *YeShellManager) GetChainInfo(kind string, key []byte) ([]byte, er
 	defer vex.gcdTimer.Stop()
 
 	// do not use kex.key[0:] for req.Key, since it's an array than a slice,
-	// on which "0"s might have been padded after copy(...) called above.
+	// on which "0"s might have been padded, or it's the hash of a long key.
 	req := sch.MsgShellGetChainInfoReq {
 		Seq: vex.gcdSeq,
 		Kind: kex.name,
 		Key: key,
+		Peer: peer,
 	}
 	msg := sch.SchMessage{}
 	yeShMgr.chainInst.SchMakeMessage(&msg, &sch.PseudoSchTsk, yeShMgr.ptnChainShell, sch.EvShellGetChainInfoReq, &req)
@@ -830,21 +1249,31 @@ func (yeShMgr *YeShellManager) GetChainInfo(kind string, key []byte) ([]byte, er
 }
 
 func (yeShMgr *YeShellManager) DhtFindNode(target *config.NodeID, done chan interface{}) error {
+	if yeShMgr.dhtInst == nil {
+		return yesDhtDisabled
+	}
 	if target == nil || done == nil {
 		yesLog.Debug("DhtFindNode: invalid parameters")
 		return sch.SchEnoParameter
 	}
 
+	// the command is mapped before it's sent, since the response might come
+	// back before function SchSendMessage returns.
+	yeShMgr.cmdLock.Lock()
 	if len(yeShMgr.findNodeMap) >= yesMaxFindNode {
+		yeShMgr.cmdLock.Unlock()
 		yesLog.Debug("DhtFindNode: too much, max: %d", yesMaxFindNode)
 		return sch.SchEnoResource
 	}
 
 	key := *(*yesKey)(dht.RutMgrNodeId2Hash(*target))
 	if _, ok := yeShMgr.findNodeMap[key]; ok {
+		yeShMgr.cmdLock.Unlock()
 		yesLog.Debug("DhtFindNode: duplicated")
 		return sch.SchEnoDuplicated
 	}
+	yeShMgr.findNodeMap[key] = done
+	yeShMgr.cmdLock.Unlock()
 
 	req := sch.MsgDhtQryMgrQueryStartReq{
 		Target:  key,
@@ -857,14 +1286,19 @@ func (yeShMgr *YeShellManager) DhtFindNode(target *config.NodeID, done chan inte
 	yeShMgr.dhtInst.SchMakeMessage(&msg, &sch.PseudoSchTsk, yeShMgr.ptnDhtShell, sch.EvDhtMgrFindPeerReq, &req)
 	if eno := yeShMgr.dhtInst.SchSendMessage(&msg); eno != sch.SchEnoNone {
 		yesLog.Debug("DhtFindNode: failed, eno: %d, error: %s", eno, eno.Error())
+		yeShMgr.cmdLock.Lock()
+		delete(yeShMgr.findNodeMap, key)
+		yeShMgr.cmdLock.Unlock()
 		return eno
 	}
 
-	yeShMgr.findNodeMap[key] = done
 	return nil
 }
 
 func (yeShMgr *YeShellManager) DhtGetProvider(key []byte, done chan interface{}) error {
+	if yeShMgr.dhtInst == nil {
+		return yesDhtDisabled
+	}
 	if len(key) != yesKeyBytes {
 		yesLog.Debug("DhtGetProvider: invalid key: %x", key)
 		return sch.SchEnoParameter
@@ -876,10 +1310,14 @@ func (yeShMgr *YeShellManager) DhtGetProvider(key []byte, done chan interface{})
 
 	var yk yesKey
 	copy(yk[0:], key)
+	yeShMgr.cmdLock.Lock()
 	if _, ok := yeShMgr.getProviderMap[yk]; ok {
+		yeShMgr.cmdLock.Unlock()
 		yesLog.Debug("DhtGetProvider: duplicated")
 		return sch.SchEnoDuplicated
 	}
+	yeShMgr.getProviderMap[yk] = done
+	yeShMgr.cmdLock.Unlock()
 
 	req := sch.MsgDhtMgrGetProviderReq{
 		Key: key,
@@ -888,14 +1326,19 @@ func (yeShMgr *YeShellManager) DhtGetProvider(key []byte, done chan interface{})
 	yeShMgr.dhtInst.SchMakeMessage(&msg, &sch.PseudoSchTsk, yeShMgr.ptnDhtShell, sch.EvDhtMgrGetProviderReq, &req)
 	if eno := yeShMgr.dhtInst.SchSendMessage(&msg); eno != sch.SchEnoNone {
 		yesLog.Debug("DhtGetProvider: failed, eno: %d, error: %s", eno, eno.Error())
+		yeShMgr.cmdLock.Lock()
+		delete(yeShMgr.getProviderMap, yk)
+		yeShMgr.cmdLock.Unlock()
 		return eno
 	}
 
-	yeShMgr.getProviderMap[yk] = done
 	return nil
 }
 
 func (yeShMgr *YeShellManager) DhtSetProvider(key []byte, provider *config.Node, done chan interface{}) error {
+	if yeShMgr.dhtInst == nil {
+		return yesDhtDisabled
+	}
 	if len(key) != yesKeyBytes {
 		yesLog.Debug("DhtSetProvider: invalid key: %x", key)
 		return sch.SchEnoParameter
@@ -907,10 +1350,14 @@ func (yeShMgr *YeShellManager) DhtSetProvider(key []byte, provider *config.Node,
 
 	var yk yesKey
 	copy(yk[0:], key)
-	if _, ok := yeShMgr.getProviderMap[yk]; ok {
+	yeShMgr.cmdLock.Lock()
+	if _, ok := yeShMgr.putProviderMap[yk]; ok {
+		yeShMgr.cmdLock.Unlock()
 		yesLog.Debug("DhtSetProvider: duplicated")
 		return sch.SchEnoDuplicated
 	}
+	yeShMgr.putProviderMap[yk] = done
+	yeShMgr.cmdLock.Unlock()
 
 	req := sch.MsgDhtPrdMgrAddProviderReq{
 		Key: key,
@@ -920,10 +1367,12 @@ func (yeShMgr *YeShellManager) DhtSetProvider(key []byte, provider *config.Node,
 	yeShMgr.dhtInst.SchMakeMessage(&msg, &sch.PseudoSchTsk, yeShMgr.ptnDhtShell, sch.EvDhtMgrPutProviderReq, &req)
 	if eno := yeShMgr.dhtInst.SchSendMessage(&msg); eno != sch.SchEnoNone {
 		yesLog.Debug("DhtSetProvider: failed, eno: %d, error: %s", eno, eno.Error())
+		yeShMgr.cmdLock.Lock()
+		delete(yeShMgr.putProviderMap, yk)
+		yeShMgr.cmdLock.Unlock()
 		return eno
 	}
 
-	yeShMgr.putProviderMap[yk] = done
 	return nil
 }
 
@@ -1056,6 +1505,9 @@ func (yeShMgr *YeShellManager) chainRxProc() {
 	bkCount := 0
 	xxCount := 0
 
+	// messages are not relayed if the node is not reachable, see the self probe
+	peMgr, _ := yeShMgr.chainInst.SchGetTaskObject(sch.PeerMgrName).(*peer.PeerManager)
+
 _rxLoop:
 	for {
 		select {
@@ -1113,6 +1565,9 @@ _rxLoop:
 						sub, _ := key.(*Subscriber)
 						sub.MsgChan <- msg
 
+						if peMgr != nil && !peMgr.Relayable() {
+							return true
+						}
 						exclude := pkg.PeerInfo.NodeId
 						err := error(nil)
 						switch msg.MsgType {
@@ -1147,9 +1602,11 @@ _bootstarp:
 		case <-yeShMgr.bsTicker.C:
 
 			if len(thisCfg.dhtBootstrapNodes) <= 0 {
-				yesLog.Debug("dhtBootstrapProc: none of bootstarp nodes")
+				if !yeShMgr.dhtChainBootstrap() {
+					yesLog.Debug("dhtBootstrapProc: none of bootstarp nodes")
+				}
 			} else {
-				r := rand.Int31n(int32(len(thisCfg.dhtBootstrapNodes)))
+				r := yeShMgr.dhtInst.SchRandInt31n(int32(len(thisCfg.dhtBootstrapNodes)))
 				req := sch.MsgDhtBlindConnectReq{
 					Peer: thisCfg.dhtBootstrapNodes[r],
 				}
@@ -1166,6 +1623,28 @@ _bootstarp:
 	yesLog.Debug("dhtBootstrapProc: exit")
 }
 
+// Blind connect to the dht of a chain peer picked randomly, for the case that
+// no dht bootstrap nodes configured but chain ones, false if none of chain peers
+// announced dht.
+func (yeShMgr *YeShellManager) dhtChainBootstrap() bool {
+	if !yeShMgr.config.DhtChainBootstrap || yeShMgr.chainInst == nil || yeShMgr.ptChainShMgr == nil {
+		return false
+	}
+	peers := yeShMgr.ptChainShMgr.GetDhtPeers()
+	if len(peers) == 0 {
+		return false
+	}
+	r := yeShMgr.dhtInst.SchRandInt31n(int32(len(peers)))
+	yesLog.Debug("dhtChainBootstrap: peer: %s, ip: %s, port: %d", config.P2pNodeId2String(peers[r].ID), peers[r].IP, peers[r].TCP)
+	req := sch.MsgDhtBlindConnectReq{
+		Peer: peers[r],
+	}
+	msg := sch.SchMessage{}
+	yeShMgr.dhtInst.SchMakeMessage(&msg, &sch.PseudoSchTsk, yeShMgr.ptnDhtShell, sch.EvDhtBlindConnectReq, &req)
+	yeShMgr.dhtInst.SchSendMessage(&msg)
+	return true
+}
+
 func (yeShMgr *YeShellManager)dhtPutValMapKey(key []byte, to time.Duration, ch chan bool) error {
 	yeShMgr.putValLock.Lock()
 	defer yeShMgr.putValLock.Unlock()
@@ -1342,35 +1821,50 @@ func (yeShMgr *YeShellManager) dhtBlindConnectRsp(msg *sch.MsgDhtBlindConnectRsp
 	yesLog.Debug("dhtBlindConnectRsp: msg: %+v", *msg)
 	thisCfg := yeShMgr.config
 
+	if msg.Eno != dht.DhtEnoNone.GetEno() && msg.Eno != dht.DhtEnoDuplicated.GetEno() {
+		return sch.SchEnoMismatched
+	}
+	// chain peers are connected for bootstrap if no bootstrap nodes, see dhtChainBootstrap
+	bootstrapped := len(thisCfg.dhtBootstrapNodes) == 0 && thisCfg.DhtChainBootstrap
 	for _, bsn := range thisCfg.dhtBootstrapNodes {
-		if msg.Eno == dht.DhtEnoNone.GetEno() || msg.Eno == dht.DhtEnoDuplicated.GetEno() {
-			if bytes.Compare(msg.Peer.ID[0:], bsn.ID[0:]) == 0 {
-
-				// done the blind-connect routine
-				yesLog.Debug("dhtBlindConnectRsp: bootstrap node connected, id: %x", msg.Peer.ID)
-				yeShMgr.dhtBsChan <- true
-
-				// when coming here, we should have added the connected bootstarp node to our
-				// dht route table, but it's the only node there, we need to start a bootstrap
-				// procedure to fill our route now.
-				time.Sleep(time.Second)
-				schMsg := sch.SchMessage{}
-				yeShMgr.dhtInst.SchMakeMessage(&schMsg, &sch.PseudoSchTsk, yeShMgr.ptnDhtShell, sch.EvDhtRutRefreshReq, nil)
-				yeShMgr.dhtInst.SchSendMessage(&schMsg)
-
-				return sch.SchEnoNone
-			}
+		if bytes.Compare(msg.Peer.ID[0:], bsn.ID[0:]) == 0 {
+			bootstrapped = true
+			break
 		}
 	}
+	if !bootstrapped {
+		return sch.SchEnoMismatched
+	}
+
+	// done the blind-connect routine, not blocked if responses to more requests
+	// come before the routine exits
+	yesLog.Debug("dhtBlindConnectRsp: bootstrap node connected, id: %s", config.P2pNodeId2String(msg.Peer.ID))
+	select {
+	case yeShMgr.dhtBsChan <- true:
+	default:
+		return sch.SchEnoNone
+	}
+
+	// when coming here, we should have added the connected bootstarp node to our
+	// dht route table, but it's the only node there, we need to start a bootstrap
+	// procedure to fill our route now.
+	time.Sleep(time.Second)
+	schMsg := sch.SchMessage{}
+	yeShMgr.dhtInst.SchMakeMessage(&schMsg, &sch.PseudoSchTsk, yeShMgr.ptnDhtShell, sch.EvDhtRutRefreshReq, nil)
+	yeShMgr.dhtInst.SchSendMessage(&schMsg)
 
-	return sch.SchEnoMismatched
+	return sch.SchEnoNone
 }
 
 func (yeShMgr *YeShellManager) dhtMgrFindPeerRsp(msg *sch.MsgDhtQryMgrQueryResultInd) sch.SchErrno {
 	yesLog.Debug("dhtMgrFindPeerRsp: msg: %+v", *msg)
-	if done, ok := yeShMgr.findNodeMap[msg.Target]; ok {
+	yeShMgr.partition.dhtQueried(dhtQueryFailed(msg.Eno), time.Now())
+	yeShMgr.cmdLock.Lock()
+	done, ok := yeShMgr.findNodeMap[msg.Target]
+	delete(yeShMgr.findNodeMap, msg.Target)
+	yeShMgr.cmdLock.Unlock()
+	if ok {
 		done <- msg
-		delete(yeShMgr.findNodeMap, msg.Target)
 	}
 	return sch.SchEnoNone
 }
@@ -1397,23 +1891,30 @@ func (yeShMgr *YeShellManager) dhtMgrPutProviderRsp(msg *sch.MsgDhtPrdMgrAddProv
 	}
 	yk := yesKey{}
 	copy(yk[0:], msg.Key)
-	if done, ok := yeShMgr.putProviderMap[yk]; ok {
+	yeShMgr.cmdLock.Lock()
+	done, ok := yeShMgr.putProviderMap[yk]
+	delete(yeShMgr.putProviderMap, yk)
+	yeShMgr.cmdLock.Unlock()
+	if ok {
 		done <- msg
-		delete(yeShMgr.putProviderMap, yk)
 	}
 	return sch.SchEnoNone
 }
 
 func (yeShMgr *YeShellManager) dhtMgrGetProviderRsp(msg *sch.MsgDhtMgrGetProviderRsp) sch.SchErrno {
 	yesLog.Debug("dhtMgrGetProviderRsp: msg: %+v", *msg)
+	yeShMgr.partition.dhtQueried(dhtQueryFailed(msg.Eno), time.Now())
 	if len(msg.Key) != yesKeyBytes {
 		return sch.SchEnoParameter
 	}
 	yk := yesKey{}
 	copy(yk[0:], msg.Key)
-	if done, ok := yeShMgr.getProviderMap[yk]; ok {
+	yeShMgr.cmdLock.Lock()
+	done, ok := yeShMgr.getProviderMap[yk]
+	delete(yeShMgr.getProviderMap, yk)
+	yeShMgr.cmdLock.Unlock()
+	if ok {
 		done <- msg
-		delete(yeShMgr.getProviderMap, yk)
 	}
 	return sch.SchEnoNone
 }
@@ -1440,6 +1941,7 @@ func (yeShMgr *YeShellManager) dhtMgrPutValueRsp(msg *sch.MsgDhtMgrPutValueRsp)
 
 func (yeShMgr *YeShellManager) dhtMgrGetValueRsp(msg *sch.MsgDhtMgrGetValueRsp) sch.SchErrno {
 	yesLog.Debug("dhtMgrGetValueRsp: msg: %+v", *msg)
+	yeShMgr.partition.dhtQueried(dhtQueryFailed(msg.Eno), time.Now())
 	gvr := getValueResult{
 		eno: msg.Eno,
 		key: msg.Key,
@@ -1499,6 +2001,7 @@ func (yeShMgr *YeShellManager) broadcastTxOsn(msg *Message, exclude *config.Node
 		Key:     msg.Key,
 		Data:    msg.Data,
 		Exclude: exclude,
+		TTL:     msg.TTL,
 	}
 	yeShMgr.chainInst.SchMakeMessage(&schMsg, &sch.PseudoSchTsk, yeShMgr.ptnChainShell, sch.EvShellBroadcastReq, &req)
 	if eno := yeShMgr.chainInst.SchSendMessage(&schMsg); eno != sch.SchEnoNone {
@@ -1537,6 +2040,7 @@ func (yeShMgr *YeShellManager) broadcastEvOsn(msg *Message, exclude *config.Node
 		Key:     msg.Key,
 		Data:    msg.Data,
 		Exclude: exclude,
+		TTL:     msg.TTL,
 	}
 	schMsg := sch.SchMessage{}
 	yeShMgr.chainInst.SchMakeMessage(&schMsg, &sch.PseudoSchTsk, yeShMgr.ptnChainShell, sch.EvShellBroadcastReq, &req)
@@ -1545,7 +2049,7 @@ func (yeShMgr *YeShellManager) broadcastEvOsn(msg *Message, exclude *config.Node
 		return eno
 	}
 
-	if dht {
+	if dht && yeShMgr.dhtInst != nil {
 		req2Dht := sch.MsgDhtMgrPutValueReq{
 			Key:      msg.Key,
 			Val:      msg.Data,
@@ -1589,6 +2093,7 @@ func (yeShMgr *YeShellManager) broadcastBhOsn(msg *Message, exclude *config.Node
 		Key:     msg.Key,
 		Data:    msg.Data,
 		Exclude: exclude,
+		TTL:     msg.TTL,
 	}
 	yeShMgr.chainInst.SchMakeMessage(&schMsg, &sch.PseudoSchTsk, yeShMgr.ptnChainShell, sch.EvShellBroadcastReq, &req)
 	if eno := yeShMgr.chainInst.SchSendMessage(&schMsg); eno != sch.SchEnoNone {
@@ -1645,6 +2150,7 @@ func (yeShMgr *YeShellManager) broadcastBkOsn(msg *Message, exclude *config.Node
 			Key:     msg.Key,
 			Data:    msg.Data,
 			Exclude: exclude,
+			TTL:     msg.TTL,
 		}
 		yeShMgr.chainInst.SchMakeMessage(&schMsg, &sch.PseudoSchTsk, yeShMgr.ptnChainShell, sch.EvShellBroadcastReq, &req)
 		if eno := yeShMgr.chainInst.SchSendMessage(&schMsg); eno != sch.SchEnoNone {
@@ -1728,21 +2234,433 @@ _dedup:
 	yesLog.Debug("deDupTickerProc: exit")
 }
 
+func (yeShMgr *YeShellManager) anyInst() *sch.Scheduler {
+	// the dht configuration is copied from the chain one, so both hold the
+	// same local identities, take whichever is running.
+	if yeShMgr.chainInst != nil {
+		return yeShMgr.chainInst
+	}
+	return yeShMgr.dhtInst
+}
+
 func (yeShMgr *YeShellManager) GetLocalNode() *config.Node {
-	cfg := yeShMgr.chainInst.SchGetP2pConfig()
+	cfg := yeShMgr.anyInst().SchGetP2pConfig()
 	return &cfg.Local
 }
 
 func (yeShMgr *YeShellManager) GetLocalPrivateKey() *ecdsa.PrivateKey {
-	cfg := yeShMgr.chainInst.SchGetP2pConfig()
+	cfg := yeShMgr.anyInst().SchGetP2pConfig()
 	return cfg.PrivateKey
 }
 
 func (yeShMgr *YeShellManager) GetLocalDhtNode() *config.Node {
-	cfg := yeShMgr.chainInst.SchGetP2pConfig()
+	cfg := yeShMgr.anyInst().SchGetP2pConfig()
 	return &cfg.DhtLocal
 }
 
+// Lookup asks the table manager of the chain to look up target in subnet snid,
+// and returns the closest nodes found, with their endpoints. it's blocked until
+// the lookup done, which takes rounds of queries till no closer nodes found.
+func (yeShMgr *YeShellManager) Lookup(snid config.SubNetworkID, target config.NodeID) ([]*config.Node, error) {
+	if yeShMgr.chainInst == nil {
+		return nil, yesChainDisabled
+	}
+	eno, ptnTabMgr := yeShMgr.chainInst.SchGetUserTaskNode(sch.TabMgrName)
+	if eno != sch.SchEnoNone || ptnTabMgr == nil {
+		return nil, errors.New("Lookup: table manager not found")
+	}
+
+	req := sch.MsgTabLookupReq{
+		Snid:   snid,
+		Target: target,
+		Done:   make(chan *sch.MsgTabLookupRsp, 1),
+	}
+	msg := sch.SchMessage{}
+	yeShMgr.chainInst.SchMakeMessage(&msg, &sch.PseudoSchTsk, ptnTabMgr, sch.EvTabLookupReq, &req)
+	if eno := yeShMgr.chainInst.SchSendMessage(&msg); eno != sch.SchEnoNone {
+		yesLog.Debug("Lookup: SchSendMessage failed, eno: %d", eno)
+		return nil, eno
+	}
+
+	tm := time.NewTimer(LKTO)
+	defer tm.Stop()
+	select {
+	case <-tm.C:
+		yesLog.Debug("Lookup: timeout, snid: %x, target: %x", snid, target)
+		return nil, errors.New("Lookup: timeout")
+	case rsp := <-req.Done:
+		if rsp.Result != tab.TabMgrEnoNone {
+			yesLog.Debug("Lookup: failed, snid: %x, target: %x, result: %d", snid, target, rsp.Result)
+			return nil, errors.New(fmt.Sprintf("Lookup: failed, result: %d", rsp.Result))
+		}
+		return rsp.Nodes, nil
+	}
+}
+
+func (yeShMgr *YeShellManager) GetPeerVersions() (map[string]int, error) {
+	if yeShMgr.chainInst == nil {
+		return nil, yesChainDisabled
+	}
+	peMgr, ok := yeShMgr.chainInst.SchGetTaskObject(sch.PeerMgrName).(*peer.PeerManager)
+	if !ok || peMgr == nil {
+		return nil, errors.New("GetPeerVersions: peer manager not found")
+	}
+	return peMgr.GetPeerVersions(), nil
+}
+
+func (yeShMgr *YeShellManager) GetAcceptStats() (*AcceptStats, error) {
+	if yeShMgr.chainInst == nil {
+		return nil, yesChainDisabled
+	}
+	peMgr, ok := yeShMgr.chainInst.SchGetTaskObject(sch.PeerMgrName).(*peer.PeerManager)
+	if !ok || peMgr == nil {
+		return nil, errors.New("GetAcceptStats: peer manager not found")
+	}
+	as := peMgr.GetAcceptStats()
+	return &AcceptStats{
+		Paused:    as.Paused,
+		Pauses:    as.Pauses,
+		Resumes:   as.Resumes,
+		Deferred:  as.Deferred,
+		IpFull:    as.IpFull,
+		IpLimited: as.IpLimited,
+	}, nil
+}
+
+func (yeShMgr *YeShellManager) GetKillStats() ([]KillStat, error) {
+	if yeShMgr.chainInst == nil {
+		return nil, yesChainDisabled
+	}
+	peMgr, ok := yeShMgr.chainInst.SchGetTaskObject(sch.PeerMgrName).(*peer.PeerManager)
+	if !ok || peMgr == nil {
+		return nil, errors.New("GetKillStats: peer manager not found")
+	}
+	stats := make([]KillStat, 0)
+	for _, ks := range peMgr.GetKillStats() {
+		stats = append(stats, KillStat{
+			Snid:  fmt.Sprintf("%x", ks.Snid),
+			Cause: ks.Cause,
+			Count: ks.Count,
+		})
+	}
+	return stats, nil
+}
+
+// Peers lists peers known with statistics kept across reconnects and restarts,
+// the ones seen latest first, see peer.GetPeerStats.
+func (yeShMgr *YeShellManager) Peers() ([]PeerSummary, error) {
+	peMgr, err := yeShMgr.peerMgr("Peers")
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[config.NodeID]bool, 0)
+	if yeShMgr.ptChainShMgr != nil {
+		for _, id := range yeShMgr.ptChainShMgr.GetActivePeers() {
+			active[id] = true
+		}
+	}
+	peers := make([]PeerSummary, 0)
+	for _, ps := range peMgr.GetPeerStats() {
+		peers = append(peers, PeerSummary{
+			Id:             config.P2pNodeId2String(ps.Id),
+			Active:         active[ps.Id],
+			TxBytes:        ps.TxBytes,
+			RxBytes:        ps.RxBytes,
+			Connections:    ps.Connections,
+			AvgSession:     ps.AvgSession(),
+			LastSeen:       ps.LastSeen,
+			LastDisconnect: ps.LastDisconnect,
+		})
+	}
+	return peers, nil
+}
+
+func (yeShMgr *YeShellManager) GetMsgStats() ([]peer.MsgStat, error) {
+	if yeShMgr.chainInst == nil {
+		return nil, yesChainDisabled
+	}
+	peMgr, ok := yeShMgr.chainInst.SchGetTaskObject(sch.PeerMgrName).(*peer.PeerManager)
+	if !ok || peMgr == nil {
+		return nil, errors.New("GetMsgStats: peer manager not found")
+	}
+	return peMgr.GetMsgStats(), nil
+}
+
+// RegisterFastPath delivers messages of msgType from peers to the ring buffer
+// returned, bypassing deduplication and relaying, see peer.RegisterFastPath.
+func (yeShMgr *YeShellManager) RegisterFastPath(msgType string, size int) (*peer.FastRing, error) {
+	if yeShMgr.chainInst == nil {
+		return nil, yesChainDisabled
+	}
+	mid, ok := yesMtAtoi[msgType]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("RegisterFastPath: invalid type: %s", msgType))
+	}
+	peMgr, ok := yeShMgr.chainInst.SchGetTaskObject(sch.PeerMgrName).(*peer.PeerManager)
+	if !ok || peMgr == nil {
+		return nil, errors.New("RegisterFastPath: peer manager not found")
+	}
+	ring, eno := peMgr.RegisterFastPath(uint32(mid), size)
+	if eno != peer.PeMgrEnoNone {
+		return nil, errors.New(fmt.Sprintf("RegisterFastPath: failed, eno: %d", eno))
+	}
+	return ring, nil
+}
+
+func (yeShMgr *YeShellManager) UnregisterFastPath(msgType string) error {
+	if yeShMgr.chainInst == nil {
+		return yesChainDisabled
+	}
+	mid, ok := yesMtAtoi[msgType]
+	if !ok {
+		return errors.New(fmt.Sprintf("UnregisterFastPath: invalid type: %s", msgType))
+	}
+	if peMgr, ok := yeShMgr.chainInst.SchGetTaskObject(sch.PeerMgrName).(*peer.PeerManager); ok {
+		peMgr.UnregisterFastPath(uint32(mid))
+	}
+	return nil
+}
+
+// RegisterProtocol registers protocol pid of version ver at runtime, packages
+// of it from peers are passed to handler. it's advertised to peers connected
+// since then, see peer.RegisterProtocol.
+func (yeShMgr *YeShellManager) RegisterProtocol(pid uint32, ver [4]byte, handler peer.ProtocolHandler) error {
+	peMgr, err := yeShMgr.peerMgr("RegisterProtocol")
+	if err != nil {
+		return err
+	}
+	if eno := peMgr.RegisterProtocol(pid, ver, handler); eno != peer.PeMgrEnoNone {
+		return errors.New(fmt.Sprintf("RegisterProtocol: failed, eno: %d", eno))
+	}
+	return nil
+}
+
+// RegisterProtocolPool registers protocol pid as RegisterProtocol, packages of
+// it are handled by a worker pool configured by pool, see peer.ProtocolPool.
+func (yeShMgr *YeShellManager) RegisterProtocolPool(pid uint32, ver [4]byte, handler peer.ProtocolHandler, pool peer.ProtocolPool) error {
+	peMgr, err := yeShMgr.peerMgr("RegisterProtocolPool")
+	if err != nil {
+		return err
+	}
+	if eno := peMgr.RegisterProtocolPool(pid, ver, handler, pool); eno != peer.PeMgrEnoNone {
+		return errors.New(fmt.Sprintf("RegisterProtocolPool: failed, eno: %d", eno))
+	}
+	return nil
+}
+
+func (yeShMgr *YeShellManager) UnregisterProtocol(pid uint32) error {
+	peMgr, err := yeShMgr.peerMgr("UnregisterProtocol")
+	if err != nil {
+		return err
+	}
+	if eno := peMgr.UnregisterProtocol(pid); eno != peer.PeMgrEnoNone {
+		return errors.New(fmt.Sprintf("UnregisterProtocol: failed, eno: %d", eno))
+	}
+	return nil
+}
+
+// AddStatic adds a static node in format "id@ip:udp:tcp" and connects to it,
+// it's kept till removed, see peer.AddStaticPeer.
+func (yeShMgr *YeShellManager) AddStatic(url string) error {
+	nodes := config.P2pSetupBootstrapNodes([]string{url})
+	if len(nodes) != 1 {
+		return errors.New(fmt.Sprintf("AddStatic: invalid url: %s", url))
+	}
+	peMgr, err := yeShMgr.peerMgr("AddStatic")
+	if err != nil {
+		return err
+	}
+	if eno := peMgr.AddStaticPeer(nodes[0]); eno != peer.PeMgrEnoNone {
+		return errors.New(fmt.Sprintf("AddStatic: failed, eno: %d", eno))
+	}
+	return nil
+}
+
+// Keep connections to nodes warm, replacing those set before
+func (yeShMgr *YeShellManager) SetStandbyPeers(urls []string) error {
+	nodes := config.P2pSetupBootstrapNodes(urls)
+	if len(nodes) != len(urls) {
+		return errors.New(fmt.Sprintf("SetStandbyPeers: invalid urls: %v", urls))
+	}
+	peMgr, err := yeShMgr.peerMgr("SetStandbyPeers")
+	if err != nil {
+		return err
+	}
+	if eno := peMgr.SetStandby(nodes); eno != peer.PeMgrEnoNone {
+		return errors.New(fmt.Sprintf("SetStandbyPeers: failed, eno: %d", eno))
+	}
+	return nil
+}
+
+func (yeShMgr *YeShellManager) ActivateStandbyPeers() error {
+	peMgr, err := yeShMgr.peerMgr("ActivateStandbyPeers")
+	if err != nil {
+		return err
+	}
+	if eno := peMgr.ActivateStandby(); eno != peer.PeMgrEnoNone {
+		return errors.New(fmt.Sprintf("ActivateStandbyPeers: failed, eno: %d", eno))
+	}
+	return nil
+}
+
+func (yeShMgr *YeShellManager) RemovePeer(id string) error {
+	nid, err := config.P2pString2NodeId(id)
+	if err != nil {
+		return err
+	}
+	peMgr, err := yeShMgr.peerMgr("RemovePeer")
+	if err != nil {
+		return err
+	}
+	if eno := peMgr.RemovePeer(*nid); eno != peer.PeMgrEnoNone {
+		return errors.New(fmt.Sprintf("RemovePeer: failed, eno: %d", eno))
+	}
+	return nil
+}
+
+// Ban a peer by node identity, or addresses by ip or CIDR, for duration
+func (yeShMgr *YeShellManager) BanPeer(id string, duration time.Duration) error {
+	peMgr, err := yeShMgr.peerMgr("BanPeer")
+	if err != nil {
+		return err
+	}
+	nid, ipNet, err := p2pParseBanTarget(id)
+	if err != nil {
+		return err
+	}
+	var eno peer.PeMgrErrno
+	if nid != nil {
+		eno = peMgr.BanPeer(*nid, duration)
+	} else {
+		eno = peMgr.BanNet(ipNet, duration)
+	}
+	// not connected is fine, the peer is banned anyway
+	if eno != peer.PeMgrEnoNone && eno != peer.PeMgrEnoNotfound {
+		return errors.New(fmt.Sprintf("BanPeer: failed, eno: %d", eno))
+	}
+	return nil
+}
+
+func (yeShMgr *YeShellManager) UnbanPeer(id string) error {
+	peMgr, err := yeShMgr.peerMgr("UnbanPeer")
+	if err != nil {
+		return err
+	}
+	nid, ipNet, err := p2pParseBanTarget(id)
+	if err != nil {
+		return err
+	}
+	if nid != nil {
+		peMgr.UnbanPeer(*nid)
+	} else {
+		peMgr.UnbanNet(ipNet)
+	}
+	return nil
+}
+
+// Probe the link to a peer with count echo requests padded to size bytes, it
+// blocks till all responses received or timed out, see peer.Echo.
+func (yeShMgr *YeShellManager) Ping(id string, size int, count int) (*PingStats, error) {
+	nid, err := config.P2pString2NodeId(id)
+	if err != nil {
+		return nil, err
+	}
+	peMgr, err := yeShMgr.peerMgr("Ping")
+	if err != nil {
+		return nil, err
+	}
+	es, eno := peMgr.Echo(*nid, size, count)
+	if eno != peer.PeMgrEnoNone {
+		return nil, errors.New(fmt.Sprintf("Ping: failed, eno: %d", eno))
+	}
+	return &PingStats{
+		Sent:     es.Sent,
+		Received: es.Received,
+		Loss:     es.Loss(),
+		Min:      es.Min,
+		Avg:      es.Avg,
+		Max:      es.Max,
+	}, nil
+}
+
+// node identity, or else ip or CIDR, banned
+func p2pParseBanTarget(id string) (*config.NodeID, *net.IPNet, error) {
+	if nid, err := config.P2pString2NodeId(id); err == nil {
+		return nid, nil, nil
+	}
+	ipNet, err := peer.ParseBanNet(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, ipNet, nil
+}
+
+func (yeShMgr *YeShellManager) GetNodeInfo() (*NodeInfo, error) {
+	peMgr, err := yeShMgr.peerMgr("GetNodeInfo")
+	if err != nil {
+		return nil, err
+	}
+	local, dht := yeShMgr.GetLocalNode(), yeShMgr.GetLocalDhtNode()
+	nat := peMgr.GetNatStatus()
+	probe := peMgr.GetSelfProbeStatus()
+	info := NodeInfo{
+		Id:        config.P2pNodeId2String(local.ID),
+		Addr:      fmt.Sprintf("%s:%d:%d", local.IP, local.UDP, local.TCP),
+		DhtAddr:   fmt.Sprintf("%s:%d:%d", dht.IP, dht.UDP, dht.TCP),
+		NatReady:  nat.Ready,
+		Banned:    make(map[string]time.Time, 0),
+		Dialable:  probe.Dialable,
+		Relay:     probe.Relay,
+		Reachable: make(map[string]string, 0),
+	}
+	for _, r := range probe.Families {
+		info.Reachable[r.Family] = p2pReachable(r)
+	}
+	if nat.Ready {
+		info.PubAddr = fmt.Sprintf("%s:%d", nat.PubIp, nat.PubPort)
+	}
+	snids, _ := peMgr.GetLocalSubnetInfo()
+	for _, snid := range snids {
+		info.Subnets = append(info.Subnets, fmt.Sprintf("%x", snid))
+	}
+	for id, expired := range peMgr.GetBannedPeers() {
+		info.Banned[config.P2pNodeId2String(id)] = expired
+	}
+	for cidr, expired := range peMgr.GetBannedNets() {
+		info.Banned[cidr] = expired
+	}
+	return &info, nil
+}
+
+func p2pReachable(r peer.Reachability) string {
+	switch {
+	case r.TcpOk && r.UdpOk:
+		return "tcp+udp"
+	case r.TcpOk:
+		return "tcp"
+	case r.UdpOk:
+		return "udp"
+	}
+	return "none"
+}
+
+func (yeShMgr *YeShellManager) peerMgr(who string) (*peer.PeerManager, error) {
+	if yeShMgr.chainInst == nil {
+		return nil, yesChainDisabled
+	}
+	peMgr, ok := yeShMgr.chainInst.SchGetTaskObject(sch.PeerMgrName).(*peer.PeerManager)
+	if !ok || peMgr == nil {
+		return nil, errors.New(fmt.Sprintf("%s: peer manager not found", who))
+	}
+	return peMgr, nil
+}
+
+func (yeShMgr *YeShellManager) msgDecodeFailed(pid uint32, mid uint32) {
+	if peMgr, ok := yeShMgr.chainInst.SchGetTaskObject(sch.PeerMgrName).(*peer.PeerManager); ok {
+		peMgr.MsgDecodeFailed(pid, mid)
+	}
+}
+
 func (yeShMgr *YeShellManager) checkDupKey(k yesKey) bool {
 	yeShMgr.deDupLock.Lock()
 	defer yeShMgr.deDupLock.Unlock()
@@ -1761,11 +2679,17 @@ func (yeShMgr *YeShellManager) getChainDataFromPeer(rxPkg *peer.P2pPackageRx) sc
 	msg := peer.ExtMessage{}
 	if eno := upkg.GetExtMessage(&msg); eno != peer.PeMgrEnoNone {
 		yesLog.Debug("getChainDataFromPeer: GetExtMessage failed, eno: %d", eno)
+		yeShMgr.msgDecodeFailed(upkg.Pid, upkg.Mid)
 		return sch.SchEnoUserTask
 	}
 
 	if yeShMgr.cp != nil {
-		data := yeShMgr.cp.GetChainData(msg.Gcd.Name, msg.Gcd.Key)
+		var data []byte
+		if cpp, ok := yeShMgr.cp.(ChainPeerProvider); ok && rxPkg.PeerInfo != nil {
+			data = cpp.GetChainDataFor(config.P2pNodeId2String(rxPkg.PeerInfo.NodeId), msg.Gcd.Name, msg.Gcd.Key)
+		} else {
+			data = yeShMgr.cp.GetChainData(msg.Gcd.Name, msg.Gcd.Key)
+		}
 
 		yesLog.Debug("getChainDataFromPeer: cp: sdl: %s, kind: %s, key: %x, data: %x",
 			yeShMgr.chainSdlName, msg.Gcd.Name, msg.Gcd.Key, data)
@@ -1802,14 +2726,11 @@ func (yeShMgr *YeShellManager) putChainDataFromPeer(rxPkg *peer.P2pPackageRx) sc
 	msg := peer.ExtMessage{}
 	if eno := upkg.GetExtMessage(&msg); eno != peer.PeMgrEnoNone {
 		yesLog.Debug("putChainDataFromPeer: GetExtMessage failed, eno: %d", eno)
+		yeShMgr.msgDecodeFailed(upkg.Pid, upkg.Mid)
 		return sch.SchEnoUserTask
 	}
 
-	kex := getChainInfoKeyEx{
-		name: msg.Pcd.Name,
-		keyLen: len(msg.Pcd.Key),
-	}
-	copy(kex.key[0:], msg.Pcd.Key)
+	kex := newGetChainInfoKeyEx(msg.Pcd.Name, msg.Pcd.Key)
 
 	vex, ok := yeShMgr.gciMap[kex]
 	if !ok {
@@ -0,0 +1,193 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package p2p
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	config "github.com/yeeco/gyee/p2p/config"
+	"github.com/yeeco/gyee/p2p/peer"
+	sch "github.com/yeeco/gyee/p2p/scheduler"
+)
+
+const dhtTestStacks = 3
+
+//
+// Bring up dht stacks in one process over the in-memory network, the first one
+// is the bootstrap node of the others. the chain overlay is disabled.
+//
+func dhtTestStart(t *testing.T) []*YeShellManager {
+	mn := peer.NewMemNetwork()
+	tag := time.Now().UnixNano()
+	mgrs := make([]*YeShellManager, 0, dhtTestStacks)
+	for idx := 0; idx < dhtTestStacks; idx++ {
+		ip := fmt.Sprintf("10.0.0.%d", idx+1)
+		yesCfg := DefaultYeShellConfig
+		yesCfg.Name = fmt.Sprintf("dht_test_%d_%d", tag, idx)
+		yesCfg.Validator = true
+		yesCfg.BootstrapNode = idx == 0
+		yesCfg.BootstrapNodes = []string{}
+		yesCfg.DhtBootstrapNodes = []string{}
+		yesCfg.LocalNodeIp = ip
+		yesCfg.LocalDhtIp = ip
+		yesCfg.NodeDataDir = t.TempDir()
+		yesCfg.NatType = config.NATT_NONE
+		yesCfg.BootstrapTime = time.Millisecond * 500
+		yesCfg.DisableChain = true
+		yesCfg.DhtTransport = mn.Transport(net.ParseIP(ip))
+		yesCfg.localSnid = make([]config.SubNetworkID, 0)
+		yesCfg.localNode = make(map[config.SubNetworkID]config.Node, 0)
+		yesCfg.dhtBootstrapNodes = make([]*config.Node, 0)
+		if idx > 0 {
+			bsn := mgrs[0].GetLocalDhtNode()
+			yesCfg.DhtBootstrapNodes = append(yesCfg.DhtBootstrapNodes,
+				fmt.Sprintf("%X@%s:%d:%d", bsn.ID, bsn.IP.String(), bsn.UDP, bsn.TCP))
+		}
+		yeShMgr := NewYeShellManager(&yesCfg)
+		if yeShMgr == nil {
+			t.Fatalf("dhtTestStart: NewYeShellManager failed, idx: %d", idx)
+		}
+		if err := yeShMgr.Start(); err != nil {
+			t.Fatalf("dhtTestStart: Start failed, idx: %d, error: %s", idx, err.Error())
+		}
+		mgrs = append(mgrs, yeShMgr)
+	}
+	return mgrs
+}
+
+func dhtTestStop(mgrs []*YeShellManager) {
+	for idx := len(mgrs) - 1; idx >= 0; idx-- {
+		mgrs[idx].Stop()
+	}
+}
+
+func dhtTestKey(what string) []byte {
+	key := sha256.Sum256([]byte(what))
+	return key[0:]
+}
+
+//
+// Retry an operation until it succeeds or the time is out, since the routes of
+// the stacks are filled asynchronously after they started.
+//
+func dhtTestRetry(to time.Duration, op func() error) error {
+	expired := time.Now().Add(to)
+	for {
+		err := op()
+		if err == nil || time.Now().After(expired) {
+			return err
+		}
+		time.Sleep(time.Millisecond * 200)
+	}
+}
+
+func dhtTestWait(t *testing.T, done chan interface{}, what string) interface{} {
+	select {
+	case rsp := <-done:
+		return rsp
+	case <-time.After(time.Second * 16):
+		t.Fatalf("%s: timeout", what)
+	}
+	return nil
+}
+
+func TestDhtPutGetValue(t *testing.T) {
+	mgrs := dhtTestStart(t)
+	defer dhtTestStop(mgrs)
+
+	key := dhtTestKey("TestDhtPutGetValue")
+	val := []byte("value put by the second stack")
+	if err := dhtTestRetry(time.Second*16, func() error {
+		return mgrs[1].DhtSetValue(key, val)
+	}); err != nil {
+		t.Fatalf("DhtSetValue failed, error: %s", err.Error())
+	}
+
+	var got []byte
+	if err := dhtTestRetry(time.Second*16, func() error {
+		var err error
+		got, err = mgrs[2].DhtGetValue(key)
+		return err
+	}); err != nil {
+		t.Fatalf("DhtGetValue failed, error: %s", err.Error())
+	}
+	if !bytes.Equal(got, val) {
+		t.Errorf("value mismatched, got: %x, want: %x", got, val)
+	}
+}
+
+func TestDhtPutGetProvider(t *testing.T) {
+	mgrs := dhtTestStart(t)
+	defer dhtTestStop(mgrs)
+
+	key := dhtTestKey("TestDhtPutGetProvider")
+	prd := mgrs[1].GetLocalDhtNode()
+
+	var putRsp *sch.MsgDhtPrdMgrAddProviderRsp
+	if err := dhtTestRetry(time.Second*16, func() error {
+		done := make(chan interface{}, 1)
+		if err := mgrs[1].DhtSetProvider(key, prd, done); err != nil {
+			return err
+		}
+		putRsp = dhtTestWait(t, done, "DhtSetProvider").(*sch.MsgDhtPrdMgrAddProviderRsp)
+		if putRsp.Eno != 0 {
+			return fmt.Errorf("eno: %d", putRsp.Eno)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("DhtSetProvider failed, error: %s", err.Error())
+	}
+	if !bytes.Equal(putRsp.Key, key) {
+		t.Errorf("key of put provider response mismatched: %x", putRsp.Key)
+	}
+
+	var getRsp *sch.MsgDhtMgrGetProviderRsp
+	if err := dhtTestRetry(time.Second*16, func() error {
+		done := make(chan interface{}, 1)
+		if err := mgrs[2].DhtGetProvider(key, done); err != nil {
+			return err
+		}
+		getRsp = dhtTestWait(t, done, "DhtGetProvider").(*sch.MsgDhtMgrGetProviderRsp)
+		if getRsp.Eno != 0 || len(getRsp.Prds) == 0 {
+			return fmt.Errorf("eno: %d, providers: %d", getRsp.Eno, len(getRsp.Prds))
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("DhtGetProvider failed, error: %s", err.Error())
+	}
+	if !bytes.Equal(getRsp.Key, key) {
+		t.Errorf("key of get provider response mismatched: %x", getRsp.Key)
+	}
+	found := false
+	for _, p := range getRsp.Prds {
+		if p != nil && p.ID == prd.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("provider not found, providers: %d", len(getRsp.Prds))
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package persistent
+
+/*
+   静态加密：磁盘可能被盗的部署中，数据库的value用AES-GCM加密后再写入，key不加密
+   （查找、前缀遍历都依赖key的顺序）。每个value用随机nonce，nonce放在密文前面；
+   value对应的key作为附加数据参与认证，密文被挪到别的key下也会解密失败。
+   加密密钥由节点的秘密（比如keystore中的coinbase私钥）经HKDF派生，不直接使用原始秘密。
+   已有的明文数据打开加密后读不出来，需要清掉重建（dht存储和节点数据库都可以重建）。
+*/
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// size of keys of value encryption, AES-256
+const StorageKeySize = 32
+
+var (
+	ErrStorageKeySize = errors.New("persistent: invalid storage encryption key size")
+	ErrDecrypt        = errors.New("persistent: failed to decrypt value")
+)
+
+// DeriveStorageKey derives the key of value encryption from a node secret
+func DeriveStorageKey(secret []byte) ([]byte, error) {
+	key := make([]byte, StorageKeySize)
+	r := hkdf.New(sha256.New, secret, nil, []byte("gyee persistent value encryption"))
+	if _, err := io.ReadFull(r, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// ValueCipher encrypts values of a db by AES-GCM, with keys of them
+// authenticated as additional data
+type ValueCipher struct {
+	aead cipher.AEAD
+}
+
+func NewValueCipher(key []byte) (*ValueCipher, error) {
+	if len(key) != StorageKeySize {
+		return nil, ErrStorageKeySize
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &ValueCipher{aead: aead}, nil
+}
+
+// Seal value stored under key, as nonce followed by ciphertext
+func (vc *ValueCipher) Seal(key, value []byte) []byte {
+	nonceSize := vc.aead.NonceSize()
+	out := make([]byte, nonceSize, nonceSize+len(value)+vc.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, out); err != nil {
+		panic(err)
+	}
+	return vc.aead.Seal(out, out, value, key)
+}
+
+// Open value sealed under key
+func (vc *ValueCipher) Open(key, data []byte) ([]byte, error) {
+	nonceSize := vc.aead.NonceSize()
+	if len(data) < nonceSize+vc.aead.Overhead() {
+		return nil, ErrDecrypt
+	}
+	value, err := vc.aead.Open(nil, data[:nonceSize], data[nonceSize:], key)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return value, nil
+}
+
+// EncryptedStorage encrypts values written to the underlying storage
+type EncryptedStorage struct {
+	storage Storage
+	cipher  *ValueCipher
+}
+
+func NewEncryptedStorage(storage Storage, key []byte) (*EncryptedStorage, error) {
+	vc, err := NewValueCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedStorage{
+		storage: storage,
+		cipher:  vc,
+	}, nil
+}
+
+func (es *EncryptedStorage) Has(key []byte) (bool, error) {
+	return es.storage.Has(key)
+}
+
+func (es *EncryptedStorage) Get(key []byte) ([]byte, error) {
+	data, err := es.storage.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	return es.cipher.Open(key, data)
+}
+
+func (es *EncryptedStorage) Put(key []byte, value []byte) error {
+	return es.storage.Put(key, es.cipher.Seal(key, value))
+}
+
+func (es *EncryptedStorage) Del(key []byte) error {
+	return es.storage.Del(key)
+}
+
+func (es *EncryptedStorage) Close() error {
+	return es.storage.Close()
+}
+
+func (es *EncryptedStorage) NewBatch() Batch {
+	return &encryptedBatch{
+		batch:  es.storage.NewBatch(),
+		cipher: es.cipher,
+	}
+}
+
+type encryptedBatch struct {
+	batch  Batch
+	cipher *ValueCipher
+	size   int
+}
+
+func (b *encryptedBatch) Put(key, value []byte) error {
+	b.size += len(value)
+	return b.batch.Put(key, b.cipher.Seal(key, value))
+}
+
+func (b *encryptedBatch) Del(key []byte) error {
+	b.size += 1
+	return b.batch.Del(key)
+}
+
+// size of plain values, as batches of other storages
+func (b *encryptedBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *encryptedBatch) Write() error {
+	return b.batch.Write()
+}
+
+func (b *encryptedBatch) Reset() {
+	b.batch.Reset()
+	b.size = 0
+}
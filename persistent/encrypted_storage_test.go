@@ -0,0 +1,72 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package persistent
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptedStorage(t *testing.T) {
+	key, err := DeriveStorageKey([]byte("node secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := NewMemoryStorage()
+	storage, err := NewEncryptedStorage(mem, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := []byte("peer record")
+	storage.Put([]byte("k1"), value)
+	if raw, _ := mem.Get([]byte("k1")); bytes.Contains(raw, value) {
+		t.Errorf("value stored in plain")
+	}
+	if got, err := storage.Get([]byte("k1")); err != nil || !bytes.Equal(got, value) {
+		t.Errorf("Get() got %q %v", got, err)
+	}
+
+	batch := storage.NewBatch()
+	batch.Put([]byte("k2"), value)
+	batch.Write()
+	if got, err := storage.Get([]byte("k2")); err != nil || !bytes.Equal(got, value) {
+		t.Errorf("Get() of batch got %q %v", got, err)
+	}
+
+	// value moved under another key
+	raw, _ := mem.Get([]byte("k1"))
+	mem.Put([]byte("k3"), raw)
+	if _, err := storage.Get([]byte("k3")); err != ErrDecrypt {
+		t.Errorf("Get() of moved value got %v", err)
+	}
+
+	// another secret
+	key2, _ := DeriveStorageKey([]byte("another secret"))
+	storage2, _ := NewEncryptedStorage(mem, key2)
+	if _, err := storage2.Get([]byte("k1")); err != ErrDecrypt {
+		t.Errorf("Get() by another key got %v", err)
+	}
+
+	if _, err := NewEncryptedStorage(mem, key[:16]); err != ErrStorageKeySize {
+		t.Errorf("NewEncryptedStorage() of short key got %v", err)
+	}
+}
@@ -0,0 +1,345 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package persistent
+
+/*
+   冷存储（freezer）：只追加、不修改的数据（比如已经确定的区块头、区块体）不适合放在
+   leveldb里，和频繁更新的索引混在一起，每次压缩都要重写一遍。这里按表顺序存放：每个
+   表一个数据文件（name.dat）顺序追加，一个索引文件（name.idx）记录每一项在数据文件中
+   的结束位置（8字节大端），第n项从第n-1项的结束位置开始。所有表的项数相同，一起追加。
+   追加时先写数据再写索引，崩溃后打开时截掉不完整的部分，各表截到相同的项数。
+   只读打开时不修复，由Refresh重新读取写入进程追加的项数。
+*/
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const freezerIndexEntry = 8
+
+var (
+	ErrFreezerOutOfOrder = errors.New("persistent.freezer: item appended out of order")
+	ErrFreezerNotFound   = errors.New("persistent.freezer: item not frozen")
+	ErrFreezerTable      = errors.New("persistent.freezer: unknown table or values of tables mismatch")
+	ErrFreezerClosed     = errors.New("persistent.freezer: closed")
+)
+
+// Freezer is an append-only store of tables of items numbered from 0
+type Freezer struct {
+	path     string
+	readOnly bool
+
+	lock   sync.RWMutex
+	names  []string
+	tables map[string]*freezerTable
+	items  uint64
+	closed bool
+}
+
+type freezerTable struct {
+	data  *os.File
+	index *os.File
+	size  uint64 // bytes of data of items
+	items uint64 // items in index
+}
+
+// OpenFreezer opens or creates the freezer of tables under path, readOnly for
+// one written by another process
+func OpenFreezer(path string, tables []string, readOnly bool) (*Freezer, error) {
+	if !readOnly {
+		if err := os.MkdirAll(path, 0755); err != nil {
+			return nil, err
+		}
+	}
+	f := &Freezer{
+		path:     path,
+		readOnly: readOnly,
+		names:    tables,
+		tables:   make(map[string]*freezerTable, len(tables)),
+	}
+	for _, name := range tables {
+		t, err := openFreezerTable(path, name, readOnly)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		f.tables[name] = t
+	}
+	if err := f.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func openFreezerTable(path, name string, readOnly bool) (*freezerTable, error) {
+	flag := os.O_RDWR | os.O_CREATE
+	if readOnly {
+		flag = os.O_RDONLY
+	}
+	data, err := os.OpenFile(filepath.Join(path, name+".dat"), flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	index, err := os.OpenFile(filepath.Join(path, name+".idx"), flag, 0644)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	return &freezerTable{data: data, index: index}, nil
+}
+
+// load items of tables, truncating parts not completely written if writable
+func (f *Freezer) load() error {
+	f.items = ^uint64(0)
+	for _, name := range f.names {
+		t := f.tables[name]
+		if err := t.load(); err != nil {
+			return err
+		}
+		if t.items < f.items {
+			f.items = t.items
+		}
+	}
+	if len(f.names) == 0 {
+		f.items = 0
+	}
+	if f.readOnly {
+		return nil
+	}
+	for _, name := range f.names {
+		if err := f.tables[name].truncate(f.items); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// items with index entry and data completely written
+func (t *freezerTable) load() error {
+	istat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	dstat, err := t.data.Stat()
+	if err != nil {
+		return err
+	}
+	t.items = uint64(istat.Size()) / freezerIndexEntry
+	for t.items > 0 {
+		end, err := t.offset(t.items)
+		if err != nil {
+			return err
+		}
+		if end <= uint64(dstat.Size()) {
+			t.size = end
+			return nil
+		}
+		t.items--
+	}
+	t.size = 0
+	return nil
+}
+
+// end offset of data of the item-th item, from 1
+func (t *freezerTable) offset(item uint64) (uint64, error) {
+	if item == 0 {
+		return 0, nil
+	}
+	var buf [freezerIndexEntry]byte
+	if _, err := t.index.ReadAt(buf[:], int64((item-1)*freezerIndexEntry)); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf[:]), nil
+}
+
+func (t *freezerTable) truncate(items uint64) error {
+	size, err := t.offset(items)
+	if err != nil {
+		return err
+	}
+	if err := t.index.Truncate(int64(items * freezerIndexEntry)); err != nil {
+		return err
+	}
+	if err := t.data.Truncate(int64(size)); err != nil {
+		return err
+	}
+	t.items, t.size = items, size
+	return nil
+}
+
+func (t *freezerTable) append(value []byte) error {
+	if _, err := t.data.WriteAt(value, int64(t.size)); err != nil {
+		return err
+	}
+	var buf [freezerIndexEntry]byte
+	binary.BigEndian.PutUint64(buf[:], t.size+uint64(len(value)))
+	if _, err := t.index.WriteAt(buf[:], int64(t.items*freezerIndexEntry)); err != nil {
+		return err
+	}
+	t.size += uint64(len(value))
+	t.items++
+	return nil
+}
+
+func (t *freezerTable) retrieve(item uint64) ([]byte, error) {
+	start, err := t.offset(item)
+	if err != nil {
+		return nil, err
+	}
+	end, err := t.offset(item + 1)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, io.ErrUnexpectedEOF
+	}
+	value := make([]byte, end-start)
+	if _, err := t.data.ReadAt(value, int64(start)); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+func (t *freezerTable) close() {
+	t.data.Close()
+	t.index.Close()
+}
+
+// Number of items frozen, the next item appended
+func (f *Freezer) Items() uint64 {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+	return f.items
+}
+
+// Append values of item to tables, in order of tables opened
+func (f *Freezer) Append(item uint64, values [][]byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return ErrFreezerClosed
+	}
+	if f.readOnly {
+		return ErrReadOnly
+	}
+	if item != f.items {
+		return ErrFreezerOutOfOrder
+	}
+	if len(values) != len(f.names) {
+		return ErrFreezerTable
+	}
+	for i, name := range f.names {
+		if err := f.tables[name].append(values[i]); err != nil {
+			// drop values appended to other tables
+			f.load()
+			return err
+		}
+	}
+	f.items++
+	return nil
+}
+
+// Retrieve value of item in table
+func (f *Freezer) Retrieve(table string, item uint64) ([]byte, error) {
+	f.lock.RLock()
+	defer f.lock.RUnlock()
+
+	if f.closed {
+		return nil, ErrFreezerClosed
+	}
+	t, ok := f.tables[table]
+	if !ok {
+		return nil, ErrFreezerTable
+	}
+	if item >= f.items {
+		return nil, ErrFreezerNotFound
+	}
+	return t.retrieve(item)
+}
+
+// Sync flushes data and index of tables to disk
+func (f *Freezer) Sync() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed || f.readOnly {
+		return nil
+	}
+	for _, name := range f.names {
+		t := f.tables[name]
+		if err := t.data.Sync(); err != nil {
+			return err
+		}
+		if err := t.index.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Refresh reloads items appended by the process writing a freezer opened
+// read-only
+func (f *Freezer) Refresh() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return ErrFreezerClosed
+	}
+	return f.load()
+}
+
+func (f *Freezer) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	var err error
+	if !f.readOnly {
+		for _, t := range f.tables {
+			if e := t.data.Sync(); e != nil && err == nil {
+				err = e
+			}
+			if e := t.index.Sync(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	for _, t := range f.tables {
+		t.close()
+	}
+	f.closed = true
+	return err
+}
+
+// Path of the directory of freezer files
+func (f *Freezer) Path() string {
+	return f.path
+}
@@ -0,0 +1,110 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package persistent
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func freezerTestValues(i int) [][]byte {
+	return [][]byte{
+		[]byte(fmt.Sprintf("header-%d", i)),
+		bytes.Repeat([]byte{byte(i)}, i),
+	}
+}
+
+func TestFreezer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gyee-freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tables := []string{"headers", "bodies"}
+
+	f, err := OpenFreezer(dir, tables, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if err := f.Append(uint64(i), freezerTestValues(i)); err != nil {
+			t.Fatalf("Append(%d) %v", i, err)
+		}
+	}
+	if err := f.Append(11, freezerTestValues(11)); err != ErrFreezerOutOfOrder {
+		t.Errorf("Append() out of order got %v", err)
+	}
+	ro, err := OpenFreezer(dir, tables, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	f.Append(10, freezerTestValues(10))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// read-only sees items appended since opened after refresh
+	if ro.Items() != 10 {
+		t.Errorf("read-only Items() got %d", ro.Items())
+	}
+	if err := ro.Refresh(); err != nil || ro.Items() != 11 {
+		t.Errorf("read-only Items() after refresh got %d %v", ro.Items(), err)
+	}
+	if err := ro.Append(11, freezerTestValues(11)); err != ErrReadOnly {
+		t.Errorf("read-only Append() got %v", err)
+	}
+
+	// partial write of an item is dropped when reopened
+	data, _ := os.OpenFile(filepath.Join(dir, "headers.dat"), os.O_WRONLY|os.O_APPEND, 0644)
+	data.Write([]byte("partial"))
+	data.Close()
+	index, _ := os.OpenFile(filepath.Join(dir, "bodies.idx"), os.O_WRONLY|os.O_APPEND, 0644)
+	index.Write([]byte{0, 0})
+	index.Close()
+	f, err = OpenFreezer(dir, tables, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if f.Items() != 11 {
+		t.Fatalf("Items() after reopen got %d", f.Items())
+	}
+	for i := 0; i < 11; i++ {
+		want := freezerTestValues(i)
+		for j, table := range tables {
+			got, err := f.Retrieve(table, uint64(i))
+			if err != nil || !bytes.Equal(got, want[j]) {
+				t.Errorf("Retrieve(%s, %d) got %q %v", table, i, got, err)
+			}
+		}
+	}
+	if _, err := f.Retrieve("headers", 11); err != ErrFreezerNotFound {
+		t.Errorf("Retrieve() not frozen got %v", err)
+	}
+	if err := f.Append(11, freezerTestValues(11)); err != nil {
+		t.Errorf("Append() after reopen %v", err)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/filter"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
 type LevelStorage struct {
@@ -75,6 +76,18 @@ func (storage *LevelStorage) GetLevelDB() *leveldb.DB {
 	return storage.db
 }
 
+// Compact keys of prefix, the whole db if prefix is empty
+func (storage *LevelStorage) Compact(prefix []byte) error {
+	return storage.db.CompactRange(*util.BytesPrefix(prefix))
+}
+
+// Estimate disk usage in bytes of keys of prefix, the whole db if prefix is empty.
+// It's approximate, by offsets of blocks in tables, data not compacted yet to
+// tables is not included.
+func (storage *LevelStorage) DiskUsage(prefix []byte) (uint64, error) {
+	return levelDiskUsage(storage.db, prefix)
+}
+
 func (storage *LevelStorage) NewBatch() Batch {
 	return &ldbBatch{db: storage.db, b: new(leveldb.Batch)}
 }
@@ -109,3 +122,22 @@ func (b *ldbBatch) Reset() {
 	b.b.Reset()
 	b.size = 0
 }
+
+func levelDiskUsage(db *leveldb.DB, prefix []byte) (uint64, error) {
+	if len(prefix) == 0 {
+		var stats leveldb.DBStats
+		if err := db.Stats(&stats); err != nil {
+			return 0, err
+		}
+		var size int64
+		for _, n := range stats.LevelSizes {
+			size += n
+		}
+		return uint64(size), nil
+	}
+	sizes, err := db.SizeOf([]util.Range{*util.BytesPrefix(prefix)})
+	if err != nil {
+		return 0, err
+	}
+	return uint64(sizes.Sum()), nil
+}
@@ -0,0 +1,273 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package persistent
+
+/*
+   只读打开：另一个进程（比如只提供RPC查询的副本）在主节点运行时读同一个链数据库。
+   goleveldb只读打开也要对LOCK文件加共享锁，和主节点的独占锁冲突，所以这里用不加锁的
+   只读文件存储，按CURRENT找到manifest，恢复出打开时刻的数据库视图。之后主节点写入的
+   数据看不到，需要定期Refresh重新打开。主节点压缩时可能删掉正在读的表文件，读出错时
+   也是Refresh之后重试。
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/filter"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+)
+
+var ErrReadOnly = errors.New("persistent: storage is read-only")
+
+// ReadOnlyLevelStorage reads a level db written by another process, the view
+// of it is fixed when opened or refreshed
+type ReadOnlyLevelStorage struct {
+	path string
+
+	lock sync.RWMutex
+	db   *leveldb.DB
+}
+
+func NewReadOnlyLevelStorage(path string) (*ReadOnlyLevelStorage, error) {
+	db, err := openReadOnlyLevelDB(path)
+	if err != nil {
+		return nil, err
+	}
+	return &ReadOnlyLevelStorage{
+		path: path,
+		db:   db,
+	}, nil
+}
+
+func openReadOnlyLevelDB(path string) (*leveldb.DB, error) {
+	return leveldb.Open(&roFileStorage{path: path}, &opt.Options{
+		ReadOnly:               true,
+		ErrorIfMissing:         true,
+		OpenFilesCacheCapacity: 500,
+		BlockCacheCapacity:     8 * opt.MiB,
+		Filter:                 filter.NewBloomFilter(10),
+	})
+}
+
+// Refresh reopens db to see data written since opened, the view is kept if
+// it fails
+func (storage *ReadOnlyLevelStorage) Refresh() error {
+	db, err := openReadOnlyLevelDB(storage.path)
+	if err != nil {
+		return err
+	}
+	storage.lock.Lock()
+	old := storage.db
+	storage.db = db
+	storage.lock.Unlock()
+	return old.Close()
+}
+
+func (storage *ReadOnlyLevelStorage) Has(key []byte) (bool, error) {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+	return storage.db.Has(key, nil)
+}
+
+func (storage *ReadOnlyLevelStorage) Get(key []byte) ([]byte, error) {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+	val, err := storage.db.Get(key, nil)
+	if err == leveldb.ErrNotFound {
+		err = ErrKeyNotFound
+	}
+	return val, err
+}
+
+func (storage *ReadOnlyLevelStorage) Put(key []byte, value []byte) error {
+	return ErrReadOnly
+}
+
+func (storage *ReadOnlyLevelStorage) Del(key []byte) error {
+	return ErrReadOnly
+}
+
+func (storage *ReadOnlyLevelStorage) Compact(prefix []byte) error {
+	return ErrReadOnly
+}
+
+func (storage *ReadOnlyLevelStorage) DiskUsage(prefix []byte) (uint64, error) {
+	storage.lock.RLock()
+	defer storage.lock.RUnlock()
+	return levelDiskUsage(storage.db, prefix)
+}
+
+func (storage *ReadOnlyLevelStorage) Close() error {
+	storage.lock.Lock()
+	defer storage.lock.Unlock()
+	return storage.db.Close()
+}
+
+func (storage *ReadOnlyLevelStorage) NewBatch() Batch {
+	return &roBatch{}
+}
+
+// batch of read-only storage, fails on write
+type roBatch struct {
+	size int
+}
+
+func (b *roBatch) Put(key, value []byte) error {
+	b.size += len(value)
+	return nil
+}
+
+func (b *roBatch) Del(key []byte) error {
+	b.size += 1
+	return nil
+}
+
+func (b *roBatch) ValueSize() int {
+	return b.size
+}
+
+func (b *roBatch) Write() error {
+	return ErrReadOnly
+}
+
+func (b *roBatch) Reset() {
+	b.size = 0
+}
+
+// file storage of level db opened read-only, without the LOCK file locked
+type roFileStorage struct {
+	path string
+}
+
+type roLock struct{}
+
+func (roLock) Unlock() {}
+
+func (fs *roFileStorage) Lock() (storage.Locker, error) {
+	return roLock{}, nil
+}
+
+func (fs *roFileStorage) Log(str string) {}
+
+func (fs *roFileStorage) SetMeta(fd storage.FileDesc) error {
+	return ErrReadOnly
+}
+
+func (fs *roFileStorage) GetMeta() (storage.FileDesc, error) {
+	b, err := ioutil.ReadFile(filepath.Join(fs.path, "CURRENT"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = os.ErrNotExist
+		}
+		return storage.FileDesc{}, err
+	}
+	fd, ok := parseLevelFileName(strings.TrimSuffix(string(b), "\n"))
+	if !ok || fd.Type != storage.TypeManifest {
+		return storage.FileDesc{}, &storage.ErrCorrupted{
+			Err: fmt.Errorf("invalid CURRENT %q", b),
+		}
+	}
+	if _, err := os.Stat(filepath.Join(fs.path, fd.String())); err != nil {
+		return storage.FileDesc{}, os.ErrNotExist
+	}
+	return fd, nil
+}
+
+func (fs *roFileStorage) List(ft storage.FileType) ([]storage.FileDesc, error) {
+	dir, err := os.Open(fs.path)
+	if err != nil {
+		return nil, err
+	}
+	names, err := dir.Readdirnames(0)
+	dir.Close()
+	if err != nil {
+		return nil, err
+	}
+	var fds []storage.FileDesc
+	for _, name := range names {
+		if fd, ok := parseLevelFileName(name); ok && fd.Type&ft != 0 {
+			fds = append(fds, fd)
+		}
+	}
+	return fds, nil
+}
+
+func (fs *roFileStorage) Open(fd storage.FileDesc) (storage.Reader, error) {
+	f, err := os.Open(filepath.Join(fs.path, fd.String()))
+	if os.IsNotExist(err) && fd.Type == storage.TypeTable {
+		// older name of table files
+		f, err = os.Open(filepath.Join(fs.path, fmt.Sprintf("%06d.sst", fd.Num)))
+	}
+	if err != nil {
+		if os.IsNotExist(err) {
+			err = os.ErrNotExist
+		}
+		return nil, err
+	}
+	return f, nil
+}
+
+func (fs *roFileStorage) Create(fd storage.FileDesc) (storage.Writer, error) {
+	return nil, ErrReadOnly
+}
+
+func (fs *roFileStorage) Remove(fd storage.FileDesc) error {
+	return ErrReadOnly
+}
+
+func (fs *roFileStorage) Rename(oldfd, newfd storage.FileDesc) error {
+	return ErrReadOnly
+}
+
+func (fs *roFileStorage) Close() error {
+	return nil
+}
+
+// parse name of level db file, as the file storage of goleveldb names them
+func parseLevelFileName(name string) (fd storage.FileDesc, ok bool) {
+	var tail string
+	if _, err := fmt.Sscanf(name, "%d.%s", &fd.Num, &tail); err == nil {
+		switch tail {
+		case "log":
+			fd.Type = storage.TypeJournal
+		case "ldb", "sst":
+			fd.Type = storage.TypeTable
+		case "tmp":
+			fd.Type = storage.TypeTemp
+		default:
+			return fd, false
+		}
+		return fd, true
+	}
+	if n, _ := fmt.Sscanf(name, "MANIFEST-%d%s", &fd.Num, &tail); n == 1 {
+		fd.Type = storage.TypeManifest
+		return fd, true
+	}
+	return fd, false
+}
@@ -0,0 +1,70 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package persistent
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestReadOnlyLevelStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gyee-ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writer, err := NewLevelStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+	if err := writer.Put([]byte("k1"), []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewReadOnlyLevelStorage(dir)
+	if err != nil {
+		t.Fatalf("NewReadOnlyLevelStorage() with writer open %v", err)
+	}
+	defer reader.Close()
+	if v, err := reader.Get([]byte("k1")); err != nil || string(v) != "v1" {
+		t.Errorf("Get() got %s %v", v, err)
+	}
+	if err := reader.Put([]byte("k2"), []byte("v2")); err != ErrReadOnly {
+		t.Errorf("Put() got %v", err)
+	}
+	if err := reader.NewBatch().Write(); err != ErrReadOnly {
+		t.Errorf("Batch Write() got %v", err)
+	}
+
+	writer.Put([]byte("k2"), []byte("v2"))
+	if _, err := reader.Get([]byte("k2")); err != ErrKeyNotFound {
+		t.Errorf("Get() before refresh got %v", err)
+	}
+	if err := reader.Refresh(); err != nil {
+		t.Fatalf("Refresh() %v", err)
+	}
+	if v, err := reader.Get([]byte("k2")); err != nil || string(v) != "v2" {
+		t.Errorf("Get() after refresh got %s %v", v, err)
+	}
+}
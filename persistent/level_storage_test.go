@@ -22,6 +22,8 @@ package persistent
 
 import (
 	"bytes"
+	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
 	"testing"
@@ -57,6 +59,39 @@ func TestNewLevelStorage(t *testing.T) {
 	}
 }
 
+func TestLevelStorageCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gyee-compact")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	storage, err := NewLevelStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	for i := 0; i < 5000; i++ {
+		storage.Put([]byte(fmt.Sprintf("a-%d", i)), randBytes(1024))
+		storage.Put([]byte(fmt.Sprintf("b-%d", i)), randBytes(16))
+	}
+	if err := storage.Compact(nil); err != nil {
+		t.Fatalf("Compact() %v", err)
+	}
+	a, err := storage.DiskUsage([]byte("a-"))
+	if err != nil {
+		t.Fatalf("DiskUsage() %v", err)
+	}
+	b, _ := storage.DiskUsage([]byte("b-"))
+	all, _ := storage.DiskUsage(nil)
+	if a == 0 || a < b || all < a+b {
+		t.Errorf("DiskUsage() got a %d, b %d, all %d", a, b, all)
+	}
+	if err := storage.Compact([]byte("a-")); err != nil {
+		t.Errorf("Compact() of prefix %v", err)
+	}
+}
+
 const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
 func randBytes(n int) []byte {
@@ -53,6 +53,15 @@ type Storage interface {
 	NewBatch() Batch
 }
 
+// Compacter is storage able to compact and estimate disk usage of key ranges
+type Compacter interface {
+	// Compact keys of prefix, the whole storage if prefix is empty
+	Compact(prefix []byte) error
+
+	// Estimate disk usage in bytes of keys of prefix
+	DiskUsage(prefix []byte) (uint64, error)
+}
+
 type Batch interface {
 	Putter
 	Deleter
@@ -22,13 +22,17 @@ package rpc
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
-	"math/big"
+	"fmt"
 	"time"
 
 	"github.com/yeeco/gyee/accounts"
 	"github.com/yeeco/gyee/common/address"
+	"github.com/yeeco/gyee/common/hexutil"
 	"github.com/yeeco/gyee/core"
+	"github.com/yeeco/gyee/log"
+	"github.com/yeeco/gyee/p2p"
 	"github.com/yeeco/gyee/rpc/pb"
 )
 
@@ -86,9 +90,9 @@ func (s *AdminService) SendTransaction(ctx context.Context, req *rpcpb.SendTrans
 	if err != nil {
 		return nil, err
 	}
-	amount, ok := new(big.Int).SetString(req.Amount, 10)
-	if !ok {
-		return nil, errors.New("failed to parse amount")
+	amount, err := hexutil.ParseBig(req.Amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse amount: %v", err)
 	}
 	chainID := s.core.Chain().ChainID()
 	to := toAddr.CommonAddress()
@@ -101,6 +105,7 @@ func (s *AdminService) SendTransaction(ctx context.Context, req *rpcpb.SendTrans
 		return nil, err
 	}
 	tx := core.NewTransaction(uint32(chainID), req.Nonce, to, amount)
+	tx.SetWindow(req.NotBefore, req.ExpiresAt)
 	if err := tx.Sign(signer); err != nil {
 		return nil, err
 	}
@@ -111,3 +116,200 @@ func (s *AdminService) SendTransaction(ctx context.Context, req *rpcpb.SendTrans
 		Hash: tx.Hash().Hex(),
 	}, nil
 }
+
+func (s *AdminService) AddStatic(ctx context.Context, req *rpcpb.AddStaticRequest) (*rpcpb.AdminResultResponse, error) {
+	pa, err := s.peerAdmin()
+	if err != nil {
+		return nil, err
+	}
+	err = pa.AddStatic(req.Url)
+	return &rpcpb.AdminResultResponse{Result: err == nil}, err
+}
+
+func (s *AdminService) RemovePeer(ctx context.Context, req *rpcpb.RemovePeerRequest) (*rpcpb.AdminResultResponse, error) {
+	pa, err := s.peerAdmin()
+	if err != nil {
+		return nil, err
+	}
+	err = pa.RemovePeer(req.Id)
+	return &rpcpb.AdminResultResponse{Result: err == nil}, err
+}
+
+// BanPeer bans a peer, an ip or a CIDR for duration in seconds, or unbans it if duration is 0
+func (s *AdminService) BanPeer(ctx context.Context, req *rpcpb.BanPeerRequest) (*rpcpb.AdminResultResponse, error) {
+	pa, err := s.peerAdmin()
+	if err != nil {
+		return nil, err
+	}
+	if req.Duration == 0 {
+		err = pa.UnbanPeer(req.Id)
+	} else {
+		err = pa.BanPeer(req.Id, time.Duration(req.Duration)*time.Second)
+	}
+	return &rpcpb.AdminResultResponse{Result: err == nil}, err
+}
+
+// default number of echo requests sent by PingPeer
+const defaultPingCount = 4
+
+// PingPeer probes the link to a peer with echo requests, count of them padded
+// to size bytes, and returns round trip times in microseconds
+func (s *AdminService) PingPeer(ctx context.Context, req *rpcpb.PingPeerRequest) (*rpcpb.PingPeerResponse, error) {
+	pa, err := s.peerAdmin()
+	if err != nil {
+		return nil, err
+	}
+	count := req.Count
+	if count == 0 {
+		count = defaultPingCount
+	}
+	stats, err := pa.Ping(req.Id, int(req.Size), int(count))
+	if err != nil {
+		return nil, err
+	}
+	return &rpcpb.PingPeerResponse{
+		Sent:     uint32(stats.Sent),
+		Received: uint32(stats.Received),
+		Loss:     stats.Loss,
+		Min:      int64(stats.Min / time.Microsecond),
+		Avg:      int64(stats.Avg / time.Microsecond),
+		Max:      int64(stats.Max / time.Microsecond),
+	}, nil
+}
+
+func (s *AdminService) SetLogLevel(ctx context.Context, req *rpcpb.SetLogLevelRequest) (*rpcpb.AdminResultResponse, error) {
+	err := log.SetLevel(req.Level)
+	return &rpcpb.AdminResultResponse{Result: err == nil}, err
+}
+
+func (s *AdminService) NodeInfo(ctx context.Context, req *rpcpb.NonParamsRequest) (*rpcpb.P2PNodeInfoResponse, error) {
+	pa, err := s.peerAdmin()
+	if err != nil {
+		return nil, err
+	}
+	info, err := pa.GetNodeInfo()
+	if err != nil {
+		return nil, err
+	}
+	banned := make(map[string]int64, len(info.Banned))
+	for id, expired := range info.Banned {
+		banned[id] = expired.Unix()
+	}
+	return &rpcpb.P2PNodeInfoResponse{
+		Id:       info.Id,
+		Addr:     info.Addr,
+		DhtAddr:  info.DhtAddr,
+		NatReady: info.NatReady,
+		PubAddr:  info.PubAddr,
+		Subnets:  info.Subnets,
+		Banned:   banned,
+	}, nil
+}
+
+func (s *AdminService) StartStop(ctx context.Context, req *rpcpb.StartStopRequest) (*rpcpb.AdminResultResponse, error) {
+	sc, ok := s.server.Node().(SubsystemController)
+	if !ok {
+		return nil, errors.New("subsystems can't be started or stopped")
+	}
+	err := sc.StartStop(req.Subsystem, req.Start)
+	return &rpcpb.AdminResultResponse{Result: err == nil}, err
+}
+
+// max number of evidences returned at a time
+const maxEvidences = 100
+
+// GetEvidences returns evidences of malicious blocks and txs recorded, limit of
+// them from the start-th one
+func (s *AdminService) GetEvidences(ctx context.Context, req *rpcpb.GetEvidencesRequest) (*rpcpb.EvidencesResponse, error) {
+	limit := req.Limit
+	if limit == 0 || limit > maxEvidences {
+		limit = maxEvidences
+	}
+	chain := s.core.Chain()
+	evs, err := chain.GetEvidences(req.Start, limit)
+	if err != nil {
+		return nil, err
+	}
+	resp := &rpcpb.EvidencesResponse{
+		Count:     chain.EvidenceCount(),
+		Evidences: make([]*rpcpb.EvidenceResponse, 0, len(evs)),
+	}
+	for _, ev := range evs {
+		signers := make([]string, 0, len(ev.Signers))
+		for _, addr := range ev.Signers {
+			signers = append(signers, addr.Hex())
+		}
+		resp.Evidences = append(resp.Evidences, &rpcpb.EvidenceResponse{
+			Kind:    ev.Kind,
+			Number:  ev.Number,
+			Hash:    ev.Hash.Hex(),
+			Peer:    ev.Peer,
+			Reason:  ev.Reason,
+			Time:    int64(ev.Time),
+			Data:    hex.EncodeToString(ev.Data),
+			Other:   hex.EncodeToString(ev.Other),
+			Signers: signers,
+		})
+	}
+	return resp, nil
+}
+
+// GetStateDigest returns digest of accounts under an address prefix in state of
+// a block, with sub-digests by the next hex digit of address
+func (s *AdminService) GetStateDigest(ctx context.Context, req *rpcpb.GetStateDigestRequest) (*rpcpb.StateDigestResponse, error) {
+	sd, err := s.core.Chain().StateDigest(req.Number, req.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	resp := &rpcpb.StateDigestResponse{
+		Number:    sd.Number,
+		Hash:      sd.Hash.Hex(),
+		StateRoot: sd.StateRoot.Hex(),
+		Digest: &rpcpb.PrefixDigestResponse{
+			Prefix:   sd.Prefix,
+			Accounts: sd.Accounts,
+			Digest:   sd.Digest.Hex(),
+		},
+		Subs: make([]*rpcpb.PrefixDigestResponse, 0, len(sd.Subs)),
+	}
+	for _, sub := range sd.Subs {
+		resp.Subs = append(resp.Subs, &rpcpb.PrefixDigestResponse{
+			Prefix:   sub.Prefix,
+			Accounts: sub.Accounts,
+			Digest:   sub.Digest.Hex(),
+		})
+	}
+	return resp, nil
+}
+
+// CompactStorage starts compaction of a keyspace of chain db in background
+func (s *AdminService) CompactStorage(ctx context.Context, req *rpcpb.CompactStorageRequest) (*rpcpb.AdminResultResponse, error) {
+	err := s.core.CompactStorage(req.Keyspace)
+	return &rpcpb.AdminResultResponse{Result: err == nil}, err
+}
+
+// DiskUsage returns estimated disk usage of chain db by keyspace, and of dht
+func (s *AdminService) DiskUsage(ctx context.Context, req *rpcpb.NonParamsRequest) (*rpcpb.DiskUsageResponse, error) {
+	usages, err := s.core.DiskUsage()
+	if err != nil {
+		return nil, err
+	}
+	resp := &rpcpb.DiskUsageResponse{
+		Keyspaces: make([]*rpcpb.KeyspaceUsage, 0, len(usages)),
+	}
+	for _, usage := range usages {
+		resp.Keyspaces = append(resp.Keyspaces, &rpcpb.KeyspaceUsage{
+			Name:  usage.Name,
+			Bytes: usage.Bytes,
+		})
+	}
+	return resp, nil
+}
+
+func (s *AdminService) peerAdmin() (p2p.PeerAdmin, error) {
+	pa, ok := s.server.Node().P2pService().(p2p.PeerAdmin)
+	if !ok {
+		return nil, errors.New("peers can't be managed by p2p service")
+	}
+	return pa, nil
+}
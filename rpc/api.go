@@ -47,11 +47,21 @@ func newAPIService(server RPCServer) *APIService {
 
 func (s *APIService) NodeInfo(ctx context.Context, req *rpcpb.NonParamsRequest) (*rpcpb.NodeInfoResponse, error) {
 	nodeId := s.server.Node().NodeID()
-	return &rpcpb.NodeInfoResponse{Id: nodeId, Version: 1}, nil
+	info := s.core.VersionInfo()
+	return &rpcpb.NodeInfoResponse{
+		Id:            nodeId,
+		Version:       1,
+		ClientVersion: info.Client,
+		GitCommit:     info.GitCommit,
+		BuildDate:     info.BuildDate,
+	}, nil
 }
 
 func (s *APIService) GetBlockByHash(ctx context.Context, req *rpcpb.GetBlockByHashRequest) (*rpcpb.BlockResponse, error) {
-	bhash := common.HexToHash(req.Hash)
+	bhash, err := common.ParseHash(req.Hash)
+	if err != nil {
+		return nil, err
+	}
 	b := s.core.Chain().GetBlockByHash(bhash)
 	return blockResponse(b)
 }
@@ -67,7 +77,10 @@ func (s *APIService) GetLastBlock(ctx context.Context, req *rpcpb.GetLastBlockRe
 }
 
 func (s *APIService) GetTxByHash(ctx context.Context, req *rpcpb.GetTxByHashRequest) (*rpcpb.TransactionResponse, error) {
-	txHash := common.HexToHash(req.Hash)
+	txHash, err := common.ParseHash(req.Hash)
+	if err != nil {
+		return nil, err
+	}
 	tx := s.core.Chain().GetTxByHash(txHash)
 	return txResponse(tx)
 }
@@ -114,13 +127,17 @@ func txResponse(tx *core.Transaction) (*rpcpb.TransactionResponse, error) {
 	if tx == nil {
 		return nil, errors.New("tx not found")
 	}
-	return &rpcpb.TransactionResponse{
-		Hash:      tx.Hash().Hex(),
-		Nonce:     tx.Nonce(),
-		From:      tx.From().Hex(),
-		Recipient: tx.Recipient().Hex(),
-		Amount:    tx.Amount().String(),
-	}, nil
+	resp := &rpcpb.TransactionResponse{
+		Hash:   tx.Hash().Hex(),
+		Nonce:  tx.Nonce(),
+		From:   tx.From().Hex(),
+		Amount: tx.Amount().String(),
+	}
+	// data txs may have no recipient
+	if to := tx.Recipient(); to != nil {
+		resp.Recipient = to.Hex()
+	}
+	return resp, nil
 }
 
 func accountStateResponse(account state.Account) (*rpcpb.GetAccountStateResponse, error) {
@@ -0,0 +1,87 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package rpc
+
+//Admin authentication: with rpc.admin_auth enabled, the server writes a random
+//token to the node dir when started, readable by the owner only, and methods of
+//admin service are rejected unless the token is sent in metadata. clients on
+//the same host read the token file, see ReadAdminToken and WithAdminToken.
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"io/ioutil"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	adminTokenKey    = "admin-token"          // metadata key of token
+	adminTokenSize   = 32                     // bytes of random token
+	adminMethodPrefx = "/rpcpb.AdminService/" // full method prefix of admin service
+)
+
+// create a random token and write it to file, the token is returned even if
+// it's not written, so admin methods are rejected but not left open
+func newAdminToken(path string) (string, error) {
+	buf := make([]byte, adminTokenSize)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	return token, ioutil.WriteFile(path, []byte(token), 0600)
+}
+
+// ReadAdminToken reads token written by the server
+func ReadAdminToken(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// WithAdminToken returns context with token to call admin methods
+func WithAdminToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, adminTokenKey, token)
+}
+
+// interceptor checking token of admin methods
+func adminAuth(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !strings.HasPrefix(info.FullMethod, adminMethodPrefx) {
+			return handler(ctx, req)
+		}
+		md, _ := metadata.FromIncomingContext(ctx)
+		for _, t := range md.Get(adminTokenKey) {
+			if len(token) > 0 && subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				return handler(ctx, req)
+			}
+		}
+		return nil, status.Error(codes.Unauthenticated, "admin token required")
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package rpc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	limiterMaxKeys = 4096 // keys tracked before idle ones pruned
+)
+
+// token bucket
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter limits requests by key, Rate requests per second and Burst at
+// most, with a bucket for each key
+type rateLimiter struct {
+	rate    float64
+	burst   float64
+	lock    sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (rl *rateLimiter) allow(key string) bool {
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	now := time.Now()
+	b, ok := rl.buckets[key]
+	if !ok {
+		if len(rl.buckets) >= limiterMaxKeys {
+			rl.prune(now)
+		}
+		b = &bucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * rl.rate
+	if b.tokens > rl.burst {
+		b.tokens = rl.burst
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// remove buckets refilled, they are the same as new ones
+func (rl *rateLimiter) prune(now time.Time) {
+	for key, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rl.rate >= rl.burst {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// parse per-method limit, in format "method:rate:burst"
+func parseMethodLimit(spec string) (string, *rateLimiter, error) {
+	fields := strings.Split(spec, ":")
+	if len(fields) != 3 || len(fields[0]) == 0 {
+		return "", nil, fmt.Errorf("invalid method limit: %s", spec)
+	}
+	rate, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || rate <= 0 {
+		return "", nil, fmt.Errorf("invalid rate of method limit: %s", spec)
+	}
+	burst, err := strconv.Atoi(fields[2])
+	if err != nil || burst < 1 {
+		return "", nil, fmt.Errorf("invalid burst of method limit: %s", spec)
+	}
+	return fields[0], newRateLimiter(rate, burst), nil
+}
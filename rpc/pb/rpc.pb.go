@@ -34,7 +34,7 @@ func (m *NonParamsRequest) Reset()         { *m = NonParamsRequest{} }
 func (m *NonParamsRequest) String() string { return proto.CompactTextString(m) }
 func (*NonParamsRequest) ProtoMessage()    {}
 func (*NonParamsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{0}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{0}
 }
 func (m *NonParamsRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_NonParamsRequest.Unmarshal(m, b)
@@ -82,7 +82,7 @@ func (m *BlockResponse) Reset()         { *m = BlockResponse{} }
 func (m *BlockResponse) String() string { return proto.CompactTextString(m) }
 func (*BlockResponse) ProtoMessage()    {}
 func (*BlockResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{1}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{1}
 }
 func (m *BlockResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BlockResponse.Unmarshal(m, b)
@@ -177,7 +177,7 @@ func (m *GetBlockByHashRequest) Reset()         { *m = GetBlockByHashRequest{} }
 func (m *GetBlockByHashRequest) String() string { return proto.CompactTextString(m) }
 func (*GetBlockByHashRequest) ProtoMessage()    {}
 func (*GetBlockByHashRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{2}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{2}
 }
 func (m *GetBlockByHashRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetBlockByHashRequest.Unmarshal(m, b)
@@ -216,7 +216,7 @@ func (m *GetBlockByHeightRequest) Reset()         { *m = GetBlockByHeightRequest
 func (m *GetBlockByHeightRequest) String() string { return proto.CompactTextString(m) }
 func (*GetBlockByHeightRequest) ProtoMessage()    {}
 func (*GetBlockByHeightRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{3}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{3}
 }
 func (m *GetBlockByHeightRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetBlockByHeightRequest.Unmarshal(m, b)
@@ -257,7 +257,7 @@ func (m *GetLastBlockResponse) Reset()         { *m = GetLastBlockResponse{} }
 func (m *GetLastBlockResponse) String() string { return proto.CompactTextString(m) }
 func (*GetLastBlockResponse) ProtoMessage()    {}
 func (*GetLastBlockResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{4}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{4}
 }
 func (m *GetLastBlockResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetLastBlockResponse.Unmarshal(m, b)
@@ -301,7 +301,7 @@ func (m *GetLastBlockRequest) Reset()         { *m = GetLastBlockRequest{} }
 func (m *GetLastBlockRequest) String() string { return proto.CompactTextString(m) }
 func (*GetLastBlockRequest) ProtoMessage()    {}
 func (*GetLastBlockRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{5}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{5}
 }
 func (m *GetLastBlockRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetLastBlockRequest.Unmarshal(m, b)
@@ -341,7 +341,7 @@ func (m *TransactionResponse) Reset()         { *m = TransactionResponse{} }
 func (m *TransactionResponse) String() string { return proto.CompactTextString(m) }
 func (*TransactionResponse) ProtoMessage()    {}
 func (*TransactionResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{6}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{6}
 }
 func (m *TransactionResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_TransactionResponse.Unmarshal(m, b)
@@ -408,7 +408,7 @@ func (m *GetTxByHashRequest) Reset()         { *m = GetTxByHashRequest{} }
 func (m *GetTxByHashRequest) String() string { return proto.CompactTextString(m) }
 func (*GetTxByHashRequest) ProtoMessage()    {}
 func (*GetTxByHashRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{7}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{7}
 }
 func (m *GetTxByHashRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetTxByHashRequest.Unmarshal(m, b)
@@ -451,7 +451,7 @@ func (m *GetAccountStateResponse) Reset()         { *m = GetAccountStateResponse
 func (m *GetAccountStateResponse) String() string { return proto.CompactTextString(m) }
 func (*GetAccountStateResponse) ProtoMessage()    {}
 func (*GetAccountStateResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{8}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{8}
 }
 func (m *GetAccountStateResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetAccountStateResponse.Unmarshal(m, b)
@@ -504,7 +504,7 @@ func (m *GetAccountStateRequest) Reset()         { *m = GetAccountStateRequest{}
 func (m *GetAccountStateRequest) String() string { return proto.CompactTextString(m) }
 func (*GetAccountStateRequest) ProtoMessage()    {}
 func (*GetAccountStateRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{9}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{9}
 }
 func (m *GetAccountStateRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetAccountStateRequest.Unmarshal(m, b)
@@ -536,7 +536,13 @@ type NodeInfoResponse struct {
 	// the node ID.
 	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
 	// the node version.
-	Version              uint32   `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	Version uint32 `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"`
+	// the client version string, also announced to peers in handshake.
+	ClientVersion string `protobuf:"bytes,3,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"`
+	// the git commit built from.
+	GitCommit string `protobuf:"bytes,4,opt,name=git_commit,json=gitCommit,proto3" json:"git_commit,omitempty"`
+	// the build date.
+	BuildDate            string   `protobuf:"bytes,5,opt,name=build_date,json=buildDate,proto3" json:"build_date,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -546,7 +552,7 @@ func (m *NodeInfoResponse) Reset()         { *m = NodeInfoResponse{} }
 func (m *NodeInfoResponse) String() string { return proto.CompactTextString(m) }
 func (*NodeInfoResponse) ProtoMessage()    {}
 func (*NodeInfoResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{10}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{10}
 }
 func (m *NodeInfoResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_NodeInfoResponse.Unmarshal(m, b)
@@ -580,6 +586,27 @@ func (m *NodeInfoResponse) GetVersion() uint32 {
 	return 0
 }
 
+func (m *NodeInfoResponse) GetClientVersion() string {
+	if m != nil {
+		return m.ClientVersion
+	}
+	return ""
+}
+
+func (m *NodeInfoResponse) GetGitCommit() string {
+	if m != nil {
+		return m.GitCommit
+	}
+	return ""
+}
+
+func (m *NodeInfoResponse) GetBuildDate() string {
+	if m != nil {
+		return m.BuildDate
+	}
+	return ""
+}
+
 type AccountsResponse struct {
 	// account address list
 	Addresses            []string `protobuf:"bytes,1,rep,name=addresses,proto3" json:"addresses,omitempty"`
@@ -592,7 +619,7 @@ func (m *AccountsResponse) Reset()         { *m = AccountsResponse{} }
 func (m *AccountsResponse) String() string { return proto.CompactTextString(m) }
 func (*AccountsResponse) ProtoMessage()    {}
 func (*AccountsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{11}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{11}
 }
 func (m *AccountsResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_AccountsResponse.Unmarshal(m, b)
@@ -630,7 +657,7 @@ func (m *NewAccountRequest) Reset()         { *m = NewAccountRequest{} }
 func (m *NewAccountRequest) String() string { return proto.CompactTextString(m) }
 func (*NewAccountRequest) ProtoMessage()    {}
 func (*NewAccountRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{12}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{12}
 }
 func (m *NewAccountRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_NewAccountRequest.Unmarshal(m, b)
@@ -668,7 +695,7 @@ func (m *NewAccountResponse) Reset()         { *m = NewAccountResponse{} }
 func (m *NewAccountResponse) String() string { return proto.CompactTextString(m) }
 func (*NewAccountResponse) ProtoMessage()    {}
 func (*NewAccountResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{13}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{13}
 }
 func (m *NewAccountResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_NewAccountResponse.Unmarshal(m, b)
@@ -708,7 +735,7 @@ func (m *UnlockAccountRequest) Reset()         { *m = UnlockAccountRequest{} }
 func (m *UnlockAccountRequest) String() string { return proto.CompactTextString(m) }
 func (*UnlockAccountRequest) ProtoMessage()    {}
 func (*UnlockAccountRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{14}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{14}
 }
 func (m *UnlockAccountRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UnlockAccountRequest.Unmarshal(m, b)
@@ -760,7 +787,7 @@ func (m *UnlockAccountResponse) Reset()         { *m = UnlockAccountResponse{} }
 func (m *UnlockAccountResponse) String() string { return proto.CompactTextString(m) }
 func (*UnlockAccountResponse) ProtoMessage()    {}
 func (*UnlockAccountResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{15}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{15}
 }
 func (m *UnlockAccountResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_UnlockAccountResponse.Unmarshal(m, b)
@@ -798,7 +825,7 @@ func (m *LockAccountRequest) Reset()         { *m = LockAccountRequest{} }
 func (m *LockAccountRequest) String() string { return proto.CompactTextString(m) }
 func (*LockAccountRequest) ProtoMessage()    {}
 func (*LockAccountRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{16}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{16}
 }
 func (m *LockAccountRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_LockAccountRequest.Unmarshal(m, b)
@@ -836,7 +863,7 @@ func (m *LockAccountResponse) Reset()         { *m = LockAccountResponse{} }
 func (m *LockAccountResponse) String() string { return proto.CompactTextString(m) }
 func (*LockAccountResponse) ProtoMessage()    {}
 func (*LockAccountResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{17}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{17}
 }
 func (m *LockAccountResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_LockAccountResponse.Unmarshal(m, b)
@@ -870,6 +897,10 @@ type SendTransactionRequest struct {
 	To string `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
 	// tx amount decimal string
 	Amount string `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	// unix time in seconds tx valid from, 0 for no limit
+	NotBefore uint64 `protobuf:"varint,4,opt,name=not_before,json=notBefore,proto3" json:"not_before,omitempty"`
+	// unix time in seconds tx expires at, 0 for never
+	ExpiresAt uint64 `protobuf:"varint,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
 	// account nonce
 	Nonce                uint64   `protobuf:"varint,15,opt,name=nonce,proto3" json:"nonce,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -881,7 +912,7 @@ func (m *SendTransactionRequest) Reset()         { *m = SendTransactionRequest{}
 func (m *SendTransactionRequest) String() string { return proto.CompactTextString(m) }
 func (*SendTransactionRequest) ProtoMessage()    {}
 func (*SendTransactionRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{18}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{18}
 }
 func (m *SendTransactionRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SendTransactionRequest.Unmarshal(m, b)
@@ -922,6 +953,20 @@ func (m *SendTransactionRequest) GetAmount() string {
 	return ""
 }
 
+func (m *SendTransactionRequest) GetNotBefore() uint64 {
+	if m != nil {
+		return m.NotBefore
+	}
+	return 0
+}
+
+func (m *SendTransactionRequest) GetExpiresAt() uint64 {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return 0
+}
+
 func (m *SendTransactionRequest) GetNonce() uint64 {
 	if m != nil {
 		return m.Nonce
@@ -941,7 +986,7 @@ func (m *SendTransactionResponse) Reset()         { *m = SendTransactionResponse
 func (m *SendTransactionResponse) String() string { return proto.CompactTextString(m) }
 func (*SendTransactionResponse) ProtoMessage()    {}
 func (*SendTransactionResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_rpc_0da49ab51868ae98, []int{19}
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{19}
 }
 func (m *SendTransactionResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SendTransactionResponse.Unmarshal(m, b)
@@ -952,20 +997,1010 @@ func (m *SendTransactionResponse) XXX_Marshal(b []byte, deterministic bool) ([]b
 func (dst *SendTransactionResponse) XXX_Merge(src proto.Message) {
 	xxx_messageInfo_SendTransactionResponse.Merge(dst, src)
 }
-func (m *SendTransactionResponse) XXX_Size() int {
-	return xxx_messageInfo_SendTransactionResponse.Size(m)
+func (m *SendTransactionResponse) XXX_Size() int {
+	return xxx_messageInfo_SendTransactionResponse.Size(m)
+}
+func (m *SendTransactionResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SendTransactionResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SendTransactionResponse proto.InternalMessageInfo
+
+func (m *SendTransactionResponse) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+type AddStaticRequest struct {
+	// static node url, "id@ip:udp:tcp"
+	Url                  string   `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AddStaticRequest) Reset()         { *m = AddStaticRequest{} }
+func (m *AddStaticRequest) String() string { return proto.CompactTextString(m) }
+func (*AddStaticRequest) ProtoMessage()    {}
+func (*AddStaticRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{20}
+}
+func (m *AddStaticRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AddStaticRequest.Unmarshal(m, b)
+}
+func (m *AddStaticRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AddStaticRequest.Marshal(b, m, deterministic)
+}
+func (dst *AddStaticRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AddStaticRequest.Merge(dst, src)
+}
+func (m *AddStaticRequest) XXX_Size() int {
+	return xxx_messageInfo_AddStaticRequest.Size(m)
+}
+func (m *AddStaticRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_AddStaticRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AddStaticRequest proto.InternalMessageInfo
+
+func (m *AddStaticRequest) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+type RemovePeerRequest struct {
+	// node identity
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RemovePeerRequest) Reset()         { *m = RemovePeerRequest{} }
+func (m *RemovePeerRequest) String() string { return proto.CompactTextString(m) }
+func (*RemovePeerRequest) ProtoMessage()    {}
+func (*RemovePeerRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{21}
+}
+func (m *RemovePeerRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RemovePeerRequest.Unmarshal(m, b)
+}
+func (m *RemovePeerRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RemovePeerRequest.Marshal(b, m, deterministic)
+}
+func (dst *RemovePeerRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RemovePeerRequest.Merge(dst, src)
+}
+func (m *RemovePeerRequest) XXX_Size() int {
+	return xxx_messageInfo_RemovePeerRequest.Size(m)
+}
+func (m *RemovePeerRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RemovePeerRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RemovePeerRequest proto.InternalMessageInfo
+
+func (m *RemovePeerRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+type BanPeerRequest struct {
+	// node identity
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// ban duration in seconds, 0 to unban
+	Duration             uint64   `protobuf:"varint,2,opt,name=duration,proto3" json:"duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BanPeerRequest) Reset()         { *m = BanPeerRequest{} }
+func (m *BanPeerRequest) String() string { return proto.CompactTextString(m) }
+func (*BanPeerRequest) ProtoMessage()    {}
+func (*BanPeerRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{22}
+}
+func (m *BanPeerRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BanPeerRequest.Unmarshal(m, b)
+}
+func (m *BanPeerRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BanPeerRequest.Marshal(b, m, deterministic)
+}
+func (dst *BanPeerRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BanPeerRequest.Merge(dst, src)
+}
+func (m *BanPeerRequest) XXX_Size() int {
+	return xxx_messageInfo_BanPeerRequest.Size(m)
+}
+func (m *BanPeerRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_BanPeerRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BanPeerRequest proto.InternalMessageInfo
+
+func (m *BanPeerRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *BanPeerRequest) GetDuration() uint64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+type SetLogLevelRequest struct {
+	// debug, info, warn, error, fatal or panic
+	Level                string   `protobuf:"bytes,1,opt,name=level,proto3" json:"level,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SetLogLevelRequest) Reset()         { *m = SetLogLevelRequest{} }
+func (m *SetLogLevelRequest) String() string { return proto.CompactTextString(m) }
+func (*SetLogLevelRequest) ProtoMessage()    {}
+func (*SetLogLevelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{23}
+}
+func (m *SetLogLevelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SetLogLevelRequest.Unmarshal(m, b)
+}
+func (m *SetLogLevelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SetLogLevelRequest.Marshal(b, m, deterministic)
+}
+func (dst *SetLogLevelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SetLogLevelRequest.Merge(dst, src)
+}
+func (m *SetLogLevelRequest) XXX_Size() int {
+	return xxx_messageInfo_SetLogLevelRequest.Size(m)
+}
+func (m *SetLogLevelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SetLogLevelRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SetLogLevelRequest proto.InternalMessageInfo
+
+func (m *SetLogLevelRequest) GetLevel() string {
+	if m != nil {
+		return m.Level
+	}
+	return ""
+}
+
+type StartStopRequest struct {
+	// subsystem name, "ws" for websocket listeners
+	Subsystem string `protobuf:"bytes,1,opt,name=subsystem,proto3" json:"subsystem,omitempty"`
+	// start it or stop it
+	Start                bool     `protobuf:"varint,2,opt,name=start,proto3" json:"start,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StartStopRequest) Reset()         { *m = StartStopRequest{} }
+func (m *StartStopRequest) String() string { return proto.CompactTextString(m) }
+func (*StartStopRequest) ProtoMessage()    {}
+func (*StartStopRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{24}
+}
+func (m *StartStopRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StartStopRequest.Unmarshal(m, b)
+}
+func (m *StartStopRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StartStopRequest.Marshal(b, m, deterministic)
+}
+func (dst *StartStopRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StartStopRequest.Merge(dst, src)
+}
+func (m *StartStopRequest) XXX_Size() int {
+	return xxx_messageInfo_StartStopRequest.Size(m)
+}
+func (m *StartStopRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StartStopRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StartStopRequest proto.InternalMessageInfo
+
+func (m *StartStopRequest) GetSubsystem() string {
+	if m != nil {
+		return m.Subsystem
+	}
+	return ""
+}
+
+func (m *StartStopRequest) GetStart() bool {
+	if m != nil {
+		return m.Start
+	}
+	return false
+}
+
+type AdminResultResponse struct {
+	Result               bool     `protobuf:"varint,1,opt,name=result,proto3" json:"result,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AdminResultResponse) Reset()         { *m = AdminResultResponse{} }
+func (m *AdminResultResponse) String() string { return proto.CompactTextString(m) }
+func (*AdminResultResponse) ProtoMessage()    {}
+func (*AdminResultResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{25}
+}
+func (m *AdminResultResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AdminResultResponse.Unmarshal(m, b)
+}
+func (m *AdminResultResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AdminResultResponse.Marshal(b, m, deterministic)
+}
+func (dst *AdminResultResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AdminResultResponse.Merge(dst, src)
+}
+func (m *AdminResultResponse) XXX_Size() int {
+	return xxx_messageInfo_AdminResultResponse.Size(m)
+}
+func (m *AdminResultResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_AdminResultResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AdminResultResponse proto.InternalMessageInfo
+
+func (m *AdminResultResponse) GetResult() bool {
+	if m != nil {
+		return m.Result
+	}
+	return false
+}
+
+type P2PNodeInfoResponse struct {
+	// node identity in canonical textual format
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// chain address, "ip:udp:tcp"
+	Addr string `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	// dht address, "ip:udp:tcp"
+	DhtAddr string `protobuf:"bytes,3,opt,name=dht_addr,json=dhtAddr,proto3" json:"dht_addr,omitempty"`
+	// public address available after nat
+	NatReady bool `protobuf:"varint,4,opt,name=nat_ready,json=natReady,proto3" json:"nat_ready,omitempty"`
+	// public tcp address, "ip:port"
+	PubAddr string `protobuf:"bytes,5,opt,name=pub_addr,json=pubAddr,proto3" json:"pub_addr,omitempty"`
+	// sub network identities in hex
+	Subnets []string `protobuf:"bytes,6,rep,name=subnets,proto3" json:"subnets,omitempty"`
+	// banned peers, unix time bans expire by node identity
+	Banned               map[string]int64 `protobuf:"bytes,7,rep,name=banned,proto3" json:"banned,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *P2PNodeInfoResponse) Reset()         { *m = P2PNodeInfoResponse{} }
+func (m *P2PNodeInfoResponse) String() string { return proto.CompactTextString(m) }
+func (*P2PNodeInfoResponse) ProtoMessage()    {}
+func (*P2PNodeInfoResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{26}
+}
+func (m *P2PNodeInfoResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_P2PNodeInfoResponse.Unmarshal(m, b)
+}
+func (m *P2PNodeInfoResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_P2PNodeInfoResponse.Marshal(b, m, deterministic)
+}
+func (dst *P2PNodeInfoResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_P2PNodeInfoResponse.Merge(dst, src)
+}
+func (m *P2PNodeInfoResponse) XXX_Size() int {
+	return xxx_messageInfo_P2PNodeInfoResponse.Size(m)
+}
+func (m *P2PNodeInfoResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_P2PNodeInfoResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_P2PNodeInfoResponse proto.InternalMessageInfo
+
+func (m *P2PNodeInfoResponse) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *P2PNodeInfoResponse) GetAddr() string {
+	if m != nil {
+		return m.Addr
+	}
+	return ""
+}
+
+func (m *P2PNodeInfoResponse) GetDhtAddr() string {
+	if m != nil {
+		return m.DhtAddr
+	}
+	return ""
+}
+
+func (m *P2PNodeInfoResponse) GetNatReady() bool {
+	if m != nil {
+		return m.NatReady
+	}
+	return false
+}
+
+func (m *P2PNodeInfoResponse) GetPubAddr() string {
+	if m != nil {
+		return m.PubAddr
+	}
+	return ""
+}
+
+func (m *P2PNodeInfoResponse) GetSubnets() []string {
+	if m != nil {
+		return m.Subnets
+	}
+	return nil
+}
+
+func (m *P2PNodeInfoResponse) GetBanned() map[string]int64 {
+	if m != nil {
+		return m.Banned
+	}
+	return nil
+}
+
+type GetEvidencesRequest struct {
+	// sequence number of the first evidence
+	Start uint64 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	// number of evidences at most
+	Limit                uint64   `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetEvidencesRequest) Reset()         { *m = GetEvidencesRequest{} }
+func (m *GetEvidencesRequest) String() string { return proto.CompactTextString(m) }
+func (*GetEvidencesRequest) ProtoMessage()    {}
+func (*GetEvidencesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{27}
+}
+func (m *GetEvidencesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetEvidencesRequest.Unmarshal(m, b)
+}
+func (m *GetEvidencesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetEvidencesRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetEvidencesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetEvidencesRequest.Merge(dst, src)
+}
+func (m *GetEvidencesRequest) XXX_Size() int {
+	return xxx_messageInfo_GetEvidencesRequest.Size(m)
+}
+func (m *GetEvidencesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetEvidencesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetEvidencesRequest proto.InternalMessageInfo
+
+func (m *GetEvidencesRequest) GetStart() uint64 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *GetEvidencesRequest) GetLimit() uint64 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type EvidenceResponse struct {
+	// encoding, signature, block, state, doublesign or tx
+	Kind string `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	// block height, 0 if unknown
+	Number uint64 `protobuf:"varint,2,opt,name=number,proto3" json:"number,omitempty"`
+	// block or tx hash
+	Hash string `protobuf:"bytes,3,opt,name=hash,proto3" json:"hash,omitempty"`
+	// peer received from
+	Peer string `protobuf:"bytes,4,opt,name=peer,proto3" json:"peer,omitempty"`
+	// error of verification
+	Reason string `protobuf:"bytes,5,opt,name=reason,proto3" json:"reason,omitempty"`
+	// unix time recorded
+	Time int64 `protobuf:"varint,6,opt,name=time,proto3" json:"time,omitempty"`
+	// offending bytes in hex
+	Data string `protobuf:"bytes,7,opt,name=data,proto3" json:"data,omitempty"`
+	// conflicting block in hex, for double-sign
+	Other string `protobuf:"bytes,8,opt,name=other,proto3" json:"other,omitempty"`
+	// validators signed offending block
+	Signers              []string `protobuf:"bytes,9,rep,name=signers,proto3" json:"signers,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EvidenceResponse) Reset()         { *m = EvidenceResponse{} }
+func (m *EvidenceResponse) String() string { return proto.CompactTextString(m) }
+func (*EvidenceResponse) ProtoMessage()    {}
+func (*EvidenceResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{28}
+}
+func (m *EvidenceResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EvidenceResponse.Unmarshal(m, b)
+}
+func (m *EvidenceResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EvidenceResponse.Marshal(b, m, deterministic)
+}
+func (dst *EvidenceResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EvidenceResponse.Merge(dst, src)
+}
+func (m *EvidenceResponse) XXX_Size() int {
+	return xxx_messageInfo_EvidenceResponse.Size(m)
+}
+func (m *EvidenceResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_EvidenceResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EvidenceResponse proto.InternalMessageInfo
+
+func (m *EvidenceResponse) GetKind() string {
+	if m != nil {
+		return m.Kind
+	}
+	return ""
+}
+
+func (m *EvidenceResponse) GetNumber() uint64 {
+	if m != nil {
+		return m.Number
+	}
+	return 0
+}
+
+func (m *EvidenceResponse) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+func (m *EvidenceResponse) GetPeer() string {
+	if m != nil {
+		return m.Peer
+	}
+	return ""
+}
+
+func (m *EvidenceResponse) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+func (m *EvidenceResponse) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *EvidenceResponse) GetData() string {
+	if m != nil {
+		return m.Data
+	}
+	return ""
+}
+
+func (m *EvidenceResponse) GetOther() string {
+	if m != nil {
+		return m.Other
+	}
+	return ""
+}
+
+func (m *EvidenceResponse) GetSigners() []string {
+	if m != nil {
+		return m.Signers
+	}
+	return nil
+}
+
+type EvidencesResponse struct {
+	// number of evidences recorded
+	Count                uint64              `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Evidences            []*EvidenceResponse `protobuf:"bytes,2,rep,name=evidences,proto3" json:"evidences,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *EvidencesResponse) Reset()         { *m = EvidencesResponse{} }
+func (m *EvidencesResponse) String() string { return proto.CompactTextString(m) }
+func (*EvidencesResponse) ProtoMessage()    {}
+func (*EvidencesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{29}
+}
+func (m *EvidencesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EvidencesResponse.Unmarshal(m, b)
+}
+func (m *EvidencesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EvidencesResponse.Marshal(b, m, deterministic)
+}
+func (dst *EvidencesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EvidencesResponse.Merge(dst, src)
+}
+func (m *EvidencesResponse) XXX_Size() int {
+	return xxx_messageInfo_EvidencesResponse.Size(m)
+}
+func (m *EvidencesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_EvidencesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EvidencesResponse proto.InternalMessageInfo
+
+func (m *EvidencesResponse) GetCount() uint64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *EvidencesResponse) GetEvidences() []*EvidenceResponse {
+	if m != nil {
+		return m.Evidences
+	}
+	return nil
+}
+
+type GetStateDigestRequest struct {
+	// block height
+	Number uint64 `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	// account address prefix in hex, empty for all accounts
+	Prefix               string   `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetStateDigestRequest) Reset()         { *m = GetStateDigestRequest{} }
+func (m *GetStateDigestRequest) String() string { return proto.CompactTextString(m) }
+func (*GetStateDigestRequest) ProtoMessage()    {}
+func (*GetStateDigestRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{30}
+}
+func (m *GetStateDigestRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStateDigestRequest.Unmarshal(m, b)
+}
+func (m *GetStateDigestRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStateDigestRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetStateDigestRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStateDigestRequest.Merge(dst, src)
+}
+func (m *GetStateDigestRequest) XXX_Size() int {
+	return xxx_messageInfo_GetStateDigestRequest.Size(m)
+}
+func (m *GetStateDigestRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStateDigestRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStateDigestRequest proto.InternalMessageInfo
+
+func (m *GetStateDigestRequest) GetNumber() uint64 {
+	if m != nil {
+		return m.Number
+	}
+	return 0
+}
+
+func (m *GetStateDigestRequest) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+type PrefixDigestResponse struct {
+	// account address prefix in hex
+	Prefix string `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	// number of accounts under prefix
+	Accounts uint64 `protobuf:"varint,2,opt,name=accounts,proto3" json:"accounts,omitempty"`
+	// digest of accounts under prefix
+	Digest               string   `protobuf:"bytes,3,opt,name=digest,proto3" json:"digest,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PrefixDigestResponse) Reset()         { *m = PrefixDigestResponse{} }
+func (m *PrefixDigestResponse) String() string { return proto.CompactTextString(m) }
+func (*PrefixDigestResponse) ProtoMessage()    {}
+func (*PrefixDigestResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{31}
+}
+func (m *PrefixDigestResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PrefixDigestResponse.Unmarshal(m, b)
+}
+func (m *PrefixDigestResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PrefixDigestResponse.Marshal(b, m, deterministic)
+}
+func (dst *PrefixDigestResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PrefixDigestResponse.Merge(dst, src)
+}
+func (m *PrefixDigestResponse) XXX_Size() int {
+	return xxx_messageInfo_PrefixDigestResponse.Size(m)
+}
+func (m *PrefixDigestResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PrefixDigestResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PrefixDigestResponse proto.InternalMessageInfo
+
+func (m *PrefixDigestResponse) GetPrefix() string {
+	if m != nil {
+		return m.Prefix
+	}
+	return ""
+}
+
+func (m *PrefixDigestResponse) GetAccounts() uint64 {
+	if m != nil {
+		return m.Accounts
+	}
+	return 0
+}
+
+func (m *PrefixDigestResponse) GetDigest() string {
+	if m != nil {
+		return m.Digest
+	}
+	return ""
+}
+
+type StateDigestResponse struct {
+	Number uint64 `protobuf:"varint,1,opt,name=number,proto3" json:"number,omitempty"`
+	// block hash
+	Hash      string `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	StateRoot string `protobuf:"bytes,3,opt,name=state_root,json=stateRoot,proto3" json:"state_root,omitempty"`
+	// digest of accounts under prefix requested
+	Digest *PrefixDigestResponse `protobuf:"bytes,4,opt,name=digest,proto3" json:"digest,omitempty"`
+	// digests by the next hex digit of address
+	Subs                 []*PrefixDigestResponse `protobuf:"bytes,5,rep,name=subs,proto3" json:"subs,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
+	XXX_unrecognized     []byte                  `json:"-"`
+	XXX_sizecache        int32                   `json:"-"`
+}
+
+func (m *StateDigestResponse) Reset()         { *m = StateDigestResponse{} }
+func (m *StateDigestResponse) String() string { return proto.CompactTextString(m) }
+func (*StateDigestResponse) ProtoMessage()    {}
+func (*StateDigestResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{32}
+}
+func (m *StateDigestResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StateDigestResponse.Unmarshal(m, b)
+}
+func (m *StateDigestResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StateDigestResponse.Marshal(b, m, deterministic)
+}
+func (dst *StateDigestResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StateDigestResponse.Merge(dst, src)
+}
+func (m *StateDigestResponse) XXX_Size() int {
+	return xxx_messageInfo_StateDigestResponse.Size(m)
+}
+func (m *StateDigestResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StateDigestResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StateDigestResponse proto.InternalMessageInfo
+
+func (m *StateDigestResponse) GetNumber() uint64 {
+	if m != nil {
+		return m.Number
+	}
+	return 0
+}
+
+func (m *StateDigestResponse) GetHash() string {
+	if m != nil {
+		return m.Hash
+	}
+	return ""
+}
+
+func (m *StateDigestResponse) GetStateRoot() string {
+	if m != nil {
+		return m.StateRoot
+	}
+	return ""
+}
+
+func (m *StateDigestResponse) GetDigest() *PrefixDigestResponse {
+	if m != nil {
+		return m.Digest
+	}
+	return nil
+}
+
+func (m *StateDigestResponse) GetSubs() []*PrefixDigestResponse {
+	if m != nil {
+		return m.Subs
+	}
+	return nil
+}
+
+type CompactStorageRequest struct {
+	// headers, bodies, tx, state, index or evidence, empty for whole chain db
+	Keyspace             string   `protobuf:"bytes,1,opt,name=keyspace,proto3" json:"keyspace,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CompactStorageRequest) Reset()         { *m = CompactStorageRequest{} }
+func (m *CompactStorageRequest) String() string { return proto.CompactTextString(m) }
+func (*CompactStorageRequest) ProtoMessage()    {}
+func (*CompactStorageRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{33}
+}
+func (m *CompactStorageRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CompactStorageRequest.Unmarshal(m, b)
+}
+func (m *CompactStorageRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CompactStorageRequest.Marshal(b, m, deterministic)
+}
+func (dst *CompactStorageRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CompactStorageRequest.Merge(dst, src)
+}
+func (m *CompactStorageRequest) XXX_Size() int {
+	return xxx_messageInfo_CompactStorageRequest.Size(m)
+}
+func (m *CompactStorageRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CompactStorageRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CompactStorageRequest proto.InternalMessageInfo
+
+func (m *CompactStorageRequest) GetKeyspace() string {
+	if m != nil {
+		return m.Keyspace
+	}
+	return ""
+}
+
+type KeyspaceUsage struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// estimated bytes on disk
+	Bytes                uint64   `protobuf:"varint,2,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyspaceUsage) Reset()         { *m = KeyspaceUsage{} }
+func (m *KeyspaceUsage) String() string { return proto.CompactTextString(m) }
+func (*KeyspaceUsage) ProtoMessage()    {}
+func (*KeyspaceUsage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{34}
+}
+func (m *KeyspaceUsage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyspaceUsage.Unmarshal(m, b)
+}
+func (m *KeyspaceUsage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyspaceUsage.Marshal(b, m, deterministic)
+}
+func (dst *KeyspaceUsage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyspaceUsage.Merge(dst, src)
+}
+func (m *KeyspaceUsage) XXX_Size() int {
+	return xxx_messageInfo_KeyspaceUsage.Size(m)
+}
+func (m *KeyspaceUsage) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyspaceUsage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyspaceUsage proto.InternalMessageInfo
+
+func (m *KeyspaceUsage) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *KeyspaceUsage) GetBytes() uint64 {
+	if m != nil {
+		return m.Bytes
+	}
+	return 0
+}
+
+type DiskUsageResponse struct {
+	// keyspaces of chain db, "all" for whole of it, and "dht"
+	Keyspaces            []*KeyspaceUsage `protobuf:"bytes,1,rep,name=keyspaces,proto3" json:"keyspaces,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *DiskUsageResponse) Reset()         { *m = DiskUsageResponse{} }
+func (m *DiskUsageResponse) String() string { return proto.CompactTextString(m) }
+func (*DiskUsageResponse) ProtoMessage()    {}
+func (*DiskUsageResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{35}
+}
+func (m *DiskUsageResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DiskUsageResponse.Unmarshal(m, b)
+}
+func (m *DiskUsageResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DiskUsageResponse.Marshal(b, m, deterministic)
+}
+func (dst *DiskUsageResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DiskUsageResponse.Merge(dst, src)
+}
+func (m *DiskUsageResponse) XXX_Size() int {
+	return xxx_messageInfo_DiskUsageResponse.Size(m)
+}
+func (m *DiskUsageResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DiskUsageResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DiskUsageResponse proto.InternalMessageInfo
+
+func (m *DiskUsageResponse) GetKeyspaces() []*KeyspaceUsage {
+	if m != nil {
+		return m.Keyspaces
+	}
+	return nil
+}
+
+type PingPeerRequest struct {
+	// node identity
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// padding bytes of each echo request
+	Size uint32 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	// number of echo requests
+	Count                uint32   `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PingPeerRequest) Reset()         { *m = PingPeerRequest{} }
+func (m *PingPeerRequest) String() string { return proto.CompactTextString(m) }
+func (*PingPeerRequest) ProtoMessage()    {}
+func (*PingPeerRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{36}
+}
+func (m *PingPeerRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PingPeerRequest.Unmarshal(m, b)
+}
+func (m *PingPeerRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PingPeerRequest.Marshal(b, m, deterministic)
+}
+func (dst *PingPeerRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PingPeerRequest.Merge(dst, src)
+}
+func (m *PingPeerRequest) XXX_Size() int {
+	return xxx_messageInfo_PingPeerRequest.Size(m)
+}
+func (m *PingPeerRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PingPeerRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PingPeerRequest proto.InternalMessageInfo
+
+func (m *PingPeerRequest) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *PingPeerRequest) GetSize() uint32 {
+	if m != nil {
+		return m.Size
+	}
+	return 0
+}
+
+func (m *PingPeerRequest) GetCount() uint32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+type PingPeerResponse struct {
+	Sent     uint32 `protobuf:"varint,1,opt,name=sent,proto3" json:"sent,omitempty"`
+	Received uint32 `protobuf:"varint,2,opt,name=received,proto3" json:"received,omitempty"`
+	// ratio of echo requests without responses
+	Loss float64 `protobuf:"fixed64,3,opt,name=loss,proto3" json:"loss,omitempty"`
+	// round trip times in microseconds
+	Min                  int64    `protobuf:"varint,4,opt,name=min,proto3" json:"min,omitempty"`
+	Avg                  int64    `protobuf:"varint,5,opt,name=avg,proto3" json:"avg,omitempty"`
+	Max                  int64    `protobuf:"varint,6,opt,name=max,proto3" json:"max,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PingPeerResponse) Reset()         { *m = PingPeerResponse{} }
+func (m *PingPeerResponse) String() string { return proto.CompactTextString(m) }
+func (*PingPeerResponse) ProtoMessage()    {}
+func (*PingPeerResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_rpc_968c18cc9ae1b2ba, []int{37}
+}
+func (m *PingPeerResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PingPeerResponse.Unmarshal(m, b)
+}
+func (m *PingPeerResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PingPeerResponse.Marshal(b, m, deterministic)
+}
+func (dst *PingPeerResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PingPeerResponse.Merge(dst, src)
+}
+func (m *PingPeerResponse) XXX_Size() int {
+	return xxx_messageInfo_PingPeerResponse.Size(m)
+}
+func (m *PingPeerResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PingPeerResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PingPeerResponse proto.InternalMessageInfo
+
+func (m *PingPeerResponse) GetSent() uint32 {
+	if m != nil {
+		return m.Sent
+	}
+	return 0
+}
+
+func (m *PingPeerResponse) GetReceived() uint32 {
+	if m != nil {
+		return m.Received
+	}
+	return 0
+}
+
+func (m *PingPeerResponse) GetLoss() float64 {
+	if m != nil {
+		return m.Loss
+	}
+	return 0
 }
-func (m *SendTransactionResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_SendTransactionResponse.DiscardUnknown(m)
+
+func (m *PingPeerResponse) GetMin() int64 {
+	if m != nil {
+		return m.Min
+	}
+	return 0
 }
 
-var xxx_messageInfo_SendTransactionResponse proto.InternalMessageInfo
+func (m *PingPeerResponse) GetAvg() int64 {
+	if m != nil {
+		return m.Avg
+	}
+	return 0
+}
 
-func (m *SendTransactionResponse) GetHash() string {
+func (m *PingPeerResponse) GetMax() int64 {
 	if m != nil {
-		return m.Hash
+		return m.Max
 	}
-	return ""
+	return 0
 }
 
 func init() {
@@ -989,6 +2024,25 @@ func init() {
 	proto.RegisterType((*LockAccountResponse)(nil), "rpcpb.LockAccountResponse")
 	proto.RegisterType((*SendTransactionRequest)(nil), "rpcpb.SendTransactionRequest")
 	proto.RegisterType((*SendTransactionResponse)(nil), "rpcpb.SendTransactionResponse")
+	proto.RegisterType((*AddStaticRequest)(nil), "rpcpb.AddStaticRequest")
+	proto.RegisterType((*RemovePeerRequest)(nil), "rpcpb.RemovePeerRequest")
+	proto.RegisterType((*BanPeerRequest)(nil), "rpcpb.BanPeerRequest")
+	proto.RegisterType((*SetLogLevelRequest)(nil), "rpcpb.SetLogLevelRequest")
+	proto.RegisterType((*StartStopRequest)(nil), "rpcpb.StartStopRequest")
+	proto.RegisterType((*AdminResultResponse)(nil), "rpcpb.AdminResultResponse")
+	proto.RegisterType((*P2PNodeInfoResponse)(nil), "rpcpb.P2pNodeInfoResponse")
+	proto.RegisterMapType((map[string]int64)(nil), "rpcpb.P2pNodeInfoResponse.BannedEntry")
+	proto.RegisterType((*GetEvidencesRequest)(nil), "rpcpb.GetEvidencesRequest")
+	proto.RegisterType((*EvidenceResponse)(nil), "rpcpb.EvidenceResponse")
+	proto.RegisterType((*EvidencesResponse)(nil), "rpcpb.EvidencesResponse")
+	proto.RegisterType((*GetStateDigestRequest)(nil), "rpcpb.GetStateDigestRequest")
+	proto.RegisterType((*PrefixDigestResponse)(nil), "rpcpb.PrefixDigestResponse")
+	proto.RegisterType((*StateDigestResponse)(nil), "rpcpb.StateDigestResponse")
+	proto.RegisterType((*CompactStorageRequest)(nil), "rpcpb.CompactStorageRequest")
+	proto.RegisterType((*KeyspaceUsage)(nil), "rpcpb.KeyspaceUsage")
+	proto.RegisterType((*DiskUsageResponse)(nil), "rpcpb.DiskUsageResponse")
+	proto.RegisterType((*PingPeerRequest)(nil), "rpcpb.PingPeerRequest")
+	proto.RegisterType((*PingPeerResponse)(nil), "rpcpb.PingPeerResponse")
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1237,6 +2291,23 @@ type AdminServiceClient interface {
 	UnlockAccount(ctx context.Context, in *UnlockAccountRequest, opts ...grpc.CallOption) (*UnlockAccountResponse, error)
 	LockAccount(ctx context.Context, in *LockAccountRequest, opts ...grpc.CallOption) (*LockAccountResponse, error)
 	SendTransaction(ctx context.Context, in *SendTransactionRequest, opts ...grpc.CallOption) (*SendTransactionResponse, error)
+	// p2p runtime control, see p2p.PeerAdmin
+	AddStatic(ctx context.Context, in *AddStaticRequest, opts ...grpc.CallOption) (*AdminResultResponse, error)
+	RemovePeer(ctx context.Context, in *RemovePeerRequest, opts ...grpc.CallOption) (*AdminResultResponse, error)
+	BanPeer(ctx context.Context, in *BanPeerRequest, opts ...grpc.CallOption) (*AdminResultResponse, error)
+	SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*AdminResultResponse, error)
+	NodeInfo(ctx context.Context, in *NonParamsRequest, opts ...grpc.CallOption) (*P2PNodeInfoResponse, error)
+	StartStop(ctx context.Context, in *StartStopRequest, opts ...grpc.CallOption) (*AdminResultResponse, error)
+	// evidences of malicious blocks and txs received
+	GetEvidences(ctx context.Context, in *GetEvidencesRequest, opts ...grpc.CallOption) (*EvidencesResponse, error)
+	// digest of state at a block, to compare states of nodes
+	GetStateDigest(ctx context.Context, in *GetStateDigestRequest, opts ...grpc.CallOption) (*StateDigestResponse, error)
+	// compact chain db in background
+	CompactStorage(ctx context.Context, in *CompactStorageRequest, opts ...grpc.CallOption) (*AdminResultResponse, error)
+	// estimated disk usage by keyspace
+	DiskUsage(ctx context.Context, in *NonParamsRequest, opts ...grpc.CallOption) (*DiskUsageResponse, error)
+	// probe the link to a peer with echo requests
+	PingPeer(ctx context.Context, in *PingPeerRequest, opts ...grpc.CallOption) (*PingPeerResponse, error)
 }
 
 type adminServiceClient struct {
@@ -1292,6 +2363,105 @@ func (c *adminServiceClient) SendTransaction(ctx context.Context, in *SendTransa
 	return out, nil
 }
 
+func (c *adminServiceClient) AddStatic(ctx context.Context, in *AddStaticRequest, opts ...grpc.CallOption) (*AdminResultResponse, error) {
+	out := new(AdminResultResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/AddStatic", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) RemovePeer(ctx context.Context, in *RemovePeerRequest, opts ...grpc.CallOption) (*AdminResultResponse, error) {
+	out := new(AdminResultResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/RemovePeer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) BanPeer(ctx context.Context, in *BanPeerRequest, opts ...grpc.CallOption) (*AdminResultResponse, error) {
+	out := new(AdminResultResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/BanPeer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) SetLogLevel(ctx context.Context, in *SetLogLevelRequest, opts ...grpc.CallOption) (*AdminResultResponse, error) {
+	out := new(AdminResultResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/SetLogLevel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) NodeInfo(ctx context.Context, in *NonParamsRequest, opts ...grpc.CallOption) (*P2PNodeInfoResponse, error) {
+	out := new(P2PNodeInfoResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/NodeInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) StartStop(ctx context.Context, in *StartStopRequest, opts ...grpc.CallOption) (*AdminResultResponse, error) {
+	out := new(AdminResultResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/StartStop", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetEvidences(ctx context.Context, in *GetEvidencesRequest, opts ...grpc.CallOption) (*EvidencesResponse, error) {
+	out := new(EvidencesResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/GetEvidences", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) GetStateDigest(ctx context.Context, in *GetStateDigestRequest, opts ...grpc.CallOption) (*StateDigestResponse, error) {
+	out := new(StateDigestResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/GetStateDigest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) CompactStorage(ctx context.Context, in *CompactStorageRequest, opts ...grpc.CallOption) (*AdminResultResponse, error) {
+	out := new(AdminResultResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/CompactStorage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) DiskUsage(ctx context.Context, in *NonParamsRequest, opts ...grpc.CallOption) (*DiskUsageResponse, error) {
+	out := new(DiskUsageResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/DiskUsage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *adminServiceClient) PingPeer(ctx context.Context, in *PingPeerRequest, opts ...grpc.CallOption) (*PingPeerResponse, error) {
+	out := new(PingPeerResponse)
+	err := c.cc.Invoke(ctx, "/rpcpb.AdminService/PingPeer", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AdminServiceServer is the server API for AdminService service.
 type AdminServiceServer interface {
 	Accounts(context.Context, *NonParamsRequest) (*AccountsResponse, error)
@@ -1299,6 +2469,23 @@ type AdminServiceServer interface {
 	UnlockAccount(context.Context, *UnlockAccountRequest) (*UnlockAccountResponse, error)
 	LockAccount(context.Context, *LockAccountRequest) (*LockAccountResponse, error)
 	SendTransaction(context.Context, *SendTransactionRequest) (*SendTransactionResponse, error)
+	// p2p runtime control, see p2p.PeerAdmin
+	AddStatic(context.Context, *AddStaticRequest) (*AdminResultResponse, error)
+	RemovePeer(context.Context, *RemovePeerRequest) (*AdminResultResponse, error)
+	BanPeer(context.Context, *BanPeerRequest) (*AdminResultResponse, error)
+	SetLogLevel(context.Context, *SetLogLevelRequest) (*AdminResultResponse, error)
+	NodeInfo(context.Context, *NonParamsRequest) (*P2PNodeInfoResponse, error)
+	StartStop(context.Context, *StartStopRequest) (*AdminResultResponse, error)
+	// evidences of malicious blocks and txs received
+	GetEvidences(context.Context, *GetEvidencesRequest) (*EvidencesResponse, error)
+	// digest of state at a block, to compare states of nodes
+	GetStateDigest(context.Context, *GetStateDigestRequest) (*StateDigestResponse, error)
+	// compact chain db in background
+	CompactStorage(context.Context, *CompactStorageRequest) (*AdminResultResponse, error)
+	// estimated disk usage by keyspace
+	DiskUsage(context.Context, *NonParamsRequest) (*DiskUsageResponse, error)
+	// probe the link to a peer with echo requests
+	PingPeer(context.Context, *PingPeerRequest) (*PingPeerResponse, error)
 }
 
 func RegisterAdminServiceServer(s *grpc.Server, srv AdminServiceServer) {
@@ -1395,6 +2582,204 @@ func _AdminService_SendTransaction_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AdminService_AddStatic_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddStaticRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).AddStatic(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/AddStatic",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).AddStatic(ctx, req.(*AddStaticRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_RemovePeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemovePeerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).RemovePeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/RemovePeer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).RemovePeer(ctx, req.(*RemovePeerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_BanPeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BanPeerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).BanPeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/BanPeer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).BanPeer(ctx, req.(*BanPeerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_SetLogLevel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLogLevelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).SetLogLevel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/SetLogLevel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).SetLogLevel(ctx, req.(*SetLogLevelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_NodeInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NonParamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).NodeInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/NodeInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).NodeInfo(ctx, req.(*NonParamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_StartStop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartStopRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).StartStop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/StartStop",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).StartStop(ctx, req.(*StartStopRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetEvidences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetEvidencesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetEvidences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/GetEvidences",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetEvidences(ctx, req.(*GetEvidencesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_GetStateDigest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStateDigestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).GetStateDigest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/GetStateDigest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).GetStateDigest(ctx, req.(*GetStateDigestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_CompactStorage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompactStorageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).CompactStorage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/CompactStorage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).CompactStorage(ctx, req.(*CompactStorageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_DiskUsage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NonParamsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).DiskUsage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/DiskUsage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).DiskUsage(ctx, req.(*NonParamsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AdminService_PingPeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PingPeerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AdminServiceServer).PingPeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/rpcpb.AdminService/PingPeer",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AdminServiceServer).PingPeer(ctx, req.(*PingPeerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _AdminService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "rpcpb.AdminService",
 	HandlerType: (*AdminServiceServer)(nil),
@@ -1419,65 +2804,166 @@ var _AdminService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "SendTransaction",
 			Handler:    _AdminService_SendTransaction_Handler,
 		},
+		{
+			MethodName: "AddStatic",
+			Handler:    _AdminService_AddStatic_Handler,
+		},
+		{
+			MethodName: "RemovePeer",
+			Handler:    _AdminService_RemovePeer_Handler,
+		},
+		{
+			MethodName: "BanPeer",
+			Handler:    _AdminService_BanPeer_Handler,
+		},
+		{
+			MethodName: "SetLogLevel",
+			Handler:    _AdminService_SetLogLevel_Handler,
+		},
+		{
+			MethodName: "NodeInfo",
+			Handler:    _AdminService_NodeInfo_Handler,
+		},
+		{
+			MethodName: "StartStop",
+			Handler:    _AdminService_StartStop_Handler,
+		},
+		{
+			MethodName: "GetEvidences",
+			Handler:    _AdminService_GetEvidences_Handler,
+		},
+		{
+			MethodName: "GetStateDigest",
+			Handler:    _AdminService_GetStateDigest_Handler,
+		},
+		{
+			MethodName: "CompactStorage",
+			Handler:    _AdminService_CompactStorage_Handler,
+		},
+		{
+			MethodName: "DiskUsage",
+			Handler:    _AdminService_DiskUsage_Handler,
+		},
+		{
+			MethodName: "PingPeer",
+			Handler:    _AdminService_PingPeer_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "rpc.proto",
 }
 
-func init() { proto.RegisterFile("rpc.proto", fileDescriptor_rpc_0da49ab51868ae98) }
-
-var fileDescriptor_rpc_0da49ab51868ae98 = []byte{
-	// 826 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x56, 0xdd, 0x6e, 0xe3, 0x44,
-	0x14, 0x4e, 0xdc, 0xe6, 0xc7, 0xa7, 0x4d, 0x5a, 0xa6, 0x69, 0xea, 0x98, 0x6c, 0xa9, 0x06, 0x21,
-	0x45, 0xa0, 0x0d, 0x90, 0xbd, 0x45, 0x48, 0x5d, 0x24, 0x4a, 0x51, 0xb4, 0x42, 0xee, 0xc2, 0x6d,
-	0x35, 0xb1, 0x67, 0x89, 0x21, 0x99, 0x31, 0x33, 0x93, 0xa5, 0x3c, 0x02, 0xcf, 0xc0, 0x03, 0xf1,
-	0x54, 0x48, 0x68, 0xc6, 0xe3, 0x9f, 0x38, 0xce, 0x46, 0xbd, 0xf3, 0xf9, 0x3f, 0xf3, 0x9d, 0x73,
-	0x3e, 0x19, 0x5c, 0x91, 0x84, 0xd3, 0x44, 0x70, 0xc5, 0x51, 0x4b, 0x24, 0x61, 0xb2, 0xc0, 0x08,
-	0xce, 0xdf, 0x70, 0xf6, 0x13, 0x11, 0x64, 0x2d, 0x03, 0xfa, 0xc7, 0x86, 0x4a, 0x85, 0xff, 0x71,
-	0xa0, 0xf7, 0x7a, 0xc5, 0xc3, 0xdf, 0x03, 0x2a, 0x13, 0xce, 0x24, 0x45, 0x08, 0x8e, 0x97, 0x44,
-	0x2e, 0xbd, 0xe6, 0x4d, 0x73, 0xe2, 0x06, 0xe6, 0x1b, 0x7d, 0x02, 0x27, 0x09, 0x11, 0x94, 0xa9,
-	0x47, 0x63, 0x72, 0x8c, 0x09, 0x52, 0xd5, 0x0f, 0xda, 0x61, 0x08, 0xed, 0x25, 0x8d, 0x7f, 0x5d,
-	0x2a, 0xef, 0xe8, 0xa6, 0x39, 0x39, 0x0e, 0xac, 0x84, 0xc6, 0xe0, 0xaa, 0x78, 0x4d, 0xa5, 0x22,
-	0xeb, 0xc4, 0x3b, 0x36, 0xa6, 0x42, 0x81, 0x46, 0xd0, 0x0d, 0x97, 0x24, 0x66, 0x8f, 0x71, 0xe4,
-	0xb5, 0x6e, 0x9a, 0x93, 0x5e, 0xd0, 0x31, 0xf2, 0x7d, 0x84, 0x3e, 0x83, 0x7e, 0xa8, 0xdb, 0x61,
-	0x72, 0x23, 0x1f, 0x05, 0xe7, 0xca, 0x6b, 0x9b, 0xa2, 0xbd, 0x5c, 0x1b, 0x70, 0xae, 0xd0, 0x0b,
-	0x00, 0xa9, 0x88, 0xa2, 0xa9, 0x4b, 0xc7, 0xb8, 0xb8, 0x46, 0x63, 0xcc, 0x23, 0xe8, 0xaa, 0x27,
-	0x1b, 0xdf, 0x35, 0xc6, 0x8e, 0x7a, 0x4a, 0x23, 0x3f, 0x85, 0x9e, 0xa0, 0x21, 0x8d, 0x13, 0x65,
-	0xed, 0xae, 0xb1, 0x9f, 0x66, 0x4a, 0xed, 0x84, 0xbf, 0x80, 0xcb, 0x3b, 0xaa, 0x0c, 0x3e, 0xaf,
-	0xff, 0xd2, 0x0f, 0xb5, 0xb0, 0xd5, 0x81, 0x84, 0xbf, 0x86, 0xab, 0x92, 0xb3, 0x79, 0x7f, 0xe6,
-	0x5e, 0xc0, 0xd3, 0x2c, 0xc3, 0x83, 0x7f, 0x81, 0xc1, 0x1d, 0x55, 0x73, 0x22, 0xd5, 0xe1, 0x19,
-	0x7c, 0x0e, 0xad, 0x85, 0x76, 0x32, 0xe8, 0x9f, 0xcc, 0x06, 0x53, 0x33, 0xd4, 0xe9, 0x56, 0x60,
-	0x90, 0xba, 0xe0, 0x4b, 0xb8, 0xd8, 0xce, 0x9b, 0x0e, 0xfb, 0xef, 0x26, 0x5c, 0xbc, 0x15, 0x84,
-	0x49, 0x12, 0xaa, 0x98, 0xb3, 0x0f, 0x96, 0x1b, 0x40, 0x8b, 0x71, 0x16, 0x52, 0x53, 0xee, 0x38,
-	0x48, 0x05, 0xed, 0xf9, 0x4e, 0xf0, 0xb5, 0x99, 0xb2, 0x1b, 0x98, 0x6f, 0x3d, 0x63, 0x41, 0xc3,
-	0x38, 0x89, 0x29, 0x53, 0x66, 0xc6, 0x6e, 0x50, 0x28, 0xf4, 0xd3, 0xc9, 0x9a, 0x6f, 0x98, 0x32,
-	0x13, 0x76, 0x03, 0x2b, 0xe1, 0x09, 0xa0, 0x3b, 0xaa, 0xde, 0x3e, 0x1d, 0xc6, 0x35, 0x34, 0xb8,
-	0xde, 0x86, 0xa1, 0x8e, 0x7b, 0x30, 0xb3, 0xcd, 0x1a, 0xf7, 0xa0, 0x43, 0xa2, 0x48, 0x50, 0x29,
-	0x6d, 0x44, 0x26, 0xee, 0x69, 0xdf, 0x83, 0xce, 0x82, 0xac, 0x88, 0xd6, 0xa7, 0x2f, 0xc8, 0x44,
-	0x3c, 0x83, 0xe1, 0x4e, 0x91, 0xb4, 0xa5, 0xbd, 0x35, 0xf0, 0x37, 0xfa, 0x9e, 0x22, 0x7a, 0xcf,
-	0xde, 0xf1, 0xbc, 0xa3, 0x3e, 0x38, 0x71, 0x64, 0x1d, 0x9d, 0x38, 0xd2, 0xd1, 0xef, 0xa9, 0x90,
-	0x31, 0x67, 0xa6, 0x93, 0x5e, 0x90, 0x89, 0xf8, 0x2b, 0x38, 0xb7, 0xe5, 0x64, 0x1e, 0x3d, 0x06,
-	0xd7, 0x26, 0xa7, 0xba, 0xda, 0x91, 0x86, 0x32, 0x57, 0xe0, 0x57, 0xf0, 0xd1, 0x1b, 0xfa, 0xa7,
-	0x0d, 0xca, 0xda, 0xbb, 0x06, 0x48, 0x88, 0x94, 0xc9, 0x52, 0x10, 0x49, 0x6d, 0xe1, 0x92, 0x06,
-	0x4f, 0x01, 0x95, 0x83, 0x0e, 0x01, 0x87, 0x57, 0x30, 0xf8, 0x99, 0xe9, 0xa5, 0xa9, 0xd4, 0xd9,
-	0x0f, 0xf5, 0x76, 0x07, 0x4e, 0xb5, 0x03, 0xe4, 0x43, 0x37, 0xda, 0x08, 0xa2, 0x37, 0xce, 0xb2,
-	0x43, 0x2e, 0xe3, 0x2f, 0xe1, 0xb2, 0x52, 0xcd, 0x36, 0x38, 0x84, 0xb6, 0xa0, 0x72, 0xb3, 0x4a,
-	0x2f, 0xa6, 0x1b, 0x58, 0x49, 0x3f, 0x67, 0xfe, 0x8c, 0xe6, 0xf0, 0x4b, 0xb8, 0x98, 0x3f, 0x23,
-	0xfd, 0x6f, 0x30, 0x7c, 0xa0, 0x2c, 0xda, 0x3a, 0x92, 0x7c, 0x33, 0xcd, 0xe6, 0x37, 0x4b, 0x9b,
-	0xdf, 0x07, 0x47, 0x71, 0xfb, 0x62, 0x47, 0xf1, 0xd2, 0xae, 0x1f, 0x95, 0x77, 0xbd, 0x58, 0xc6,
-	0xb3, 0xd2, 0x32, 0xe2, 0x97, 0x70, 0xb5, 0x53, 0x6b, 0xff, 0x41, 0xce, 0xfe, 0x3d, 0x02, 0xb8,
-	0x4d, 0xe2, 0x07, 0x2a, 0xde, 0xc7, 0x21, 0x45, 0xdf, 0x42, 0x37, 0x5b, 0x3e, 0x74, 0x65, 0xb9,
-	0xa0, 0xca, 0xee, 0x7e, 0x61, 0xd8, 0x5e, 0x53, 0xdc, 0x40, 0xdf, 0x43, 0x7f, 0x9b, 0xda, 0xd0,
-	0xd8, 0x3a, 0xd7, 0x32, 0x9e, 0x5f, 0xcb, 0x37, 0xb8, 0x81, 0x7e, 0x84, 0xf3, 0x2a, 0xeb, 0xa1,
-	0xeb, 0xdd, 0x4c, 0x65, 0x3a, 0xdc, 0x9b, 0xeb, 0x1e, 0x4e, 0xcb, 0xb4, 0x85, 0xfc, 0x22, 0x4f,
-	0x95, 0xcb, 0xfc, 0x8f, 0x6b, 0x6d, 0xa5, 0xe7, 0x9d, 0x94, 0xe8, 0x05, 0x8d, 0x0a, 0xef, 0x0a,
-	0xe5, 0xf8, 0x59, 0x91, 0x9a, 0x39, 0xe0, 0x06, 0x0a, 0xe0, 0xac, 0xc2, 0x0b, 0xe8, 0x45, 0x91,
-	0xab, 0x86, 0x2f, 0xfc, 0xeb, 0x7d, 0xe6, 0x2c, 0xe7, 0xec, 0x3f, 0x07, 0x4e, 0x6f, 0xa3, 0x75,
-	0xcc, 0x4a, 0xb3, 0xcc, 0xa8, 0xe0, 0xf0, 0x2c, 0xab, 0xa4, 0x81, 0x1b, 0xe8, 0x3b, 0x80, 0xe2,
-	0xc6, 0x91, 0x97, 0x65, 0xa8, 0x72, 0x85, 0x3f, 0xaa, 0xb1, 0xe4, 0x49, 0xe6, 0xd0, 0xdb, 0x3a,
-	0x45, 0x94, 0x21, 0x5c, 0x47, 0x07, 0xfe, 0xb8, 0xde, 0x58, 0xc6, 0xbf, 0x74, 0x77, 0x39, 0xfe,
-	0xbb, 0xb7, 0x9b, 0xe3, 0x3f, 0xaf, 0xcd, 0x13, 0xc0, 0x59, 0xe5, 0x48, 0x72, 0xfc, 0xeb, 0x0f,
-	0x35, 0xc7, 0x7f, 0xcf, 0x6d, 0xe1, 0xc6, 0xa2, 0x6d, 0xfe, 0x8a, 0x5e, 0xfd, 0x1f, 0x00, 0x00,
-	0xff, 0xff, 0xe0, 0x01, 0xe7, 0xfd, 0x22, 0x09, 0x00, 0x00,
+func init() { proto.RegisterFile("rpc.proto", fileDescriptor_rpc_968c18cc9ae1b2ba) }
+
+var fileDescriptor_rpc_968c18cc9ae1b2ba = []byte{
+	// 1732 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x58, 0xef, 0x6e, 0xdb, 0xc8,
+	0x11, 0x37, 0x25, 0xd9, 0x12, 0xc7, 0xb1, 0xe3, 0xac, 0xff, 0x44, 0x61, 0x9c, 0xd4, 0xd8, 0x6b,
+	0x0b, 0xe3, 0x8a, 0xf3, 0xb5, 0x0e, 0x0a, 0xf4, 0x8a, 0x36, 0xa8, 0x9c, 0x5c, 0xdc, 0xf4, 0x8c,
+	0x83, 0x41, 0xdf, 0xdd, 0x57, 0x75, 0x45, 0x6e, 0x24, 0xc2, 0xd2, 0x92, 0xe5, 0xae, 0x54, 0xab,
+	0x6f, 0x50, 0xa0, 0x6f, 0xd0, 0x4f, 0xf7, 0xad, 0x4f, 0xd2, 0xbe, 0x41, 0x5f, 0xa6, 0x5f, 0x8a,
+	0x1d, 0xee, 0xf2, 0x9f, 0xa8, 0xe8, 0xee, 0xdb, 0xce, 0xec, 0xcc, 0xec, 0xec, 0xfc, 0xdb, 0x1f,
+	0x09, 0x6e, 0x9a, 0x04, 0x17, 0x49, 0x1a, 0xab, 0x98, 0x6c, 0xa7, 0x49, 0x90, 0x8c, 0x28, 0x81,
+	0x83, 0xaf, 0x63, 0x71, 0xcb, 0x52, 0x36, 0x93, 0x3e, 0xff, 0xcb, 0x9c, 0x4b, 0x45, 0xff, 0xd9,
+	0x82, 0xbd, 0xab, 0x69, 0x1c, 0xdc, 0xfb, 0x5c, 0x26, 0xb1, 0x90, 0x9c, 0x10, 0xe8, 0x4c, 0x98,
+	0x9c, 0xf4, 0x9d, 0x33, 0xe7, 0xdc, 0xf5, 0x71, 0x4d, 0x7e, 0x02, 0xbb, 0x09, 0x4b, 0xb9, 0x50,
+	0x43, 0xdc, 0x6a, 0xe1, 0x16, 0x64, 0xac, 0x3f, 0x6a, 0x81, 0x13, 0xd8, 0x99, 0xf0, 0x68, 0x3c,
+	0x51, 0xfd, 0xf6, 0x99, 0x73, 0xde, 0xf1, 0x0d, 0x45, 0x4e, 0xc1, 0x55, 0xd1, 0x8c, 0x4b, 0xc5,
+	0x66, 0x49, 0xbf, 0x83, 0x5b, 0x05, 0x83, 0x3c, 0x83, 0x5e, 0x30, 0x61, 0x91, 0x18, 0x46, 0x61,
+	0x7f, 0xfb, 0xcc, 0x39, 0xdf, 0xf3, 0xbb, 0x48, 0xbf, 0x0f, 0xc9, 0xcf, 0x60, 0x3f, 0xd0, 0xee,
+	0x08, 0x39, 0x97, 0xc3, 0x34, 0x8e, 0x55, 0x7f, 0x07, 0x0f, 0xdd, 0xcb, 0xb9, 0x7e, 0x1c, 0x2b,
+	0xf2, 0x02, 0x40, 0x2a, 0xa6, 0x78, 0x26, 0xd2, 0x45, 0x11, 0x17, 0x39, 0xb8, 0xfd, 0x0c, 0x7a,
+	0xea, 0xc1, 0xe8, 0xf7, 0x70, 0xb3, 0xab, 0x1e, 0x32, 0xcd, 0x4f, 0x60, 0x2f, 0xe5, 0x01, 0x8f,
+	0x12, 0x65, 0xf6, 0x5d, 0xdc, 0x7f, 0x64, 0x99, 0x5a, 0x88, 0xfe, 0x02, 0x8e, 0xaf, 0xb9, 0xc2,
+	0xf8, 0x5c, 0x2d, 0xf5, 0x45, 0x4d, 0xd8, 0x9a, 0x82, 0x44, 0x7f, 0x05, 0x4f, 0x4b, 0xc2, 0x78,
+	0x7f, 0x2b, 0x5e, 0x84, 0xc7, 0x29, 0x87, 0x87, 0x7e, 0x07, 0x47, 0xd7, 0x5c, 0xdd, 0x30, 0xa9,
+	0x36, 0xe7, 0xe0, 0x53, 0xd8, 0x1e, 0x69, 0x21, 0x8c, 0xfe, 0xee, 0xe5, 0xd1, 0x05, 0x26, 0xf5,
+	0xa2, 0xa2, 0xe8, 0x67, 0x22, 0xf4, 0x18, 0x0e, 0xab, 0x76, 0xb3, 0x64, 0xff, 0xdd, 0x81, 0xc3,
+	0x6f, 0x52, 0x26, 0x24, 0x0b, 0x54, 0x14, 0x8b, 0x8f, 0x1e, 0x77, 0x04, 0xdb, 0x22, 0x16, 0x01,
+	0xc7, 0xe3, 0x3a, 0x7e, 0x46, 0x68, 0xc9, 0x0f, 0x69, 0x3c, 0xc3, 0x2c, 0xbb, 0x3e, 0xae, 0x75,
+	0x8e, 0x53, 0x1e, 0x44, 0x49, 0xc4, 0x85, 0xc2, 0x1c, 0xbb, 0x7e, 0xc1, 0xd0, 0x57, 0x67, 0xb3,
+	0x78, 0x2e, 0x14, 0x66, 0xd8, 0xf5, 0x0d, 0x45, 0xcf, 0x81, 0x5c, 0x73, 0xf5, 0xcd, 0xc3, 0xe6,
+	0xb8, 0x06, 0x18, 0xd7, 0x41, 0x10, 0x68, 0xbd, 0x3b, 0xcc, 0xad, 0x75, 0xbc, 0x0f, 0x5d, 0x16,
+	0x86, 0x29, 0x97, 0xd2, 0x68, 0x58, 0x72, 0x8d, 0xfb, 0x7d, 0xe8, 0x8e, 0xd8, 0x94, 0x69, 0x7e,
+	0x76, 0x03, 0x4b, 0xd2, 0x4b, 0x38, 0x59, 0x39, 0x24, 0x73, 0x69, 0xed, 0x19, 0xf4, 0x7b, 0x47,
+	0x37, 0x54, 0xc8, 0xdf, 0x8b, 0x0f, 0x71, 0xee, 0xd2, 0x3e, 0xb4, 0xa2, 0xd0, 0x48, 0xb6, 0xa2,
+	0x50, 0xab, 0x2f, 0x78, 0x2a, 0xa3, 0x58, 0xa0, 0x2b, 0x7b, 0xbe, 0x25, 0xb1, 0xc4, 0xa7, 0x3a,
+	0x46, 0x43, 0x2b, 0xd0, 0x36, 0x25, 0x8e, 0xdc, 0xef, 0x8c, 0xd8, 0x0b, 0x80, 0x71, 0xa4, 0x86,
+	0x41, 0x3c, 0x9b, 0x45, 0x79, 0x7c, 0xc7, 0x91, 0x7a, 0x83, 0x0c, 0xbd, 0x3d, 0x9a, 0x47, 0xd3,
+	0x70, 0x18, 0x32, 0xc5, 0x4d, 0x8c, 0x5d, 0xe4, 0xbc, 0x65, 0x8a, 0xd3, 0x5f, 0xc2, 0x81, 0xb9,
+	0x94, 0xcc, 0x5d, 0x3c, 0x05, 0xd7, 0x5c, 0x81, 0xeb, 0x3b, 0xb5, 0xb5, 0x46, 0xce, 0xa0, 0xaf,
+	0xe0, 0xc9, 0xd7, 0xfc, 0xaf, 0x46, 0xc9, 0x06, 0xe1, 0x25, 0x40, 0xc2, 0xa4, 0x4c, 0x26, 0x29,
+	0x93, 0xdc, 0xdc, 0xae, 0xc4, 0xa1, 0x17, 0x40, 0xca, 0x4a, 0x9b, 0xd2, 0x43, 0xa7, 0x70, 0xf4,
+	0xad, 0xd0, 0xa5, 0x59, 0x3b, 0x67, 0x7d, 0x42, 0xab, 0x1e, 0xb4, 0xea, 0x1e, 0x10, 0x0f, 0x7a,
+	0xe1, 0x3c, 0x65, 0xca, 0xc6, 0xb1, 0xe3, 0xe7, 0x34, 0xfd, 0x1c, 0x8e, 0x6b, 0xa7, 0x19, 0x07,
+	0x4f, 0x60, 0x27, 0xe5, 0x72, 0x3e, 0xcd, 0xfa, 0xb2, 0xe7, 0x1b, 0x4a, 0x5f, 0xe7, 0xe6, 0x47,
+	0x38, 0x47, 0x3f, 0x83, 0xc3, 0x9b, 0x1f, 0x61, 0xfe, 0x5f, 0x0e, 0x9c, 0xdc, 0x71, 0x11, 0x56,
+	0x7a, 0x31, 0x6f, 0x00, 0x6c, 0x30, 0xa7, 0xd4, 0x60, 0xfb, 0xd0, 0x52, 0xb1, 0xb9, 0x72, 0x4b,
+	0xc5, 0xa5, 0x96, 0x6a, 0x97, 0x5b, 0x4a, 0x97, 0x82, 0x88, 0xd5, 0x70, 0xc4, 0x3f, 0xc4, 0x29,
+	0xb7, 0xd3, 0x56, 0xc4, 0xea, 0x0a, 0x19, 0x7a, 0x9b, 0x3f, 0x24, 0x51, 0xca, 0xe5, 0x90, 0x65,
+	0xdd, 0xd8, 0xf1, 0x5d, 0xc3, 0x19, 0xa8, 0xa2, 0x63, 0x1e, 0x97, 0x3a, 0x86, 0x7e, 0x06, 0x4f,
+	0x57, 0x3c, 0x5d, 0x3f, 0x35, 0xe8, 0x4f, 0xe1, 0x60, 0x10, 0x86, 0xba, 0x7f, 0xa2, 0xc0, 0x5e,
+	0xe9, 0x00, 0xda, 0xf3, 0x74, 0x6a, 0xc4, 0xf4, 0x92, 0x7e, 0x02, 0x4f, 0x7c, 0x3e, 0x8b, 0x17,
+	0xfc, 0x96, 0xf3, 0xd4, 0x8a, 0xd5, 0x1a, 0x87, 0xfe, 0x0e, 0xf6, 0xaf, 0x98, 0xf8, 0x88, 0x44,
+	0x25, 0xe5, 0xad, 0x5a, 0xca, 0x3f, 0x05, 0x72, 0xc7, 0xd5, 0x4d, 0x3c, 0xbe, 0xe1, 0x0b, 0x3e,
+	0xb5, 0x16, 0x8e, 0x60, 0x7b, 0xaa, 0x69, 0x63, 0x24, 0x23, 0xe8, 0x3b, 0x38, 0xb8, 0x53, 0x2c,
+	0x55, 0x77, 0x2a, 0x4e, 0xac, 0xe4, 0x29, 0xb8, 0x72, 0x3e, 0x92, 0x4b, 0xa9, 0xb8, 0x4d, 0x46,
+	0xc1, 0xd0, 0x76, 0xa4, 0xd6, 0xc0, 0x63, 0x7b, 0x7e, 0x46, 0xe8, 0x2a, 0x18, 0x84, 0xb3, 0x48,
+	0x47, 0x68, 0x3e, 0xdd, 0x5c, 0x05, 0xdf, 0xb7, 0xe0, 0xf0, 0xf6, 0x32, 0xd9, 0x38, 0x41, 0x08,
+	0x74, 0x74, 0x9d, 0x99, 0x02, 0xc0, 0xb5, 0x7e, 0xd8, 0xc2, 0x89, 0x1a, 0x22, 0xdf, 0x4c, 0xb2,
+	0x70, 0xa2, 0x06, 0x7a, 0xeb, 0x39, 0xb8, 0x82, 0xa9, 0x61, 0xca, 0x59, 0xb8, 0xc4, 0x22, 0xe8,
+	0xf9, 0x3d, 0xc1, 0x94, 0xaf, 0x69, 0xad, 0x97, 0xcc, 0x47, 0x99, 0x5e, 0x36, 0x2b, 0xba, 0xc9,
+	0x7c, 0x84, 0x7a, 0x7d, 0xe8, 0xca, 0xf9, 0x48, 0x70, 0x25, 0xfb, 0x3b, 0x38, 0x13, 0x2c, 0x49,
+	0x5e, 0xc3, 0xce, 0x88, 0x09, 0xc1, 0xc3, 0x7e, 0xf7, 0xac, 0x7d, 0xbe, 0x7b, 0xf9, 0x73, 0xf3,
+	0xf4, 0x34, 0x38, 0x7f, 0x71, 0x85, 0x82, 0x5f, 0x0a, 0x95, 0x2e, 0x7d, 0xa3, 0xe5, 0x7d, 0x01,
+	0xbb, 0x25, 0xb6, 0xae, 0x87, 0x7b, 0xbe, 0xb4, 0xf5, 0x70, 0xcf, 0x97, 0x3a, 0x9c, 0x0b, 0x36,
+	0x9d, 0x67, 0x6d, 0xdd, 0xf6, 0x33, 0xe2, 0xb7, 0xad, 0xdf, 0x38, 0x74, 0x80, 0x0f, 0xd9, 0x97,
+	0x8b, 0x28, 0xe4, 0x22, 0xe0, 0xb2, 0x94, 0xc7, 0x2c, 0xfe, 0xd9, 0x73, 0x9a, 0x11, 0x98, 0xdd,
+	0x48, 0x0f, 0x49, 0x33, 0xf3, 0x91, 0xa0, 0xff, 0x75, 0xe0, 0xc0, 0x1a, 0x28, 0xd7, 0xee, 0x7d,
+	0x24, 0x6c, 0x94, 0x71, 0xad, 0xf3, 0x24, 0xe6, 0xb3, 0x11, 0x4f, 0x8d, 0xbe, 0xa1, 0xf2, 0x3a,
+	0x6f, 0x97, 0x5e, 0x47, 0x02, 0x9d, 0x84, 0xf3, 0xd4, 0x8c, 0x63, 0x5c, 0x67, 0x79, 0x66, 0x32,
+	0x16, 0xf6, 0xa5, 0xcb, 0x28, 0x2d, 0xab, 0x21, 0x0f, 0x02, 0x98, 0xb6, 0x8f, 0x6b, 0xcd, 0x0b,
+	0x99, 0x62, 0x06, 0xb1, 0xe0, 0x5a, 0xbb, 0x1f, 0xab, 0x09, 0x4f, 0x0d, 0x52, 0xc9, 0x08, 0x4c,
+	0x4b, 0x34, 0x16, 0x3c, 0x95, 0x7d, 0xd7, 0xa4, 0x25, 0x23, 0xe9, 0x9f, 0xe1, 0x49, 0x29, 0x30,
+	0xe6, 0x62, 0x47, 0xb0, 0x8d, 0x33, 0xc8, 0x46, 0x06, 0x09, 0xf2, 0x6b, 0x70, 0xb9, 0x15, 0xed,
+	0xb7, 0x30, 0x89, 0x4f, 0x4d, 0x12, 0xeb, 0xa1, 0xf1, 0x0b, 0x49, 0x7a, 0x8d, 0xf0, 0x07, 0x5f,
+	0xc3, 0xb7, 0xd1, 0x98, 0xcb, 0x32, 0x9e, 0x31, 0xa1, 0x72, 0x2a, 0xa1, 0x3a, 0x81, 0x9d, 0x24,
+	0xe5, 0x1f, 0xa2, 0x07, 0x53, 0xac, 0x86, 0xa2, 0x23, 0x38, 0xba, 0xc5, 0x95, 0x35, 0x53, 0xb4,
+	0x86, 0x91, 0x77, 0xca, 0xf2, 0xba, 0xb3, 0x99, 0x79, 0xb5, 0x6c, 0x67, 0x5b, 0x5a, 0xeb, 0x84,
+	0x68, 0xc5, 0x4e, 0xbf, 0x8c, 0xa2, 0xff, 0x76, 0xe0, 0xb0, 0xe2, 0x6a, 0x71, 0x46, 0xa3, 0xaf,
+	0x36, 0xad, 0xad, 0x52, 0x5a, 0xab, 0x70, 0xb2, 0x5d, 0x87, 0x93, 0xaf, 0xf2, 0xa3, 0x3b, 0x88,
+	0xc1, 0x9e, 0xdb, 0x46, 0x68, 0xb8, 0x9b, 0xf5, 0x8b, 0x7c, 0x0e, 0x1d, 0x3d, 0x38, 0xfa, 0xdb,
+	0x67, 0xed, 0x4d, 0x2a, 0x28, 0x48, 0x5f, 0xc1, 0xf1, 0x9b, 0x78, 0x96, 0xb0, 0x40, 0x0f, 0xa4,
+	0x94, 0x8d, 0x73, 0x24, 0xe2, 0x41, 0xef, 0x9e, 0x2f, 0x65, 0xc2, 0x02, 0xfb, 0x04, 0xe7, 0x34,
+	0xfd, 0x02, 0xf6, 0xbe, 0x32, 0xeb, 0x6f, 0x25, 0x1b, 0x63, 0x85, 0x09, 0x36, 0xb3, 0x82, 0xb8,
+	0xd6, 0xc5, 0x31, 0x5a, 0x2a, 0x6e, 0x63, 0x9a, 0x11, 0xf4, 0x1a, 0x9e, 0xbc, 0x8d, 0xe4, 0x3d,
+	0xaa, 0xe5, 0x51, 0xbb, 0x04, 0xd7, 0xda, 0xce, 0x30, 0x42, 0x81, 0x38, 0x2b, 0xe7, 0xf8, 0x85,
+	0x18, 0xfd, 0x0a, 0x1e, 0xdf, 0x46, 0x62, 0xfc, 0xb1, 0x91, 0x4d, 0xa0, 0x23, 0xa3, 0xbf, 0x71,
+	0x03, 0x85, 0x70, 0x5d, 0x94, 0x6c, 0x1b, 0x99, 0x19, 0x41, 0xff, 0xe1, 0xc0, 0x41, 0x61, 0xad,
+	0x68, 0x5b, 0xc9, 0x4d, 0x71, 0x6b, 0x75, 0x2e, 0x30, 0x2a, 0x88, 0xd9, 0x17, 0x3c, 0x34, 0x66,
+	0x73, 0x5a, 0xcb, 0x4f, 0x63, 0x29, 0xd1, 0xb2, 0xe3, 0xe3, 0x5a, 0x8f, 0x9f, 0x59, 0x24, 0x30,
+	0x83, 0x6d, 0x5f, 0x2f, 0x35, 0x87, 0x2d, 0xc6, 0xd8, 0xb5, 0x6d, 0x5f, 0x2f, 0x51, 0x86, 0x3d,
+	0x98, 0x8e, 0xd5, 0xcb, 0xcb, 0xff, 0xb4, 0x01, 0x06, 0x49, 0x74, 0xc7, 0xd3, 0x45, 0x14, 0x70,
+	0xf2, 0x1a, 0x7a, 0x76, 0xf4, 0x11, 0xdb, 0x49, 0xf5, 0x6f, 0x2b, 0xaf, 0xd8, 0xa8, 0x0e, 0x49,
+	0xba, 0x45, 0xde, 0xc1, 0x7e, 0xf5, 0xc3, 0x82, 0x9c, 0x1a, 0xe1, 0xc6, 0xef, 0x0d, 0xaf, 0x11,
+	0xed, 0xd3, 0x2d, 0xf2, 0x27, 0x38, 0xa8, 0x7f, 0x73, 0x90, 0x97, 0xab, 0x96, 0xca, 0x1f, 0x23,
+	0x6b, 0x6d, 0xbd, 0x87, 0x47, 0xe5, 0x8f, 0x06, 0xe2, 0x15, 0x76, 0xea, 0x5f, 0x12, 0xde, 0xf3,
+	0xc6, 0xbd, 0xd2, 0xf5, 0x76, 0x4b, 0xe0, 0x9e, 0x3c, 0x2b, 0xa4, 0x6b, 0x80, 0xdf, 0xb3, 0x87,
+	0x34, 0x00, 0x0c, 0xba, 0x45, 0x7c, 0x78, 0x5c, 0x43, 0xe5, 0xe4, 0x45, 0x61, 0xab, 0x01, 0xad,
+	0x7b, 0x2f, 0xd7, 0x6d, 0x5b, 0x9b, 0x97, 0xff, 0xeb, 0xc1, 0x23, 0x7c, 0xa6, 0x4b, 0xb9, 0x1c,
+	0xd8, 0xe1, 0xb2, 0x31, 0x97, 0x75, 0x30, 0x4d, 0xb7, 0xc8, 0x1b, 0x80, 0x02, 0xfb, 0x92, 0xbe,
+	0xb5, 0x50, 0xc7, 0xd0, 0xde, 0xb3, 0x86, 0x9d, 0xdc, 0xc8, 0x0d, 0xec, 0x55, 0x20, 0x2a, 0xb1,
+	0x11, 0x6e, 0x82, 0xc9, 0xde, 0x69, 0xf3, 0x66, 0x39, 0xfe, 0x25, 0x3c, 0x9a, 0xc7, 0x7f, 0x15,
+	0xd3, 0x7a, 0x5e, 0xd3, 0x56, 0x39, 0xfe, 0x35, 0xf4, 0x97, 0xc7, 0xbf, 0x19, 0xbf, 0x7a, 0x2f,
+	0xd7, 0x6d, 0xe7, 0x36, 0xaf, 0xc0, 0xcd, 0x21, 0x62, 0x1e, 0xef, 0x3a, 0x68, 0xcc, 0xfd, 0x6a,
+	0x00, 0x54, 0x74, 0x8b, 0xbc, 0x05, 0x28, 0x00, 0x64, 0x1e, 0xf2, 0x15, 0x4c, 0xb9, 0xc1, 0xca,
+	0x6b, 0xe8, 0x1a, 0x84, 0x49, 0x8e, 0x6d, 0x4f, 0x30, 0xf1, 0xc3, 0xf5, 0xdf, 0xc1, 0x6e, 0x09,
+	0x63, 0xe6, 0x51, 0x5e, 0xc5, 0x9d, 0x1b, 0xec, 0x0c, 0x7e, 0xc8, 0x30, 0xf1, 0xd6, 0x83, 0xae,
+	0x2c, 0xa8, 0x39, 0x84, 0xcd, 0x6d, 0xd4, 0x41, 0xed, 0xc6, 0xeb, 0x3c, 0x2a, 0x63, 0xad, 0x72,
+	0xff, 0xd7, 0x01, 0x98, 0xd7, 0xaf, 0xa1, 0x07, 0x59, 0x29, 0xe5, 0xfd, 0x2a, 0x6a, 0x28, 0xcf,
+	0xb6, 0x55, 0x30, 0x91, 0x7b, 0xd5, 0xf0, 0x78, 0x67, 0xd6, 0xaa, 0xaf, 0x61, 0x6e, 0xad, 0xf1,
+	0x91, 0xdc, 0x70, 0xc7, 0x3f, 0x80, 0x9b, 0xbf, 0x75, 0xeb, 0x63, 0x6d, 0x6f, 0xb7, 0xf2, 0x2c,
+	0xd2, 0x2d, 0xf2, 0x7b, 0xe8, 0xd9, 0x67, 0x89, 0x9c, 0xd8, 0x9c, 0x54, 0x5f, 0x3d, 0xef, 0xe9,
+	0x0a, 0xdf, 0xaa, 0x8f, 0x76, 0xf0, 0x8f, 0xdc, 0xab, 0xff, 0x0f, 0x00, 0xe3, 0x9d, 0x22, 0xc4,
+	0x9e, 0x13, 0x00, 0x00,
 }
@@ -0,0 +1,276 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package rpc
+
+//Access policy of transports: the local IPC, and the public ones, grpc on
+//rpc_listen and websocket on ws_listen. each transport has its allowlist of
+//methods, named "Service.Method" for grpc and "ws.method" for websocket, with
+//"Service.*" and "*" patterns. public transports may also require an api token
+//or a jwt signed with HS256, check the origin of browsers, and are limited by
+//client ip and by method, so an exposed endpoint is not a DoS target.
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/yeeco/gyee/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	TransportIPC    = "ipc"
+	TransportPublic = "public"
+
+	authMetadataKey = "authorization" // metadata key or http header of credential
+	authQueryKey    = "token"         // query key of credential, for browsers
+	authBearerPrefx = "Bearer "       // optional prefix of credential
+	wsMethodPrefx   = "ws."           // prefix of websocket method names
+	grpcPkgPrefx    = "rpcpb."        // package prefix of grpc service names
+)
+
+var (
+	ErrMethodNotAllowed = errors.New("rpc: method not allowed on transport")
+	ErrRateLimited      = errors.New("rpc: rate limited")
+	ErrUnauthenticated  = errors.New("rpc: authentication required")
+	ErrOriginNotAllowed = errors.New("rpc: origin not allowed")
+
+	errJWT = errors.New("rpc: invalid jwt")
+
+	// methods allowed on public transports if not configured
+	defaultPublicMethods = []string{"ApiService.*", wsMethodPrefx + "*"}
+)
+
+type policy struct {
+	transport string
+	methods   []string                // allowlist of methods, nil for all
+	origins   []string                // allowed origins of browsers, nil for all
+	token     string                  // api token, empty if not accepted
+	jwtSecret []byte                  // jwt secret, nil if not accepted
+	ipLimit   *rateLimiter            // limit by client ip, nil for unlimited
+	mtLimits  map[string]*rateLimiter // limit by method of all clients
+}
+
+func newPolicy(transport string, conf *config.RpcConfig) (*policy, error) {
+	p := &policy{
+		transport: transport,
+		mtLimits:  make(map[string]*rateLimiter),
+	}
+	if transport == TransportIPC {
+		// local clients are trusted but for the methods
+		p.methods = conf.IpcMethods
+		return p, nil
+	}
+	p.methods = conf.PublicMethods
+	if len(p.methods) == 0 {
+		p.methods = defaultPublicMethods
+	}
+	p.origins = conf.CorsOrigins
+	p.token = conf.AuthToken
+	if conf.JwtSecret != "" {
+		p.jwtSecret = []byte(conf.JwtSecret)
+	}
+	if conf.RateLimit > 0 {
+		p.ipLimit = newRateLimiter(conf.RateLimit, conf.RateBurst)
+	}
+	for _, spec := range conf.MethodLimits {
+		method, rl, err := parseMethodLimit(spec)
+		if err != nil {
+			return nil, err
+		}
+		p.mtLimits[method] = rl
+	}
+	return p, nil
+}
+
+// check credential of client, with or without the bearer prefix
+func (p *policy) authenticate(cred string) error {
+	if p.token == "" && p.jwtSecret == nil {
+		return nil
+	}
+	cred = strings.TrimPrefix(strings.TrimSpace(cred), authBearerPrefx)
+	if cred == "" {
+		return ErrUnauthenticated
+	}
+	if p.token != "" && subtle.ConstantTimeCompare([]byte(cred), []byte(p.token)) == 1 {
+		return nil
+	}
+	if p.jwtSecret != nil && verifyJWT(cred, p.jwtSecret, time.Now()) == nil {
+		return nil
+	}
+	return ErrUnauthenticated
+}
+
+// check method called by client of ip, empty if not known
+func (p *policy) allow(method string, ip string) error {
+	if !p.allowed(method) {
+		return ErrMethodNotAllowed
+	}
+	if err := p.limitIP(ip); err != nil {
+		return err
+	}
+	if rl, ok := p.mtLimits[method]; ok && !rl.allow("") {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// check request of client of ip, by any method or connecting
+func (p *policy) limitIP(ip string) error {
+	if p.ipLimit != nil && ip != "" && !p.ipLimit.allow(ip) {
+		return ErrRateLimited
+	}
+	return nil
+}
+
+func (p *policy) allowed(method string) bool {
+	if p.methods == nil {
+		return true
+	}
+	for _, pattern := range p.methods {
+		if pattern == "*" || pattern == method ||
+			strings.HasSuffix(pattern, ".*") && strings.HasPrefix(method, pattern[:len(pattern)-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// check origin of browser, non-browser clients may send none
+func (p *policy) checkOrigin(origin string) error {
+	if len(p.origins) == 0 || origin == "" {
+		return nil
+	}
+	for _, o := range p.origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return nil
+		}
+	}
+	return ErrOriginNotAllowed
+}
+
+// interceptor applying policy to grpc calls
+func (p *policy) unaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		cred, ip := "", ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(authMetadataKey); len(v) > 0 {
+				cred = v[0]
+			}
+		}
+		if pr, ok := peer.FromContext(ctx); ok && pr.Addr != nil {
+			ip = hostOf(pr.Addr.String())
+		}
+		if err := p.authenticate(cred); err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		if err := p.allow(grpcMethodName(info.FullMethod), ip); err == ErrRateLimited {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		} else if err != nil {
+			return nil, status.Error(codes.PermissionDenied, err.Error())
+		}
+		return handler(ctx, req)
+	}
+}
+
+// "/rpcpb.ApiService/NodeInfo" to "ApiService.NodeInfo"
+func grpcMethodName(fullMethod string) string {
+	return strings.Replace(strings.TrimPrefix(strings.TrimPrefix(fullMethod, "/"), grpcPkgPrefx), "/", ".", 1)
+}
+
+// ip of address, empty for unix sockets
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// chain interceptors, the first one is the outermost
+func chainUnary(ics ...grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		next := handler
+		for i := len(ics) - 1; i >= 0; i-- {
+			ic, h := ics[i], next
+			next = func(ctx context.Context, req interface{}) (interface{}, error) {
+				return ic(ctx, req, info, h)
+			}
+		}
+		return next(ctx, req)
+	}
+}
+
+// verify jwt signed with HS256, and its exp and nbf claims if any
+func verifyJWT(token string, secret []byte, now time.Time) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return errJWT
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return errJWT
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return errJWT
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return errJWT
+	}
+	var claims struct {
+		Exp *int64 `json:"exp"`
+		Nbf *int64 `json:"nbf"`
+	}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return errJWT
+	}
+	if claims.Exp != nil && now.Unix() >= *claims.Exp {
+		return errJWT
+	}
+	if claims.Nbf != nil && now.Unix() < *claims.Nbf {
+		return errJWT
+	}
+	return nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
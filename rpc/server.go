@@ -22,12 +22,15 @@ package rpc
 
 import (
 	"net"
+	"net/http"
+	"os"
 	"sync"
 
 	"github.com/yeeco/gyee/config"
 	"github.com/yeeco/gyee/core"
 	"github.com/yeeco/gyee/log"
 	"github.com/yeeco/gyee/rpc/pb"
+	"github.com/yeeco/gyee/utils/datadir"
 	"google.golang.org/grpc"
 )
 
@@ -39,23 +42,49 @@ type Server struct {
 	conf      *config.Config
 	node      core.INode
 	core      *core.Core
-	rpcServer *grpc.Server
+	rpcServer *grpc.Server // grpc on ipc
+	pubServer *grpc.Server // grpc on rpc_listen
+	public    *policy      // policy of public transports
+	wsServers []*http.Server
+	tokenFile string // admin token file, empty if admin auth disabled
 
 	lock sync.RWMutex
 }
 
-func NewServer(conf *config.Config, node core.INode) *Server {
-	rpc := grpc.NewServer()
+func NewServer(conf *config.Config, node core.INode) (*Server, error) {
+	ipc, err := newPolicy(TransportIPC, conf.Rpc)
+	if err != nil {
+		return nil, err
+	}
+	public, err := newPolicy(TransportPublic, conf.Rpc)
+	if err != nil {
+		return nil, err
+	}
+	ics := []grpc.UnaryServerInterceptor{ipc.unaryInterceptor()}
+	tokenFile := ""
+	if conf.Rpc.AdminAuth {
+		tokenFile = datadir.New(conf.NodeDir).AdminTokenFile()
+		token, err := newAdminToken(tokenFile)
+		if err != nil {
+			log.Error("admin token not written, admin methods rejected", "err", err)
+		}
+		ics = append(ics, adminAuth(token))
+	}
 	srv := &Server{
 		conf:      conf,
 		node:      node,
 		core:      node.Core(),
-		rpcServer: rpc,
+		rpcServer: grpc.NewServer(grpc.UnaryInterceptor(chainUnary(ics...))),
+		pubServer: grpc.NewServer(grpc.UnaryInterceptor(public.unaryInterceptor())),
+		public:    public,
+		tokenFile: tokenFile,
+	}
+	for _, rpc := range []*grpc.Server{srv.rpcServer, srv.pubServer} {
+		rpcpb.RegisterAdminServiceServer(rpc, newAdminService(srv))
+		rpcpb.RegisterApiServiceServer(rpc, newAPIService(srv))
 	}
-	rpcpb.RegisterAdminServiceServer(rpc, newAdminService(srv))
-	rpcpb.RegisterApiServiceServer(rpc, newAPIService(srv))
 
-	return srv
+	return srv, nil
 }
 
 func (s *Server) Node() core.INode {
@@ -70,6 +99,12 @@ func (s *Server) Serve(lis net.Listener) error {
 	return s.rpcServer.Serve(lis)
 }
 
+// Serve grpc on public listener, methods are limited by policy of public
+// transports
+func (s *Server) ServePublic(lis net.Listener) error {
+	return s.pubServer.Serve(lis)
+}
+
 func (s *Server) Start() error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
@@ -84,4 +119,27 @@ func (s *Server) Stop() {
 	log.Info("RPC stop...")
 
 	s.rpcServer.Stop()
+	s.pubServer.Stop()
+	s.stopWS()
+	if s.tokenFile != "" {
+		if err := os.Remove(s.tokenFile); err != nil && !os.IsNotExist(err) {
+			log.Warn("remove admin token", "err", err)
+		}
+	}
+}
+
+// Stop serving WebSocket subscriptions, the listeners are closed
+func (s *Server) StopWS() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.stopWS()
+}
+
+func (s *Server) stopWS() {
+	for _, hs := range s.wsServers {
+		if err := hs.Close(); err != nil {
+			log.Warn("close ws server", "err", err)
+		}
+	}
+	s.wsServers = nil
 }
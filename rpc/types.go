@@ -30,7 +30,15 @@ type RPCServer interface {
 	Node() core.INode
 	Core() *core.Core
 	Serve(lis net.Listener) error
+	ServePublic(lis net.Listener) error
+	ServeWS(lis net.Listener) error
+	StopWS()
 
 	Start() error
 	Stop()
 }
+
+// Implemented by nodes able to start or stop subsystems at runtime
+type SubsystemController interface {
+	StartStop(subsystem string, start bool) error
+}
@@ -0,0 +1,344 @@
+/*
+ *  Copyright (C) 2017 gyee authors
+ *
+ *  This file is part of the gyee library.
+ *
+ *  The gyee library is free software: you can redistribute it and/or modify
+ *  it under the terms of the GNU General Public License as published by
+ *  the Free Software Foundation, either version 3 of the License, or
+ *  (at your option) any later version.
+ *
+ *  The gyee library is distributed in the hope that it will be useful,
+ *  but WITHOUT ANY WARRANTY; without even the implied warranty of
+ *  MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ *  GNU General Public License for more details.
+ *
+ *  You should have received a copy of the GNU General Public License
+ *  along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package rpc
+
+//WebSocket subscriptions: clients send json requests
+//  {"id":1,"method":"subscribe","params":{"topic":"newHeads","cursor":100}}
+//  {"id":2,"method":"unsubscribe","params":{"subscription":"1"}}
+//and get notifications of topics subscribed, backed by the core event feed:
+//  newHeads: blocks added, with cursor of block height. a client reconnecting
+//            with the last cursor got is backfilled with the heads it missed.
+//  pendingTxs: txs accepted by tx pool
+//  logs: logs of contract txs, filtered by contract address if given
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/yeeco/gyee/common/address"
+	"github.com/yeeco/gyee/core"
+	"github.com/yeeco/gyee/log"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	TopicNewHeads   = "newHeads"
+	TopicPendingTxs = "pendingTxs"
+	TopicLogs       = "logs"
+
+	wsMaxBackfill = 1024 // max heads backfilled for a cursor
+	wsEventQueue  = 256  // size of event queue of a subscription
+)
+
+type wsRequest struct {
+	ID     uint64   `json:"id"`
+	Method string   `json:"method"`
+	Params wsParams `json:"params"`
+}
+
+type wsParams struct {
+	Topic        string  `json:"topic,omitempty"`
+	Cursor       *uint64 `json:"cursor,omitempty"`  // height of last head got, for newHeads
+	Address      string  `json:"address,omitempty"` // contract address, for logs
+	Subscription string  `json:"subscription,omitempty"`
+}
+
+type wsResponse struct {
+	ID     uint64      `json:"id"`
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+type wsNotification struct {
+	Subscription string      `json:"subscription"`
+	Topic        string      `json:"topic"`
+	Cursor       uint64      `json:"cursor,omitempty"` // block height, for newHeads and logs
+	Result       interface{} `json:"result"`
+}
+
+type wsLog struct {
+	Address string   `json:"address"`
+	Topics  []string `json:"topics"`
+	Data    string   `json:"data"`
+}
+
+type wsConn struct {
+	srv   *Server
+	conn  *websocket.Conn
+	ip    string     // ip of client
+	wlock sync.Mutex // lock to serialize writes
+	lock  sync.Mutex // lock to protect subs
+	subs  map[string]*wsSub
+	seq   uint64 // last subscription id
+}
+
+type wsSub struct {
+	id      string
+	topic   string
+	addr    *address.Address // contract address of logs, nil for all
+	evSub   *core.EventSub
+	done    chan struct{}
+	cursor  uint64 // height of last head sent
+	started bool   // if any head sent
+}
+
+// Serve WebSocket subscriptions on listener, until server stopped
+func (s *Server) ServeWS(lis net.Listener) error {
+	hs := &http.Server{
+		Handler: websocket.Server{
+			Handler:   s.serveWSConn,
+			Handshake: s.wsHandshake,
+		},
+	}
+	s.lock.Lock()
+	s.wsServers = append(s.wsServers, hs)
+	s.lock.Unlock()
+	if err := hs.Serve(lis); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// check rate, origin and credential of client, the credential is in header,
+// or query for browsers
+func (s *Server) wsHandshake(cfg *websocket.Config, req *http.Request) error {
+	if err := s.public.limitIP(hostOf(req.RemoteAddr)); err != nil {
+		return err
+	}
+	if err := s.public.checkOrigin(req.Header.Get("Origin")); err != nil {
+		return err
+	}
+	cred := req.Header.Get(authMetadataKey)
+	if cred == "" {
+		cred = req.URL.Query().Get(authQueryKey)
+	}
+	return s.public.authenticate(cred)
+}
+
+func (s *Server) serveWSConn(conn *websocket.Conn) {
+	wc := &wsConn{
+		srv:  s,
+		conn: conn,
+		ip:   hostOf(conn.Request().RemoteAddr),
+		subs: make(map[string]*wsSub),
+	}
+	defer wc.close()
+	for {
+		req := new(wsRequest)
+		if err := websocket.JSON.Receive(conn, req); err != nil {
+			log.Debug("ws connection closed", "remote", conn.Request().RemoteAddr, "err", err)
+			return
+		}
+		var result interface{}
+		err := wc.srv.public.allow(wsMethodPrefx+req.Method, wc.ip)
+		if err == nil {
+			result, err = wc.call(req)
+		}
+		resp := &wsResponse{ID: req.ID}
+		if err != nil {
+			resp.Error = err.Error()
+		} else {
+			resp.Result = result
+		}
+		if err := wc.send(resp); err != nil {
+			return
+		}
+	}
+}
+
+func (wc *wsConn) call(req *wsRequest) (interface{}, error) {
+	switch req.Method {
+	case "subscribe":
+		return wc.subscribe(&req.Params)
+	case "unsubscribe":
+		return wc.unsubscribe(req.Params.Subscription)
+	}
+	return nil, fmt.Errorf("unknown method %s", req.Method)
+}
+
+func (wc *wsConn) send(v interface{}) error {
+	wc.wlock.Lock()
+	defer wc.wlock.Unlock()
+	return websocket.JSON.Send(wc.conn, v)
+}
+
+func (wc *wsConn) subscribe(params *wsParams) (string, error) {
+	chain := wc.srv.core.Chain()
+	sub := &wsSub{
+		topic: params.Topic,
+		done:  make(chan struct{}),
+	}
+	switch params.Topic {
+	case TopicNewHeads:
+		if params.Cursor != nil {
+			if last := chain.CurrentBlockHeight(); last > *params.Cursor+wsMaxBackfill {
+				return "", fmt.Errorf("cursor %d too old, %d heads to backfill at most", *params.Cursor, wsMaxBackfill)
+			}
+			sub.cursor, sub.started = *params.Cursor, true
+		}
+		sub.evSub = chain.SubscribeEvents(wsEventQueue, core.EventNewHead)
+	case TopicPendingTxs:
+		sub.evSub = chain.SubscribeEvents(wsEventQueue, core.EventPendingTx)
+	case TopicLogs:
+		if params.Address != "" {
+			addr, err := address.AddressParse(params.Address)
+			if err != nil {
+				return "", err
+			}
+			sub.addr = addr
+		}
+		sub.evSub = chain.SubscribeEvents(wsEventQueue, core.EventLogs)
+	default:
+		return "", fmt.Errorf("unknown topic %s", params.Topic)
+	}
+	wc.lock.Lock()
+	wc.seq++
+	sub.id = fmt.Sprint(wc.seq)
+	wc.subs[sub.id] = sub
+	wc.lock.Unlock()
+	go wc.forward(sub)
+	return sub.id, nil
+}
+
+func (wc *wsConn) unsubscribe(id string) (bool, error) {
+	wc.lock.Lock()
+	sub, ok := wc.subs[id]
+	delete(wc.subs, id)
+	wc.lock.Unlock()
+	if !ok {
+		return false, errors.New("subscription not found")
+	}
+	sub.evSub.Unsubscribe()
+	close(sub.done)
+	return true, nil
+}
+
+func (wc *wsConn) close() {
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+	for id, sub := range wc.subs {
+		sub.evSub.Unsubscribe()
+		close(sub.done)
+		delete(wc.subs, id)
+	}
+}
+
+func (wc *wsConn) forward(sub *wsSub) {
+	if sub.topic == TopicNewHeads && sub.started {
+		// backfill heads missed since cursor
+		if err := wc.sendHeads(sub, wc.srv.core.Chain().CurrentBlockHeight()); err != nil {
+			return
+		}
+	}
+	for {
+		select {
+		case <-sub.done:
+			return
+		case ev := <-sub.evSub.C:
+			var err error
+			switch ev.Kind {
+			case core.EventNewHead:
+				err = wc.sendHeads(sub, ev.Block.Number())
+			case core.EventPendingTx:
+				err = wc.sendTx(sub, ev.Tx)
+			case core.EventLogs:
+				err = wc.sendLogs(sub, ev)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// send heads after the last one sent up to height, heads sent before or
+// dropped for subscriber too slow are got from chain
+func (wc *wsConn) sendHeads(sub *wsSub, height uint64) error {
+	from := height
+	if sub.started {
+		if height <= sub.cursor {
+			return nil
+		}
+		from = sub.cursor + 1
+	}
+	for num := from; num <= height; num++ {
+		b := wc.srv.core.Chain().GetBlockByNumber(num)
+		if b == nil {
+			return nil
+		}
+		br, err := blockResponse(b)
+		if err != nil {
+			return err
+		}
+		if err := wc.send(&wsNotification{
+			Subscription: sub.id,
+			Topic:        sub.topic,
+			Cursor:       num,
+			Result:       br,
+		}); err != nil {
+			return err
+		}
+		sub.cursor, sub.started = num, true
+	}
+	return nil
+}
+
+func (wc *wsConn) sendTx(sub *wsSub, tx *core.Transaction) error {
+	tr, err := txResponse(tx)
+	if err != nil {
+		return nil
+	}
+	return wc.send(&wsNotification{
+		Subscription: sub.id,
+		Topic:        sub.topic,
+		Result:       tr,
+	})
+}
+
+func (wc *wsConn) sendLogs(sub *wsSub, ev *core.Event) error {
+	logs := make([]*wsLog, 0, len(ev.Logs))
+	for _, l := range ev.Logs {
+		if sub.addr != nil && l.Address != *sub.addr.CommonAddress() {
+			continue
+		}
+		wl := &wsLog{
+			Address: l.Address.Hex(),
+			Data:    hex.EncodeToString(l.Data),
+		}
+		for _, topic := range l.Topics {
+			wl.Topics = append(wl.Topics, topic.Hex())
+		}
+		logs = append(logs, wl)
+	}
+	if len(logs) == 0 {
+		return nil
+	}
+	return wc.send(&wsNotification{
+		Subscription: sub.id,
+		Topic:        sub.topic,
+		Cursor:       ev.Block.Number(),
+		Result:       logs,
+	})
+}
@@ -0,0 +1,323 @@
+// Copyright (C) 2019 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+package datadir
+
+/*
+   节点目录布局管理
+   所有模块的数据文件（node key, dht store, chain db, keystore, logs）都通过Layout
+   取得路径，不再各自拼接。目录根下的LAYOUT文件记录布局版本，布局调整时增加一个
+   migration，把旧文件移动到新位置后更新版本号，避免用户数据丢失。
+
+   version 0: 无LAYOUT文件的旧目录，chaindata/keystore/p2p直接放在根目录下
+   version 1: chain/ keystore/ p2p/ logs/
+
+   旧版本的目录如果没有需要移动的文件（比如只有keystore/和p2p/，位置在各版本中
+   相同），打开时直接记录为当前版本，只有确实需要移动文件时才要求migrate。
+*/
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	LayoutLegacy  = 0             // unversioned layout of old releases
+	LayoutV1      = 1             // chain/keystore/p2p/logs split
+	CurrentLayout = LayoutV1      // layout written by this binary
+	VersionFile   = "LAYOUT"      // file under root recording the layout version
+	LockFile      = "LOCK"        // node dir lock file, same path in all layouts
+	legacyChainDB = "chaindata"   // chain db path in legacy layout
+	dirChain      = "chain"       // chain db path since v1
+	dirChainOf    = "chain-%d"    // chain db path of extra chains hosted by the node
+	dirKeystore   = "keystore"    // keystore path
+	dirP2p        = "p2p"         // p2p data path (node key, node db, dht store)
+	dirP2pOf      = "p2p-%d"      // p2p data path of extra chains of their own p2p networks
+	dirLogs       = "logs"        // log files path
+	dirCheckpoint = "checkpoints" // checkpoint files path
+	fileAdminTok  = "admin.token" // token of admin rpc methods
+)
+
+var (
+	ErrLayoutOutdated = errors.New("datadir: layout outdated, run \"gyee datadir migrate\"")
+	ErrLayoutTooNew   = errors.New("datadir: layout created by a newer version")
+	ErrInvalidVersion = errors.New("datadir: invalid layout version file")
+)
+
+// Layout resolves paths of node data files for one layout version
+type Layout struct {
+	root    string
+	version int
+}
+
+// New returns the current layout rooted at root, without touching the disk
+func New(root string) *Layout {
+	return &Layout{
+		root:    root,
+		version: CurrentLayout,
+	}
+}
+
+// Open reads the layout version of an existing root. A root without version
+// file is taken as legacy if it holds any legacy data, otherwise it's a new
+// node directory and the current version is recorded. An outdated root with
+// nothing to move by the migrations pending is recorded as current as well.
+func Open(root string) (*Layout, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	l, recorded, err := inspect(root)
+	if err != nil || recorded {
+		return l, err
+	}
+	if l.version == CurrentLayout {
+		if err := writeVersion(root, CurrentLayout); err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Inspect is the same as Open except that nothing is created or written, for
+// readers like "gyee datadir info". A root not exist is an error, and one not
+// yet recorded is reported as the version Open would record.
+func Inspect(root string) (*Layout, error) {
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+	l, _, err := inspect(root)
+	return l, err
+}
+
+// read layout version of root, recorded is false if the version is still to
+// be recorded, for no version file found or nothing to migrate
+func inspect(root string) (l *Layout, recorded bool, err error) {
+	l = &Layout{root: root}
+	ver, err := readVersion(root)
+	if err == nil {
+		l.version, recorded = ver, true
+		if ver > CurrentLayout {
+			return l, true, ErrLayoutTooNew
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, false, err
+	} else if isLegacy(root) {
+		l.version = LayoutLegacy
+	} else {
+		l.version = CurrentLayout
+	}
+	if l.version < CurrentLayout && !l.movesPending() {
+		l.version, recorded = CurrentLayout, false
+	}
+	return l, recorded, nil
+}
+
+func (l *Layout) Root() string {
+	return l.root
+}
+
+func (l *Layout) Version() int {
+	return l.version
+}
+
+// Check returns an error if the layout can't be used by this binary as is
+func (l *Layout) Check() error {
+	if l.version < CurrentLayout {
+		return ErrLayoutOutdated
+	} else if l.version > CurrentLayout {
+		return ErrLayoutTooNew
+	}
+	return nil
+}
+
+func (l *Layout) ChainDataDir() string {
+	if l.version == LayoutLegacy {
+		return filepath.Join(l.root, legacyChainDB)
+	}
+	return filepath.Join(l.root, dirChain)
+}
+
+// ChainDataDirOf returns the chain db path of an extra chain hosted by the node
+// besides the primary one, whose db is at ChainDataDir
+func (l *Layout) ChainDataDirOf(chainID uint32) string {
+	return filepath.Join(l.root, fmt.Sprintf(dirChainOf, chainID))
+}
+
+func (l *Layout) KeystoreDir() string {
+	return filepath.Join(l.root, dirKeystore)
+}
+
+func (l *Layout) P2pDir() string {
+	return filepath.Join(l.root, dirP2p)
+}
+
+// P2pDirOf returns the p2p data path of an extra chain which is not sharing
+// the p2p network of the node, see P2pDir
+func (l *Layout) P2pDirOf(chainID uint32) string {
+	return filepath.Join(l.root, fmt.Sprintf(dirP2pOf, chainID))
+}
+
+func (l *Layout) LogDir() string {
+	return filepath.Join(l.root, dirLogs)
+}
+
+func (l *Layout) CheckpointDir() string {
+	return filepath.Join(l.root, dirCheckpoint)
+}
+
+// AdminTokenFile returns the path of token file, which clients of admin rpc
+// methods read to authenticate themselves
+func (l *Layout) AdminTokenFile() string {
+	return filepath.Join(l.root, fileAdminTok)
+}
+
+func (l *Layout) LockFile() string {
+	return filepath.Join(l.root, LockFile)
+}
+
+// Move describes a single file or directory relocation, paths relative to root
+type Move struct {
+	From string
+	To   string
+}
+
+// Migration upgrades a root from version From to From+1
+type Migration struct {
+	From  int
+	Desc  string
+	Moves []Move
+}
+
+// migrations must be kept sorted by From, one entry for each version step
+var migrations = []Migration{
+	{
+		From: LayoutLegacy,
+		Desc: "move chain db into chain/",
+		Moves: []Move{
+			{From: legacyChainDB, To: dirChain},
+		},
+	},
+}
+
+// Pending returns migrations needed to bring the root to the current version
+func (l *Layout) Pending() []Migration {
+	pending := make([]Migration, 0)
+	for _, m := range migrations {
+		if m.From >= l.version && m.From < CurrentLayout {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// if any file is to be moved by the migrations pending
+func (l *Layout) movesPending() bool {
+	for _, m := range l.Pending() {
+		for _, mv := range m.Moves {
+			if _, err := os.Stat(filepath.Join(l.root, mv.From)); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Migrate applies pending migrations one by one, the version file is updated
+// after each step so that an interrupted migration can be resumed. Progress is
+// reported with report, nothing is changed on disk when dryRun is set.
+func (l *Layout) Migrate(dryRun bool, report func(string)) error {
+	if l.version > CurrentLayout {
+		return ErrLayoutTooNew
+	}
+	if report == nil {
+		report = func(string) {}
+	}
+	for _, m := range l.Pending() {
+		report(fmt.Sprintf("layout %d -> %d: %s", m.From, m.From+1, m.Desc))
+		for _, mv := range m.Moves {
+			src := filepath.Join(l.root, mv.From)
+			dst := filepath.Join(l.root, mv.To)
+			if _, err := os.Stat(src); os.IsNotExist(err) {
+				report(fmt.Sprintf("  skip %s: not exist", mv.From))
+				continue
+			}
+			if _, err := os.Stat(dst); err == nil {
+				return fmt.Errorf("datadir: migration target exists: %s", dst)
+			}
+			report(fmt.Sprintf("  move %s -> %s", mv.From, mv.To))
+			if dryRun {
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+				return err
+			}
+			if err := os.Rename(src, dst); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			continue
+		}
+		if err := writeVersion(l.root, m.From+1); err != nil {
+			return err
+		}
+		l.version = m.From + 1
+	}
+	if !dryRun && l.version < CurrentLayout {
+		// no data to move for the remaining steps, just record the version
+		if err := writeVersion(l.root, CurrentLayout); err != nil {
+			return err
+		}
+		l.version = CurrentLayout
+	}
+	return nil
+}
+
+func isLegacy(root string) bool {
+	for _, name := range []string{legacyChainDB, dirKeystore, dirP2p} {
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func readVersion(root string) (int, error) {
+	data, err := ioutil.ReadFile(filepath.Join(root, VersionFile))
+	if err != nil {
+		return 0, err
+	}
+	ver, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || ver < 0 {
+		return 0, ErrInvalidVersion
+	}
+	return ver, nil
+}
+
+func writeVersion(root string, ver int) error {
+	file := filepath.Join(root, VersionFile)
+	tmp := file + ".tmp"
+	if err := ioutil.WriteFile(tmp, []byte(strconv.Itoa(ver)+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
@@ -0,0 +1,192 @@
+// Copyright (C) 2019 gyee authors
+//
+// This file is part of the gyee library.
+//
+// The gyee library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The gyee library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with the gyee library.  If not, see <http://www.gnu.org/licenses/>.
+
+package datadir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenNewDir(t *testing.T) {
+	root, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	l, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Version() != CurrentLayout {
+		t.Fatalf("version: %d, want %d", l.Version(), CurrentLayout)
+	}
+	if err := l.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if ver, err := readVersion(root); err != nil || ver != CurrentLayout {
+		t.Fatalf("version file: %d, %v", ver, err)
+	}
+}
+
+func TestMigrateLegacy(t *testing.T) {
+	root, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	legacyDB := filepath.Join(root, legacyChainDB)
+	if err := os.MkdirAll(legacyDB, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(legacyDB, "CURRENT"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Version() != LayoutLegacy {
+		t.Fatalf("version: %d, want legacy", l.Version())
+	}
+	if l.Check() != ErrLayoutOutdated {
+		t.Fatal("legacy layout should be outdated")
+	}
+	if l.ChainDataDir() != legacyDB {
+		t.Fatalf("legacy chain dir: %s", l.ChainDataDir())
+	}
+
+	if err := l.Migrate(true, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(legacyDB); err != nil {
+		t.Fatal("dry run should not move files")
+	}
+	if l.Version() != LayoutLegacy {
+		t.Fatal("dry run should not change version")
+	}
+
+	if err := l.Migrate(false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if l.Version() != CurrentLayout {
+		t.Fatalf("version: %d, want %d", l.Version(), CurrentLayout)
+	}
+	if _, err := os.Stat(filepath.Join(l.ChainDataDir(), "CURRENT")); err != nil {
+		t.Fatal("chain db not moved")
+	}
+
+	reopened, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Version() != CurrentLayout || len(reopened.Pending()) != 0 {
+		t.Fatalf("reopened version: %d", reopened.Version())
+	}
+}
+
+func TestOpenNothingToMove(t *testing.T) {
+	root, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// keystore/ and p2p/ are at the same paths in all layouts
+	for _, name := range []string{dirKeystore, dirP2p} {
+		if err := os.MkdirAll(filepath.Join(root, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if l, err := Inspect(root); err != nil || l.Version() != CurrentLayout {
+		t.Fatalf("inspected: %v, %v", l, err)
+	}
+	l, err := Open(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Check(); err != nil {
+		t.Fatal(err)
+	}
+	if ver, err := readVersion(root); err != nil || ver != CurrentLayout {
+		t.Fatalf("version file: %d, %v", ver, err)
+	}
+
+	// nor an outdated version recorded
+	if err := writeVersion(root, LayoutLegacy); err != nil {
+		t.Fatal(err)
+	}
+	if l, err = Open(root); err != nil || l.Check() != nil {
+		t.Fatalf("outdated: %v, %v", l, err)
+	}
+	if ver, err := readVersion(root); err != nil || ver != CurrentLayout {
+		t.Fatalf("version file: %d, %v", ver, err)
+	}
+}
+
+func TestOpenTooNew(t *testing.T) {
+	root, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if err := writeVersion(root, CurrentLayout+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(root); err != ErrLayoutTooNew {
+		t.Fatalf("err: %v, want %v", err, ErrLayoutTooNew)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	root, err := ioutil.TempDir("", "datadir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if _, err := Inspect(filepath.Join(root, "none")); !os.IsNotExist(err) {
+		t.Fatalf("err: %v, want not exist", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "none")); !os.IsNotExist(err) {
+		t.Fatal("root created")
+	}
+
+	l, err := Inspect(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Version() != CurrentLayout {
+		t.Fatalf("version: %d, want %d", l.Version(), CurrentLayout)
+	}
+	if _, err := os.Stat(filepath.Join(root, VersionFile)); !os.IsNotExist(err) {
+		t.Fatal("version file written")
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, legacyChainDB), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if l, err = Inspect(root); err != nil || l.Version() != LayoutLegacy {
+		t.Fatalf("legacy: %v, %v", l, err)
+	}
+}
@@ -34,21 +34,56 @@ var (
 	// The full version string
 	Version = "0.0.1"
 
-	// GitCommit is set with --ldflags "-X main.gitCommit=$(git rev-parse HEAD)"
+	// GitCommit is set with --ldflags "-X github.com/yeeco/gyee/version.GitCommit=$(git rev-parse HEAD)"
 	GitCommit string
+
+	// BuildDate is set with --ldflags "-X github.com/yeeco/gyee/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+	BuildDate string
 )
 
+// Info about version and build of the node
+type Info struct {
+	Version   string // full version string
+	Client    string // client version announced to peers, see ClientVersion
+	GitCommit string // git commit, empty if not set
+	BuildDate string // build date, empty if not set
+	GoVersion string // go version built with
+	OS        string // target os
+	Arch      string // target architecture
+}
+
 func init() {
 	if GitCommit != "" {
 		Version += "-" + GitCommit[:8]
 	}
 }
 
+func GetInfo() Info {
+	return Info{
+		Version:   Version,
+		Client:    ClientVersion(),
+		GitCommit: GitCommit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+}
+
+// ClientVersion returns the string identifying this client to peers,
+// like "gyee/v0.0.1-1a2b3c4d/linux-amd64/go1.10"
+func ClientVersion() string {
+	return fmt.Sprintf("gyee/v%s/%s-%s/%s", Version, runtime.GOOS, runtime.GOARCH, runtime.Version())
+}
+
 func PrintVersion() {
 	fmt.Println("Version:", Version)
 	if GitCommit != "" {
 		fmt.Println("Git Commit:", GitCommit)
 	}
+	if BuildDate != "" {
+		fmt.Println("Build Date:", BuildDate)
+	}
 	//fmt.Println("Network Id:", c.GlobalInt(utils.NetworkIdFlag.Name))
 	fmt.Println("Go Version:", runtime.Version())
 	fmt.Println("OS:", runtime.GOOS)